@@ -97,6 +97,20 @@ func (c *ADSBClient) FetchMilitary(ctx context.Context) ([]ADSBAircraft, error)
 }
 
 func (c *ADSBClient) fetchAircraft(ctx context.Context, url string) ([]ADSBAircraft, error) {
+	body, err := c.fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var adsbResp ADSBResponse
+	if err := json.Unmarshal(body, &adsbResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return adsbResp.AC, nil
+}
+
+func (c *ADSBClient) fetch(ctx context.Context, url string) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -117,13 +131,7 @@ func (c *ADSBClient) fetchAircraft(ctx context.Context, url string) ([]ADSBAircr
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
-
-	var adsbResp ADSBResponse
-	if err := json.Unmarshal(body, &adsbResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	return adsbResp.AC, nil
+	return body, nil
 }
 
 // nacpToEPU maps NACp (Navigation Accuracy Category - Position) to
@@ -20,6 +20,7 @@ type PollerConfig struct {
 	RadiusNM        int
 	Callsign        string
 	ICAO            string
+	URL             string
 	IntervalSeconds int
 }
 
@@ -128,6 +129,28 @@ func Run(ctx context.Context, logger *slog.Logger, _ string) error {
 		},
 		"required": []any{"icao"},
 	})
+	localSchema, _ := structpb.NewStruct(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"url": map[string]any{
+				"type":           "string",
+				"title":          "aircraft.json",
+				"description":    "URL or file path of the aircraft.json written by dump1090, dump1090-fa or readsb",
+				"ui:placeholder": "e.g. http://localhost:8080/data/aircraft.json",
+				"ui:order":       0,
+			},
+			"interval_seconds": map[string]any{
+				"type":        "number",
+				"title":       "Poll Interval",
+				"description": "How often to read aircraft.json; dump1090 rewrites it every second",
+				"default":     1,
+				"minimum":     1,
+				"ui:unit":     "s",
+				"ui:order":    1,
+			},
+		},
+		"required": []any{"url"},
+	})
 
 	serviceEntityID := controllerName + ".service"
 	if err := controller.Push(ctx, &pb.Entity{
@@ -145,6 +168,7 @@ func Run(ctx context.Context, logger *slog.Logger, _ string) error {
 				{Class: "military", Label: "Military Poller"},
 				{Class: "callsign", Label: "Callsign Poller"},
 				{Class: "icao", Label: "ICAO Poller"},
+				{Class: "local", Label: "Local dump1090 Feed"},
 			},
 		},
 		Interactivity: &pb.InteractivityComponent{
@@ -159,6 +183,7 @@ func Run(ctx context.Context, logger *slog.Logger, _ string) error {
 		{Class: "military", Label: "Military Poller", Schema: militarySchema},
 		{Class: "callsign", Label: "Callsign Poller", Schema: callsignSchema},
 		{Class: "icao", Label: "ICAO Poller", Schema: icaoSchema},
+		{Class: "local", Label: "Local dump1090 Feed", Schema: localSchema},
 	}
 
 	return controller.WatchChildren(ctx, serviceEntityID, controllerName, classes, func(ctx context.Context, entityID string) error {
@@ -224,6 +249,12 @@ func pollAndPush(ctx context.Context, _ *slog.Logger, entityID string, config *P
 		}
 		aircraft, err = adsbClient.FetchByICAO(requestCtx, config.ICAO)
 
+	case "local":
+		if config.URL == "" {
+			return fmt.Errorf("local feed requires url field")
+		}
+		aircraft, err = adsbClient.FetchDump1090(requestCtx, config.URL)
+
 	default:
 		return fmt.Errorf("unknown device class %q", config.ConfigKey)
 	}
@@ -302,6 +333,9 @@ func parsePollerConfig(entity *pb.Entity) (*PollerConfig, error) {
 	if v, ok := fields["icao"]; ok {
 		pollerConfig.ICAO = v.GetStringValue()
 	}
+	if v, ok := fields["url"]; ok {
+		pollerConfig.URL = v.GetStringValue()
+	}
 	if v, ok := fields["interval_seconds"]; ok {
 		pollerConfig.IntervalSeconds = int(v.GetNumberValue())
 	}
@@ -0,0 +1,72 @@
+package adsblol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// maxPositionAge is how old, in seconds, a position in a dump1090 feed may
+// be. dump1090 keeps aircraft for minutes after their last message; older
+// positions would put them on the map where they no longer are.
+const maxPositionAge = 60
+
+// dump1090Aircraft is an entry of dump1090's aircraft.json. dump1090-fa
+// and readsb use the adsb.lol field names; the original dump1090 and
+// dump1090-mutability report "altitude" and "speed" instead.
+type dump1090Aircraft struct {
+	ADSBAircraft
+	Altitude *FlexibleInt `json:"altitude"`
+	Speed    *float64     `json:"speed"`
+}
+
+// Dump1090Response is dump1090's aircraft.json.
+type Dump1090Response struct {
+	Now      float64            `json:"now"`
+	Messages int                `json:"messages"`
+	Aircraft []dump1090Aircraft `json:"aircraft"`
+}
+
+// ParseDump1090 parses an aircraft.json written by dump1090 or one of its
+// forks. Aircraft whose position is older than maxPositionAge are left out.
+func ParseDump1090(data []byte) ([]ADSBAircraft, error) {
+	var resp Dump1090Response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse aircraft.json: %w", err)
+	}
+
+	aircraft := make([]ADSBAircraft, 0, len(resp.Aircraft))
+	for _, ac := range resp.Aircraft {
+		if ac.SeenPos != nil && *ac.SeenPos > maxPositionAge {
+			continue
+		}
+		a := ac.ADSBAircraft
+		a.Hex = strings.ToLower(strings.TrimSpace(a.Hex))
+		if a.AltBaro == nil {
+			a.AltBaro = ac.Altitude
+		}
+		if a.GroundSpeed == nil {
+			a.GroundSpeed = ac.Speed
+		}
+		aircraft = append(aircraft, a)
+	}
+	return aircraft, nil
+}
+
+// FetchDump1090 reads the aircraft.json at url, which is either an http(s)
+// URL of dump1090's web server or the path of the file on disk.
+func (c *ADSBClient) FetchDump1090(ctx context.Context, url string) ([]ADSBAircraft, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		data, err = c.fetch(ctx, url)
+	} else {
+		data, err = os.ReadFile(strings.TrimPrefix(url, "file://"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseDump1090(data)
+}
@@ -0,0 +1,82 @@
+package adsblol
+
+import (
+	"context"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestParseDump1090(t *testing.T) {
+	data, err := os.ReadFile("testdata/aircraft.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	aircraft, err := ParseDump1090(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 3c4b26 last reported its position two minutes ago.
+	byHex := make(map[string]ADSBAircraft)
+	for _, ac := range aircraft {
+		byHex[ac.Hex] = ac
+	}
+	if len(aircraft) != 4 || byHex["3c4b26"].Hex != "" {
+		t.Fatalf("got %d aircraft %v, want all but the stale 3c4b26", len(aircraft), byHex)
+	}
+
+	// The original dump1090 names altitude and speed differently.
+	dlh := byHex["3c6444"]
+	if dlh.AltBaro == nil || !dlh.AltBaro.Valid || dlh.AltBaro.Value != 36000 {
+		t.Errorf("altitude not mapped: %+v", dlh.AltBaro)
+	}
+	if dlh.GroundSpeed == nil || *dlh.GroundSpeed != 452 {
+		t.Errorf("speed not mapped: %v", dlh.GroundSpeed)
+	}
+
+	if _, ok := byHex["4b1814"]; !ok {
+		t.Error("hex not lowercased")
+	}
+}
+
+func TestDump1090ToEntity(t *testing.T) {
+	client := NewADSBClient()
+	aircraft, err := client.FetchDump1090(context.Background(), "testdata/aircraft.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entities := make(map[string]bool)
+	for _, ac := range aircraft {
+		e := ADSBAircraftToEntity(ac, "adsblol", "adsblol.local", 1)
+		if e == nil {
+			continue
+		}
+		entities[e.Id] = true
+
+		switch e.Id {
+		case "icao:3c6444":
+			if got := e.Geo.GetAltitude(); math.Abs(got-36000*0.3048) > 1e-6 {
+				t.Errorf("altitude %v m, want FL360", got)
+			}
+			if e.GetLabel() != "DLH123" || e.Transponder.Adsb.GetIcaoAddress() != 0x3c6444 {
+				t.Errorf("label %q icao %x", e.GetLabel(), e.Transponder.Adsb.GetIcaoAddress())
+			}
+			if e.Kinematics.GetVelocityEnu() == nil {
+				t.Error("no velocity from speed")
+			}
+		case "icao:4b1814":
+			if got := e.Geo.GetAltitude(); got != 0 {
+				t.Errorf("aircraft on ground at %v m", got)
+			}
+		case "icao:3e1bf9":
+			if !e.Navigation.GetEmergency() {
+				t.Error("squawk 7700 not flagged as emergency")
+			}
+		}
+	}
+	if len(entities) != 3 {
+		t.Errorf("got entities %v, want the 3 aircraft with a fresh position", entities)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/paulmach/orb/geo"
 	"github.com/projectqai/hydris/builtin"
 	"github.com/projectqai/hydris/builtin/controller"
+	"github.com/projectqai/hydris/pkg/backoff"
 	pb "github.com/projectqai/proto/go"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
@@ -37,6 +38,12 @@ type StreamConfig struct {
 	Longitude           *float64 `json:"longitude"`
 	RadiusKM            *float64 `json:"radius_km"`
 
+	// Vessel symbol; empty derives it from the vessel type
+	SIDC string `json:"sidc"`
+
+	// Cap of the reconnect backoff; backoff.DefaultMax when zero
+	ReconnectMaxSeconds float64 `json:"reconnect_max_seconds"`
+
 	// Self position (receiver position from GPS RMC sentences)
 	SelfEntityID     string `json:"self_entity_id"`
 	SelfLabel        string `json:"self_label"`
@@ -98,6 +105,25 @@ func Run(ctx context.Context, logger *slog.Logger, _ string) error {
 				"ui:group":    "connection",
 				"ui:order":    2,
 			},
+			"sidc": map[string]any{
+				"type":           "string",
+				"title":          "Vessel Symbol",
+				"description":    "MIL-STD-2525C symbol code for vessels",
+				"ui:placeholder": "e.g. " + defaultVesselSIDC,
+				"ui:group":       "connection",
+				"ui:order":       3,
+			},
+			"reconnect_max_seconds": map[string]any{
+				"type":        "number",
+				"title":       "Max Reconnect Delay",
+				"description": "The delay before reconnecting doubles after each failure, up to this",
+				"default":     backoff.DefaultMax.Seconds(),
+				"minimum":     1,
+				"ui:unit":     "s",
+				"ui:group":    "connection",
+				"ui:order":    4,
+			},
+			controller.ExpireOnTeardownField: controller.ExpireOnTeardownProperty(),
 			"latitude": map[string]any{
 				"type":           "number",
 				"title":          "Latitude",
@@ -143,7 +169,7 @@ func Run(ctx context.Context, logger *slog.Logger, _ string) error {
 				"type":           "string",
 				"title":          "Symbol",
 				"description":    "MIL-STD-2525C symbol code for self",
-				"ui:placeholder": "e.g. SFSPXM----*****",
+				"ui:placeholder": "e.g. " + defaultVesselSIDC,
 				"ui:group":       "self",
 				"ui:order":       2,
 			},
@@ -191,7 +217,7 @@ func Run(ctx context.Context, logger *slog.Logger, _ string) error {
 	return controller.WatchChildren(ctx, serviceEntityID, controllerName, classes, func(ctx context.Context, entityID string) error {
 		return controller.Run(ctx, entityID, func(ctx context.Context, entity *pb.Entity, ready func()) error {
 			return runStream(ctx, logger, entity, ready)
-		})
+		}, controller.WithExpireOnTeardownConfig(controllerName))
 	})
 }
 
@@ -228,6 +254,7 @@ func runStream(ctx context.Context, logger *slog.Logger, entity *pb.Entity, read
 	aisDecoder := ais.CodecNew(false, false)
 	aisDecoder.DropSpace = true
 
+	bo := streamConfig.reconnectBackoff()
 	for {
 		select {
 		case <-ctx.Done():
@@ -237,10 +264,14 @@ func runStream(ctx context.Context, logger *slog.Logger, entity *pb.Entity, read
 
 		conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
 		if err != nil {
-			logger.Error("Failed to connect", "error", err)
-			time.Sleep(5 * time.Second)
+			delay := bo.Next()
+			logger.Error("Failed to connect", "entityID", entity.Id, "backoff", delay, "error", err)
+			if !sleepCtx(ctx, delay) {
+				return ctx.Err()
+			}
 			continue
 		}
+		started := time.Now()
 
 		_ = conn.SetReadDeadline(time.Now().Add(30 * time.Second))
 		scanner := bufio.NewScanner(conn)
@@ -263,8 +294,29 @@ func runStream(ctx context.Context, logger *slog.Logger, entity *pb.Entity, read
 		}
 
 		_ = conn.Close()
-		logger.Warn("Connection closed, reconnecting...", "entityID", entity.Id)
-		time.Sleep(2 * time.Second)
+		// A feed that accepts and then drops connections backs off as
+		// well; one that streamed for a while starts over.
+		bo.ResetIfHealthy(started)
+		delay := bo.Next()
+		logger.Warn("Connection closed, reconnecting", "entityID", entity.Id, "backoff", delay)
+		if !sleepCtx(ctx, delay) {
+			return ctx.Err()
+		}
+	}
+}
+
+// reconnectBackoff returns the backoff of the stream's reconnect loop.
+func (c *StreamConfig) reconnectBackoff() *backoff.Backoff {
+	return &backoff.Backoff{Max: time.Duration(c.ReconnectMaxSeconds * float64(time.Second))}
+}
+
+// sleepCtx waits for d and reports whether ctx is still live.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
 	}
 }
 
@@ -398,7 +450,7 @@ func processAISPacket(ctx context.Context, logger *slog.Logger, packet ais.Packe
 			return false
 		}
 
-		entity := VesselToEntity(vessel, controllerName, trackerID, time.Duration(config.EntityExpirySeconds))
+		entity := VesselToEntity(vessel, controllerName, trackerID, time.Duration(config.EntityExpirySeconds), config.SIDC)
 		if entity == nil {
 			return false
 		}
@@ -434,7 +486,7 @@ func processAISPacket(ctx context.Context, logger *slog.Logger, packet ais.Packe
 			return false
 		}
 
-		entity := VesselToEntity(vessel, controllerName, trackerID, time.Duration(config.EntityExpirySeconds))
+		entity := VesselToEntity(vessel, controllerName, trackerID, time.Duration(config.EntityExpirySeconds), config.SIDC)
 		if entity == nil {
 			return false
 		}
@@ -472,7 +524,7 @@ func processAISPacket(ctx context.Context, logger *slog.Logger, packet ais.Packe
 			return false
 		}
 
-		entity := VesselToEntity(vessel, controllerName, trackerID, time.Duration(config.EntityExpirySeconds))
+		entity := VesselToEntity(vessel, controllerName, trackerID, time.Duration(config.EntityExpirySeconds), config.SIDC)
 		if entity == nil {
 			return false
 		}
@@ -487,6 +539,46 @@ func processAISPacket(ctx context.Context, logger *slog.Logger, packet ais.Packe
 
 		return true
 
+	case ais.StandardSearchAndRescueAircraftReport:
+		if msg.UserID == 0 || !validPosition(float64(msg.Latitude), float64(msg.Longitude)) {
+			return false
+		}
+
+		entity := SARAircraftToEntity(msg, controllerName, trackerID, time.Duration(config.EntityExpirySeconds))
+		if !checkGeoFilter(&AISVessel{Latitude: entity.Geo.Latitude, Longitude: entity.Geo.Longitude}, config) {
+			return false
+		}
+
+		_, err := worldClient.Push(ctx, &pb.EntityChangeRequest{
+			Changes: []*pb.Entity{entity},
+		})
+		if err != nil {
+			logger.Error("Failed to push SAR aircraft", "error", err)
+			return false
+		}
+
+		return true
+
+	case ais.LongRangeAisBroadcastMessage:
+		if msg.UserID == 0 || !validPosition(float64(msg.Latitude), float64(msg.Longitude)) {
+			return false
+		}
+
+		entity := LongRangeToEntity(msg, controllerName, trackerID, time.Duration(config.EntityExpirySeconds), config.SIDC)
+		if !checkGeoFilter(&AISVessel{Latitude: entity.Geo.Latitude, Longitude: entity.Geo.Longitude}, config) {
+			return false
+		}
+
+		_, err := worldClient.Push(ctx, &pb.EntityChangeRequest{
+			Changes: []*pb.Entity{entity},
+		})
+		if err != nil {
+			logger.Error("Failed to push long-range vessel", "error", err)
+			return false
+		}
+
+		return true
+
 	case ais.ShipStaticData:
 		mmsi := msg.UserID
 		if mmsi == 0 {
@@ -580,11 +672,15 @@ func checkGeoFilter(vessel *AISVessel, config *StreamConfig) bool {
 	return distanceKM <= *config.RadiusKM
 }
 
-func VesselToEntity(vessel *AISVessel, controllerName string, trackerID string, expires time.Duration) *pb.Entity {
+// VesselToEntity converts a vessel to an entity with symbol sidc, or one
+// derived from the vessel type if sidc is empty.
+func VesselToEntity(vessel *AISVessel, controllerName string, trackerID string, expires time.Duration, sidc string) *pb.Entity {
 	entityID := fmt.Sprintf("mmsi:%d", vessel.MMSI)
 
 	altitude := 0.0
-	sidc := vesselTypeToSIDC(vessel.Type)
+	if sidc == "" {
+		sidc = vesselTypeToSIDC(vessel.Type)
+	}
 
 	// AIS position accuracy: true = DGPS (<10m), false = autonomous GNSS
 	// Convert to variance (σ²) assuming EPU ≈ 2σ
@@ -662,21 +758,112 @@ func VesselToEntity(vessel *AISVessel, controllerName string, trackerID string,
 		}
 
 		if vessel.Speed > 0 && vessel.Speed < 102.3 {
-			speedMs := vessel.Speed * 0.514444
-			east := speedMs * math.Sin(rad)
-			north := speedMs * math.Cos(rad)
-			entity.Kinematics = &pb.KinematicsComponent{
-				VelocityEnu: &pb.KinematicsEnu{
-					East:  &east,
-					North: &north,
-				},
-			}
+			entity.Kinematics = velocityENU(vessel.Course, vessel.Speed)
 		}
 	}
 
 	return entity
 }
 
+// velocityENU returns the velocity of a track moving at knots over the
+// ground along course, in degrees.
+func velocityENU(course, knots float64) *pb.KinematicsComponent {
+	rad := course * math.Pi / 180.0
+	speedMs := knots * 0.514444
+	east := speedMs * math.Sin(rad)
+	north := speedMs * math.Cos(rad)
+	return &pb.KinematicsComponent{
+		VelocityEnu: &pb.KinematicsEnu{
+			East:  &east,
+			North: &north,
+		},
+	}
+}
+
+// validPosition reports whether lat and lon are a position rather than
+// the AIS "not available" values of 91 and 181.
+func validPosition(lat, lon float64) bool {
+	return math.Abs(lat) <= 90 && math.Abs(lon) <= 180
+}
+
+// sarAircraftSIDC is a friendly military aircraft; SAR aircraft are mostly
+// flown by coast guards and navies.
+const sarAircraftSIDC = "SFAPM-----*****"
+
+// SARAircraftToEntity converts a standard SAR aircraft position report
+// (message 9) to an air track. Unlike vessels it has an altitude and its
+// speed is reported in whole knots up to 1022.
+func SARAircraftToEntity(msg ais.StandardSearchAndRescueAircraftReport, controllerName string, trackerID string, expires time.Duration) *pb.Entity {
+	vessel := &AISVessel{
+		MMSI:             msg.UserID,
+		Latitude:         float64(msg.Latitude),
+		Longitude:        float64(msg.Longitude),
+		Course:           float64(msg.Cog),
+		PositionAccuracy: msg.PositionAccuracy,
+		LastSeen:         time.Now(),
+	}
+	entity := VesselToEntity(vessel, controllerName, trackerID, expires, sarAircraftSIDC)
+
+	// Navigational status is a vessel field.
+	entity.Navigation = nil
+
+	// 4095 is not available; 4094 means 4094 m or higher.
+	entity.Geo.Altitude = nil
+	if msg.Altitude < 4095 {
+		altitude := float64(msg.Altitude)
+		entity.Geo.Altitude = &altitude
+	}
+
+	// 1023 is not available; 1022 means 1022 knots or faster.
+	if entity.Orientation != nil && msg.Sog > 0 && msg.Sog < 1023 {
+		entity.Kinematics = velocityENU(vessel.Course, float64(msg.Sog))
+	}
+
+	return entity
+}
+
+// longRangeMinExpiry is the shortest lifetime of a long-range position.
+// Message 27 is sent every three minutes, so shorter lifetimes would let
+// the track expire between reports.
+const longRangeMinExpiry = 10 * time.Minute
+
+// LongRangeToEntity converts a long-range AIS broadcast (message 27), as
+// received by satellites and distant shore stations, to a vessel track. Its
+// position is given in tenths of a minute, so the covariance is widened to
+// cover the quantization.
+func LongRangeToEntity(msg ais.LongRangeAisBroadcastMessage, controllerName string, trackerID string, expires time.Duration, sidc string) *pb.Entity {
+	vessel := &AISVessel{
+		MMSI:               msg.UserID,
+		Latitude:           float64(msg.Latitude),
+		Longitude:          float64(msg.Longitude),
+		Course:             float64(msg.Cog),
+		Heading:            511,
+		PositionAccuracy:   msg.PositionAccuracy,
+		NavigationalStatus: msg.NavigationalStatus,
+		LastSeen:           time.Now(),
+	}
+	// 63 is not available; the course is 511 then too, which
+	// VesselToEntity already ignores.
+	if msg.Sog < 63 {
+		vessel.Speed = float64(msg.Sog)
+	}
+
+	if expires*time.Second < longRangeMinExpiry {
+		expires = longRangeMinExpiry / time.Second
+	}
+	entity := VesselToEntity(vessel, controllerName, trackerID, expires, sidc)
+
+	// A tenth of a minute of latitude is about 185 m. Uniform quantization
+	// over that cell adds 185²/12 m² to the GNSS variance.
+	posVar := entity.Geo.Covariance.GetMxx() + 185*185/12.0
+	entity.Geo.Covariance = &pb.CovarianceMatrix{
+		Mxx: &posVar,
+		Myy: &posVar,
+	}
+
+	return entity
+}
+
 func SelfToEntity(rmc nmea.RMC, controllerName string, trackerID string, config *StreamConfig) *pb.Entity {
 	entityID := config.SelfEntityID
 	if entityID == "" {
@@ -690,7 +877,7 @@ func SelfToEntity(rmc nmea.RMC, controllerName string, trackerID string, config
 
 	sidc := config.SelfSIDC
 	if sidc == "" {
-		sidc = "SFSPXM----*****"
+		sidc = defaultVesselSIDC
 	}
 
 	altitude := 0.0
@@ -760,8 +947,11 @@ func aisNavStatusToNavMode(navStatus uint8) pb.NavigationMode {
 	}
 }
 
+// defaultVesselSIDC is a friendly merchant ship.
+const defaultVesselSIDC = "SFSPXM----*****"
+
 func vesselTypeToSIDC(shipType uint8) string {
-	return "SFSPXM----*****"
+	return defaultVesselSIDC
 }
 
 func parseStreamConfig(config *pb.ConfigurationComponent) (*StreamConfig, error) {
@@ -781,6 +971,9 @@ func parseStreamConfig(config *pb.ConfigurationComponent) (*StreamConfig, error)
 	if v, ok := fields["entity_expiry_seconds"]; ok {
 		streamConfig.EntityExpirySeconds = int(v.GetNumberValue())
 	}
+	if v, ok := fields["reconnect_max_seconds"]; ok {
+		streamConfig.ReconnectMaxSeconds = v.GetNumberValue()
+	}
 	if v, ok := fields["latitude"]; ok {
 		lat := v.GetNumberValue()
 		streamConfig.Latitude = &lat
@@ -793,6 +986,9 @@ func parseStreamConfig(config *pb.ConfigurationComponent) (*StreamConfig, error)
 		radius := v.GetNumberValue()
 		streamConfig.RadiusKM = &radius
 	}
+	if v, ok := fields["sidc"]; ok {
+		streamConfig.SIDC = v.GetStringValue()
+	}
 	if v, ok := fields["self_entity_id"]; ok {
 		streamConfig.SelfEntityID = v.GetStringValue()
 	}
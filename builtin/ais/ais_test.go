@@ -0,0 +1,125 @@
+package ais
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/BertoldVdb/go-ais"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestVesselToEntity_SIDC(t *testing.T) {
+	vessel := &AISVessel{MMSI: 211234560, Latitude: 53.5, Longitude: 9.9, Name: "TESTSHIP"}
+
+	e := VesselToEntity(vessel, "ais", "ais.stream.1", 300, "")
+	if got := e.GetSymbol().GetMilStd2525C(); got != defaultVesselSIDC {
+		t.Errorf("default sidc %q, want %q", got, defaultVesselSIDC)
+	}
+
+	const sidc = "SNSPXF----*****"
+	e = VesselToEntity(vessel, "ais", "ais.stream.1", 300, sidc)
+	if got := e.GetSymbol().GetMilStd2525C(); got != sidc {
+		t.Errorf("configured sidc %q, want %q", got, sidc)
+	}
+}
+
+// aisBits packs fields of (width, value) pairs into the one-bit-per-byte
+// payload the decoder takes. Negative values are two's complement.
+func aisBits(fields ...[2]int64) []byte {
+	var bits []byte
+	for _, f := range fields {
+		width, value := f[0], f[1]
+		for i := width - 1; i >= 0; i-- {
+			bits = append(bits, byte(value>>i)&1)
+		}
+	}
+	return bits
+}
+
+func TestSARAircraftToEntity(t *testing.T) {
+	// Message 9 from MMSI 111232511 at 54.5 N 10.25 E, 450 m, 120 knots
+	// on course 270.
+	packet := ais.CodecNew(false, false).DecodePacket(aisBits(
+		[2]int64{6, 9}, [2]int64{2, 0}, [2]int64{30, 111232511},
+		[2]int64{12, 450}, [2]int64{10, 120}, [2]int64{1, 1},
+		[2]int64{28, int64(10.25 * 600000)}, [2]int64{27, int64(54.5 * 600000)},
+		[2]int64{12, 2700}, [2]int64{6, 30}, [2]int64{1, 0}, [2]int64{7, 0},
+		[2]int64{1, 1}, [2]int64{3, 0}, [2]int64{1, 0}, [2]int64{1, 0}, [2]int64{20, 0},
+	))
+	msg, ok := packet.(ais.StandardSearchAndRescueAircraftReport)
+	if !ok {
+		t.Fatalf("decoded %T, want a SAR aircraft report", packet)
+	}
+
+	e := SARAircraftToEntity(msg, "ais", "ais.stream.1", 300)
+	if e.Id != "mmsi:111232511" {
+		t.Errorf("id %q", e.Id)
+	}
+	if got := e.GetSymbol().GetMilStd2525C(); got[2] != 'A' {
+		t.Errorf("sidc %q is not an air track", got)
+	}
+	if math.Abs(e.Geo.Latitude-54.5) > 1e-6 || math.Abs(e.Geo.Longitude-10.25) > 1e-6 {
+		t.Errorf("position %v, %v", e.Geo.Latitude, e.Geo.Longitude)
+	}
+	if e.Geo.GetAltitude() != 450 {
+		t.Errorf("altitude %v, want 450", e.Geo.GetAltitude())
+	}
+	if e.Navigation != nil {
+		t.Errorf("aircraft has a vessel navigation status: %v", e.Navigation)
+	}
+	// 120 knots due west, past the 102.3 knot limit of vessels.
+	if east := e.GetKinematics().GetVelocityEnu().GetEast(); math.Abs(east+120*0.514444) > 1e-3 {
+		t.Errorf("east velocity %v, want %v", east, -120*0.514444)
+	}
+}
+
+func TestLongRangeToEntity(t *testing.T) {
+	// Message 27 from MMSI 211234560 at 53.5 N 9.9 E, 12 knots on course
+	// 90, under way using engine.
+	packet := ais.CodecNew(false, false).DecodePacket(aisBits(
+		[2]int64{6, 27}, [2]int64{2, 3}, [2]int64{30, 211234560},
+		[2]int64{1, 0}, [2]int64{1, 0}, [2]int64{4, 0},
+		[2]int64{18, int64(9.9 * 600)}, [2]int64{17, int64(53.5 * 600)},
+		[2]int64{6, 12}, [2]int64{9, 90}, [2]int64{1, 0}, [2]int64{1, 0},
+	))
+	msg, ok := packet.(ais.LongRangeAisBroadcastMessage)
+	if !ok {
+		t.Fatalf("decoded %T, want a long-range broadcast", packet)
+	}
+
+	e := LongRangeToEntity(msg, "ais", "ais.stream.1", 300, "")
+	if got := e.GetSymbol().GetMilStd2525C(); got != defaultVesselSIDC {
+		t.Errorf("sidc %q, want the sea surface default %q", got, defaultVesselSIDC)
+	}
+	if math.Abs(e.Geo.Latitude-53.5) > 0.01 || math.Abs(e.Geo.Longitude-9.9) > 0.01 {
+		t.Errorf("position %v, %v", e.Geo.Latitude, e.Geo.Longitude)
+	}
+	if v := e.Geo.GetCovariance().GetMxx(); v <= 2500 {
+		t.Errorf("variance %v does not cover the coarse position", v)
+	}
+	if until := e.Lifetime.Until.AsTime().Sub(e.Lifetime.From.AsTime()); until < longRangeMinExpiry {
+		t.Errorf("lifetime %v shorter than a report interval allows", until)
+	}
+	if north := e.GetKinematics().GetVelocityEnu().GetNorth(); math.Abs(north) > 1e-6 {
+		t.Errorf("north velocity %v for a vessel heading east", north)
+	}
+}
+
+func TestStreamConfig_ReconnectBackoff(t *testing.T) {
+	value, _ := structpb.NewStruct(map[string]any{"host": "ais.example.com", "port": 5631, "reconnect_max_seconds": 8})
+	cfg, err := parseStreamConfig(&pb.ConfigurationComponent{Value: value})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bo := cfg.reconnectBackoff()
+	bo.Rand = func(n int64) int64 { return n - 1 } // every delay its ceiling
+	want := []time.Duration{1, 2, 4, 8, 8}
+	for i, w := range want {
+		if got := bo.Next(); got != w*time.Second {
+			t.Fatalf("delay %d after repeated failures = %v, want %v", i, got, w*time.Second)
+		}
+	}
+}
@@ -11,18 +11,25 @@ import (
 	_ "github.com/projectqai/hydris/builtin/ais"
 	_ "github.com/projectqai/hydris/builtin/artifacts"
 	_ "github.com/projectqai/hydris/builtin/asterix"
+	_ "github.com/projectqai/hydris/builtin/dis"
+	_ "github.com/projectqai/hydris/builtin/discovery"
 	_ "github.com/projectqai/hydris/builtin/edgetx"
 	_ "github.com/projectqai/hydris/builtin/federation"
+	_ "github.com/projectqai/hydris/builtin/gps"
 	_ "github.com/projectqai/hydris/builtin/hal"
 	_ "github.com/projectqai/hydris/builtin/mavlink"
 	_ "github.com/projectqai/hydris/builtin/mediaserver"
 	_ "github.com/projectqai/hydris/builtin/meshtastic"
 	_ "github.com/projectqai/hydris/builtin/mission"
+	_ "github.com/projectqai/hydris/builtin/mqtt"
 	_ "github.com/projectqai/hydris/builtin/netscan"
 	_ "github.com/projectqai/hydris/builtin/playground"
 	_ "github.com/projectqai/hydris/builtin/plugins"
+	_ "github.com/projectqai/hydris/builtin/prometheus"
 	_ "github.com/projectqai/hydris/builtin/reolink"
 	_ "github.com/projectqai/hydris/builtin/sapient"
+	_ "github.com/projectqai/hydris/builtin/sim"
 	_ "github.com/projectqai/hydris/builtin/spacetrack"
 	_ "github.com/projectqai/hydris/builtin/tak"
+	_ "github.com/projectqai/hydris/builtin/webhook"
 )
@@ -42,6 +42,7 @@ func Run(ctx context.Context, logger *slog.Logger, _ string) error {
 				"ui:placeholder": "e.g. radar1",
 				"ui:order":       2,
 			},
+			controller.ExpireOnTeardownField: controller.ExpireOnTeardownProperty(),
 		},
 	})
 	senderSchema, _ := structpb.NewStruct(map[string]any{
@@ -117,7 +118,7 @@ func Run(ctx context.Context, logger *slog.Logger, _ string) error {
 				return runSender(ctx, logger, entity, ready)
 			}
 			return fmt.Errorf("unknown device class: %s", entity.Device.GetClass())
-		})
+		}, controller.WithExpireOnTeardownConfig(controllerName))
 	})
 }
 
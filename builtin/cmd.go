@@ -122,6 +122,7 @@ func BuiltinClientConn() (*grpc.ClientConn, error) {
 	return grpc.NewClient(
 		"passthrough:///bufconn",
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(observePushes),
 		BuiltinDialer(),
 	)
 }
@@ -147,6 +148,22 @@ var (
 	builtinServer string
 )
 
+// ServerConn connects a builtin to the world at serverURL. Once StartAll
+// has run, the engine serves the in-process listener and that is used
+// instead, as the server port may require TLS and client certificates.
+func ServerConn(serverURL string) (*grpc.ClientConn, error) {
+	builtinMu.Lock()
+	started := builtinParent != nil
+	builtinMu.Unlock()
+	if started {
+		return BuiltinClientConn()
+	}
+	return grpc.NewClient(serverURL,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(observePushes),
+	)
+}
+
 func StartAll(ctx context.Context, serverURL string) {
 	builtinMu.Lock()
 	builtinParent = ctx
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/projectqai/hydris/builtin"
 	"github.com/projectqai/hydris/goclient"
@@ -23,12 +24,33 @@ type DeviceClass struct {
 // ChildHandler is called per child device. It should block until done or ctx cancelled.
 type ChildHandler func(ctx context.Context, entityID string) error
 
+// ChildOption configures optional behavior for WatchChildren.
+type ChildOption func(*childConfig)
+
+type childConfig struct {
+	removeDebounce time.Duration
+}
+
+// WithRemoveDebounce delays stopping a child's handler after the child
+// expires. If the child reappears within d, as a flapping USB device does,
+// the handler keeps running and never sees the removal.
+func WithRemoveDebounce(d time.Duration) ChildOption {
+	return func(c *childConfig) {
+		c.removeDebounce = d
+	}
+}
+
 // WatchChildren watches for device entities parented to serviceEntityID.
 // When a child with a recognized device_class appears, it pushes
 // ConfigurableComponent (schema for that class) + Controller onto it
 // and starts handler(ctx, entityID) in a goroutine.
 // When the child expires or is unobserved, the handler's context is cancelled.
-func WatchChildren(ctx context.Context, serviceEntityID, controllerName string, classes []DeviceClass, handler ChildHandler) error {
+func WatchChildren(ctx context.Context, serviceEntityID, controllerName string, classes []DeviceClass, handler ChildHandler, opts ...ChildOption) error {
+	var cfg childConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+
 	classMap := make(map[string]DeviceClass, len(classes))
 	for _, c := range classes {
 		classMap[c.Class] = c
@@ -60,16 +82,8 @@ func WatchChildren(ctx context.Context, serviceEntityID, controllerName string,
 		return err
 	}
 
-	var mu sync.Mutex
-	children := make(map[string]context.CancelFunc)
-
-	defer func() {
-		mu.Lock()
-		for _, cancel := range children {
-			cancel()
-		}
-		mu.Unlock()
-	}()
+	children := newChildSet(ctx, cfg.removeDebounce)
+	defer children.stopAll()
 
 	for {
 		event, err := stream.Recv()
@@ -85,10 +99,8 @@ func WatchChildren(ctx context.Context, serviceEntityID, controllerName string,
 
 		switch event.T {
 		case pb.EntityChange_EntityChangeUpdated:
-			mu.Lock()
-			_, running := children[entityID]
-			mu.Unlock()
-			if running {
+			running, revived := children.revive(entityID)
+			if running && !revived {
 				continue
 			}
 
@@ -124,28 +136,106 @@ func WatchChildren(ctx context.Context, serviceEntityID, controllerName string,
 				continue
 			}
 
-			childCtx, cancel := context.WithCancel(ctx)
-			mu.Lock()
-			children[entityID] = cancel
-			mu.Unlock()
-
-			go func() {
-				err := handler(childCtx, entityID)
-				if err != nil && childCtx.Err() == nil {
-					slog.Error("WatchChildren: handler error", "entity", entityID, "error", err)
-				}
-				mu.Lock()
-				delete(children, entityID)
-				mu.Unlock()
-			}()
+			// A child that came back before its removal was due had its
+			// components re-pushed above and keeps its running handler.
+			if !running {
+				children.start(entityID, handler)
+			}
 
 		case pb.EntityChange_EntityChangeExpired, pb.EntityChange_EntityChangeUnobserved:
-			mu.Lock()
-			if cancel, ok := children[entityID]; ok {
-				cancel()
-				delete(children, entityID)
-			}
-			mu.Unlock()
+			children.remove(entityID)
+		}
+	}
+}
+
+// childSet tracks the running handlers of WatchChildren.
+type childSet struct {
+	ctx      context.Context
+	debounce time.Duration
+
+	mu       sync.Mutex
+	children map[string]*child
+}
+
+type child struct {
+	cancel context.CancelFunc
+	// removal is the pending debounced removal, nil if there is none.
+	removal *time.Timer
+}
+
+func newChildSet(ctx context.Context, debounce time.Duration) *childSet {
+	return &childSet{ctx: ctx, debounce: debounce, children: make(map[string]*child)}
+}
+
+// revive reports whether a handler is running for entityID and, if its
+// removal was pending, cancels the removal and reports revived.
+func (s *childSet) revive(entityID string) (running, revived bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.children[entityID]
+	if !ok {
+		return false, false
+	}
+	if c.removal != nil && c.removal.Stop() {
+		c.removal = nil
+		return true, true
+	}
+	return c.removal == nil, false
+}
+
+// start runs handler for entityID in its own goroutine.
+func (s *childSet) start(entityID string, handler ChildHandler) {
+	childCtx, cancel := context.WithCancel(s.ctx)
+	c := &child{cancel: cancel}
+	s.mu.Lock()
+	s.children[entityID] = c
+	s.mu.Unlock()
+
+	go func() {
+		err := handler(childCtx, entityID)
+		if err != nil && childCtx.Err() == nil {
+			slog.Error("WatchChildren: handler error", "entity", entityID, "error", err)
+		}
+		s.mu.Lock()
+		if s.children[entityID] == c {
+			delete(s.children, entityID)
+		}
+		s.mu.Unlock()
+	}()
+}
+
+// remove stops the handler for entityID, after the debounce if one is set.
+func (s *childSet) remove(entityID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.children[entityID]
+	if !ok || c.removal != nil {
+		return
+	}
+	if s.debounce <= 0 {
+		c.cancel()
+		delete(s.children, entityID)
+		return
+	}
+	c.removal = time.AfterFunc(s.debounce, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.children[entityID] == c {
+			c.cancel()
+			delete(s.children, entityID)
+		}
+	})
+}
+
+// stopAll stops every handler, including those pending removal.
+func (s *childSet) stopAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, c := range s.children {
+		if c.removal != nil {
+			c.removal.Stop()
 		}
+		c.cancel()
+		delete(s.children, id)
 	}
 }
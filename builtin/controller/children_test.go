@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingHandler counts handler starts and stops.
+func countingHandler(starts, stops *atomic.Int32) ChildHandler {
+	return func(ctx context.Context, entityID string) error {
+		starts.Add(1)
+		<-ctx.Done()
+		stops.Add(1)
+		return nil
+	}
+}
+
+func TestChildSet_FlappingChildKeepsRunning(t *testing.T) {
+	var starts, stops atomic.Int32
+	children := newChildSet(context.Background(), 100*time.Millisecond)
+	defer children.stopAll()
+
+	children.start("usb0", countingHandler(&starts, &stops))
+	for range 5 {
+		children.remove("usb0")
+		if running, revived := children.revive("usb0"); !running || !revived {
+			t.Fatalf("revive = %v, %v; want running, revived", running, revived)
+		}
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if n := starts.Load(); n != 1 {
+		t.Errorf("handler started %d times, want 1", n)
+	}
+	if n := stops.Load(); n != 0 {
+		t.Errorf("handler stopped %d times while flapping", n)
+	}
+}
+
+func TestChildSet_RemovalAfterDebounce(t *testing.T) {
+	var starts, stops atomic.Int32
+	children := newChildSet(context.Background(), 20*time.Millisecond)
+	defer children.stopAll()
+
+	children.start("usb0", countingHandler(&starts, &stops))
+	children.remove("usb0")
+
+	deadline := time.Now().Add(time.Second)
+	for stops.Load() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("handler not stopped after the debounce")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if running, _ := children.revive("usb0"); running {
+		t.Error("removed child still tracked")
+	}
+}
@@ -32,8 +32,10 @@ type RunFunc func(ctx context.Context, entity *pb.Entity, ready func()) error
 type Option func(*runConfig)
 
 type runConfig struct {
-	entity   *pb.Entity
-	onUpdate func(*pb.Entity)
+	entity             *pb.Entity
+	onUpdate           func(*pb.Entity)
+	expireOnTeardown   string
+	expireIfConfigured bool
 }
 
 // WithEntity provides the entity template for registration.
@@ -93,33 +95,17 @@ func Run(ctx context.Context, entityID string, run RunFunc, opts ...Option) erro
 	}
 
 	pushConfigurableState := func(current *pb.Entity, state pb.ConfigurableState, errMsg string, applied bool) {
-		var cfgComp *pb.ConfigurableComponent
-		if current.Configurable != nil {
-			cfgComp = proto.Clone(current.Configurable).(*pb.ConfigurableComponent)
-		} else {
-			cfgComp = &pb.ConfigurableComponent{}
-		}
-		cfgComp.State = state
-		if errMsg != "" {
-			cfgComp.Error = proto.String(errMsg)
-		} else {
-			cfgComp.Error = nil
-		}
-		if applied && current.Config != nil {
-			cfgComp.AppliedVersion = current.Config.Version
-		}
-		_, _ = worldClient.Push(ctx, &pb.EntityChangeRequest{
-			Changes: []*pb.Entity{{
-				Id:           entityID,
-				Configurable: cfgComp,
-			}},
-		})
+		pushStatus(ctx, worldClient, entityID, configurableStatus(current, state, errMsg, applied))
 	}
 
 	var cancel context.CancelFunc
 	var currentEntity *pb.Entity
 	entityExpired := false
 
+	// runDone is closed when the goroutine started by startRunning exits.
+	var runDone chan struct{}
+	var own owners
+
 	stopRunning := func() {
 		if cancel != nil {
 			cancel()
@@ -135,8 +121,12 @@ func Run(ctx context.Context, entityID string, run RunFunc, opts ...Option) erro
 		var connCtx context.Context
 		connCtx, cancel = context.WithCancel(ctx)
 		currentEntity = e
+		done := make(chan struct{})
+		runDone = done
+		teardownController := cfg.teardownController(e)
 
 		go func() {
+			defer close(done)
 			for {
 				if connCtx.Err() != nil {
 					return
@@ -152,7 +142,14 @@ func Run(ctx context.Context, entityID string, run RunFunc, opts ...Option) erro
 					}
 				}
 
-				err := run(connCtx, e, ready)
+				runCtx, token := connCtx, uint64(0)
+				if teardownController != "" {
+					runCtx, token = own.begin(connCtx)
+				}
+				err := run(runCtx, e, ready)
+				if teardownController != "" {
+					expireOwned(ctx, worldClient, teardownController, own.release(token))
+				}
 				if connCtx.Err() != nil {
 					return
 				}
@@ -182,7 +179,14 @@ func Run(ctx context.Context, entityID string, run RunFunc, opts ...Option) erro
 		}()
 	}
 
-	defer stopRunning()
+	defer func() {
+		stopRunning()
+		// Teardown still needs the connection, which closes once Run
+		// returns.
+		if cfg.expireOnTeardown != "" && runDone != nil {
+			<-runDone
+		}
+	}()
 
 	for {
 		event, err := stream.Recv()
@@ -217,7 +221,48 @@ func Run(ctx context.Context, entityID string, run RunFunc, opts ...Option) erro
 	}
 }
 
-// Push pushes one or more entities to the world service.
+// statusPushTimeout bounds a status push, which may run after the
+// controller's context is done.
+const statusPushTimeout = 5 * time.Second
+
+// configurableStatus returns a copy of current's ConfigurableComponent
+// reporting state and errMsg. An empty errMsg clears the last error. If
+// applied, the version of current's Config is recorded as applied.
+func configurableStatus(current *pb.Entity, state pb.ConfigurableState, errMsg string, applied bool) *pb.ConfigurableComponent {
+	var cfgComp *pb.ConfigurableComponent
+	if current.Configurable != nil {
+		cfgComp = proto.Clone(current.Configurable).(*pb.ConfigurableComponent)
+	} else {
+		cfgComp = &pb.ConfigurableComponent{}
+	}
+	cfgComp.State = state
+	if errMsg != "" {
+		cfgComp.Error = proto.String(errMsg)
+	} else {
+		cfgComp.Error = nil
+	}
+	if applied && current.Config != nil {
+		cfgComp.AppliedVersion = current.Config.Version
+	}
+	return cfgComp
+}
+
+// pushStatus pushes cfgComp onto entityID. It detaches from ctx so that
+// the Inactive status pushed on shutdown still reaches the engine.
+func pushStatus(ctx context.Context, client pb.WorldServiceClient, entityID string, cfgComp *pb.ConfigurableComponent) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), statusPushTimeout)
+	defer cancel()
+	_, _ = client.Push(ctx, &pb.EntityChangeRequest{
+		Changes: []*pb.Entity{{
+			Id:           entityID,
+			Configurable: cfgComp,
+		}},
+	})
+}
+
+// Push pushes one or more entities to the world service. Called with the
+// context of a run function, the entities are tracked as that run's (see
+// WithExpireOnTeardown).
 func Push(ctx context.Context, entities ...*pb.Entity) error {
 	grpcConn, err := builtin.BuiltinClientConn()
 	if err != nil {
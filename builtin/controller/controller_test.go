@@ -0,0 +1,189 @@
+package controller_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydris/builtin"
+	"github.com/projectqai/hydris/builtin/controller"
+	"github.com/projectqai/hydris/engine"
+	pb "github.com/projectqai/proto/go"
+	"github.com/projectqai/proto/go/_goconnect"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// startWorldOnBufconn serves a WorldServer on the builtin bufconn
+// listener, as StartEngine does.
+func startWorldOnBufconn(t *testing.T) *engine.WorldServer {
+	t.Helper()
+
+	eng := engine.NewWorldServer()
+	eng.InitNodeIdentity()
+
+	mux := http.NewServeMux()
+	worldPath, worldHandler := _goconnect.NewWorldServiceHandler(eng)
+	mux.Handle(worldPath, worldHandler)
+
+	srv := &http.Server{Handler: h2c.NewHandler(mux, &http2.Server{})}
+	go func() { _ = srv.Serve(builtin.GetBuiltinListener()) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return eng
+}
+
+// waitState polls until the Configurable of id reports state.
+func waitState(t *testing.T, eng *engine.WorldServer, id string, state pb.ConfigurableState) *pb.ConfigurableComponent {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if e := eng.GetHead(id); e != nil && e.Configurable.GetState() == state {
+			return e.Configurable
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s never reached %v (have %v)", id, state, eng.GetHead(id).GetConfigurable())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRun_ReportsFailureAndClearsOnShutdown(t *testing.T) {
+	eng := startWorldOnBufconn(t)
+
+	value, _ := structpb.NewStruct(map[string]any{"port": 1})
+	if _, err := eng.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{
+		Changes: []*pb.Entity{{Id: "svc1", Config: &pb.ConfigurationComponent{Value: value}}},
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- controller.Run(ctx, "svc1", func(ctx context.Context, entity *pb.Entity, ready func()) error {
+			return errors.New("port 1 is reserved")
+		})
+	}()
+
+	status := waitState(t, eng, "svc1", pb.ConfigurableState_ConfigurableStateFailed)
+	if got := status.GetError(); got != "port 1 is reserved" {
+		t.Errorf("error %q, want the run function's error", got)
+	}
+
+	cancel()
+	<-done
+	status = waitState(t, eng, "svc1", pb.ConfigurableState_ConfigurableStateInactive)
+	if status.Error != nil {
+		t.Errorf("error %q kept after clean shutdown", status.GetError())
+	}
+}
+
+func TestRun_ExpireOnTeardown(t *testing.T) {
+	eng := startWorldOnBufconn(t)
+
+	value, _ := structpb.NewStruct(map[string]any{"port": 1})
+	other := "other"
+	if _, err := eng.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{
+		Changes: []*pb.Entity{
+			{Id: "svc1", Config: &pb.ConfigurationComponent{Value: value}},
+			{Id: "other.track", Controller: &pb.Controller{Id: &other}},
+		},
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- controller.Run(ctx, "svc1", func(ctx context.Context, entity *pb.Entity, ready func()) error {
+			name := "svc"
+			if err := controller.Push(ctx,
+				&pb.Entity{Id: "svc1.track", Controller: &pb.Controller{Id: &name}},
+				// Taken over by another controller below, so left alone.
+				&pb.Entity{Id: "svc1.handed", Controller: &pb.Controller{Id: &name}},
+			); err != nil {
+				return err
+			}
+			ready()
+			<-ctx.Done()
+			return nil
+		}, controller.WithExpireOnTeardown("svc"))
+	}()
+
+	waitState(t, eng, "svc1", pb.ConfigurableState_ConfigurableStateActive)
+	if _, err := eng.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{
+		Changes: []*pb.Entity{{Id: "svc1.handed", Controller: &pb.Controller{Id: &other}}},
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	<-done
+	eng.GC()
+
+	if e := eng.GetHead("svc1.track"); e != nil {
+		t.Errorf("svc1.track survived teardown: %v", e)
+	}
+	for _, id := range []string{"svc1.handed", "other.track", "svc1"} {
+		if eng.GetHead(id) == nil {
+			t.Errorf("%s was expired, but is not the instance's", id)
+		}
+	}
+}
+
+func TestRun_ExpireOnTeardownConfig(t *testing.T) {
+	eng := startWorldOnBufconn(t)
+
+	optIn, _ := structpb.NewStruct(map[string]any{controller.ExpireOnTeardownField: true})
+	optOut, _ := structpb.NewStruct(map[string]any{"port": 1})
+	if _, err := eng.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{
+		Changes: []*pb.Entity{
+			{Id: "in", Config: &pb.ConfigurationComponent{Value: optIn}},
+			{Id: "out", Config: &pb.ConfigurationComponent{Value: optOut}},
+		},
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 2)
+	for _, id := range []string{"in", "out"} {
+		go func() {
+			done <- controller.Run(ctx, id, func(ctx context.Context, entity *pb.Entity, ready func()) error {
+				// Pushed like the ingest controllers do, not with controller.Push.
+				conn, err := builtin.BuiltinClientConn()
+				if err != nil {
+					return err
+				}
+				defer func() { _ = conn.Close() }()
+				name := "svc"
+				if _, err := pb.NewWorldServiceClient(conn).Push(ctx, &pb.EntityChangeRequest{
+					Changes: []*pb.Entity{{Id: id + ".track", Controller: &pb.Controller{Id: &name}}},
+				}); err != nil {
+					return err
+				}
+				ready()
+				<-ctx.Done()
+				return nil
+			}, controller.WithExpireOnTeardownConfig("svc"))
+		}()
+	}
+
+	waitState(t, eng, "in", pb.ConfigurableState_ConfigurableStateActive)
+	waitState(t, eng, "out", pb.ConfigurableState_ConfigurableStateActive)
+	cancel()
+	<-done
+	<-done
+	eng.GC()
+
+	if e := eng.GetHead("in.track"); e != nil {
+		t.Errorf("in.track survived teardown of an instance that opted in: %v", e)
+	}
+	if eng.GetHead("out.track") == nil {
+		t.Error("out.track was expired, but its instance did not opt in")
+	}
+}
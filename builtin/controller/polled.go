@@ -42,32 +42,13 @@ func RunPolled(ctx context.Context, entityID string, run PollFunc) error {
 	}
 
 	pushConfigurableState := func(current *pb.Entity, state pb.ConfigurableState, errMsg string, scheduledAt *time.Time, applied bool) {
-		var cfg *pb.ConfigurableComponent
-		if current.Configurable != nil {
-			cfg = proto.Clone(current.Configurable).(*pb.ConfigurableComponent)
-		} else {
-			cfg = &pb.ConfigurableComponent{}
-		}
-		cfg.State = state
-		if errMsg != "" {
-			cfg.Error = proto.String(errMsg)
-		} else {
-			cfg.Error = nil
-		}
+		cfg := configurableStatus(current, state, errMsg, applied)
 		if scheduledAt != nil {
 			cfg.ScheduledAt = timestamppb.New(*scheduledAt)
 		} else {
 			cfg.ScheduledAt = nil
 		}
-		if applied && current.Config != nil {
-			cfg.AppliedVersion = current.Config.Version
-		}
-		_, _ = worldClient.Push(ctx, &pb.EntityChangeRequest{
-			Changes: []*pb.Entity{{
-				Id:           entityID,
-				Configurable: cfg,
-			}},
-		})
+		pushStatus(ctx, worldClient, entityID, cfg)
 	}
 
 	var cancel context.CancelFunc
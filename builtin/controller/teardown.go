@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/projectqai/hydris/builtin"
+	pb "github.com/projectqai/proto/go"
+)
+
+// WithExpireOnTeardown makes Run expire the entities a run of the run
+// function pushed once that run returns, whether it was cancelled, failed
+// or went idle. This keeps a crashed or reconfigured instance from leaving
+// its entities behind until their lifetime ends.
+//
+// Entities are tracked when pushed with the run function's context over a
+// connection from builtin.BuiltinClientConn or builtin.ServerConn, which
+// includes Push, or when passed to Track. At teardown, only those still
+// controlled by controllerID are expired, so an entity another controller
+// took over is left alone, as is one the next run pushed again.
+func WithExpireOnTeardown(controllerID string) Option {
+	return func(c *runConfig) {
+		c.expireOnTeardown = controllerID
+		c.expireIfConfigured = false
+	}
+}
+
+// ExpireOnTeardownField is the config field WithExpireOnTeardownConfig
+// reads.
+const ExpireOnTeardownField = "expire_on_teardown"
+
+// WithExpireOnTeardownConfig is WithExpireOnTeardown for the instances
+// whose config sets ExpireOnTeardownField to true, so operators choose per
+// instance. Add ExpireOnTeardownProperty to the device class schemas that
+// offer it.
+func WithExpireOnTeardownConfig(controllerID string) Option {
+	return func(c *runConfig) {
+		c.expireOnTeardown = controllerID
+		c.expireIfConfigured = true
+	}
+}
+
+// ExpireOnTeardownProperty is the schema property of ExpireOnTeardownField.
+func ExpireOnTeardownProperty() map[string]any {
+	return map[string]any{
+		"type":        "boolean",
+		"title":       "Remove Entities on Stop",
+		"description": "Expire the entities received by this instance when it stops or is reconfigured, instead of keeping them until their lifetime ends",
+		"default":     false,
+	}
+}
+
+// teardownController is the controller id whose entities a run on e
+// expires at teardown, or "" if it expires none.
+func (c *runConfig) teardownController(e *pb.Entity) string {
+	if c.expireIfConfigured && !e.GetConfig().GetValue().GetFields()[ExpireOnTeardownField].GetBoolValue() {
+		return ""
+	}
+	return c.expireOnTeardown
+}
+
+// Track records ids as pushed by the current run, for Run to expire at
+// teardown if WithExpireOnTeardown is set. ctx must be, or derive from,
+// the context Run passed to the run function; otherwise Track does
+// nothing. Pushes over builtin connections are tracked automatically;
+// Track is for entities that reach the world another way.
+func Track(ctx context.Context, ids ...string) {
+	if r, ok := ctx.Value(runKey{}).(runToken); ok {
+		r.owners.claim(r.token, ids)
+	}
+}
+
+// runKey is the context key of the runToken.
+type runKey struct{}
+
+// runToken identifies one run of the run function.
+type runToken struct {
+	owners *owners
+	token  uint64
+}
+
+// owners maps entity ids to the run that last pushed them.
+type owners struct {
+	mu    sync.Mutex
+	next  uint64
+	owner map[string]uint64
+}
+
+// begin returns ctx carrying the token of a new run.
+func (o *owners) begin(ctx context.Context) (context.Context, uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.next++
+	token := o.next
+	ctx = context.WithValue(ctx, runKey{}, runToken{owners: o, token: token})
+	return builtin.WithPushObserver(ctx, func(ids []string) { o.claim(token, ids) }), token
+}
+
+func (o *owners) claim(token uint64, ids []string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.owner == nil {
+		o.owner = make(map[string]uint64)
+	}
+	for _, id := range ids {
+		o.owner[id] = token
+	}
+}
+
+// release forgets the run and returns the ids it still owns.
+func (o *owners) release(token uint64) []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var ids []string
+	for id, t := range o.owner {
+		if t == token {
+			ids = append(ids, id)
+			delete(o.owner, id)
+		}
+	}
+	return ids
+}
+
+// expireOwned expires those of ids still controlled by controllerID. It
+// detaches from ctx, as teardown usually follows its cancellation.
+func expireOwned(ctx context.Context, client pb.WorldServiceClient, controllerID string, ids []string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), statusPushTimeout)
+	defer cancel()
+	for _, id := range ids {
+		resp, err := client.GetEntity(ctx, &pb.GetEntityRequest{Id: id})
+		if err != nil || resp.Entity.GetController().GetId() != controllerID {
+			continue
+		}
+		if _, err := client.ExpireEntity(ctx, &pb.ExpireEntityRequest{Id: id}); err != nil {
+			slog.Warn("failed to expire entity on teardown", "entity", id, "error", err)
+		}
+	}
+}
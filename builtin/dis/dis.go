@@ -0,0 +1,196 @@
+// Package dis ingests DIS (IEEE 1278.1) Entity State PDUs from simulators.
+package dis
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/projectqai/hydris/builtin"
+	"github.com/projectqai/hydris/builtin/controller"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func init() {
+	builtin.Register("dis", Run)
+}
+
+func Run(ctx context.Context, logger *slog.Logger, _ string) error {
+	controllerName := "dis"
+
+	receiverSchema, _ := structpb.NewStruct(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"listen": map[string]any{
+				"type":           "string",
+				"title":          "Listen Address",
+				"description":    "UDP address to receive DIS PDUs",
+				"default":        ":3000",
+				"ui:placeholder": "e.g. :3000",
+				"ui:order":       0,
+			},
+			"exercise_id": map[string]any{
+				"type":        "integer",
+				"title":       "Exercise ID",
+				"description": "Only accept PDUs of this exercise (0 = any)",
+				"default":     0,
+				"minimum":     0,
+				"maximum":     255,
+				"ui:order":    1,
+			},
+			"expiry_seconds": map[string]any{
+				"type":        "number",
+				"title":       "Expiry",
+				"description": "Remove entities that sent no update for this long",
+				"default":     15,
+				"minimum":     1,
+				"ui:unit":     "s",
+				"ui:order":    2,
+			},
+			controller.ExpireOnTeardownField: controller.ExpireOnTeardownProperty(),
+		},
+	})
+
+	serviceID := controllerName + ".service"
+
+	if err := controller.Push(ctx, &pb.Entity{
+		Id:    serviceID,
+		Label: proto.String("DIS"),
+		Controller: &pb.Controller{
+			Id: &controllerName,
+		},
+		Device: &pb.DeviceComponent{
+			Category: proto.String("Feeds"),
+		},
+		Configurable: &pb.ConfigurableComponent{
+			SupportedDeviceClasses: []*pb.DeviceClassOption{
+				{Class: "receiver", Label: "Receiver"},
+			},
+		},
+		Interactivity: &pb.InteractivityComponent{
+			Icon: proto.String("radar"),
+		},
+	}); err != nil {
+		return fmt.Errorf("publish device: %w", err)
+	}
+
+	classes := []controller.DeviceClass{
+		{Class: "receiver", Label: "Receiver", Schema: receiverSchema},
+	}
+
+	return controller.WatchChildren(ctx, serviceID, controllerName, classes, func(ctx context.Context, entityID string) error {
+		return controller.Run(ctx, entityID, func(ctx context.Context, entity *pb.Entity, ready func()) error {
+			return runReceiver(ctx, logger, controllerName, entity, ready)
+		}, controller.WithExpireOnTeardownConfig(controllerName))
+	})
+}
+
+func runReceiver(ctx context.Context, logger *slog.Logger, controllerName string, entity *pb.Entity, ready func()) error {
+	listenAddr := ":3000"
+	var exerciseID uint8
+	expiry := 15 * time.Second
+
+	if entity.Config != nil && entity.Config.Value != nil && entity.Config.Value.Fields != nil {
+		fields := entity.Config.Value.Fields
+		if v, ok := fields["listen"]; ok && v.GetStringValue() != "" {
+			listenAddr = v.GetStringValue()
+		}
+		if v, ok := fields["exercise_id"]; ok {
+			exerciseID = uint8(v.GetNumberValue())
+		}
+		if v, ok := fields["expiry_seconds"]; ok && v.GetNumberValue() >= 1 {
+			expiry = time.Duration(v.GetNumberValue() * float64(time.Second))
+		}
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", listenAddr)
+	if err != nil {
+		return fmt.Errorf("resolve UDP addr: %w", err)
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return fmt.Errorf("listen UDP: %w", err)
+	}
+	ready()
+	defer func() { _ = conn.Close() }()
+
+	logger.Info("DIS receiver listening", "addr", listenAddr, "exerciseID", exerciseID)
+
+	grpcConn, err := builtin.BuiltinClientConn()
+	if err != nil {
+		return fmt.Errorf("gRPC connection: %w", err)
+	}
+	defer func() { _ = grpcConn.Close() }()
+
+	client := pb.NewWorldServiceClient(grpcConn)
+
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	var pdusReceived, entitiesPushed uint64
+	buffer := make([]byte, 65536)
+	for {
+		n, remoteAddr, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logger.Error("UDP read error", "error", err)
+			continue
+		}
+
+		var entities []*pb.Entity
+		for _, pdu := range splitPDUs(buffer[:n]) {
+			pdusReceived++
+			state, err := decodeEntityState(pdu)
+			if err != nil {
+				logger.Debug("Skip PDU", "from", remoteAddr, "error", err)
+				continue
+			}
+			if exerciseID != 0 && state.Exercise != exerciseID {
+				continue
+			}
+			entities = append(entities, stateToEntity(state, controllerName, entity.Id, expiry))
+		}
+		if len(entities) == 0 {
+			continue
+		}
+
+		if _, err := client.Push(ctx, &pb.EntityChangeRequest{Changes: entities}); err != nil {
+			logger.Error("Push to Hydris failed", "error", err, "count", len(entities))
+			continue
+		}
+		entitiesPushed += uint64(len(entities))
+		_, _ = client.Push(ctx, &pb.EntityChangeRequest{
+			Changes: []*pb.Entity{{
+				Id: entity.Id,
+				Metric: &pb.MetricComponent{Metrics: []*pb.Metric{
+					{Kind: pb.MetricKind_MetricKindCount.Enum(), Unit: pb.MetricUnit_MetricUnitCount, Label: proto.String("PDUs received"), Id: proto.Uint32(1), Val: &pb.Metric_Uint64{Uint64: pdusReceived}},
+					{Kind: pb.MetricKind_MetricKindCount.Enum(), Unit: pb.MetricUnit_MetricUnitCount, Label: proto.String("entities pushed"), Id: proto.Uint32(2), Val: &pb.Metric_Uint64{Uint64: entitiesPushed}},
+				}},
+			}},
+		})
+	}
+}
+
+// splitPDUs splits a datagram into the PDUs it carries; DIS allows
+// several PDUs back to back, each prefixed with its length in the header.
+func splitPDUs(b []byte) [][]byte {
+	var pdus [][]byte
+	for len(b) >= headerLength {
+		length := int(binary.BigEndian.Uint16(b[8:10]))
+		if length < headerLength || length > len(b) {
+			break
+		}
+		pdus = append(pdus, b[:length])
+		b = b[length:]
+	}
+	return pdus
+}
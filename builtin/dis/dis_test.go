@@ -0,0 +1,138 @@
+package dis
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+	"time"
+)
+
+// fighterPDU is a DIS 7 Entity State PDU of a friendly US fighter
+// "VIPER01" at 48.137N 11.575E, 520 m, heading 90 degrees with 10 degrees
+// pitch up and flying east at 10 m/s.
+const fighterPDU = "07030101000000000090000000010c1d002a0100010200e1010300000000000000000000" +
+	"c0006a93411cbefe00000000414fe027e93ad0a2412a1d511d4869b3415208a4c5cc4210" +
+	"3fd3ed7abe04cc85c019d02c000000000000000000000000000000000000000000000000" +
+	"000000000000000000000000000000000000000001564950455230310000000000000000"
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestDecodeEntityState(t *testing.T) {
+	s, err := decodeEntityState(decodeHex(t, fighterPDU))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Exercise != 3 || s.Site != 1 || s.Application != 3101 || s.Entity != 42 {
+		t.Errorf("entity id %d:%d.%d.%d", s.Exercise, s.Site, s.Application, s.Entity)
+	}
+	if s.Force != 1 || s.Type.Kind != 1 || s.Type.Domain != 2 || s.Type.Country != 225 || s.Type.Category != 1 {
+		t.Errorf("type %+v force %d", s.Type, s.Force)
+	}
+	if s.Marking != "VIPER01" {
+		t.Errorf("marking %q", s.Marking)
+	}
+}
+
+func TestStateToEntity(t *testing.T) {
+	s, err := decodeEntityState(decodeHex(t, fighterPDU))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := stateToEntity(s, "dis", "dis.receiver", 15*time.Second)
+
+	if e.Id != "dis.3.1.3101.42" || e.GetLabel() != "VIPER01" {
+		t.Errorf("id %q label %q", e.Id, e.GetLabel())
+	}
+	if !near(e.Geo.Latitude, 48.137, 1e-7) || !near(e.Geo.Longitude, 11.575, 1e-7) || !near(e.Geo.GetAltitude(), 520, 1e-3) {
+		t.Errorf("position %v %v %v", e.Geo.Latitude, e.Geo.Longitude, e.Geo.GetAltitude())
+	}
+
+	v := e.Kinematics.VelocityEnu
+	if !near(v.GetEast(), 10, 1e-3) || !near(v.GetNorth(), 0, 1e-3) || !near(v.GetUp(), 0, 1e-3) {
+		t.Errorf("velocity east %v north %v up %v", v.GetEast(), v.GetNorth(), v.GetUp())
+	}
+
+	heading, pitch, roll := localEuler(s.Orientation, e.Geo.Latitude, e.Geo.Longitude)
+	if !near(heading, math.Pi/2, 1e-5) || !near(pitch, 10*math.Pi/180, 1e-5) || !near(roll, 0, 1e-5) {
+		t.Errorf("heading %v pitch %v roll %v", heading, pitch, roll)
+	}
+	q := e.Orientation.Orientation
+	if !near(q.X*q.X+q.Y*q.Y+q.Z*q.Z+q.W*q.W, 1, 1e-9) {
+		t.Errorf("quaternion %v not normalized", q)
+	}
+
+	if got := e.Symbol.GetMilStd2525C(); got != "SFAPMFF--------" {
+		t.Errorf("symbol %q", got)
+	}
+	if !e.Lifetime.Until.AsTime().After(time.Now()) {
+		t.Error("active entity should not be expired")
+	}
+
+	s.Appearance |= appearanceDeactivated
+	if e := stateToEntity(s, "dis", "dis.receiver", 15*time.Second); e.Lifetime.Until.AsTime().After(time.Now()) {
+		t.Error("deactivated entity should expire")
+	}
+}
+
+func TestDecodeEntityState_Invalid(t *testing.T) {
+	pdu := decodeHex(t, fighterPDU)
+
+	if _, err := decodeEntityState(pdu[:100]); err == nil {
+		t.Error("truncated PDU should fail")
+	}
+	fire := append([]byte(nil), pdu...)
+	fire[2] = 2
+	if _, err := decodeEntityState(fire); err == nil {
+		t.Error("fire PDU should be rejected")
+	}
+	articulated := append([]byte(nil), pdu...)
+	articulated[19] = 2
+	if _, err := decodeEntityState(articulated); err == nil {
+		t.Error("PDU shorter than its articulation parameters should fail")
+	}
+}
+
+func TestSplitPDUs(t *testing.T) {
+	pdu := decodeHex(t, fighterPDU)
+	datagram := append(append(append([]byte(nil), pdu...), pdu...), 0x07, 0x03)
+	if got := splitPDUs(datagram); len(got) != 2 || len(got[0]) != len(pdu) || len(got[1]) != len(pdu) {
+		t.Errorf("got %d PDUs", len(got))
+	}
+}
+
+func TestEntityTypeToSIDC(t *testing.T) {
+	tests := []struct {
+		name  string
+		force uint8
+		typ   EntityType
+		want  string
+	}{
+		{"friendly tank", 1, EntityType{Kind: 1, Domain: 1, Category: 1}, "SFGPEVAT-------"},
+		{"opposing truck", 2, EntityType{Kind: 1, Domain: 1, Category: 6}, "SHGPEV---------"},
+		{"neutral airliner", 3, EntityType{Kind: 1, Domain: 2, Category: 57}, "SNAPMF---------"},
+		{"opposing helicopter", 2, EntityType{Kind: 1, Domain: 2, Category: 20}, "SHAPMH---------"},
+		{"friendly UAV", 1, EntityType{Kind: 1, Domain: 2, Category: 51}, "SFAPMFQ--------"},
+		{"other ship", 0, EntityType{Kind: 1, Domain: 3, Category: 1}, "SUSP-----------"},
+		{"friendly submarine", 4, EntityType{Kind: 1, Domain: 4, Category: 1}, "SFUPS----------"},
+		{"opposing missile", 2, EntityType{Kind: 2, Domain: 2}, "SHAPWM---------"},
+		{"friendly soldier", 1, EntityType{Kind: 3, Domain: 1}, "SFGPUCI--------"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := entityTypeToSIDC(tt.force, tt.typ); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
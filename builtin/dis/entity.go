@@ -0,0 +1,166 @@
+package dis
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/projectqai/hydris/pkg/coords"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// appearanceDeactivated is the platform appearance bit for an entity that
+// has left the exercise.
+const appearanceDeactivated = 1 << 23
+
+// entityID returns the hydris entity id of a DIS entity, unique within
+// all exercises.
+func entityID(s *EntityState) string {
+	return fmt.Sprintf("dis.%d.%d.%d.%d", s.Exercise, s.Site, s.Application, s.Entity)
+}
+
+// stateToEntity converts an Entity State PDU to an entity that lives for
+// expiry unless a newer PDU refreshes it. Deactivated entities expire
+// immediately.
+func stateToEntity(s *EntityState, controllerName, trackerID string, expiry time.Duration) *pb.Entity {
+	lat, lon, alt := coords.FromECEF(s.Location[0], s.Location[1], s.Location[2])
+	east, north, up := velocityENU(s.Velocity, lat, lon)
+	heading, pitch, roll := localEuler(s.Orientation, lat, lon)
+
+	// ZYX intrinsic, as for MAVLink attitude
+	cr, sr := math.Cos(roll/2), math.Sin(roll/2)
+	cp, sp := math.Cos(pitch/2), math.Sin(pitch/2)
+	cy, sy := math.Cos(heading/2), math.Sin(heading/2)
+
+	now := time.Now()
+	until := now.Add(expiry)
+	if s.Appearance&appearanceDeactivated != 0 {
+		until = now
+	}
+
+	e := &pb.Entity{
+		Id: entityID(s),
+		Geo: &pb.GeoSpatialComponent{
+			Latitude:  lat,
+			Longitude: lon,
+			Altitude:  proto.Float64(alt),
+		},
+		Orientation: &pb.OrientationComponent{
+			Orientation: &pb.Quaternion{
+				W: cr*cp*cy + sr*sp*sy,
+				X: sr*cp*cy - cr*sp*sy,
+				Y: cr*sp*cy + sr*cp*sy,
+				Z: cr*cp*sy - sr*sp*cy,
+			},
+		},
+		Kinematics: &pb.KinematicsComponent{
+			VelocityEnu: &pb.KinematicsEnu{
+				East:  proto.Float64(east),
+				North: proto.Float64(north),
+				Up:    proto.Float64(up),
+			},
+		},
+		Symbol: &pb.SymbolComponent{
+			MilStd2525C: entityTypeToSIDC(s.Force, s.Type),
+		},
+		Controller: &pb.Controller{
+			Id: &controllerName,
+		},
+		Track: &pb.TrackComponent{
+			Tracker: &trackerID,
+		},
+		Lifetime: &pb.Lifetime{
+			From:  timestamppb.New(now),
+			Until: timestamppb.New(until),
+		},
+		Routing: &pb.Routing{Channels: []*pb.Channel{{}}},
+	}
+	if s.Marking != "" {
+		e.Label = proto.String(s.Marking)
+	}
+	return e
+}
+
+// DIS entity kinds and domains.
+const (
+	kindPlatform = 1
+	kindMunition = 2
+	kindLifeForm = 3
+
+	domainLand       = 1
+	domainAir        = 2
+	domainSurface    = 3
+	domainSubsurface = 4
+	domainSpace      = 5
+)
+
+// airFunctions maps DIS air platform categories to 2525C function ids.
+var airFunctions = map[uint8]string{
+	1:  "MFF---", // fighter/air defense
+	2:  "MFA---", // attack/strike
+	3:  "MFB---", // bomber
+	4:  "MFC---", // cargo/tanker
+	7:  "MFR---", // reconnaissance
+	20: "MH----", // attack helicopter
+	21: "MH----", // utility helicopter
+	22: "MH----", // antisubmarine warfare helicopter
+	23: "MH----", // cargo helicopter
+	24: "MH----", // observation helicopter
+	25: "MH----", // special operations helicopter
+	51: "MFQ---", // unmanned
+}
+
+// entityTypeToSIDC maps a DIS force id and entity type to a 2525C SIDC.
+// Types without a more specific symbol get the plain frame of their
+// domain.
+func entityTypeToSIDC(force uint8, t EntityType) string {
+	// Force ids repeat friendly, opposing, neutral from 1 on.
+	affiliation := byte('U')
+	if force > 0 {
+		affiliation = "FHN"[(force-1)%3]
+	}
+
+	dimension := byte('G')
+	switch t.Domain {
+	case domainAir:
+		dimension = 'A'
+	case domainSurface:
+		dimension = 'S'
+	case domainSubsurface:
+		dimension = 'U'
+	case domainSpace:
+		dimension = 'P'
+	}
+
+	function := "------"
+	switch t.Kind {
+	case kindPlatform:
+		switch t.Domain {
+		case domainLand:
+			function = "EV----"
+			if t.Category == 1 {
+				function = "EVAT--"
+			}
+		case domainAir:
+			function = "MF----"
+			if f, ok := airFunctions[t.Category]; ok {
+				function = f
+			}
+		case domainSubsurface:
+			function = "S-----"
+		case domainSpace:
+			function = "S-----"
+		}
+	case kindMunition:
+		dimension = 'A'
+		function = "WM----"
+	case kindLifeForm:
+		if t.Domain == domainLand {
+			function = "UCI---"
+		}
+	}
+
+	return fmt.Sprintf("S%c%cP%s-----", affiliation, dimension, function)
+}
@@ -0,0 +1,148 @@
+package dis
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// PDU types and lengths from IEEE 1278.1.
+const (
+	pduTypeEntityState = 1
+	headerLength       = 12
+	entityStateLength  = 144
+	articulationLength = 16
+)
+
+// EntityType is the DIS entity type record.
+type EntityType struct {
+	Kind        uint8
+	Domain      uint8
+	Country     uint16
+	Category    uint8
+	Subcategory uint8
+	Specific    uint8
+	Extra       uint8
+}
+
+// EntityState is the part of an Entity State PDU that hydris uses.
+type EntityState struct {
+	Exercise    uint8
+	Site        uint16
+	Application uint16
+	Entity      uint16
+	Force       uint8
+	Type        EntityType
+	// Velocity is in geocentric (ECEF) coordinates, m/s.
+	Velocity [3]float32
+	// Location is in geocentric (ECEF) coordinates, m.
+	Location [3]float64
+	// Orientation holds the psi, theta, phi Euler angles in radians,
+	// relative to the geocentric frame.
+	Orientation [3]float32
+	Appearance  uint32
+	Marking     string
+}
+
+// decodeEntityState parses a DIS (version 6 or 7) Entity State PDU. PDUs
+// of any other type are rejected.
+func decodeEntityState(b []byte) (*EntityState, error) {
+	if len(b) < headerLength {
+		return nil, fmt.Errorf("PDU too short: %d bytes", len(b))
+	}
+	if b[2] != pduTypeEntityState {
+		return nil, fmt.Errorf("not an entity state PDU: type %d", b[2])
+	}
+	length := int(binary.BigEndian.Uint16(b[8:10]))
+	if length < entityStateLength || length > len(b) {
+		return nil, fmt.Errorf("entity state PDU length %d, have %d bytes", length, len(b))
+	}
+	if want := entityStateLength + int(b[19])*articulationLength; length < want {
+		return nil, fmt.Errorf("entity state PDU length %d, want %d", length, want)
+	}
+
+	s := &EntityState{
+		Exercise:    b[1],
+		Site:        binary.BigEndian.Uint16(b[12:14]),
+		Application: binary.BigEndian.Uint16(b[14:16]),
+		Entity:      binary.BigEndian.Uint16(b[16:18]),
+		Force:       b[18],
+		Type: EntityType{
+			Kind:        b[20],
+			Domain:      b[21],
+			Country:     binary.BigEndian.Uint16(b[22:24]),
+			Category:    b[24],
+			Subcategory: b[25],
+			Specific:    b[26],
+			Extra:       b[27],
+		},
+		Appearance: binary.BigEndian.Uint32(b[84:88]),
+	}
+	for i := range 3 {
+		s.Velocity[i] = math.Float32frombits(binary.BigEndian.Uint32(b[36+4*i:]))
+		s.Location[i] = math.Float64frombits(binary.BigEndian.Uint64(b[48+8*i:]))
+		s.Orientation[i] = math.Float32frombits(binary.BigEndian.Uint32(b[72+4*i:]))
+	}
+	// Marking: one character set byte, then 11 characters. Only ASCII
+	// (character set 1) is meaningful as a label.
+	if b[128] == 1 {
+		marking, _, _ := strings.Cut(string(b[129:140]), "\x00")
+		s.Marking = strings.TrimSpace(marking)
+	}
+	return s, nil
+}
+
+// localFrame returns the north, east and down unit vectors at a geodetic
+// position, in geocentric coordinates.
+func localFrame(lat, lon float64) (north, east, down [3]float64) {
+	sinLat, cosLat := math.Sincos(lat * math.Pi / 180)
+	sinLon, cosLon := math.Sincos(lon * math.Pi / 180)
+	north = [3]float64{-sinLat * cosLon, -sinLat * sinLon, cosLat}
+	east = [3]float64{-sinLon, cosLon, 0}
+	down = [3]float64{-cosLat * cosLon, -cosLat * sinLon, -sinLat}
+	return
+}
+
+func dot(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+// velocityENU rotates a geocentric velocity into east, north and up at a
+// geodetic position.
+func velocityENU(v [3]float32, lat, lon float64) (east, north, up float64) {
+	n, e, d := localFrame(lat, lon)
+	vec := [3]float64{float64(v[0]), float64(v[1]), float64(v[2])}
+	return dot(vec, e), dot(vec, n), -dot(vec, d)
+}
+
+// localEuler converts DIS Euler angles (psi, theta, phi relative to the
+// geocentric frame) to heading, pitch and roll in radians relative to
+// north-east-down at a geodetic position.
+func localEuler(o [3]float32, lat, lon float64) (heading, pitch, roll float64) {
+	sinPsi, cosPsi := math.Sincos(float64(o[0]))
+	sinTheta, cosTheta := math.Sincos(float64(o[1]))
+	sinPhi, cosPhi := math.Sincos(float64(o[2]))
+
+	// Body axes in geocentric coordinates.
+	x := [3]float64{cosTheta * cosPsi, cosTheta * sinPsi, -sinTheta}
+	y := [3]float64{
+		sinPhi*sinTheta*cosPsi - cosPhi*sinPsi,
+		sinPhi*sinTheta*sinPsi + cosPhi*cosPsi,
+		sinPhi * cosTheta,
+	}
+	z := [3]float64{
+		cosPhi*sinTheta*cosPsi + sinPhi*sinPsi,
+		cosPhi*sinTheta*sinPsi - sinPhi*cosPsi,
+		cosPhi * cosTheta,
+	}
+
+	n, e, d := localFrame(lat, lon)
+	heading = math.Atan2(dot(x, e), dot(x, n))
+	if heading < 0 {
+		heading += 2 * math.Pi
+	}
+	pitch = math.Asin(math.Max(-1, math.Min(1, -dot(x, d))))
+	roll = math.Atan2(dot(y, d), dot(z, d))
+	return heading, pitch, roll
+}
@@ -0,0 +1,182 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/projectqai/hydris/builtin"
+	"github.com/projectqai/hydris/builtin/controller"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func init() {
+	builtin.Register("discovery", Run)
+}
+
+// Run publishes the discovery service. Each multicast instance announces
+// this node and publishes every node it hears as "node.<id>", with its
+// gRPC address in device.ip.host, which federation accepts as a target.
+func Run(ctx context.Context, logger *slog.Logger, serverURL string) error {
+	controllerName := "discovery"
+
+	multicastSchema, _ := structpb.NewStruct(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"group": map[string]any{
+				"type":        "string",
+				"title":       "Multicast Group",
+				"description": "UDP multicast group and port to announce and listen on",
+				"default":     DefaultGroup,
+				"ui:order":    0,
+			},
+			"interval_seconds": map[string]any{
+				"type":        "number",
+				"title":       "Interval",
+				"description": "Seconds between announcements",
+				"default":     DefaultInterval.Seconds(),
+				"minimum":     1,
+				"ui:unit":     "s",
+				"ui:order":    1,
+			},
+			"address": map[string]any{
+				"type":           "string",
+				"title":          "Advertised Address",
+				"description":    "gRPC address other nodes reach this node on. Empty announces the port only and peers use the address the announcement came from",
+				"ui:placeholder": "e.g. 10.0.0.2:50051",
+				"ui:order":       2,
+			},
+		},
+	})
+
+	serviceID := controllerName + ".service"
+
+	if err := controller.Push(ctx, &pb.Entity{
+		Id:    serviceID,
+		Label: proto.String("Discovery"),
+		Controller: &pb.Controller{
+			Id: &controllerName,
+		},
+		Device: &pb.DeviceComponent{
+			Category: proto.String("Network"),
+		},
+		Configurable: &pb.ConfigurableComponent{
+			SupportedDeviceClasses: []*pb.DeviceClassOption{
+				{Class: "multicast", Label: "Multicast"},
+			},
+		},
+		Interactivity: &pb.InteractivityComponent{
+			Icon: proto.String("network"),
+		},
+	}); err != nil {
+		return fmt.Errorf("push service entity: %w", err)
+	}
+
+	classes := []controller.DeviceClass{
+		{Class: "multicast", Label: "Multicast", Schema: multicastSchema},
+	}
+
+	return controller.WatchChildren(ctx, serviceID, controllerName, classes, func(ctx context.Context, entityID string) error {
+		return controller.Run(ctx, entityID, func(ctx context.Context, entity *pb.Entity, ready func()) error {
+			switch entity.Device.GetClass() {
+			case "multicast":
+				return runMulticast(ctx, logger, serverURL, entity, ready)
+			}
+			return fmt.Errorf("unknown device class: %s", entity.Device.GetClass())
+		})
+	})
+}
+
+func runMulticast(ctx context.Context, logger *slog.Logger, serverURL string, entity *pb.Entity, ready func()) error {
+	controllerName := "discovery"
+
+	var group, address string
+	interval := DefaultInterval
+	if entity.Config != nil && entity.Config.Value != nil {
+		fields := entity.Config.Value.Fields
+		if v, ok := fields["group"]; ok {
+			group = v.GetStringValue()
+		}
+		if v, ok := fields["address"]; ok {
+			address = v.GetStringValue()
+		}
+		if v, ok := fields["interval_seconds"]; ok && v.GetNumberValue() > 0 {
+			interval = time.Duration(v.GetNumberValue() * float64(time.Second))
+		}
+	}
+
+	addr, err := advertisedAddr(serverURL, address)
+	if err != nil {
+		return err
+	}
+
+	grpcConn, err := builtin.BuiltinClientConn()
+	if err != nil {
+		return fmt.Errorf("grpc connect: %w", err)
+	}
+	defer func() { _ = grpcConn.Close() }()
+	client := pb.NewWorldServiceClient(grpcConn)
+
+	resp, err := client.GetLocalNode(ctx, &pb.GetLocalNodeRequest{})
+	if err != nil {
+		return fmt.Errorf("get local node: %w", err)
+	}
+
+	d := &Discoverer{
+		Node:     resp.NodeId,
+		Addr:     addr,
+		Group:    group,
+		Interval: interval,
+		Logger:   logger,
+		OnPeer: func(peer Peer) {
+			// A peer missing three announcements in a row expires.
+			now := time.Now()
+			_, err := client.Push(ctx, &pb.EntityChangeRequest{Changes: []*pb.Entity{{
+				Id:         "node." + peer.Node,
+				Controller: &pb.Controller{Id: &controllerName},
+				Device: &pb.DeviceComponent{
+					Parent:   proto.String(entity.Id),
+					Category: proto.String("Network"),
+					State:    pb.DeviceState_DeviceStateActive,
+					Ip:       &pb.IpDevice{Host: proto.String(peer.Addr)},
+				},
+				Lifetime: &pb.Lifetime{
+					Fresh: timestamppb.New(now),
+					Until: timestamppb.New(now.Add(3 * interval)),
+				},
+			}}})
+			if err != nil {
+				logger.Warn("failed to publish discovered node", "node", peer.Node, "error", err)
+			}
+		},
+	}
+
+	logger.Info("starting discovery", "entityID", entity.Id, "node", d.Node, "addr", addr, "group", group)
+	ready()
+	return d.Run(ctx)
+}
+
+// advertisedAddr is the gRPC address to announce: override if set, or the
+// port of serverURL. A server only reachable on loopback is announced by
+// port alone so peers use the address the announcement came from.
+func advertisedAddr(serverURL, override string) (string, error) {
+	if override != "" {
+		if _, _, err := net.SplitHostPort(override); err != nil {
+			return "", fmt.Errorf("invalid address %q: %w", override, err)
+		}
+		return override, nil
+	}
+	host, port, err := net.SplitHostPort(serverURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid server address %q: %w", serverURL, err)
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		host = ""
+	}
+	return net.JoinHostPort(host, port), nil
+}
@@ -0,0 +1,156 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// DefaultGroup is the UDP multicast group and port nodes announce
+// themselves on unless configured.
+const DefaultGroup = "239.255.42.99:9419"
+
+// DefaultInterval is the time between announcements unless configured.
+const DefaultInterval = 5 * time.Second
+
+// announcementType marks a datagram as a Hydris node announcement, so that
+// other traffic on the group is ignored.
+const announcementType = "hydris.node"
+
+// announcement is the JSON datagram a node sends to the group.
+type announcement struct {
+	Type string `json:"type"`
+	Node string `json:"node"`
+	Addr string `json:"addr"`
+}
+
+// Peer is a node found on the group.
+type Peer struct {
+	// Node is the node id of the peer, its local node entity being
+	// "node.<Node>".
+	Node string
+	// Addr is the gRPC address of the peer as host:port.
+	Addr string
+}
+
+// Discoverer announces a node on a multicast group and reports the other
+// nodes it hears there.
+type Discoverer struct {
+	// Node is the id of this node.
+	Node string
+	// Addr is the gRPC address of this node. If its host is empty or
+	// unspecified, receivers use the address the announcement came from.
+	Addr string
+	// Group is the multicast group and port, DefaultGroup if empty.
+	Group string
+	// Interval is the time between announcements, DefaultInterval if zero.
+	Interval time.Duration
+	// OnPeer is called for every announcement of another node.
+	OnPeer func(Peer)
+
+	Logger *slog.Logger
+}
+
+// Run announces and listens until ctx is done.
+func (d *Discoverer) Run(ctx context.Context) error {
+	groupAddr := d.Group
+	if groupAddr == "" {
+		groupAddr = DefaultGroup
+	}
+	group, err := net.ResolveUDPAddr("udp4", groupAddr)
+	if err != nil {
+		return fmt.Errorf("resolve group: %w", err)
+	}
+	if !group.IP.IsMulticast() {
+		return fmt.Errorf("%s is not a multicast address", groupAddr)
+	}
+	interval := d.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	logger := d.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	listener, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", groupAddr, err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	sender, err := net.DialUDP("udp4", nil, group)
+	if err != nil {
+		return fmt.Errorf("dial %s: %w", groupAddr, err)
+	}
+	defer func() { _ = sender.Close() }()
+
+	msg, err := json.Marshal(announcement{Type: announcementType, Node: d.Node, Addr: d.Addr})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			// A failed send, e.g. while the network is down, is retried
+			// with the next announcement.
+			if _, err := sender.Write(msg); err != nil {
+				logger.Debug("discovery announcement failed", "group", groupAddr, "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	buf := make([]byte, 1500)
+	for {
+		n, src, err := listener.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			continue
+		}
+		peer, ok := parseAnnouncement(buf[:n], src)
+		if !ok || peer.Node == d.Node {
+			continue
+		}
+		if d.OnPeer != nil {
+			d.OnPeer(peer)
+		}
+	}
+}
+
+// parseAnnouncement decodes a datagram from src. The host of an address
+// announced without one is taken from src.
+func parseAnnouncement(b []byte, src *net.UDPAddr) (Peer, bool) {
+	var a announcement
+	if err := json.Unmarshal(b, &a); err != nil || a.Type != announcementType || a.Node == "" {
+		return Peer{}, false
+	}
+	host, port, err := net.SplitHostPort(a.Addr)
+	if err != nil {
+		return Peer{}, false
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = src.IP.String()
+	}
+	return Peer{Node: a.Node, Addr: net.JoinHostPort(host, port)}, true
+}
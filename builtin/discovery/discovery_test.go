@@ -0,0 +1,123 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testGroup returns a multicast group on a free port, or skips the test if
+// this host cannot send multicast.
+func testGroup(t *testing.T) string {
+	t.Helper()
+	l, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.LocalAddr().(*net.UDPAddr).Port
+	_ = l.Close()
+
+	group := net.JoinHostPort("239.255.42.99", strconv.Itoa(port))
+	conn, err := net.Dial("udp4", group)
+	if err == nil {
+		_, err = conn.Write([]byte("probe"))
+		_ = conn.Close()
+	}
+	if err != nil {
+		t.Skipf("multicast unavailable: %v", err)
+	}
+	return group
+}
+
+func TestDiscoverer_FindEachOther(t *testing.T) {
+	group := testGroup(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	found := make(chan Peer, 16)
+	start := func(node, addr string) {
+		d := &Discoverer{
+			Node:     node,
+			Addr:     addr,
+			Group:    group,
+			Interval: 100 * time.Millisecond,
+			OnPeer: func(p Peer) {
+				select {
+				case found <- p:
+				default:
+				}
+			},
+		}
+		go func() {
+			if err := d.Run(ctx); err != nil && ctx.Err() == nil {
+				t.Errorf("%s: %v", node, err)
+			}
+		}()
+	}
+	start("alpha", "10.0.0.1:50051")
+	start("bravo", "10.0.0.2:50051")
+
+	want := map[string]string{"alpha": "10.0.0.1:50051", "bravo": "10.0.0.2:50051"}
+	seen := map[string]bool{}
+	for len(seen) < len(want) {
+		select {
+		case p := <-found:
+			if p.Addr != want[p.Node] {
+				t.Errorf("peer %s at %s, want %s", p.Node, p.Addr, want[p.Node])
+			}
+			seen[p.Node] = true
+		case <-ctx.Done():
+			t.Fatalf("discovered only %v", seen)
+		}
+	}
+}
+
+func TestParseAnnouncement(t *testing.T) {
+	src := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 7), Port: 40000}
+
+	p, ok := parseAnnouncement([]byte(`{"type":"hydris.node","node":"n1","addr":":50051"}`), src)
+	if !ok || p.Node != "n1" || p.Addr != "192.168.1.7:50051" {
+		t.Errorf("port only: got %+v, %v", p, ok)
+	}
+	p, ok = parseAnnouncement([]byte(`{"type":"hydris.node","node":"n1","addr":"0.0.0.0:50051"}`), src)
+	if !ok || p.Addr != "192.168.1.7:50051" {
+		t.Errorf("unspecified host: got %+v, %v", p, ok)
+	}
+	p, ok = parseAnnouncement([]byte(`{"type":"hydris.node","node":"n1","addr":"10.0.0.2:9090"}`), src)
+	if !ok || p.Addr != "10.0.0.2:9090" {
+		t.Errorf("explicit host: got %+v, %v", p, ok)
+	}
+
+	for _, bad := range []string{
+		`not json`,
+		`{"type":"other","node":"n1","addr":":50051"}`,
+		`{"type":"hydris.node","addr":":50051"}`,
+		`{"type":"hydris.node","node":"n1","addr":"50051"}`,
+	} {
+		if p, ok := parseAnnouncement([]byte(bad), src); ok {
+			t.Errorf("%s: parsed as %+v", bad, p)
+		}
+	}
+}
+
+func TestAdvertisedAddr(t *testing.T) {
+	tests := []struct {
+		serverURL, override, want string
+	}{
+		{"localhost:50051", "", ":50051"},
+		{"127.0.0.1:50051", "", ":50051"},
+		{"10.0.0.2:50051", "", "10.0.0.2:50051"},
+		{"localhost:50051", "hydris.lan:9090", "hydris.lan:9090"},
+	}
+	for _, tt := range tests {
+		got, err := advertisedAddr(tt.serverURL, tt.override)
+		if err != nil || got != tt.want {
+			t.Errorf("advertisedAddr(%q, %q) = %q, %v, want %q", tt.serverURL, tt.override, got, err, tt.want)
+		}
+	}
+	if _, err := advertisedAddr("localhost:50051", "no-port"); err == nil {
+		t.Error("expected an error for an address without port")
+	}
+}
@@ -0,0 +1,240 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// resumeCursor remembers what an instance has already forwarded so that a
+// reconnect does not re-replicate the whole world.
+//
+// Every (re)connected watch stream starts with a full snapshot. Without a
+// cursor each snapshot entity is pushed to the destination again, and for
+// entities this node originates that also bumps fresh, so every reconnect
+// turns into a full resync. The cursor records a digest of each forwarded
+// entity together with the time it was forwarded. An event whose digest is
+// unchanged and whose last forward is recent enough that the destination copy
+// is still well within its keepalive TTL is skipped. Anything that actually
+// changed is forwarded. Entities that were removed while the stream was down
+// are not in the snapshot; expireVanished forwards their removal.
+//
+// The cursor is stored in a file next to the other caches of hydris (see
+// cursorFile), so it also survives a restart of the process. It is only
+// resumed by an instance with the same key, i.e. the same remote and
+// config, and removed when its instance is.
+type resumeCursor struct {
+	mu   sync.Mutex
+	key  string
+	last time.Time // time of the last successful forward
+	sent map[string]sentRecord
+}
+
+type sentRecord struct {
+	Digest uint64    `json:"digest"`
+	At     time.Time `json:"at"`
+}
+
+func newResumeCursor(key string) *resumeCursor {
+	return &resumeCursor{key: key, sent: make(map[string]sentRecord)}
+}
+
+// shouldForward reports whether an entity with the given digest needs to be
+// forwarded. Unchanged entities are skipped for the duration of window after
+// their last forward; after that they are forwarded again so the keepalive
+// keeps refreshing the destination's TTL.
+func (c *resumeCursor) shouldForward(id string, digest uint64, now time.Time, window time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rec, ok := c.sent[id]
+	if !ok || rec.Digest != digest {
+		return true
+	}
+	return now.Sub(rec.At) >= window
+}
+
+// record marks an entity as successfully forwarded.
+func (c *resumeCursor) record(id string, digest uint64, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent[id] = sentRecord{Digest: digest, At: now}
+	c.last = now
+}
+
+// forget drops an entity from the cursor, e.g. once it expired.
+func (c *resumeCursor) forget(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.sent, id)
+}
+
+// lastForward returns the time of the last successful forward, or the zero
+// time if nothing was forwarded yet.
+func (c *resumeCursor) lastForward() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// ids returns the ids of all forwarded entities.
+func (c *resumeCursor) ids() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids := make([]string, 0, len(c.sent))
+	for id := range c.sent {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// cursorSaveInterval is how often a running instance writes its cursor.
+const cursorSaveInterval = 10 * time.Second
+
+// cursorFileData is the JSON form of a resumeCursor.
+type cursorFileData struct {
+	Key  string                `json:"key"`
+	Last time.Time             `json:"last"`
+	Sent map[string]sentRecord `json:"sent"`
+}
+
+// cursorFile returns where the cursor of an instance direction is stored,
+// or "" if there is no user cache directory.
+func cursorFile(entityID, direction string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "hydris", "federation", url.QueryEscape(entityID)+"."+direction+".json")
+}
+
+// loadCursor reads the cursor stored at path. A missing or unreadable file,
+// or one written for a different key, gives a new cursor.
+func loadCursor(path, key string) (*resumeCursor, error) {
+	c := newResumeCursor(key)
+	if path == "" {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	var f cursorFileData
+	if err := json.Unmarshal(data, &f); err != nil {
+		return c, err
+	}
+	if f.Key != key {
+		return c, nil
+	}
+	c.last = f.Last
+	if f.Sent != nil {
+		c.sent = f.Sent
+	}
+	return c, nil
+}
+
+// save writes the cursor to path, replacing the file atomically. Records
+// older than maxAge are dropped first: the destination has let those
+// entities expire, so they would be forwarded again anyway.
+func (c *resumeCursor) save(path string, now time.Time, maxAge time.Duration) error {
+	if path == "" {
+		return nil
+	}
+	c.mu.Lock()
+	for id, rec := range c.sent {
+		if now.Sub(rec.At) > maxAge {
+			delete(c.sent, id)
+		}
+	}
+	data, err := json.Marshal(cursorFileData{Key: c.key, Last: c.last, Sent: c.sent})
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// removeCursors deletes the stored cursors of an instance that was removed.
+func removeCursors(entityID string) {
+	for _, direction := range []string{"push", "pull"} {
+		if path := cursorFile(entityID, direction); path != "" {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+// expireVanished forwards the removal of every entity in the cursor that
+// src no longer has. The snapshot of a new stream only contains what
+// exists, so removals that happened while the stream was down, or the
+// process was not running, would otherwise never reach dst.
+func (i *Instance) expireVanished(ctx context.Context, src, dst pb.WorldServiceClient, cursor *resumeCursor) error {
+	resp, err := src.ListEntities(ctx, &pb.ListEntitiesRequest{Filter: i.filter})
+	if err != nil {
+		return err
+	}
+	present := make(map[string]bool, len(resp.Entities))
+	for _, e := range resp.Entities {
+		present[e.Id] = true
+	}
+	for _, id := range cursor.ids() {
+		if present[id] {
+			continue
+		}
+		target := &pb.Entity{Id: id}
+		relabel(target, i.idPrefix, "")
+		_, err := dst.ExpireEntity(ctx, &pb.ExpireEntityRequest{Id: target.Id})
+		if err != nil && status.Code(err) != codes.NotFound {
+			i.logger.Warn("failed to expire vanished entity", "entityID", i.entityID, "targetEntity", target.Id, "error", err)
+			continue
+		}
+		cursor.forget(id)
+		i.logger.Debug("expired vanished entity", "entityID", i.entityID, "targetEntity", target.Id)
+	}
+	return nil
+}
+
+// entityDigest hashes the deterministic wire encoding of an entity.
+func entityDigest(e *pb.Entity) uint64 {
+	return messageDigest(e)
+}
+
+// messageDigest hashes the deterministic wire encoding of m.
+func messageDigest(m proto.Message) uint64 {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return 0
+	}
+	h := fnv.New64a()
+	_, _ = h.Write(b)
+	return h.Sum64()
+}
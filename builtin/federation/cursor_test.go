@@ -0,0 +1,174 @@
+package federation
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestResumeCursor_ShouldForward(t *testing.T) {
+	c := newResumeCursor("")
+	now := time.Now()
+	window := 15 * time.Second
+
+	if !c.shouldForward("e1", 1, now, window) {
+		t.Error("unknown entity should be forwarded")
+	}
+
+	c.record("e1", 1, now)
+
+	if c.shouldForward("e1", 1, now.Add(time.Second), window) {
+		t.Error("unchanged entity inside the window should be skipped")
+	}
+	if !c.shouldForward("e1", 2, now.Add(time.Second), window) {
+		t.Error("changed entity should be forwarded")
+	}
+	if !c.shouldForward("e1", 1, now.Add(window), window) {
+		t.Error("unchanged entity past the window should be forwarded again")
+	}
+
+	c.forget("e1")
+	if !c.shouldForward("e1", 1, now.Add(time.Second), window) {
+		t.Error("forgotten entity should be forwarded")
+	}
+	if !c.lastForward().Equal(now) {
+		t.Errorf("lastForward = %v, want %v", c.lastForward(), now)
+	}
+}
+
+func TestEntityDigest(t *testing.T) {
+	a := makeEntity("e1", "node-a", 52, time.Unix(1000, 0))
+	b := proto.Clone(a).(*pb.Entity)
+	if entityDigest(a) != entityDigest(b) {
+		t.Error("identical entities should have the same digest")
+	}
+	b.Label = proto.String("changed")
+	if entityDigest(a) == entityDigest(b) {
+		t.Error("different entities should have different digests")
+	}
+}
+
+func TestResumeCursor_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cursor.json")
+	now := time.Now()
+	c := newResumeCursor("10.0.0.1:50051|1")
+	c.record("fresh", 1, now)
+	c.record("stale", 2, now.Add(-time.Hour))
+	if err := c.save(path, now, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadCursor(path, "10.0.0.1:50051|1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.lastForward().Equal(c.lastForward()) {
+		t.Errorf("lastForward = %v, want %v", loaded.lastForward(), c.lastForward())
+	}
+	if loaded.shouldForward("fresh", 1, now.Add(time.Second), time.Minute) {
+		t.Error("entity forwarded before saving should be skipped after loading")
+	}
+	if ids := loaded.ids(); len(ids) != 1 {
+		t.Errorf("records past the keepalive TTL should be dropped, got %v", ids)
+	}
+
+	other, err := loadCursor(path, "10.0.0.2:50051|1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(other.ids()) != 0 || !other.lastForward().IsZero() {
+		t.Error("a different remote or config should start over")
+	}
+	if missing, err := loadCursor(filepath.Join(t.TempDir(), "none.json"), ""); err != nil || len(missing.ids()) != 0 {
+		t.Errorf("missing file: %v, %v", missing.ids(), err)
+	}
+}
+
+func TestRemoveCursors(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	path := cursorFile("federation.removed", "push")
+	if err := newResumeCursor("").save(path, time.Now(), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	removeCursors("federation.removed")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("cursor of a removed instance still stored: %v", err)
+	}
+}
+
+// TestResume_NoGapNoResync drops a push instance, changes the world while it
+// is down and restarts it from its stored cursor. Changes and removals made
+// during the outage must arrive; the entity that was already replicated
+// must not be sent again.
+func TestResume_NoGapNoResync(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	a := startTestNode(t)
+	b := startTestNode(t)
+
+	a.push(t, makeEntity("ea", a.nodeID, 10, time.Now()))
+	updated := makeEntity("eu", a.nodeID, 11, time.Now())
+	a.push(t, updated)
+	a.push(t, makeEntity("gone", a.nodeID, 13, time.Now()))
+
+	run := func() (context.CancelFunc, <-chan error) {
+		inst := &Instance{
+			entityID:  "federation.resume.test",
+			serverURL: a.addr,
+			remote:    b.addr,
+			mode:      "push",
+			logger:    slog.Default(),
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- inst.runPush(ctx) }()
+		t.Cleanup(cancel)
+		return cancel, done
+	}
+
+	cancel, done := run()
+	waitFor(t, 5*time.Second, "initial replication", func() bool {
+		return b.has(t, "ea") && b.has(t, "eu") && b.has(t, "gone")
+	})
+	freshOnB := getFresh(t, b, "ea")
+
+	// Drop the connection.
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("runPush did not return after cancel")
+	}
+
+	// Change the world while disconnected.
+	a.push(t, makeEntity("ea2", a.nodeID, 12, time.Now()))
+	updated.Label = proto.String("changed while down")
+	updated.Lifetime = nil
+	a.push(t, updated)
+	if _, err := a.engine.ExpireEntity(context.Background(), connect.NewRequest(&pb.ExpireEntityRequest{Id: "gone"})); err != nil {
+		t.Fatal(err)
+	}
+	a.engine.GC()
+
+	run()
+	waitFor(t, 5*time.Second, "changes made during the outage", func() bool {
+		e := b.get(t, "eu")
+		return b.has(t, "ea2") && e != nil && e.GetLabel() == "changed while down"
+	})
+	waitFor(t, 5*time.Second, "removal made during the outage", func() bool {
+		b.engine.GC()
+		return !b.has(t, "gone")
+	})
+
+	// Give the snapshot time to be fully processed.
+	time.Sleep(200 * time.Millisecond)
+	if got := getFresh(t, b, "ea"); !got.Equal(freshOnB) {
+		t.Errorf("unchanged entity was re-replicated: fresh was %v, now %v", freshOnB, got)
+	}
+}
@@ -4,15 +4,18 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net"
 	"net/netip"
 	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/projectqai/hydris/builtin"
 	"github.com/projectqai/hydris/builtin/controller"
 	"github.com/projectqai/hydris/goclient"
+	"github.com/projectqai/hydris/pkg/projection"
 	pb "github.com/projectqai/proto/go"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
@@ -24,11 +27,25 @@ type Instance struct {
 	entityID  string
 	serverURL string
 	remote    string
-	mode      string // "push" or "pull"
+	mode      string // "push", "pull" or "sync"
 	filter    *pb.EntityFilter
 	limiter   *pb.WatchBehavior
 	logger    *slog.Logger
 	wgConfig  *goclient.WireGuardConfig // optional WireGuard config
+
+	// connectOpts are the TLS options of the connection to the remote.
+	connectOpts []goclient.ConnectOption
+
+	// Optional relabeling applied to forwarded entities, see relabel.
+	idPrefix     string
+	controllerID string
+
+	// components is an optional allowlist of components to forward.
+	components []uint32
+
+	// cursorKey identifies what the instance forwards and where to; a
+	// stored cursor is only resumed by an instance with the same key.
+	cursorKey string
 }
 
 var (
@@ -36,72 +53,122 @@ var (
 	globalServerURL string
 )
 
+// forwardSchema returns the configuration schema of a push, pull or sync
+// instance: the class specific properties, which include the required
+// remote address, plus the properties all three classes share.
+func forwardSchema(required string, properties map[string]any) *structpb.Struct {
+	maps.Copy(properties, map[string]any{
+		"limiter": map[string]any{
+			"type":        "object",
+			"title":       "Rate Limiter",
+			"description": "Watch behavior / rate limiter",
+			"ui:order":    2,
+		},
+		"wireguard": map[string]any{
+			"type":        "object",
+			"title":       "WireGuard",
+			"description": "Inline WireGuard tunnel config",
+			"ui:order":    3,
+		},
+		"id_prefix": map[string]any{
+			"type":           "string",
+			"title":          "ID Prefix",
+			"description":    "Prefix prepended to the id of every forwarded entity",
+			"ui:placeholder": "e.g. org-a.",
+			"ui:order":       4,
+		},
+		"controller_id": map[string]any{
+			"type":        "string",
+			"title":       "Controller ID",
+			"description": "Replaces controller.id on every forwarded entity",
+			"ui:order":    5,
+		},
+		"components": map[string]any{
+			"type":        "array",
+			"title":       "Components",
+			"description": "Only forward these components (entity field numbers). Empty forwards everything",
+			"items":       map[string]any{"type": "integer"},
+			"ui:order":    6,
+		},
+		"tls_cert": map[string]any{
+			"type":           "string",
+			"title":          "Client Certificate",
+			"description":    "Path to the client certificate PEM file, for remotes that require one",
+			"ui:placeholder": "e.g. ./certs/client.pem",
+			"ui:order":       7,
+		},
+		"tls_key": map[string]any{
+			"type":           "string",
+			"title":          "Client Key",
+			"description":    "Path to the client key PEM file",
+			"ui:placeholder": "e.g. ./certs/client-key.pem",
+			"ui:order":       8,
+		},
+		"tls_ca": map[string]any{
+			"type":           "string",
+			"title":          "CA Certificate",
+			"description":    "Path to the CA PEM file to verify the remote with instead of the system roots",
+			"ui:placeholder": "e.g. ./certs/ca.pem",
+			"ui:order":       9,
+		},
+	})
+	schema, _ := structpb.NewStruct(map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   []any{required},
+	})
+	return schema
+}
+
 func Run(ctx context.Context, logger *slog.Logger, serverURL string) error {
 	globalLogger = logger
 	globalServerURL = serverURL
 	controllerName := "federation"
 
-	pushSchema, _ := structpb.NewStruct(map[string]any{
-		"type": "object",
-		"properties": map[string]any{
-			"target": map[string]any{
-				"type":           "string",
-				"title":          "Target",
-				"description":    "Remote server address to push entities to",
-				"ui:placeholder": "e.g. 10.0.0.2:9090",
-				"ui:order":       0,
-			},
-			"filter": map[string]any{
-				"type":        "object",
-				"title":       "Filter",
-				"description": "Entity filter to select which entities to push",
-				"ui:order":    1,
-			},
-			"limiter": map[string]any{
-				"type":        "object",
-				"title":       "Rate Limiter",
-				"description": "Watch behavior / rate limiter",
-				"ui:order":    2,
-			},
-			"wireguard": map[string]any{
-				"type":        "object",
-				"title":       "WireGuard",
-				"description": "Inline WireGuard tunnel config",
-				"ui:order":    3,
-			},
+	pushSchema := forwardSchema("target", map[string]any{
+		"target": map[string]any{
+			"type":           "string",
+			"title":          "Target",
+			"description":    "Remote server address to push entities to, or a discovered node entity",
+			"ui:placeholder": "e.g. 10.0.0.2:9090",
+			"ui:order":       0,
+		},
+		"filter": map[string]any{
+			"type":        "object",
+			"title":       "Filter",
+			"description": "Entity filter to select which entities to push",
+			"ui:order":    1,
 		},
-		"required": []any{"target"},
 	})
-	pullSchema, _ := structpb.NewStruct(map[string]any{
-		"type": "object",
-		"properties": map[string]any{
-			"source": map[string]any{
-				"type":           "string",
-				"title":          "Source",
-				"description":    "Remote server address to pull entities from",
-				"ui:placeholder": "e.g. 10.0.0.2:9090",
-				"ui:order":       0,
-			},
-			"filter": map[string]any{
-				"type":        "object",
-				"title":       "Filter",
-				"description": "Entity filter to select which entities to pull",
-				"ui:order":    1,
-			},
-			"limiter": map[string]any{
-				"type":        "object",
-				"title":       "Rate Limiter",
-				"description": "Watch behavior / rate limiter",
-				"ui:order":    2,
-			},
-			"wireguard": map[string]any{
-				"type":        "object",
-				"title":       "WireGuard",
-				"description": "Inline WireGuard tunnel config",
-				"ui:order":    3,
-			},
+	pullSchema := forwardSchema("source", map[string]any{
+		"source": map[string]any{
+			"type":           "string",
+			"title":          "Source",
+			"description":    "Remote server address to pull entities from, or a discovered node entity",
+			"ui:placeholder": "e.g. 10.0.0.2:9090",
+			"ui:order":       0,
+		},
+		"filter": map[string]any{
+			"type":        "object",
+			"title":       "Filter",
+			"description": "Entity filter to select which entities to pull",
+			"ui:order":    1,
+		},
+	})
+	syncSchema := forwardSchema("remote", map[string]any{
+		"remote": map[string]any{
+			"type":           "string",
+			"title":          "Remote",
+			"description":    "Remote server address to replicate entities with in both directions, or a discovered node entity",
+			"ui:placeholder": "e.g. 10.0.0.2:9090",
+			"ui:order":       0,
+		},
+		"filter": map[string]any{
+			"type":        "object",
+			"title":       "Filter",
+			"description": "Entity filter to select which entities to replicate",
+			"ui:order":    1,
 		},
-		"required": []any{"source"},
 	})
 
 	serviceID := controllerName + ".service"
@@ -119,6 +186,7 @@ func Run(ctx context.Context, logger *slog.Logger, serverURL string) error {
 			SupportedDeviceClasses: []*pb.DeviceClassOption{
 				{Class: "push", Label: "Push"},
 				{Class: "pull", Label: "Pull"},
+				{Class: "sync", Label: "Sync"},
 			},
 		},
 		Interactivity: &pb.InteractivityComponent{
@@ -131,9 +199,17 @@ func Run(ctx context.Context, logger *slog.Logger, serverURL string) error {
 	classes := []controller.DeviceClass{
 		{Class: "push", Label: "Push", Schema: pushSchema},
 		{Class: "pull", Label: "Pull", Schema: pullSchema},
+		{Class: "sync", Label: "Sync", Schema: syncSchema},
 	}
 
+	serviceCtx := ctx
 	return controller.WatchChildren(ctx, serviceID, controllerName, classes, func(ctx context.Context, entityID string) error {
+		// The instance is gone unless the whole service is stopping.
+		defer func() {
+			if serviceCtx.Err() == nil {
+				removeCursors(entityID)
+			}
+		}()
 		return controller.Run(ctx, entityID, func(ctx context.Context, entity *pb.Entity, ready func()) error {
 			ready()
 			switch entity.Device.GetClass() {
@@ -141,6 +217,8 @@ func Run(ctx context.Context, logger *slog.Logger, serverURL string) error {
 				return runInstance(ctx, globalLogger, globalServerURL, entity, "push")
 			case "pull":
 				return runInstance(ctx, globalLogger, globalServerURL, entity, "pull")
+			case "sync":
+				return runInstance(ctx, globalLogger, globalServerURL, entity, "sync")
 			}
 			return fmt.Errorf("unknown device class: %s", entity.Device.GetClass())
 		})
@@ -159,6 +237,8 @@ func runInstance(ctx context.Context, logger *slog.Logger, serverURL string, ent
 	var filter *pb.EntityFilter
 	var limiter *pb.WatchBehavior
 	var wgConfig *goclient.WireGuardConfig
+	var idPrefix, controllerID string
+	var components []uint32
 
 	// Remote target/source
 	if v, ok := fields["target"]; ok {
@@ -167,6 +247,9 @@ func runInstance(ctx context.Context, logger *slog.Logger, serverURL string, ent
 	if v, ok := fields["source"]; ok {
 		remote = v.GetStringValue()
 	}
+	if v, ok := fields["remote"]; ok {
+		remote = v.GetStringValue()
+	}
 
 	// Parse filter
 	if v, ok := fields["filter"]; ok {
@@ -183,8 +266,38 @@ func runInstance(ctx context.Context, logger *slog.Logger, serverURL string, ent
 		wgConfig = parseWireGuardConfig(v)
 	}
 
+	// Parse relabeling
+	if v, ok := fields["id_prefix"]; ok {
+		idPrefix = v.GetStringValue()
+	}
+	if v, ok := fields["controller_id"]; ok {
+		controllerID = v.GetStringValue()
+	}
+
+	// Parse component allowlist
+	if v, ok := fields["components"]; ok {
+		components = parseComponentList(v)
+	}
+
+	// Parse client TLS
+	var connectOpts []goclient.ConnectOption
+	if cert, key := fields["tls_cert"].GetStringValue(), fields["tls_key"].GetStringValue(); cert != "" || key != "" {
+		connectOpts = append(connectOpts, goclient.WithClientCertificate(cert, key))
+	}
+	if ca := fields["tls_ca"].GetStringValue(); ca != "" {
+		connectOpts = append(connectOpts, goclient.WithRootCA(ca))
+	}
+
 	if remote == "" {
-		return fmt.Errorf("federation config missing target/source")
+		return fmt.Errorf("federation config missing target/source/remote")
+	}
+	if strings.HasPrefix(remote, "node.") {
+		addr, err := resolveNodeAddr(ctx, remote)
+		if err != nil {
+			return err
+		}
+		logger.Info("resolved federation remote", "entityID", entity.Id, "node", remote, "remote", addr)
+		remote = addr
 	}
 
 	instance := &Instance{
@@ -196,6 +309,13 @@ func runInstance(ctx context.Context, logger *slog.Logger, serverURL string, ent
 		limiter:   limiter,
 		logger:    logger,
 		wgConfig:  wgConfig,
+
+		connectOpts: connectOpts,
+
+		idPrefix:     idPrefix,
+		controllerID: controllerID,
+		components:   components,
+		cursorKey:    fmt.Sprintf("%s|%x", remote, messageDigest(entity.Config.Value)),
 	}
 
 	if wgConfig != nil {
@@ -204,12 +324,35 @@ func runInstance(ctx context.Context, logger *slog.Logger, serverURL string, ent
 		logger.Info("starting federation", "entityID", entity.Id, "mode", mode, "remote", remote)
 	}
 
-	if mode == "push" {
+	switch mode {
+	case "push":
 		return instance.runPush(ctx)
+	case "sync":
+		return instance.runSync(ctx)
 	}
 	return instance.runPull(ctx)
 }
 
+// resolveNodeAddr returns the gRPC address of a node entity, as published
+// by the discovery builtin in device.ip.host.
+func resolveNodeAddr(ctx context.Context, nodeEntityID string) (string, error) {
+	grpcConn, err := builtin.BuiltinClientConn()
+	if err != nil {
+		return "", fmt.Errorf("grpc connect: %w", err)
+	}
+	defer func() { _ = grpcConn.Close() }()
+
+	resp, err := pb.NewWorldServiceClient(grpcConn).GetEntity(ctx, &pb.GetEntityRequest{Id: nodeEntityID})
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", nodeEntityID, err)
+	}
+	addr := resp.Entity.GetDevice().GetIp().GetHost()
+	if addr == "" {
+		return "", fmt.Errorf("node %s has no discovered address", nodeEntityID)
+	}
+	return addr, nil
+}
+
 const defaultFederationKeepaliveMs = 30000 // 30s
 
 // ensureKeepalive makes sure the WatchBehavior has a keepalive interval set.
@@ -241,7 +384,7 @@ func (i *Instance) connectToRemote() (*goclient.Connection, error) {
 		}
 		return &goclient.Connection{ClientConn: conn, Tunnel: tunnel}, nil
 	}
-	return goclient.Connect(i.remote)
+	return goclient.Connect(i.remote, i.connectOpts...)
 }
 
 // discoverNode queries a world service for the local node and returns its
@@ -363,46 +506,181 @@ func filterForFederation(entity *pb.Entity, sourceNodeID string, keepaliveTTL ti
 	return true
 }
 
+// relabel namespaces a forwarded entity for the destination by prepending
+// idPrefix to its id and replacing controller.id with controllerID. Empty
+// values leave the respective field alone.
+//
+// It runs after the camera URL rewrite, since media proxy URLs must keep
+// referring to the id the entity has on its origin. Controller.Node is never
+// touched, so the loop-prevention checks keyed on the origin node keep
+// working. The prefix is applied even to ids that already start with it,
+// so that no id from the source can land in the destination's own
+// namespace. An entity relabeled here that comes back is an echo, which
+// sync drops by its origin node (see isEcho) before relabeling it again.
+func relabel(entity *pb.Entity, idPrefix, controllerID string) {
+	if idPrefix != "" {
+		entity.Id = idPrefix + entity.Id
+	}
+	if controllerID != "" {
+		if entity.Controller == nil {
+			entity.Controller = &pb.Controller{}
+		}
+		entity.Controller.Id = proto.String(controllerID)
+	}
+}
+
+// session holds the connections and discovered node identities shared by the
+// push and pull halves of an instance. A sync instance opens a single session
+// and runs both halves on it, so node discovery and the clock offset estimate
+// are only done once per connection.
+type session struct {
+	local  pb.WorldServiceClient
+	remote pb.WorldServiceClient
+
+	localNodeID      string
+	localNodeEntity  *pb.Entity
+	remoteNodeID     string
+	remoteNodeEntity *pb.Entity
+
+	// clockOffset is remote_clock - local_clock.
+	clockOffset time.Duration
+}
+
+// openSession connects to the local and remote world services and discovers
+// the node identities needed by the requested directions. The returned close
+// function releases both connections.
+func (i *Instance) openSession(ctx context.Context, needLocal, needRemote bool) (*session, func(), error) {
+	// The local engine is reached in-process, so its port may require
+	// TLS and client certificates.
+	localConn, err := builtin.ServerConn(i.serverURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	remoteConn, err := i.connectToRemote()
+	if err != nil {
+		_ = localConn.Close()
+		return nil, nil, err
+	}
+
+	closeFn := func() {
+		_ = remoteConn.Close()
+		_ = localConn.Close()
+	}
+
+	s := &session{
+		local:  pb.NewWorldServiceClient(localConn),
+		remote: pb.NewWorldServiceClient(remoteConn),
+	}
+
+	// Discover local node_id — the push direction bumps fresh only for
+	// entities that originated here.
+	if needLocal {
+		s.localNodeID, s.localNodeEntity, err = discoverNode(ctx, s.local)
+		if err != nil {
+			closeFn()
+			return nil, nil, fmt.Errorf("discover local node ID: %w", err)
+		}
+		i.logger.Info(i.mode+": discovered local node", "nodeID", s.localNodeID)
+	}
+
+	// Discover remote node_id — the pull direction bumps fresh only for
+	// entities that originated on the remote.
+	if needRemote {
+		s.remoteNodeID, s.remoteNodeEntity, err = discoverNode(ctx, s.remote)
+		if err != nil {
+			closeFn()
+			return nil, nil, fmt.Errorf("discover remote node ID: %w", err)
+		}
+		i.logger.Info(i.mode+": discovered remote node", "nodeID", s.remoteNodeID)
+	}
+
+	s.clockOffset = estimateClockOffset(ctx, s.remote)
+	if s.clockOffset != 0 {
+		i.logger.Info(i.mode+": clock offset estimated", "offset", s.clockOffset)
+	}
+
+	return s, closeFn, nil
+}
+
 // runPull connects to a remote node and pulls their entities to local.
 func (i *Instance) runPull(ctx context.Context) error {
 	i.ensureKeepalive()
 
-	localConn, err := goclient.Connect(i.serverURL)
+	s, closeSession, err := i.openSession(ctx, false, true)
 	if err != nil {
 		return err
 	}
-	defer func() { _ = localConn.Close() }()
+	defer closeSession()
 
-	remoteConn, err := i.connectToRemote()
+	// Push the remote node entity to local so receivers can resolve the sender.
+	// No clock offset: the node entity lifetime is stamped with local now.
+	federateNodeEntity(ctx, s.local, s.remoteNodeEntity, i.keepaliveTTL(), 0)
+
+	return i.pullLoop(ctx, s, "", 1)
+}
+
+// runPush watches local entities and pushes them to a remote node.
+func (i *Instance) runPush(ctx context.Context) error {
+	i.ensureKeepalive()
+
+	s, closeSession, err := i.openSession(ctx, true, false)
 	if err != nil {
 		return err
 	}
-	defer func() { _ = remoteConn.Close() }()
+	defer closeSession()
+
+	// Push the local node entity to remote so receivers can resolve the sender.
+	federateNodeEntity(ctx, s.remote, s.localNodeEntity, i.keepaliveTTL(), s.clockOffset)
 
-	localClient := pb.NewWorldServiceClient(localConn)
-	remoteClient := pb.NewWorldServiceClient(remoteConn)
+	return i.pushLoop(ctx, s, "", 1)
+}
 
-	// Discover remote node_id — we only pull entities that originated there
-	// (no multi-hop: skip anything the remote itself received via federation).
-	remoteNodeID, remoteNodeEntity, err := discoverNode(ctx, remoteClient)
+// runSync replicates in both directions over a single session. The push and
+// pull halves run concurrently; when either one fails the other is cancelled
+// and runSync returns the first error once both have exited.
+//
+// On top of the fresh-bumping rule in filterForFederation, each half drops
+// entities that originated on the peer it is sending to. Without that, an
+// entity pushed from A to B would be pulled straight back into A, which
+// updates it locally, which makes the push half send it again with a bumped
+// fresh — a ping-pong that never settles.
+func (i *Instance) runSync(ctx context.Context) error {
+	i.ensureKeepalive()
+
+	s, closeSession, err := i.openSession(ctx, true, true)
 	if err != nil {
-		return fmt.Errorf("discover remote node ID: %w", err)
+		return err
 	}
-	i.logger.Info("pull: discovered remote node", "nodeID", remoteNodeID)
+	defer closeSession()
 
-	clockOffset := estimateClockOffset(ctx, remoteClient)
-	if clockOffset != 0 {
-		i.logger.Info("pull: clock offset estimated", "offset", clockOffset)
-	}
+	federateNodeEntity(ctx, s.remote, s.localNodeEntity, i.keepaliveTTL(), s.clockOffset)
+	federateNodeEntity(ctx, s.local, s.remoteNodeEntity, i.keepaliveTTL(), 0)
 
-	// Push the remote node entity to local so receivers can resolve the sender.
-	// No clock offset: the node entity lifetime is stamped with local now.
-	federateNodeEntity(ctx, localClient, remoteNodeEntity, i.keepaliveTTL(), 0)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errc := make(chan error, 2)
+	go func() { errc <- i.pushLoop(ctx, s, s.remoteNodeID, 1) }()
+	go func() { errc <- i.pullLoop(ctx, s, s.localNodeID, 3) }()
 
-	stream, err := goclient.WatchEntitiesWithRetry(ctx, remoteClient, &pb.ListEntitiesRequest{
+	first := <-errc
+	cancel()
+	<-errc
+	return first
+}
+
+// pullLoop watches the remote and pushes accepted entities to local.
+// Entities originating on echoNodeID are skipped (see runSync). metricID is
+// the id of the first of the two counters reported on the instance entity.
+func (i *Instance) pullLoop(ctx context.Context, s *session, echoNodeID string, metricID uint32) error {
+	// reconnected is set when a stream (re)connects, to look for entities
+	// removed in the meantime before handling its first event.
+	reconnected := true
+	stream, err := goclient.WatchEntitiesWithRetry(ctx, s.remote, &pb.ListEntitiesRequest{
 		Filter:    i.filter,
 		Behaviour: i.limiter,
-	})
+	}, i.logStreamState("pull", func() { reconnected = true }))
 	if err != nil {
 		return err
 	}
@@ -411,6 +689,25 @@ func (i *Instance) runPull(ctx context.Context) error {
 
 	keepaliveTTL := i.keepaliveTTL()
 
+	cursorPath := cursorFile(i.entityID, "pull")
+	cursor, err := loadCursor(cursorPath, i.cursorKey)
+	if err != nil {
+		i.logger.Warn("failed to load pull cursor, starting over", "entityID", i.entityID, "error", err)
+	}
+	if last := cursor.lastForward(); !last.IsZero() {
+		i.logger.Info("pull resuming", "entityID", i.entityID, "lastForward", last)
+	}
+	saveCursor := func() {
+		if err := cursor.save(cursorPath, time.Now(), keepaliveTTL); err != nil {
+			i.logger.Warn("failed to save pull cursor", "entityID", i.entityID, "error", err)
+		}
+	}
+	defer saveCursor()
+	lastSave := time.Now()
+	// Unchanged entities forwarded less than half a keepalive interval ago
+	// are not sent again; the next keepalive refreshes them as usual.
+	resumeWindow := keepaliveTTL / 4
+
 	var entitiesReceived, entitiesPushed uint64
 
 	for {
@@ -425,10 +722,32 @@ func (i *Instance) runPull(ctx context.Context) error {
 
 		entitiesReceived++
 
+		if reconnected {
+			reconnected = false
+			if err := i.expireVanished(ctx, s.remote, s.local, cursor); err != nil {
+				i.logger.Warn("failed to look for vanished entities", "entityID", i.entityID, "error", err)
+			}
+		}
+		if time.Since(lastSave) >= cursorSaveInterval {
+			saveCursor()
+			lastSave = time.Now()
+		}
+
+		if isEcho(event.Entity, echoNodeID) {
+			continue
+		}
+
+		entityID := event.Entity.GetId()
+		digest := entityDigest(event.Entity)
+		expired := event.T == pb.EntityChange_EntityChangeExpired
+		if !expired && !cursor.shouldForward(entityID, digest, time.Now(), resumeWindow) {
+			continue
+		}
+
 		// Translate timestamps from remote clock domain to local.
-		shiftEntityTimestamps(event.Entity, -clockOffset)
+		shiftEntityTimestamps(event.Entity, -s.clockOffset)
 
-		if !filterForFederation(event.Entity, remoteNodeID, keepaliveTTL) {
+		if !filterForFederation(event.Entity, s.remoteNodeID, keepaliveTTL) {
 			continue
 		}
 
@@ -437,7 +756,10 @@ func (i *Instance) runPull(ctx context.Context) error {
 			rewriteCameraURLs(event.Entity, "http://"+i.remote)
 		}
 
-		_, err = localClient.Push(ctx, &pb.EntityChangeRequest{
+		relabel(event.Entity, i.idPrefix, i.controllerID)
+		projection.Keep(event.Entity, i.components)
+
+		_, err = s.local.Push(ctx, &pb.EntityChangeRequest{
 			Changes: []*pb.Entity{event.Entity},
 		})
 		if err != nil {
@@ -445,67 +767,55 @@ func (i *Instance) runPull(ctx context.Context) error {
 			continue
 		}
 
+		if expired {
+			cursor.forget(entityID)
+		} else {
+			cursor.record(entityID, digest, time.Now())
+		}
+
 		entitiesPushed++
-		_, _ = localClient.Push(ctx, &pb.EntityChangeRequest{
-			Changes: []*pb.Entity{{
-				Id: i.entityID,
-				Metric: &pb.MetricComponent{Metrics: []*pb.Metric{
-					{Kind: pb.MetricKind_MetricKindCount.Enum(), Unit: pb.MetricUnit_MetricUnitCount, Label: proto.String("entities received"), Id: proto.Uint32(1), Val: &pb.Metric_Uint64{Uint64: entitiesReceived}},
-					{Kind: pb.MetricKind_MetricKindCount.Enum(), Unit: pb.MetricUnit_MetricUnitCount, Label: proto.String("entities pushed"), Id: proto.Uint32(2), Val: &pb.Metric_Uint64{Uint64: entitiesPushed}},
-				}},
-			}},
-		})
+		i.reportCounters(ctx, s.local, metricID, "pull", entitiesReceived, entitiesPushed)
 
 		i.logger.Debug("pulled", "entityID", i.entityID, "targetEntity", event.Entity.Id)
 	}
 }
 
-// runPush watches local entities and pushes them to a remote node.
-func (i *Instance) runPush(ctx context.Context) error {
-	i.ensureKeepalive()
-
-	localConn, err := goclient.Connect(i.serverURL)
+// pushLoop watches local entities and pushes accepted ones to the remote.
+// Entities originating on echoNodeID are skipped (see runSync). metricID is
+// the id of the first of the two counters reported on the instance entity.
+func (i *Instance) pushLoop(ctx context.Context, s *session, echoNodeID string, metricID uint32) error {
+	// reconnected is set when a stream (re)connects, see pullLoop.
+	reconnected := true
+	stream, err := goclient.WatchEntitiesWithRetry(ctx, s.local, &pb.ListEntitiesRequest{
+		Filter:    i.filter,
+		Behaviour: i.limiter,
+	}, i.logStreamState("push", func() { reconnected = true }))
 	if err != nil {
 		return err
 	}
-	defer func() { _ = localConn.Close() }()
 
-	remoteConn, err := i.connectToRemote()
-	if err != nil {
-		return err
-	}
-	defer func() { _ = remoteConn.Close() }()
+	i.logger.Info("push started", "entityID", i.entityID)
 
-	localClient := pb.NewWorldServiceClient(localConn)
-	remoteClient := pb.NewWorldServiceClient(remoteConn)
+	keepaliveTTL := i.keepaliveTTL()
 
-	// Discover local node_id — we only push entities that originated here
-	// (no multi-hop: skip anything we received via federation from other nodes).
-	localNodeID, localNodeEntity, err := discoverNode(ctx, localClient)
+	cursorPath := cursorFile(i.entityID, "push")
+	cursor, err := loadCursor(cursorPath, i.cursorKey)
 	if err != nil {
-		return fmt.Errorf("discover local node ID: %w", err)
+		i.logger.Warn("failed to load push cursor, starting over", "entityID", i.entityID, "error", err)
 	}
-	i.logger.Info("push: discovered local node", "nodeID", localNodeID)
-
-	clockOffset := estimateClockOffset(ctx, remoteClient)
-	if clockOffset != 0 {
-		i.logger.Info("push: clock offset estimated", "offset", clockOffset)
+	if last := cursor.lastForward(); !last.IsZero() {
+		i.logger.Info("push resuming", "entityID", i.entityID, "lastForward", last)
 	}
-
-	// Push the local node entity to remote so receivers can resolve the sender.
-	federateNodeEntity(ctx, remoteClient, localNodeEntity, i.keepaliveTTL(), clockOffset)
-
-	stream, err := goclient.WatchEntitiesWithRetry(ctx, localClient, &pb.ListEntitiesRequest{
-		Filter:    i.filter,
-		Behaviour: i.limiter,
-	})
-	if err != nil {
-		return err
+	saveCursor := func() {
+		if err := cursor.save(cursorPath, time.Now(), keepaliveTTL); err != nil {
+			i.logger.Warn("failed to save push cursor", "entityID", i.entityID, "error", err)
+		}
 	}
-
-	i.logger.Info("push started", "entityID", i.entityID)
-
-	keepaliveTTL := i.keepaliveTTL()
+	defer saveCursor()
+	lastSave := time.Now()
+	// Unchanged entities forwarded less than half a keepalive interval ago
+	// are not sent again; the next keepalive refreshes them as usual.
+	resumeWindow := keepaliveTTL / 4
 
 	var entitiesReceived, entitiesPushed uint64
 
@@ -521,7 +831,29 @@ func (i *Instance) runPush(ctx context.Context) error {
 
 		entitiesReceived++
 
-		if !filterForFederation(event.Entity, localNodeID, keepaliveTTL) {
+		if reconnected {
+			reconnected = false
+			if err := i.expireVanished(ctx, s.local, s.remote, cursor); err != nil {
+				i.logger.Warn("failed to look for vanished entities", "entityID", i.entityID, "error", err)
+			}
+		}
+		if time.Since(lastSave) >= cursorSaveInterval {
+			saveCursor()
+			lastSave = time.Now()
+		}
+
+		if isEcho(event.Entity, echoNodeID) {
+			continue
+		}
+
+		entityID := event.Entity.GetId()
+		digest := entityDigest(event.Entity)
+		expired := event.T == pb.EntityChange_EntityChangeExpired
+		if !expired && !cursor.shouldForward(entityID, digest, time.Now(), resumeWindow) {
+			continue
+		}
+
+		if !filterForFederation(event.Entity, s.localNodeID, keepaliveTTL) {
 			continue
 		}
 
@@ -532,9 +864,12 @@ func (i *Instance) runPush(ctx context.Context) error {
 		}
 
 		// Translate timestamps from local clock domain to remote.
-		shiftEntityTimestamps(event.Entity, clockOffset)
+		shiftEntityTimestamps(event.Entity, s.clockOffset)
 
-		_, err = remoteClient.Push(ctx, &pb.EntityChangeRequest{
+		relabel(event.Entity, i.idPrefix, i.controllerID)
+		projection.Keep(event.Entity, i.components)
+
+		_, err = s.remote.Push(ctx, &pb.EntityChangeRequest{
 			Changes: []*pb.Entity{event.Entity},
 		})
 		if err != nil {
@@ -542,21 +877,64 @@ func (i *Instance) runPush(ctx context.Context) error {
 			continue
 		}
 
+		if expired {
+			cursor.forget(entityID)
+		} else {
+			cursor.record(entityID, digest, time.Now())
+		}
+
 		entitiesPushed++
-		_, _ = localClient.Push(ctx, &pb.EntityChangeRequest{
-			Changes: []*pb.Entity{{
-				Id: i.entityID,
-				Metric: &pb.MetricComponent{Metrics: []*pb.Metric{
-					{Kind: pb.MetricKind_MetricKindCount.Enum(), Unit: pb.MetricUnit_MetricUnitCount, Label: proto.String("entities received"), Id: proto.Uint32(1), Val: &pb.Metric_Uint64{Uint64: entitiesReceived}},
-					{Kind: pb.MetricKind_MetricKindCount.Enum(), Unit: pb.MetricUnit_MetricUnitCount, Label: proto.String("entities pushed"), Id: proto.Uint32(2), Val: &pb.Metric_Uint64{Uint64: entitiesPushed}},
-				}},
-			}},
-		})
+		i.reportCounters(ctx, s.local, metricID, "push", entitiesReceived, entitiesPushed)
 
 		i.logger.Debug("pushed", "entityID", i.entityID, "targetEntity", event.Entity.Id)
 	}
 }
 
+// logStreamState logs watch stream transitions so that a reconnect shows up
+// as such rather than as a failure, and calls connected whenever the stream
+// is established.
+func (i *Instance) logStreamState(direction string, connected func()) goclient.WatchOption {
+	return goclient.OnStateChange(func(sc goclient.StreamStateChange) {
+		switch sc.State {
+		case goclient.StreamBackoff:
+			i.logger.Warn(direction+" stream interrupted, reconnecting", "entityID", i.entityID, "backoff", sc.Backoff, "attempt", sc.Attempt, "error", sc.Err)
+		case goclient.StreamConnected:
+			connected()
+			if sc.Attempt > 0 {
+				i.logger.Info(direction+" stream reconnected", "entityID", i.entityID, "attempts", sc.Attempt)
+			}
+		}
+	})
+}
+
+// isEcho reports whether entity originated on peerNodeID. An empty
+// peerNodeID disables the check.
+func isEcho(entity *pb.Entity, peerNodeID string) bool {
+	if peerNodeID == "" || entity == nil || entity.Controller == nil || entity.Controller.Node == nil {
+		return false
+	}
+	return *entity.Controller.Node == peerNodeID
+}
+
+// reportCounters publishes the received/pushed counters on the instance
+// entity as metrics metricID and metricID+1. Sync instances report both
+// directions on the same entity, so their labels carry the direction.
+func (i *Instance) reportCounters(ctx context.Context, local pb.WorldServiceClient, metricID uint32, direction string, received, pushed uint64) {
+	suffix := ""
+	if i.mode == "sync" {
+		suffix = " (" + direction + ")"
+	}
+	_, _ = local.Push(ctx, &pb.EntityChangeRequest{
+		Changes: []*pb.Entity{{
+			Id: i.entityID,
+			Metric: &pb.MetricComponent{Metrics: []*pb.Metric{
+				{Kind: pb.MetricKind_MetricKindCount.Enum(), Unit: pb.MetricUnit_MetricUnitCount, Label: proto.String("entities received" + suffix), Id: proto.Uint32(metricID), Val: &pb.Metric_Uint64{Uint64: received}},
+				{Kind: pb.MetricKind_MetricKindCount.Enum(), Unit: pb.MetricUnit_MetricUnitCount, Label: proto.String("entities pushed" + suffix), Id: proto.Uint32(metricID + 1), Val: &pb.Metric_Uint64{Uint64: pushed}},
+			}},
+		}},
+	})
+}
+
 // parseWireGuardConfig parses inline WireGuard config from structpb.Value
 func parseWireGuardConfig(v *structpb.Value) *goclient.WireGuardConfig {
 	if v == nil {
@@ -586,6 +964,21 @@ func parseWireGuardConfig(v *structpb.Value) *goclient.WireGuardConfig {
 		}
 	}
 
+	if list := s.Fields["allowed_ips"].GetListValue(); list != nil {
+		for _, v := range list.Values {
+			if prefix, err := netip.ParsePrefix(v.GetStringValue()); err == nil {
+				cfg.AllowedIPs = append(cfg.AllowedIPs, prefix)
+			}
+		}
+	}
+	if list := s.Fields["dns"].GetListValue(); list != nil {
+		for _, v := range list.Values {
+			if addr, err := netip.ParseAddr(v.GetStringValue()); err == nil {
+				cfg.DNS = append(cfg.DNS, addr)
+			}
+		}
+	}
+
 	// Validate - return nil if missing required fields
 	if cfg.PrivateKey == "" || cfg.PeerPublicKey == "" || cfg.Endpoint == "" || !cfg.Address.IsValid() {
 		return nil
@@ -617,11 +1010,7 @@ func parseEntityFilter(v *structpb.Value) *pb.EntityFilter {
 	}
 
 	if components, ok := s.Fields["component"]; ok {
-		if list := components.GetListValue(); list != nil {
-			for _, c := range list.Values {
-				filter.Component = append(filter.Component, uint32(c.GetNumberValue()))
-			}
-		}
+		filter.Component = parseComponentList(components)
 	}
 
 	if configFilter, ok := s.Fields["config"]; ok {
@@ -633,6 +1022,19 @@ func parseEntityFilter(v *structpb.Value) *pb.EntityFilter {
 	return filter
 }
 
+// parseComponentList parses a list of entity component field numbers.
+func parseComponentList(v *structpb.Value) []uint32 {
+	list := v.GetListValue()
+	if list == nil {
+		return nil
+	}
+	var components []uint32
+	for _, c := range list.Values {
+		components = append(components, uint32(c.GetNumberValue()))
+	}
+	return components
+}
+
 func parseWatchLimiter(v *structpb.Value) *pb.WatchBehavior {
 	if v == nil {
 		return nil
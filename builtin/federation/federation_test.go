@@ -3,6 +3,7 @@ package federation
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
 	"testing"
@@ -15,6 +16,7 @@ import (
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/projectqai/hydris/goclient"
@@ -171,6 +173,18 @@ func makeEntity(id, node string, geo float64, fresh time.Time) *pb.Entity {
 	return e
 }
 
+// waitFor polls cond until it returns true or the timeout elapses.
+func waitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
 func getFresh(t *testing.T, n *testNode, id string) time.Time {
 	t.Helper()
 	e := n.get(t, id)
@@ -581,3 +595,251 @@ func TestKeepalive_OriginRefreshes(t *testing.T) {
 		t.Errorf("keepalive should bump fresh: first=%v, second=%v", fresh1, fresh2)
 	}
 }
+
+// ---------------------------------------------------------------------------
+// Sync mode tests
+// ---------------------------------------------------------------------------
+
+// startSync runs a sync instance from local to remote until the test ends.
+func startSync(t *testing.T, local, remote *testNode) (cancel func(), done <-chan error) {
+	t.Helper()
+	inst := &Instance{
+		entityID:  "federation.sync.test",
+		serverURL: local.addr,
+		remote:    remote.addr,
+		mode:      "sync",
+		logger:    slog.Default(),
+	}
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- inst.runSync(ctx) }()
+	t.Cleanup(cancelCtx)
+	return cancelCtx, errc
+}
+
+func TestSync_Converges(t *testing.T) {
+	a := startTestNode(t)
+	b := startTestNode(t)
+
+	a.push(t, makeEntity("ea", a.nodeID, 10, time.Now()))
+	b.push(t, makeEntity("eb", b.nodeID, 20, time.Now()))
+
+	cancel, done := startSync(t, a, b)
+
+	waitFor(t, 5*time.Second, "initial convergence", func() bool {
+		return a.has(t, "eb") && b.has(t, "ea")
+	})
+
+	// Entities created while the sync is running replicate as well.
+	a.push(t, makeEntity("ea2", a.nodeID, 11, time.Now()))
+	b.push(t, makeEntity("eb2", b.nodeID, 21, time.Now()))
+
+	waitFor(t, 5*time.Second, "live convergence", func() bool {
+		return a.has(t, "eb2") && b.has(t, "ea2")
+	})
+
+	for _, id := range []string{"ea", "ea2", "eb", "eb2"} {
+		if !a.has(t, id) || !b.has(t, id) {
+			t.Errorf("both nodes should have %s", id)
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("runSync did not return after cancel")
+	}
+}
+
+func TestSync_NoPingPong(t *testing.T) {
+	a := startTestNode(t)
+	b := startTestNode(t)
+
+	a.push(t, makeEntity("ea", a.nodeID, 10, time.Now()))
+
+	startSync(t, a, b)
+
+	waitFor(t, 5*time.Second, "ea on B", func() bool { return b.has(t, "ea") })
+
+	// Let any echo settle, then verify fresh stays put. A ping-pong between
+	// the two halves would keep bumping it on every round trip.
+	time.Sleep(200 * time.Millisecond)
+	freshOnB := getFresh(t, b, "ea")
+	freshOnA := getFresh(t, a, "ea")
+
+	time.Sleep(500 * time.Millisecond)
+
+	if got := getFresh(t, b, "ea"); !got.Equal(freshOnB) {
+		t.Errorf("ea on B kept changing: was %v, now %v", freshOnB, got)
+	}
+	if got := getFresh(t, a, "ea"); !got.Equal(freshOnA) {
+		t.Errorf("ea on A kept changing: was %v, now %v", freshOnA, got)
+	}
+}
+
+func TestIsEcho(t *testing.T) {
+	e := makeEntity("e1", "node-a", 52, time.Now())
+	if !isEcho(e, "node-a") {
+		t.Error("entity from peer should be an echo")
+	}
+	if isEcho(e, "node-b") {
+		t.Error("entity from another node should not be an echo")
+	}
+	if isEcho(e, "") {
+		t.Error("empty peer disables the check")
+	}
+	if isEcho(&pb.Entity{Id: "x"}, "node-a") {
+		t.Error("entity without controller node should not be an echo")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Relabel tests
+// ---------------------------------------------------------------------------
+
+func TestRelabel(t *testing.T) {
+	e := makeEntity("track-1", "node-a", 52, time.Now())
+	e.Controller.Id = proto.String("adsb")
+
+	relabel(e, "org-a.", "org-a")
+	if e.Id != "org-a.track-1" {
+		t.Errorf("id = %q, want org-a.track-1", e.Id)
+	}
+	if e.Controller.GetId() != "org-a" {
+		t.Errorf("controller.id = %q, want org-a", e.Controller.GetId())
+	}
+	if e.Controller.GetNode() != "node-a" {
+		t.Errorf("controller.node must be preserved, got %q", e.Controller.GetNode())
+	}
+
+	// An id that already carries the prefix is prefixed all the same, so
+	// it can't collide with an id of the destination.
+	relabel(e, "org-a.", "")
+	if e.Id != "org-a.org-a.track-1" {
+		t.Errorf("prefixed id = %q, want org-a.org-a.track-1", e.Id)
+	}
+
+	// Empty config leaves the entity alone.
+	e2 := makeEntity("track-2", "node-a", 52, time.Now())
+	relabel(e2, "", "")
+	if e2.Id != "track-2" || e2.Controller.Id != nil {
+		t.Errorf("empty relabel changed the entity: %v", e2)
+	}
+}
+
+func TestRelabel_PushRewritesIDAndController(t *testing.T) {
+	a := startTestNode(t)
+	b := startTestNode(t)
+
+	e := makeEntity("track-1", a.nodeID, 10, time.Now())
+	e.Controller.Id = proto.String("adsb")
+	a.push(t, e)
+
+	inst := &Instance{
+		entityID:     "federation.relabel.test",
+		serverURL:    a.addr,
+		remote:       b.addr,
+		mode:         "push",
+		logger:       slog.Default(),
+		idPrefix:     "org-a.",
+		controllerID: "org-a",
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = inst.runPush(ctx) }()
+
+	waitFor(t, 5*time.Second, "org-a.track-1 on B", func() bool { return b.has(t, "org-a.track-1") })
+
+	got := b.get(t, "org-a.track-1")
+	if got.Controller.GetId() != "org-a" {
+		t.Errorf("controller.id on B = %q, want org-a", got.Controller.GetId())
+	}
+	if got.Controller.GetNode() != a.nodeID {
+		t.Errorf("controller.node on B = %q, want %q", got.Controller.GetNode(), a.nodeID)
+	}
+	if b.has(t, "track-1") {
+		t.Error("unprefixed id should not exist on B")
+	}
+}
+
+// TestRelabel_SyncNoEcho checks that the origin node check still stops the
+// relabeled copy from being pulled back into its origin.
+func TestRelabel_SyncNoEcho(t *testing.T) {
+	a := startTestNode(t)
+	b := startTestNode(t)
+
+	a.push(t, makeEntity("ea", a.nodeID, 10, time.Now()))
+
+	inst := &Instance{
+		entityID:  "federation.relabel.sync.test",
+		serverURL: a.addr,
+		remote:    b.addr,
+		mode:      "sync",
+		logger:    slog.Default(),
+		idPrefix:  "org-a.",
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = inst.runSync(ctx) }()
+
+	waitFor(t, 5*time.Second, "org-a.ea on B", func() bool { return b.has(t, "org-a.ea") })
+
+	time.Sleep(300 * time.Millisecond)
+	if a.has(t, "org-a.ea") {
+		t.Error("relabeled copy was pulled back into its origin")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Component allowlist tests
+// ---------------------------------------------------------------------------
+
+func TestComponents_PushForwardsOnlyAllowlisted(t *testing.T) {
+	a := startTestNode(t)
+	b := startTestNode(t)
+
+	e := makeEntity("track-1", a.nodeID, 10, time.Now())
+	e.Label = proto.String("secret label")
+	e.Power = &pb.PowerComponent{}
+	a.push(t, e)
+
+	inst := &Instance{
+		entityID:   "federation.components.test",
+		serverURL:  a.addr,
+		remote:     b.addr,
+		mode:       "push",
+		logger:     slog.Default(),
+		components: []uint32{uint32(pb.EntityComponent_EntityComponentGeo)},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = inst.runPush(ctx) }()
+
+	waitFor(t, 5*time.Second, "track-1 on B", func() bool { return b.has(t, "track-1") })
+
+	got := b.get(t, "track-1")
+	if got.Geo == nil || got.Geo.Latitude != 10 {
+		t.Errorf("geo should be retained, got %v", got.Geo)
+	}
+	if got.Power != nil {
+		t.Error("power should be stripped")
+	}
+	if got.Label != nil {
+		t.Error("label should be stripped")
+	}
+	if got.Controller.GetNode() != a.nodeID || got.Lifetime == nil {
+		t.Error("controller and lifetime should be retained")
+	}
+}
+
+func TestParseComponentList(t *testing.T) {
+	v, _ := structpb.NewValue([]any{28.0, 33.0})
+	got := parseComponentList(v)
+	if len(got) != 2 || got[0] != 28 || got[1] != 33 {
+		t.Errorf("parseComponentList = %v, want [28 33]", got)
+	}
+	if parseComponentList(structpb.NewStringValue("nope")) != nil {
+		t.Error("non-list value should yield nil")
+	}
+}
@@ -0,0 +1,178 @@
+package gps
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/projectqai/hydris/builtin"
+	"github.com/projectqai/hydris/builtin/controller"
+	"github.com/projectqai/hydris/hal"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	controllerName = "gps"
+	serialClass    = "gps.serial.v0"
+	defaultBaud    = 9600
+)
+
+func init() {
+	builtin.Register(controllerName, Run)
+}
+
+func Run(ctx context.Context, logger *slog.Logger, _ string) error {
+	serialSchema, _ := structpb.NewStruct(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"device": map[string]any{
+				"type":           "string",
+				"title":          "Device",
+				"description":    "Entity ID of the serial device the GPS is attached to",
+				"ui:placeholder": "e.g. hal.serial.ttyUSB0",
+				"ui:order":       0,
+			},
+			"path": map[string]any{
+				"type":           "string",
+				"title":          "Path",
+				"description":    "Serial port path, used instead of the device's",
+				"ui:placeholder": "e.g. /dev/ttyACM0",
+				"ui:order":       1,
+			},
+			"baud": map[string]any{
+				"type":     "number",
+				"title":    "Baud Rate",
+				"default":  defaultBaud,
+				"minimum":  1200,
+				"ui:order": 2,
+			},
+		},
+	})
+
+	serviceEntityID := controllerName + ".service"
+	if err := controller.Push(ctx, &pb.Entity{
+		Id:    serviceEntityID,
+		Label: proto.String("GPS"),
+		Controller: &pb.Controller{
+			Id: proto.String(controllerName),
+		},
+		Device: &pb.DeviceComponent{
+			Category: proto.String("Sensors"),
+			State:    pb.DeviceState_DeviceStateActive,
+		},
+		Configurable: &pb.ConfigurableComponent{
+			SupportedDeviceClasses: []*pb.DeviceClassOption{
+				{Class: serialClass, Label: "Serial GPS"},
+			},
+		},
+		Interactivity: &pb.InteractivityComponent{
+			Icon: proto.String("satellite"),
+		},
+	}); err != nil {
+		return fmt.Errorf("push service entity: %w", err)
+	}
+
+	classes := []controller.DeviceClass{
+		{Class: serialClass, Label: "Serial GPS", Schema: serialSchema},
+	}
+
+	return controller.WatchChildren(ctx, serviceEntityID, controllerName, classes, func(ctx context.Context, entityID string) error {
+		return controller.Run(ctx, entityID, func(ctx context.Context, entity *pb.Entity, ready func()) error {
+			ready()
+			return runSerial(ctx, logger, entity)
+		})
+	})
+}
+
+// runSerial reads NMEA from the configured serial port and keeps the local
+// node entity's position up to date.
+func runSerial(ctx context.Context, logger *slog.Logger, entity *pb.Entity) error {
+	var fields map[string]*structpb.Value
+	if entity.Config != nil && entity.Config.Value != nil {
+		fields = entity.Config.Value.Fields
+	}
+	serialPath := fields["path"].GetStringValue()
+	baud := defaultBaud
+	if v := fields["baud"].GetNumberValue(); v > 0 {
+		baud = int(v)
+	}
+
+	grpcConn, err := builtin.BuiltinClientConn()
+	if err != nil {
+		return fmt.Errorf("grpc connect: %w", err)
+	}
+	defer func() { _ = grpcConn.Close() }()
+
+	client := pb.NewWorldServiceClient(grpcConn)
+
+	if serialPath == "" {
+		// Like meshtastic, resolve the path from the serial device entity:
+		// the configured one, else the one this entity is composed of.
+		deviceID := fields["device"].GetStringValue()
+		if deviceID == "" && entity.Device != nil && len(entity.Device.Composition) > 0 {
+			deviceID = entity.Device.Composition[0]
+		}
+		if deviceID == "" {
+			return fmt.Errorf("no serial device or path configured for entity %s", entity.Id)
+		}
+
+		parentResp, err := client.GetEntity(ctx, &pb.GetEntityRequest{Id: deviceID})
+		if err != nil {
+			return fmt.Errorf("get parent device entity %s: %w", deviceID, err)
+		}
+		parentEntity := parentResp.Entity
+		if parentEntity.Device == nil || parentEntity.Device.Serial == nil {
+			return fmt.Errorf("parent device %s has no serial descriptor", deviceID)
+		}
+		serialPath = parentEntity.Device.Serial.GetPath()
+		if serialPath == "" {
+			return fmt.Errorf("parent device %s has empty serial path", deviceID)
+		}
+	}
+
+	nodeResp, err := client.GetLocalNode(ctx, &pb.GetLocalNodeRequest{})
+	if err != nil {
+		return fmt.Errorf("get local node: %w", err)
+	}
+	nodeEntityID := nodeResp.Entity.Id
+
+	logger.Info("Opening GPS serial port", "path", serialPath, "baud", baud)
+	port, err := hal.OpenSerial(serialPath, baud)
+	if err != nil {
+		return fmt.Errorf("open serial %s: %w", serialPath, err)
+	}
+	defer func() { _ = port.Close() }()
+
+	go func() {
+		<-ctx.Done()
+		_ = port.Close()
+	}()
+
+	logger.Info("GPS serial port opened, reading NMEA", "path", serialPath, "nodeEntityID", nodeEntityID)
+
+	var fix fixState
+	scanner := bufio.NewScanner(port)
+	for scanner.Scan() {
+		update := fix.update(scanner.Text(), nodeEntityID, time.Now())
+		if update == nil {
+			continue
+		}
+		if _, err := client.Push(ctx, &pb.EntityChangeRequest{Changes: []*pb.Entity{update}}); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			logger.Error("failed to push GPS position", "error", err)
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read NMEA: %w", err)
+	}
+	return fmt.Errorf("serial port %s closed", serialPath)
+}
@@ -0,0 +1,91 @@
+package gps
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/adrianmo/go-nmea"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	knotsToMs = 0.514444
+
+	// uereM is the user equivalent range error in meters. HDOP times
+	// uereM is the standard deviation of the horizontal position.
+	uereM = 5.0
+)
+
+// fixState is what a GPS reported across sentences. RMC carries no
+// altitude and GGA no velocity, so each update fills in what the other
+// sentence last said rather than clearing it.
+type fixState struct {
+	altitude *float64
+	hdop     float64
+}
+
+// update parses one NMEA sentence and returns the components to push onto
+// entityID, or nil if the sentence isn't an RMC or GGA with a valid fix.
+func (s *fixState) update(line, entityID string, now time.Time) *pb.Entity {
+	sentence, err := nmea.Parse(strings.TrimSpace(line))
+	if err != nil {
+		return nil
+	}
+
+	switch m := sentence.(type) {
+	case nmea.RMC:
+		if m.Validity != "A" {
+			return nil
+		}
+		e := s.entity(entityID, m.Latitude, m.Longitude, now)
+		if m.Speed > 0 && m.Course >= 0 && m.Course < 360 {
+			rad := m.Course * math.Pi / 180
+			speed := m.Speed * knotsToMs
+			e.Kinematics = &pb.KinematicsComponent{
+				VelocityEnu: &pb.KinematicsEnu{
+					East:  proto.Float64(speed * math.Sin(rad)),
+					North: proto.Float64(speed * math.Cos(rad)),
+				},
+			}
+		}
+		return e
+
+	case nmea.GGA:
+		if m.FixQuality == "0" {
+			s.altitude = nil
+			return nil
+		}
+		s.altitude = proto.Float64(m.Altitude)
+		s.hdop = m.HDOP
+		e := s.entity(entityID, m.Latitude, m.Longitude, now)
+		e.Gnss = &pb.GnssComponent{
+			FixType:        pb.GnssFixType_GnssFixType3D.Enum(),
+			SatellitesUsed: proto.Uint32(uint32(m.NumSatellites)),
+		}
+		return e
+	}
+	return nil
+}
+
+func (s *fixState) entity(entityID string, lat, lon float64, now time.Time) *pb.Entity {
+	geo := &pb.GeoSpatialComponent{
+		Latitude:  lat,
+		Longitude: lon,
+		Altitude:  s.altitude,
+	}
+	if s.hdop > 0 {
+		variance := (s.hdop * uereM) * (s.hdop * uereM)
+		geo.Covariance = &pb.CovarianceMatrix{
+			Mxx: proto.Float64(variance),
+			Myy: proto.Float64(variance),
+		}
+	}
+	return &pb.Entity{
+		Id:       entityID,
+		Geo:      geo,
+		Lifetime: &pb.Lifetime{Fresh: timestamppb.New(now)},
+	}
+}
@@ -0,0 +1,96 @@
+package gps
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+const (
+	rmc     = "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A"
+	gga     = "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47\r\n"
+	voidRMC = "$GNRMC,123520,V,,,,,,,230394,,,N*45"
+	noFix   = "$GPGGA,123520,,,,,0,00,99.9,,M,,M,,*76"
+	stillGN = "$GNRMC,123521,A,4807.038,N,01131.000,E,000.0,,230394,,,A*4B"
+)
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+
+func TestUpdate_RMC(t *testing.T) {
+	var s fixState
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := s.update(rmc, "node.n1", now)
+	if e == nil {
+		t.Fatal("no update for a valid RMC")
+	}
+	if e.Id != "node.n1" {
+		t.Errorf("id %q", e.Id)
+	}
+	if !near(e.Geo.Latitude, 48+7.038/60) || !near(e.Geo.Longitude, 11+31.0/60) {
+		t.Errorf("position %v, %v", e.Geo.Latitude, e.Geo.Longitude)
+	}
+	if e.Geo.Altitude != nil {
+		t.Errorf("RMC has no altitude, got %v", *e.Geo.Altitude)
+	}
+	if !e.Lifetime.Fresh.AsTime().Equal(now) {
+		t.Errorf("fresh %v", e.Lifetime.Fresh.AsTime())
+	}
+
+	v := e.GetKinematics().GetVelocityEnu()
+	speed := 22.4 * knotsToMs
+	if v == nil || math.Abs(math.Hypot(v.GetEast(), v.GetNorth())-speed) > 1e-6 {
+		t.Fatalf("velocity %v, want %.2f m/s", v, speed)
+	}
+	if v.GetEast() < 11 || v.GetNorth() < 1 {
+		t.Errorf("velocity (%.2f, %.2f), want mostly east", v.GetEast(), v.GetNorth())
+	}
+}
+
+func TestUpdate_GGA(t *testing.T) {
+	var s fixState
+	e := s.update(gga, "node.n1", time.Now())
+	if e == nil {
+		t.Fatal("no update for a valid GGA")
+	}
+	if e.Geo.Altitude == nil || *e.Geo.Altitude != 545.4 {
+		t.Errorf("altitude %v, want 545.4", e.Geo.Altitude)
+	}
+	if got := e.Gnss.GetSatellitesUsed(); got != 8 {
+		t.Errorf("satellites %d, want 8", got)
+	}
+	want := (0.9 * uereM) * (0.9 * uereM)
+	if got := e.Geo.Covariance.GetMxx(); !near(got, want) {
+		t.Errorf("variance %v, want %v", got, want)
+	}
+
+	// A following RMC keeps the altitude and accuracy GGA reported.
+	e = s.update(stillGN, "node.n1", time.Now())
+	if e == nil {
+		t.Fatal("no update for a GN talker RMC")
+	}
+	if e.Geo.Altitude == nil || *e.Geo.Altitude != 545.4 {
+		t.Errorf("RMC after GGA dropped the altitude: %v", e.Geo.Altitude)
+	}
+	if !near(e.Geo.Covariance.GetMxx(), want) {
+		t.Errorf("RMC after GGA variance %v, want %v", e.Geo.Covariance.GetMxx(), want)
+	}
+	if e.Kinematics != nil {
+		t.Error("stationary RMC should carry no velocity")
+	}
+}
+
+func TestUpdate_SkipsWithoutFix(t *testing.T) {
+	var s fixState
+	for _, line := range []string{
+		voidRMC,
+		noFix,
+		"$GPGSV,3,1,11,03,03,111,00,04,15,270,00,06,01,010,00,13,06,292,00*74",
+		"$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*00", // bad checksum
+		"garbage",
+		"",
+	} {
+		if e := s.update(line, "node.n1", time.Now()); e != nil {
+			t.Errorf("%q gave an update: %v", line, e)
+		}
+	}
+}
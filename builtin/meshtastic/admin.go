@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"math/rand/v2"
+	"reflect"
 	"time"
 
 	"github.com/projectqai/hydris/builtin/meshtastic/meshpb"
@@ -188,7 +189,8 @@ func parseRadioSettings(fields map[string]*structpb.Value, currentState map[stri
 
 // applyRadioConfig sends admin messages to configure the radio based on the
 // desired settings. It reads the current config from the handshake, merges
-// user values, and sends only changed sections.
+// user values, and sends only changed sections. Sections the radio accepted
+// are recorded in the handshake, so readRadioState reflects them.
 //
 // LoRa config changes (region, preset, tx power, tx enabled) require a
 // transactional edit (BeginEditSettings / CommitEditSettings) which causes
@@ -201,7 +203,62 @@ func applyRadioConfig(logger *slog.Logger, radio *Radio, handshake *RadioHandsha
 	}
 
 	nodeNum := handshake.NodeNum
+	loraMsgs, softMsgs := radioConfigMessages(logger, handshake, desired)
 
+	if len(loraMsgs) == 0 && len(softMsgs) == 0 {
+		logger.Info("No radio config changes to apply")
+		return nil
+	}
+
+	// Apply soft changes individually — no transaction, no reboot.
+	if len(softMsgs) > 0 {
+		logger.Info("Applying soft radio config (no reboot)", "sections", len(softMsgs))
+		for _, msg := range softMsgs {
+			if err := sendAdminPacket(radio, nodeNum, msg); err != nil {
+				return fmt.Errorf("send admin: %w", err)
+			}
+			recordAdminMsg(handshake, msg)
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	// Apply LoRa changes in a transaction — device will save and reboot.
+	if len(loraMsgs) > 0 {
+		logger.Info("Applying LoRa config (device will reboot)", "sections", len(loraMsgs))
+
+		if err := sendAdminPacket(radio, nodeNum, &meshpb.AdminMsg{
+			PayloadVariant: &meshpb.AdminMsg_BeginEditSettings{BeginEditSettings: true},
+		}); err != nil {
+			return fmt.Errorf("begin edit: %w", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		for _, msg := range loraMsgs {
+			if err := sendAdminPacket(radio, nodeNum, msg); err != nil {
+				return fmt.Errorf("send admin: %w", err)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		logger.Info("Committing LoRa config")
+		if err := sendAdminPacket(radio, nodeNum, &meshpb.AdminMsg{
+			PayloadVariant: &meshpb.AdminMsg_CommitEditSettings{CommitEditSettings: true},
+		}); err != nil {
+			return fmt.Errorf("commit edit: %w", err)
+		}
+		for _, msg := range loraMsgs {
+			recordAdminMsg(handshake, msg)
+		}
+	}
+
+	logger.Info("Radio config applied successfully")
+	return nil
+}
+
+// radioConfigMessages translates the desired settings into admin messages,
+// split into LoRa changes, which must be sent in an edit transaction, and
+// soft changes. Sections that would not change are left out.
+func radioConfigMessages(logger *slog.Logger, handshake *RadioHandshake, desired *radioSettings) (loraMsgs, softMsgs []*meshpb.AdminMsg) {
 	// Decode current config from handshake blobs.
 	curLora := &meshpb.LoraConfig{}
 	curDevice := &meshpb.DeviceConfig{}
@@ -217,11 +274,6 @@ func applyRadioConfig(logger *slog.Logger, radio *Radio, handshake *RadioHandsha
 		}
 	}
 
-	// Separate LoRa changes (require transaction + device reboot) from
-	// soft changes (can be applied individually without reboot).
-	var loraMsgs []*meshpb.AdminMsg
-	var softMsgs []*meshpb.AdminMsg
-
 	if lora := mergeLora(logger, curLora, desired); lora != nil {
 		loraMsgs = append(loraMsgs, &meshpb.AdminMsg{
 			PayloadVariant: &meshpb.AdminMsg_SetConfig{
@@ -264,50 +316,55 @@ func applyRadioConfig(logger *slog.Logger, radio *Radio, handshake *RadioHandsha
 		})
 	}
 
-	if len(loraMsgs) == 0 && len(softMsgs) == 0 {
-		logger.Info("No radio config changes to apply")
-		return nil
-	}
+	return loraMsgs, softMsgs
+}
 
-	// Apply soft changes individually — no transaction, no reboot.
-	if len(softMsgs) > 0 {
-		logger.Info("Applying soft radio config (no reboot)", "sections", len(softMsgs))
-		for _, msg := range softMsgs {
-			if err := sendAdminPacket(radio, nodeNum, msg); err != nil {
-				return fmt.Errorf("send admin: %w", err)
+// recordAdminMsg updates the handshake with a config section the radio
+// accepted, as if it had been received in a new handshake.
+func recordAdminMsg(handshake *RadioHandshake, msg *meshpb.AdminMsg) {
+	switch v := msg.PayloadVariant.(type) {
+	case *meshpb.AdminMsg_SetConfig:
+		var section *meshpb.RadioConfig
+		switch c := v.SetConfig.GetPayloadVariant().(type) {
+		case *meshpb.CfgSet_Lora:
+			b, _ := proto.Marshal(c.Lora)
+			section = &meshpb.RadioConfig{Section: &meshpb.RadioConfig_Lora{Lora: b}}
+		case *meshpb.CfgSet_Device:
+			b, _ := proto.Marshal(c.Device)
+			section = &meshpb.RadioConfig{Section: &meshpb.RadioConfig_Device{Device: b}}
+		case *meshpb.CfgSet_Position:
+			b, _ := proto.Marshal(c.Position)
+			section = &meshpb.RadioConfig{Section: &meshpb.RadioConfig_Position{Position: b}}
+		default:
+			return
+		}
+		for i, cfg := range handshake.Configs {
+			if reflect.TypeOf(cfg.Section) == reflect.TypeOf(section.Section) {
+				handshake.Configs[i] = section
+				return
 			}
-			time.Sleep(100 * time.Millisecond)
 		}
-	}
+		handshake.Configs = append(handshake.Configs, section)
 
-	// Apply LoRa changes in a transaction — device will save and reboot.
-	if len(loraMsgs) > 0 {
-		logger.Info("Applying LoRa config (device will reboot)", "sections", len(loraMsgs))
+	case *meshpb.AdminMsg_SetOwner:
+		handshake.LongName = v.SetOwner.GetLongName()
+		handshake.ShortName = v.SetOwner.GetShortName()
 
-		if err := sendAdminPacket(radio, nodeNum, &meshpb.AdminMsg{
-			PayloadVariant: &meshpb.AdminMsg_BeginEditSettings{BeginEditSettings: true},
-		}); err != nil {
-			return fmt.Errorf("begin edit: %w", err)
-		}
-		time.Sleep(100 * time.Millisecond)
-
-		for _, msg := range loraMsgs {
-			if err := sendAdminPacket(radio, nodeNum, msg); err != nil {
-				return fmt.Errorf("send admin: %w", err)
+	case *meshpb.AdminMsg_SetChannel:
+		settings, _ := proto.Marshal(v.SetChannel.GetSettings())
+		index := v.SetChannel.GetIndex()
+		for _, ch := range handshake.Channels {
+			if ch.Index == index {
+				ch.Settings = settings
+				return
 			}
-			time.Sleep(100 * time.Millisecond)
-		}
-
-		logger.Info("Committing LoRa config")
-		if err := sendAdminPacket(radio, nodeNum, &meshpb.AdminMsg{
-			PayloadVariant: &meshpb.AdminMsg_CommitEditSettings{CommitEditSettings: true},
-		}); err != nil {
-			return fmt.Errorf("commit edit: %w", err)
 		}
+		handshake.Channels = append(handshake.Channels, &meshpb.Chan{
+			Index:    index,
+			Settings: settings,
+			Role:     uint32(v.SetChannel.GetRole()),
+		})
 	}
-
-	logger.Info("Radio config applied successfully")
-	return nil
 }
 
 func sendAdminPacket(radio *Radio, nodeNum uint32, msg *meshpb.AdminMsg) error {
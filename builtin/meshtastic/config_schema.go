@@ -162,3 +162,25 @@ func radioConfigSchemaProperties() map[string]interface{} {
 		},
 	}
 }
+
+// radioDeviceConfigKeys are the radio settings exposed on the radio device
+// entity: the LoRa region and modem preset and the primary channel.
+var radioDeviceConfigKeys = []string{"radio_region", "radio_preset", "channel_name", "channel_psk"}
+
+// radioDeviceSchema returns the JSON schema of the radio device entity's
+// config, a subset of radioConfigSchemaProperties.
+func radioDeviceSchema() map[string]interface{} {
+	all := radioConfigSchemaProperties()
+	props := make(map[string]interface{}, len(radioDeviceConfigKeys))
+	for _, k := range radioDeviceConfigKeys {
+		props[k] = all[k]
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"ui:groups": []interface{}{
+			map[string]interface{}{"key": "radio", "title": "Radio"},
+			map[string]interface{}{"key": "channel", "title": "Channel"},
+		},
+		"properties": props,
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/projectqai/hydris/builtin/controller"
 	"github.com/projectqai/hydris/builtin/meshtastic/meshpb"
 	"github.com/projectqai/hydris/hal"
+	"github.com/projectqai/hydris/pkg/logsample"
 	pb "github.com/projectqai/proto/go"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
@@ -24,6 +25,8 @@ var (
 	defaultChannel  uint32 = 0
 	defaultHopLimit uint32 = 3
 	defaultSendFmt  string = ""
+	defaultSIDC     string = "SFGPU----------"
+	defaultLogRate  int    = logsample.DefaultPerSecond
 )
 
 // activeRadios tracks the number of radios in active state.
@@ -74,6 +77,25 @@ func Run(ctx context.Context, logger *slog.Logger, _ string) error {
 			"ui:group": "messaging",
 			"ui:order": 2,
 		},
+		"sidc": map[string]interface{}{
+			"type":           "string",
+			"title":          "Node Symbol",
+			"description":    "MIL-STD-2525C symbol code for received nodes",
+			"default":        "SFGPU----------",
+			"ui:placeholder": "e.g. SFGPU----------",
+			"ui:group":       "messaging",
+			"ui:order":       3,
+		},
+		"log_rate": map[string]interface{}{
+			"type":        "integer",
+			"title":       "Log Rate",
+			"description": "Maximum log lines per second for each kind of received packet. Errors are always logged; 0 logs everything.",
+			"default":     logsample.DefaultPerSecond,
+			"minimum":     0,
+			"ui:unit":     "/s",
+			"ui:group":    "messaging",
+			"ui:order":    4,
+		},
 	}
 	for k, v := range radioConfigSchemaProperties() {
 		defaultProps[k] = v
@@ -164,6 +186,12 @@ func runDefaultConfig(ctx context.Context, logger *slog.Logger, entity *pb.Entit
 		if v, ok := entity.Config.Value.Fields["send_format"]; ok {
 			defaultSendFmt = v.GetStringValue()
 		}
+		if v, ok := entity.Config.Value.Fields["sidc"]; ok && v.GetStringValue() != "" {
+			defaultSIDC = v.GetStringValue()
+		}
+		if v, ok := entity.Config.Value.Fields["log_rate"]; ok {
+			defaultLogRate = int(v.GetNumberValue())
+		}
 	}
 	defaultsMu.Unlock()
 
@@ -171,6 +199,8 @@ func runDefaultConfig(ctx context.Context, logger *slog.Logger, entity *pb.Entit
 		"channel", defaultChannel,
 		"hopLimit", defaultHopLimit,
 		"sendFormat", defaultSendFmt,
+		"sidc", defaultSIDC,
+		"logRate", defaultLogRate,
 	)
 
 	<-ctx.Done()
@@ -209,6 +239,8 @@ func runInstance(parentCtx context.Context, logger *slog.Logger, entity *pb.Enti
 	channel := defaultChannel
 	hopLimit := defaultHopLimit
 	sendFormat := defaultSendFmt
+	sidc := defaultSIDC
+	logRate := defaultLogRate
 	defaultsMu.RUnlock()
 
 	if config != nil && config.Value != nil && config.Value.Fields != nil {
@@ -221,6 +253,9 @@ func runInstance(parentCtx context.Context, logger *slog.Logger, entity *pb.Enti
 		if v, ok := config.Value.Fields["send_format"]; ok {
 			sendFormat = v.GetStringValue()
 		}
+		if v, ok := config.Value.Fields["sidc"]; ok && v.GetStringValue() != "" {
+			sidc = v.GetStringValue()
+		}
 	}
 
 	// Backward compat for old send format names
@@ -428,6 +463,17 @@ func runInstance(parentCtx context.Context, logger *slog.Logger, entity *pb.Enti
 		_, _ = client.ExpireEntity(expireCtx, &pb.ExpireEntityRequest{Id: radioDeviceID})
 	}()
 
+	// Radio settings written to the radio device entity are forwarded to
+	// this entity's config, which restarts the instance to apply them.
+	go controller.Run(ctx, radioDeviceID, func(ctx context.Context, radioEntity *pb.Entity, ready func()) error { //nolint:errcheck // fire-and-forget goroutine
+		if err := forwardRadioDeviceConfig(ctx, client, entity.Id, radioEntity.GetConfig().GetValue().GetFields()); err != nil {
+			return err
+		}
+		ready()
+		<-ctx.Done()
+		return nil
+	})
+
 	controllerID := entity.Id
 
 	go func() {
@@ -444,7 +490,7 @@ func runInstance(parentCtx context.Context, logger *slog.Logger, entity *pb.Enti
 	errCh := make(chan error, 1+senderCount)
 
 	go func() {
-		errCh <- runReceiver(ctx, logger, grpcConn, radio, controllerID, radioDeviceID, chatIDs)
+		errCh <- runReceiver(ctx, logsample.Logger(logger, logRate), grpcConn, radio, controllerID, sidc, radioDeviceID, chatIDs)
 	}()
 
 	// Re-request config so the receiver picks up the cached node database.
@@ -519,8 +565,77 @@ func buildRadioEntities(cfg *RadioHandshake, deviceID, parentDeviceEntityID, con
 		Controller: &pb.Controller{
 			Id: proto.String("meshtastic"),
 		},
-		Device: dev,
+		Device:       dev,
+		Configurable: radioDeviceConfigurable(cfg),
 	}
 
 	return []*pb.Entity{deviceEntity}
 }
+
+// radioDeviceConfigurable shows the radio's current region, modem preset
+// and primary channel as the editable config of the radio device entity.
+func radioDeviceConfigurable(cfg *RadioHandshake) *pb.ConfigurableComponent {
+	state := readRadioState(cfg)
+	current := make(map[string]interface{}, len(radioDeviceConfigKeys))
+	for _, k := range radioDeviceConfigKeys {
+		if v, ok := state[k]; ok {
+			current[k] = v
+		}
+	}
+	schema, _ := structpb.NewStruct(radioDeviceSchema())
+	value, _ := structpb.NewStruct(current)
+	return &pb.ConfigurableComponent{
+		Schema: schema,
+		Value:  value,
+		State:  pb.ConfigurableState_ConfigurableStateActive,
+	}
+}
+
+// forwardRadioDeviceConfig merges radio settings written to the radio
+// device entity into the config of the connection entity configEntityID.
+// Keeping the connection config the only source of radio settings means
+// the two entities can never ask the radio for different ones.
+func forwardRadioDeviceConfig(ctx context.Context, client pb.WorldServiceClient, configEntityID string, fields map[string]*structpb.Value) error {
+	resp, err := client.GetEntity(ctx, &pb.GetEntityRequest{Id: configEntityID})
+	if err != nil {
+		return fmt.Errorf("get config entity %s: %w", configEntityID, err)
+	}
+	merged, changed := mergeRadioDeviceConfig(resp.Entity.GetConfig().GetValue(), fields)
+	if !changed {
+		return nil
+	}
+	if _, err := client.Push(ctx, &pb.EntityChangeRequest{
+		Changes: []*pb.Entity{{
+			Id:     configEntityID,
+			Config: &pb.ConfigurationComponent{Value: merged},
+		}},
+	}); err != nil {
+		return fmt.Errorf("push config entity %s: %w", configEntityID, err)
+	}
+	return nil
+}
+
+// mergeRadioDeviceConfig returns current with the radio device settings of
+// fields applied, and whether any of them changed.
+func mergeRadioDeviceConfig(current *structpb.Struct, fields map[string]*structpb.Value) (*structpb.Struct, bool) {
+	merged := &structpb.Struct{}
+	if current != nil {
+		merged = proto.Clone(current).(*structpb.Struct)
+	}
+	if merged.Fields == nil {
+		merged.Fields = make(map[string]*structpb.Value)
+	}
+	changed := false
+	for _, k := range radioDeviceConfigKeys {
+		v, ok := fields[k]
+		if !ok || v.GetStringValue() == "" {
+			continue
+		}
+		if cur, ok := merged.Fields[k]; ok && proto.Equal(cur, v) {
+			continue
+		}
+		merged.Fields[k] = v
+		changed = true
+	}
+	return merged, changed
+}
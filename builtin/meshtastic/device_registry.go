@@ -272,6 +272,7 @@ func runAutoManager(ctx context.Context, logger *slog.Logger) error {
 				"channel":     float64(defaultChannel),
 				"hop_limit":   float64(defaultHopLimit),
 				"send_format": defaultSendFmt,
+				"sidc":        defaultSIDC,
 			})
 			defaultsMu.RUnlock()
 
@@ -339,6 +340,15 @@ func meshtasticDeviceForParent(parent *pb.Entity) *pb.Entity {
 			"ui:group": "messaging",
 			"ui:order": 2,
 		},
+		"sidc": map[string]interface{}{
+			"type":           "string",
+			"title":          "Node Symbol",
+			"description":    "MIL-STD-2525C symbol code for received nodes",
+			"default":        "SFGPU----------",
+			"ui:placeholder": "e.g. SFGPU----------",
+			"ui:group":       "messaging",
+			"ui:order":       3,
+		},
 	}
 	for k, v := range radioConfigSchemaProperties() {
 		usbProps[k] = v
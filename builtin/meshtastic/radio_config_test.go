@@ -0,0 +1,158 @@
+package meshtastic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/projectqai/hydris/builtin/meshtastic/meshpb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// recordingConn captures what is written to a radio.
+type recordingConn struct {
+	bytes.Buffer
+}
+
+func (c *recordingConn) Read([]byte) (int, error) { return 0, io.EOF }
+func (c *recordingConn) Close() error             { return nil }
+
+// adminMsgs decodes the admin messages of all ToRadio frames written to c.
+func (c *recordingConn) adminMsgs(t *testing.T, nodeNum uint32) []*meshpb.AdminMsg {
+	t.Helper()
+	var msgs []*meshpb.AdminMsg
+	b := c.Bytes()
+	for len(b) > 0 {
+		if len(b) < headerLen || b[0] != start1 || b[1] != start2 {
+			t.Fatalf("bad frame header % x", b[:min(len(b), headerLen)])
+		}
+		n := int(binary.BigEndian.Uint16(b[2:4]))
+		var to meshpb.ToRadio
+		if err := proto.Unmarshal(b[headerLen:headerLen+n], &to); err != nil {
+			t.Fatal(err)
+		}
+		b = b[headerLen+n:]
+
+		pkt := to.GetPacket()
+		if pkt.GetDst() != nodeNum || pkt.GetDecoded().GetPort() != meshpb.Port_PORT_ADMIN {
+			t.Fatalf("not an admin packet to %d: %v", nodeNum, pkt)
+		}
+		var msg meshpb.AdminMsg
+		if err := proto.Unmarshal(pkt.GetDecoded().GetData(), &msg); err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, &msg)
+	}
+	return msgs
+}
+
+func testHandshake(t *testing.T) *RadioHandshake {
+	t.Helper()
+	lora, err := proto.Marshal(&meshpb.LoraConfig{Region: meshpb.RegionCode_REGION_US, UsePreset: true, ModemPreset: meshpb.ModemPreset_MODEM_LONG_FAST, TxEnabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	channel, err := proto.Marshal(&meshpb.ChanSettings{Name: "LongFast", Psk: []byte{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &RadioHandshake{
+		NodeNum:  0x1234,
+		Configs:  []*meshpb.RadioConfig{{Section: &meshpb.RadioConfig_Lora{Lora: lora}}},
+		Channels: []*meshpb.Chan{{Index: 0, Settings: channel, Role: uint32(meshpb.ChannelRole_CH_PRIMARY)}},
+	}
+}
+
+func TestRadioConfigMessages_Region(t *testing.T) {
+	h := testHandshake(t)
+	fields := map[string]*structpb.Value{
+		"radio_region": structpb.NewStringValue("EU_868"),
+		"radio_preset": structpb.NewStringValue("long_fast"),
+	}
+	desired := parseRadioSettings(fields, readRadioState(h))
+	if desired == nil {
+		t.Fatal("region change not detected")
+	}
+
+	loraMsgs, softMsgs := radioConfigMessages(slog.Default(), h, desired)
+	if len(loraMsgs) != 1 || len(softMsgs) != 0 {
+		t.Fatalf("got %d LoRa and %d soft messages", len(loraMsgs), len(softMsgs))
+	}
+	lora := loraMsgs[0].GetSetConfig().GetLora()
+	if lora.GetRegion() != meshpb.RegionCode_REGION_EU_868 || lora.GetModemPreset() != meshpb.ModemPreset_MODEM_LONG_FAST || !lora.GetTxEnabled() {
+		t.Errorf("LoRa config %v", lora)
+	}
+
+	conn := &recordingConn{}
+	if err := applyRadioConfig(slog.Default(), NewRadio(conn), h, desired); err != nil {
+		t.Fatal(err)
+	}
+	msgs := conn.adminMsgs(t, h.NodeNum)
+	if len(msgs) != 3 || !msgs[0].GetBeginEditSettings() || !proto.Equal(msgs[1], loraMsgs[0]) || !msgs[2].GetCommitEditSettings() {
+		t.Errorf("LoRa change should be sent as begin, set config, commit; got %v", msgs)
+	}
+	if got := readRadioState(h)["radio_region"]; got != "EU_868" {
+		t.Errorf("handshake region %v after apply", got)
+	}
+}
+
+func TestRadioConfig_ChannelRoundTrip(t *testing.T) {
+	h := testHandshake(t)
+	fields := map[string]*structpb.Value{
+		"channel_name": structpb.NewStringValue("ops"),
+	}
+
+	conn := &recordingConn{}
+	if err := applyRadioConfig(slog.Default(), NewRadio(conn), h, parseRadioSettings(fields, readRadioState(h))); err != nil {
+		t.Fatal(err)
+	}
+	msgs := conn.adminMsgs(t, h.NodeNum)
+	if len(msgs) != 1 {
+		t.Fatalf("got %d admin messages, want 1", len(msgs))
+	}
+	ch := msgs[0].GetSetChannel()
+	if ch.GetIndex() != 0 || ch.GetRole() != meshpb.ChannelRole_CH_PRIMARY || ch.GetSettings().GetName() != "ops" || !bytes.Equal(ch.GetSettings().GetPsk(), []byte{1}) {
+		t.Errorf("channel %v", ch)
+	}
+
+	state := readRadioState(h)
+	if state["channel_name"] != "ops" {
+		t.Errorf("radio state channel %v, want ops", state["channel_name"])
+	}
+	if rs := parseRadioSettings(fields, state); rs != nil {
+		t.Error("applied channel change should not be applied again")
+	}
+}
+
+func TestMergeRadioDeviceConfig(t *testing.T) {
+	current, _ := structpb.NewStruct(map[string]interface{}{
+		"send_format":  "tak",
+		"radio_region": "US",
+	})
+	fields := map[string]*structpb.Value{
+		"radio_region": structpb.NewStringValue("EU_868"),
+		"channel_name": structpb.NewStringValue(""),
+		"send_format":  structpb.NewStringValue("hydris"),
+	}
+
+	merged, changed := mergeRadioDeviceConfig(current, fields)
+	if !changed {
+		t.Fatal("region change not merged")
+	}
+	if merged.Fields["radio_region"].GetStringValue() != "EU_868" || merged.Fields["send_format"].GetStringValue() != "tak" {
+		t.Errorf("merged %v", merged.AsMap())
+	}
+	if _, ok := merged.Fields["channel_name"]; ok {
+		t.Error("empty channel name should be ignored")
+	}
+	if current.Fields["radio_region"].GetStringValue() != "US" {
+		t.Error("current config modified")
+	}
+
+	if _, changed := mergeRadioDeviceConfig(merged, fields); changed {
+		t.Error("merging the same settings again should be a no-op")
+	}
+}
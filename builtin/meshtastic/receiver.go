@@ -18,7 +18,7 @@ import (
 
 var meshtasticControllerName = "meshtastic"
 
-func runReceiver(ctx context.Context, logger *slog.Logger, grpcConn *grpc.ClientConn, radio *Radio, trackerID string, radioEntityID string, chatIDs *msgIDMap) error {
+func runReceiver(ctx context.Context, logger *slog.Logger, grpcConn *grpc.ClientConn, radio *Radio, trackerID string, sidc string, radioEntityID string, chatIDs *msgIDMap) error {
 	client := pb.NewWorldServiceClient(grpcConn)
 
 	var callsignsMu sync.RWMutex
@@ -61,7 +61,7 @@ func runReceiver(ctx context.Context, logger *slog.Logger, grpcConn *grpc.Client
 				}
 
 				if info.Position != nil {
-					e := nodeInfoToEntity(info, trackerID, &callsignsMu, callsigns)
+					e := nodeInfoToEntity(info, trackerID, sidc, &callsignsMu, callsigns)
 					if e != nil {
 						if _, err := client.Push(ctx, &pb.EntityChangeRequest{Changes: []*pb.Entity{e}}); err != nil {
 							logger.Error("Push node info entity failed", "error", err)
@@ -88,7 +88,7 @@ func runReceiver(ctx context.Context, logger *slog.Logger, grpcConn *grpc.Client
 
 		switch decoded.GetPort() {
 		case meshpb.Port_PORT_TAK:
-			e, err := handleATAKPlugin(ctx, decoded.GetData(), fromNode, packet.Packet.GetRxTime(), trackerID, &callsignsMu, callsigns, client, logger)
+			e, err := handleATAKPlugin(ctx, decoded.GetData(), fromNode, packet.Packet.GetRxTime(), trackerID, sidc, &callsignsMu, callsigns, client, logger)
 			if err != nil {
 				logger.Debug("ATAK_PLUGIN decode error", "error", err, "from", fmt.Sprintf("!%08x", fromNode))
 				continue
@@ -111,7 +111,7 @@ func runReceiver(ctx context.Context, logger *slog.Logger, grpcConn *grpc.Client
 			}
 
 		case meshpb.Port_PORT_POSITION:
-			e, err := handlePositionApp(decoded.GetData(), fromNode, trackerID, &callsignsMu, callsigns)
+			e, err := handlePositionApp(decoded.GetData(), fromNode, trackerID, sidc, &callsignsMu, callsigns)
 			if err != nil {
 				logger.Debug("POSITION_APP decode error", "error", err, "from", fmt.Sprintf("!%08x", fromNode))
 				continue
@@ -14,7 +14,7 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-func handleATAKPlugin(ctx context.Context, payload []byte, fromNode uint32, rxTime uint32, trackerID string, mu *sync.RWMutex, callsigns map[uint32]string, client pb.WorldServiceClient, logger *slog.Logger) (*pb.Entity, error) {
+func handleATAKPlugin(ctx context.Context, payload []byte, fromNode uint32, rxTime uint32, trackerID string, sidc string, mu *sync.RWMutex, callsigns map[uint32]string, client pb.WorldServiceClient, logger *slog.Logger) (*pb.Entity, error) {
 	var tp meshpb.TAKPacket
 	if err := proto.Unmarshal(payload, &tp); err != nil {
 		return nil, fmt.Errorf("unmarshal TAKPacket: %w", err)
@@ -110,8 +110,6 @@ func handleATAKPlugin(ctx context.Context, payload []byte, fromNode uint32, rxTi
 
 	now := time.Now()
 	stale := now.Add(10 * time.Minute)
-	sidc := "SFGPU----------"
-
 	return &pb.Entity{
 		Id:      entityID,
 		Label:   &label,
@@ -216,7 +214,7 @@ func handleATAKForwarder(payload []byte, fromNode uint32, trackerID string, foun
 	return []*pb.Entity{entity}, nil
 }
 
-func handlePositionApp(payload []byte, fromNode uint32, trackerID string, mu *sync.RWMutex, callsigns map[uint32]string) (*pb.Entity, error) {
+func handlePositionApp(payload []byte, fromNode uint32, trackerID string, sidc string, mu *sync.RWMutex, callsigns map[uint32]string) (*pb.Entity, error) {
 	var pos meshpb.Pos
 	if err := proto.Unmarshal(payload, &pos); err != nil {
 		return nil, fmt.Errorf("unmarshal Position: %w", err)
@@ -241,8 +239,6 @@ func handlePositionApp(payload []byte, fromNode uint32, trackerID string, mu *sy
 
 	now := time.Now()
 	stale := now.Add(10 * time.Minute)
-	sidc := "SFGPU----------"
-
 	return &pb.Entity{
 		Id:      entityID,
 		Label:   &label,
@@ -268,7 +264,7 @@ func handlePositionApp(payload []byte, fromNode uint32, trackerID string, mu *sy
 	}, nil
 }
 
-func nodeInfoToEntity(info *meshpb.NodeEntry, trackerID string, mu *sync.RWMutex, callsigns map[uint32]string) *pb.Entity {
+func nodeInfoToEntity(info *meshpb.NodeEntry, trackerID string, sidc string, mu *sync.RWMutex, callsigns map[uint32]string) *pb.Entity {
 	pos := info.Position
 	lat := float64(pos.GetLatI()) * 1e-7
 	lon := float64(pos.GetLonI()) * 1e-7
@@ -289,8 +285,6 @@ func nodeInfoToEntity(info *meshpb.NodeEntry, trackerID string, mu *sync.RWMutex
 
 	now := time.Now()
 	stale := now.Add(10 * time.Minute)
-	sidc := "SFGPU----------"
-
 	return &pb.Entity{
 		Id:      entityID,
 		Label:   &label,
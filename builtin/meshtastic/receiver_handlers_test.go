@@ -0,0 +1,37 @@
+package meshtastic
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/projectqai/hydris/builtin/meshtastic/meshpb"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHandlers_ConfiguredSIDC(t *testing.T) {
+	const sidc = "SHGPUCI--------"
+	pos := &meshpb.Pos{LatI: 481234567, LonI: 115678901, Alt: 520}
+
+	var mu sync.RWMutex
+	callsigns := map[uint32]string{0x1234abcd: "ALPHA"}
+
+	payload, err := proto.Marshal(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := handlePositionApp(payload, 0x1234abcd, "meshtastic.device.1", sidc, &mu, callsigns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.GetSymbol().GetMilStd2525C(); got != sidc {
+		t.Errorf("position entity sidc %q, want %q", got, sidc)
+	}
+
+	e = nodeInfoToEntity(&meshpb.NodeEntry{Num: 0x1234abcd, Position: pos}, "meshtastic.device.1", sidc, &mu, callsigns)
+	if e == nil {
+		t.Fatal("no entity for a node with a position")
+	}
+	if got := e.GetSymbol().GetMilStd2525C(); got != sidc {
+		t.Errorf("node info entity sidc %q, want %q", got, sidc)
+	}
+}
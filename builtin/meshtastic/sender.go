@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log/slog"
 	"math/rand/v2"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 	"unicode/utf8"
@@ -106,6 +108,23 @@ func runSender(ctx context.Context, logger *slog.Logger, grpcConn *grpc.ClientCo
 				continue
 			}
 
+			// Chats addressed to a mesh node go out as a native direct
+			// message in every format, and are expired once sent.
+			if dst, ok := meshRecipient(entity.Chat.GetTo()); ok {
+				logger.Info("Sending direct message to mesh", "entityID", entity.Id, "to", fmt.Sprintf("!%08x", dst))
+				if err := sendChatAsText(ctx, logger, radio, entity, dst, channel, hopLimit, chatIDs); err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					logger.Error("Failed to send direct message to mesh", "entityID", entity.Id, "error", err)
+					continue
+				}
+				if _, err := client.ExpireEntity(ctx, &pb.ExpireEntityRequest{Id: entity.Id}); err != nil {
+					logger.Warn("Failed to expire sent direct message", "entityID", entity.Id, "error", err)
+				}
+				continue
+			}
+
 			isSelf := localNodeEntityID != "" && entity.Chat.GetSender() == localNodeEntityID
 
 			// Self chat always goes via native PORT_TEXT.
@@ -118,7 +137,7 @@ func runSender(ctx context.Context, logger *slog.Logger, grpcConn *grpc.ClientCo
 			logger.Info("Sending chat to mesh", "entityID", entity.Id, "message", entity.Chat.Message)
 			var sendErr error
 			if isSelf {
-				sendErr = sendChatAsText(ctx, logger, radio, entity, channel, hopLimit, chatIDs)
+				sendErr = sendChatAsText(ctx, logger, radio, entity, broadcastNum, channel, hopLimit, chatIDs)
 			} else {
 				switch sendFormat {
 				case "tak":
@@ -363,7 +382,28 @@ func sendChatAsTAKPacket(ctx context.Context, logger *slog.Logger, radio *Radio,
 	return radio.Send(toRadio)
 }
 
-func sendChatAsText(ctx context.Context, logger *slog.Logger, radio *Radio, entity *pb.Entity, channel, hopLimit uint32, chatIDs *msgIDMap) error {
+// meshRecipient returns the node number of a chat recipient that is a mesh
+// node, given as its entity id ("meshtastic.1234abcd") or node id
+// ("!1234abcd").
+func meshRecipient(to string) (uint32, bool) {
+	id, ok := strings.CutPrefix(to, "meshtastic.")
+	if !ok {
+		id, ok = strings.CutPrefix(to, "!")
+	}
+	if !ok || len(id) != 8 {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(id, 16, 32)
+	if err != nil || n == broadcastNum {
+		return 0, false
+	}
+	return uint32(n), true
+}
+
+// sendChatAsText sends a chat as a native text message to dst, which is
+// broadcastNum for the channel or a node number for a direct message.
+// Direct messages request an acknowledgement.
+func sendChatAsText(ctx context.Context, logger *slog.Logger, radio *Radio, entity *pb.Entity, dst, channel, hopLimit uint32, chatIDs *msgIDMap) error {
 	msg := entity.Chat.Message
 	data := []byte(msg)
 	if len(data) > maxPayloadSize {
@@ -403,12 +443,12 @@ func sendChatAsText(ctx context.Context, logger *slog.Logger, radio *Radio, enti
 	toRadio := &meshpb.ToRadio{
 		Msg: &meshpb.ToRadio_Packet{
 			Packet: &meshpb.Packet{
-				Dst:      broadcastNum,
+				Dst:      dst,
 				Ch:       channel,
 				HopLimit: hopLimit,
 				HopStart: hopLimit,
 				Id:       packetID,
-				WantAck:  false,
+				WantAck:  dst != broadcastNum,
 				Body: &meshpb.Packet_Decoded{
 					Decoded: decoded,
 				},
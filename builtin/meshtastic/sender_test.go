@@ -0,0 +1,68 @@
+package meshtastic
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/projectqai/hydris/builtin/meshtastic/meshpb"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMeshRecipient(t *testing.T) {
+	tests := []struct {
+		to   string
+		want uint32
+		ok   bool
+	}{
+		{"meshtastic.1234abcd", 0x1234abcd, true},
+		{"!1234abcd", 0x1234abcd, true},
+		{"meshtastic.chat.1234abcd.1", 0, false},
+		{"!ffffffff", 0, false},
+		{"All Chat Rooms", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := meshRecipient(tt.to)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("meshRecipient(%q) = %x, %v; want %x, %v", tt.to, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSendChatAsText_DirectMessage(t *testing.T) {
+	entity := &pb.Entity{
+		Id: "chat.1",
+		Chat: &pb.ChatComponent{
+			Sender:  proto.String("node.self"),
+			To:      proto.String("meshtastic.1234abcd"),
+			Message: "rally at checkpoint 3",
+		},
+	}
+	dst, ok := meshRecipient(entity.Chat.GetTo())
+	if !ok {
+		t.Fatal("recipient not recognized")
+	}
+
+	conn := &recordingConn{}
+	chatIDs := newMsgIDMap(8)
+	if err := sendChatAsText(context.Background(), slog.Default(), NewRadio(conn), entity, dst, 2, 3, chatIDs); err != nil {
+		t.Fatal(err)
+	}
+
+	var to meshpb.ToRadio
+	if err := proto.Unmarshal(conn.Bytes()[headerLen:], &to); err != nil {
+		t.Fatal(err)
+	}
+	pkt := to.GetPacket()
+	if pkt.GetDst() != 0x1234abcd || pkt.GetCh() != 2 || pkt.GetHopLimit() != 3 || !pkt.GetWantAck() {
+		t.Errorf("packet dst %x ch %d hops %d ack %v", pkt.GetDst(), pkt.GetCh(), pkt.GetHopLimit(), pkt.GetWantAck())
+	}
+	if d := pkt.GetDecoded(); d.GetPort() != meshpb.Port_PORT_TEXT || string(d.GetData()) != "rally at checkpoint 3" {
+		t.Errorf("payload port %v data %q", d.GetPort(), d.GetData())
+	}
+	if id, ok := chatIDs.PacketID("chat.1"); !ok || id != pkt.GetId() {
+		t.Error("sent packet id not recorded for replies")
+	}
+}
@@ -0,0 +1,102 @@
+package mqtt
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// broker is the part of an MQTT client the ingest and egress loops need.
+// It is an interface so tests can run the loops without a real broker.
+type broker interface {
+	Subscribe(topic string, handle func(topic string, payload []byte)) error
+	Publish(topic string, payload []byte, retained bool) error
+	Close()
+}
+
+const connectTimeout = 10 * time.Second
+
+// pahoBroker is a broker backed by a paho client. The client reconnects
+// on its own with a clean session, which drops the subscriptions, so they
+// are kept here and made again on every connect.
+type pahoBroker struct {
+	client paho.Client
+	qos    byte
+	logger *slog.Logger
+
+	mu   sync.Mutex
+	subs map[string]paho.MessageHandler
+}
+
+func dialBroker(config *BrokerConfig, logger *slog.Logger) (broker, error) {
+	b := &pahoBroker{qos: config.QoS, logger: logger, subs: make(map[string]paho.MessageHandler)}
+	opts := paho.NewClientOptions().
+		AddBroker(config.URL).
+		SetClientID(config.ClientID).
+		SetAutoReconnect(true).
+		SetConnectTimeout(connectTimeout).
+		SetOnConnectHandler(b.resubscribe)
+	if config.Username != "" {
+		opts.SetUsername(config.Username)
+		opts.SetPassword(config.Password)
+	}
+
+	b.client = paho.NewClient(opts)
+	token := b.client.Connect()
+	if !token.WaitTimeout(connectTimeout) {
+		return nil, fmt.Errorf("connect to %s: timeout", config.URL)
+	}
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", config.URL, err)
+	}
+	return b, nil
+}
+
+// resubscribe makes the subscriptions again after a reconnect.
+func (b *pahoBroker) resubscribe(client paho.Client) {
+	b.mu.Lock()
+	subs := maps.Clone(b.subs)
+	b.mu.Unlock()
+
+	for topic, handler := range subs {
+		token := client.Subscribe(topic, b.qos, handler)
+		if !token.WaitTimeout(connectTimeout) {
+			b.logger.Warn("resubscribe timed out", "topic", topic)
+			continue
+		}
+		if err := token.Error(); err != nil {
+			b.logger.Warn("resubscribe failed", "topic", topic, "error", err)
+		}
+	}
+}
+
+func (b *pahoBroker) Subscribe(topic string, handle func(topic string, payload []byte)) error {
+	handler := func(_ paho.Client, msg paho.Message) {
+		handle(msg.Topic(), msg.Payload())
+	}
+	b.mu.Lock()
+	b.subs[topic] = handler
+	b.mu.Unlock()
+
+	token := b.client.Subscribe(topic, b.qos, handler)
+	if !token.WaitTimeout(connectTimeout) {
+		return fmt.Errorf("subscribe to %s: timeout", topic)
+	}
+	return token.Error()
+}
+
+func (b *pahoBroker) Publish(topic string, payload []byte, retained bool) error {
+	token := b.client.Publish(topic, b.qos, retained, payload)
+	if !token.WaitTimeout(connectTimeout) {
+		return fmt.Errorf("publish to %s: timeout", topic)
+	}
+	return token.Error()
+}
+
+func (b *pahoBroker) Close() {
+	b.client.Disconnect(250)
+}
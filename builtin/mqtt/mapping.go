@@ -0,0 +1,155 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/projectqai/hydris/pkg/idtemplate"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Mapping says where in a JSON message the entity's attributes are found.
+// Each field is a dotted path into the message, e.g. "position.lat".
+// "$topic" refers to the message topic and "$topic.N" to its N-th
+// slash-separated segment, for brokers that encode the device in the topic.
+//
+// ID may also be an id template such as "{$topic.1}.{serial}", whose
+// fields are paths as above; see package idtemplate.
+type Mapping struct {
+	ID        string
+	Label     string
+	Latitude  string
+	Longitude string
+	Altitude  string
+}
+
+// mapMessage converts one MQTT message to an entity. Messages without an
+// id or without a complete position are rejected.
+func mapMessage(topic string, payload []byte, config *IngestConfig) (*pb.Entity, error) {
+	m := config.Mapping
+	var doc map[string]any
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return nil, fmt.Errorf("parse message: %w", err)
+	}
+
+	id, err := mappedID(doc, topic, m.ID)
+	if err != nil {
+		return nil, err
+	}
+	lat, okLat := lookupNumber(doc, topic, m.Latitude)
+	lon, okLon := lookupNumber(doc, topic, m.Longitude)
+	if !okLat || !okLon {
+		return nil, fmt.Errorf("message %s has no position at %q/%q", id, m.Latitude, m.Longitude)
+	}
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return nil, fmt.Errorf("message %s has position %g,%g out of range", id, lat, lon)
+	}
+
+	geo := &pb.GeoSpatialComponent{Latitude: lat, Longitude: lon}
+	if alt, ok := lookupNumber(doc, topic, m.Altitude); ok {
+		geo.Altitude = &alt
+	}
+
+	controllerID := controllerName
+	trackerID := config.EntityID
+	entity := &pb.Entity{
+		Id: config.IDPrefix + id,
+		Lifetime: &pb.Lifetime{
+			From:  timestamppb.Now(),
+			Until: timestamppb.New(time.Now().Add(config.TTL())),
+		},
+		Geo: geo,
+		Controller: &pb.Controller{
+			Id: &controllerID,
+		},
+		Track: &pb.TrackComponent{
+			Tracker: &trackerID,
+		},
+	}
+	if label, ok := lookupString(doc, topic, m.Label); ok && label != "" {
+		entity.Label = &label
+	}
+	return entity, nil
+}
+
+// mappedID returns the device id of a message, from the path or id
+// template idField.
+func mappedID(doc map[string]any, topic, idField string) (string, error) {
+	if strings.Contains(idField, "{") {
+		tmpl, err := idtemplate.Parse(idField)
+		if err != nil {
+			return "", err
+		}
+		return tmpl.ExpandFunc(func(path string) (string, bool) {
+			return lookupString(doc, topic, path)
+		})
+	}
+	id, ok := lookupString(doc, topic, idField)
+	if !ok || id == "" {
+		return "", fmt.Errorf("message has no id at %q", idField)
+	}
+	return id, nil
+}
+
+func lookup(doc map[string]any, topic, path string) (any, bool) {
+	if path == "" {
+		return nil, false
+	}
+	if path == "$topic" {
+		return topic, true
+	}
+	if rest, ok := strings.CutPrefix(path, "$topic."); ok {
+		n, err := strconv.Atoi(rest)
+		segments := strings.Split(topic, "/")
+		if err != nil || n < 0 || n >= len(segments) {
+			return nil, false
+		}
+		return segments[n], true
+	}
+
+	var cur any = doc
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func lookupString(doc map[string]any, topic, path string) (string, bool) {
+	v, ok := lookup(doc, topic, path)
+	if !ok {
+		return "", false
+	}
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+func lookupNumber(doc map[string]any, topic, path string) (float64, bool) {
+	v, ok := lookup(doc, topic, path)
+	if !ok {
+		return 0, false
+	}
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+	}
+	return 0, false
+}
@@ -0,0 +1,465 @@
+// Package mqtt bridges entities to and from MQTT brokers. Ingest devices
+// subscribe to a topic and turn JSON messages into entities, egress devices
+// publish every entity as JSON.
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/projectqai/hydris/builtin"
+	"github.com/projectqai/hydris/builtin/controller"
+	"github.com/projectqai/hydris/goclient"
+	"github.com/projectqai/hydris/pkg/idtemplate"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const controllerName = "mqtt"
+
+type BrokerConfig struct {
+	URL      string
+	ClientID string
+	Username string
+	Password string
+	QoS      byte
+}
+
+type IngestConfig struct {
+	BrokerConfig
+	EntityID   string
+	Topic      string
+	IDPrefix   string
+	Mapping    Mapping
+	TTLSeconds int
+}
+
+func (c *IngestConfig) TTL() time.Duration {
+	if c.TTLSeconds <= 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(c.TTLSeconds) * time.Second
+}
+
+type EgressConfig struct {
+	BrokerConfig
+	Topic    string
+	Retained bool
+}
+
+func brokerSchema() map[string]any {
+	return map[string]any{
+		"broker": map[string]any{
+			"type":           "string",
+			"title":          "Broker",
+			"description":    "MQTT broker URL",
+			"ui:placeholder": "e.g. tcp://localhost:1883",
+			"ui:group":       "broker",
+			"ui:order":       0,
+		},
+		"username": map[string]any{
+			"type":     "string",
+			"title":    "Username",
+			"ui:group": "broker",
+			"ui:order": 1,
+		},
+		"password": map[string]any{
+			"type":      "string",
+			"title":     "Password",
+			"ui:widget": "password",
+			"ui:group":  "broker",
+			"ui:order":  2,
+		},
+		"qos": map[string]any{
+			"type":        "number",
+			"title":       "QoS",
+			"description": "MQTT quality of service level",
+			"default":     0,
+			"minimum":     0,
+			"maximum":     2,
+			"ui:group":    "broker",
+			"ui:order":    3,
+		},
+	}
+}
+
+func Run(ctx context.Context, logger *slog.Logger, _ string) error {
+	ingestProperties := brokerSchema()
+	ingestProperties["topic"] = map[string]any{
+		"type":           "string",
+		"title":          "Topic",
+		"description":    "Topic filter to subscribe to, wildcards allowed",
+		"ui:placeholder": "e.g. sensors/+/position",
+		"ui:order":       0,
+	}
+	ingestProperties["id_field"] = map[string]any{
+		"type":        "string",
+		"title":       "ID Field",
+		"description": "Path to the device id in the message, or $topic.N for the N-th topic segment. Several can be combined as a template, e.g. {site}.{serial}",
+		"default":     "id",
+		"ui:group":    "mapping",
+		"ui:order":    0,
+	}
+	ingestProperties["label_field"] = map[string]any{
+		"type":        "string",
+		"title":       "Label Field",
+		"description": "Path to the display name in the message",
+		"ui:group":    "mapping",
+		"ui:order":    1,
+	}
+	ingestProperties["latitude_field"] = map[string]any{
+		"type":     "string",
+		"title":    "Latitude Field",
+		"default":  "lat",
+		"ui:group": "mapping",
+		"ui:order": 2,
+	}
+	ingestProperties["longitude_field"] = map[string]any{
+		"type":     "string",
+		"title":    "Longitude Field",
+		"default":  "lon",
+		"ui:group": "mapping",
+		"ui:order": 3,
+	}
+	ingestProperties["altitude_field"] = map[string]any{
+		"type":     "string",
+		"title":    "Altitude Field",
+		"default":  "alt",
+		"ui:group": "mapping",
+		"ui:order": 4,
+	}
+	ingestProperties["id_prefix"] = map[string]any{
+		"type":        "string",
+		"title":       "ID Prefix",
+		"description": "Prepended to the device id to form the entity id",
+		"default":     "mqtt.",
+		"ui:group":    "mapping",
+		"ui:order":    5,
+	}
+	ingestProperties["ttl_seconds"] = map[string]any{
+		"type":        "number",
+		"title":       "Expiry",
+		"description": "How long an entity stays alive after its last message",
+		"default":     60,
+		"minimum":     1,
+		"ui:unit":     "s",
+		"ui:group":    "mapping",
+		"ui:order":    6,
+	}
+	ingestProperties[controller.ExpireOnTeardownField] = controller.ExpireOnTeardownProperty()
+	ingestSchema, _ := structpb.NewStruct(map[string]any{
+		"type":       "object",
+		"properties": ingestProperties,
+		"required":   []any{"broker", "topic"},
+	})
+
+	egressProperties := brokerSchema()
+	egressProperties["topic"] = map[string]any{
+		"type":        "string",
+		"title":       "Topic",
+		"description": "Topic to publish to; {id} is replaced by the entity id",
+		"default":     "hydris/entities/{id}",
+		"ui:order":    0,
+	}
+	egressProperties["retained"] = map[string]any{
+		"type":        "boolean",
+		"title":       "Retained",
+		"description": "Publish as retained messages and clear them when the entity expires",
+		"default":     false,
+		"ui:order":    1,
+	}
+	egressSchema, _ := structpb.NewStruct(map[string]any{
+		"type":       "object",
+		"properties": egressProperties,
+		"required":   []any{"broker"},
+	})
+
+	serviceEntityID := controllerName + ".service"
+	name := controllerName
+	if err := controller.Push(ctx, &pb.Entity{
+		Id:    serviceEntityID,
+		Label: proto.String("MQTT"),
+		Controller: &pb.Controller{
+			Id: &name,
+		},
+		Device: &pb.DeviceComponent{
+			Category: proto.String("Network"),
+		},
+		Configurable: &pb.ConfigurableComponent{
+			SupportedDeviceClasses: []*pb.DeviceClassOption{
+				{Class: "ingest", Label: "Ingest"},
+				{Class: "egress", Label: "Egress"},
+			},
+		},
+		Interactivity: &pb.InteractivityComponent{
+			Icon: proto.String("network"),
+		},
+	}); err != nil {
+		return fmt.Errorf("push service entity: %w", err)
+	}
+
+	classes := []controller.DeviceClass{
+		{Class: "ingest", Label: "Ingest", Schema: ingestSchema},
+		{Class: "egress", Label: "Egress", Schema: egressSchema},
+	}
+
+	return controller.WatchChildren(ctx, serviceEntityID, controllerName, classes, func(ctx context.Context, entityID string) error {
+		return controller.Run(ctx, entityID, func(ctx context.Context, entity *pb.Entity, ready func()) error {
+			switch entity.Device.GetClass() {
+			case "ingest":
+				return runIngest(ctx, logger, entity, ready)
+			case "egress":
+				return runEgress(ctx, logger, entity, ready)
+			}
+			return fmt.Errorf("unknown device class: %s", entity.Device.GetClass())
+		}, controller.WithExpireOnTeardownConfig(controllerName))
+	})
+}
+
+func runIngest(ctx context.Context, logger *slog.Logger, entity *pb.Entity, ready func()) error {
+	config, err := parseIngestConfig(entity)
+	if err != nil {
+		return err
+	}
+
+	b, err := dialBroker(&config.BrokerConfig, logger)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	grpcConn, err := builtin.BuiltinClientConn()
+	if err != nil {
+		return fmt.Errorf("gRPC connection: %w", err)
+	}
+	defer func() { _ = grpcConn.Close() }()
+
+	worldClient := pb.NewWorldServiceClient(grpcConn)
+	ready()
+
+	return ingest(ctx, logger, b, config, func(ctx context.Context, entities []*pb.Entity) error {
+		_, err := worldClient.Push(ctx, &pb.EntityChangeRequest{Changes: entities})
+		return err
+	})
+}
+
+// ingest subscribes to the configured topic and pushes the entities mapped
+// from incoming messages until ctx is cancelled. Messages that arrive while
+// a push is in flight are pushed together in the next one.
+func ingest(ctx context.Context, logger *slog.Logger, b broker, config *IngestConfig, push func(context.Context, []*pb.Entity) error) error {
+	entities := make(chan *pb.Entity, 256)
+
+	err := b.Subscribe(config.Topic, func(topic string, payload []byte) {
+		entity, err := mapMessage(topic, payload, config)
+		if err != nil {
+			logger.Debug("skipping MQTT message", "topic", topic, "error", err)
+			return
+		}
+		select {
+		case entities <- entity:
+		case <-ctx.Done():
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		var batch []*pb.Entity
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case entity := <-entities:
+			batch = append(batch, entity)
+		}
+	drain:
+		for {
+			select {
+			case entity := <-entities:
+				batch = append(batch, entity)
+			default:
+				break drain
+			}
+		}
+
+		if err := push(ctx, batch); err != nil {
+			return fmt.Errorf("push entities: %w", err)
+		}
+	}
+}
+
+func runEgress(ctx context.Context, logger *slog.Logger, entity *pb.Entity, ready func()) error {
+	config, err := parseEgressConfig(entity)
+	if err != nil {
+		return err
+	}
+
+	b, err := dialBroker(&config.BrokerConfig, logger)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	grpcConn, err := builtin.BuiltinClientConn()
+	if err != nil {
+		return fmt.Errorf("gRPC connection: %w", err)
+	}
+	defer func() { _ = grpcConn.Close() }()
+
+	worldClient := pb.NewWorldServiceClient(grpcConn)
+	stream, err := goclient.WatchEntitiesWithRetry(ctx, worldClient, &pb.ListEntitiesRequest{})
+	if err != nil {
+		return err
+	}
+	ready()
+
+	return egress(logger, b, config, stream.Recv)
+}
+
+// egress publishes every entity update as protojson. Entities ingested from
+// MQTT are skipped so that overlapping ingest and egress topics don't loop.
+func egress(logger *slog.Logger, b broker, config *EgressConfig, recv func() (*pb.EntityChangeEvent, error)) error {
+	for {
+		event, err := recv()
+		if err != nil {
+			return err
+		}
+		if event.Entity == nil || event.Entity.Controller.GetId() == controllerName {
+			continue
+		}
+
+		topic := strings.ReplaceAll(config.Topic, "{id}", event.Entity.Id)
+
+		var payload []byte
+		switch event.T {
+		case pb.EntityChange_EntityChangeUpdated:
+			payload, err = protojson.Marshal(event.Entity)
+			if err != nil {
+				logger.Error("Error encoding entity", "entityID", event.Entity.Id, "error", err)
+				continue
+			}
+		case pb.EntityChange_EntityChangeExpired, pb.EntityChange_EntityChangeUnobserved:
+			// An empty retained message deletes the retained one.
+			if !config.Retained {
+				continue
+			}
+		default:
+			continue
+		}
+
+		if err := b.Publish(topic, payload, config.Retained); err != nil {
+			return err
+		}
+	}
+}
+
+func parseBrokerConfig(entity *pb.Entity) (BrokerConfig, error) {
+	config := BrokerConfig{ClientID: "hydris-" + entity.Id}
+	if entity.Config == nil || entity.Config.Value == nil || entity.Config.Value.Fields == nil {
+		return config, fmt.Errorf("broker field is required")
+	}
+
+	fields := entity.Config.Value.Fields
+	if v, ok := fields["broker"]; ok {
+		config.URL = v.GetStringValue()
+	}
+	if config.URL == "" {
+		return config, fmt.Errorf("broker field is required")
+	}
+	if v, ok := fields["username"]; ok {
+		config.Username = v.GetStringValue()
+	}
+	if v, ok := fields["password"]; ok {
+		config.Password = v.GetStringValue()
+	}
+	if v, ok := fields["qos"]; ok {
+		qos := v.GetNumberValue()
+		if qos < 0 || qos > 2 {
+			return config, fmt.Errorf("qos %g is out of range [0, 2]", qos)
+		}
+		config.QoS = byte(qos)
+	}
+	return config, nil
+}
+
+func parseIngestConfig(entity *pb.Entity) (*IngestConfig, error) {
+	brokerConfig, err := parseBrokerConfig(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &IngestConfig{
+		BrokerConfig: brokerConfig,
+		EntityID:     entity.Id,
+		IDPrefix:     "mqtt.",
+		Mapping: Mapping{
+			ID:        "id",
+			Latitude:  "lat",
+			Longitude: "lon",
+			Altitude:  "alt",
+		},
+	}
+
+	fields := entity.Config.Value.Fields
+	if v, ok := fields["topic"]; ok {
+		config.Topic = v.GetStringValue()
+	}
+	if config.Topic == "" {
+		return nil, fmt.Errorf("topic field is required")
+	}
+	stringField := func(key string, dst *string) {
+		if v, ok := fields[key]; ok && v.GetStringValue() != "" {
+			*dst = v.GetStringValue()
+		}
+	}
+	stringField("id_field", &config.Mapping.ID)
+	stringField("label_field", &config.Mapping.Label)
+	stringField("latitude_field", &config.Mapping.Latitude)
+	stringField("longitude_field", &config.Mapping.Longitude)
+	stringField("altitude_field", &config.Mapping.Altitude)
+	if strings.Contains(config.Mapping.ID, "{") {
+		if _, err := idtemplate.Parse(config.Mapping.ID); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := fields["id_prefix"]; ok {
+		config.IDPrefix = v.GetStringValue()
+	}
+	if v, ok := fields["ttl_seconds"]; ok {
+		config.TTLSeconds = int(v.GetNumberValue())
+	}
+	return config, nil
+}
+
+func parseEgressConfig(entity *pb.Entity) (*EgressConfig, error) {
+	brokerConfig, err := parseBrokerConfig(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &EgressConfig{
+		BrokerConfig: brokerConfig,
+		Topic:        "hydris/entities/{id}",
+	}
+
+	fields := entity.Config.Value.Fields
+	if v, ok := fields["topic"]; ok && v.GetStringValue() != "" {
+		config.Topic = v.GetStringValue()
+	}
+	if strings.ContainsAny(config.Topic, "+#") {
+		return nil, fmt.Errorf("topic %q must not contain wildcards", config.Topic)
+	}
+	if v, ok := fields["retained"]; ok {
+		config.Retained = v.GetBoolValue()
+	}
+	return config, nil
+}
+
+func init() {
+	builtin.Register("mqtt", Run)
+}
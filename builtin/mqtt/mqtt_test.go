@@ -0,0 +1,205 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+type published struct {
+	topic    string
+	payload  []byte
+	retained bool
+}
+
+// fakeBroker delivers messages to its subscribers in process.
+type fakeBroker struct {
+	mu        sync.Mutex
+	filter    string
+	handle    func(topic string, payload []byte)
+	published []published
+}
+
+func (b *fakeBroker) Subscribe(topic string, handle func(string, []byte)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.filter, b.handle = topic, handle
+	return nil
+}
+
+func (b *fakeBroker) Publish(topic string, payload []byte, retained bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.published = append(b.published, published{topic, payload, retained})
+	return nil
+}
+
+func (b *fakeBroker) Close() {}
+
+func (b *fakeBroker) deliver(topic, payload string) {
+	b.mu.Lock()
+	handle := b.handle
+	b.mu.Unlock()
+	handle(topic, []byte(payload))
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testIngestConfig() *IngestConfig {
+	return &IngestConfig{
+		EntityID: "mqtt.sensors",
+		Topic:    "sensors/+/position",
+		IDPrefix: "mqtt.",
+		Mapping: Mapping{
+			ID:        "$topic.1",
+			Label:     "name",
+			Latitude:  "pos.lat",
+			Longitude: "pos.lon",
+			Altitude:  "alt",
+		},
+	}
+}
+
+func TestMapMessage(t *testing.T) {
+	config := testIngestConfig()
+
+	entity, err := mapMessage("sensors/buoy-7/position", []byte(`{"name":"Buoy 7","pos":{"lat":"54.1","lon":10.5},"alt":2}`), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entity.Id != "mqtt.buoy-7" || entity.GetLabel() != "Buoy 7" {
+		t.Errorf("got id %q label %q", entity.Id, entity.GetLabel())
+	}
+	if entity.Geo.Latitude != 54.1 || entity.Geo.Longitude != 10.5 || entity.Geo.GetAltitude() != 2 {
+		t.Errorf("got geo %v", entity.Geo)
+	}
+	if entity.Controller.GetId() != "mqtt" || entity.Track.GetTracker() != "mqtt.sensors" {
+		t.Errorf("got controller %v track %v", entity.Controller, entity.Track)
+	}
+	if entity.Lifetime.Until.AsTime().Sub(time.Now()) < 50*time.Second {
+		t.Errorf("expected default ttl, got until %v", entity.Lifetime.Until.AsTime())
+	}
+
+	for name, payload := range map[string]string{
+		"not json":     `lat=1`,
+		"no position":  `{"name":"x"}`,
+		"out of range": `{"pos":{"lat":91,"lon":0}}`,
+		"nan":          `{"pos":{"lat":"NaN","lon":0}}`,
+	} {
+		if _, err := mapMessage("sensors/x/position", []byte(payload), config); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	config.Mapping.ID = "$topic.9"
+	if _, err := mapMessage("sensors/x/position", []byte(`{"pos":{"lat":1,"lon":1}}`), config); err == nil {
+		t.Error("missing topic segment should be rejected")
+	}
+}
+
+func TestMapMessage_IDTemplate(t *testing.T) {
+	config := testIngestConfig()
+	config.Mapping.ID = "{$topic.1}.{device.serial}"
+
+	entity, err := mapMessage("sensors/pier north/position", []byte(`{"device":{"serial":"A/17"},"pos":{"lat":1,"lon":1}}`), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entity.Id != "mqtt.pier_north.A_17" {
+		t.Errorf("got id %q", entity.Id)
+	}
+
+	if _, err := mapMessage("sensors/pier/position", []byte(`{"pos":{"lat":1,"lon":1}}`), config); err == nil {
+		t.Error("message without the serial should be rejected")
+	}
+}
+
+func TestIngest(t *testing.T) {
+	b := &fakeBroker{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pushed := make(chan []*pb.Entity, 4)
+	done := make(chan error, 1)
+	go func() {
+		done <- ingest(ctx, discardLogger(), b, testIngestConfig(), func(_ context.Context, entities []*pb.Entity) error {
+			pushed <- entities
+			return nil
+		})
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		b.mu.Lock()
+		subscribed := b.handle != nil
+		b.mu.Unlock()
+		if subscribed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("ingest did not subscribe")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if b.filter != "sensors/+/position" {
+		t.Errorf("subscribed to %q", b.filter)
+	}
+
+	b.deliver("sensors/a/position", `{"garbage":true}`)
+	b.deliver("sensors/a/position", `{"pos":{"lat":1,"lon":2}}`)
+
+	select {
+	case entities := <-pushed:
+		if len(entities) != 1 || entities[0].Id != "mqtt.a" {
+			t.Errorf("pushed %v", entities)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("nothing pushed")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("ingest returned %v", err)
+	}
+}
+
+func TestEgress(t *testing.T) {
+	events := []*pb.EntityChangeEvent{
+		{T: pb.EntityChange_EntityChangeUpdated, Entity: &pb.Entity{Id: "ship1", Label: proto.String("Ship")}},
+		{T: pb.EntityChange_EntityChangeUpdated, Entity: &pb.Entity{Id: "mqtt.a", Controller: &pb.Controller{Id: proto.String("mqtt")}}},
+		{T: pb.EntityChange_EntityChangeExpired, Entity: &pb.Entity{Id: "ship1"}},
+	}
+	recv := func() (*pb.EntityChangeEvent, error) {
+		if len(events) == 0 {
+			return nil, io.EOF
+		}
+		event := events[0]
+		events = events[1:]
+		return event, nil
+	}
+
+	b := &fakeBroker{}
+	config := &EgressConfig{Topic: "hydris/{id}", Retained: true}
+	if err := egress(discardLogger(), b, config, recv); !errors.Is(err, io.EOF) {
+		t.Fatalf("egress returned %v", err)
+	}
+
+	if len(b.published) != 2 {
+		t.Fatalf("expected update and clear, got %v", b.published)
+	}
+	if p := b.published[0]; p.topic != "hydris/ship1" || !p.retained || len(p.payload) == 0 {
+		t.Errorf("update published as %+v", p)
+	}
+	if p := b.published[1]; p.topic != "hydris/ship1" || len(p.payload) != 0 {
+		t.Errorf("expiry published as %+v", p)
+	}
+}
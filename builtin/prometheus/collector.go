@@ -0,0 +1,106 @@
+package prometheus
+
+import (
+	"strconv"
+
+	"github.com/projectqai/hydris/pkg/metrics"
+	promclient "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	entitiesByControllerDesc = promclient.NewDesc(
+		"hydris_entities",
+		"Number of entities held, by controller.",
+		[]string{"controller"}, nil)
+	entitiesByComponentDesc = promclient.NewDesc(
+		"hydris_entity_components",
+		"Number of entities carrying a component.",
+		[]string{"component"}, nil)
+	consumerQueueDepthDesc = promclient.NewDesc(
+		"hydris_consumer_queue_depth",
+		"Changes waiting to be sent to a WatchEntities consumer.",
+		[]string{"consumer"}, nil)
+	consumerFilterEvaluationsDesc = promclient.NewDesc(
+		"hydris_consumer_filter_evaluations_total",
+		"Entities a WatchEntities consumer's filter was evaluated against.",
+		[]string{"consumer"}, nil)
+	consumerFilterMatchesDesc = promclient.NewDesc(
+		"hydris_consumer_filter_matches_total",
+		"Entities a WatchEntities consumer's filter matched.",
+		[]string{"consumer"}, nil)
+	consumerFilterDurationDesc = promclient.NewDesc(
+		"hydris_consumer_filter_seconds_total",
+		"Time spent evaluating a WatchEntities consumer's filter.",
+		[]string{"consumer"}, nil)
+	entitiesPushedDesc = promclient.NewDesc(
+		"hydris_entities_pushed_total",
+		"Entities accepted by Push.",
+		nil, nil)
+	entitiesExpiredDesc = promclient.NewDesc(
+		"hydris_entities_expired_total",
+		"Entities removed by the world GC.",
+		nil, nil)
+	gcRunsDesc = promclient.NewDesc(
+		"hydris_gc_runs_total",
+		"World GC sweeps.",
+		nil, nil)
+	gcDurationDesc = promclient.NewDesc(
+		"hydris_gc_duration_seconds_total",
+		"Time spent in world GC sweeps.",
+		nil, nil)
+	gcLastDurationDesc = promclient.NewDesc(
+		"hydris_gc_last_duration_seconds",
+		"Duration of the most recent world GC sweep.",
+		nil, nil)
+)
+
+// worldCollector turns engine stats into Prometheus metrics at scrape time.
+type worldCollector struct {
+	read func() (metrics.Stats, bool)
+}
+
+func newWorldCollector(read func() (metrics.Stats, bool)) *worldCollector {
+	return &worldCollector{read: read}
+}
+
+func (c *worldCollector) Describe(ch chan<- *promclient.Desc) {
+	ch <- entitiesByControllerDesc
+	ch <- entitiesByComponentDesc
+	ch <- consumerQueueDepthDesc
+	ch <- consumerFilterEvaluationsDesc
+	ch <- consumerFilterMatchesDesc
+	ch <- consumerFilterDurationDesc
+	ch <- entitiesPushedDesc
+	ch <- entitiesExpiredDesc
+	ch <- gcRunsDesc
+	ch <- gcDurationDesc
+	ch <- gcLastDurationDesc
+}
+
+func (c *worldCollector) Collect(ch chan<- promclient.Metric) {
+	stats, ok := c.read()
+	if !ok {
+		return
+	}
+
+	for controller, n := range stats.EntitiesByController {
+		ch <- promclient.MustNewConstMetric(entitiesByControllerDesc, promclient.GaugeValue, float64(n), controller)
+	}
+	for component, n := range stats.EntitiesByComponent {
+		ch <- promclient.MustNewConstMetric(entitiesByComponentDesc, promclient.GaugeValue, float64(n), component)
+	}
+	for id, depth := range stats.ConsumerQueueDepths {
+		ch <- promclient.MustNewConstMetric(consumerQueueDepthDesc, promclient.GaugeValue, float64(depth), strconv.FormatUint(id, 10))
+	}
+	for id, f := range stats.ConsumerFilters {
+		consumer := strconv.FormatUint(id, 10)
+		ch <- promclient.MustNewConstMetric(consumerFilterEvaluationsDesc, promclient.CounterValue, float64(f.Evaluations), consumer)
+		ch <- promclient.MustNewConstMetric(consumerFilterMatchesDesc, promclient.CounterValue, float64(f.Matches), consumer)
+		ch <- promclient.MustNewConstMetric(consumerFilterDurationDesc, promclient.CounterValue, f.Duration.Seconds(), consumer)
+	}
+	ch <- promclient.MustNewConstMetric(entitiesPushedDesc, promclient.CounterValue, float64(stats.EntitiesPushed))
+	ch <- promclient.MustNewConstMetric(entitiesExpiredDesc, promclient.CounterValue, float64(stats.EntitiesExpired))
+	ch <- promclient.MustNewConstMetric(gcRunsDesc, promclient.CounterValue, float64(stats.GCRuns))
+	ch <- promclient.MustNewConstMetric(gcDurationDesc, promclient.CounterValue, stats.GCDuration.Seconds())
+	ch <- promclient.MustNewConstMetric(gcLastDurationDesc, promclient.GaugeValue, stats.GCLastDuration.Seconds())
+}
@@ -0,0 +1,119 @@
+// Package prometheus serves world metrics for Prometheus on a separate
+// listen address, so they can be scraped without exposing the engine API.
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/projectqai/hydris/builtin"
+	"github.com/projectqai/hydris/builtin/controller"
+	"github.com/projectqai/hydris/pkg/metrics"
+	pb "github.com/projectqai/proto/go"
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const defaultListenAddress = "127.0.0.1:9464"
+
+func init() {
+	builtin.Register("prometheus", Run)
+}
+
+func Run(ctx context.Context, logger *slog.Logger, _ string) error {
+	controllerName := "prometheus"
+
+	schema, _ := structpb.NewStruct(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"listen_address": map[string]any{
+				"type":           "string",
+				"title":          "Listen Address",
+				"description":    "Address to serve /metrics on",
+				"default":        defaultListenAddress,
+				"ui:placeholder": "e.g. :9464",
+				"ui:order":       0,
+			},
+		},
+	})
+
+	if err := controller.Push(ctx, &pb.Entity{
+		Id:    "prometheus.service",
+		Label: proto.String("Prometheus Exporter"),
+		Controller: &pb.Controller{
+			Id: &controllerName,
+		},
+		Device: &pb.DeviceComponent{
+			Category: proto.String("Network"),
+		},
+		Configurable: &pb.ConfigurableComponent{
+			Label:  proto.String("Prometheus Exporter"),
+			Schema: schema,
+		},
+		Interactivity: &pb.InteractivityComponent{
+			Icon: proto.String("network"),
+		},
+	}); err != nil {
+		return fmt.Errorf("push service entity: %w", err)
+	}
+
+	return controller.Run(ctx, "prometheus.service", func(ctx context.Context, entity *pb.Entity, ready func()) error {
+		addr := defaultListenAddress
+		if entity.Config != nil && entity.Config.Value != nil {
+			if v, ok := entity.Config.Value.Fields["listen_address"]; ok && v.GetStringValue() != "" {
+				addr = v.GetStringValue()
+			}
+		}
+
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("listen on %s: %w", addr, err)
+		}
+		logger.Info("prometheus exporter listening", "addr", listener.Addr().String())
+		ready()
+
+		return serve(ctx, listener, newHandler(metrics.ReadStats))
+	})
+}
+
+// newHandler returns a mux serving /metrics with the world stats from read
+// and the Go runtime metrics, including GC pause timings.
+func newHandler(read func() (metrics.Stats, bool)) http.Handler {
+	registry := promclient.NewRegistry()
+	registry.MustRegister(
+		newWorldCollector(read),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+func serve(ctx context.Context, listener net.Listener, handler http.Handler) error {
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return ctx.Err()
+}
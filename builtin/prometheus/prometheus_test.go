@@ -0,0 +1,80 @@
+package prometheus
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/projectqai/hydris/pkg/metrics"
+)
+
+func TestHandler_ExposesWorldMetrics(t *testing.T) {
+	read := func() (metrics.Stats, bool) {
+		return metrics.Stats{
+			EntitiesByController: map[string]int{"adsblol": 3, "tak": 1},
+			EntitiesByComponent:  map[string]int{"geo": 4},
+			ConsumerQueueDepths:  map[uint64]int{7: 12},
+			ConsumerFilters:      map[uint64]metrics.FilterStats{7: {Evaluations: 40, Matches: 3, Duration: 500 * time.Millisecond}},
+			EntitiesPushed:       100,
+			EntitiesExpired:      5,
+			GCRuns:               60,
+			GCDuration:           250 * time.Millisecond,
+			GCLastDuration:       20 * time.Millisecond,
+		}, true
+	}
+
+	srv := httptest.NewServer(newHandler(read))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`hydris_entities{controller="adsblol"} 3`,
+		`hydris_entities{controller="tak"} 1`,
+		`hydris_entity_components{component="geo"} 4`,
+		`hydris_consumer_queue_depth{consumer="7"} 12`,
+		`hydris_consumer_filter_evaluations_total{consumer="7"} 40`,
+		`hydris_consumer_filter_matches_total{consumer="7"} 3`,
+		`hydris_consumer_filter_seconds_total{consumer="7"} 0.5`,
+		`hydris_entities_pushed_total 100`,
+		`hydris_entities_expired_total 5`,
+		`hydris_gc_runs_total 60`,
+		`hydris_gc_duration_seconds_total 0.25`,
+		`hydris_gc_last_duration_seconds 0.02`,
+		`go_gc_duration_seconds`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("missing %q in scrape", want)
+		}
+	}
+}
+
+func TestHandler_NoEngine(t *testing.T) {
+	srv := httptest.NewServer(newHandler(func() (metrics.Stats, bool) { return metrics.Stats{}, false }))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	if strings.Contains(string(body), "hydris_") {
+		t.Error("world metrics should be absent without an engine")
+	}
+}
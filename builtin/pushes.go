@@ -0,0 +1,42 @@
+package builtin
+
+import (
+	"context"
+	"strings"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/grpc"
+)
+
+// pushObserverKey is the context key of the observer set by
+// WithPushObserver.
+type pushObserverKey struct{}
+
+// WithPushObserver returns ctx carrying fn. Once a push made with ctx, or
+// a context derived from it, over a connection from BuiltinClientConn or
+// ServerConn succeeds, fn is called with the ids of the pushed entities.
+func WithPushObserver(ctx context.Context, fn func(ids []string)) context.Context {
+	return context.WithValue(ctx, pushObserverKey{}, fn)
+}
+
+// observePushes is the unary interceptor of builtin connections that
+// reports successful pushes to the observer of the call's context.
+func observePushes(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if err := invoker(ctx, method, req, reply, cc, opts...); err != nil {
+		return err
+	}
+	fn, ok := ctx.Value(pushObserverKey{}).(func([]string))
+	change, isPush := req.(*pb.EntityChangeRequest)
+	if !ok || !isPush || !strings.HasSuffix(method, "/Push") {
+		return nil
+	}
+	ids := make([]string, 0, len(change.Changes)+len(change.Replacements))
+	for _, e := range change.Changes {
+		ids = append(ids, e.GetId())
+	}
+	for _, e := range change.Replacements {
+		ids = append(ids, e.GetId())
+	}
+	fn(ids)
+	return nil
+}
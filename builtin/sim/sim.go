@@ -0,0 +1,175 @@
+// Package sim is a builtin that generates synthetic entities, to load test
+// the engine and to have something moving on the map in demos.
+package sim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/projectqai/hydris/builtin"
+	"github.com/projectqai/hydris/builtin/controller"
+	"github.com/projectqai/hydris/goclient"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	controllerName = "sim"
+	tracksClass    = "sim.tracks.v0"
+)
+
+func init() {
+	builtin.Register(controllerName, Run)
+}
+
+func Run(ctx context.Context, logger *slog.Logger, _ string) error {
+	d := defaultTracksConfig()
+	tracksSchema, _ := structpb.NewStruct(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"count": map[string]any{
+				"type":        "integer",
+				"title":       "Tracks",
+				"description": "Number of simulated tracks",
+				"default":     d.Count,
+				"minimum":     1,
+				"ui:order":    0,
+			},
+			"latitude": map[string]any{
+				"type":     "number",
+				"title":    "Latitude",
+				"default":  d.Latitude,
+				"ui:order": 1,
+			},
+			"longitude": map[string]any{
+				"type":     "number",
+				"title":    "Longitude",
+				"default":  d.Longitude,
+				"ui:order": 2,
+			},
+			"radius_km": map[string]any{
+				"type":        "number",
+				"title":       "Radius",
+				"description": "Tracks stay within this distance of the center",
+				"default":     d.RadiusKM,
+				"minimum":     0.1,
+				"ui:unit":     "km",
+				"ui:order":    3,
+			},
+			"update_rate_hz": map[string]any{
+				"type":        "number",
+				"title":       "Update Rate",
+				"description": "Position updates per track per second",
+				"default":     d.UpdateRateHz,
+				"minimum":     0.01,
+				"ui:unit":     "Hz",
+				"ui:order":    4,
+			},
+			"speed": map[string]any{
+				"type":        "number",
+				"title":       "Speed",
+				"description": "Mean track speed",
+				"default":     d.Speed,
+				"minimum":     0,
+				"ui:unit":     "m/s",
+				"ui:order":    5,
+			},
+		},
+	})
+
+	serviceEntityID := controllerName + ".service"
+	if err := controller.Push(ctx, &pb.Entity{
+		Id:    serviceEntityID,
+		Label: proto.String("Simulator"),
+		Controller: &pb.Controller{
+			Id: proto.String(controllerName),
+		},
+		Device: &pb.DeviceComponent{
+			Category: proto.String("Feeds"),
+			State:    pb.DeviceState_DeviceStateActive,
+		},
+		Configurable: &pb.ConfigurableComponent{
+			SupportedDeviceClasses: []*pb.DeviceClassOption{
+				{Class: tracksClass, Label: "Simulated Tracks"},
+			},
+		},
+		Interactivity: &pb.InteractivityComponent{
+			Icon: proto.String("shuffle"),
+		},
+	}); err != nil {
+		return fmt.Errorf("push service entity: %w", err)
+	}
+
+	classes := []controller.DeviceClass{
+		{Class: tracksClass, Label: "Simulated Tracks", Schema: tracksSchema},
+	}
+
+	return controller.WatchChildren(ctx, serviceEntityID, controllerName, classes, func(ctx context.Context, entityID string) error {
+		return controller.Run(ctx, entityID, func(ctx context.Context, entity *pb.Entity, ready func()) error {
+			ready()
+			return runTracks(ctx, logger, entity)
+		})
+	})
+}
+
+// runTracks moves the configured tracks until ctx is done, then expires
+// them.
+func runTracks(ctx context.Context, logger *slog.Logger, entity *pb.Entity) error {
+	cfg := defaultTracksConfig()
+	if entity.Config != nil && entity.Config.Value != nil {
+		b, _ := entity.Config.Value.MarshalJSON()
+		_ = json.Unmarshal(b, &cfg)
+	}
+	cfg.normalize()
+
+	grpcConn, err := builtin.BuiltinClientConn()
+	if err != nil {
+		return fmt.Errorf("grpc connect: %w", err)
+	}
+	defer func() { _ = grpcConn.Close() }()
+
+	world := goclient.NewWorld(grpcConn)
+	s := newSwarm(entity.Id+".track", cfg, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+
+	defer func() {
+		expireCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		for _, t := range s.tracks {
+			if err := world.Expire(expireCtx, t.id); err != nil {
+				logger.Warn("failed to expire simulated track", "id", t.id, "error", err)
+				return
+			}
+		}
+	}()
+
+	logger.Info("Starting simulated tracks",
+		"entityID", entity.Id,
+		"count", cfg.Count,
+		"lat", cfg.Latitude, "lon", cfg.Longitude,
+		"radius_km", cfg.RadiusKM,
+		"rate_hz", cfg.UpdateRateHz,
+	)
+
+	interval := time.Duration(float64(time.Second) / cfg.UpdateRateHz)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		if err := world.PushEntities(ctx, s.entities(last)...); err != nil && ctx.Err() == nil {
+			logger.Error("failed to push simulated tracks", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			s.step(now.Sub(last))
+			last = now
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	earthRadiusM = 6378137.0
+	trackSIDC    = "SUGP------*****"
+)
+
+// TracksConfig configures a sim.tracks.v0 instance.
+type TracksConfig struct {
+	Count        int     `json:"count"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	RadiusKM     float64 `json:"radius_km"`
+	UpdateRateHz float64 `json:"update_rate_hz"`
+	Speed        float64 `json:"speed"` // m/s
+}
+
+func defaultTracksConfig() TracksConfig {
+	return TracksConfig{
+		Count:        100,
+		Latitude:     52.52,
+		Longitude:    13.405,
+		RadiusKM:     10,
+		UpdateRateHz: 1,
+		Speed:        15,
+	}
+}
+
+// normalize replaces out of range values with the defaults.
+func (c *TracksConfig) normalize() {
+	d := defaultTracksConfig()
+	if c.Count <= 0 {
+		c.Count = d.Count
+	}
+	if c.RadiusKM <= 0 {
+		c.RadiusKM = d.RadiusKM
+	}
+	if c.UpdateRateHz <= 0 {
+		c.UpdateRateHz = d.UpdateRateHz
+	}
+	if c.Speed < 0 {
+		c.Speed = d.Speed
+	}
+}
+
+// swarm is a set of tracks moving at constant speed inside a circle around
+// the configured center. Positions are kept in meters east and north of the
+// center; a track reaching the edge turns back inside.
+type swarm struct {
+	prefix string
+	cfg    TracksConfig
+	rng    *rand.Rand
+	tracks []simTrack
+}
+
+type simTrack struct {
+	id      string
+	x, y    float64 // m east, north of the center
+	heading float64 // radians clockwise from north
+	speed   float64 // m/s
+}
+
+func newSwarm(prefix string, cfg TracksConfig, rng *rand.Rand) *swarm {
+	s := &swarm{prefix: prefix, cfg: cfg, rng: rng, tracks: make([]simTrack, cfg.Count)}
+	radius := cfg.RadiusKM * 1000
+	for i := range s.tracks {
+		// Uniform over the disc.
+		r := radius * math.Sqrt(rng.Float64())
+		a := rng.Float64() * 2 * math.Pi
+		s.tracks[i] = simTrack{
+			id:      fmt.Sprintf("%s.%d", prefix, i),
+			x:       r * math.Sin(a),
+			y:       r * math.Cos(a),
+			heading: rng.Float64() * 2 * math.Pi,
+			// Vary speeds by ±50% so tracks don't move in lockstep.
+			speed: cfg.Speed * (0.5 + rng.Float64()),
+		}
+	}
+	return s
+}
+
+// step advances every track by dt and lets it wander a little.
+func (s *swarm) step(dt time.Duration) {
+	radius := s.cfg.RadiusKM * 1000
+	secs := dt.Seconds()
+	for i := range s.tracks {
+		t := &s.tracks[i]
+		t.heading += (s.rng.Float64() - 0.5) * 0.2 * secs
+		x := t.x + t.speed*secs*math.Sin(t.heading)
+		y := t.y + t.speed*secs*math.Cos(t.heading)
+		if math.Hypot(x, y) > radius {
+			// Head back towards the center, give or take.
+			t.heading = math.Atan2(-t.x, -t.y) + (s.rng.Float64()-0.5)*math.Pi/2
+			continue
+		}
+		t.x, t.y = x, y
+	}
+}
+
+// entities returns the current state of every track. Each one lives for a
+// few update intervals, so tracks left behind by a crash expire by
+// themselves.
+func (s *swarm) entities(now time.Time) []*pb.Entity {
+	ttl := time.Duration(5 / s.cfg.UpdateRateHz * float64(time.Second))
+	fresh := timestamppb.New(now)
+	until := timestamppb.New(now.Add(ttl))
+	cosLat := math.Cos(s.cfg.Latitude * math.Pi / 180)
+
+	out := make([]*pb.Entity, len(s.tracks))
+	for i, t := range s.tracks {
+		out[i] = &pb.Entity{
+			Id:    t.id,
+			Label: proto.String(fmt.Sprintf("SIM %d", i)),
+			Controller: &pb.Controller{
+				Id: proto.String(controllerName),
+			},
+			Lifetime: &pb.Lifetime{Fresh: fresh, Until: until},
+			Geo: &pb.GeoSpatialComponent{
+				Latitude:  s.cfg.Latitude + t.y/earthRadiusM*180/math.Pi,
+				Longitude: s.cfg.Longitude + t.x/(earthRadiusM*cosLat)*180/math.Pi,
+			},
+			Kinematics: &pb.KinematicsComponent{
+				VelocityEnu: &pb.KinematicsEnu{
+					East:  proto.Float64(t.speed * math.Sin(t.heading)),
+					North: proto.Float64(t.speed * math.Cos(t.heading)),
+				},
+			},
+			Symbol: &pb.SymbolComponent{MilStd2525C: trackSIDC},
+		}
+	}
+	return out
+}
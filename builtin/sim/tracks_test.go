@@ -0,0 +1,77 @@
+package sim
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+// distanceM is the distance in meters of a position from the center of
+// cfg, good enough at the scales of a simulation area.
+func distanceM(cfg TracksConfig, lat, lon float64) float64 {
+	north := (lat - cfg.Latitude) * math.Pi / 180 * earthRadiusM
+	east := (lon - cfg.Longitude) * math.Pi / 180 * earthRadiusM * math.Cos(cfg.Latitude*math.Pi/180)
+	return math.Hypot(east, north)
+}
+
+func TestSwarm_StaysInArea(t *testing.T) {
+	cfg := TracksConfig{Count: 50, Latitude: 48.1, Longitude: 11.5, RadiusKM: 2, UpdateRateHz: 2, Speed: 40}
+	s := newSwarm("sim.test.track", cfg, rand.New(rand.NewPCG(1, 2)))
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	first := s.entities(start)
+	if len(first) != cfg.Count {
+		t.Fatalf("%d entities, want %d", len(first), cfg.Count)
+	}
+	ids := make(map[string]bool)
+	for _, e := range first {
+		ids[e.Id] = true
+	}
+	if len(ids) != cfg.Count {
+		t.Fatalf("%d distinct ids, want %d", len(ids), cfg.Count)
+	}
+
+	// Ten minutes at 40 m/s is far more than the area is wide, so every
+	// track has hit the edge a few times.
+	now := start
+	for range 1200 {
+		s.step(500 * time.Millisecond)
+		now = now.Add(500 * time.Millisecond)
+		for _, e := range s.entities(now) {
+			if d := distanceM(cfg, e.Geo.Latitude, e.Geo.Longitude); d > cfg.RadiusKM*1000+1 {
+				t.Fatalf("%s is %.0f m from the center, outside %.0f km", e.Id, d, cfg.RadiusKM)
+			}
+		}
+	}
+
+	last := s.entities(now)
+	moved := 0
+	for i, e := range last {
+		if e.Id != first[i].Id {
+			t.Fatalf("track %d changed id from %s to %s", i, first[i].Id, e.Id)
+		}
+		if e.Geo.Latitude != first[i].Geo.Latitude || e.Geo.Longitude != first[i].Geo.Longitude {
+			moved++
+		}
+		v := e.Kinematics.VelocityEnu
+		if speed := math.Hypot(v.GetEast(), v.GetNorth()); speed < cfg.Speed*0.5 || speed > cfg.Speed*1.5 {
+			t.Errorf("%s moves at %.1f m/s, want within 50%% of %.0f", e.Id, speed, cfg.Speed)
+		}
+		if !e.Lifetime.Until.AsTime().After(now) {
+			t.Errorf("%s is already expired", e.Id)
+		}
+	}
+	if moved != cfg.Count {
+		t.Errorf("%d of %d tracks moved", moved, cfg.Count)
+	}
+}
+
+func TestTracksConfig_Normalize(t *testing.T) {
+	d := defaultTracksConfig()
+	cfg := TracksConfig{Latitude: d.Latitude, Longitude: d.Longitude, Speed: -1}
+	cfg.normalize()
+	if cfg != defaultTracksConfig() {
+		t.Errorf("normalized %+v, want the defaults", cfg)
+	}
+}
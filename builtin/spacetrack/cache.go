@@ -0,0 +1,98 @@
+package spacetrack
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/akhenakh/sgp4"
+)
+
+// defaultCacheMaxAge is how old cached TLEs may be to be used when the
+// source can't be fetched. SGP4 errors grow by about 1.5 km a day.
+const defaultCacheMaxAge = 72 * time.Hour
+
+// defaultCacheFile returns where the TLEs of a tracker are cached unless
+// configured, or "" if there is no user cache directory.
+func defaultCacheFile(configEntityID string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "hydris", "spacetrack", sanitizeIDComponent(configEntityID)+".tle")
+}
+
+// writeTLECache stores data, the body of a successful fetch, at path. The
+// file is replaced atomically so that a crash never leaves half a cache.
+func writeTLECache(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readTLECache returns the TLEs cached at path and when they were
+// written, if that is at most maxAge before now.
+func readTLECache(path string, maxAge time.Duration, now time.Time) ([]*sgp4.TLE, time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	written := info.ModTime()
+	if age := now.Sub(written); age > maxAge {
+		return nil, written, fmt.Errorf("cached TLEs are %s old, more than %s", age.Round(time.Minute), maxAge)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, written, err
+	}
+	tles, err := parseTLEList(data)
+	if err != nil {
+		return nil, written, fmt.Errorf("cache %s: %w", path, err)
+	}
+	return tles, written, nil
+}
+
+// loadURLTLEs fetches the TLEs of config's source and caches them. If the
+// fetch fails, it falls back to the cache within its max age; cached is
+// then the time the cache was written.
+func loadURLTLEs(ctx context.Context, logger *slog.Logger, config *TrackerConfig) (tles []*sgp4.TLE, cached time.Time, err error) {
+	fetchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	data, err := fetchTLEData(fetchCtx, config.TLESource, config.Username, config.Password)
+	cancel()
+	if err == nil {
+		tles, err = parseTLEList(data)
+	}
+	if err == nil {
+		if config.CacheFile != "" {
+			if err := writeTLECache(config.CacheFile, data); err != nil {
+				logger.Warn("Failed to cache TLEs", "path", config.CacheFile, "error", err)
+			}
+		}
+		return tles, time.Time{}, nil
+	}
+	if config.CacheFile == "" {
+		return nil, time.Time{}, err
+	}
+
+	tles, cached, cerr := readTLECache(config.CacheFile, config.CacheMaxAge, time.Now())
+	if cerr != nil {
+		return nil, time.Time{}, fmt.Errorf("%w; no usable cache: %w", err, cerr)
+	}
+	return tles, cached, nil
+}
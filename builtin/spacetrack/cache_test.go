@@ -0,0 +1,108 @@
+package spacetrack
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const issTLE = `ISS (ZARYA)
+1 25544U 98067A   08264.51782528 -.00002182  00000-0 -11606-4 0  2927
+2 25544  51.6416 247.4627 0006703 130.5360 325.0288 15.72125391563537
+`
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// tleServer serves issTLE while up is true and fails otherwise.
+func tleServer(t *testing.T, up *bool) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !*up {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, issTLE)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestLoadURLTLEs_WritesCache(t *testing.T) {
+	up := true
+	config := &TrackerConfig{
+		TLESource:   tleServer(t, &up),
+		CacheFile:   filepath.Join(t.TempDir(), "cache", "iss.tle"),
+		CacheMaxAge: time.Hour,
+	}
+
+	tles, cached, err := loadURLTLEs(context.Background(), discard, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tles) != 1 || !cached.IsZero() {
+		t.Fatalf("got %d TLEs, cached %v; want 1 fetched", len(tles), cached)
+	}
+	data, err := os.ReadFile(config.CacheFile)
+	if err != nil {
+		t.Fatalf("cache not written: %v", err)
+	}
+	if string(data) != issTLE {
+		t.Errorf("cache holds %q", data)
+	}
+}
+
+func TestLoadURLTLEs_FallsBackToCache(t *testing.T) {
+	up := true
+	config := &TrackerConfig{
+		TLESource:   tleServer(t, &up),
+		CacheFile:   filepath.Join(t.TempDir(), "iss.tle"),
+		CacheMaxAge: time.Hour,
+	}
+	if _, _, err := loadURLTLEs(context.Background(), discard, config); err != nil {
+		t.Fatal(err)
+	}
+
+	up = false
+	tles, cached, err := loadURLTLEs(context.Background(), discard, config)
+	if err != nil {
+		t.Fatalf("expected the cache to be used: %v", err)
+	}
+	if len(tles) != 1 || tles[0].Name != "ISS (ZARYA)" {
+		t.Errorf("got %d TLEs from cache", len(tles))
+	}
+	if cached.IsZero() || time.Since(cached) > time.Minute {
+		t.Errorf("cache written at %v, want just now", cached)
+	}
+}
+
+func TestLoadURLTLEs_RefusesStaleCache(t *testing.T) {
+	up := false
+	config := &TrackerConfig{
+		TLESource:   tleServer(t, &up),
+		CacheFile:   filepath.Join(t.TempDir(), "iss.tle"),
+		CacheMaxAge: time.Hour,
+	}
+	if err := writeTLECache(config.CacheFile, []byte(issTLE)); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(config.CacheFile, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if tles, _, err := loadURLTLEs(context.Background(), discard, config); err == nil {
+		t.Fatalf("got %d TLEs from a cache past its max age", len(tles))
+	}
+
+	// Within a longer max age, the same cache is used.
+	config.CacheMaxAge = 3 * time.Hour
+	if _, _, err := loadURLTLEs(context.Background(), discard, config); err != nil {
+		t.Errorf("cache within max age refused: %v", err)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/akhenakh/sgp4"
 	"github.com/projectqai/hydris/builtin"
 	"github.com/projectqai/hydris/builtin/controller"
+	"github.com/projectqai/hydris/goclient"
 	pb "github.com/projectqai/proto/go"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
@@ -30,6 +31,11 @@ type TrackerConfig struct {
 	Username             string  `json:"username"`
 	Password             string  `json:"password"`
 	DisableOrbitTrack    bool    `json:"disable_orbit_track"`
+
+	// CacheFile is where fetched TLEs are kept for when the source is
+	// unreachable, and CacheMaxAge how old they may be to be used then.
+	CacheFile   string        `json:"cache_file"`
+	CacheMaxAge time.Duration `json:"-"`
 }
 
 type SatellitePosition struct {
@@ -53,7 +59,8 @@ func parseInlineTLE(data string) (*sgp4.TLE, error) {
 	return tle, nil
 }
 
-func fetchMultipleTLEs(ctx context.Context, url, username, password string) ([]*sgp4.TLE, error) {
+// fetchTLEData returns the body of the TLE file at url.
+func fetchTLEData(ctx context.Context, url, username, password string) ([]byte, error) {
 	client := &http.Client{Timeout: 30 * time.Second}
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -80,7 +87,12 @@ func fetchMultipleTLEs(ctx context.Context, url, username, password string) ([]*
 	if err != nil {
 		return nil, fmt.Errorf("failed to read TLE response: %w", err)
 	}
+	return body, nil
+}
 
+// parseTLEList parses a file of three line TLEs, skipping those that
+// don't parse.
+func parseTLEList(body []byte) ([]*sgp4.TLE, error) {
 	allLines := strings.Split(strings.TrimSpace(string(body)), "\n")
 	for i := range allLines {
 		allLines[i] = strings.TrimSpace(allLines[i])
@@ -184,6 +196,16 @@ func Run(ctx context.Context, logger *slog.Logger, _ string) error {
 				"ui:group":       "source",
 				"ui:order":       0,
 			},
+			"cache_max_age_hours": map[string]any{
+				"type":        "number",
+				"title":       "Cache Max Age",
+				"description": "How old cached TLEs may be to be used while the URL is unreachable",
+				"default":     72.0,
+				"minimum":     0.0,
+				"ui:unit":     "h",
+				"ui:group":    "source",
+				"ui:order":    2,
+			},
 			"disable_orbit_track": map[string]any{
 				"type":        "boolean",
 				"title":       "Disable Orbit Track",
@@ -326,7 +348,7 @@ func runTracker(ctx context.Context, logger *slog.Logger, entity *pb.Entity) err
 	}
 	defer func() { _ = grpcConn.Close() }()
 
-	worldClient := pb.NewWorldServiceClient(grpcConn)
+	world := goclient.NewWorld(grpcConn, goclient.WithCallTimeout(2*time.Second))
 	ticker := time.NewTicker(time.Duration(trackerConfig.IntervalSeconds * float64(time.Second)))
 	defer ticker.Stop()
 
@@ -335,9 +357,15 @@ func runTracker(ctx context.Context, logger *slog.Logger, entity *pb.Entity) err
 	tleTicker := time.NewTicker(time.Duration(trackerConfig.TLERefreshSeconds) * time.Second)
 	defer tleTicker.Stop()
 
-	fetchCtx, fetchCancel := context.WithTimeout(ctx, 30*time.Second)
 	if isURLSource {
-		tles, err = fetchMultipleTLEs(fetchCtx, trackerConfig.TLESource, trackerConfig.Username, trackerConfig.Password)
+		if trackerConfig.CacheFile == "" {
+			trackerConfig.CacheFile = defaultCacheFile(entity.Id)
+		}
+		var cached time.Time
+		tles, cached, err = loadURLTLEs(ctx, logger, trackerConfig)
+		if err == nil && !cached.IsZero() {
+			logger.Warn("TLE source unreachable, using cached TLEs", "configEntityID", entity.Id, "cached", cached)
+		}
 	} else {
 		var tle *sgp4.TLE
 		tle, err = parseInlineTLE(trackerConfig.TLESource)
@@ -345,7 +373,6 @@ func runTracker(ctx context.Context, logger *slog.Logger, entity *pb.Entity) err
 			tles = []*sgp4.TLE{tle}
 		}
 	}
-	fetchCancel()
 
 	if err != nil {
 		return fmt.Errorf("load initial TLE: %w", err)
@@ -356,19 +383,11 @@ func runTracker(ctx context.Context, logger *slog.Logger, entity *pb.Entity) err
 	var positionUpdateCount uint64
 
 	// Push initial position + orbit updates
-	pushPositionUpdates(ctx, logger, worldClient, tles, entity.Id, trackerConfig)
+	pushPositionUpdates(ctx, logger, world, tles, entity.Id, trackerConfig)
 	positionUpdateCount += uint64(len(tles))
-	_, _ = worldClient.Push(ctx, &pb.EntityChangeRequest{
-		Changes: []*pb.Entity{{
-			Id: entity.Id,
-			Metric: &pb.MetricComponent{Metrics: []*pb.Metric{
-				{Kind: pb.MetricKind_MetricKindCount.Enum(), Unit: pb.MetricUnit_MetricUnitCount, Label: proto.String("satellites tracked"), Id: proto.Uint32(1), Val: &pb.Metric_Uint64{Uint64: uint64(len(tles))}},
-				{Kind: pb.MetricKind_MetricKindCount.Enum(), Unit: pb.MetricUnit_MetricUnitCount, Label: proto.String("position updates"), Id: proto.Uint32(2), Val: &pb.Metric_Uint64{Uint64: positionUpdateCount}},
-			}},
-		}},
-	})
+	pushTrackerMetrics(ctx, world, entity.Id, len(tles), positionUpdateCount)
 	if !trackerConfig.DisableOrbitTrack {
-		pushOrbitEntities(ctx, logger, worldClient, tles, entity.Id, trackerConfig)
+		pushOrbitEntities(ctx, logger, world, tles, entity.Id, trackerConfig)
 	}
 
 	orbitTicker := time.NewTicker(time.Duration(trackerConfig.OrbitIntervalSeconds * float64(time.Second)))
@@ -381,42 +400,47 @@ func runTracker(ctx context.Context, logger *slog.Logger, entity *pb.Entity) err
 			return ctx.Err()
 
 		case <-ticker.C:
-			pushPositionUpdates(ctx, logger, worldClient, tles, entity.Id, trackerConfig)
+			pushPositionUpdates(ctx, logger, world, tles, entity.Id, trackerConfig)
 			positionUpdateCount += uint64(len(tles))
-			_, _ = worldClient.Push(ctx, &pb.EntityChangeRequest{
-				Changes: []*pb.Entity{{
-					Id: entity.Id,
-					Metric: &pb.MetricComponent{Metrics: []*pb.Metric{
-						{Kind: pb.MetricKind_MetricKindCount.Enum(), Unit: pb.MetricUnit_MetricUnitCount, Label: proto.String("satellites tracked"), Id: proto.Uint32(1), Val: &pb.Metric_Uint64{Uint64: uint64(len(tles))}},
-						{Kind: pb.MetricKind_MetricKindCount.Enum(), Unit: pb.MetricUnit_MetricUnitCount, Label: proto.String("position updates"), Id: proto.Uint32(2), Val: &pb.Metric_Uint64{Uint64: positionUpdateCount}},
-					}},
-				}},
-			})
+			pushTrackerMetrics(ctx, world, entity.Id, len(tles), positionUpdateCount)
 
 		case <-orbitTicker.C:
 			if trackerConfig.DisableOrbitTrack {
 				continue
 			}
-			pushOrbitEntities(ctx, logger, worldClient, tles, entity.Id, trackerConfig)
+			pushOrbitEntities(ctx, logger, world, tles, entity.Id, trackerConfig)
 
 		case <-tleTicker.C:
 			if isURLSource {
-				fetchCtx, fetchCancel := context.WithTimeout(ctx, 30*time.Second)
-				newTLEs, err := fetchMultipleTLEs(fetchCtx, trackerConfig.TLESource, trackerConfig.Username, trackerConfig.Password)
-				fetchCancel()
-				if err != nil {
+				newTLEs, cached, err := loadURLTLEs(ctx, logger, trackerConfig)
+				switch {
+				case err != nil:
 					logger.Error("Failed to refresh TLEs", "configEntityID", entity.Id, "error", err)
-				} else {
+				case !cached.IsZero():
+					// The TLEs in use are at least as recent as the cache.
+					logger.Warn("Failed to refresh TLEs, cache still within max age", "configEntityID", entity.Id, "cached", cached)
+				default:
 					tles = newTLEs
 					logger.Info("Refreshed TLEs", "configEntityID", entity.Id, "count", len(tles))
-					pushOrbitEntities(ctx, logger, worldClient, tles, entity.Id, trackerConfig)
+					pushOrbitEntities(ctx, logger, world, tles, entity.Id, trackerConfig)
 				}
 			}
 		}
 	}
 }
 
-func pushPositionUpdates(ctx context.Context, logger *slog.Logger, worldClient pb.WorldServiceClient, tles []*sgp4.TLE, configEntityID string, config *TrackerConfig) {
+// pushTrackerMetrics reports the tracker's counters on its config entity.
+func pushTrackerMetrics(ctx context.Context, world *goclient.World, configEntityID string, tracked int, updates uint64) {
+	_ = world.PushEntities(ctx, &pb.Entity{
+		Id: configEntityID,
+		Metric: &pb.MetricComponent{Metrics: []*pb.Metric{
+			{Kind: pb.MetricKind_MetricKindCount.Enum(), Unit: pb.MetricUnit_MetricUnitCount, Label: proto.String("satellites tracked"), Id: proto.Uint32(1), Val: &pb.Metric_Uint64{Uint64: uint64(tracked)}},
+			{Kind: pb.MetricKind_MetricKindCount.Enum(), Unit: pb.MetricUnit_MetricUnitCount, Label: proto.String("position updates"), Id: proto.Uint32(2), Val: &pb.Metric_Uint64{Uint64: updates}},
+		}},
+	})
+}
+
+func pushPositionUpdates(ctx context.Context, logger *slog.Logger, world *goclient.World, tles []*sgp4.TLE, configEntityID string, config *TrackerConfig) {
 	for _, tle := range tles {
 		// Check for cancellation before processing each TLE
 		select {
@@ -444,15 +468,7 @@ func pushPositionUpdates(ctx context.Context, logger *slog.Logger, worldClient p
 			entity.Track.Prediction = nil
 		}
 
-		entities := []*pb.Entity{entity}
-
-		pushCtx, pushCancel := context.WithTimeout(ctx, 2*time.Second)
-		_, err = worldClient.Push(pushCtx, &pb.EntityChangeRequest{
-			Changes: entities,
-		})
-		pushCancel()
-
-		if err != nil {
+		if err := world.PushEntities(ctx, entity); err != nil {
 			logger.Error("Failed to push entity", "configEntityID", configEntityID, "satellite", tle.Name, "error", err)
 		}
 	}
@@ -573,7 +589,7 @@ func positionToEntity(position *SatellitePosition, tle *sgp4.TLE, entityID, labe
 	return entity
 }
 
-func pushOrbitEntities(ctx context.Context, logger *slog.Logger, worldClient pb.WorldServiceClient, tles []*sgp4.TLE, configEntityID string, config *TrackerConfig) {
+func pushOrbitEntities(ctx context.Context, logger *slog.Logger, world *goclient.World, tles []*sgp4.TLE, configEntityID string, config *TrackerConfig) {
 	for _, tle := range tles {
 		select {
 		case <-ctx.Done():
@@ -585,13 +601,7 @@ func pushOrbitEntities(ctx context.Context, logger *slog.Logger, worldClient pb.
 		expires := time.Duration(config.OrbitIntervalSeconds * float64(time.Second))
 		entity := orbitMissionEntity(tle, entityID, "spacetrack", expires)
 
-		pushCtx, pushCancel := context.WithTimeout(ctx, 2*time.Second)
-		_, err := worldClient.Push(pushCtx, &pb.EntityChangeRequest{
-			Changes: []*pb.Entity{entity},
-		})
-		pushCancel()
-
-		if err != nil {
+		if err := world.PushEntities(ctx, entity); err != nil {
 			logger.Error("Failed to push orbit entity", "configEntityID", configEntityID, "satellite", tle.Name, "error", err)
 		}
 	}
@@ -666,6 +676,7 @@ func parseTrackerConfig(config *pb.ConfigurationComponent) (*TrackerConfig, erro
 		IntervalSeconds:      1.0,
 		OrbitIntervalSeconds: 60,
 		TLERefreshSeconds:    3600,
+		CacheMaxAge:          defaultCacheMaxAge,
 	}
 
 	if config.Value == nil || config.Value.Fields == nil {
@@ -709,6 +720,14 @@ func parseTrackerConfig(config *pb.ConfigurationComponent) (*TrackerConfig, erro
 	if v, ok := fields["disable_orbit_track"]; ok {
 		trackerConfig.DisableOrbitTrack = v.GetBoolValue()
 	}
+	if v, ok := fields["cache_file"]; ok {
+		trackerConfig.CacheFile = v.GetStringValue()
+	}
+	if v, ok := fields["cache_max_age_hours"]; ok {
+		if hours := v.GetNumberValue(); hours > 0 {
+			trackerConfig.CacheMaxAge = time.Duration(hours * float64(time.Hour))
+		}
+	}
 	if v, ok := fields["username"]; ok {
 		trackerConfig.Username = v.GetStringValue()
 	}
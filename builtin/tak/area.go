@@ -0,0 +1,63 @@
+package view
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+// parseArea parses an area of interest given as a bounding box
+// "min_lon,min_lat,max_lon,max_lat" into the filter for a client's watch
+// stream. An empty string returns nil, so the client receives everything.
+func parseArea(s string) (*pb.EntityFilter, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("area must be min_lon,min_lat,max_lon,max_lat, got %q", s)
+	}
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("area: invalid number %q", p)
+		}
+		v[i] = f
+	}
+	minLon, minLat, maxLon, maxLat := v[0], v[1], v[2], v[3]
+	if minLon >= maxLon || minLat >= maxLat {
+		return nil, fmt.Errorf("area: min must be less than max in %q", s)
+	}
+	if minLat < -90 || maxLat > 90 || minLon < -180 || maxLon > 180 {
+		return nil, fmt.Errorf("area: out of range in %q", s)
+	}
+
+	return areaFilter(minLon, minLat, maxLon, maxLat), nil
+}
+
+// areaFilter matches entities inside the bounding box. Entities without a
+// position, such as chat messages, are not spatial and always pass.
+func areaFilter(minLon, minLat, maxLon, maxLat float64) *pb.EntityFilter {
+	box := &pb.PlanarPolygon{
+		Outer: &pb.PlanarRing{
+			Points: []*pb.PlanarPoint{
+				{Latitude: minLat, Longitude: minLon},
+				{Latitude: maxLat, Longitude: minLon},
+				{Latitude: maxLat, Longitude: maxLon},
+				{Latitude: minLat, Longitude: maxLon},
+				{Latitude: minLat, Longitude: minLon},
+			},
+		},
+	}
+	return &pb.EntityFilter{Or: []*pb.EntityFilter{
+		{Geo: &pb.GeoFilter{Geo: &pb.GeoFilter_Geometry{Geometry: &pb.Geometry{
+			Planar: &pb.PlanarGeometry{Plane: &pb.PlanarGeometry_Polygon{Polygon: box}},
+		}}}},
+		{Not: &pb.EntityFilter{Component: []uint32{uint32(pb.EntityComponent_EntityComponentGeo)}}},
+	}}
+}
@@ -0,0 +1,122 @@
+package view
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydris/engine"
+	"github.com/projectqai/hydris/pkg/cot"
+	pb "github.com/projectqai/proto/go"
+	_goconnect "github.com/projectqai/proto/go/_goconnect"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestParseArea(t *testing.T) {
+	f, err := parseArea("")
+	if err != nil || f != nil {
+		t.Errorf("empty area: got %v, %v; want nil filter", f, err)
+	}
+	if _, err := parseArea("10,47,12,49"); err != nil {
+		t.Errorf("valid area: %v", err)
+	}
+	for _, bad := range []string{"10,47,12", "a,47,12,49", "12,47,10,49", "10,47,12,95"} {
+		if _, err := parseArea(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+// startWorld serves a WorldServer over h2c and returns its address.
+func startWorld(t *testing.T) (*engine.WorldServer, string) {
+	t.Helper()
+	w := engine.NewWorldServer()
+
+	mux := http.NewServeMux()
+	path, handler := _goconnect.NewWorldServiceHandler(w)
+	mux.Handle(path, handler)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: h2c.NewHandler(mux, &http2.Server{})}
+	go func() { _ = srv.Serve(listener) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return w, listener.Addr().String()
+}
+
+// TestHandleConn_AreaSubscription connects a TAK client with a bounding box
+// subscription and checks it only receives entities inside the box.
+func TestHandleConn_AreaSubscription(t *testing.T) {
+	w, addr := startWorld(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := w.Push(ctx, connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{
+		{Id: "inside", Label: proto.String("inside"), Geo: &pb.GeoSpatialComponent{Latitude: 48, Longitude: 11}},
+		{Id: "outside", Label: proto.String("outside"), Geo: &pb.GeoSpatialComponent{Latitude: 52, Longitude: 13}},
+	}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filter, err := parseArea("10,47,12,49")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, client := net.Pipe()
+	defer func() { _ = client.Close() }()
+	go handleConn(ctx, server, addr, slog.New(slog.NewTextHandler(io.Discard, nil)), "tak.test", "", filter, cot.Options{})
+
+	received := make(chan string, 16)
+	go func() {
+		r := bufio.NewReader(client)
+		for {
+			line, err := r.ReadString('>')
+			if err != nil {
+				close(received)
+				return
+			}
+			if strings.Contains(line, "<event") {
+				received <- line
+			}
+		}
+	}()
+
+	sawInside := false
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev, ok := <-received:
+			if !ok {
+				received = nil
+				continue
+			}
+			if strings.Contains(ev, `uid="outside"`) {
+				t.Fatalf("received out-of-area entity: %s", ev)
+			}
+			if strings.Contains(ev, `uid="inside"`) && !sawInside {
+				sawInside = true
+				// Give an out-of-area entity a chance to show up.
+				timeout = time.After(300 * time.Millisecond)
+			}
+		case <-timeout:
+			if !sawInside {
+				t.Fatal("no in-area entity received")
+			}
+			return
+		}
+	}
+}
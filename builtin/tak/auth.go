@@ -0,0 +1,93 @@
+package view
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+// handshakeTimeout bounds how long a client may take to complete the TLS
+// handshake before it is dropped.
+const handshakeTimeout = 10 * time.Second
+
+// buildServerTLSConfig returns the TLS config of a TAK TCP server, or nil if
+// tls_cert/tls_key are not set. With TLS, clients must present a certificate
+// signed by tls_ca; a server without tls_ca would accept anyone and is
+// rejected as misconfigured.
+func buildServerTLSConfig(entity *pb.Entity) (*tls.Config, error) {
+	certPath := configString(entity, "tls_cert", "")
+	keyPath := configString(entity, "tls_key", "")
+	if certPath == "" && keyPath == "" {
+		return nil, nil
+	}
+	if certPath == "" || keyPath == "" {
+		return nil, fmt.Errorf("tls_cert and tls_key must be set together")
+	}
+
+	cert, err := loadKeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	caPath := configString(entity, "tls_ca", "")
+	if caPath == "" {
+		return nil, fmt.Errorf("tls_ca is required to authenticate TAK clients")
+	}
+	pool, err := loadCAPool(caPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// authenticateConn completes the TLS handshake of a server connection and
+// returns the client's identity: the common name of its certificate, or its
+// first DNS name if the CN is empty. Plain TCP connections have no identity.
+func authenticateConn(ctx context.Context, conn net.Conn) (string, error) {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return "", nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	defer cancel()
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return "", fmt.Errorf("tls handshake: %w", err)
+	}
+
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return "", errors.New("no client certificate")
+	}
+	if cn := certs[0].Subject.CommonName; cn != "" {
+		return cn, nil
+	}
+	if len(certs[0].DNSNames) > 0 {
+		return certs[0].DNSNames[0], nil
+	}
+	return "", errors.New("client certificate has no common name or DNS name")
+}
+
+// stampIdentity records the authenticated client that reported an entity as
+// the id of its administrative component, unless the entity already has
+// one.
+func stampIdentity(entity *pb.Entity, identity string) {
+	if identity == "" || entity.GetAdministrative().GetId() != "" {
+		return
+	}
+	if entity.Administrative == nil {
+		entity.Administrative = &pb.AdministrativeComponent{}
+	}
+	entity.Administrative.Id = &identity
+}
@@ -0,0 +1,145 @@
+package view
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/projectqai/hydris/builtin"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// allowTempDir returns a temporary directory the builtins may read from.
+func allowTempDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	prev := builtin.LocalPermissions.AllowedPaths
+	builtin.LocalPermissions.AllowedPaths = append(append([]string{}, prev...), dir)
+	t.Cleanup(func() { builtin.LocalPermissions.AllowedPaths = prev })
+	return dir
+}
+
+func configEntity(t *testing.T, fields map[string]any) *pb.Entity {
+	t.Helper()
+	value, err := structpb.NewStruct(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pb.Entity{Id: "tak.server", Config: &pb.ConfigurationComponent{Value: value}}
+}
+
+func TestBuildServerTLSConfig(t *testing.T) {
+	dir := allowTempDir(t)
+	ca := newTestCA(t)
+	certPath, keyPath := writeTLSFiles(t, dir, "server", ca.issue(t, "server", true))
+
+	conf, err := buildServerTLSConfig(configEntity(t, map[string]any{}))
+	if err != nil || conf != nil {
+		t.Fatalf("no TLS config expected without cert, got %v, %v", conf, err)
+	}
+
+	if _, err := buildServerTLSConfig(configEntity(t, map[string]any{
+		"tls_cert": certPath, "tls_key": keyPath,
+	})); err == nil {
+		t.Error("TLS without a client CA should be rejected")
+	}
+
+	if _, err := buildServerTLSConfig(configEntity(t, map[string]any{"tls_cert": certPath})); err == nil {
+		t.Error("tls_cert without tls_key should be rejected")
+	}
+}
+
+// TestTLSListener_AuthenticatesClients serves TLS with client-cert auth and
+// checks that a client with a CA-signed certificate is identified by its CN
+// while a client without one is rejected.
+func TestTLSListener_AuthenticatesClients(t *testing.T) {
+	dir := allowTempDir(t)
+	ca := newTestCA(t)
+	certPath, keyPath := writeTLSFiles(t, dir, "server", ca.issue(t, "server", true))
+	caPath := ca.writePEM(t, dir)
+
+	conf, err := buildServerTLSConfig(configEntity(t, map[string]any{
+		"tls_cert": certPath, "tls_key": keyPath, "tls_ca": caPath,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := tls.NewListener(raw, conf)
+	defer func() { _ = listener.Close() }()
+
+	type result struct {
+		identity string
+		err      error
+	}
+	results := make(chan result, 2)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			identity, err := authenticateConn(context.Background(), conn)
+			results <- result{identity, err}
+			_ = conn.Close()
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	dial := func(certs []tls.Certificate) {
+		conn, err := tls.Dial("tcp", raw.Addr().String(), &tls.Config{RootCAs: roots, Certificates: certs})
+		if err == nil {
+			// Force the handshake to complete on both ends; with TLS 1.3
+			// a rejected client cert only surfaces on the first read.
+			_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+			_, _ = conn.Read(make([]byte, 1))
+			_ = conn.Close()
+		}
+	}
+
+	dial([]tls.Certificate{ca.issue(t, "alpha", false)})
+	r := <-results
+	if r.err != nil || r.identity != "alpha" {
+		t.Errorf("authenticated client: got %q, %v; want alpha", r.identity, r.err)
+	}
+
+	dial(nil)
+	r = <-results
+	if r.err == nil {
+		t.Errorf("client without certificate should be rejected, got identity %q", r.identity)
+	}
+}
+
+func TestAuthenticateConn_PlainTCP(t *testing.T) {
+	a, b := net.Pipe()
+	defer func() { _ = a.Close(); _ = b.Close() }()
+	identity, err := authenticateConn(context.Background(), a)
+	if err != nil || identity != "" {
+		t.Errorf("plain connection: got %q, %v", identity, err)
+	}
+}
+
+func TestStampIdentity(t *testing.T) {
+	e := &pb.Entity{Id: "tak.ANDROID-1"}
+	stampIdentity(e, "")
+	if e.Administrative != nil {
+		t.Error("empty identity should not add an administrative component")
+	}
+	stampIdentity(e, "alpha")
+	if e.GetAdministrative().GetId() != "alpha" {
+		t.Errorf("administrative id = %q, want alpha", e.GetAdministrative().GetId())
+	}
+	stampIdentity(e, "bravo")
+	if e.GetAdministrative().GetId() != "alpha" {
+		t.Errorf("administrative id overwritten with %q", e.GetAdministrative().GetId())
+	}
+}
@@ -0,0 +1,166 @@
+package view
+
+import (
+	"context"
+	"io"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+const (
+	// coalesceWindow is how long an outbound CoT event may wait for others
+	// to share its write.
+	coalesceWindow = 20 * time.Millisecond
+
+	// streamBatchBytes flushes a TCP batch early once it reaches this size.
+	streamBatchBytes = 64 << 10
+
+	// datagramMTU is the largest UDP payload a batch may grow to. A single
+	// event larger than this is still sent, on its own.
+	datagramMTU = 1400
+)
+
+// cotBatcher coalesces CoT events into fewer writes. On a stream every flush
+// is one write of all pending events. In datagram mode each write is one
+// packet, so events are never split across writes and a batch is cut before
+// it would exceed the MTU.
+type cotBatcher struct {
+	w        io.Writer
+	limit    int
+	datagram bool
+
+	// onFlush is called after a successful write with the number of events
+	// it carried.
+	onFlush func(events int)
+	// onWriteError, if set, receives write errors and the batch is dropped,
+	// as is usual for UDP. Otherwise flush returns the error.
+	onWriteError func(error)
+
+	buf    []byte
+	events int
+}
+
+func newStreamBatcher(w io.Writer) *cotBatcher {
+	return &cotBatcher{w: w, limit: streamBatchBytes}
+}
+
+func newDatagramBatcher(w io.Writer) *cotBatcher {
+	return &cotBatcher{w: w, limit: datagramMTU, datagram: true}
+}
+
+// add queues one encoded event, flushing first or afterwards as the size
+// limit requires.
+func (b *cotBatcher) add(cot []byte) error {
+	if b.datagram && b.events > 0 && len(b.buf)+len(cot) > b.limit {
+		if err := b.flush(); err != nil {
+			return err
+		}
+	}
+	b.buf = append(b.buf, cot...)
+	b.events++
+	if len(b.buf) >= b.limit {
+		return b.flush()
+	}
+	return nil
+}
+
+func (b *cotBatcher) pending() bool {
+	return b.events > 0
+}
+
+// flush writes all pending events in one write.
+func (b *cotBatcher) flush() error {
+	if b.events == 0 {
+		return nil
+	}
+	n := b.events
+	_, err := b.w.Write(b.buf)
+	b.buf = b.buf[:0]
+	b.events = 0
+	if err != nil {
+		if b.onWriteError != nil {
+			b.onWriteError(err)
+			return nil
+		}
+		return err
+	}
+	if b.onFlush != nil {
+		b.onFlush(n)
+	}
+	return nil
+}
+
+// pumpCoT forwards events from recv to b until ctx is done or recv fails.
+// encode returns the CoT for an event, or nil to skip it. An event is held
+// back for at most coalesceWindow; rate limiting stays with the server side
+// of the watch stream, which decides how fast events arrive here.
+func pumpCoT(ctx context.Context, recv func() (*pb.EntityChangeEvent, error), b *cotBatcher, encode func(*pb.EntityChangeEvent) []byte) error {
+	type received struct {
+		event *pb.EntityChangeEvent
+		err   error
+	}
+	events := make(chan received)
+	go func() {
+		for {
+			event, err := recv()
+			select {
+			case events <- received{event, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var timer *time.Timer
+	var timerC <-chan time.Time
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = b.flush()
+			return ctx.Err()
+
+		case <-timerC:
+			timerC = nil
+			if err := b.flush(); err != nil {
+				return err
+			}
+
+		case r := <-events:
+			if r.err != nil {
+				if err := b.flush(); err != nil {
+					return err
+				}
+				return r.err
+			}
+			data := encode(r.event)
+			if data == nil {
+				continue
+			}
+			if err := b.add(data); err != nil {
+				return err
+			}
+			switch {
+			case b.pending() && timerC == nil:
+				if timer == nil {
+					timer = time.NewTimer(coalesceWindow)
+				} else {
+					timer.Reset(coalesceWindow)
+				}
+				timerC = timer.C
+			case !b.pending() && timerC != nil:
+				timer.Stop()
+				timerC = nil
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package view
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+// countingWriter records every write it receives.
+type countingWriter struct {
+	mu     sync.Mutex
+	writes [][]byte
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes = append(w.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func (w *countingWriter) snapshot() [][]byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([][]byte(nil), w.writes...)
+}
+
+// fakeEvents returns a recv func that yields n events and then blocks until
+// ctx is done.
+func fakeEvents(ctx context.Context, n int) func() (*pb.EntityChangeEvent, error) {
+	i := 0
+	return func() (*pb.EntityChangeEvent, error) {
+		if i < n {
+			i++
+			return &pb.EntityChangeEvent{Entity: &pb.Entity{Id: fmt.Sprintf("e%d", i)}}, nil
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+}
+
+func encodeID(event *pb.EntityChangeEvent) []byte {
+	return []byte(`<event uid="` + event.Entity.Id + `"/>`)
+}
+
+func TestPumpCoT_CoalescesRapidEvents(t *testing.T) {
+	const n = 200
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := &countingWriter{}
+	done := make(chan error, 1)
+	go func() { done <- pumpCoT(ctx, fakeEvents(ctx, n), newStreamBatcher(w), encodeID) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if bytes.Count(bytes.Join(w.snapshot(), nil), []byte("<event")) == n {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("not all events were written")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("pumpCoT returned %v, want context.Canceled", err)
+	}
+
+	writes := w.snapshot()
+	if len(writes) >= n {
+		t.Errorf("%d events took %d writes, want fewer", n, len(writes))
+	}
+	all := string(bytes.Join(writes, nil))
+	for i := 1; i <= n; i++ {
+		if !strings.Contains(all, fmt.Sprintf(`uid="e%d"`, i)) {
+			t.Fatalf("event e%d missing", i)
+		}
+	}
+}
+
+func TestCotBatcher_DatagramRespectsMTU(t *testing.T) {
+	w := &countingWriter{}
+	b := newDatagramBatcher(w)
+
+	event := bytes.Repeat([]byte("x"), 500)
+	for i := 0; i < 5; i++ {
+		if err := b.add(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	big := bytes.Repeat([]byte("y"), datagramMTU+100)
+	if err := b.add(big); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	writes := w.snapshot()
+	total := 0
+	for _, p := range writes {
+		total += len(p)
+		if len(p) > datagramMTU && !bytes.Equal(p, big) {
+			t.Errorf("datagram of %d bytes exceeds MTU", len(p))
+		}
+		if len(p)%500 != 0 && !bytes.Equal(p, big) {
+			t.Errorf("datagram of %d bytes splits an event", len(p))
+		}
+	}
+	if total != 5*500+len(big) {
+		t.Errorf("wrote %d bytes, want %d", total, 5*500+len(big))
+	}
+	// 5 x 500 bytes fit two to a packet, the oversized event goes alone.
+	if len(writes) != 4 {
+		t.Errorf("got %d datagrams, want 4", len(writes))
+	}
+}
+
+func TestCotBatcher_StreamFlushesAtLimit(t *testing.T) {
+	w := &countingWriter{}
+	b := newStreamBatcher(w)
+
+	chunk := bytes.Repeat([]byte("z"), streamBatchBytes/2)
+	for i := 0; i < 2; i++ {
+		if err := b.add(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(w.snapshot()) != 1 || b.pending() {
+		t.Errorf("batch reaching the limit should be flushed immediately")
+	}
+}
@@ -6,20 +6,22 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
 	"net"
 	"os"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/projectqai/hydris/builtin"
 	"github.com/projectqai/hydris/builtin/controller"
 	"github.com/projectqai/hydris/goclient"
+	"github.com/projectqai/hydris/pkg/backoff"
 	"github.com/projectqai/hydris/pkg/cot"
 	pb "github.com/projectqai/proto/go"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
 )
@@ -31,10 +33,14 @@ var (
 
 // handleConn runs bidirectional CoT streaming on a TCP connection.
 // It reads inbound CoT from the remote side (parsing and pushing to Hydris)
-// and writes outbound entity changes as CoT XML.
-func handleConn(ctx context.Context, conn net.Conn, serverURL string, logger *slog.Logger, trackerID string) {
+// and writes outbound entity changes as CoT XML. identity is the
+// authenticated name of the client, or empty for unauthenticated
+// connections; it is stamped onto every entity the client sends. filter
+// restricts what is sent to the client; nil sends everything. opts controls
+// how entities are rendered as CoT.
+func handleConn(ctx context.Context, conn net.Conn, serverURL string, logger *slog.Logger, trackerID string, identity string, filter *pb.EntityFilter, opts cot.Options) {
 	clientID := clientCount.Add(1)
-	logger.Info("Connection active", "clientID", clientID, "remoteAddr", conn.RemoteAddr())
+	logger.Info("Connection active", "clientID", clientID, "remoteAddr", conn.RemoteAddr(), "identity", identity)
 
 	defer func() {
 		clientCount.Add(-1)
@@ -44,7 +50,7 @@ func handleConn(ctx context.Context, conn net.Conn, serverURL string, logger *sl
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	grpcConn, err := grpc.NewClient(serverURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	grpcConn, err := builtin.ServerConn(serverURL)
 	if err != nil {
 		logger.Error("gRPC connection failed", "clientID", clientID, "error", err)
 		return
@@ -93,6 +99,7 @@ func handleConn(ctx context.Context, conn net.Conn, serverURL string, logger *sl
 					} else if entity != nil {
 						entity.Routing = &pb.Routing{Channels: []*pb.Channel{{}}}
 						entity.Id = fmt.Sprintf("tak.%s", entity.Id)
+						stampIdentity(entity, identity)
 
 						if _, err := client.Push(ctx, &pb.EntityChangeRequest{Changes: []*pb.Entity{entity}}); err != nil {
 							logger.Error("Error pushing chat to Hydris", "clientID", clientID, "error", err)
@@ -102,14 +109,22 @@ func handleConn(ctx context.Context, conn net.Conn, serverURL string, logger *sl
 					}
 				}
 
-				if strings.Contains(data, `type="a-`) && !strings.Contains(data, `type="t-`) {
-					entity, err := cot.CoTToEntity(buffer[:n], "tak", trackerID)
+				if cot.IsFileshareCoT(data) {
+					pushFileshare(ctx, logger, client, buffer[:n], trackerID, identity)
+				} else if cot.IsEmergencyCoT(data) {
+					// The alert links to the sender's a- type, so it must
+					// not be parsed as a position report as well.
+					pushEmergency(ctx, logger, client, buffer[:n], trackerID, identity)
+				} else if strings.Contains(data, `type="a-`) && !strings.Contains(data, `type="t-`) {
+					entity, marking, err := cot.CoTToEntityWithMarking(buffer[:n], "tak", trackerID)
 					if err != nil {
 						logger.Error("Error parsing CoT", "clientID", clientID, "error", err)
 					} else {
 						entity.Routing = &pb.Routing{Channels: []*pb.Channel{{}}}
 						entity.Id = fmt.Sprintf("tak.%s", entity.Id)
+						markings.Store(entity.Id, marking)
 						cot.SetControllerOrigin(entity, trackerID)
+						stampIdentity(entity, identity)
 						logger.Debug("Parsed entity", "clientID", clientID, "id", entity.Id,
 							"callsign", *entity.Label, "lat", entity.Geo.Latitude, "lon", entity.Geo.Longitude)
 
@@ -125,83 +140,153 @@ func handleConn(ctx context.Context, conn net.Conn, serverURL string, logger *sl
 	}()
 
 	// Write outbound entity changes as CoT XML
-	stream, err := goclient.WatchEntitiesWithRetry(ctx, client, &pb.ListEntitiesRequest{})
+	stream, err := goclient.WatchEntitiesWithRetry(ctx, client, &pb.ListEntitiesRequest{Filter: filter})
 	if err != nil {
 		logger.Error("WatchEntities failed", "clientID", clientID, "error", err)
 		return
 	}
 
-	writer := bufio.NewWriter(conn)
 	sentCount := 0
 	connectedAt := time.Now()
 
-	for {
-		event, err := stream.Recv()
-		if err != nil {
-			logger.Error("Stream error", "clientID", clientID, "error", err)
-			return
-		}
+	batcher := newStreamBatcher(conn)
+	batcher.onFlush = func(events int) {
+		sentCount += events
+		logger.Info("Sent entities", "clientID", clientID, "count", events, "total", sentCount)
+	}
 
+	err = pumpCoT(ctx, stream.Recv, batcher, func(event *pb.EntityChangeEvent) []byte {
 		if event.Entity == nil {
-			continue
+			return nil
 		}
 
 		// Anti-loop: don't reflect entities back to the connection that sent them
 		if event.Entity.Controller != nil && event.Entity.Controller.Origin != nil && *event.Entity.Controller.Origin == trackerID {
-			continue
+			return nil
 		}
 
 		// Don't replay old chat messages to newly connected clients
 		if isOldChat(event.Entity, connectedAt) {
-			continue
+			return nil
 		}
 
-		cotXML, cotErr := entityToCoTBytes(event)
+		cotXML, cotErr := entityToCoTBytes(event, opts)
 		if cotErr != nil {
 			logger.Error("Error converting entity", "clientID", clientID, "entityID", event.Entity.Id, "error", cotErr)
-			continue
-		}
-
-		if cotXML == nil {
-			continue
+			return nil
 		}
 
-		if verbose {
+		if verbose && cotXML != nil {
 			logger.Debug("CoT XML", "clientID", clientID, "entityID", event.Entity.Id, "xml", string(cotXML))
 		}
+		return cotXML
+	})
+	if err != nil && ctx.Err() == nil {
+		logger.Error("Stream error", "clientID", clientID, "error", err)
+	}
+}
 
-		if _, err := writer.Write(cotXML); err != nil {
-			logger.Error("Write error", "clientID", clientID, "error", err)
-			return
-		}
+var globalServerURL string
 
-		if err := writer.Flush(); err != nil {
-			logger.Error("Flush error", "clientID", clientID, "error", err)
-			return
-		}
+// withStreamProperties adds the CoT stream options that tcp_server and
+// tcp_client share to the properties of their schema, in the connection
+// group after its address.
+func withStreamProperties(properties map[string]any) map[string]any {
+	maps.Copy(properties, map[string]any{
+		"area": map[string]any{
+			"type":           "string",
+			"title":          "Area of Interest",
+			"description":    "Only send entities inside this bounding box (min_lon,min_lat,max_lon,max_lat); empty sends everything",
+			"ui:placeholder": "e.g. 10.0,47.0,12.0,49.0",
+			"ui:group":       "connection",
+			"ui:order":       1,
+		},
+		"milsym_2525d": map[string]any{
+			"type":        "boolean",
+			"title":       "MIL-STD-2525D Symbols",
+			"description": "Send symbol codes as 2525D instead of 2525C, for clients that render 2525D",
+			"default":     false,
+			"ui:group":    "connection",
+			"ui:order":    2,
+		},
+		"reconnect_max_seconds": reconnectProperty("connection", 3),
+		"stale_minutes": map[string]any{
+			"type":        "number",
+			"title":       "Default Stale Time",
+			"description": "How long TAK clients show entities that have no expiry; 0 keeps them. Entities that expire go stale when they do.",
+			"minimum":     0,
+			"ui:unit":     "min",
+			"ui:group":    "connection",
+			"ui:order":    4,
+		},
+	})
+	return properties
+}
 
-		sentCount++
-		logger.Info("Sent entity", "clientID", clientID, "entityID", event.Entity.Id, "total", sentCount)
+// reconnectProperty is the schema property of reconnect_max_seconds, at
+// order within group or ungrouped if group is empty.
+func reconnectProperty(group string, order int) map[string]any {
+	property := map[string]any{
+		"type":        "number",
+		"title":       "Max Retry Delay",
+		"description": "The delay before retrying doubles after each failure, up to this",
+		"default":     backoff.DefaultMax.Seconds(),
+		"minimum":     1,
+		"ui:unit":     "s",
+		"ui:order":    order,
+	}
+	if group != "" {
+		property["ui:group"] = group
 	}
+	return property
 }
 
-var globalServerURL string
-
 func Run(ctx context.Context, logger *slog.Logger, serverURL string) error {
 	globalServerURL = serverURL
 	controllerName := "tak"
 
 	tcpServerSchema, _ := structpb.NewStruct(map[string]any{
 		"type": "object",
-		"properties": map[string]any{
+		"ui:groups": []any{
+			map[string]any{"key": "connection", "title": "Connection"},
+			map[string]any{"key": "tls", "title": "TLS", "collapsed": true},
+		},
+		"properties": withStreamProperties(map[string]any{
+			controller.ExpireOnTeardownField: controller.ExpireOnTeardownProperty(),
 			"listen": map[string]any{
 				"type":           "string",
 				"title":          "Listen Address",
 				"description":    "TCP address to accept incoming TAK connections",
 				"default":        ":8088",
 				"ui:placeholder": "e.g. :8088 or 0.0.0.0:8088",
+				"ui:group":       "connection",
+				"ui:order":       0,
 			},
-		},
+			"tls_cert": map[string]any{
+				"type":           "string",
+				"title":          "Server Certificate",
+				"description":    "Path to server certificate PEM file; enables TLS",
+				"ui:placeholder": "e.g. ./certs/server.pem",
+				"ui:group":       "tls",
+				"ui:order":       0,
+			},
+			"tls_key": map[string]any{
+				"type":           "string",
+				"title":          "Server Key",
+				"description":    "Path to server key PEM file",
+				"ui:placeholder": "e.g. ./certs/server-key.pem",
+				"ui:group":       "tls",
+				"ui:order":       1,
+			},
+			"tls_ca": map[string]any{
+				"type":           "string",
+				"title":          "Client CA Certificate",
+				"description":    "Path to the CA PEM file that signs client certificates; required with TLS",
+				"ui:placeholder": "e.g. ./certs/ca.pem",
+				"ui:group":       "tls",
+				"ui:order":       2,
+			},
+		}),
 	})
 
 	tcpClientSchema, _ := structpb.NewStruct(map[string]any{
@@ -210,7 +295,8 @@ func Run(ctx context.Context, logger *slog.Logger, serverURL string) error {
 			map[string]any{"key": "connection", "title": "Connection"},
 			map[string]any{"key": "tls", "title": "TLS", "collapsed": true},
 		},
-		"properties": map[string]any{
+		"properties": withStreamProperties(map[string]any{
+			controller.ExpireOnTeardownField: controller.ExpireOnTeardownProperty(),
 			"address": map[string]any{
 				"type":           "string",
 				"title":          "Address",
@@ -259,7 +345,7 @@ func Run(ctx context.Context, logger *slog.Logger, serverURL string) error {
 				"ui:group":       "tls",
 				"ui:order":       4,
 			},
-		},
+		}),
 		"required": []any{"address"},
 	})
 
@@ -282,6 +368,7 @@ func Run(ctx context.Context, logger *slog.Logger, serverURL string) error {
 				"ui:unit":     "Hz",
 				"ui:order":    1,
 			},
+			"reconnect_max_seconds": reconnectProperty("", 2),
 		},
 		"required": []any{"address"},
 	})
@@ -289,6 +376,7 @@ func Run(ctx context.Context, logger *slog.Logger, serverURL string) error {
 	udpReceiveSchema, _ := structpb.NewStruct(map[string]any{
 		"type": "object",
 		"properties": map[string]any{
+			controller.ExpireOnTeardownField: controller.ExpireOnTeardownProperty(),
 			"listen": map[string]any{
 				"type":           "string",
 				"title":          "Listen Address",
@@ -302,6 +390,7 @@ func Run(ctx context.Context, logger *slog.Logger, serverURL string) error {
 	multicastSchema, _ := structpb.NewStruct(map[string]any{
 		"type": "object",
 		"properties": map[string]any{
+			controller.ExpireOnTeardownField: controller.ExpireOnTeardownProperty(),
 			"address": map[string]any{
 				"type":           "string",
 				"title":          "Multicast Address",
@@ -357,6 +446,8 @@ func Run(ctx context.Context, logger *slog.Logger, serverURL string) error {
 		{Class: "multicast", Label: "Multicast", Schema: multicastSchema},
 	}
 
+	go forgetExpired(ctx, logger, globalServerURL)
+
 	return controller.WatchChildren(ctx, serviceID, controllerName, classes, func(ctx context.Context, entityID string) error {
 		return controller.Run(ctx, entityID, func(ctx context.Context, entity *pb.Entity, ready func()) error {
 			ready()
@@ -373,7 +464,7 @@ func Run(ctx context.Context, logger *slog.Logger, serverURL string) error {
 				return runMulticast(ctx, logger, globalServerURL, entity)
 			}
 			return fmt.Errorf("unknown device class: %s", entity.Device.GetClass())
-		})
+		}, controller.WithExpireOnTeardownConfig(controllerName))
 	})
 }
 
@@ -395,6 +486,21 @@ func configFloat32(entity *pb.Entity, key string, fallback float32) float32 {
 	return fallback
 }
 
+// reconnectBackoff returns the retry backoff of entity's connection,
+// capped at its reconnect_max_seconds.
+func reconnectBackoff(entity *pb.Entity) *backoff.Backoff {
+	maxDelay := configFloat32(entity, "reconnect_max_seconds", 0)
+	return &backoff.Backoff{Max: time.Duration(float64(maxDelay) * float64(time.Second))}
+}
+
+// cotOptions returns the CoT rendering options configured on entity.
+func cotOptions(entity *pb.Entity) cot.Options {
+	return cot.Options{
+		Symbol2525D: configBool(entity, "milsym_2525d"),
+		Stale:       time.Duration(float64(configFloat32(entity, "stale_minutes", 0)) * float64(time.Minute)),
+	}
+}
+
 func configBool(entity *pb.Entity, key string) bool {
 	if entity.Config != nil && entity.Config.Value != nil && entity.Config.Value.Fields != nil {
 		if v, ok := entity.Config.Value.Fields[key]; ok {
@@ -409,6 +515,16 @@ func configBool(entity *pb.Entity, key string) bool {
 func runTcpServer(ctx context.Context, logger *slog.Logger, serverURL string, entity *pb.Entity) error {
 	listenAddr := configString(entity, "listen", ":8088")
 
+	tlsConf, err := buildServerTLSConfig(entity)
+	if err != nil {
+		return err
+	}
+	filter, err := parseArea(configString(entity, "area", ""))
+	if err != nil {
+		return err
+	}
+
+	bo := reconnectBackoff(entity)
 	for {
 		select {
 		case <-ctx.Done():
@@ -420,16 +536,22 @@ func runTcpServer(ctx context.Context, logger *slog.Logger, serverURL string, en
 
 		listener, err := net.Listen("tcp", listenAddr)
 		if err != nil {
-			logger.Error("Failed to start server, retrying in 5s", "entityID", entity.Id, "error", err)
+			delay := bo.Next()
+			logger.Error("Failed to start server, retrying", "entityID", entity.Id, "backoff", delay, "error", err)
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-			case <-time.After(5 * time.Second):
+			case <-time.After(delay):
 				continue
 			}
 		}
+		started := time.Now()
 
-		logger.Info("TAK TCP server listening", "entityID", entity.Id, "listenAddr", listenAddr)
+		if tlsConf != nil {
+			listener = tls.NewListener(listener, tlsConf)
+		}
+
+		logger.Info("TAK TCP server listening", "entityID", entity.Id, "listenAddr", listenAddr, "tls", tlsConf != nil)
 
 		done := make(chan struct{})
 		go func() {
@@ -449,11 +571,19 @@ func runTcpServer(ctx context.Context, logger *slog.Logger, serverURL string, en
 					_ = listener.Close()
 					return ctx.Err()
 				}
-				logger.Error("Accept error, restarting in 5s", "entityID", entity.Id, "error", err)
+				logger.Error("Accept error, restarting", "entityID", entity.Id, "error", err)
 				acceptErr = true
 				break
 			}
-			go handleConn(ctx, conn, serverURL, logger, entity.Id)
+			go func() {
+				identity, err := authenticateConn(ctx, conn)
+				if err != nil {
+					logger.Warn("Rejected TAK client", "entityID", entity.Id, "remoteAddr", conn.RemoteAddr(), "error", err)
+					_ = conn.Close()
+					return
+				}
+				handleConn(ctx, conn, serverURL, logger, entity.Id, identity, filter, cotOptions(entity))
+			}()
 		}
 
 		close(done)
@@ -463,10 +593,13 @@ func runTcpServer(ctx context.Context, logger *slog.Logger, serverURL string, en
 			return nil
 		}
 
+		bo.ResetIfHealthy(started)
+		delay := bo.Next()
+		logger.Info("Restarting server", "entityID", entity.Id, "backoff", delay)
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-time.After(5 * time.Second):
+		case <-time.After(delay):
 			continue
 		}
 	}
@@ -484,31 +617,18 @@ func buildTLSConfig(entity *pb.Entity) (*tls.Config, error) {
 	certPath := configString(entity, "tls_cert", "")
 	keyPath := configString(entity, "tls_key", "")
 	if certPath != "" && keyPath != "" {
-		if err := builtin.ValidatePath(certPath); err != nil {
-			return nil, fmt.Errorf("tls_cert: %w", err)
-		}
-		if err := builtin.ValidatePath(keyPath); err != nil {
-			return nil, fmt.Errorf("tls_key: %w", err)
-		}
-		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		cert, err := loadKeyPair(certPath, keyPath)
 		if err != nil {
-			return nil, fmt.Errorf("load client certificate: %w", err)
+			return nil, err
 		}
 		tlsConf.Certificates = []tls.Certificate{cert}
 	}
 
 	caPath := configString(entity, "tls_ca", "")
 	if caPath != "" {
-		if err := builtin.ValidatePath(caPath); err != nil {
-			return nil, fmt.Errorf("tls_ca: %w", err)
-		}
-		caCert, err := os.ReadFile(caPath)
+		pool, err := loadCAPool(caPath)
 		if err != nil {
-			return nil, fmt.Errorf("read CA certificate: %w", err)
-		}
-		pool := x509.NewCertPool()
-		if !pool.AppendCertsFromPEM(caCert) {
-			return nil, fmt.Errorf("failed to parse CA certificate from %s", caPath)
+			return nil, err
 		}
 		tlsConf.RootCAs = pool
 	}
@@ -516,22 +636,57 @@ func buildTLSConfig(entity *pb.Entity) (*tls.Config, error) {
 	return tlsConf, nil
 }
 
+// loadKeyPair reads a PEM certificate and key from allowed paths.
+func loadKeyPair(certPath, keyPath string) (tls.Certificate, error) {
+	if err := builtin.ValidatePath(certPath); err != nil {
+		return tls.Certificate{}, fmt.Errorf("tls_cert: %w", err)
+	}
+	if err := builtin.ValidatePath(keyPath); err != nil {
+		return tls.Certificate{}, fmt.Errorf("tls_key: %w", err)
+	}
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("load certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// loadCAPool reads a PEM CA bundle from an allowed path.
+func loadCAPool(caPath string) (*x509.CertPool, error) {
+	if err := builtin.ValidatePath(caPath); err != nil {
+		return nil, fmt.Errorf("tls_ca: %w", err)
+	}
+	caCert, err := os.ReadFile(caPath)
+	if err != nil {
+		return nil, fmt.Errorf("read CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to parse CA certificate from %s", caPath)
+	}
+	return pool, nil
+}
+
 func runTcpClient(ctx context.Context, logger *slog.Logger, serverURL string, entity *pb.Entity) error {
 	address := configString(entity, "address", "")
 	if address == "" {
 		return fmt.Errorf("address is required")
 	}
+	filter, err := parseArea(configString(entity, "area", ""))
+	if err != nil {
+		return err
+	}
 	useTLS := configBool(entity, "tls")
 
 	var tlsConf *tls.Config
 	if useTLS {
-		var err error
 		tlsConf, err = buildTLSConfig(entity)
 		if err != nil {
 			return err
 		}
 	}
 
+	bo := reconnectBackoff(entity)
 	for {
 		select {
 		case <-ctx.Done():
@@ -549,16 +704,18 @@ func runTcpClient(ctx context.Context, logger *slog.Logger, serverURL string, en
 			conn, err = net.Dial("tcp", address)
 		}
 		if err != nil {
-			logger.Error("Connection failed, retrying in 5s", "entityID", entity.Id, "error", err)
+			delay := bo.Next()
+			logger.Error("Connection failed, retrying", "entityID", entity.Id, "backoff", delay, "error", err)
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-			case <-time.After(5 * time.Second):
+			case <-time.After(delay):
 				continue
 			}
 		}
 
 		logger.Info("Connected to TAK server", "entityID", entity.Id, "address", address)
+		started := time.Now()
 
 		// handleConn blocks until the connection drops
 		done := make(chan struct{})
@@ -570,7 +727,7 @@ func runTcpClient(ctx context.Context, logger *slog.Logger, serverURL string, en
 			}
 		}()
 
-		handleConn(ctx, conn, serverURL, logger, entity.Id)
+		handleConn(ctx, conn, serverURL, logger, entity.Id, "", filter, cotOptions(entity))
 		_ = conn.Close()
 		close(done)
 
@@ -578,11 +735,15 @@ func runTcpClient(ctx context.Context, logger *slog.Logger, serverURL string, en
 			return ctx.Err()
 		}
 
-		logger.Info("Disconnected, reconnecting in 5s", "entityID", entity.Id)
+		// A server that accepts and then drops connections backs off as
+		// well; one that held the connection for a while starts over.
+		bo.ResetIfHealthy(started)
+		delay := bo.Next()
+		logger.Info("Disconnected, reconnecting", "entityID", entity.Id, "backoff", delay)
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-time.After(5 * time.Second):
+		case <-time.After(delay):
 			continue
 		}
 	}
@@ -602,79 +763,40 @@ func runUdpSend(ctx context.Context, logger *slog.Logger, serverURL string, enti
 		return fmt.Errorf("resolve address: %w", err)
 	}
 
-	udpConn, err := net.DialUDP("udp", nil, destAddr)
-	if err != nil {
-		return fmt.Errorf("dial UDP: %w", err)
-	}
-	defer func() { _ = udpConn.Close() }()
-
-	logger.Info("UDP send started", "entityID", entity.Id, "destination", address)
-
-	grpcConn, err := grpc.NewClient(serverURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		return err
-	}
-	defer func() { _ = grpcConn.Close() }()
-
-	client := pb.NewWorldServiceClient(grpcConn)
-
-	req := &pb.ListEntitiesRequest{}
-	if maxRateHz > 0 {
-		req.Behaviour = &pb.WatchBehavior{MaxRateHz: &maxRateHz}
-		logger.Info("Rate limiting enabled", "maxRateHz", maxRateHz)
-	}
-
-	stream, err := goclient.WatchEntitiesWithRetry(ctx, client, req)
-	if err != nil {
-		return err
-	}
-
-	var entitiesSent uint64
-	startedAt := time.Now()
+	bo := reconnectBackoff(entity)
 	for {
-		select {
-		case <-ctx.Done():
+		started := time.Now()
+		err := runUdpSender(ctx, logger, serverURL, entity.Id, destAddr, maxRateHz)
+		if ctx.Err() != nil {
 			return ctx.Err()
-		default:
-		}
-
-		event, err := stream.Recv()
-		if err != nil {
-			return err
-		}
-
-		if event.Entity == nil {
-			continue
 		}
 
-		if isOldChat(event.Entity, startedAt) {
-			continue
+		// A sender that ran for a while was healthy; start over with a
+		// short backoff instead of carrying over an old long one.
+		bo.ResetIfHealthy(started)
+		delay := bo.Next()
+		logger.Error("UDP send error, reconnecting", "entityID", entity.Id, "destination", address, "backoff", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
 		}
+	}
+}
 
-		cotXML, cotErr := entityToCoTBytes(event)
-		if cotErr != nil {
-			logger.Error("Error converting entity", "entityID", event.Entity.Id, "error", cotErr)
-			continue
-		}
-		if cotXML == nil {
-			continue
-		}
+// runUdpSender dials destAddr and streams CoT to it until a send fails. On a
+// connected UDP socket, a peer that is down surfaces as a write error (ICMP
+// port unreachable), which ends the run so runUdpSend can redial.
+func runUdpSender(ctx context.Context, logger *slog.Logger, serverURL string, entityID string, destAddr *net.UDPAddr, maxRateHz float32) error {
+	udpConn, err := net.DialUDP("udp", nil, destAddr)
+	if err != nil {
+		return fmt.Errorf("dial UDP: %w", err)
+	}
+	defer func() { _ = udpConn.Close() }()
 
-		if _, err := udpConn.Write(cotXML); err != nil {
-			logger.Error("UDP write error", "error", err)
-			continue
-		}
+	logger.Info("UDP send started", "entityID", entityID, "destination", destAddr)
 
-		entitiesSent++
-		_, _ = client.Push(ctx, &pb.EntityChangeRequest{
-			Changes: []*pb.Entity{{
-				Id: entity.Id,
-				Metric: &pb.MetricComponent{Metrics: []*pb.Metric{
-					{Kind: pb.MetricKind_MetricKindCount.Enum(), Unit: pb.MetricUnit_MetricUnitCount, Label: proto.String("entities sent"), Id: proto.Uint32(1), Val: &pb.Metric_Uint64{Uint64: entitiesSent}},
-				}},
-			}},
-		})
-	}
+	return sendCoTDatagrams(ctx, logger, serverURL, udpConn, entityID, maxRateHz, false)
 }
 
 // --- UDP Receive ---
@@ -703,7 +825,7 @@ func runUdpReceive(ctx context.Context, logger *slog.Logger, serverURL string, e
 
 	logger.Info("UDP receive started", "entityID", entity.Id, "listenAddr", listenAddr)
 
-	grpcConn, err := grpc.NewClient(serverURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	grpcConn, err := builtin.ServerConn(serverURL)
 	if err != nil {
 		return err
 	}
@@ -749,7 +871,17 @@ func runUdpReceive(ctx context.Context, logger *slog.Logger, serverURL string, e
 			continue
 		}
 
-		ent, err := cot.CoTToEntity(buffer[:n], "tak", entity.Id)
+		if cot.IsFileshareCoT(data) {
+			pushFileshare(ctx, logger, client, buffer[:n], entity.Id, "")
+			continue
+		}
+
+		if cot.IsEmergencyCoT(data) {
+			pushEmergency(ctx, logger, client, buffer[:n], entity.Id, "")
+			continue
+		}
+
+		ent, marking, err := cot.CoTToEntityWithMarking(buffer[:n], "tak", entity.Id)
 		if err != nil {
 			logger.Error("Error parsing CoT", "error", err)
 			continue
@@ -757,6 +889,7 @@ func runUdpReceive(ctx context.Context, logger *slog.Logger, serverURL string, e
 
 		ent.Routing = &pb.Routing{Channels: []*pb.Channel{{}}}
 		ent.Id = fmt.Sprintf("tak.%s", ent.Id)
+		markings.Store(ent.Id, marking)
 		cot.SetControllerOrigin(ent, entity.Id)
 
 		if _, err := client.Push(ctx, &pb.EntityChangeRequest{Changes: []*pb.Entity{ent}}); err != nil {
@@ -825,7 +958,17 @@ func runMulticastBroadcaster(ctx context.Context, logger *slog.Logger, serverURL
 
 	logger.Info("UDP multicast connection", "local", udpConn.LocalAddr(), "multicast", multicastAddress)
 
-	grpcConn, err := grpc.NewClient(serverURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	// Multicast has no peer that could be down; a failed send is logged
+	// and the stream keeps going.
+	return sendCoTDatagrams(ctx, logger, serverURL, udpConn, entityID, maxRateHz, true)
+}
+
+// sendCoTDatagrams watches the world and writes every entity as CoT to w,
+// one batch per datagram, and reports the number of entities sent as a
+// metric on metricEntityID. With dropWriteErrors a failed write is logged and
+// skipped; otherwise it ends the stream with an error.
+func sendCoTDatagrams(ctx context.Context, logger *slog.Logger, serverURL string, w io.Writer, metricEntityID string, maxRateHz float32, dropWriteErrors bool) error {
+	grpcConn, err := builtin.ServerConn(serverURL)
 	if err != nil {
 		return err
 	}
@@ -846,69 +989,146 @@ func runMulticastBroadcaster(ctx context.Context, logger *slog.Logger, serverURL
 
 	var entitiesSent uint64
 	startedAt := time.Now()
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
 
-		event, err := stream.Recv()
-		if err != nil {
-			return err
+	batcher := newDatagramBatcher(w)
+	if dropWriteErrors {
+		batcher.onWriteError = func(err error) {
+			logger.Error("UDP write error", "error", err)
 		}
+	}
+	batcher.onFlush = func(events int) {
+		entitiesSent += uint64(events)
+		_, _ = client.Push(ctx, &pb.EntityChangeRequest{
+			Changes: []*pb.Entity{{
+				Id: metricEntityID,
+				Metric: &pb.MetricComponent{Metrics: []*pb.Metric{
+					{Kind: pb.MetricKind_MetricKindCount.Enum(), Unit: pb.MetricUnit_MetricUnitCount, Label: proto.String("entities sent"), Id: proto.Uint32(1), Val: &pb.Metric_Uint64{Uint64: entitiesSent}},
+				}},
+			}},
+		})
+	}
 
+	return pumpCoT(ctx, stream.Recv, batcher, func(event *pb.EntityChangeEvent) []byte {
 		if event.Entity == nil {
-			continue
+			return nil
 		}
 
 		if isOldChat(event.Entity, startedAt) {
-			continue
+			return nil
 		}
 
-		cotXML, cotErr := entityToCoTBytes(event)
+		cotXML, cotErr := entityToCoTBytes(event, cot.Options{})
 		if cotErr != nil {
 			logger.Error("Error converting entity", "entityID", event.Entity.Id, "error", cotErr)
-			continue
+			return nil
 		}
 		if cotXML == nil {
-			continue
+			return nil
 		}
 
 		if verbose {
 			logger.Debug("CoT XML", "entityID", event.Entity.Id, "xml", string(cotXML))
 		}
-
-		if _, err := udpConn.Write(cotXML); err != nil {
-			logger.Error("UDP write error", "error", err)
-			continue
-		}
-
-		entitiesSent++
-		_, _ = client.Push(ctx, &pb.EntityChangeRequest{
-			Changes: []*pb.Entity{{
-				Id: entityID,
-				Metric: &pb.MetricComponent{Metrics: []*pb.Metric{
-					{Kind: pb.MetricKind_MetricKindCount.Enum(), Unit: pb.MetricUnit_MetricUnitCount, Label: proto.String("entities sent"), Id: proto.Uint32(1), Val: &pb.Metric_Uint64{Uint64: entitiesSent}},
-				}},
-			}},
-		})
-	}
+		return cotXML
+	})
 }
 
 // --- Helpers ---
 
-func entityToCoTBytes(event *pb.EntityChangeEvent) ([]byte, error) {
-	if event.T == pb.EntityChange_EntityChangeExpired {
+func entityToCoTBytes(event *pb.EntityChangeEvent, opts cot.Options) ([]byte, error) {
+	// Unobserved means the entity left the client's filter, e.g. its area
+	// of interest; remove it from the client's map like an expired one.
+	if event.T == pb.EntityChange_EntityChangeExpired || event.T == pb.EntityChange_EntityChangeUnobserved {
+		if event.T == pb.EntityChange_EntityChangeExpired {
+			fileshares.Delete(event.Entity.Id)
+			markings.Delete(event.Entity.Id)
+		}
+		if event.Entity.GetNavigation().GetEmergency() {
+			return cot.EntityEmergencyCancelCoT(event.Entity)
+		}
 		return cot.EntityDeleteCoT(event.Entity)
 	}
+	if event.Entity.GetNavigation().GetEmergency() {
+		return cot.EntityToEmergencyCoT(event.Entity)
+	}
+	if fs, ok := fileshares.Load(event.Entity.Id); ok {
+		return cot.EntityToFileshareCoT(event.Entity, fs)
+	}
 	if event.Entity.Chat != nil {
 		return cot.EntityToChatCoT(event.Entity)
 	}
 	if event.Entity.Shape != nil {
 		return cot.EntityToShapeCoT(event.Entity)
 	}
-	return cot.EntityToCoT(event.Entity)
+	if m, ok := markings.Load(event.Entity.Id); ok {
+		opts.Marking = m
+	}
+	return cot.EntityToCoTWithOptions(event.Entity, opts)
+}
+
+// markings holds the how and access attributes of the events received from
+// TAK clients, by entity id, so that the entities are sent on with the
+// classification marking they came with. Hydris has no component for
+// either, so the marking exists only in this process: an entity is sent
+// to TAK clients without its access marking, however it was classified,
+// once this node restarted, once the marking was evicted from the table,
+// or when the entity reached this node by federation. GeoJSON, KML and
+// federation never carry the marking.
+var markings = newSideTable[cot.Marking](maxSideEntries)
+
+// pushEmergency pushes an emergency alert CoT as an entity, or expires the
+// alert entity when the CoT cancels it.
+func pushEmergency(ctx context.Context, logger *slog.Logger, client pb.WorldServiceClient, data []byte, trackerID string, identity string) {
+	entity, cancel, err := cot.CoTEmergencyToEntity(data, "tak", trackerID)
+	if err != nil {
+		logger.Error("Error parsing emergency CoT", "error", err)
+		return
+	}
+	entity.Id = fmt.Sprintf("tak.%s", entity.Id)
+
+	if cancel {
+		if _, err := client.ExpireEntity(ctx, &pb.ExpireEntityRequest{Id: entity.Id}); err != nil {
+			logger.Error("Error expiring emergency", "entityID", entity.Id, "error", err)
+		} else {
+			logger.Info("Emergency cancelled", "entityID", entity.Id)
+		}
+		return
+	}
+
+	entity.Routing = &pb.Routing{Channels: []*pb.Channel{{}}}
+	stampIdentity(entity, identity)
+	if _, err := client.Push(ctx, &pb.EntityChangeRequest{Changes: []*pb.Entity{entity}}); err != nil {
+		logger.Error("Error pushing emergency", "entityID", entity.Id, "error", err)
+	} else {
+		logger.Warn("Emergency received", "entityID", entity.Id, "callsign", entity.GetLabel())
+	}
+}
+
+// fileshares holds the file transfer requests received from TAK clients,
+// by entity id, so they can be re-emitted to the other clients as they
+// were sent. Without it, e.g. after a restart, the announcement still goes
+// out as a GeoChat message.
+var fileshares = newSideTable[*cot.Fileshare](maxSideEntries)
+
+// pushFileshare pushes a file transfer request as a chat entity announcing
+// the file. The file itself is not transferred.
+func pushFileshare(ctx context.Context, logger *slog.Logger, client pb.WorldServiceClient, data []byte, trackerID string, identity string) {
+	entity, fs, err := cot.CoTFileshareToEntity(data, "tak", trackerID)
+	if err != nil {
+		logger.Error("Error parsing file transfer CoT", "error", err)
+		return
+	}
+	entity.Id = fmt.Sprintf("tak.%s", entity.Id)
+	entity.Routing = &pb.Routing{Channels: []*pb.Channel{{}}}
+	stampIdentity(entity, identity)
+
+	fileshares.Store(entity.Id, fs)
+	if _, err := client.Push(ctx, &pb.EntityChangeRequest{Changes: []*pb.Entity{entity}}); err != nil {
+		fileshares.Delete(entity.Id)
+		logger.Error("Error pushing file share", "entityID", entity.Id, "error", err)
+	} else {
+		logger.Info("File shared", "entityID", entity.Id, "file", fs.Filename, "url", fs.SenderURL)
+	}
 }
 
 // isOldChat returns true if the entity is a chat message created before the
@@ -0,0 +1,38 @@
+package view
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/projectqai/hydris/pkg/cot"
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestEntityToCoTBytes_ReemitsFileshare(t *testing.T) {
+	fs := &cot.Fileshare{Filename: "recon.zip", SenderURL: "https://tak.example/getfile?file=recon.zip"}
+	entity := &pb.Entity{Id: "tak.share-1", Chat: &pb.ChatComponent{Message: "VIPER shared recon.zip"}}
+
+	out, err := entityToCoTBytes(&pb.EntityChangeEvent{T: pb.EntityChange_EntityChangeUpdated, Entity: entity}, cot.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `type="b-t-f"`) {
+		t.Errorf("unknown file share should go out as GeoChat, got %s", out)
+	}
+
+	fileshares.Store(entity.Id, fs)
+	out, err = entityToCoTBytes(&pb.EntityChangeEvent{T: pb.EntityChange_EntityChangeUpdated, Entity: entity}, cot.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `type="b-f-t-r"`) || !strings.Contains(string(out), `uid="share-1"`) || !strings.Contains(string(out), fs.SenderURL) {
+		t.Errorf("expected the file transfer request, got %s", out)
+	}
+
+	if _, err := entityToCoTBytes(&pb.EntityChangeEvent{T: pb.EntityChange_EntityChangeExpired, Entity: entity}, cot.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fileshares.Load(entity.Id); ok {
+		t.Error("expired file share still registered")
+	}
+}
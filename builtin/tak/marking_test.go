@@ -0,0 +1,29 @@
+package view
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/projectqai/hydris/pkg/cot"
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestEntityToCoTBytes_KeepsMarking(t *testing.T) {
+	entity := &pb.Entity{Id: "tak.ANDROID-1234", Geo: &pb.GeoSpatialComponent{Latitude: 48.1, Longitude: 11.5}}
+	markings.Store(entity.Id, cot.Marking{How: "h-e", Access: "SECRET"})
+
+	out, err := entityToCoTBytes(&pb.EntityChangeEvent{T: pb.EntityChange_EntityChangeUpdated, Entity: entity}, cot.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `access="SECRET"`) || !strings.Contains(string(out), `how="h-e"`) {
+		t.Errorf("marking not re-emitted: %s", out)
+	}
+
+	if _, err := entityToCoTBytes(&pb.EntityChangeEvent{T: pb.EntityChange_EntityChangeExpired, Entity: entity}, cot.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := markings.Load(entity.Id); ok {
+		t.Error("expired entity's marking still registered")
+	}
+}
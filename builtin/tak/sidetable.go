@@ -0,0 +1,109 @@
+package view
+
+import (
+	"container/list"
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/projectqai/hydris/builtin"
+	"github.com/projectqai/hydris/goclient"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxSideEntries bounds each side table. TAK clients may send any number of
+// distinct uids; beyond this many the entry stored longest ago is dropped.
+const maxSideEntries = 10000
+
+// sideTable holds, by entity id, what the events received from TAK clients
+// carry that hydris has no component for. Entries are removed when the
+// entity expires in the engine (see forgetExpired), and the table drops
+// the entry stored longest ago once it holds max entries.
+type sideTable[T any] struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]*list.Element
+	order   *list.List // of *sideEntry[T], most recently stored last
+}
+
+type sideEntry[T any] struct {
+	id    string
+	value T
+}
+
+func newSideTable[T any](max int) *sideTable[T] {
+	return &sideTable[T]{max: max, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+func (t *sideTable[T]) Store(id string, value T) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if el, ok := t.entries[id]; ok {
+		el.Value.(*sideEntry[T]).value = value
+		t.order.MoveToBack(el)
+		return
+	}
+	t.entries[id] = t.order.PushBack(&sideEntry[T]{id: id, value: value})
+	for t.order.Len() > t.max {
+		oldest := t.order.Remove(t.order.Front()).(*sideEntry[T])
+		delete(t.entries, oldest.id)
+	}
+}
+
+func (t *sideTable[T]) Load(id string) (T, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if el, ok := t.entries[id]; ok {
+		return el.Value.(*sideEntry[T]).value, true
+	}
+	var zero T
+	return zero, false
+}
+
+func (t *sideTable[T]) Delete(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if el, ok := t.entries[id]; ok {
+		t.order.Remove(el)
+		delete(t.entries, id)
+	}
+}
+
+func (t *sideTable[T]) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.order.Len()
+}
+
+// forgetExpired removes the side data of the entities TAK clients sent
+// once the engine expires them. The senders only see the expiry of the
+// entities that pass their filter, and a node that only receives has no
+// sender at all.
+func forgetExpired(ctx context.Context, logger *slog.Logger, serverURL string) {
+	grpcConn, err := builtin.ServerConn(serverURL)
+	if err != nil {
+		logger.Error("gRPC connection failed", "error", err)
+		return
+	}
+	defer func() { _ = grpcConn.Close() }()
+
+	client := pb.NewWorldServiceClient(grpcConn)
+	stream, err := goclient.WatchEntitiesWithRetry(ctx, client, &pb.ListEntitiesRequest{
+		Filter: &pb.EntityFilter{Controller: &pb.ControllerFilter{Id: proto.String("tak")}},
+	})
+	if err != nil {
+		logger.Error("WatchEntities failed", "error", err)
+		return
+	}
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			return
+		}
+		if event.T == pb.EntityChange_EntityChangeExpired && event.Entity != nil {
+			markings.Delete(event.Entity.Id)
+			fileshares.Delete(event.Entity.Id)
+		}
+	}
+}
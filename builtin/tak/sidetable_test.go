@@ -0,0 +1,31 @@
+package view
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSideTable_EvictsLeastRecentlyStored(t *testing.T) {
+	table := newSideTable[int](3)
+	for i := range 3 {
+		table.Store(fmt.Sprint(i), i)
+	}
+	// Storing 0 again makes 1 the oldest entry.
+	table.Store("0", 10)
+	table.Store("3", 3)
+
+	if table.Len() != 3 {
+		t.Fatalf("table holds %d entries, want 3", table.Len())
+	}
+	if _, ok := table.Load("1"); ok {
+		t.Error("oldest entry 1 not evicted")
+	}
+	if v, ok := table.Load("0"); !ok || v != 10 {
+		t.Errorf("entry 0 = %d, %v, want 10", v, ok)
+	}
+
+	table.Delete("3")
+	if _, ok := table.Load("3"); ok || table.Len() != 2 {
+		t.Errorf("entry 3 still stored after Delete, %d entries", table.Len())
+	}
+}
@@ -0,0 +1,114 @@
+package view
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydris/pkg/backoff"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestRunUdpSend_DeliversEntities(t *testing.T) {
+	w, addr := startWorld(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := w.Push(ctx, connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{
+		{Id: "unicast-track", Label: proto.String("track"), Geo: &pb.GeoSpatialComponent{Latitude: 48, Longitude: 11}},
+	}})); err != nil {
+		t.Fatal(err)
+	}
+
+	peer, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = peer.Close() }()
+
+	entity := configEntity(t, map[string]any{"address": peer.LocalAddr().String()})
+	entity.Id = "tak.unicast"
+	go func() { _ = runUdpSend(ctx, discardLogger(), addr, entity) }()
+
+	buf := make([]byte, 64<<10)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_ = peer.SetReadDeadline(deadline)
+		n, _, err := peer.ReadFromUDP(buf)
+		if err != nil {
+			t.Fatalf("no CoT received: %v", err)
+		}
+		if strings.Contains(string(buf[:n]), `uid="unicast-track"`) {
+			return
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("connection refused") }
+
+// TestSendCoTDatagrams_WriteErrors checks that a unicast sender stops on a
+// failed send so it can reconnect, while multicast keeps going.
+func TestSendCoTDatagrams_WriteErrors(t *testing.T) {
+	w, addr := startWorld(t)
+	if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{
+		{Id: "track", Geo: &pb.GeoSpatialComponent{Latitude: 48, Longitude: 11}},
+	}})); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := sendCoTDatagrams(ctx, discardLogger(), addr, failingWriter{}, "tak.unicast", 0, false)
+	if err == nil || ctx.Err() != nil {
+		t.Fatalf("unicast sender should fail on write error, got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	err = sendCoTDatagrams(ctx, discardLogger(), addr, failingWriter{}, "tak.multicast", 0, true)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("multicast sender should keep going, got %v", err)
+	}
+}
+
+// maxJitter makes every backoff delay its ceiling, so tests can assert on
+// it.
+func maxJitter(n int64) int64 { return n - 1 }
+
+func TestReconnectBackoff_ConfiguredCap(t *testing.T) {
+	value, _ := structpb.NewStruct(map[string]any{"reconnect_max_seconds": 4})
+	bo := reconnectBackoff(&pb.Entity{Config: &pb.ConfigurationComponent{Value: value}})
+	bo.Rand = maxJitter
+
+	want := []time.Duration{1, 2, 4, 4, 4}
+	for i, w := range want {
+		if got := bo.Next(); got != w*time.Second {
+			t.Fatalf("delay %d after repeated failures = %v, want %v", i, got, w*time.Second)
+		}
+	}
+
+	// Without a configured cap the default applies.
+	bo = reconnectBackoff(&pb.Entity{})
+	bo.Rand = maxJitter
+	var last time.Duration
+	for range 10 {
+		last = bo.Next()
+	}
+	if last != backoff.DefaultMax {
+		t.Errorf("default cap %v, want %v", last, backoff.DefaultMax)
+	}
+}
@@ -0,0 +1,197 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/projectqai/hydris/pkg/backoff"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the
+	// body, keyed with the configured secret.
+	SignatureHeader = "X-Hydris-Signature"
+	// EventHeader carries the change type, e.g. "updated" or "expired".
+	EventHeader = "X-Hydris-Event"
+)
+
+// parseTemplate parses a payload template. Besides the builtins it
+// provides "json", which encodes a value as a JSON literal.
+func parseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("payload").Option("missingkey=zero").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// render produces the request body for event: the event as protojson, or
+// the template executed on that JSON.
+func render(tmpl *template.Template, event *pb.EntityChangeEvent) ([]byte, error) {
+	raw, err := protojson.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl == nil {
+		return raw, nil
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("template output is not valid JSON")
+	}
+	return buf.Bytes(), nil
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// eventName is the lower-case change type without its prefix.
+func eventName(t pb.EntityChange) string {
+	return strings.ToLower(strings.TrimPrefix(t.String(), "EntityChange"))
+}
+
+type sender struct {
+	config *Config
+	logger *slog.Logger
+	client *http.Client
+}
+
+func newSender(config *Config, logger *slog.Logger) *sender {
+	return &sender{
+		config: config,
+		logger: logger,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// run delivers events until ctx is cancelled or events is closed. Changes
+// are held for the debounce window; within it only the latest change of
+// each entity is kept, and entities are sent in the order they first
+// changed.
+func (s *sender) run(ctx context.Context, events <-chan *pb.EntityChangeEvent) {
+	pending := make(map[string]*pb.EntityChangeEvent)
+	var order []string
+	var window <-chan time.Time
+
+	flush := func() {
+		for _, id := range order {
+			if err := s.post(ctx, pending[id]); err != nil {
+				s.logger.Error("webhook delivery failed", "entityID", id, "error", err)
+			}
+		}
+		clear(pending)
+		order = order[:0]
+		window = nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				flush()
+				return
+			}
+			if event.Entity == nil {
+				continue
+			}
+			id := event.Entity.Id
+			if _, seen := pending[id]; !seen {
+				order = append(order, id)
+			}
+			pending[id] = event
+			if s.config.Debounce <= 0 {
+				flush()
+			} else if window == nil {
+				window = time.After(s.config.Debounce)
+			}
+		case <-window:
+			flush()
+		}
+	}
+}
+
+// post sends one event, retrying network errors, 429 and 5xx responses
+// with jittered exponential backoff from Config.Backoff.
+func (s *sender) post(ctx context.Context, event *pb.EntityChangeEvent) error {
+	body, err := render(s.config.Template, event)
+	if err != nil {
+		return fmt.Errorf("render payload: %w", err)
+	}
+
+	bo := backoff.Backoff{Min: s.config.Backoff}
+	for attempt := 0; ; attempt++ {
+		retry, err := s.do(ctx, event.T, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= s.config.MaxRetries {
+			return err
+		}
+		s.logger.Debug("webhook delivery failed, retrying", "attempt", attempt+1, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(bo.Next()):
+		}
+	}
+}
+
+func (s *sender) do(ctx context.Context, t pb.EntityChange, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventName(t))
+	if s.config.Secret != "" {
+		req.Header.Set(SignatureHeader, sign(s.config.Secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("endpoint returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+}
@@ -0,0 +1,218 @@
+// Package webhook POSTs entity changes to HTTP endpoints.
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/projectqai/hydris/builtin"
+	"github.com/projectqai/hydris/builtin/controller"
+	"github.com/projectqai/hydris/goclient"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type Config struct {
+	URL        string
+	Filter     *pb.EntityFilter
+	Template   *template.Template
+	Secret     string
+	MaxRetries int
+	Backoff    time.Duration
+	Debounce   time.Duration
+}
+
+func init() {
+	builtin.Register("webhook", Run)
+}
+
+func Run(ctx context.Context, logger *slog.Logger, _ string) error {
+	controllerName := "webhook"
+
+	schema, _ := structpb.NewStruct(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"url": map[string]any{
+				"type":           "string",
+				"title":          "URL",
+				"description":    "Endpoint that receives a POST for every change",
+				"ui:placeholder": "e.g. https://example.com/hooks/hydris",
+				"ui:order":       0,
+			},
+			"filter": map[string]any{
+				"type":        "object",
+				"title":       "Filter",
+				"description": "Entity filter to select which changes are sent",
+				"ui:order":    1,
+			},
+			"template": map[string]any{
+				"type":        "string",
+				"title":       "Payload Template",
+				"description": "Go template producing the JSON body, executed on the change event as JSON. Empty sends the event itself",
+				"ui:widget":   "textarea",
+				"ui:order":    2,
+			},
+			"secret": map[string]any{
+				"type":        "string",
+				"title":       "Signing Secret",
+				"description": "Signs each body with HMAC-SHA256 in the " + SignatureHeader + " header",
+				"ui:widget":   "password",
+				"ui:order":    3,
+			},
+			"debounce_ms": map[string]any{
+				"type":        "number",
+				"title":       "Debounce",
+				"description": "Changes to the same entity within this window are sent once",
+				"default":     1000,
+				"minimum":     0,
+				"ui:unit":     "ms",
+				"ui:group":    "delivery",
+				"ui:order":    0,
+			},
+			"max_retries": map[string]any{
+				"type":        "number",
+				"title":       "Max Retries",
+				"description": "Retries after a network error or 5xx response",
+				"default":     3,
+				"minimum":     0,
+				"ui:group":    "delivery",
+				"ui:order":    1,
+			},
+		},
+		"required": []any{"url"},
+	})
+
+	serviceEntityID := controllerName + ".service"
+	if err := controller.Push(ctx, &pb.Entity{
+		Id:    serviceEntityID,
+		Label: proto.String("Webhooks"),
+		Controller: &pb.Controller{
+			Id: &controllerName,
+		},
+		Device: &pb.DeviceComponent{
+			Category: proto.String("Network"),
+		},
+		Configurable: &pb.ConfigurableComponent{
+			SupportedDeviceClasses: []*pb.DeviceClassOption{
+				{Class: "webhook", Label: "Webhook"},
+			},
+		},
+		Interactivity: &pb.InteractivityComponent{
+			Icon: proto.String("network"),
+		},
+	}); err != nil {
+		return fmt.Errorf("push service entity: %w", err)
+	}
+
+	classes := []controller.DeviceClass{
+		{Class: "webhook", Label: "Webhook", Schema: schema},
+	}
+
+	return controller.WatchChildren(ctx, serviceEntityID, controllerName, classes, func(ctx context.Context, entityID string) error {
+		return controller.Run(ctx, entityID, func(ctx context.Context, entity *pb.Entity, ready func()) error {
+			return runWebhook(ctx, logger, entity, ready)
+		})
+	})
+}
+
+func runWebhook(ctx context.Context, logger *slog.Logger, entity *pb.Entity, ready func()) error {
+	config, err := parseConfig(entity)
+	if err != nil {
+		return err
+	}
+
+	grpcConn, err := builtin.BuiltinClientConn()
+	if err != nil {
+		return fmt.Errorf("gRPC connection: %w", err)
+	}
+	defer func() { _ = grpcConn.Close() }()
+
+	client := pb.NewWorldServiceClient(grpcConn)
+	stream, err := goclient.WatchEntitiesWithRetry(ctx, client, &pb.ListEntitiesRequest{
+		Filter: config.Filter,
+	})
+	if err != nil {
+		return err
+	}
+	ready()
+
+	events := make(chan *pb.EntityChangeEvent, 64)
+	recvErr := make(chan error, 1)
+	go func() {
+		defer close(events)
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	newSender(config, logger.With("webhook", entity.Id)).run(ctx, events)
+
+	select {
+	case err := <-recvErr:
+		return err
+	default:
+		return ctx.Err()
+	}
+}
+
+func parseConfig(entity *pb.Entity) (*Config, error) {
+	config := &Config{
+		MaxRetries: 3,
+		Backoff:    time.Second,
+		Debounce:   time.Second,
+	}
+	if entity.Config == nil || entity.Config.Value == nil || entity.Config.Value.Fields == nil {
+		return nil, fmt.Errorf("url field is required")
+	}
+
+	fields := entity.Config.Value.Fields
+	if v, ok := fields["url"]; ok {
+		config.URL = v.GetStringValue()
+	}
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("url %q must be an http or https URL", config.URL)
+	}
+
+	if v, ok := fields["filter"]; ok && v.GetStructValue() != nil {
+		raw, err := protojson.Marshal(v.GetStructValue())
+		if err != nil {
+			return nil, fmt.Errorf("filter: %w", err)
+		}
+		config.Filter = &pb.EntityFilter{}
+		if err := protojson.Unmarshal(raw, config.Filter); err != nil {
+			return nil, fmt.Errorf("filter: %w", err)
+		}
+	}
+	if v, ok := fields["template"]; ok && v.GetStringValue() != "" {
+		config.Template, err = parseTemplate(v.GetStringValue())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := fields["secret"]; ok {
+		config.Secret = v.GetStringValue()
+	}
+	if v, ok := fields["debounce_ms"]; ok {
+		config.Debounce = time.Duration(v.GetNumberValue()) * time.Millisecond
+	}
+	if v, ok := fields["max_retries"]; ok {
+		config.MaxRetries = int(v.GetNumberValue())
+	}
+	return config, nil
+}
@@ -0,0 +1,216 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+// recorder answers with the queued status codes, then 200, and keeps every
+// request it received.
+type recorder struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []request
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	r.requests = append(r.requests, request{req.Header.Clone(), body})
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	r.mu.Unlock()
+	w.WriteHeader(status)
+}
+
+func (r *recorder) received() []request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]request(nil), r.requests...)
+}
+
+func testSender(url string) *sender {
+	return newSender(&Config{
+		URL:        url,
+		MaxRetries: 3,
+		Backoff:    time.Millisecond,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func updated(id, label string) *pb.EntityChangeEvent {
+	return &pb.EntityChangeEvent{
+		T:      pb.EntityChange_EntityChangeUpdated,
+		Entity: &pb.Entity{Id: id, Label: proto.String(label)},
+	}
+}
+
+func TestPost_RawEventWithSignature(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	s := testSender(srv.URL)
+	s.config.Secret = "s3cret"
+	if err := s.post(context.Background(), updated("ship1", "Ship")); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := rec.received()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests", len(reqs))
+	}
+	var payload struct {
+		Entity struct{ ID, Label string }
+		T      string
+	}
+	if err := json.Unmarshal(reqs[0].body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Entity.ID != "ship1" || payload.Entity.Label != "Ship" || payload.T != "EntityChangeUpdated" {
+		t.Errorf("payload %s", reqs[0].body)
+	}
+	if got, want := reqs[0].header.Get(SignatureHeader), sign("s3cret", reqs[0].body); got != want {
+		t.Errorf("signature %q, want %q", got, want)
+	}
+	if got := reqs[0].header.Get(EventHeader); got != "updated" {
+		t.Errorf("event header %q", got)
+	}
+}
+
+func TestPost_Template(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	s := testSender(srv.URL)
+	var err error
+	s.config.Template, err = parseTemplate(`{"text": {{json (printf "%s moved" .entity.label)}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.post(context.Background(), updated("ship1", `Ship "A"`)); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := rec.received()
+	if len(reqs) != 1 || string(reqs[0].body) != `{"text": "Ship \"A\" moved"}` {
+		t.Errorf("got %v", reqs)
+	}
+	if reqs[0].header.Get(SignatureHeader) != "" {
+		t.Error("unsigned without secret")
+	}
+
+	s.config.Template, _ = parseTemplate(`not json`)
+	if err := s.post(context.Background(), updated("ship1", "Ship")); err == nil {
+		t.Error("invalid template output should fail")
+	}
+}
+
+func TestPost_RetriesServerErrors(t *testing.T) {
+	rec := &recorder{statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	if err := testSender(srv.URL).post(context.Background(), updated("ship1", "Ship")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rec.received()); n != 3 {
+		t.Errorf("expected 2 retries, got %d requests", n)
+	}
+
+	rec = &recorder{statuses: []int{http.StatusBadRequest}}
+	srv2 := httptest.NewServer(rec)
+	defer srv2.Close()
+	if err := testSender(srv2.URL).post(context.Background(), updated("ship1", "Ship")); err == nil {
+		t.Error("4xx should fail")
+	}
+	if n := len(rec.received()); n != 1 {
+		t.Errorf("4xx should not be retried, got %d requests", n)
+	}
+
+	rec = &recorder{statuses: []int{500, 500, 500, 500, 500}}
+	srv3 := httptest.NewServer(rec)
+	defer srv3.Close()
+	if err := testSender(srv3.URL).post(context.Background(), updated("ship1", "Ship")); err == nil {
+		t.Error("should give up after max retries")
+	}
+	if n := len(rec.received()); n != 4 {
+		t.Errorf("expected 1 attempt and 3 retries, got %d requests", n)
+	}
+}
+
+func TestRun_DebouncesPerEntity(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	s := testSender(srv.URL)
+	s.config.Debounce = 50 * time.Millisecond
+
+	events := make(chan *pb.EntityChangeEvent)
+	done := make(chan struct{})
+	go func() {
+		s.run(context.Background(), events)
+		close(done)
+	}()
+
+	events <- &pb.EntityChangeEvent{T: pb.EntityChange_EntityChangeInvalid}
+	events <- updated("a", "a1")
+	events <- updated("b", "b1")
+	events <- updated("a", "a2")
+	events <- updated("a", "a3")
+	close(events)
+	<-done
+
+	reqs := rec.received()
+	if len(reqs) != 2 {
+		t.Fatalf("expected one request per entity, got %d", len(reqs))
+	}
+	var first, second struct{ Entity struct{ ID, Label string } }
+	_ = json.Unmarshal(reqs[0].body, &first)
+	_ = json.Unmarshal(reqs[1].body, &second)
+	if first.Entity.ID != "a" || first.Entity.Label != "a3" || second.Entity.ID != "b" {
+		t.Errorf("got %s then %s", reqs[0].body, reqs[1].body)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	value, err := structpb.NewStruct(map[string]any{
+		"url":         "https://example.com/hook",
+		"filter":      map[string]any{"label": "ship"},
+		"debounce_ms": 250,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := parseConfig(&pb.Entity{Id: "webhook.a", Config: &pb.ConfigurationComponent{Value: value}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Filter.GetLabel() != "ship" || config.Debounce != 250*time.Millisecond || config.MaxRetries != 3 {
+		t.Errorf("got %+v", config)
+	}
+
+	value.Fields["url"] = structpb.NewStringValue("ftp://example.com")
+	if _, err := parseConfig(&pb.Entity{Config: &pb.ConfigurationComponent{Value: value}}); err == nil {
+		t.Error("non-http url should be rejected")
+	}
+}
@@ -367,34 +367,11 @@ func runLS(cmd *cobra.Command, args []string) error {
 
 	// Bounding box geometry
 	if filterBBox != "" {
-		var lon1, lat1, lon2, lat2 float64
-		_, err := fmt.Sscanf(filterBBox, "%f,%f,%f,%f", &lon1, &lat1, &lon2, &lat2)
+		geo, err := bboxFilter(filterBBox)
 		if err != nil {
-			return fmt.Errorf("invalid bbox format, expected 'lon1,lat1,lon2,lat2': %w", err)
-		}
-
-		// Create a planar polygon from the bounding box
-		filter.Geo = &pb.GeoFilter{
-			Geo: &pb.GeoFilter_Geometry{
-				Geometry: &pb.Geometry{
-					Planar: &pb.PlanarGeometry{
-						Plane: &pb.PlanarGeometry_Polygon{
-							Polygon: &pb.PlanarPolygon{
-								Outer: &pb.PlanarRing{
-									Points: []*pb.PlanarPoint{
-										{Longitude: lon1, Latitude: lat1},
-										{Longitude: lon2, Latitude: lat1},
-										{Longitude: lon2, Latitude: lat2},
-										{Longitude: lon1, Latitude: lat2},
-										{Longitude: lon1, Latitude: lat1},
-									},
-								},
-							},
-						},
-					},
-				},
-			},
+			return err
 		}
+		filter.Geo = geo
 	}
 
 	req := &pb.ListEntitiesRequest{Filter: filter}
@@ -418,6 +395,37 @@ func runLS(cmd *cobra.Command, args []string) error {
 	}
 }
 
+// bboxFilter parses "lon1,lat1,lon2,lat2" into a planar polygon filter.
+func bboxFilter(bbox string) (*pb.GeoFilter, error) {
+	var lon1, lat1, lon2, lat2 float64
+	_, err := fmt.Sscanf(bbox, "%f,%f,%f,%f", &lon1, &lat1, &lon2, &lat2)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bbox format, expected 'lon1,lat1,lon2,lat2': %w", err)
+	}
+
+	return &pb.GeoFilter{
+		Geo: &pb.GeoFilter_Geometry{
+			Geometry: &pb.Geometry{
+				Planar: &pb.PlanarGeometry{
+					Plane: &pb.PlanarGeometry_Polygon{
+						Polygon: &pb.PlanarPolygon{
+							Outer: &pb.PlanarRing{
+								Points: []*pb.PlanarPoint{
+									{Longitude: lon1, Latitude: lat1},
+									{Longitude: lon2, Latitude: lat1},
+									{Longitude: lon2, Latitude: lat2},
+									{Longitude: lon1, Latitude: lat2},
+									{Longitude: lon1, Latitude: lat1},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
 func humanDuration(d time.Duration) string {
 	if d < 0 {
 		d = -d
@@ -459,15 +467,28 @@ func termWidth() int {
 }
 
 func printEntitiesTable(entities []*pb.Entity, localNodeID string) {
+	writeEntitiesTable(os.Stdout, entities, localNodeID, termWidth(), time.Now())
+}
+
+// Column caps of the entities table. Longer cells are truncated with "…".
+const (
+	tableMaxID = 40
+	// tableMinWidth is how narrow the last column may be truncated to fit
+	// the terminal before it is dropped instead.
+	tableMinWidth = 10
+)
+
+// writeEntitiesTable writes entities to w as a table. If width is
+// positive, columns are dropped from the right, and the last one kept is
+// truncated, so that lines fit in width columns.
+func writeEntitiesTable(w io.Writer, entities []*pb.Entity, localNodeID string, width int, now time.Time) {
 	if len(entities) == 0 {
-		fmt.Println("No entities found")
+		fmt.Fprintln(w, "No entities found")
 		return
 	}
 
-	now := time.Now()
-
 	// All columns in display order; rightmost are dropped first.
-	headers := []string{"CH", "ID", "Label", "State", "Seen", "Expire", "Latitude", "Longitude"}
+	headers := []string{"CH", "ID", "Label", "State", "Seen", "Expire", "Latitude", "Longitude", "Components"}
 	var rows [][]string
 
 	for _, entity := range entities {
@@ -553,12 +574,16 @@ func printEntitiesTable(entities []*pb.Entity, localNodeID string) {
 		}
 		ch = prefix + ch
 
-		rows = append(rows, []string{ch, entity.Id, label, state, fresh, until, lat, lon})
+		id := entity.Id
+		if runewidth.StringWidth(id) > tableMaxID {
+			id = runewidth.Truncate(id, tableMaxID, "…")
+		}
+
+		rows = append(rows, []string{ch, id, label, state, fresh, until, lat, lon, strings.Join(componentNames(entity), ",")})
 	}
 
 	// Calculate how many columns fit in the terminal.
 	// Drop columns from the right until the table fits.
-	width := termWidth()
 	padding := 2
 	numCols := len(headers)
 
@@ -576,10 +601,12 @@ func printEntitiesTable(entities []*pb.Entity, localNodeID string) {
 	if width > 0 {
 		for numCols > 1 {
 			total := 0
-			for col := 0; col < numCols; col++ {
+			for col := 0; col < numCols-1; col++ {
 				total += colWidths[col] + padding
 			}
-			if total <= width {
+			last := colWidths[numCols-1]
+			if rest := width - total - padding; rest >= min(last, tableMinWidth) {
+				colWidths[numCols-1] = min(last, rest)
 				break
 			}
 			numCols--
@@ -590,12 +617,12 @@ func printEntitiesTable(entities []*pb.Entity, localNodeID string) {
 	for i := 0; i < numCols; i++ {
 		ifaces[i] = headers[i]
 	}
-	tbl := table.New(ifaces...)
+	tbl := table.New(ifaces...).WithWriter(w).WithWidthFunc(runewidth.StringWidth)
 
 	for _, row := range rows {
 		vals := make([]interface{}, numCols)
 		for i := 0; i < numCols; i++ {
-			vals[i] = row[i]
+			vals[i] = runewidth.Truncate(row[i], colWidths[i], "…")
 		}
 		tbl.AddRow(vals...)
 	}
@@ -722,68 +749,24 @@ func runGet(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get entity: %w", err)
 	}
 
-	marshaler := protojson.MarshalOptions{
-		UseProtoNames:   true,
-		EmitUnpopulated: false,
-		Indent:          "  ",
-	}
-
-	jsonBytes, err := marshaler.Marshal(resp.Entity)
+	b, err := formatEntity(resp.Entity, "json")
 	if err != nil {
-		return fmt.Errorf("failed to marshal entity: %w", err)
+		return err
 	}
-
-	fmt.Println(string(jsonBytes))
+	fmt.Print(string(b))
 	return nil
 }
 
 func runPut(cmd *cobra.Command, args []string) error {
 	client := pb.NewWorldServiceClient(conn)
-	path := args[0]
-
-	// Read from file or stdin
-	var inputBytes []byte
-	var err error
 
-	if path == "-" {
-		inputBytes, err = io.ReadAll(os.Stdin)
-		if err != nil {
-			return fmt.Errorf("failed to read from stdin: %w", err)
-		}
-	} else {
-		inputBytes, err = os.ReadFile(path)
-		if err != nil {
-			return fmt.Errorf("failed to read file: %w", err)
-		}
-	}
-
-	var entities []*pb.Entity
-
-	// Try JSON first (single entity)
-	entity := &pb.Entity{}
-	unmarshaler := protojson.UnmarshalOptions{
-		DiscardUnknown: false,
+	inputBytes, err := readEntityInput(args[0], os.Stdin)
+	if err != nil {
+		return err
 	}
-
-	err = unmarshaler.Unmarshal(inputBytes, entity)
+	entities, err := parseEntities(inputBytes)
 	if err != nil {
-		// JSON failed, try YAML (supports single and multiple documents)
-		var parseErrs []error
-		entities, parseErrs = yamlToProtoMulti(inputBytes)
-		if len(parseErrs) > 0 {
-			for _, e := range parseErrs {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", e)
-			}
-		}
-		if len(entities) == 0 {
-			if len(parseErrs) == 0 {
-				return fmt.Errorf("no entities found in input")
-			}
-			return fmt.Errorf("all entities failed to parse")
-		}
-	} else {
-		// JSON succeeded
-		entities = []*pb.Entity{entity}
+		return err
 	}
 
 	// Push entities
@@ -807,6 +790,49 @@ func runPut(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// readEntityInput reads the file at path, or stdin if path is "-".
+func readEntityInput(path string, stdin io.Reader) ([]byte, error) {
+	if path == "-" {
+		b, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from stdin: %w", err)
+		}
+		return b, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return b, nil
+}
+
+// parseEntities parses a single JSON entity or one or more YAML documents.
+// Documents that fail to parse are reported on stderr and skipped; it is
+// an error if none is left.
+func parseEntities(input []byte) ([]*pb.Entity, error) {
+	// Try JSON first (single entity)
+	entity := &pb.Entity{}
+	unmarshaler := protojson.UnmarshalOptions{
+		DiscardUnknown: false,
+	}
+	if err := unmarshaler.Unmarshal(input, entity); err == nil {
+		return []*pb.Entity{entity}, nil
+	}
+
+	// JSON failed, try YAML (supports single and multiple documents)
+	entities, parseErrs := yamlToProtoMulti(input)
+	for _, e := range parseErrs {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", e)
+	}
+	if len(entities) == 0 {
+		if len(parseErrs) == 0 {
+			return nil, fmt.Errorf("no entities found in input")
+		}
+		return nil, fmt.Errorf("all entities failed to parse")
+	}
+	return entities, nil
+}
+
 func runEdit(cmd *cobra.Command, args []string) error {
 	client := pb.NewWorldServiceClient(conn)
 	entityID := args[0]
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	pb "github.com/projectqai/proto/go"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var (
+	listFilterFlags filterFlags
+	listOutput      string
+	getOutput       string
+)
+
+func init() {
+	listCmd := &cobra.Command{
+		Use:   "ls",
+		Short: "List entities",
+		Long: "List the entities in the world as a table, like ec ls. " +
+			"The filter flags are combined: an entity must match all of them.",
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: connect,
+		RunE:              runListCommand,
+	}
+	AddConnectionFlags(listCmd)
+	listFilterFlags.addTo(listCmd)
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", "table", "output format: table, yaml, json")
+
+	getCmd := &cobra.Command{
+		Use:               "get <entity-id>",
+		Short:             "Print one entity",
+		Args:              cobra.ExactArgs(1),
+		PersistentPreRunE: connect,
+		RunE:              runGetCommand,
+	}
+	AddConnectionFlags(getCmd)
+	getCmd.Flags().StringVarP(&getOutput, "output", "o", "yaml", "output format: yaml, json")
+
+	CMD.AddCommand(listCmd)
+	CMD.AddCommand(getCmd)
+}
+
+func runListCommand(cmd *cobra.Command, args []string) error {
+	filter, err := listFilterFlags.filter()
+	if err != nil {
+		return err
+	}
+
+	client := pb.NewWorldServiceClient(conn)
+	resp, err := client.ListEntities(context.Background(), &pb.ListEntitiesRequest{Filter: filter})
+	if err != nil {
+		return fmt.Errorf("failed to list entities: %w", err)
+	}
+
+	switch listOutput {
+	case "table":
+		printEntitiesTable(resp.Entities, getLocalNodeID(client))
+		return nil
+	case "yaml":
+		return printEntitiesYAML(resp.Entities)
+	case "json":
+		return printEntitiesJSON(resp.Entities)
+	default:
+		return fmt.Errorf("unknown output format: %s (use: table, yaml, json)", listOutput)
+	}
+}
+
+func runGetCommand(cmd *cobra.Command, args []string) error {
+	resp, err := pb.NewWorldServiceClient(conn).GetEntity(context.Background(), &pb.GetEntityRequest{Id: args[0]})
+	if err != nil {
+		return fmt.Errorf("failed to get entity: %w", err)
+	}
+
+	b, err := formatEntity(resp.Entity, getOutput)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(b))
+	return nil
+}
+
+// formatEntity renders entity as "yaml" or "json", ending in a newline.
+func formatEntity(entity *pb.Entity, format string) ([]byte, error) {
+	switch format {
+	case "yaml":
+		return protoToYAML(entity)
+	case "json":
+		b, err := protojson.MarshalOptions{UseProtoNames: true, Indent: "  "}.Marshal(entity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal entity: %w", err)
+		}
+		return append(b, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unknown output format: %s (use: yaml, json)", format)
+	}
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestFilterFlags_IDWithout(t *testing.T) {
+	f := filterFlags{id: "adsb.3c6444", without: []int{11}}
+	got, err := f.filter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &pb.EntityFilter{
+		Id:  proto.String("adsb.3c6444"),
+		Not: &pb.EntityFilter{Component: []uint32{11}},
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestEntitiesTable(t *testing.T) {
+	entities := []*pb.Entity{
+		{Id: "adsb.3c6444", Label: proto.String("DLH123"), Geo: &pb.GeoSpatialComponent{Latitude: 48, Longitude: 11}},
+		{Id: "a", Label: proto.String("A very long label"), Symbol: &pb.SymbolComponent{}, Kinematics: &pb.KinematicsComponent{}},
+	}
+	render := func(entities []*pb.Entity, width int) []string {
+		var b strings.Builder
+		writeEntitiesTable(&b, entities, "", width, time.Now())
+		return strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	}
+
+	long := append(entities, &pb.Entity{Id: strings.Repeat("x", 50)})
+	lines := render(long, 0)
+	if len(lines) != 4 || !strings.HasSuffix(strings.TrimRight(lines[0], " "), "Components") {
+		t.Fatalf("got %q, want a header and 3 rows ending in components", lines)
+	}
+	if !strings.Contains(lines[1], "label,geo") {
+		t.Errorf("components missing: %q", lines[1])
+	}
+	if strings.Contains(lines[2], "A very long label") {
+		t.Errorf("long label not truncated: %q", lines[2])
+	}
+	if !strings.Contains(lines[3], strings.Repeat("x", tableMaxID-1)+"…") {
+		t.Errorf("long id not truncated: %q", lines[3])
+	}
+
+	// A narrow terminal truncates the components column, but not below
+	// its minimum: then it is dropped.
+	lines = render(entities, 90)
+	for _, line := range lines {
+		if w := runewidth.StringWidth(line); w > 90 {
+			t.Errorf("%q is %d columns wide", line, w)
+		}
+	}
+	if last := strings.TrimRight(lines[len(lines)-1], " "); !strings.HasSuffix(last, "…") {
+		t.Errorf("long components not truncated: %q", last)
+	}
+	if header := strings.TrimRight(render(entities, 85)[0], " "); !strings.HasSuffix(header, "Longitude") {
+		t.Errorf("components not dropped: %q", header)
+	}
+}
+
+func TestFormatEntity(t *testing.T) {
+	e := &pb.Entity{Id: "adsb.3c6444", Label: proto.String("DLH123")}
+
+	b, err := formatEntity(e, "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "id: adsb.3c6444\nlabel: DLH123\n"; string(b) != want {
+		t.Errorf("yaml %q, want %q", b, want)
+	}
+
+	b, err = formatEntity(e, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back pb.Entity
+	if err := protojson.Unmarshal(b, &back); err != nil || !proto.Equal(&back, e) {
+		t.Errorf("json %s doesn't round trip: %v", b, err)
+	}
+
+	if _, err := formatEntity(e, "xml"); err == nil {
+		t.Error("unknown format: no error")
+	}
+}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/joho/godotenv"
 	"github.com/projectqai/hydris/goclient"
@@ -18,14 +19,32 @@ var CMD = &cobra.Command{
 }
 
 var (
-	conn         *goclient.Connection
-	serverURL    string
-	wgConfigPath string
+	conn          *goclient.Connection
+	serverURL     string
+	wgConfigPath  string
+	tlsClientCert string
+	tlsClientKey  string
+	tlsCA         string
 )
 
 func AddConnectionFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().StringVar(&serverURL, "server", "localhost:50051", "gRPC server address")
 	cmd.PersistentFlags().StringVar(&wgConfigPath, "wireguard", "", "path to WireGuard config to each the server")
+	cmd.PersistentFlags().StringVar(&tlsClientCert, "tls-client-cert", os.Getenv("HYDRIS_TLS_CLIENT_CERT"), "PEM client certificate, for servers started with --tls-client-ca (env HYDRIS_TLS_CLIENT_CERT)")
+	cmd.PersistentFlags().StringVar(&tlsClientKey, "tls-client-key", os.Getenv("HYDRIS_TLS_CLIENT_KEY"), "PEM client key (env HYDRIS_TLS_CLIENT_KEY)")
+	cmd.PersistentFlags().StringVar(&tlsCA, "tls-ca", os.Getenv("HYDRIS_TLS_CA"), "PEM CA bundle to verify the server with instead of the system roots (env HYDRIS_TLS_CA)")
+}
+
+// connectOptions are the goclient options of the TLS flags.
+func connectOptions() []goclient.ConnectOption {
+	var opts []goclient.ConnectOption
+	if tlsClientCert != "" || tlsClientKey != "" {
+		opts = append(opts, goclient.WithClientCertificate(tlsClientCert, tlsClientKey))
+	}
+	if tlsCA != "" {
+		opts = append(opts, goclient.WithRootCA(tlsCA))
+	}
+	return opts
 }
 
 func connect(cmd *cobra.Command, args []string) error {
@@ -34,7 +53,7 @@ func connect(cmd *cobra.Command, args []string) error {
 	if wgConfigPath != "" {
 		conn, err = goclient.ConnectWithWireGuard(serverURL, wgConfigPath)
 	} else {
-		conn, err = goclient.Connect(serverURL)
+		conn, err = goclient.Connect(serverURL, connectOptions()...)
 	}
 
 	if err != nil {
@@ -12,6 +12,7 @@ import (
 	"github.com/at-wat/ebml-go/webm"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
 	pb "github.com/projectqai/proto/go"
 	"github.com/spf13/cobra"
 	"google.golang.org/protobuf/proto"
@@ -23,6 +24,12 @@ const (
 	controllerName = "External Matroska Player"
 )
 
+var (
+	playLoopFlag     bool
+	playSeedFlag     time.Duration
+	playSeedOnlyFlag bool
+)
+
 func init() {
 	playCmd := &cobra.Command{
 		Use:   "play <timeline.mkv>",
@@ -33,6 +40,9 @@ func init() {
 	}
 
 	AddConnectionFlags(playCmd)
+	playCmd.Flags().BoolVar(&playLoopFlag, "loop", false, "restart from the beginning when the end is reached")
+	playCmd.Flags().DurationVar(&playSeedFlag, "seed", 0, "push all frames up to this time as fast as possible, then continue playing in real time from there")
+	playCmd.Flags().BoolVar(&playSeedOnlyFlag, "seed-only", false, "exit after seeding instead of playing on; without --seed, seeds the whole recording")
 
 	CMD.AddCommand(playCmd)
 }
@@ -70,6 +80,15 @@ type Player struct {
 	playing       bool
 	lastPlayedIdx int // Index of last played frame
 
+	// Looping: with loop set, playback wraps from the end of the active
+	// region to its start. Setting an A and/or B marker narrows the region
+	// and always loops it.
+	loop     bool
+	markA    time.Duration
+	markB    time.Duration
+	hasMarkA bool
+	hasMarkB bool
+
 	// Frame emission
 	frameChan chan Frame
 	stopChan  chan struct{}
@@ -229,6 +248,55 @@ func (r *TimelineReader) Close() error {
 	return nil
 }
 
+// TimelineBlock is a raw block of the entity track
+type TimelineBlock struct {
+	Timestamp time.Duration // Absolute timestamp from start of the timeline
+	Data      []byte        // Serialized pb.EntityChangeBatch
+}
+
+// EntityBlocks returns the blocks of the X_HYDRA/EntityChangeBatch track in
+// file order, with cluster and block timecodes resolved to absolute
+// timestamps.
+func (r *TimelineReader) EntityBlocks() ([]TimelineBlock, error) {
+	// Find the track with Codec ID "X_HYDRA/EntityChangeBatch"
+	entityTrackNumber := uint64(0)
+	found := false
+	for _, track := range r.tracks {
+		if track.CodecID == timelineCodecID {
+			entityTrackNumber = track.TrackNumber
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no track with Codec ID 'X_HYDRA/EntityChangeBatch' found")
+	}
+
+	var blocks []TimelineBlock
+	for _, cluster := range r.clusters {
+		for _, block := range cluster.SimpleBlock {
+			// Skip blocks from other tracks
+			if block.TrackNumber != entityTrackNumber {
+				continue
+			}
+
+			// Extract block data
+			if len(block.Data) == 0 {
+				continue
+			}
+
+			// Calculate absolute timestamp
+			absoluteTimestamp := int64(cluster.Timecode) + int64(block.Timecode)
+			blocks = append(blocks, TimelineBlock{
+				Timestamp: time.Duration(absoluteTimestamp) * time.Millisecond,
+				Data:      block.Data[0],
+			})
+		}
+	}
+
+	return blocks, nil
+}
+
 // Helper function to deserialize entities from bytes using pb.EntityChangeBatch
 func deserializeEntities(data []byte) ([]*pb.Entity, error) {
 	// Unmarshal EntityChangeBatch
@@ -253,75 +321,50 @@ func NewPlayer(path string) (*Player, error) {
 		return nil, fmt.Errorf("failed to open timeline: %w", err)
 	}
 
-	// Find the track with Codec ID "X_HYDRA/EntityChangeBatch"
-	entityTrackNumber := uint64(0)
-	found := false
-	for _, track := range reader.tracks {
-		if track.CodecID == "X_HYDRA/EntityChangeBatch" {
-			entityTrackNumber = track.TrackNumber
-			found = true
-			break
-		}
-	}
-	if !found {
-		return nil, fmt.Errorf("no track with Codec ID 'X_HYDRA/EntityChangeBatch' found")
+	timelineBlocks, err := reader.EntityBlocks()
+	if err != nil {
+		return nil, err
 	}
 
 	// Index all blocks for random access
 	var blocks []Frame
 	var maxTimestamp time.Duration
 
-	for clusterIdx, cluster := range reader.clusters {
-		for blockIdx, block := range cluster.SimpleBlock {
-			// Skip blocks from other tracks
-			if block.TrackNumber != entityTrackNumber {
-				continue
-			}
-
-			// Calculate absolute timestamp
-			absoluteTimestamp := int64(cluster.Timecode) + int64(block.Timecode)
-			timestamp := time.Duration(absoluteTimestamp) * time.Millisecond
-
-			if timestamp > maxTimestamp {
-				maxTimestamp = timestamp
-			}
-
-			// Extract block data
-			if len(block.Data) == 0 {
-				continue
-			}
+	for i, block := range timelineBlocks {
+		if block.Timestamp > maxTimestamp {
+			maxTimestamp = block.Timestamp
+		}
 
-			// Deserialize entities
-			entities, err := deserializeEntities(block.Data[0])
-			if err != nil {
-				fmt.Printf("Warning: failed to deserialize block %d in cluster %d: %v\n", blockIdx, clusterIdx, err)
-				continue
-			}
+		// Deserialize entities
+		entities, err := deserializeEntities(block.Data)
+		if err != nil {
+			fmt.Printf("Warning: failed to deserialize block %d: %v\n", i, err)
+			continue
+		}
 
-			// Convert entity timestamps from relative to absolute
-			for _, entity := range entities {
-				if entity.Lifetime != nil && entity.Lifetime.From != nil {
-					relativeTime := entity.Lifetime.From.AsTime()
-					// relativeTime is epoch + offset, extract offset
-					offset := relativeTime.Sub(time.Unix(0, 0))
-					absoluteTime := reader.startTime.Add(offset)
-					entity.Lifetime.From = timestamppb.New(absoluteTime)
-
-					if entity.Lifetime.Until != nil {
-						relativeUntil := entity.Lifetime.Until.AsTime()
-						offsetUntil := relativeUntil.Sub(time.Unix(0, 0))
-						absoluteUntil := reader.startTime.Add(offsetUntil)
-						entity.Lifetime.Until = timestamppb.New(absoluteUntil)
-					}
+		// Convert entity timestamps from relative to absolute
+		for _, entity := range entities {
+			if entity.Lifetime != nil && entity.Lifetime.From != nil {
+				relativeTime := entity.Lifetime.From.AsTime()
+				// relativeTime is epoch + offset, extract offset
+				offset := relativeTime.Sub(time.Unix(0, 0))
+				absoluteTime := reader.startTime.Add(offset)
+				entity.Lifetime.From = timestamppb.New(absoluteTime)
+
+				if entity.Lifetime.Until != nil {
+					relativeUntil := entity.Lifetime.Until.AsTime()
+					offsetUntil := relativeUntil.Sub(time.Unix(0, 0))
+					absoluteUntil := reader.startTime.Add(offsetUntil)
+					entity.Lifetime.Until = timestamppb.New(absoluteUntil)
 				}
 			}
-
-			blocks = append(blocks, Frame{
-				Timestamp: timestamp,
-				Entities:  entities,
-				BlockIdx:  len(blocks),
-			})
 		}
+
+		blocks = append(blocks, Frame{
+			Timestamp: block.Timestamp,
+			Entities:  entities,
+			BlockIdx:  len(blocks),
+		})
 	}
 
 	_ = reader.Close()
@@ -342,6 +385,62 @@ func NewPlayer(path string) (*Player, error) {
 	}, nil
 }
 
+// Seed clears this player's entities, then pushes every frame up to and
+// including to in order, as fast as the server takes them rather than in
+// real time, and leaves the playhead at to. Entity lifetimes are shifted so
+// that to corresponds to now, which keeps playback continuing from there in
+// step with the wall clock. It returns the number of frames pushed and must
+// be called before Start.
+func (p *Player) Seed(to time.Duration) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	to = clampDuration(to, 0, p.duration)
+	if p.worldClient != nil {
+		if err := p.worldClient.ClearOwnEntities(); err != nil {
+			return 0, err
+		}
+	}
+
+	start := time.Now().Add(-to)
+	shift := start.Sub(p.startTime)
+	p.startTime = start
+	for _, frame := range p.blocks {
+		for _, entity := range frame.Entities {
+			shiftLifetime(entity.Lifetime, shift)
+		}
+	}
+
+	p.lastPlayedIdx = -1
+	pushed := 0
+	for i, frame := range p.blocks {
+		if frame.Timestamp > to {
+			break
+		}
+		if p.worldClient != nil && len(frame.Entities) > 0 {
+			if err := p.worldClient.Push(frame.Entities); err != nil {
+				return pushed, fmt.Errorf("seed frame at %s: %w", formatDuration(frame.Timestamp), err)
+			}
+			pushed++
+		}
+		p.lastPlayedIdx = i
+	}
+	p.currentTime = to
+	return pushed, nil
+}
+
+// shiftLifetime moves the timestamps of l by d.
+func shiftLifetime(l *pb.Lifetime, d time.Duration) {
+	if l == nil {
+		return
+	}
+	for _, ts := range []**timestamppb.Timestamp{&l.From, &l.Until} {
+		if *ts != nil {
+			*ts = timestamppb.New((*ts).AsTime().Add(d))
+		}
+	}
+}
+
 // SetWorldClient sets the gRPC client for pushing entities
 func (p *Player) SetWorldClient(client *WorldClient) {
 	p.mu.Lock()
@@ -383,17 +482,94 @@ func (p *Player) tick() {
 	deltaTime := time.Millisecond * time.Duration(p.playbackRate*1000) / 1000
 	p.currentTime += deltaTime
 
-	// Clamp to duration
-	if p.currentTime > p.duration {
-		p.currentTime = p.duration
+	start, end := p.regionLocked()
+	wrap := false
+	switch {
+	case p.currentTime < start:
+		p.seekLocked(start)
+	case p.currentTime >= end && p.loopingLocked() && end > start:
+		p.currentTime = end
+		wrap = true
+	case p.currentTime > end:
+		// Clamp to the end of the region
+		p.currentTime = end
 		p.playing = false
 	}
 
 	currentTime := p.currentTime
+	worldClient := p.worldClient
 	p.mu.Unlock()
 
 	// Find and emit all frames that should be played at this tick
 	p.emitFramesForTime(currentTime)
+
+	if !wrap {
+		return
+	}
+
+	// Wrap around to the start of the loop region
+	p.mu.Lock()
+	p.seekLocked(start)
+	p.mu.Unlock()
+
+	// Replaying from the very beginning starts from an empty world, same as
+	// seeking to the start.
+	if start == 0 && worldClient != nil {
+		_ = worldClient.ClearOwnEntities()
+	}
+
+	p.emitFramesForTime(start)
+}
+
+// regionLocked returns the active playback region. Without markers it is the
+// whole timeline. Caller must hold p.mu.
+func (p *Player) regionLocked() (start, end time.Duration) {
+	start, end = 0, p.duration
+	if p.hasMarkA {
+		start = p.markA
+	}
+	if p.hasMarkB {
+		end = p.markB
+	}
+	return start, end
+}
+
+// loopingLocked reports whether playback wraps at the end of the region.
+// Caller must hold p.mu.
+func (p *Player) loopingLocked() bool {
+	return p.loop || p.hasMarkA || p.hasMarkB
+}
+
+// clampLocked clamps t to the active region. Caller must hold p.mu.
+func (p *Player) clampLocked(t time.Duration) time.Duration {
+	start, end := p.regionLocked()
+	return clampDuration(t, start, end)
+}
+
+func clampDuration(t, lo, hi time.Duration) time.Duration {
+	if t < lo {
+		return lo
+	}
+	if t > hi {
+		return hi
+	}
+	return t
+}
+
+// seekLocked moves the playhead to t and rewinds the played index so that
+// frames at t are emitted again. Caller must hold p.mu.
+func (p *Player) seekLocked(t time.Duration) {
+	p.currentTime = t
+
+	// Find the last frame before this time (not including frames at this time)
+	p.lastPlayedIdx = -1
+	for i, frame := range p.blocks {
+		if frame.Timestamp < t {
+			p.lastPlayedIdx = i
+		} else {
+			break
+		}
+	}
 }
 
 // emitFramesForTime emits all frames that should be played up to the current playback time
@@ -478,24 +654,8 @@ func (p *Player) TogglePlayPause() {
 func (p *Player) Seek(t time.Duration) {
 	p.mu.Lock()
 
-	if t < 0 {
-		t = 0
-	}
-	if t > p.duration {
-		t = p.duration
-	}
-
-	p.currentTime = t
-
-	// Find the last frame before this time (not including frames at this time)
-	p.lastPlayedIdx = -1
-	for i, frame := range p.blocks {
-		if frame.Timestamp < t {
-			p.lastPlayedIdx = i
-		} else {
-			break
-		}
-	}
+	t = p.clampLocked(t)
+	p.seekLocked(t)
 
 	// Clear entities when seeking to start
 	// Pause playback during clear to avoid race condition
@@ -516,25 +676,71 @@ func (p *Player) SeekRelative(delta time.Duration) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	newTime := p.currentTime + delta
-	if newTime < 0 {
-		newTime = 0
-	}
-	if newTime > p.duration {
-		newTime = p.duration
+	p.seekLocked(p.clampLocked(p.currentTime + delta))
+}
+
+// SetLoop enables or disables looping over the whole timeline
+func (p *Player) SetLoop(loop bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.loop = loop
+}
+
+// ToggleLoop toggles looping over the whole timeline
+func (p *Player) ToggleLoop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.loop = !p.loop
+}
+
+// SetMarkerA sets the start of the loop region. A B marker before it is
+// dropped.
+func (p *Player) SetMarkerA(t time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.markA = clampDuration(t, 0, p.duration)
+	p.hasMarkA = true
+	if p.hasMarkB && p.markB <= p.markA {
+		p.hasMarkB = false
 	}
+	p.seekLocked(p.clampLocked(p.currentTime))
+}
 
-	p.currentTime = newTime
+// SetMarkerB sets the end of the loop region. An A marker after it is
+// dropped.
+func (p *Player) SetMarkerB(t time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	// Find the last frame before this time (not including frames at this time)
-	p.lastPlayedIdx = -1
-	for i, frame := range p.blocks {
-		if frame.Timestamp < newTime {
-			p.lastPlayedIdx = i
-		} else {
-			break
-		}
+	p.markB = clampDuration(t, 0, p.duration)
+	p.hasMarkB = true
+	if p.hasMarkA && p.markA >= p.markB {
+		p.hasMarkA = false
 	}
+	p.seekLocked(p.clampLocked(p.currentTime))
+}
+
+// ClearMarkers removes the A and B markers
+func (p *Player) ClearMarkers() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.hasMarkA = false
+	p.hasMarkB = false
+}
+
+// GetMarkers returns the A and B markers and whether each is set
+func (p *Player) GetMarkers() (a time.Duration, hasA bool, b time.Duration, hasB bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.markA, p.hasMarkA, p.markB, p.hasMarkB
+}
+
+// IsLooping returns whether playback wraps at the end of the active region
+func (p *Player) IsLooping() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.loopingLocked()
 }
 
 // SetPlaybackRate sets the playback speed multiplier
@@ -607,6 +813,7 @@ type playModel struct {
 	// Display state
 	currentFrame *Frame
 	frameCount   int
+	selected     int // Index of the selected entity in currentFrame
 
 	// Error state
 	err error
@@ -690,6 +897,36 @@ func (m playModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.player.Seek(m.player.GetDuration())
 			return m, nil
 
+		case "a":
+			// Set loop start marker at the current position
+			m.player.SetMarkerA(m.player.GetCurrentTime())
+			return m, nil
+
+		case "b":
+			// Set loop end marker at the current position
+			m.player.SetMarkerB(m.player.GetCurrentTime())
+			return m, nil
+
+		case "c":
+			// Clear loop markers
+			m.player.ClearMarkers()
+			return m, nil
+
+		case "l":
+			// Toggle looping
+			m.player.ToggleLoop()
+			return m, nil
+
+		case "j":
+			// Select next entity
+			m.selected = clampSelection(m.selected+1, m.entityCount())
+			return m, nil
+
+		case "k":
+			// Select previous entity
+			m.selected = clampSelection(m.selected-1, m.entityCount())
+			return m, nil
+
 		case "1":
 			m.player.SetPlaybackRate(0.25)
 			return m, nil
@@ -763,6 +1000,7 @@ func (m playModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		frame := Frame(msg)
 		m.currentFrame = &frame
 		m.frameCount++
+		m.selected = clampSelection(m.selected, len(frame.Entities))
 		return m, waitForFrame(m.player)
 	}
 
@@ -789,6 +1027,10 @@ func (m playModel) View() string {
 	entityStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("252"))
 
+	selectedStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("86"))
+
 	helpStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("241")).
 		MarginTop(1)
@@ -816,7 +1058,18 @@ func (m playModel) View() string {
 		progress = float64(currentTime) / float64(duration)
 	}
 
-	progressBar := renderProgressBar(progress, barWidth)
+	var markers []float64
+	if duration > 0 {
+		markA, hasA, markB, hasB := m.player.GetMarkers()
+		if hasA {
+			markers = append(markers, float64(markA)/float64(duration))
+		}
+		if hasB {
+			markers = append(markers, float64(markB)/float64(duration))
+		}
+	}
+
+	progressBar := renderProgressBar(progress, barWidth, markers...)
 	b.WriteString(progressBar)
 	b.WriteString("\n")
 
@@ -830,6 +1083,9 @@ func (m playModel) View() string {
 		formatDuration(duration),
 		playStatus,
 		rate)
+	if m.player.IsLooping() {
+		statusLine += " | ⟲ LOOP"
+	}
 	b.WriteString(statusStyle.Render(statusLine))
 	b.WriteString("\n")
 
@@ -846,18 +1102,38 @@ func (m playModel) View() string {
 	visibleCount := getVisibleEntityCount(m.height)
 	entityList := []string{}
 
-	if m.currentFrame != nil && len(m.currentFrame.Entities) > 0 {
-		endIdx := min(visibleCount-1, len(m.currentFrame.Entities)) // Reserve one line for "... and N more"
+	var entities []*pb.Entity
+	if m.currentFrame != nil {
+		entities = m.currentFrame.Entities
+	}
+	selected := clampSelection(m.selected, len(entities))
+
+	// With enough room the selected entity is shown in a detail pane on the
+	// right, otherwise the list takes the full width.
+	showDetail := m.width >= minDetailWidth
+	listWidth := m.width
+	if showDetail {
+		listWidth = m.width / 2
+	}
+
+	if len(entities) > 0 {
+		endIdx := min(visibleCount-1, len(entities)) // Reserve one line for "... and N more"
+
+		// Scroll the list so that the selected entity stays visible
+		first := 0
+		if selected >= endIdx {
+			first = selected - endIdx + 1
+		}
 
-		for i := 0; i < endIdx; i++ {
-			entity := m.currentFrame.Entities[i]
+		for i := first; i < first+endIdx; i++ {
+			entity := entities[i]
 			label := "<no label>"
 			if entity.Label != nil {
 				label = *entity.Label
 			}
 
 			// Truncate long labels
-			maxLabelWidth := m.width - 10
+			maxLabelWidth := listWidth - 10
 			if maxLabelWidth < 20 {
 				maxLabelWidth = 20
 			}
@@ -865,12 +1141,16 @@ func (m playModel) View() string {
 				label = label[:maxLabelWidth-3] + "..."
 			}
 
-			entityList = append(entityList, fmt.Sprintf("  %s", label))
+			cursor := "  "
+			if i == selected {
+				cursor = "› "
+			}
+			entityList = append(entityList, cursor+label)
 		}
 
 		// Show indicator if there are more entities
-		if endIdx < len(m.currentFrame.Entities) {
-			entityList = append(entityList, fmt.Sprintf("  ... and %d more", len(m.currentFrame.Entities)-endIdx))
+		if rest := len(entities) - endIdx; rest > 0 {
+			entityList = append(entityList, fmt.Sprintf("  ... and %d more", rest))
 		}
 	}
 
@@ -879,12 +1159,31 @@ func (m playModel) View() string {
 		entityList = append(entityList, "")
 	}
 
+	var detail []string
+	if showDetail && len(entities) > 0 {
+		detail = componentSummary(entities[selected], m.width-listWidth-3)
+	}
+
 	// Render all lines
 	for i, line := range entityList {
-		if i < len(entityList)-1 || !strings.HasPrefix(line, "  ... and") {
-			b.WriteString(entityStyle.Render(line))
-		} else {
-			b.WriteString(helpStyle.Render(line))
+		style := entityStyle
+		switch {
+		case i == len(entityList)-1 && strings.HasPrefix(line, "  ... and"):
+			style = helpStyle
+		case strings.HasPrefix(line, "› "):
+			style = selectedStyle
+		}
+
+		if !showDetail {
+			b.WriteString(style.Render(line))
+			b.WriteString("\n")
+			continue
+		}
+
+		b.WriteString(style.Render(runewidth.FillRight(runewidth.Truncate(line, listWidth, "…"), listWidth)))
+		b.WriteString(helpStyle.Render(" │ "))
+		if i < len(detail) {
+			b.WriteString(entityStyle.Render(detail[i]))
 		}
 		b.WriteString("\n")
 	}
@@ -893,6 +1192,7 @@ func (m playModel) View() string {
 	b.WriteString("\n")
 	controls := []string{
 		"Space:Play/Pause  ←/→:Seek±5s  Shift+←/→:Seek±30s  ↑/↓:Speed±0.25x  1-9:Preset  r/0:Start  q:Quit",
+		"a/b:Set A/B marker  c:Clear markers  l:Loop  j/k:Select entity",
 	}
 	b.WriteString(helpStyle.Render(strings.Join(controls, "\n")))
 
@@ -917,7 +1217,9 @@ func min(a, b int) int {
 	return b
 }
 
-func renderProgressBar(progress float64, width int) string {
+// renderProgressBar renders the playback progress. markers are positions in
+// the range 0..1 that are drawn on top of the bar.
+func renderProgressBar(progress float64, width int, markers ...float64) string {
 	if progress < 0 {
 		progress = 0
 	}
@@ -926,15 +1228,35 @@ func renderProgressBar(progress float64, width int) string {
 	}
 
 	filled := int(float64(width) * progress)
-	empty := width - filled
 
 	filledStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("86"))
 	emptyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	markerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("220"))
 
-	bar := filledStyle.Render(strings.Repeat("█", filled)) +
-		emptyStyle.Render(strings.Repeat("░", empty))
+	markerAt := make(map[int]bool, len(markers))
+	for _, m := range markers {
+		pos := int(float64(width) * m)
+		if pos >= width {
+			pos = width - 1
+		}
+		if pos >= 0 {
+			markerAt[pos] = true
+		}
+	}
+
+	var bar strings.Builder
+	for i := 0; i < width; i++ {
+		switch {
+		case markerAt[i]:
+			bar.WriteString(markerStyle.Render("│"))
+		case i < filled:
+			bar.WriteString(filledStyle.Render("█"))
+		default:
+			bar.WriteString(emptyStyle.Render("░"))
+		}
+	}
 
-	return "[" + bar + "]"
+	return "[" + bar.String() + "]"
 }
 
 func formatDuration(d time.Duration) string {
@@ -963,6 +1285,22 @@ func runPlay(cmd *cobra.Command, args []string) error {
 	// Create gRPC client using the global conn variable
 	worldClient := NewWorldClient(pb.NewWorldServiceClient(conn))
 	player.SetWorldClient(worldClient)
+	player.SetLoop(playLoopFlag)
+
+	if playSeedFlag > 0 || playSeedOnlyFlag {
+		to := playSeedFlag
+		if !cmd.Flags().Changed("seed") {
+			to = player.GetDuration()
+		}
+		frames, err := player.Seed(to)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("seeded %d frames up to %s\n", frames, formatDuration(to))
+		if playSeedOnlyFlag {
+			return nil
+		}
+	}
 
 	// Start player goroutine
 	player.Start()
@@ -0,0 +1,226 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newTestPlayer returns a player with one frame per second for the given
+// number of seconds, without a world client.
+func newTestPlayer(seconds int) *Player {
+	var blocks []Frame
+	for i := 0; i <= seconds; i++ {
+		blocks = append(blocks, Frame{
+			Timestamp: time.Duration(i) * time.Second,
+			Entities:  []*pb.Entity{{Id: "e"}},
+			BlockIdx:  i,
+		})
+	}
+	return &Player{
+		blocks:        blocks,
+		duration:      time.Duration(seconds) * time.Second,
+		playbackRate:  1.0,
+		playing:       true,
+		lastPlayedIdx: -1,
+		frameChan:     make(chan Frame, 1000),
+		stopChan:      make(chan struct{}),
+		doneChan:      make(chan struct{}),
+	}
+}
+
+func TestTick_StopsAtEndWithoutLoop(t *testing.T) {
+	p := newTestPlayer(2)
+	p.currentTime = 2*time.Second - 500*time.Microsecond
+
+	p.tick()
+
+	if p.GetCurrentTime() != 2*time.Second {
+		t.Errorf("currentTime = %v, want 2s", p.GetCurrentTime())
+	}
+	if p.IsPlaying() {
+		t.Error("player should stop at the end")
+	}
+}
+
+func TestTick_LoopWrapsToStart(t *testing.T) {
+	p := newTestPlayer(2)
+	p.SetLoop(true)
+	p.currentTime = 2*time.Second - 500*time.Microsecond
+	p.lastPlayedIdx = 1
+
+	p.tick()
+
+	if p.GetCurrentTime() != 0 {
+		t.Errorf("currentTime = %v, want 0 after wraparound", p.GetCurrentTime())
+	}
+	if !p.IsPlaying() {
+		t.Error("player should keep playing when looping")
+	}
+	// The last frame and then the first frame were emitted.
+	if p.lastPlayedIdx != 0 {
+		t.Errorf("lastPlayedIdx = %d, want 0", p.lastPlayedIdx)
+	}
+	var got []time.Duration
+	for len(p.frameChan) > 0 {
+		got = append(got, (<-p.frameChan).Timestamp)
+	}
+	if len(got) != 2 || got[0] != 2*time.Second || got[1] != 0 {
+		t.Errorf("emitted frames at %v, want [2s 0s]", got)
+	}
+}
+
+func TestTick_MarkersConstrainPlayback(t *testing.T) {
+	p := newTestPlayer(10)
+	p.SetMarkerA(3 * time.Second)
+	p.SetMarkerB(5 * time.Second)
+
+	// Setting A moved the playhead into the region.
+	if p.GetCurrentTime() != 3*time.Second {
+		t.Errorf("currentTime = %v, want 3s", p.GetCurrentTime())
+	}
+
+	p.currentTime = 5*time.Second - 500*time.Microsecond
+	p.tick()
+
+	if p.GetCurrentTime() != 3*time.Second {
+		t.Errorf("currentTime = %v, want wrap to A (3s)", p.GetCurrentTime())
+	}
+	if !p.IsPlaying() {
+		t.Error("A/B region should loop")
+	}
+}
+
+func TestSeek_ClampedToMarkers(t *testing.T) {
+	p := newTestPlayer(10)
+	p.SetMarkerA(3 * time.Second)
+	p.SetMarkerB(5 * time.Second)
+
+	p.Seek(0)
+	if p.GetCurrentTime() != 3*time.Second {
+		t.Errorf("Seek(0) = %v, want 3s", p.GetCurrentTime())
+	}
+	p.Seek(8 * time.Second)
+	if p.GetCurrentTime() != 5*time.Second {
+		t.Errorf("Seek(8s) = %v, want 5s", p.GetCurrentTime())
+	}
+	p.SeekRelative(-10 * time.Second)
+	if p.GetCurrentTime() != 3*time.Second {
+		t.Errorf("SeekRelative(-10s) = %v, want 3s", p.GetCurrentTime())
+	}
+
+	p.ClearMarkers()
+	p.Seek(8 * time.Second)
+	if p.GetCurrentTime() != 8*time.Second {
+		t.Errorf("Seek(8s) without markers = %v, want 8s", p.GetCurrentTime())
+	}
+}
+
+func TestMarkers_Ordering(t *testing.T) {
+	p := newTestPlayer(10)
+	p.SetMarkerB(4 * time.Second)
+	p.SetMarkerA(6 * time.Second)
+
+	a, hasA, _, hasB := p.GetMarkers()
+	if !hasA || a != 6*time.Second {
+		t.Errorf("A = %v (%v), want 6s", a, hasA)
+	}
+	if hasB {
+		t.Error("B before A should be dropped")
+	}
+
+	p.SetMarkerA(20 * time.Second)
+	if a, _, _, _ := p.GetMarkers(); a != 10*time.Second {
+		t.Errorf("A = %v, want clamped to duration", a)
+	}
+}
+
+// recordingWorld records pushes and lists what was pushed.
+type recordingWorld struct {
+	pb.WorldServiceClient
+
+	mu     sync.Mutex
+	pushes [][]string
+	head   map[string]*pb.Entity
+}
+
+func (w *recordingWorld) Push(ctx context.Context, in *pb.EntityChangeRequest, opts ...grpc.CallOption) (*pb.EntityChangeResponse, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var ids []string
+	for _, e := range in.Changes {
+		ids = append(ids, e.Id)
+		w.head[e.Id] = e
+	}
+	w.pushes = append(w.pushes, ids)
+	return &pb.EntityChangeResponse{Accepted: true}, nil
+}
+
+func (w *recordingWorld) ListEntities(ctx context.Context, in *pb.ListEntitiesRequest, opts ...grpc.CallOption) (*pb.ListEntitiesResponse, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var out []*pb.Entity
+	for _, e := range w.head {
+		out = append(out, e)
+	}
+	return &pb.ListEntitiesResponse{Entities: out}, nil
+}
+
+func TestSeed_PushesEachFrameOnce(t *testing.T) {
+	p := newTestPlayer(10)
+	p.playing = false
+	p.startTime = time.Now()
+	for i := range p.blocks {
+		from := p.startTime.Add(p.blocks[i].Timestamp)
+		p.blocks[i].Entities = []*pb.Entity{{
+			Id:       fmt.Sprintf("e%d", i),
+			Lifetime: &pb.Lifetime{From: timestamppb.New(from)},
+		}}
+	}
+
+	world := &recordingWorld{head: map[string]*pb.Entity{
+		"stale": {Id: "stale", Controller: &pb.Controller{Id: proto.String(controllerID)}},
+	}}
+	p.SetWorldClient(NewWorldClient(world))
+
+	frames, err := p.Seed(4 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frames != 5 {
+		t.Errorf("seeded %d frames, want 5", frames)
+	}
+
+	// The clear of the stale entity comes first, then frames 0-4 in order.
+	if len(world.pushes) != 6 || len(world.pushes[0]) != 1 || world.pushes[0][0] != "stale" {
+		t.Fatalf("pushes %v, want the clear then 5 frames", world.pushes)
+	}
+	for i, ids := range world.pushes[1:] {
+		if len(ids) != 1 || ids[0] != fmt.Sprintf("e%d", i) {
+			t.Errorf("push %d = %v, want [e%d]", i+1, ids, i)
+		}
+	}
+
+	if p.GetCurrentTime() != 4*time.Second || p.lastPlayedIdx != 4 {
+		t.Errorf("playhead at %v, last played %d, want 4s and 4", p.GetCurrentTime(), p.lastPlayedIdx)
+	}
+	// The seeded time is now, so the last seeded frame is fresh.
+	if from := world.head["e4"].Lifetime.From.AsTime(); time.Since(from).Abs() > time.Second {
+		t.Errorf("last seeded frame from %v, want about now", from)
+	}
+
+	// Playing on continues with the next frame instead of repeating any.
+	p.Play()
+	p.currentTime = 5 * time.Second
+	p.emitFramesForTime(p.currentTime)
+	if p.lastPlayedIdx != 5 {
+		t.Errorf("last played %d after continuing, want 5", p.lastPlayedIdx)
+	}
+}
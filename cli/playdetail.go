@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// minDetailWidth is the terminal width from which the play view shows the
+// entity detail pane next to the list.
+const minDetailWidth = 80
+
+// entityCount returns the number of entities in the current frame.
+func (m playModel) entityCount() int {
+	if m.currentFrame == nil {
+		return 0
+	}
+	return len(m.currentFrame.Entities)
+}
+
+// clampSelection keeps a selection index within a list of n entries.
+func clampSelection(idx, n int) int {
+	if idx >= n {
+		idx = n - 1
+	}
+	if idx < 0 {
+		idx = 0
+	}
+	return idx
+}
+
+// componentNames returns the names of the components present on entity, in
+// field number order.
+func componentNames(entity *pb.Entity) []string {
+	m := entity.ProtoReflect()
+	fields := m.Descriptor().Fields()
+
+	var present []protoreflect.FieldDescriptor
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.Name() != "id" && m.Has(fd) {
+			present = append(present, fd)
+		}
+	}
+	sort.Slice(present, func(i, j int) bool { return present[i].Number() < present[j].Number() })
+
+	names := make([]string, len(present))
+	for i, fd := range present {
+		names[i] = string(fd.Name())
+	}
+	return names
+}
+
+// componentSummary renders the detail pane lines for entity: its id, the
+// present components and the key fields of geo, symbol and track. Lines are
+// truncated to width.
+func componentSummary(entity *pb.Entity, width int) []string {
+	if entity == nil {
+		return nil
+	}
+
+	lines := []string{"id: " + entity.Id}
+	if entity.Label != nil {
+		lines = append(lines, "label: "+entity.GetLabel())
+	}
+	if names := componentNames(entity); len(names) > 0 {
+		lines = append(lines, "components: "+strings.Join(names, ", "))
+	}
+
+	if geo := entity.Geo; geo != nil {
+		line := fmt.Sprintf("geo: %.6f, %.6f", geo.Latitude, geo.Longitude)
+		if geo.Altitude != nil {
+			line += fmt.Sprintf(" alt %.0fm", geo.GetAltitude())
+		}
+		lines = append(lines, line)
+	}
+	if sym := entity.Symbol; sym != nil && sym.MilStd2525C != "" {
+		lines = append(lines, "symbol: "+sym.MilStd2525C)
+	}
+	if track := entity.Track; track != nil {
+		line := "track"
+		if track.Tracker != nil {
+			line += ": tracker " + track.GetTracker()
+		}
+		lines = append(lines, line)
+	}
+
+	if width > 0 {
+		for i, line := range lines {
+			lines[i] = runewidth.Truncate(line, width, "…")
+		}
+	}
+	return lines
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattn/go-runewidth"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestClampSelection(t *testing.T) {
+	tests := []struct {
+		idx, n, want int
+	}{
+		{0, 0, 0},
+		{3, 0, 0},
+		{-1, 5, 0},
+		{2, 5, 2},
+		{5, 5, 4},
+		{9, 5, 4},
+	}
+	for _, tt := range tests {
+		if got := clampSelection(tt.idx, tt.n); got != tt.want {
+			t.Errorf("clampSelection(%d, %d) = %d, want %d", tt.idx, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestComponentSummary(t *testing.T) {
+	alt := 120.0
+	entity := &pb.Entity{
+		Id:     "track-1",
+		Label:  proto.String("Alpha"),
+		Geo:    &pb.GeoSpatialComponent{Latitude: 52.5, Longitude: 13.25, Altitude: &alt},
+		Symbol: &pb.SymbolComponent{MilStd2525C: "SFGPUCI----K---"},
+		Track:  &pb.TrackComponent{Tracker: proto.String("radar-1")},
+	}
+
+	got := componentSummary(entity, 0)
+	want := []string{
+		"id: track-1",
+		"label: Alpha",
+		"components: label, geo, symbol, track",
+		"geo: 52.500000, 13.250000 alt 120m",
+		"symbol: SFGPUCI----K---",
+		"track: tracker radar-1",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("componentSummary =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestComponentSummary_Truncates(t *testing.T) {
+	entity := &pb.Entity{Id: strings.Repeat("x", 100)}
+	for _, line := range componentSummary(entity, 20) {
+		if w := runewidth.StringWidth(line); w > 20 {
+			t.Errorf("line %q is %d wide, want <= 20", line, w)
+		}
+	}
+}
+
+func TestComponentSummary_Nil(t *testing.T) {
+	if got := componentSummary(nil, 40); got != nil {
+		t.Errorf("componentSummary(nil) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	pb "github.com/projectqai/proto/go"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	CMD.AddCommand(newPushCommand())
+}
+
+// inlineFlags describe one entity on the command line.
+type inlineFlags struct {
+	id       string
+	label    string
+	lat, lon float64
+}
+
+func newPushCommand() *cobra.Command {
+	var inline inlineFlags
+	pushCmd := &cobra.Command{
+		Use:   "push [file.yaml|-]",
+		Short: "Push entities by hand",
+		Long: "Push entities from a JSON or YAML file, from stdin with '-', or one entity described by flags:\n\n" +
+			"  hydris push --id test.1 --label Alpha --lat 48.1 --lon 11.5",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPushCommand(cmd, args, &inline)
+		},
+	}
+
+	AddConnectionFlags(pushCmd)
+	inline.addTo(pushCmd)
+	return pushCmd
+}
+
+func (f *inlineFlags) addTo(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.id, "id", "", "id of an inline entity")
+	cmd.Flags().StringVar(&f.label, "label", "", "label of the inline entity")
+	cmd.Flags().Float64Var(&f.lat, "lat", 0, "latitude of the inline entity")
+	cmd.Flags().Float64Var(&f.lon, "lon", 0, "longitude of the inline entity")
+}
+
+func runPushCommand(cmd *cobra.Command, args []string, inline *inlineFlags) error {
+	entities, err := pushInput(cmd, args, inline, os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	if err := connect(cmd, args); err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	resp, err := pb.NewWorldServiceClient(conn).Push(cmd.Context(), &pb.EntityChangeRequest{Changes: entities})
+	if err != nil {
+		return fmt.Errorf("push rejected: %w", err)
+	}
+	if !resp.Accepted {
+		return fmt.Errorf("push of %d entities was not accepted", len(entities))
+	}
+	for _, e := range entities {
+		fmt.Printf("accepted %s\n", e.Id)
+	}
+	return nil
+}
+
+// pushInput returns the entities to push: those read from the file or
+// stdin named by args, followed by the one described by the inline flags.
+func pushInput(cmd *cobra.Command, args []string, inline *inlineFlags, stdin io.Reader) ([]*pb.Entity, error) {
+	var entities []*pb.Entity
+	if len(args) == 1 {
+		b, err := readEntityInput(args[0], stdin)
+		if err != nil {
+			return nil, err
+		}
+		if entities, err = parseEntities(b); err != nil {
+			return nil, err
+		}
+	}
+
+	e, err := inline.entity(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if e != nil {
+		entities = append(entities, e)
+	}
+
+	if len(entities) == 0 {
+		return nil, fmt.Errorf("nothing to push: give a file, '-' for stdin, or --id")
+	}
+	return entities, nil
+}
+
+// entity builds the entity described by --id, --label, --lat and --lon on
+// cmd. It returns nil if none of them is set.
+func (f *inlineFlags) entity(cmd *cobra.Command) (*pb.Entity, error) {
+	flags := cmd.Flags()
+	latSet, lonSet := flags.Changed("lat"), flags.Changed("lon")
+	if !flags.Changed("id") && !flags.Changed("label") && !latSet && !lonSet {
+		return nil, nil
+	}
+
+	if f.id == "" {
+		return nil, fmt.Errorf("--id is required for an inline entity")
+	}
+	if latSet != lonSet {
+		return nil, fmt.Errorf("--lat and --lon must be given together")
+	}
+	if f.lat < -90 || f.lat > 90 || f.lon < -180 || f.lon > 180 {
+		return nil, fmt.Errorf("position %v,%v is out of range", f.lat, f.lon)
+	}
+
+	e := &pb.Entity{Id: f.id}
+	if f.label != "" {
+		e.Label = &f.label
+	}
+	if latSet {
+		e.Geo = &pb.GeoSpatialComponent{Latitude: f.lat, Longitude: f.lon}
+	}
+	return e, nil
+}
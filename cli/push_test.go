@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// pushTestCommand returns a command with the inline flags parsed from args.
+func pushTestCommand(t *testing.T, args ...string) (*cobra.Command, *inlineFlags) {
+	t.Helper()
+	var f inlineFlags
+	cmd := &cobra.Command{}
+	f.addTo(cmd)
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+	return cmd, &f
+}
+
+func TestInlineFlags(t *testing.T) {
+	cmd, f := pushTestCommand(t, "--id", "test.1", "--label", "Alpha", "--lat", "48.1", "--lon", "0")
+	e, err := f.entity(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Id != "test.1" || e.GetLabel() != "Alpha" {
+		t.Errorf("got %v", e)
+	}
+	// A zero longitude is a position, not a missing flag.
+	if e.Geo == nil || e.Geo.Latitude != 48.1 || e.Geo.Longitude != 0 {
+		t.Errorf("geo %v", e.Geo)
+	}
+
+	cmd, f = pushTestCommand(t, "--id", "test.2")
+	if e, err := f.entity(cmd); err != nil || e.Geo != nil || e.Label != nil {
+		t.Errorf("id only: got %v, %v", e, err)
+	}
+
+	cmd, f = pushTestCommand(t)
+	if e, err := f.entity(cmd); err != nil || e != nil {
+		t.Errorf("no flags: got %v, %v, want nothing", e, err)
+	}
+
+	for _, args := range [][]string{
+		{"--label", "Alpha"},
+		{"--id", "test.1", "--lat", "48"},
+		{"--id", "test.1", "--lat", "91", "--lon", "0"},
+	} {
+		cmd, f := pushTestCommand(t, args...)
+		if _, err := f.entity(cmd); err == nil {
+			t.Errorf("%v: no error", args)
+		}
+	}
+}
+
+func TestPushInput_Stdin(t *testing.T) {
+	stdin := strings.NewReader(`id: test.1
+label: Alpha
+---
+id: test.2
+geo:
+  latitude: 48.1
+  longitude: 11.5
+`)
+	cmd, f := pushTestCommand(t, "--id", "test.3")
+	entities, err := pushInput(cmd, []string{"-"}, f, stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entities) != 3 {
+		t.Fatalf("%d entities, want 3", len(entities))
+	}
+	for i, id := range []string{"test.1", "test.2", "test.3"} {
+		if entities[i].Id != id {
+			t.Errorf("entity %d is %s, want %s", i, entities[i].Id, id)
+		}
+	}
+	if entities[1].GetGeo().GetLongitude() != 11.5 {
+		t.Errorf("test.2 geo %v", entities[1].Geo)
+	}
+
+	cmd, f = pushTestCommand(t)
+	if _, err := pushInput(cmd, []string{"-"}, f, strings.NewReader("")); err == nil {
+		t.Error("empty stdin: no error")
+	}
+}
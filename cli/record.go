@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/at-wat/ebml-go/webm"
+	"github.com/projectqai/hydris/goclient"
+	pb "github.com/projectqai/proto/go"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// timelineCodecID is the codec of the entity track in timeline files.
+const timelineCodecID = "X_HYDRA/EntityChangeBatch"
+
+var (
+	recordDuration time.Duration
+	recordBatch    time.Duration
+)
+
+func init() {
+	recordCmd := &cobra.Command{
+		Use:   "record <out.mkv>",
+		Short: "Record the live world to a timeline file",
+		Long:  "Record entity changes from hydris into a Matroska timeline file that can be replayed with play",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runRecordCommand,
+	}
+
+	AddConnectionFlags(recordCmd)
+	recordCmd.Flags().DurationVar(&recordDuration, "duration", 0, "stop recording after this duration (0 = until Ctrl-C)")
+	recordCmd.Flags().DurationVar(&recordBatch, "batch", 100*time.Millisecond, "interval at which events are grouped into one block")
+
+	CMD.AddCommand(recordCmd)
+}
+
+func runRecordCommand(cmd *cobra.Command, args []string) error {
+	if err := connect(cmd, args); err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if recordDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, recordDuration)
+		defer cancel()
+	}
+
+	file, err := os.Create(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	rec, err := NewRecorder(file, time.Now())
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	stream, err := goclient.WatchEntitiesWithRetry(ctx, pb.NewWorldServiceClient(conn), &pb.ListEntitiesRequest{})
+	if err != nil {
+		_ = rec.Close()
+		return fmt.Errorf("failed to watch entities: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "recording to %s, press Ctrl-C to stop\n", args[0])
+
+	recErr := recordStream(ctx, stream, rec, recordBatch)
+	if err := rec.Close(); err != nil {
+		return fmt.Errorf("failed to finish timeline: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "recorded %d events in %d blocks\n", rec.events, rec.blocks)
+	return recErr
+}
+
+// recordStream reads events from stream until ctx is done and writes them to
+// rec, one block per batch interval.
+func recordStream(ctx context.Context, stream pb.WorldService_WatchEntitiesClient, rec *Recorder, batch time.Duration) error {
+	type received struct {
+		event *pb.EntityChangeEvent
+		err   error
+	}
+	events := make(chan received)
+	go func() {
+		for {
+			event, err := stream.Recv()
+			select {
+			case events <- received{event, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(batch)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return rec.Flush()
+		case <-ticker.C:
+			if err := rec.Flush(); err != nil {
+				return err
+			}
+		case r := <-events:
+			if r.err != nil {
+				if err := rec.Flush(); err != nil {
+					return err
+				}
+				if r.err == io.EOF || ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("stream error: %w", r.err)
+			}
+			rec.Add(r.event, time.Now())
+		}
+	}
+}
+
+// Recorder writes entity change events to a Matroska timeline file in the
+// format read by NewPlayer.
+type Recorder struct {
+	writer webm.BlockWriteCloser
+	start  time.Time
+
+	pending   []*pb.EntityChangeEvent
+	pendingAt time.Duration
+
+	events int
+	blocks int
+}
+
+// NewRecorder starts a timeline on w. Block timecodes are relative to start.
+func NewRecorder(w io.WriteCloser, start time.Time) (*Recorder, error) {
+	writer, err := newTimelineWriter(w)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{
+		writer: writer,
+		start:  start,
+	}, nil
+}
+
+// newTimelineWriter starts a Matroska file on w with a single entity track.
+// Block timestamps passed to Write are in milliseconds.
+func newTimelineWriter(w io.WriteCloser) (webm.BlockWriteCloser, error) {
+	writers, err := webm.NewSimpleBlockWriter(w, []webm.TrackEntry{{
+		Name:        "entities",
+		TrackNumber: 1,
+		TrackUID:    1,
+		CodecID:     timelineCodecID,
+		TrackType:   0x21, // metadata
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Matroska writer: %w", err)
+	}
+	return writers[0], nil
+}
+
+// Add queues an event received at the given time for the next block.
+// Events without an entity (e.g. the initial sync marker) are ignored.
+func (r *Recorder) Add(event *pb.EntityChangeEvent, at time.Time) {
+	if event == nil || event.Entity == nil {
+		return
+	}
+
+	offset := at.Sub(r.start)
+	if len(r.pending) == 0 {
+		r.pendingAt = offset
+	}
+
+	event = proto.Clone(event).(*pb.EntityChangeEvent)
+	r.relativizeLifetime(event.Entity, at)
+	r.pending = append(r.pending, event)
+}
+
+// relativizeLifetime rewrites lifetime timestamps to offsets from the unix
+// epoch, which the player maps back onto the playback start time.
+func (r *Recorder) relativizeLifetime(entity *pb.Entity, at time.Time) {
+	lt := entity.Lifetime
+	if lt == nil {
+		return
+	}
+	if lt.From == nil {
+		lt.From = timestamppb.New(at)
+	}
+	lt.From = timestamppb.New(time.Unix(0, 0).Add(lt.From.AsTime().Sub(r.start)))
+	if lt.Until != nil {
+		lt.Until = timestamppb.New(time.Unix(0, 0).Add(lt.Until.AsTime().Sub(r.start)))
+	}
+	// Fresh is not remapped by the player; an absolute value from the
+	// recording would make replayed updates lose against live state.
+	lt.Fresh = nil
+}
+
+// Flush writes all queued events as one block.
+func (r *Recorder) Flush() error {
+	if len(r.pending) == 0 {
+		return nil
+	}
+
+	data, err := proto.Marshal(&pb.EntityChangeBatch{Events: r.pending})
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+	if _, err := r.writer.Write(true, r.pendingAt.Milliseconds(), data); err != nil {
+		return fmt.Errorf("failed to write block: %w", err)
+	}
+
+	r.events += len(r.pending)
+	r.blocks++
+	r.pending = nil
+	return nil
+}
+
+// Close flushes pending events and finalizes the file.
+func (r *Recorder) Close() error {
+	flushErr := r.Flush()
+	if err := r.writer.Close(); err != nil {
+		return err
+	}
+	return flushErr
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestRecorder_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timeline.mkv")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	rec, err := NewRecorder(file, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := func(id string, at time.Time) *pb.EntityChangeEvent {
+		return &pb.EntityChangeEvent{
+			T: pb.EntityChange_EntityChangeUpdated,
+			Entity: &pb.Entity{
+				Id:  id,
+				Geo: &pb.GeoSpatialComponent{Latitude: 52, Longitude: 13},
+				Lifetime: &pb.Lifetime{
+					From:  timestamppb.New(at),
+					Until: timestamppb.New(at.Add(time.Minute)),
+				},
+			},
+		}
+	}
+
+	// Initial sync marker without an entity is skipped.
+	rec.Add(&pb.EntityChangeEvent{T: pb.EntityChange_EntityChangeInvalid}, start)
+
+	// Block 1 at 0ms with two events, block 2 at 500ms, block 3 at 40s
+	// (past the 16 bit block timecode range, so it needs a new cluster).
+	rec.Add(event("a", start), start)
+	rec.Add(event("b", start), start.Add(10*time.Millisecond))
+	if err := rec.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	rec.Add(event("a", start.Add(500*time.Millisecond)), start.Add(500*time.Millisecond))
+	if err := rec.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	rec.Add(event("c", start.Add(40*time.Second)), start.Add(40*time.Second))
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	player, err := NewPlayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		at  time.Duration
+		ids []string
+	}{
+		{0, []string{"a", "b"}},
+		{500 * time.Millisecond, []string{"a"}},
+		{40 * time.Second, []string{"c"}},
+	}
+	if len(player.blocks) != len(want) {
+		t.Fatalf("got %d blocks, want %d", len(player.blocks), len(want))
+	}
+	for i, w := range want {
+		frame := player.blocks[i]
+		if frame.Timestamp != w.at {
+			t.Errorf("block %d at %v, want %v", i, frame.Timestamp, w.at)
+		}
+		if len(frame.Entities) != len(w.ids) {
+			t.Fatalf("block %d has %d entities, want %d", i, len(frame.Entities), len(w.ids))
+		}
+		for j, id := range w.ids {
+			if frame.Entities[j].Id != id {
+				t.Errorf("block %d entity %d = %s, want %s", i, j, frame.Entities[j].Id, id)
+			}
+		}
+	}
+	if player.duration != 40*time.Second {
+		t.Errorf("duration = %v, want 40s", player.duration)
+	}
+
+	// Lifetimes are replayed relative to the playback start.
+	lt := player.blocks[1].Entities[0].Lifetime
+	if got := lt.From.AsTime().Sub(player.startTime); got != 500*time.Millisecond {
+		t.Errorf("replayed from offset = %v, want 500ms", got)
+	}
+	if got := lt.Until.AsTime().Sub(lt.From.AsTime()); got != time.Minute {
+		t.Errorf("replayed lifetime = %v, want 1m", got)
+	}
+}
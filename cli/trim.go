@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	trimFrom  time.Duration
+	trimUntil time.Duration
+)
+
+func init() {
+	trimCmd := &cobra.Command{
+		Use:   "trim <in.mkv> <out.mkv>",
+		Short: "Cut a time window out of a timeline file",
+		Long:  "Copy the blocks of a Matroska timeline that fall in [--from, --until] into a new file starting at zero",
+		Args:  cobra.ExactArgs(2),
+		RunE:  runTrim,
+	}
+
+	trimCmd.Flags().DurationVar(&trimFrom, "from", 0, "start of the window, relative to the start of the timeline")
+	trimCmd.Flags().DurationVar(&trimUntil, "until", 0, "end of the window (0 = end of the timeline)")
+
+	CMD.AddCommand(trimCmd)
+}
+
+func runTrim(cmd *cobra.Command, args []string) error {
+	n, err := TrimTimeline(args[0], args[1], trimFrom, trimUntil)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %d blocks to %s\n", n, args[1])
+	return nil
+}
+
+// TrimTimeline copies the entity blocks of in whose timestamps fall within
+// [from, until] to out, rebasing block timecodes and entity lifetimes so that
+// from becomes zero. An until of zero means the end of the timeline. It
+// returns the number of blocks written.
+func TrimTimeline(in, out string, from, until time.Duration) (int, error) {
+	if until != 0 && until < from {
+		return 0, fmt.Errorf("--until (%s) is before --from (%s)", until, from)
+	}
+
+	reader, err := NewTimelineReader(in)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open timeline: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	blocks, err := reader.EntityBlocks()
+	if err != nil {
+		return 0, err
+	}
+
+	file, err := os.Create(out)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	writer, err := newTimelineWriter(file)
+	if err != nil {
+		_ = file.Close()
+		return 0, err
+	}
+
+	written := 0
+	for _, block := range blocks {
+		// The window is matched on absolute block timestamps, so it may start
+		// or end anywhere inside a cluster.
+		if block.Timestamp < from || (until != 0 && block.Timestamp > until) {
+			continue
+		}
+
+		data, err := rebaseBatch(block.Data, from)
+		if err != nil {
+			_ = writer.Close()
+			return written, err
+		}
+
+		if _, err := writer.Write(true, (block.Timestamp - from).Milliseconds(), data); err != nil {
+			_ = writer.Close()
+			return written, fmt.Errorf("failed to write block: %w", err)
+		}
+		written++
+	}
+
+	if err := writer.Close(); err != nil {
+		return written, fmt.Errorf("failed to finish timeline: %w", err)
+	}
+	return written, nil
+}
+
+// rebaseBatch shifts the relative lifetimes stored in a serialized
+// EntityChangeBatch back by offset.
+func rebaseBatch(data []byte, offset time.Duration) ([]byte, error) {
+	if offset == 0 {
+		return data, nil
+	}
+
+	batch := &pb.EntityChangeBatch{}
+	if err := proto.Unmarshal(data, batch); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal EntityChangeBatch: %w", err)
+	}
+
+	for _, event := range batch.Events {
+		lt := event.GetEntity().GetLifetime()
+		if lt == nil {
+			continue
+		}
+		if lt.From != nil {
+			lt.From = timestamppb.New(lt.From.AsTime().Add(-offset))
+		}
+		if lt.Until != nil {
+			lt.Until = timestamppb.New(lt.Until.AsTime().Add(-offset))
+		}
+	}
+
+	return proto.Marshal(batch)
+}
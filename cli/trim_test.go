@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// writeSyntheticTimeline records n single-event blocks, step apart.
+func writeSyntheticTimeline(t *testing.T, path string, n int, step time.Duration) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	rec, err := NewRecorder(file, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		at := start.Add(time.Duration(i) * step)
+		rec.Add(&pb.EntityChangeEvent{
+			T: pb.EntityChange_EntityChangeUpdated,
+			Entity: &pb.Entity{
+				Id:       "e",
+				Lifetime: &pb.Lifetime{From: timestamppb.New(at)},
+			},
+		}, at)
+		if err := rec.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTrimTimeline(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.mkv")
+	out := filepath.Join(dir, "out.mkv")
+
+	// Blocks at 0, 250ms, 500ms, ... 4.75s: all in the first cluster, so
+	// the window starts and ends mid-cluster.
+	writeSyntheticTimeline(t, in, 20, 250*time.Millisecond)
+
+	n, err := TrimTimeline(in, out, 1*time.Second, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Fatalf("wrote %d blocks, want 5", n)
+	}
+
+	reader, err := NewTimelineReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, err := reader.EntityBlocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 5 {
+		t.Fatalf("output has %d blocks, want 5", len(blocks))
+	}
+	for i, b := range blocks {
+		want := time.Duration(i) * 250 * time.Millisecond
+		if b.Timestamp != want {
+			t.Errorf("block %d at %v, want %v", i, b.Timestamp, want)
+		}
+		entities, err := deserializeEntities(b.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		from := entities[0].Lifetime.From.AsTime().Sub(time.Unix(0, 0))
+		if from != want {
+			t.Errorf("block %d lifetime.from offset = %v, want %v", i, from, want)
+		}
+	}
+}
+
+func TestTrimTimeline_AcrossClusters(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.mkv")
+	out := filepath.Join(dir, "out.mkv")
+
+	// One block every 20s spreads the timeline over several clusters.
+	writeSyntheticTimeline(t, in, 6, 20*time.Second)
+
+	n, err := TrimTimeline(in, out, 30*time.Second, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Fatalf("wrote %d blocks, want 4", n)
+	}
+
+	player, err := NewPlayer(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Duration{10 * time.Second, 30 * time.Second, 50 * time.Second, 70 * time.Second}
+	for i, w := range want {
+		if player.blocks[i].Timestamp != w {
+			t.Errorf("block %d at %v, want %v", i, player.blocks[i].Timestamp, w)
+		}
+	}
+}
+
+func TestTrimTimeline_InvalidWindow(t *testing.T) {
+	if _, err := TrimTimeline("in.mkv", "out.mkv", 2*time.Second, time.Second); err == nil {
+		t.Error("expected error when until is before from")
+	}
+}
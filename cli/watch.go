@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/projectqai/hydris/goclient"
+	"github.com/projectqai/hydris/pkg/cot"
+	pb "github.com/projectqai/proto/go"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var (
+	watchFilterFlags filterFlags
+	watchFormat      string
+)
+
+func init() {
+	watchCmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Tail live entity changes",
+		Long: "Print every entity change from hydris as it happens, until Ctrl-C. " +
+			"The filter flags are combined: an entity must match all of them.",
+		Args: cobra.NoArgs,
+		RunE: runWatchCommand,
+	}
+
+	AddConnectionFlags(watchCmd)
+	watchFilterFlags.addTo(watchCmd)
+	watchCmd.Flags().StringVar(&watchFormat, "format", "pretty", "output format: pretty, json, cot")
+
+	CMD.AddCommand(watchCmd)
+}
+
+// filterFlags are command line flags that build an EntityFilter.
+type filterFlags struct {
+	raw        string
+	id         string
+	controller string
+	tracker    string
+	with       []int
+	without    []int
+	bbox       string
+}
+
+func (f *filterFlags) addTo(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.raw, "filter", "", "EntityFilter as JSON, e.g. '{\"label\":\"DLH123\"}'")
+	cmd.Flags().StringVar(&f.id, "id", "", "filter by entity ID (exact match)")
+	cmd.Flags().StringVar(&f.controller, "controller", "", "filter by controller ID")
+	cmd.Flags().StringVar(&f.tracker, "tracker", "", "filter by track.tracker ID")
+	cmd.Flags().IntSliceVar(&f.with, "with", nil, "filter entities with these component field numbers (e.g., 2=label, 11=geo)")
+	cmd.Flags().IntSliceVar(&f.without, "without", nil, "filter entities without these component field numbers")
+	cmd.Flags().StringVar(&f.bbox, "bbox", "", "filter by bounding box: lon1,lat1,lon2,lat2")
+}
+
+// filter returns the filter the flags describe, or nil if none is set.
+func (f *filterFlags) filter() (*pb.EntityFilter, error) {
+	var raw *pb.EntityFilter
+	if f.raw != "" {
+		raw = &pb.EntityFilter{}
+		if err := protojson.Unmarshal([]byte(f.raw), raw); err != nil {
+			return nil, fmt.Errorf("invalid --filter: %w", err)
+		}
+	}
+
+	flags := &pb.EntityFilter{}
+	set := false
+	if f.id != "" {
+		flags.Id = &f.id
+		set = true
+	}
+	if f.controller != "" {
+		flags.Controller = &pb.ControllerFilter{Id: &f.controller}
+		set = true
+	}
+	if f.tracker != "" {
+		flags.Track = &pb.TrackFilter{Tracker: &f.tracker}
+		set = true
+	}
+	if len(f.with) > 0 {
+		flags.Component = intSliceToUint32(f.with)
+		set = true
+	}
+	if len(f.without) > 0 {
+		flags.Not = &pb.EntityFilter{Component: intSliceToUint32(f.without)}
+		set = true
+	}
+	if f.bbox != "" {
+		geo, err := bboxFilter(f.bbox)
+		if err != nil {
+			return nil, err
+		}
+		flags.Geo = geo
+		set = true
+	}
+	if !set {
+		flags = nil
+	}
+
+	return goclient.And(raw, flags), nil
+}
+
+func runWatchCommand(cmd *cobra.Command, args []string) error {
+	printEvent, err := newEventPrinter(watchFormat, os.Stdout)
+	if err != nil {
+		return err
+	}
+	filter, err := watchFilterFlags.filter()
+	if err != nil {
+		return err
+	}
+
+	if err := connect(cmd, args); err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	stream, err := goclient.WatchEntitiesWithRetry(ctx, pb.NewWorldServiceClient(conn), &pb.ListEntitiesRequest{Filter: filter})
+	if err != nil {
+		return fmt.Errorf("failed to watch entities: %w", err)
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("stream error: %w", err)
+		}
+		if err := printEvent(event); err != nil {
+			return err
+		}
+	}
+}
+
+// newEventPrinter returns a function that writes one event to w in the
+// given format.
+func newEventPrinter(format string, w io.Writer) (func(*pb.EntityChangeEvent) error, error) {
+	switch format {
+	case "pretty":
+		p := newPrettyPrinter(w)
+		return func(event *pb.EntityChangeEvent) error {
+			_, err := fmt.Fprintln(w, p.format(event, time.Now()))
+			return err
+		}, nil
+	case "json":
+		marshaler := protojson.MarshalOptions{UseProtoNames: true}
+		return func(event *pb.EntityChangeEvent) error {
+			b, err := marshaler.Marshal(event)
+			if err != nil {
+				return fmt.Errorf("failed to marshal event: %w", err)
+			}
+			_, err = fmt.Fprintln(w, string(b))
+			return err
+		}, nil
+	case "cot":
+		return func(event *pb.EntityChangeEvent) error {
+			var b []byte
+			var err error
+			if event.T == pb.EntityChange_EntityChangeExpired || event.T == pb.EntityChange_EntityChangeUnobserved {
+				b, err = cot.EntityDeleteCoT(event.Entity)
+			} else {
+				b, err = cot.EntityToCoT(event.Entity)
+			}
+			if err != nil {
+				// Not every entity has a CoT form, e.g. one without Geo.
+				fmt.Fprintf(os.Stderr, "skipping %s: %v\n", event.Entity.GetId(), err)
+				return nil
+			}
+			_, err = fmt.Fprintln(w, string(b))
+			return err
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (use: pretty, json, cot)", format)
+	}
+}
+
+// prettyPrinter formats events as one colorized line each. Colors are
+// only used when the output is a terminal.
+type prettyPrinter struct {
+	time, id, dim lipgloss.Style
+	change        map[pb.EntityChange]lipgloss.Style
+}
+
+func newPrettyPrinter(w io.Writer) *prettyPrinter {
+	r := lipgloss.NewRenderer(w)
+	return &prettyPrinter{
+		time: r.NewStyle().Foreground(lipgloss.Color("241")),
+		id:   r.NewStyle().Bold(true),
+		dim:  r.NewStyle().Foreground(lipgloss.Color("245")),
+		change: map[pb.EntityChange]lipgloss.Style{
+			pb.EntityChange_EntityChangeUpdated:    r.NewStyle().Foreground(lipgloss.Color("86")),
+			pb.EntityChange_EntityChangeExpired:    r.NewStyle().Foreground(lipgloss.Color("203")),
+			pb.EntityChange_EntityChangeUnobserved: r.NewStyle().Foreground(lipgloss.Color("220")),
+		},
+	}
+}
+
+// changeName is the short upper case name of a change.
+func changeName(t pb.EntityChange) string {
+	name := t.String()
+	switch t {
+	case pb.EntityChange_EntityChangeUpdated:
+		name = "UPDATED"
+	case pb.EntityChange_EntityChangeExpired:
+		name = "EXPIRED"
+	case pb.EntityChange_EntityChangeUnobserved:
+		name = "UNOBSERVED"
+	}
+	return name
+}
+
+// format renders event received at now as
+//
+//	15:04:05.000 UPDATED    adsb.3c6444 "DLH123" 48.123456,11.543210 [adsb]
+func (p *prettyPrinter) format(event *pb.EntityChangeEvent, now time.Time) string {
+	e := event.Entity
+	name := changeName(event.T)
+	parts := []string{
+		p.time.Render(now.Format("15:04:05.000")),
+		// Pad outside the style so the columns line up with colors on.
+		p.change[event.T].Render(name) + strings.Repeat(" ", max(0, len("UNOBSERVED")-len(name))),
+		p.id.Render(e.GetId()),
+	}
+	if e.GetLabel() != "" {
+		parts = append(parts, fmt.Sprintf("%q", e.GetLabel()))
+	}
+	if geo := e.GetGeo(); geo != nil {
+		parts = append(parts, fmt.Sprintf("%.6f,%.6f", geo.Latitude, geo.Longitude))
+	}
+	if c := e.GetController().GetId(); c != "" {
+		parts = append(parts, p.dim.Render("["+c+"]"))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestFilterFlags(t *testing.T) {
+	f := filterFlags{}
+	if got, err := f.filter(); err != nil || got != nil {
+		t.Errorf("no flags: got %v, %v, want nil filter", got, err)
+	}
+
+	f = filterFlags{controller: "adsb", with: []int{11}, bbox: "11,48,12,49"}
+	got, err := f.filter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Controller.GetId() != "adsb" {
+		t.Errorf("controller %q", got.Controller.GetId())
+	}
+	if len(got.Component) != 1 || got.Component[0] != 11 {
+		t.Errorf("components %v", got.Component)
+	}
+	if pts := got.Geo.GetGeometry().GetPlanar().GetPolygon().GetOuter().GetPoints(); len(pts) != 5 || pts[2].Longitude != 12 || pts[2].Latitude != 49 {
+		t.Errorf("bbox ring %v", pts)
+	}
+
+	// --filter and the other flags must both hold.
+	f = filterFlags{raw: `{"id":"adsb.3c6444"}`, controller: "adsb"}
+	got, err = f.filter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	branches := got.GetNot().GetOr()
+	if len(branches) != 2 || branches[0].GetNot().GetId() != "adsb.3c6444" || branches[1].GetNot().GetController().GetId() != "adsb" {
+		t.Errorf("combined filter %v", got)
+	}
+
+	f = filterFlags{raw: `{"id":"adsb.3c6444"}`}
+	if got, _ := f.filter(); !proto.Equal(got, &pb.EntityFilter{Id: proto.String("adsb.3c6444")}) {
+		t.Errorf("--filter alone: got %v", got)
+	}
+
+	for _, bad := range []filterFlags{{raw: `{"nope":1}`}, {bbox: "11,48"}} {
+		if _, err := bad.filter(); err == nil {
+			t.Errorf("%+v: no error", bad)
+		}
+	}
+}
+
+func TestPrettyPrinter(t *testing.T) {
+	// Not a terminal, so no colors.
+	p := newPrettyPrinter(&bytes.Buffer{})
+	now := time.Date(2026, 1, 1, 12, 30, 45, 123e6, time.UTC)
+
+	got := p.format(&pb.EntityChangeEvent{
+		T: pb.EntityChange_EntityChangeUpdated,
+		Entity: &pb.Entity{
+			Id:         "adsb.3c6444",
+			Label:      proto.String("DLH123"),
+			Geo:        &pb.GeoSpatialComponent{Latitude: 48.1234567, Longitude: 11.5},
+			Controller: &pb.Controller{Id: proto.String("adsb")},
+		},
+	}, now)
+	want := `12:30:45.123 UPDATED    adsb.3c6444 "DLH123" 48.123457,11.500000 [adsb]`
+	if got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+
+	got = p.format(&pb.EntityChangeEvent{
+		T:      pb.EntityChange_EntityChangeExpired,
+		Entity: &pb.Entity{Id: "adsb.3c6444"},
+	}, now)
+	if want := "12:30:45.123 EXPIRED    adsb.3c6444"; got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+	if strings.Contains(got, "\x1b[") {
+		t.Errorf("colors in non-terminal output: %q", got)
+	}
+}
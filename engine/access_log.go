@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+// DefaultAccessLogSampleRate is the fraction of allowed requests the access
+// log records unless configured.
+const DefaultAccessLogSampleRate = 0.01
+
+// AccessLog records authorization decisions for audit: who did what to
+// which entity and whether it was allowed. Denied requests are always
+// recorded; allowed ones, which are far more frequent, only at a sample
+// rate. A nil AccessLog records nothing.
+type AccessLog struct {
+	logger *slog.Logger
+	rate   float64
+
+	mu sync.Mutex
+	// credit accumulates rate per allowed request; a request is recorded
+	// each time it reaches 1, so exactly rate of them are
+	credit float64
+}
+
+// NewAccessLog returns an access log that writes to logger and records
+// the given fraction, between 0 and 1, of allowed requests.
+func NewAccessLog(logger *slog.Logger, sampleRate float64) *AccessLog {
+	return &AccessLog{logger: logger, rate: min(max(sampleRate, 0), 1)}
+}
+
+// SetAccessLog installs an access log for the authorization decisions of
+// every WorldService RPC and HTTP endpoint. It must be set before NewAPIMux
+// is called.
+func (s *WorldServer) SetAccessLog(a *AccessLog) {
+	s.accessLog = a
+}
+
+// record logs one decision about in. entityIDs are the entities the request
+// names, if any; each is recorded as its own line. err is the rejection, or
+// nil if the request was allowed.
+func (a *AccessLog) record(ctx context.Context, in AuthInput, entityIDs []string, err error) {
+	if a == nil || (err == nil && !a.sample()) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("peer", in.PeerAddr),
+		slog.String("action", in.Method),
+	}
+	if in.Identity != nil {
+		attrs = append(attrs, slog.String("identity", in.Identity.CommonName))
+	}
+	level, decision := slog.LevelInfo, "allow"
+	if err != nil {
+		level, decision = slog.LevelWarn, "deny"
+	}
+	attrs = append(attrs, slog.String("decision", decision))
+	if err != nil {
+		attrs = append(attrs, slog.String("reason", err.Error()))
+	}
+
+	if len(entityIDs) == 0 {
+		a.logger.LogAttrs(ctx, level, "access", attrs...)
+		return
+	}
+	for _, id := range entityIDs {
+		a.logger.LogAttrs(ctx, level, "access", append(attrs, slog.String("entity", id))...)
+	}
+}
+
+// sample reports whether the next allowed request is recorded.
+func (a *AccessLog) sample() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.credit += a.rate
+	// Allow for rounding, or a rate such as 0.1 would come up short.
+	if a.credit < 1-1e-9 {
+		return false
+	}
+	a.credit--
+	return true
+}
+
+// requestEntityIDs returns the ids of the entities an RPC request names.
+func requestEntityIDs(msg any) []string {
+	switch m := msg.(type) {
+	case *pb.GetEntityRequest:
+		return []string{m.Id}
+	case *pb.ExpireEntityRequest:
+		return []string{m.Id}
+	case *pb.EntityChangeRequest:
+		ids := make([]string, 0, len(m.Changes))
+		for _, e := range m.Changes {
+			ids = append(ids, e.GetId())
+		}
+		return ids
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+// recordingHandler keeps the attributes of every record logged to it.
+type recordingHandler struct {
+	mu      sync.Mutex
+	records []map[string]string
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *recordingHandler) WithGroup(string) slog.Handler            { return h }
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	attrs := map[string]string{}
+	r.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value.String()
+		return true
+	})
+	h.mu.Lock()
+	h.records = append(h.records, attrs)
+	h.mu.Unlock()
+	return nil
+}
+
+func TestAccessLog_DeniedAlwaysLogged(t *testing.T) {
+	h := &recordingHandler{}
+	a := &authInterceptor{
+		authorize: func(ctx context.Context, in AuthInput) error {
+			return errors.New("push requires the ops identity")
+		},
+		log: NewAccessLog(slog.New(h), 0),
+	}
+
+	for i := 0; i < 10; i++ {
+		err := a.check(context.Background(), "/world.WorldService/Push", "10.0.0.7:5000", &pb.EntityChangeRequest{
+			Changes: []*pb.Entity{{Id: "e1"}, {Id: "e2"}},
+		})
+		if err == nil {
+			t.Fatal("expected the push to be denied")
+		}
+	}
+
+	if len(h.records) != 20 {
+		t.Fatalf("logged %d records, want one per entity of every denied push (20)", len(h.records))
+	}
+	rec := h.records[1]
+	if rec["decision"] != "deny" || rec["action"] != "Push" || rec["entity"] != "e2" || rec["peer"] != "10.0.0.7:5000" {
+		t.Errorf("record = %v", rec)
+	}
+	if rec["reason"] == "" {
+		t.Error("denied record without reason")
+	}
+}
+
+func TestAccessLog_AllowedReadsSampled(t *testing.T) {
+	h := &recordingHandler{}
+	a := &authInterceptor{log: NewAccessLog(slog.New(h), 0.1)}
+
+	for i := 0; i < 1000; i++ {
+		if err := a.check(context.Background(), "/world.WorldService/GetEntity", "10.0.0.7:5000", &pb.GetEntityRequest{Id: "e1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(h.records) != 100 {
+		t.Fatalf("logged %d of 1000 allowed reads, want 100", len(h.records))
+	}
+	if rec := h.records[0]; rec["decision"] != "allow" || rec["action"] != "GetEntity" || rec["entity"] != "e1" {
+		t.Errorf("record = %v", rec)
+	}
+}
+
+func TestAccessLog_Nil(t *testing.T) {
+	var a *AccessLog
+	a.record(context.Background(), AuthInput{Method: "Push"}, nil, errors.New("denied"))
+}
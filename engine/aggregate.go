@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// AggregateDimension is what AggregateEntities groups by.
+type AggregateDimension string
+
+const (
+	// AggregateByController groups by controller id.
+	AggregateByController AggregateDimension = "controller"
+	// AggregateByComponent counts each entity once for every component it
+	// has, keyed by the component's proto name.
+	AggregateByComponent AggregateDimension = "component"
+	// AggregateByLabel groups by label.
+	AggregateByLabel AggregateDimension = "label"
+)
+
+// AggregateRequest selects the dimension and, optionally, the entities to
+// count.
+type AggregateRequest struct {
+	By     AggregateDimension
+	Filter *pb.EntityFilter
+}
+
+// AggregateEntities counts the entities matching req.Filter per group of
+// req.By, without copying them out of the world. Entities without a
+// controller or label are counted under "".
+func (s *WorldServer) AggregateEntities(ctx context.Context, req AggregateRequest) (map[string]int, error) {
+	return s.aggregate(req, "")
+}
+
+// aggregate is AggregateEntities as seen by peerAddr: components the
+// redactor strips for the peer are not counted.
+func (s *WorldServer) aggregate(req AggregateRequest, peerAddr string) (map[string]int, error) {
+	var group func(e *pb.Entity, counts map[string]int)
+	switch req.By {
+	case AggregateByController:
+		group = func(e *pb.Entity, counts map[string]int) { counts[e.Controller.GetId()]++ }
+	case AggregateByLabel:
+		group = func(e *pb.Entity, counts map[string]int) { counts[e.GetLabel()]++ }
+	case AggregateByComponent:
+		group = func(e *pb.Entity, counts map[string]int) {
+			for _, name := range componentNames(e) {
+				counts[name]++
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown aggregate dimension %q", req.By)
+	}
+
+	counts := make(map[string]int)
+	s.l.RLock()
+	defer s.l.RUnlock()
+	for _, es := range s.head {
+		if !s.matchesEntityFilter(es.entity, req.Filter) {
+			continue
+		}
+		group(s.redactForPeer(peerAddr, es.entity), counts)
+	}
+	return counts, nil
+}
+
+// handleAggregate serves AggregateEntities as
+//
+//	GET /aggregate?by=controller&filter={"component":[11]}
+//
+// where filter is an optional EntityFilter in protojson.
+func (s *WorldServer) handleAggregate(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorizeHTTP(r, "AggregateEntities"); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	req := AggregateRequest{By: AggregateDimension(r.URL.Query().Get("by"))}
+	if raw := r.URL.Query().Get("filter"); raw != "" {
+		req.Filter = &pb.EntityFilter{}
+		if err := protojson.Unmarshal([]byte(raw), req.Filter); err != nil {
+			http.Error(w, "invalid filter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	counts, err := s.aggregate(req, r.RemoteAddr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(counts)
+}
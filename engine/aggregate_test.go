@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+func aggregateWorld() *WorldServer {
+	return testWorld(map[string]*pb.Entity{
+		"ac1":  {Id: "ac1", Label: ptr("A320"), Controller: &pb.Controller{Id: ptr("adsblol")}, Geo: &pb.GeoSpatialComponent{}, Transponder: &pb.TransponderComponent{}},
+		"ac2":  {Id: "ac2", Label: ptr("A320"), Controller: &pb.Controller{Id: ptr("adsblol")}, Geo: &pb.GeoSpatialComponent{}},
+		"ship": {Id: "ship", Controller: &pb.Controller{Id: ptr("ais")}, Geo: &pb.GeoSpatialComponent{}},
+		"note": {Id: "note", Label: ptr("todo")},
+	})
+}
+
+func TestAggregateEntities_ByController(t *testing.T) {
+	w := aggregateWorld()
+
+	got, err := w.AggregateEntities(context.Background(), AggregateRequest{By: AggregateByController})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"adsblol": 2, "ais": 1, "": 1}; !maps.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	got, err = w.AggregateEntities(context.Background(), AggregateRequest{
+		By:     AggregateByLabel,
+		Filter: &pb.EntityFilter{Controller: &pb.ControllerFilter{Id: ptr("adsblol")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"A320": 2}; !maps.Equal(got, want) {
+		t.Errorf("filtered by label: got %v, want %v", got, want)
+	}
+
+	if _, err := w.AggregateEntities(context.Background(), AggregateRequest{By: "color"}); err == nil {
+		t.Error("unknown dimension accepted")
+	}
+}
+
+func TestAggregateEntities_ByComponent(t *testing.T) {
+	w := aggregateWorld()
+
+	got, err := w.AggregateEntities(context.Background(), AggregateRequest{By: AggregateByComponent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["geo"] != 3 || got["transponder"] != 1 || got["label"] != 3 {
+		t.Errorf("got %v", got)
+	}
+	if _, ok := got["controller"]; ok {
+		t.Error("structural fields are not components")
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/aggregate?by=component&filter="+url.QueryEscape(`{"id":"ac1"}`), nil)
+	w.handleAggregate(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var counts map[string]int
+	if err := json.NewDecoder(rec.Body).Decode(&counts); err != nil {
+		t.Fatal(err)
+	}
+	if counts["transponder"] != 1 || counts["geo"] != 1 {
+		t.Errorf("http: got %v", counts)
+	}
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"strconv"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// AtomicPushHeader is the request header that puts Push into transactional
+// mode. In that mode every change is checked before any is applied, so a
+// request either applies completely or not at all. Without it, changes are
+// applied in order and a failure leaves the earlier ones in place.
+const AtomicPushHeader = "Hydris-Atomic"
+
+// isAtomicPush reports whether the request asks for transactional mode.
+func isAtomicPush(h http.Header) bool {
+	atomic, err := strconv.ParseBool(h.Get(AtomicPushHeader))
+	return err == nil && atomic
+}
+
+// stagePush runs the changes and replacements of a push, in order, on
+// copies of the entities they touch, the way the apply loop of Push does:
+// each change passes the lease check against the state the earlier changes
+// of the batch left, and is merged with mode under the same freshness
+// rules. It returns the resulting entities, one per change followed by one
+// per replacement, or the error the apply loop would fail with, naming the
+// index and id of the failing change. Nothing in the world is modified.
+// Must be called with s.l held.
+func (s *WorldServer) stagePush(changes, replacements []*pb.Entity, mode MergeMode) ([]*pb.Entity, error) {
+	// pending holds the state of every entity touched by the batch so far.
+	pending := make(map[string]*entityState)
+	state := func(id string) *entityState {
+		if es, ok := pending[id]; ok {
+			return es
+		}
+		if es, ok := s.head[id]; ok {
+			return &entityState{
+				entity:    proto.Clone(es.entity).(*pb.Entity),
+				lifetimes: maps.Clone(es.lifetimes),
+			}
+		}
+		return nil
+	}
+
+	result := make([]*pb.Entity, 0, len(changes)+len(replacements))
+	for i, e := range changes {
+		e = proto.Clone(e).(*pb.Entity)
+		es := state(e.Id)
+		if e.Lease != nil && es != nil && es.entity.Lease != nil && es.entity.Lease.Controller != e.Lease.Controller {
+			return nil, connect.NewError(connect.CodeFailedPrecondition,
+				fmt.Errorf("change %d: entity %s is leased by controller %s", i, e.Id, es.entity.Lease.Controller))
+		}
+		if es != nil {
+			if merged, accepted := s.mergeEntityComponentsMode(e.Id, es, e, mode); accepted {
+				es.entity = merged
+			}
+		} else {
+			hadNoLifetime := e.Lifetime == nil
+			fillLifetime(e)
+			es = &entityState{entity: e, lifetimes: componentMetas(e, hadNoLifetime)}
+		}
+		s.stampNode(es.entity)
+		pending[e.Id] = es
+		result = append(result, es.entity)
+	}
+	for _, e := range replacements {
+		e = proto.Clone(e).(*pb.Entity)
+		fillLifetime(e)
+		s.stampNode(e)
+		pending[e.Id] = &entityState{entity: e, lifetimes: componentMetas(e, false)}
+		result = append(result, e)
+	}
+	return result, nil
+}
@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"connectrpc.com/connect"
+)
+
+func atomicRequest(msg *pb.EntityChangeRequest) *connect.Request[pb.EntityChangeRequest] {
+	req := peerRequest(msg)
+	req.Header().Set(AtomicPushHeader, "true")
+	return req
+}
+
+// conflictingBatch leases two new entities and then tries to take over
+// dev.ttyACM0, which is held by meshtastic.
+func conflictingBatch() *pb.EntityChangeRequest {
+	return &pb.EntityChangeRequest{
+		Changes: []*pb.Entity{
+			{Id: "dev.ttyACM1", Lease: &pb.Lease{Controller: "mavlink"}},
+			{Id: "dev.ttyACM2", Label: ptr("second")},
+			{Id: "dev.ttyACM0", Lease: &pb.Lease{Controller: "mavlink"}},
+		},
+	}
+}
+
+func leasedWorld() *WorldServer {
+	return testWorld(map[string]*pb.Entity{
+		"dev.ttyACM0": {Id: "dev.ttyACM0", Lease: &pb.Lease{Controller: "meshtastic"}},
+	})
+}
+
+func TestPush_AtomicAppliesNothingOnFailure(t *testing.T) {
+	w := leasedWorld()
+
+	_, err := w.Push(context.Background(), atomicRequest(conflictingBatch()))
+	if connect.CodeOf(err) != connect.CodeFailedPrecondition {
+		t.Fatalf("got %v, want failed precondition", err)
+	}
+	if !strings.Contains(err.Error(), "change 2") || !strings.Contains(err.Error(), "dev.ttyACM0") {
+		t.Errorf("error should name the failing change: %v", err)
+	}
+	for _, id := range []string{"dev.ttyACM1", "dev.ttyACM2"} {
+		if w.GetHead(id) != nil {
+			t.Errorf("%s was applied although the batch failed", id)
+		}
+	}
+}
+
+func TestPush_DefaultModeAppliesPartially(t *testing.T) {
+	w := leasedWorld()
+
+	_, err := w.Push(context.Background(), peerRequest(conflictingBatch()))
+	if connect.CodeOf(err) != connect.CodeFailedPrecondition {
+		t.Fatalf("got %v, want failed precondition", err)
+	}
+	if w.GetHead("dev.ttyACM1") == nil || w.GetHead("dev.ttyACM2") == nil {
+		t.Error("changes before the failing one should have been applied")
+	}
+}
+
+func TestPush_AtomicAppliesAll(t *testing.T) {
+	w := leasedWorld()
+
+	_, err := w.Push(context.Background(), atomicRequest(&pb.EntityChangeRequest{
+		Changes: []*pb.Entity{
+			{Id: "dev.ttyACM1", Lease: &pb.Lease{Controller: "mavlink"}},
+			{Id: "dev.ttyACM0", Lease: &pb.Lease{Controller: "meshtastic"}, Label: ptr("updated")},
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.GetHead("dev.ttyACM1") == nil {
+		t.Error("dev.ttyACM1 should be applied")
+	}
+	if e := w.GetHead("dev.ttyACM0"); e.GetLabel() != "updated" {
+		t.Error("dev.ttyACM0 should be updated")
+	}
+}
+
+func TestStagePush_LeaseWithinBatch(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	_, err := w.stagePush([]*pb.Entity{
+		{Id: "e1", Lease: &pb.Lease{Controller: "a"}},
+		{Id: "e1", Label: ptr("no lease")},
+		{Id: "e1", Lease: &pb.Lease{Controller: "b"}},
+	}, nil, MergeReplaceComponent)
+	if err == nil || !strings.Contains(err.Error(), "change 2") {
+		t.Errorf("lease taken earlier in the batch should conflict, got %v", err)
+	}
+}
+
+// TestPush_AtomicStaleLease checks that a lease the merge rejects as stale
+// is not taken for granted by the checks of the batch.
+func TestPush_AtomicStaleLease(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"e1": {Id: "e1", Label: ptr("released")},
+	})
+	// The lease was released by an update fresher than the batch.
+	es := w.head["e1"]
+	leaseNum := int32(es.entity.ProtoReflect().Descriptor().Fields().ByName("lease").Number())
+	es.lifetimes[leaseNum] = componentMeta{fresh: time.Now().Add(time.Hour)}
+
+	_, err := w.Push(context.Background(), atomicRequest(&pb.EntityChangeRequest{
+		Changes: []*pb.Entity{
+			{Id: "e1", Lease: &pb.Lease{Controller: "a"}, Lifetime: &pb.Lifetime{Fresh: timestamppb.Now()}},
+			{Id: "e1", Lease: &pb.Lease{Controller: "b"}, Lifetime: &pb.Lifetime{Fresh: timestamppb.New(time.Now().Add(2 * time.Hour))}},
+		},
+	}))
+	if err != nil {
+		t.Fatalf("the stale lease was never taken, b may lease e1: %v", err)
+	}
+	if got := w.GetHead("e1").GetLease().GetController(); got != "b" {
+		t.Errorf("e1 leased by %q, want b", got)
+	}
+}
+
+func TestIsAtomicPush(t *testing.T) {
+	for value, want := range map[string]bool{"": false, "true": true, "1": true, "false": false, "yes": false} {
+		h := http.Header{}
+		if value != "" {
+			h.Set(AtomicPushHeader, value)
+		}
+		if got := isAtomicPush(h); got != want {
+			t.Errorf("isAtomicPush(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
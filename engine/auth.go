@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+)
+
+// Identity is the authenticated identity of a client, taken from the client
+// certificate of an mTLS connection.
+type Identity struct {
+	CommonName string
+	DNSNames   []string
+	Emails     []string
+	URIs       []string
+}
+
+// AuthInput describes a single RPC for authorization.
+type AuthInput struct {
+	// Method is the RPC method name, e.g. "Push".
+	Method string
+	// Procedure is the full procedure path, e.g. "/world.WorldService/Push".
+	Procedure string
+	// PeerAddr is the remote address of the connection.
+	PeerAddr string
+	// Identity is nil unless the client presented a certificate.
+	Identity *Identity
+}
+
+// Authorizer decides whether an RPC may proceed. A non-nil error rejects it;
+// errors that are not connect errors are reported as CodePermissionDenied.
+type Authorizer func(ctx context.Context, in AuthInput) error
+
+// SetAuthorizer installs an authorizer that is consulted for every
+// WorldService RPC. It must be set before NewAPIMux is called.
+func (s *WorldServer) SetAuthorizer(a Authorizer) {
+	s.authorizer = a
+}
+
+type identityKey struct{}
+
+// identityFromTLS extracts the identity from the verified (or, without
+// verification, presented) client certificate.
+func identityFromTLS(state *tls.ConnectionState) *Identity {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	cert := state.PeerCertificates[0]
+	id := &Identity{
+		CommonName: cert.Subject.CommonName,
+		DNSNames:   cert.DNSNames,
+		Emails:     cert.EmailAddresses,
+	}
+	for _, u := range cert.URIs {
+		id.URIs = append(id.URIs, u.String())
+	}
+	return id
+}
+
+// withClientIdentity stores the client certificate identity of the request,
+// if any, in its context for the auth interceptor.
+func withClientIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := identityFromTLS(r.TLS); id != nil {
+			r = r.WithContext(context.WithValue(r.Context(), identityKey{}, id))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IdentityFromContext returns the client identity of the current request, or
+// nil if the client did not present a certificate.
+func IdentityFromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
+
+// methodName returns the last segment of a connect procedure path.
+func methodName(procedure string) string {
+	if i := strings.LastIndexByte(procedure, '/'); i >= 0 {
+		return procedure[i+1:]
+	}
+	return procedure
+}
+
+// authInterceptor runs an Authorizer before every unary and streaming RPC
+// and records the decision in an access log. Either may be nil.
+type authInterceptor struct {
+	authorize Authorizer
+	log       *AccessLog
+}
+
+// NewAuthInterceptor returns a connect interceptor that calls authorize with
+// the method name, peer address and client identity of each RPC.
+func NewAuthInterceptor(authorize Authorizer) connect.Interceptor {
+	return &authInterceptor{authorize: authorize}
+}
+
+// check authorizes an RPC. msg is its request, or nil for a stream whose
+// request is not yet received.
+func (a *authInterceptor) check(ctx context.Context, procedure, peerAddr string, msg any) error {
+	in := AuthInput{
+		Method:    methodName(procedure),
+		Procedure: procedure,
+		PeerAddr:  peerAddr,
+		Identity:  IdentityFromContext(ctx),
+	}
+	var err error
+	if a.authorize != nil {
+		err = a.authorize(ctx, in)
+	}
+	var connectErr *connect.Error
+	if err != nil && !errors.As(err, &connectErr) {
+		err = connect.NewError(connect.CodePermissionDenied, err)
+	}
+	a.log.record(ctx, in, requestEntityIDs(msg), err)
+	return err
+}
+
+func (a *authInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if err := a.check(ctx, req.Spec().Procedure, req.Peer().Addr, req.Any()); err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+func (a *authInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (a *authInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if err := a.check(ctx, conn.Spec().Procedure, conn.Peer().Addr, nil); err != nil {
+			return err
+		}
+		return next(ctx, conn)
+	}
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	_goconnect "github.com/projectqai/proto/go/_goconnect"
+)
+
+func TestIdentityFromTLS(t *testing.T) {
+	if identityFromTLS(nil) != nil {
+		t.Error("expected nil identity without TLS")
+	}
+	if identityFromTLS(&tls.ConnectionState{}) != nil {
+		t.Error("expected nil identity without a client certificate")
+	}
+
+	cert := newTestCA(t).issue(t, "ops", false)
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := identityFromTLS(&tls.ConnectionState{PeerCertificates: []*x509.Certificate{parsed}})
+	if id == nil {
+		t.Fatal("expected identity")
+	}
+	if id.CommonName != "ops" {
+		t.Errorf("CommonName = %q, want ops", id.CommonName)
+	}
+	if len(id.DNSNames) != 1 || id.DNSNames[0] != "ops.example.com" {
+		t.Errorf("DNSNames = %v", id.DNSNames)
+	}
+	if len(id.URIs) != 1 || id.URIs[0] != "spiffe://example.com/ops" {
+		t.Errorf("URIs = %v", id.URIs)
+	}
+}
+
+func TestMethodName(t *testing.T) {
+	if got := methodName("/world.WorldService/Push"); got != "Push" {
+		t.Errorf("methodName = %q, want Push", got)
+	}
+	if got := methodName("Push"); got != "Push" {
+		t.Errorf("methodName = %q, want Push", got)
+	}
+}
+
+// TestAuthInterceptor_PolicyOnCommonName serves the world over mTLS with an
+// authorizer that only lets the "ops" identity push.
+func TestAuthInterceptor_PolicyOnCommonName(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		seen []AuthInput
+	)
+	w := NewWorldServer()
+	w.SetAuthorizer(func(ctx context.Context, in AuthInput) error {
+		mu.Lock()
+		seen = append(seen, in)
+		mu.Unlock()
+		if in.Method != "Push" {
+			return nil
+		}
+		if in.Identity == nil || in.Identity.CommonName != "ops" {
+			return errors.New("push requires the ops identity")
+		}
+		return nil
+	})
+
+	interceptor := NewAuthInterceptor(w.authorizer)
+	mux := http.NewServeMux()
+	path, h := _goconnect.NewWorldServiceHandler(w, connect.WithInterceptors(interceptor))
+	mux.Handle(path, withClientIdentity(h))
+
+	srv := httptest.NewUnstartedServer(mux)
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	ca := newTestCA(t)
+	clientFor := func(cn string) _goconnect.WorldServiceClient {
+		tr := srv.Client().Transport.(*http.Transport).Clone()
+		tr.TLSClientConfig.Certificates = []tls.Certificate{ca.issue(t, cn, false)}
+		return _goconnect.NewWorldServiceClient(&http.Client{Transport: tr}, srv.URL)
+	}
+
+	push := func(c _goconnect.WorldServiceClient) error {
+		_, err := c.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{
+			Changes: []*pb.Entity{{Id: "e1"}},
+		}))
+		return err
+	}
+
+	if err := push(clientFor("ops")); err != nil {
+		t.Fatalf("push as ops: %v", err)
+	}
+
+	guest := clientFor("guest")
+	err := push(guest)
+	if connect.CodeOf(err) != connect.CodePermissionDenied {
+		t.Fatalf("push as guest: got %v, want permission denied", err)
+	}
+	if _, err := guest.GetEntity(context.Background(), connect.NewRequest(&pb.GetEntityRequest{Id: "e1"})); err != nil {
+		t.Fatalf("get as guest: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 3 {
+		t.Fatalf("authorizer called %d times, want 3", len(seen))
+	}
+	if seen[0].Procedure != _goconnect.WorldServicePushProcedure {
+		t.Errorf("Procedure = %q, want %q", seen[0].Procedure, _goconnect.WorldServicePushProcedure)
+	}
+	if seen[1].Identity == nil || seen[1].Identity.CommonName != "guest" {
+		t.Errorf("second call identity = %+v, want guest", seen[1].Identity)
+	}
+	if seen[2].Method != "GetEntity" {
+		t.Errorf("third call method = %q, want GetEntity", seen[2].Method)
+	}
+	if seen[2].PeerAddr == "" {
+		t.Error("expected peer address")
+	}
+}
+
+// assertHTTPDenied checks that handler asks the authorizer of w about r as
+// method and refuses r when it is denied.
+func assertHTTPDenied(t *testing.T, w *WorldServer, handler http.Handler, r *http.Request, method string) {
+	t.Helper()
+	var seen []string
+	w.SetAuthorizer(func(ctx context.Context, in AuthInput) error {
+		seen = append(seen, in.Method)
+		return errors.New("denied")
+	})
+	defer w.SetAuthorizer(nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("%s %s: status %d, want 403", r.Method, r.URL.Path, rec.Code)
+	}
+	if len(seen) != 1 || seen[0] != method {
+		t.Errorf("%s %s: authorizer saw %v, want %s", r.Method, r.URL.Path, seen, method)
+	}
+}
@@ -1,19 +1,33 @@
 package engine
 
 import (
+	"context"
+	"log/slog"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/projectqai/hydris/pkg/metrics"
 	pb "github.com/projectqai/proto/go"
 )
 
 type Bus struct {
 	mu        sync.RWMutex
 	consumers map[*Consumer]struct{}
+	// unregistered is closed and replaced whenever a consumer leaves
+	unregistered chan struct{}
+	// versions numbers every change that passes through the bus, for Resync
+	versions *versionLedger
+	// componentWatchers counts the consumers with a component filter; only
+	// while there are any does Push work out which components it changed
+	componentWatchers atomic.Int32
 }
 
 func NewBus() *Bus {
 	return &Bus{
-		consumers: make(map[*Consumer]struct{}),
+		consumers:    make(map[*Consumer]struct{}),
+		unregistered: make(chan struct{}),
+		versions:     newVersionLedger(),
 	}
 }
 
@@ -21,12 +35,58 @@ func (b *Bus) Register(c *Consumer) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	b.consumers[c] = struct{}{}
+	if c.componentFilter != nil {
+		b.componentWatchers.Add(1)
+	}
 }
 
 func (b *Bus) Unregister(c *Consumer) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
+	b.removeLocked(c)
+	close(b.unregistered)
+	b.unregistered = make(chan struct{})
+}
+
+// removeLocked removes c if it is registered. The caller must hold b.mu.
+func (b *Bus) removeLocked(c *Consumer) {
+	if _, ok := b.consumers[c]; !ok {
+		return
+	}
 	delete(b.consumers, c)
+	if c.componentFilter != nil {
+		b.componentWatchers.Add(-1)
+	}
+}
+
+// WantsComponents reports whether a consumer filters on the components an
+// update added or removed, see DirtyComponents.
+func (b *Bus) WantsComponents() bool {
+	return b.componentWatchers.Load() > 0
+}
+
+// Len returns the number of registered consumers.
+func (b *Bus) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.consumers)
+}
+
+// Wait blocks until no consumers are registered or ctx is done.
+func (b *Bus) Wait(ctx context.Context) error {
+	for {
+		b.mu.RLock()
+		n, unregistered := len(b.consumers), b.unregistered
+		b.mu.RUnlock()
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-unregistered:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
 // CloseAll cancels all consumers, forcing their WatchEntities streams to terminate.
@@ -40,7 +100,98 @@ func (b *Bus) CloseAll() {
 	}
 }
 
+// EvictStalled unregisters and cancels the consumers whose send has been
+// blocked for longer than timeout at now, and returns them. They stop
+// queueing changes and no longer count for Wait; their stream handlers
+// return once the blocked send fails or the client goes away. Consumers
+// are only ever marked dirty without blocking, so a stalled one never
+// holds up the others.
+func (b *Bus) EvictStalled(timeout time.Duration, now time.Time) []*Consumer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var evicted []*Consumer
+	for c := range b.consumers {
+		if since := c.sendingSince(); !since.IsZero() && now.Sub(since) > timeout {
+			b.removeLocked(c)
+			if c.cancel != nil {
+				c.cancel()
+			}
+			evicted = append(evicted, c)
+		}
+	}
+	if len(evicted) > 0 {
+		close(b.unregistered)
+		b.unregistered = make(chan struct{})
+	}
+	return evicted
+}
+
+// QueueDepths returns the number of pending changes per consumer.
+func (b *Bus) QueueDepths() map[uint64]int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	depths := make(map[uint64]int, len(b.consumers))
+	for c := range b.consumers {
+		depths[c.id] = c.queueDepth()
+	}
+	return depths
+}
+
+// FilterStats returns the cumulative filter cost per consumer.
+func (b *Bus) FilterStats() map[uint64]metrics.FilterStats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	stats := make(map[uint64]metrics.FilterStats, len(b.consumers))
+	for c := range b.consumers {
+		stats[c.id] = c.filterStats()
+	}
+	return stats
+}
+
 func (b *Bus) Dirty(entityID string, entity *pb.Entity, change pb.EntityChange) {
+	b.dirty("", entityID, entity, change, nil)
+}
+
+// DirtyContext is Dirty for a change made by a request; the trace id of
+// ctx is handed to the consumers so that sending the change can be logged
+// with it.
+func (b *Bus) DirtyContext(ctx context.Context, entityID string, entity *pb.Entity, change pb.EntityChange) {
+	traceID := TraceIDFromContext(ctx)
+	if traceID != "" {
+		slog.DebugContext(ctx, "bus: entity dirty", "entity", entityID, "change", change)
+	}
+	b.dirty(traceID, entityID, entity, change, nil)
+}
+
+// DirtyComponents is DirtyContext for an update that added or removed
+// components of the entity. Consumers collect them until the entity is
+// sent. Callers only need to work them out if WantsComponents.
+func (b *Bus) DirtyComponents(ctx context.Context, entityID string, entity *pb.Entity, components []pb.EntityComponent) {
+	traceID := TraceIDFromContext(ctx)
+	if traceID != "" {
+		slog.DebugContext(ctx, "bus: entity dirty", "entity", entityID, "change", pb.EntityChange_EntityChangeUpdated, "components", components)
+	}
+	b.dirty(traceID, entityID, entity, pb.EntityChange_EntityChangeUpdated, components)
+}
+
+// Touch tells consumers that entityID changed in a way that is not urgent,
+// such as a heartbeat moving its lifetime. It is queued at the lowest
+// priority a consumer accepts and never demotes a change that is already
+// pending.
+func (b *Bus) Touch(entityID string) {
+	b.versions.bump(entityID, pb.EntityChange_EntityChangeUpdated)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for c := range b.consumers {
+		c.touch(entityID)
+	}
+}
+
+func (b *Bus) dirty(traceID, entityID string, entity *pb.Entity, change pb.EntityChange, components []pb.EntityComponent) {
+	b.versions.bump(entityID, change)
+
 	priority := pb.Priority_PriorityRoutine
 	if entity != nil && entity.Priority != nil {
 		priority = *entity.Priority
@@ -50,6 +201,6 @@ func (b *Bus) Dirty(entityID string, entity *pb.Entity, change pb.EntityChange)
 	defer b.mu.RUnlock()
 
 	for c := range b.consumers {
-		c.markDirty(entityID, priority, change, entity)
+		c.markDirtyTraced(traceID, entityID, priority, change, entity, components)
 	}
 }
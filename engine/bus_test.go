@@ -5,6 +5,7 @@ package engine
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -1150,3 +1151,159 @@ func TestSenderLoop_Keepalive(t *testing.T) {
 		t.Errorf("expected at least 1 send from keepalive, got %d", numSent)
 	}
 }
+
+func TestSenderLoop_UpdateOverPendingExpiry(t *testing.T) {
+	snapshot := &pb.Entity{Id: "e1", Label: ptr("last seen")}
+	world := testWorld(map[string]*pb.Entity{})
+	c := NewConsumer(world, nil, nil)
+	c.observed["e1"] = struct{}{}
+
+	// The entity expired, then a late update for it was queued before the
+	// expiry was sent.
+	c.markDirty("e1", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeExpired, snapshot)
+	c.markDirty("e1", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated, snapshot)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var mu sync.Mutex
+	var sent []*pb.EntityChangeEvent
+	go func() {
+		_ = c.SenderLoop(ctx, func(ev *pb.EntityChangeEvent) error {
+			mu.Lock()
+			sent = append(sent, ev)
+			mu.Unlock()
+			return nil
+		})
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 1 || sent[0].T != pb.EntityChange_EntityChangeExpired {
+		t.Fatalf("got %v, want the pending expiry", sent)
+	}
+	if sent[0].Entity.GetLabel() != "last seen" {
+		t.Errorf("expiry carries %v, want the snapshot", sent[0].Entity)
+	}
+	if _, ok := c.observed["e1"]; ok {
+		t.Error("expired entity still observed")
+	}
+}
+
+func TestSenderLoop_UpdateOverPendingExpiryOfRepushedEntity(t *testing.T) {
+	world := testWorld(map[string]*pb.Entity{})
+	c := NewConsumer(world, nil, nil)
+
+	c.markDirty("e1", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeExpired, &pb.Entity{Id: "e1"})
+	world.initEntity(&pb.Entity{Id: "e1", Label: ptr("again")})
+	c.markDirty("e1", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated, world.GetHead("e1"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var mu sync.Mutex
+	var sent []*pb.EntityChangeEvent
+	go func() {
+		_ = c.SenderLoop(ctx, func(ev *pb.EntityChangeEvent) error {
+			mu.Lock()
+			sent = append(sent, ev)
+			mu.Unlock()
+			return nil
+		})
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 1 || sent[0].T != pb.EntityChange_EntityChangeUpdated || sent[0].Entity.GetLabel() != "again" {
+		t.Fatalf("got %v, want the update of the pushed again entity", sent)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.expiredSnapshots["e1"]; ok {
+		t.Error("stale expiry snapshot kept")
+	}
+}
+
+func TestSenderLoop_NoUpdateAfterExpiryUnderRace(t *testing.T) {
+	const n = 50
+	entities := make(map[string]*pb.Entity, n)
+	for i := range n {
+		id := fmt.Sprintf("e%d", i)
+		entities[id] = &pb.Entity{Id: id}
+	}
+	world := testWorld(entities)
+	c := NewConsumer(world, nil, nil)
+	world.bus.Register(c)
+	defer world.bus.Unregister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	events := make(map[string][]pb.EntityChange)
+	senderDone := make(chan struct{})
+	go func() {
+		defer close(senderDone)
+		_ = c.SenderLoop(ctx, func(ev *pb.EntityChangeEvent) error {
+			mu.Lock()
+			events[ev.Entity.GetId()] = append(events[ev.Entity.GetId()], ev.T)
+			mu.Unlock()
+			return nil
+		})
+	}()
+
+	// Late updates, like transformer results or touches, race the expiry
+	// of every entity. None of the entities is pushed again.
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				for id, e := range entities {
+					world.bus.Dirty(id, e, pb.EntityChange_EntityChangeUpdated)
+				}
+			}
+		}()
+	}
+	for id, e := range entities {
+		world.l.Lock()
+		world.deleteEntity(id)
+		world.bus.Dirty(id, e, pb.EntityChange_EntityChangeExpired)
+		world.l.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+
+	// Let the sender drain what is left.
+	deadline := time.Now().Add(time.Second)
+	for c.queueDepth() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-senderDone
+
+	mu.Lock()
+	defer mu.Unlock()
+	for id := range entities {
+		got := events[id]
+		first := slices.Index(got, pb.EntityChange_EntityChangeExpired)
+		if first < 0 {
+			t.Errorf("%s: expiry never delivered, got %v", id, got)
+			continue
+		}
+		if first != len(got)-1 {
+			t.Errorf("%s: events after the expiry: %v", id, got)
+		}
+	}
+}
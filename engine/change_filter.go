@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+// ChangeFilterHeader restricts the change types WatchEntities sends. The
+// value is a comma separated list of updated, expired and unobserved;
+// "expired" gives a consumer that only sees expiries. The initial snapshot
+// is sent as updates, so it is skipped unless updated is requested.
+// WatchBehavior is defined in the proto module and has no field for this,
+// so it is carried as a header.
+const ChangeFilterHeader = "Hydris-Change-Filter"
+
+// changeFilter is the set of change types a consumer sends. Nil sends all.
+type changeFilter map[pb.EntityChange]struct{}
+
+var changeFilterTerms = map[string]pb.EntityChange{
+	"updated":    pb.EntityChange_EntityChangeUpdated,
+	"expired":    pb.EntityChange_EntityChangeExpired,
+	"unobserved": pb.EntityChange_EntityChangeUnobserved,
+}
+
+// parseChangeFilter parses the value of ChangeFilterHeader. An empty value
+// returns nil.
+func parseChangeFilter(v string) (changeFilter, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil, nil
+	}
+	f := make(changeFilter)
+	for _, term := range strings.Split(v, ",") {
+		change, ok := changeFilterTerms[strings.ToLower(strings.TrimSpace(term))]
+		if !ok {
+			return nil, fmt.Errorf("unknown change filter term %q", term)
+		}
+		f[change] = struct{}{}
+	}
+	return f, nil
+}
+
+// wants reports whether change is sent to the consumer.
+func (f changeFilter) wants(change pb.EntityChange) bool {
+	if f == nil {
+		return true
+	}
+	_, ok := f[change]
+	return ok
+}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+// drain runs c's sender loop for a short while and returns what it sent.
+func drain(t *testing.T, c *Consumer) []*pb.EntityChangeEvent {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var sent []*pb.EntityChangeEvent
+	assertContextErr(t, c.SenderLoop(ctx, func(ev *pb.EntityChangeEvent) error {
+		sent = append(sent, ev)
+		return nil
+	}))
+	return sent
+}
+
+func TestChangeFilter_ExpiredOnly(t *testing.T) {
+	world := testWorld(map[string]*pb.Entity{"e1": {Id: "e1"}})
+	c := NewConsumer(world, nil, nil)
+	c.changes, _ = parseChangeFilter("expired")
+
+	c.markDirty("e1", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated, nil)
+	// An update coalesced with a later expiry must still deliver the expiry.
+	c.markDirty("e2", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated, nil)
+	c.markDirty("e2", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeExpired, &pb.Entity{Id: "e2"})
+
+	sent := drain(t, c)
+	if len(sent) != 1 {
+		t.Fatalf("expected 1 sent, got %d", len(sent))
+	}
+	if sent[0].Entity.Id != "e2" || sent[0].T != pb.EntityChange_EntityChangeExpired {
+		t.Errorf("got %s %v, want e2 expired", sent[0].Entity.Id, sent[0].T)
+	}
+}
+
+func TestChangeFilter_UpdatedOnly(t *testing.T) {
+	world := testWorld(map[string]*pb.Entity{"e1": {Id: "e1"}})
+	c := NewConsumer(world, nil, nil)
+	c.changes, _ = parseChangeFilter("updated")
+
+	c.markDirty("e1", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated, nil)
+	c.markDirty("e2", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeExpired, &pb.Entity{Id: "e2"})
+
+	sent := drain(t, c)
+	if len(sent) != 1 {
+		t.Fatalf("expected 1 sent, got %d", len(sent))
+	}
+	if sent[0].Entity.Id != "e1" || sent[0].T != pb.EntityChange_EntityChangeUpdated {
+		t.Errorf("got %s %v, want e1 updated", sent[0].Entity.Id, sent[0].T)
+	}
+}
+
+func TestParseChangeFilter(t *testing.T) {
+	f, err := parseChangeFilter(" Expired, unobserved ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.wants(pb.EntityChange_EntityChangeUpdated) || !f.wants(pb.EntityChange_EntityChangeUnobserved) {
+		t.Errorf("unexpected filter %v", f)
+	}
+	if f, _ := parseChangeFilter(""); !f.wants(pb.EntityChange_EntityChangeUpdated) {
+		t.Error("empty filter should send everything")
+	}
+	if _, err := parseChangeFilter("deleted"); err == nil {
+		t.Error("expected error for unknown term")
+	}
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+// ComponentChangesHeader makes WatchEntities send updates only when one of
+// the listed components was added to or removed from the entity, e.g.
+// "transponder" to learn when a track gains or loses its transponder.
+// The value is a comma separated list of EntityComponent names without
+// their prefix, case insensitive. Expiries and unobserves are sent as
+// usual, and so is the initial snapshot. EntityChangeEvent is defined in
+// the proto module and has no field for the changed components, so they
+// can only be filtered on. Keepalive resends are not filtered.
+const ComponentChangesHeader = "Hydris-Component-Changes"
+
+// componentSet is a set of entity components.
+type componentSet map[pb.EntityComponent]struct{}
+
+// presentComponents returns the components entity has.
+func presentComponents(entity *pb.Entity) componentSet {
+	set := make(componentSet)
+	if entity == nil {
+		return set
+	}
+	for v := range pb.EntityComponent_name {
+		if entityHasComponent(entity, uint32(v)) {
+			set[pb.EntityComponent(v)] = struct{}{}
+		}
+	}
+	return set
+}
+
+// changedComponents returns the components that are present in exactly
+// one of before and entity, sorted.
+func changedComponents(before componentSet, entity *pb.Entity) []pb.EntityComponent {
+	after := presentComponents(entity)
+	var changed []pb.EntityComponent
+	for c := range after {
+		if _, ok := before[c]; !ok {
+			changed = append(changed, c)
+		}
+	}
+	for c := range before {
+		if _, ok := after[c]; !ok {
+			changed = append(changed, c)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// sorted returns the components of s in order, or nil if s is empty.
+func (s componentSet) sorted() []pb.EntityComponent {
+	if len(s) == 0 {
+		return nil
+	}
+	out := make([]pb.EntityComponent, 0, len(s))
+	for c := range s {
+		out = append(out, c)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// wantsAny reports whether an update that changed components is sent to
+// the consumer. A nil set wants every update.
+func (s componentSet) wantsAny(components []pb.EntityComponent) bool {
+	if s == nil {
+		return true
+	}
+	for _, c := range components {
+		if _, ok := s[c]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseComponentChanges parses the value of ComponentChangesHeader. An
+// empty value returns nil.
+func parseComponentChanges(v string) (componentSet, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil, nil
+	}
+	s := make(componentSet)
+	for _, term := range strings.Split(v, ",") {
+		c, ok := componentByName(strings.TrimSpace(term))
+		if !ok || c == 0 {
+			return nil, fmt.Errorf("unknown component %q", term)
+		}
+		s[pb.EntityComponent(c)] = struct{}{}
+	}
+	return s, nil
+}
+
+// componentByName looks up name case insensitively among the
+// EntityComponent names without their prefix.
+func componentByName(name string) (int32, bool) {
+	for n, v := range pb.EntityComponent_value {
+		if strings.EqualFold(strings.TrimPrefix(n, "EntityComponent"), name) {
+			return v, true
+		}
+	}
+	return 0, false
+}
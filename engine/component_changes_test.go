@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestPush_ReportsAddedComponents(t *testing.T) {
+	world := testWorld(map[string]*pb.Entity{
+		"track1": {Id: "track1", Lifetime: &pb.Lifetime{From: timestamppb.Now()}, Geo: &pb.GeoSpatialComponent{Latitude: 54, Longitude: 10}},
+	})
+	c := NewConsumer(world, nil, nil)
+	c.componentFilter, _ = parseComponentChanges("transponder")
+	world.bus.Register(c)
+
+	push := func(e *pb.Entity) {
+		t.Helper()
+		if _, err := world.Push(context.Background(), peerRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{e}})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	push(&pb.Entity{Id: "track1", Transponder: &pb.TransponderComponent{}})
+	if sent := drain(t, c); len(sent) != 1 {
+		t.Fatalf("got %v, want the update adding the transponder", sent)
+	}
+
+	push(&pb.Entity{Id: "track1", Geo: &pb.GeoSpatialComponent{Latitude: 55, Longitude: 10}})
+	if sent := drain(t, c); len(sent) != 0 {
+		t.Fatalf("got %v, want no update without changed components", sent)
+	}
+}
+
+func TestPush_ComponentsOnlyWithComponentFilter(t *testing.T) {
+	world := testWorld(map[string]*pb.Entity{
+		"track1": {Id: "track1", Lifetime: &pb.Lifetime{From: timestamppb.Now()}},
+	})
+	c := NewConsumer(world, nil, nil)
+	world.bus.Register(c)
+	if world.bus.WantsComponents() {
+		t.Fatal("bus wants components without a component filter")
+	}
+
+	_, err := world.Push(context.Background(), peerRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{
+		{Id: "track1", Transponder: &pb.TransponderComponent{}},
+	}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.takeComponents("track1"); got != nil {
+		t.Errorf("components %v worked out for no consumer", got)
+	}
+
+	filtered := NewConsumer(world, nil, nil)
+	filtered.componentFilter, _ = parseComponentChanges("transponder")
+	world.bus.Register(filtered)
+	world.bus.Unregister(filtered)
+	world.bus.Unregister(filtered)
+	if world.bus.WantsComponents() {
+		t.Error("bus still wants components after the filtered consumer left")
+	}
+}
+
+func TestConsumer_CoalescedComponents(t *testing.T) {
+	world := testWorld(map[string]*pb.Entity{"e1": {Id: "e1"}})
+	c := NewConsumer(world, nil, nil)
+
+	c.markDirtyTraced("", "e1", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated, nil,
+		[]pb.EntityComponent{pb.EntityComponent_EntityComponentTransponder})
+	c.markDirtyTraced("", "e1", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated, nil,
+		[]pb.EntityComponent{pb.EntityComponent_EntityComponentGeo})
+
+	want := []pb.EntityComponent{pb.EntityComponent_EntityComponentGeo, pb.EntityComponent_EntityComponentTransponder}
+	slices.Sort(want)
+	if got := c.takeComponents("e1"); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestComponentFilter_SkipsPureUpdates(t *testing.T) {
+	world := testWorld(map[string]*pb.Entity{
+		"e1": {Id: "e1"},
+		"e2": {Id: "e2"},
+		"e3": {Id: "e3"},
+	})
+	c := NewConsumer(world, nil, nil)
+	c.componentFilter, _ = parseComponentChanges("transponder")
+
+	c.markDirty("e1", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated, nil)
+	c.markDirtyTraced("", "e2", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated, nil,
+		[]pb.EntityComponent{pb.EntityComponent_EntityComponentTransponder})
+	c.markDirtyTraced("", "e3", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated, nil,
+		[]pb.EntityComponent{pb.EntityComponent_EntityComponentGeo})
+
+	sent := drain(t, c)
+	if len(sent) != 1 || sent[0].Entity.Id != "e2" {
+		t.Errorf("got %v, want only e2", sent)
+	}
+}
+
+func TestComponentFilter_Flash(t *testing.T) {
+	world := testWorld(map[string]*pb.Entity{
+		"e1": {Id: "e1"},
+		"e2": {Id: "e2"},
+	})
+	c := NewConsumer(world, nil, nil)
+	c.componentFilter, _ = parseComponentChanges("transponder")
+
+	c.markDirty("e1", pb.Priority_PriorityFlash, pb.EntityChange_EntityChangeUpdated, nil)
+	c.markDirtyTraced("", "e2", pb.Priority_PriorityFlash, pb.EntityChange_EntityChangeUpdated, nil,
+		[]pb.EntityComponent{pb.EntityComponent_EntityComponentTransponder})
+
+	sent := drain(t, c)
+	if len(sent) != 1 || sent[0].Entity.Id != "e2" {
+		t.Errorf("got %v, want only e2", sent)
+	}
+}
+
+func TestComponentFilter_KeepsKeepalives(t *testing.T) {
+	world := testWorld(map[string]*pb.Entity{"e1": {Id: "e1"}})
+	c := NewConsumer(world, nil, nil)
+	c.componentFilter, _ = parseComponentChanges("transponder")
+
+	c.requeueAll()
+	sent := drain(t, c)
+	if len(sent) != 1 || sent[0].Entity.Id != "e1" {
+		t.Fatalf("got %v, want the keepalive of e1", sent)
+	}
+
+	// The exemption ends with the keepalive.
+	c.markDirty("e1", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated, nil)
+	if sent := drain(t, c); len(sent) != 0 {
+		t.Errorf("got %v after the keepalive, want nothing", sent)
+	}
+}
+
+func TestParseComponentChanges(t *testing.T) {
+	s, err := parseComponentChanges(" Transponder, geo ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.wantsAny([]pb.EntityComponent{pb.EntityComponent_EntityComponentGeo}) || s.wantsAny([]pb.EntityComponent{pb.EntityComponent_EntityComponentShape}) {
+		t.Errorf("unexpected set %v", s)
+	}
+	if s, _ := parseComponentChanges(""); !s.wantsAny(nil) {
+		t.Error("empty header should send every update")
+	}
+	if _, err := parseComponentChanges("sonar"); err == nil {
+		t.Error("expected error for unknown component")
+	}
+}
@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"connectrpc.com/connect"
+	"github.com/kaptinlin/jsonschema"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// configSchemas caches compiled Configurable schemas by their JSON.
+var configSchemas sync.Map // string -> *jsonschema.Schema
+
+// validateConfigSchemas checks the config of every entity in a push
+// against the schema its controller advertises. The caller must hold s.l.
+func (s *WorldServer) validateConfigSchemas(req *pb.EntityChangeRequest) error {
+	for _, e := range req.Changes {
+		if err := s.validateConfigSchema(e); err != nil {
+			return err
+		}
+	}
+	for _, e := range req.Replacements {
+		if err := s.validateConfigSchema(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateConfigSchema checks e.Config against the Configurable schema of
+// e, or of the entity in head if e doesn't carry one. Entities without a
+// config value or without a schema are accepted.
+func (s *WorldServer) validateConfigSchema(e *pb.Entity) error {
+	value := e.GetConfig().GetValue()
+	if value == nil {
+		return nil
+	}
+	schema := e.GetConfigurable().GetSchema()
+	if schema == nil {
+		if es, ok := s.head[e.Id]; ok {
+			schema = es.entity.GetConfigurable().GetSchema()
+		}
+	}
+	if schema == nil {
+		return nil
+	}
+
+	compiled, err := compileConfigSchema(schema)
+	if err != nil {
+		// A broken schema is the controller's bug, not the client's.
+		return connect.NewError(connect.CodeFailedPrecondition,
+			fmt.Errorf("entity %s: invalid config schema: %w", e.Id, err))
+	}
+	result := compiled.Validate(value.AsMap())
+	if result.IsValid() {
+		return nil
+	}
+
+	var problems []string
+	for path, verr := range result.Errors {
+		problems = append(problems, path+": "+verr.Message)
+	}
+	slices.Sort(problems)
+	return connect.NewError(connect.CodeInvalidArgument,
+		fmt.Errorf("entity %s: config does not match schema: %s", e.Id, strings.Join(problems, "; ")))
+}
+
+func compileConfigSchema(schema *structpb.Struct) (*jsonschema.Schema, error) {
+	b, err := protojson.MarshalOptions{Deterministic: true}.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	if compiled, ok := configSchemas.Load(string(b)); ok {
+		return compiled.(*jsonschema.Schema), nil
+	}
+	compiled, err := jsonschema.NewCompiler().Compile(b)
+	if err != nil {
+		return nil, err
+	}
+	configSchemas.Store(string(b), compiled)
+	return compiled, nil
+}
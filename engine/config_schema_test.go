@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func mustStruct(t *testing.T, m map[string]any) *structpb.Struct {
+	t.Helper()
+	s, err := structpb.NewStruct(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestPush_ConfigSchema(t *testing.T) {
+	schema := mustStruct(t, map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"port": map[string]any{"type": "integer", "minimum": 1},
+		},
+		"required": []any{"port"},
+	})
+	w := testWorld(map[string]*pb.Entity{
+		"dev1": {Id: "dev1", Configurable: &pb.ConfigurableComponent{Schema: schema}},
+	})
+	ctx := context.Background()
+
+	push := func(value map[string]any) error {
+		_, err := w.Push(ctx, peerRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{{
+			Id:     "dev1",
+			Config: &pb.ConfigurationComponent{Value: mustStruct(t, value)},
+		}}}))
+		return err
+	}
+
+	if err := push(map[string]any{"port": 8080}); err != nil {
+		t.Fatalf("valid config rejected: %v", err)
+	}
+	for name, value := range map[string]map[string]any{
+		"wrong type": {"port": "http"},
+		"too small":  {"port": 0},
+		"missing":    {},
+	} {
+		if err := push(value); connect.CodeOf(err) != connect.CodeInvalidArgument {
+			t.Errorf("%s: got %v, want InvalidArgument", name, err)
+		}
+	}
+	if got := w.GetHead("dev1").Config.Value.Fields["port"].GetNumberValue(); got != 8080 {
+		t.Errorf("port %v after rejected pushes, want 8080", got)
+	}
+
+	// A change carrying its own schema is checked against that one.
+	_, err := w.Push(ctx, peerRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{{
+		Id:           "dev2",
+		Configurable: &pb.ConfigurableComponent{Schema: schema},
+		Config:       &pb.ConfigurationComponent{Value: mustStruct(t, map[string]any{})},
+	}}}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("new entity: got %v, want InvalidArgument", err)
+	}
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+// Configurable is one kind of configuration a controller accepts: a device
+// class a service offers, or the config of a single entity.
+type Configurable struct {
+	Controller string `json:"controller"`
+	// Class is the device class, empty for an entity's own config.
+	Class string `json:"class,omitempty"`
+	Label string `json:"label,omitempty"`
+	// Service is the entity that offers the class, whose children are
+	// configured with it.
+	Service string `json:"service,omitempty"`
+	// Schema is the JSON Schema of the config, nil until a device of the
+	// class has been set up by its controller.
+	Schema map[string]any `json:"schema,omitempty"`
+	// Entities are the ids of the entities configured by Schema, sorted.
+	Entities []string `json:"entities,omitempty"`
+}
+
+// configurableKey identifies a Configurable: the controller and either the
+// device class or, without one, the entity.
+type configurableKey struct {
+	controller, class, entity string
+}
+
+// ListConfigurables returns what controllers advertise as configurable,
+// sorted by controller, class and entity: the device classes of service
+// entities' ConfigurableComponent, with the schema their controller pushed
+// onto devices of that class, and the schemas of entities configured on
+// their own. A generic UI renders forms from them, for example to add an
+// AIS stream.
+//
+// The proto module has no RPC for this, so it is served over HTTP as
+// GET /configurables.
+func (s *WorldServer) ListConfigurables() []*Configurable {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	byKey := make(map[configurableKey]*Configurable)
+	get := func(key configurableKey) *Configurable {
+		c, ok := byKey[key]
+		if !ok {
+			c = &Configurable{Controller: key.controller, Class: key.class}
+			byKey[key] = c
+		}
+		return c
+	}
+
+	ids := make([]string, 0, len(s.head))
+	for id := range s.head {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		e := s.head[id].entity
+		configurable := e.GetConfigurable()
+		if configurable == nil {
+			continue
+		}
+		controller := e.GetController().GetId()
+
+		for _, option := range configurable.GetSupportedDeviceClasses() {
+			c := get(configurableKey{controller: controller, class: option.GetClass()})
+			c.Service = e.Id
+			if c.Label == "" {
+				c.Label = option.GetLabel()
+			}
+		}
+
+		if configurable.GetSchema() == nil {
+			continue
+		}
+		key := configurableKey{controller: controller, class: e.GetDevice().GetClass()}
+		if key.class == "" {
+			key.entity = e.Id
+		}
+		c := get(key)
+		if c.Schema == nil {
+			c.Schema = configurable.Schema.AsMap()
+		}
+		if c.Label == "" {
+			c.Label = configurable.GetLabel()
+		}
+		if c.Service == "" && key.class != "" {
+			c.Service = e.GetDevice().GetParent()
+		}
+		c.Entities = append(c.Entities, e.Id)
+	}
+
+	out := make([]*Configurable, 0, len(byKey))
+	for _, c := range byKey {
+		out = append(out, c)
+	}
+	slices.SortFunc(out, func(a, b *Configurable) int {
+		return cmp.Or(
+			cmp.Compare(a.Controller, b.Controller),
+			cmp.Compare(a.Class, b.Class),
+			slices.Compare(a.Entities, b.Entities),
+		)
+	})
+	return out
+}
+
+// handleConfigurables serves ListConfigurables over HTTP:
+//
+//	GET /configurables
+//
+// The request is checked by the authorizer as method "ListConfigurables".
+func (s *WorldServer) handleConfigurables(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorizeHTTP(r, "ListConfigurables"); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.ListConfigurables())
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func configurablesTestWorld(t *testing.T) *WorldServer {
+	t.Helper()
+	streamSchema, err := structpb.NewStruct(map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"host": map[string]any{"type": "string"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeSchema, _ := structpb.NewStruct(map[string]any{"type": "object"})
+
+	stream := func(id string) *pb.Entity {
+		return &pb.Entity{
+			Id:           id,
+			Controller:   &pb.Controller{Id: ptr("ais")},
+			Device:       &pb.DeviceComponent{Class: ptr("stream"), Parent: ptr("ais.service")},
+			Configurable: &pb.ConfigurableComponent{Schema: streamSchema, Label: ptr("AIS Stream")},
+		}
+	}
+	return testWorld(map[string]*pb.Entity{
+		"ais.service": {
+			Id:         "ais.service",
+			Controller: &pb.Controller{Id: ptr("ais")},
+			Configurable: &pb.ConfigurableComponent{SupportedDeviceClasses: []*pb.DeviceClassOption{
+				{Class: "stream", Label: "AIS Stream"},
+			}},
+		},
+		"ais.stream.b": stream("ais.stream.b"),
+		"ais.stream.a": stream("ais.stream.a"),
+		"spacetrack.service": {
+			Id:         "spacetrack.service",
+			Controller: &pb.Controller{Id: ptr("spacetrack")},
+			Configurable: &pb.ConfigurableComponent{SupportedDeviceClasses: []*pb.DeviceClassOption{
+				{Class: "orbits", Label: "Orbit Tracker"},
+			}},
+		},
+		"node": {
+			Id:           "node",
+			Controller:   &pb.Controller{Id: ptr("hydris")},
+			Configurable: &pb.ConfigurableComponent{Schema: nodeSchema},
+		},
+		"track": {Id: "track", Controller: &pb.Controller{Id: ptr("ais")}},
+	})
+}
+
+func TestListConfigurables_Aggregates(t *testing.T) {
+	got := configurablesTestWorld(t).ListConfigurables()
+	if len(got) != 3 {
+		t.Fatalf("got %d configurables, want 3: %+v", len(got), got)
+	}
+
+	ais := got[0]
+	if ais.Controller != "ais" || ais.Class != "stream" || ais.Label != "AIS Stream" || ais.Service != "ais.service" {
+		t.Errorf("ais configurable %+v", ais)
+	}
+	if ais.Schema["type"] != "object" || ais.Schema["properties"] == nil {
+		t.Errorf("ais schema %v", ais.Schema)
+	}
+	if !slices.Equal(ais.Entities, []string{"ais.stream.a", "ais.stream.b"}) {
+		t.Errorf("ais entities %v, want both streams", ais.Entities)
+	}
+
+	node := got[1]
+	if node.Controller != "hydris" || node.Class != "" || node.Schema == nil || !slices.Equal(node.Entities, []string{"node"}) {
+		t.Errorf("node configurable %+v", node)
+	}
+
+	orbits := got[2]
+	if orbits.Controller != "spacetrack" || orbits.Class != "orbits" || orbits.Service != "spacetrack.service" || orbits.Schema != nil || orbits.Entities != nil {
+		t.Errorf("orbits configurable %+v, want the class without a schema yet", orbits)
+	}
+}
+
+func TestHandleConfigurables(t *testing.T) {
+	w := configurablesTestWorld(t)
+	rec := httptest.NewRecorder()
+	w.handleConfigurables(rec, httptest.NewRequest("GET", "/configurables", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var got []Configurable
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Class != "stream" || got[0].Schema["type"] != "object" {
+		t.Errorf("got %+v", got)
+	}
+}
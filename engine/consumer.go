@@ -2,21 +2,72 @@ package engine
 
 import (
 	"context"
+	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/projectqai/hydris/pkg/metrics"
 	pb "github.com/projectqai/proto/go"
 )
 
+// Consumer queues entity changes for one WatchEntities stream and sends
+// them from SenderLoop. Changes to an entity are coalesced: only the latest
+// is pending, and what is sent is decided against the head when it is sent.
+// Per entity this is a small state machine:
+//
+//   - An Updated is sent only while the entity exists. An Updated for an
+//     entity that is gone when it is sent is dropped, so nothing for an
+//     entity follows its Expired unless the entity is pushed again.
+//   - A pending Expired is never lost to coalescing. An Updated queued on
+//     top of it, by a late transformer result or a touch, keeps the
+//     expired snapshot; if the entity is still gone when the change is
+//     sent, the Expired goes out instead. If the entity was pushed again
+//     meanwhile, the Updated is sent and the snapshot dropped.
+//   - Sending Expired or Unobserved ends the entity's observation;
+//     sending Updated starts or continues it.
 type Consumer struct {
+	id      uint64
 	world   *WorldServer
 	limiter *pb.WatchBehavior
 	filter  *pb.EntityFilter
 
+	// required are components every entity matching filter has; changes
+	// to entities without them are skipped before the full filter runs.
+	// Nil when the filter can't be reduced to a component list.
+	required []uint32
+
+	// extra holds the header filters; lifetime terms are evaluated
+	// against the time each event is sent
+	extra *headerFilter
+
+	// changes are the change types sent to the client, nil for all. Other
+	// changes are still queued and coalesced, so an update followed by an
+	// expiry delivers the expiry, and dropped only when sent.
+	changes changeFilter
+
+	// componentFilter, if set, restricts updates to those that added or
+	// removed one of its components.
+	componentFilter componentSet
+
 	mu               sync.Mutex
 	dirty            [4]map[string]pb.EntityChange // [priority]map[entityID]EntityChange
 	expiredSnapshots map[string]*pb.Entity         // last known entity for expired IDs
-	observed         map[string]struct{}           // entity IDs sent to this client
+	observed         map[string]struct{}           // entity IDs sent to this client; also read by markDirty
+	traces           map[string]string             // trace id of the request that last dirtied an entity
+	components       map[string]componentSet       // components added or removed since an entity was last sent
+	keepalives       map[string]struct{}           // entity IDs requeued by the keepalive, sent regardless of componentFilter
+	popped           string                        // entity ID the sender is handling, see prefiltered
+
+	// filterEvals, filterMatches and filterNanos accumulate the cost of
+	// matches, for Stats.
+	filterEvals   atomic.Uint64
+	filterMatches atomic.Uint64
+	filterNanos   atomic.Int64
+
+	// sendStarted is when the send in progress started, in Unix
+	// nanoseconds, or 0; read by the send watchdog
+	sendStarted atomic.Int64
 
 	signal      chan struct{}
 	cancel      context.CancelFunc // cancels SenderLoop's ctx; set by WatchEntities
@@ -24,12 +75,17 @@ type Consumer struct {
 	keepalive   *time.Ticker
 }
 
+// consumerSeq numbers consumers for metrics.
+var consumerSeq atomic.Uint64
+
 func NewConsumer(world *WorldServer, limiter *pb.WatchBehavior, filter *pb.EntityFilter) *Consumer {
 	c := &Consumer{
-		world:   world,
-		limiter: limiter,
-		filter:  filter,
-		signal:  make(chan struct{}, 1),
+		id:       consumerSeq.Add(1),
+		world:    world,
+		limiter:  limiter,
+		filter:   filter,
+		required: requiredComponents(filter),
+		signal:   make(chan struct{}, 1),
 	}
 
 	for i := range c.dirty {
@@ -37,6 +93,9 @@ func NewConsumer(world *WorldServer, limiter *pb.WatchBehavior, filter *pb.Entit
 	}
 	c.expiredSnapshots = make(map[string]*pb.Entity)
 	c.observed = make(map[string]struct{})
+	c.traces = make(map[string]string)
+	c.components = make(map[string]componentSet)
+	c.keepalives = make(map[string]struct{})
 
 	if limiter != nil && limiter.MaxRateHz != nil && *limiter.MaxRateHz > 0 {
 		interval := time.Duration(float64(time.Second) / float64(*limiter.MaxRateHz))
@@ -54,6 +113,28 @@ func NewConsumer(world *WorldServer, limiter *pb.WatchBehavior, filter *pb.Entit
 	return c
 }
 
+// matches reports whether entity passes the consumer's entity and header
+// filters, and accounts the time it took to the consumer's filter stats.
+func (c *Consumer) matches(entity *pb.Entity) bool {
+	start := time.Now()
+	ok := (c.filter == nil || c.world.matchesEntityFilter(entity, c.filter)) && c.extra.matches(entity, start)
+	c.filterNanos.Add(int64(time.Since(start)))
+	c.filterEvals.Add(1)
+	if ok {
+		c.filterMatches.Add(1)
+	}
+	return ok
+}
+
+// filterStats returns the cumulative cost of the consumer's filter.
+func (c *Consumer) filterStats() metrics.FilterStats {
+	return metrics.FilterStats{
+		Evaluations: c.filterEvals.Load(),
+		Matches:     c.filterMatches.Load(),
+		Duration:    time.Duration(c.filterNanos.Load()),
+	}
+}
+
 func (c *Consumer) minPriority() pb.Priority {
 	if c.limiter != nil && c.limiter.MinPriority != nil {
 		return *c.limiter.MinPriority
@@ -62,21 +143,57 @@ func (c *Consumer) minPriority() pb.Priority {
 }
 
 func (c *Consumer) markDirty(entityID string, priority pb.Priority, change pb.EntityChange, entity *pb.Entity) {
+	c.markDirtyTraced("", entityID, priority, change, entity, nil)
+}
+
+func (c *Consumer) markDirtyTraced(traceID, entityID string, priority pb.Priority, change pb.EntityChange, entity *pb.Entity, components []pb.EntityComponent) {
 	if priority < c.minPriority() {
 		return
 	}
 
 	c.mu.Lock()
 
+	if priority != pb.Priority_PriorityFlash && c.prefiltered(entityID, entity) {
+		c.mu.Unlock()
+		return
+	}
+
+	if traceID != "" {
+		c.traces[entityID] = traceID
+	} else {
+		delete(c.traces, entityID)
+	}
+
+	// Components changed by coalesced updates add up until the entity is
+	// sent.
+	if len(components) > 0 {
+		set := c.components[entityID]
+		if set == nil {
+			set = make(componentSet, len(components))
+			c.components[entityID] = set
+		}
+		for _, comp := range components {
+			set[comp] = struct{}{}
+		}
+	}
+
 	// just in case priority has changed, reseat it
+	expiring := false
 	for p := range c.dirty {
+		if prev, ok := c.dirty[p][entityID]; ok && prev == pb.EntityChange_EntityChangeExpired {
+			expiring = true
+		}
 		delete(c.dirty[p], entityID)
 	}
 	c.dirty[priority][entityID] = change
 
-	if change == pb.EntityChange_EntityChangeExpired && entity != nil {
+	switch {
+	case change == pb.EntityChange_EntityChangeExpired && entity != nil:
 		c.expiredSnapshots[entityID] = entity
-	} else {
+	case expiring:
+		// Keep the snapshot of the expiry this change replaces, see
+		// Consumer.
+	default:
 		delete(c.expiredSnapshots, entityID)
 	}
 
@@ -88,11 +205,49 @@ func (c *Consumer) markDirty(entityID string, priority pb.Priority, change pb.En
 	}
 }
 
+// prefiltered reports whether a change to entity can be dropped without
+// running the full filter: the entity lacks a component the filter
+// requires and was never sent, so there is nothing to unobserve either.
+// Flash changes bypass the filter and are not prefiltered, and neither is
+// the entity the sender has popped: it may be about to send it, and only
+// marks it observed once it has. The caller must hold c.mu.
+func (c *Consumer) prefiltered(entityID string, entity *pb.Entity) bool {
+	if c.required == nil || entity == nil || entityID == c.popped {
+		return false
+	}
+	if _, ok := c.observed[entityID]; ok {
+		return false
+	}
+	return !matchesComponentList(entity, c.required)
+}
+
+// touch queues entityID as an update at the lowest priority the consumer
+// accepts, unless it is already pending.
+func (c *Consumer) touch(entityID string) {
+	priority := c.minPriority()
+
+	c.mu.Lock()
+	for p := range c.dirty {
+		if _, ok := c.dirty[p][entityID]; ok {
+			c.mu.Unlock()
+			return
+		}
+	}
+	c.dirty[priority][entityID] = pb.EntityChange_EntityChangeUpdated
+	c.mu.Unlock()
+
+	select {
+	case c.signal <- struct{}{}:
+	default:
+	}
+}
+
 func (c *Consumer) popNext() (entityID string, change pb.EntityChange, priority pb.Priority, ok bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	minPri := c.minPriority()
+	c.popped = ""
 
 	// Drain in priority order: Flash(3) -> Immediate(2) -> Routine(1) -> Unspecified(0)
 	for p := pb.Priority_PriorityFlash; p >= pb.Priority_PriorityUnspecified; p-- {
@@ -101,13 +256,74 @@ func (c *Consumer) popNext() (entityID string, change pb.EntityChange, priority
 		}
 		for id, ch := range c.dirty[p] {
 			delete(c.dirty[p], id)
+			c.popped = id
 			return id, ch, p, true
 		}
 	}
 	return "", 0, 0, false
 }
 
+// takeTrace returns and forgets the trace id of the request that last
+// dirtied entityID, or "" if it was not changed by a traced request.
+func (c *Consumer) takeTrace(entityID string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := c.traces[entityID]
+	delete(c.traces, entityID)
+	return id
+}
+
+// takeComponents returns and forgets the components added to or removed
+// from entityID since it was last sent, sorted.
+func (c *Consumer) takeComponents(entityID string) []pb.EntityComponent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	components := c.components[entityID].sorted()
+	delete(c.components, entityID)
+	return components
+}
+
+// takeKeepalive reports and forgets whether entityID was requeued by the
+// keepalive since it was last sent.
+func (c *Consumer) takeKeepalive(entityID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.keepalives[entityID]
+	delete(c.keepalives, entityID)
+	return ok
+}
+
+// watchSend runs send, marking the consumer as sending for the watchdog
+// meanwhile.
+func (c *Consumer) watchSend(send func() error) error {
+	c.sendStarted.Store(time.Now().UnixNano())
+	defer c.sendStarted.Store(0)
+	return send()
+}
+
+// sendingSince returns when the send in progress started, or the zero
+// time if the consumer is not sending.
+func (c *Consumer) sendingSince() time.Time {
+	if ns := c.sendStarted.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
+
+// logSend logs a change sent to the client with the trace id of the
+// request that caused it.
+func (c *Consumer) logSend(ctx context.Context, traceID, entityID string, change pb.EntityChange) {
+	if traceID == "" {
+		return
+	}
+	slog.DebugContext(WithTraceID(ctx, traceID), "consumer: sent change", "consumer", c.id, "entity", entityID, "change", change)
+}
+
 func (c *Consumer) SenderLoop(ctx context.Context, send func(*pb.EntityChangeEvent) error) error {
+	unwatched := send
+	send = func(event *pb.EntityChangeEvent) error {
+		return c.watchSend(func() error { return unwatched(event) })
+	}
 	if c.keepalive != nil {
 		defer c.keepalive.Stop()
 	}
@@ -139,24 +355,41 @@ func (c *Consumer) SenderLoop(ctx context.Context, send func(*pb.EntityChangeEve
 			}
 		}
 
+		traceID := c.takeTrace(entityID)
+		components := c.takeComponents(entityID)
+		// A keepalive resends the entity whatever changed, so it is not
+		// subject to the component filter.
+		unfiltered := c.componentFilter == nil || c.takeKeepalive(entityID)
 		entity := c.world.GetHead(entityID)
 
-		if entity == nil && change == pb.EntityChange_EntityChangeExpired {
-			c.mu.Lock()
-			if snap, ok := c.expiredSnapshots[entityID]; ok {
+		c.mu.Lock()
+		snap, expiring := c.expiredSnapshots[entityID]
+		if entity == nil || change != pb.EntityChange_EntityChangeExpired {
+			delete(c.expiredSnapshots, entityID)
+		}
+		c.mu.Unlock()
+		if entity == nil {
+			switch {
+			case change == pb.EntityChange_EntityChangeExpired && expiring:
 				entity = snap
-				delete(c.expiredSnapshots, entityID)
-			} else {
+			case change == pb.EntityChange_EntityChangeExpired:
 				entity = &pb.Entity{Id: entityID}
+			case expiring:
+				// An update coalesced over an expiry, and the entity is
+				// still gone: the expiry is what the client must see.
+				change, entity = pb.EntityChange_EntityChangeExpired, snap
 			}
-			c.mu.Unlock()
 		}
 
 		if priority == pb.Priority_PriorityFlash {
-			if entity != nil || change == pb.EntityChange_EntityChangeExpired {
+			if change == pb.EntityChange_EntityChangeUpdated && !unfiltered && !c.componentFilter.wantsAny(components) {
+				continue
+			}
+			if (entity != nil || change == pb.EntityChange_EntityChangeExpired) && c.changes.wants(change) {
 				if err := send(&pb.EntityChangeEvent{Entity: entity, T: change}); err != nil {
 					return err
 				}
+				c.logSend(ctx, traceID, entityID, change)
 			}
 			continue
 		}
@@ -165,10 +398,13 @@ func (c *Consumer) SenderLoop(ctx context.Context, send func(*pb.EntityChangeEve
 			continue
 		}
 
-		if entity != nil && c.filter != nil && !c.world.matchesEntityFilter(entity, c.filter) {
+		if entity != nil && !c.matches(entity) {
 			// Entity no longer matches filter — send Unobserved if we previously sent it.
-			if _, wasObserved := c.observed[entityID]; wasObserved {
-				delete(c.observed, entityID)
+			c.mu.Lock()
+			_, wasObserved := c.observed[entityID]
+			delete(c.observed, entityID)
+			c.mu.Unlock()
+			if wasObserved && c.changes.wants(pb.EntityChange_EntityChangeUnobserved) {
 				if err := send(&pb.EntityChangeEvent{Entity: entity, T: pb.EntityChange_EntityChangeUnobserved}); err != nil {
 					return err
 				}
@@ -176,6 +412,18 @@ func (c *Consumer) SenderLoop(ctx context.Context, send func(*pb.EntityChangeEve
 			continue
 		}
 
+		if !c.changes.wants(change) {
+			if change == pb.EntityChange_EntityChangeExpired {
+				c.mu.Lock()
+				delete(c.observed, entityID)
+				c.mu.Unlock()
+			}
+			continue
+		}
+		if change == pb.EntityChange_EntityChangeUpdated && !unfiltered && !c.componentFilter.wantsAny(components) {
+			continue
+		}
+
 		if c.rateLimiter != nil {
 			select {
 			case <-ctx.Done():
@@ -184,15 +432,18 @@ func (c *Consumer) SenderLoop(ctx context.Context, send func(*pb.EntityChangeEve
 			}
 		}
 
+		c.mu.Lock()
 		if change == pb.EntityChange_EntityChangeExpired {
 			delete(c.observed, entityID)
 		} else {
 			c.observed[entityID] = struct{}{}
 		}
+		c.mu.Unlock()
 
 		if err := send(&pb.EntityChangeEvent{Entity: entity, T: change}); err != nil {
 			return err
 		}
+		c.logSend(ctx, traceID, entityID, change)
 	}
 }
 
@@ -205,6 +456,30 @@ func (c *Consumer) requeueAll() {
 			priority = *e.Priority
 		}
 		c.markDirty(id, priority, pb.EntityChange_EntityChangeUpdated, e)
+		if c.componentFilter != nil {
+			c.markKeepalive(id, priority)
+		}
 	}
 	c.world.l.RUnlock()
 }
+
+// markKeepalive exempts entityID, just requeued by the keepalive, from
+// the component filter until it is sent.
+func (c *Consumer) markKeepalive(entityID string, priority pb.Priority) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, queued := c.dirty[priority][entityID]; queued {
+		c.keepalives[entityID] = struct{}{}
+	}
+}
+
+// queueDepth returns the number of changes waiting to be sent.
+func (c *Consumer) queueDepth() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, m := range c.dirty {
+		n += len(m)
+	}
+	return n
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/projectqai/hydris/engine/transform"
+)
+
+// Defaults for the correlation pass.
+const (
+	DefaultCorrelationDistance = 100.0 // meters
+	DefaultCorrelationWindow   = 10 * time.Second
+)
+
+// EnableCorrelation turns on the correlation pass, which groups entities of
+// different controllers that are within maxDistanceM meters and window of
+// each other. Members carry the group id in Track.Tracker, so clients can
+// watch or filter for it. It must be called before the server handles
+// requests.
+func (s *WorldServer) EnableCorrelation(maxDistanceM float64, window time.Duration) {
+	s.correlation = transform.NewCorrelationTransformer(maxDistanceM, window)
+	s.transformers = append(s.transformers, s.correlation)
+}
+
+// CorrelationGroup is a set of entities that probably describe the same
+// object. ID is derived from the members and stays the same as long as the
+// lowest member id does; it is also the Track.Tracker of every member.
+type CorrelationGroup struct {
+	ID       string   `json:"id"`
+	Entities []string `json:"entities"`
+}
+
+// Correlations returns the current correlation groups, or nil when the
+// correlation pass is disabled.
+func (s *WorldServer) Correlations() []CorrelationGroup {
+	if s.correlation == nil {
+		return nil
+	}
+	s.l.RLock()
+	groups := s.correlation.Groups()
+	s.l.RUnlock()
+
+	out := make([]CorrelationGroup, len(groups))
+	for i, g := range groups {
+		out[i] = CorrelationGroup{ID: transform.CorrelationTrackerPrefix + g[0], Entities: g}
+	}
+	return out
+}
+
+// handleCorrelations serves the correlation groups as JSON:
+//
+//	GET /correlations[?entity=<id>]
+//
+// With entity set only the group containing that entity is returned. The
+// request is checked by the authorizer as method "ListCorrelations".
+func (s *WorldServer) handleCorrelations(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorizeHTTP(r, "ListCorrelations"); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if s.correlation == nil {
+		http.Error(w, "correlation is disabled", http.StatusNotFound)
+		return
+	}
+
+	groups := s.Correlations()
+	if id := r.URL.Query().Get("entity"); id != "" {
+		var match []CorrelationGroup
+		for _, g := range groups {
+			if slices.Contains(g.Entities, id) {
+				match = append(match, g)
+			}
+		}
+		groups = match
+	}
+	if groups == nil {
+		groups = []CorrelationGroup{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"groups": groups})
+}
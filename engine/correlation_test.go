@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestCorrelation_Push(t *testing.T) {
+	w := testWorld(nil)
+	w.EnableCorrelation(DefaultCorrelationDistance, DefaultCorrelationWindow)
+
+	_, err := w.Push(context.Background(), peerRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{
+		{Id: "ais.1", Controller: &pb.Controller{Id: ptr("ais")}, Geo: &pb.GeoSpatialComponent{Latitude: 54, Longitude: 10}},
+		{Id: "radar.7", Controller: &pb.Controller{Id: ptr("radar")}, Geo: &pb.GeoSpatialComponent{Latitude: 54.0002, Longitude: 10}},
+		{Id: "adsb.1", Controller: &pb.Controller{Id: ptr("adsb")}, Geo: &pb.GeoSpatialComponent{Latitude: 50, Longitude: 8}},
+		{Id: "adsb.2", Controller: &pb.Controller{Id: ptr("adsb")}, Geo: &pb.GeoSpatialComponent{Latitude: 50.5, Longitude: 8}},
+	}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups := w.Correlations()
+	if len(groups) != 1 || groups[0].ID != "correlation.ais.1" || len(groups[0].Entities) != 2 || groups[0].Entities[1] != "radar.7" {
+		t.Fatalf("got %+v", groups)
+	}
+
+	for id, want := range map[string]string{"ais.1": "correlation.ais.1", "radar.7": "correlation.ais.1", "adsb.1": ""} {
+		if got := w.head[id].entity.Track.GetTracker(); got != want {
+			t.Errorf("%s tracker = %q, want %q", id, got, want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	w.handleCorrelations(rec, httptest.NewRequest("GET", "/correlations?entity=adsb.1", nil))
+	var resp struct{ Groups []CorrelationGroup }
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || len(resp.Groups) != 0 {
+		t.Errorf("status %d, groups %+v; adsb.1 should not be correlated", rec.Code, resp.Groups)
+	}
+}
+
+func TestCorrelation_DisabledByDefault(t *testing.T) {
+	w := testWorld(nil)
+	if w.Correlations() != nil {
+		t.Error("expected no correlations when disabled")
+	}
+	rec := httptest.NewRecorder()
+	w.handleCorrelations(rec, httptest.NewRequest("GET", "/correlations", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status %d, want 404", rec.Code)
+	}
+}
+
+func TestCorrelation_Authorized(t *testing.T) {
+	w := testWorld(nil)
+	w.EnableCorrelation(DefaultCorrelationDistance, DefaultCorrelationWindow)
+	assertHTTPDenied(t, w, http.HandlerFunc(w.handleCorrelations), httptest.NewRequest("GET", "/correlations", nil), "ListCorrelations")
+}
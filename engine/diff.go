@@ -0,0 +1,178 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FieldDiffKind is how a field differs between two versions of an entity.
+type FieldDiffKind string
+
+const (
+	FieldAdded   FieldDiffKind = "added"
+	FieldRemoved FieldDiffKind = "removed"
+	FieldChanged FieldDiffKind = "changed"
+)
+
+// FieldDiff is one field that differs between two versions of an entity.
+// Path is the dotted proto field path, e.g. "geo.latitude"; its first
+// segment is the component. Old and New are the values as JSON friendly
+// Go values, nil when the field is not set on that side.
+type FieldDiff struct {
+	Path string        `json:"path"`
+	Kind FieldDiffKind `json:"kind"`
+	Old  any           `json:"old,omitempty"`
+	New  any           `json:"new,omitempty"`
+}
+
+// DiffEntities returns the fields that differ from before to after, in field
+// declaration order. Nested messages set on both sides are compared field
+// by field; lists and maps are compared, and reported, as a whole. A nil
+// entity has no fields set. The result is empty if both are equal.
+func DiffEntities(before, after *pb.Entity) []FieldDiff {
+	if before == nil {
+		before = &pb.Entity{}
+	}
+	if after == nil {
+		after = &pb.Entity{}
+	}
+	return diffMessages("", before.ProtoReflect(), after.ProtoReflect())
+}
+
+func diffMessages(prefix string, before, after protoreflect.Message) []FieldDiff {
+	var diffs []FieldDiff
+	fields := before.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		path := prefix + string(fd.Name())
+		hasBefore, hasAfter := before.Has(fd), after.Has(fd)
+		switch {
+		case !hasBefore && !hasAfter:
+		case !hasBefore:
+			diffs = append(diffs, FieldDiff{Path: path, Kind: FieldAdded, New: diffValue(fd, after.Get(fd))})
+		case !hasAfter:
+			diffs = append(diffs, FieldDiff{Path: path, Kind: FieldRemoved, Old: diffValue(fd, before.Get(fd))})
+		case fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap():
+			diffs = append(diffs, diffMessages(path+".", before.Get(fd).Message(), after.Get(fd).Message())...)
+		case !before.Get(fd).Equal(after.Get(fd)):
+			diffs = append(diffs, FieldDiff{Path: path, Kind: FieldChanged, Old: diffValue(fd, before.Get(fd)), New: diffValue(fd, after.Get(fd))})
+		}
+	}
+	return diffs
+}
+
+// diffValue converts the value v of field fd for FieldDiff.
+func diffValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	switch {
+	case fd.IsList():
+		l := v.List()
+		out := make([]any, l.Len())
+		for i := range out {
+			out[i] = diffScalar(fd, l.Get(i))
+		}
+		return out
+	case fd.IsMap():
+		out := make(map[string]any, v.Map().Len())
+		v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			out[k.String()] = diffScalar(fd.MapValue(), v)
+			return true
+		})
+		return out
+	}
+	return diffScalar(fd, v)
+}
+
+// diffScalar converts a single, non-repeated value of a field of fd's kind.
+func diffScalar(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		b, err := protojson.Marshal(v.Message().Interface())
+		if err != nil {
+			return v.Message().Interface()
+		}
+		return json.RawMessage(b)
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return int32(v.Enum())
+	}
+	return v.Interface()
+}
+
+// DiffEntity returns what pushing candidate would change on its entity:
+// the diff from the current head to the result of merging candidate into
+// it, as DryRunPush computes it with the merge mode of the request. If the
+// entity does not exist yet, every field is reported as added. Components
+// the caller may not read are left out of both sides.
+func (s *WorldServer) DiffEntity(ctx context.Context, req *connect.Request[pb.Entity]) ([]FieldDiff, error) {
+	return s.diffEntity(ctx, req.Msg, req.Header(), req.Peer().Addr)
+}
+
+func (s *WorldServer) diffEntity(ctx context.Context, candidate *pb.Entity, header http.Header, peerAddr string) ([]FieldDiff, error) {
+	if candidate == nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("candidate entity must be set"))
+	}
+	dryRun := connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{candidate}})
+	maps.Copy(dryRun.Header(), header)
+
+	// Copy the head, as a push may change it while it is compared.
+	var head *pb.Entity
+	s.l.RLock()
+	if es := s.head[candidate.Id]; es != nil {
+		head = proto.Clone(es.entity).(*pb.Entity)
+	}
+	s.l.RUnlock()
+
+	result, err := s.DryRunPush(ctx, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	return DiffEntities(s.redactForPeer(peerAddr, head), s.redactForPeer(peerAddr, result[0])), nil
+}
+
+// handleDiffEntity serves DiffEntity over HTTP. The body is the candidate
+// entity as protojson; the merge mode header applies as for Push:
+//
+//	POST /diff-entity {"id": "base.home", "label": "Home"}
+//
+// The request is checked by the authorizer as method "DiffEntity".
+func (s *WorldServer) handleDiffEntity(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorizeHTTP(r, "DiffEntity"); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	candidate := &pb.Entity{}
+	if err := protojson.Unmarshal(body, candidate); err != nil {
+		http.Error(w, fmt.Sprintf("invalid entity: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	diffs, err := s.diffEntity(r.Context(), candidate, r.Header, r.RemoteAddr)
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	if diffs == nil {
+		diffs = []FieldDiff{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(diffs)
+}
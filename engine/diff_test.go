@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDiffEntities_LabelChange(t *testing.T) {
+	before := &pb.Entity{Id: "e1", Label: ptr("tank"), Geo: &pb.GeoSpatialComponent{Latitude: 1, Longitude: 2}}
+	after := proto.Clone(before).(*pb.Entity)
+	after.Label = ptr("truck")
+
+	diffs := DiffEntities(before, after)
+	if len(diffs) != 1 {
+		t.Fatalf("got %v, want one diff", diffs)
+	}
+	if d := diffs[0]; d.Path != "label" || d.Kind != FieldChanged || d.Old != "tank" || d.New != "truck" {
+		t.Errorf("diff %+v", d)
+	}
+}
+
+func TestDiffEntities_NestedField(t *testing.T) {
+	before := &pb.Entity{Id: "e1", Geo: &pb.GeoSpatialComponent{Latitude: 1, Longitude: 2}}
+	after := &pb.Entity{Id: "e1", Geo: &pb.GeoSpatialComponent{Latitude: 1.5, Longitude: 2}}
+
+	diffs := DiffEntities(before, after)
+	if len(diffs) != 1 || diffs[0].Path != "geo.latitude" || diffs[0].Old != 1.0 || diffs[0].New != 1.5 {
+		t.Errorf("got %+v, want geo.latitude 1 -> 1.5", diffs)
+	}
+}
+
+func TestDiffEntities_ComponentAdded(t *testing.T) {
+	before := &pb.Entity{Id: "e1", Label: ptr("tank")}
+	after := &pb.Entity{Id: "e1", Label: ptr("tank"), Geo: &pb.GeoSpatialComponent{Latitude: 1, Longitude: 2}}
+
+	diffs := DiffEntities(before, after)
+	if len(diffs) != 1 || diffs[0].Path != "geo" || diffs[0].Kind != FieldAdded || diffs[0].Old != nil || diffs[0].New == nil {
+		t.Errorf("got %+v, want geo added", diffs)
+	}
+
+	diffs = DiffEntities(after, before)
+	if len(diffs) != 1 || diffs[0].Path != "geo" || diffs[0].Kind != FieldRemoved {
+		t.Errorf("got %+v, want geo removed", diffs)
+	}
+}
+
+func TestDiffEntities_NoOp(t *testing.T) {
+	e := &pb.Entity{Id: "e1", Label: ptr("tank"), Geo: &pb.GeoSpatialComponent{Latitude: 1, Longitude: 2}}
+	if diffs := DiffEntities(e, proto.Clone(e).(*pb.Entity)); len(diffs) != 0 {
+		t.Errorf("equal entities: got %+v", diffs)
+	}
+	if diffs := DiffEntities(nil, nil); len(diffs) != 0 {
+		t.Errorf("nil entities: got %+v", diffs)
+	}
+}
+
+func TestDiffEntity_AgainstHead(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"e1": {Id: "e1", Label: ptr("tank"), Geo: &pb.GeoSpatialComponent{Latitude: 1, Longitude: 2}},
+	})
+
+	req := connect.NewRequest(&pb.Entity{Id: "e1", Label: ptr("truck")})
+	diffs, err := w.DiffEntity(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var label *FieldDiff
+	for i, d := range diffs {
+		switch {
+		case d.Path == "label":
+			label = &diffs[i]
+		case d.Path == "geo" || strings.HasPrefix(d.Path, "geo."):
+			t.Errorf("geo is not part of the candidate but diffs: %+v", d)
+		}
+	}
+	if label == nil || label.Old != "tank" || label.New != "truck" {
+		t.Errorf("got %+v, want label tank -> truck", diffs)
+	}
+	if got := w.GetHead("e1").GetLabel(); got != "tank" {
+		t.Errorf("head label %q changed by diff", got)
+	}
+}
@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// DryRunHeader is the request header that makes Push validate a request
+// without applying it. Authorization, validation and the lease checks run
+// as for a real push and their errors are returned; on success the world
+// is left untouched and the response Debug field holds the merge preview
+// of DryRunPush as a protojson ListEntitiesResponse. A frozen world refuses
+// a dry run the same way it refuses the push.
+const DryRunHeader = "Hydris-Dry-Run"
+
+// isDryRun reports whether the request asks for a dry run.
+func isDryRun(h http.Header) bool {
+	dryRun, err := strconv.ParseBool(h.Get(DryRunHeader))
+	return err == nil && dryRun
+}
+
+// dryRunPush is Push for a request with DryRunHeader set.
+func (s *WorldServer) dryRunPush(ctx context.Context, req *connect.Request[pb.EntityChangeRequest]) (*connect.Response[pb.EntityChangeResponse], error) {
+	if s.frozen.Load() {
+		return connect.NewResponse(&pb.EntityChangeResponse{Debug: s.frozenMessage()}), nil
+	}
+	merged, err := s.DryRunPush(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	preview, err := protojson.Marshal(&pb.ListEntitiesResponse{Entities: merged})
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	return connect.NewResponse(&pb.EntityChangeResponse{Accepted: true, Debug: string(preview)}), nil
+}
+
+// DryRunPush validates a push request and returns the entities it would
+// produce, one per change followed by one per replacement, without
+// modifying the world. Changes are merged into head with the merge mode of
+// the request, and later changes of the batch see earlier ones. Fields
+// computed by transformers after the merge are not included. A frozen world
+// fails with CodeFailedPrecondition.
+func (s *WorldServer) DryRunPush(ctx context.Context, req *connect.Request[pb.EntityChangeRequest]) ([]*pb.Entity, error) {
+	mergeMode, err := mergeModeFromHeader(req.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	// Validation may fill in defaults, so work on copies.
+	changes := make([]*pb.Entity, len(req.Msg.Changes))
+	for i, e := range req.Msg.Changes {
+		changes[i] = proto.Clone(e).(*pb.Entity)
+	}
+	replacements := make([]*pb.Entity, len(req.Msg.Replacements))
+	for i, e := range req.Msg.Replacements {
+		replacements[i] = proto.Clone(e).(*pb.Entity)
+	}
+
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	if s.frozen.Load() {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New(s.frozenMessage()))
+	}
+	for _, e := range slices.Concat(changes, replacements) {
+		if err := s.validateIncoming(e); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.validateConfigSchemas(req.Msg); err != nil {
+		return nil, err
+	}
+	return s.stagePush(changes, replacements, mergeMode)
+}
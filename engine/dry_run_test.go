@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"connectrpc.com/connect"
+)
+
+func dryRunRequest(msg *pb.EntityChangeRequest) *connect.Request[pb.EntityChangeRequest] {
+	req := peerRequest(msg)
+	req.Header().Set(DryRunHeader, "true")
+	return req
+}
+
+func TestDryRunPush_ReturnsMergeWithoutApplying(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"e1": {Id: "e1", Label: ptr("tank"), Geo: &pb.GeoSpatialComponent{Latitude: 1, Longitude: 2}},
+	})
+	c := NewConsumer(w, nil, nil)
+	w.bus.Register(c)
+
+	req := dryRunRequest(&pb.EntityChangeRequest{
+		Changes: []*pb.Entity{
+			{Id: "e1", Label: ptr("truck")},
+			{Id: "e2", Label: ptr("new")},
+		},
+	})
+	merged, err := w.DryRunPush(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 2 {
+		t.Fatalf("got %d entities, want 2", len(merged))
+	}
+	if merged[0].GetLabel() != "truck" || merged[0].Geo.GetLatitude() != 1 {
+		t.Errorf("merge preview should combine the change with head, got %v", merged[0])
+	}
+	if merged[1].GetLabel() != "new" || merged[1].Lifetime == nil {
+		t.Errorf("new entity preview %v", merged[1])
+	}
+
+	resp, err := w.Push(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Msg.Accepted {
+		t.Error("dry run should report the request as accepted")
+	}
+	var preview pb.ListEntitiesResponse
+	if err := protojson.Unmarshal([]byte(resp.Msg.Debug), &preview); err != nil {
+		t.Fatalf("debug is not a preview: %v", err)
+	}
+	if len(preview.Entities) != 2 || preview.Entities[0].GetLabel() != "truck" || preview.Entities[0].Geo.GetLatitude() != 1 {
+		t.Errorf("preview %v", preview.Entities)
+	}
+	if got := w.GetHead("e1").GetLabel(); got != "tank" {
+		t.Errorf("head label %q changed by dry run", got)
+	}
+	if w.GetHead("e2") != nil {
+		t.Error("dry run created e2")
+	}
+	if _, _, _, ok := c.popNext(); ok {
+		t.Error("dry run notified consumers")
+	}
+}
+
+func TestDryRunPush_InvalidEntity(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"e1": {Id: "e1", Label: ptr("tank")},
+	})
+
+	req := dryRunRequest(&pb.EntityChangeRequest{
+		Changes: []*pb.Entity{
+			{Id: "e1", Label: ptr("truck")},
+			{Id: "e2", Geo: &pb.GeoSpatialComponent{Latitude: 91}},
+		},
+	})
+	if _, err := w.DryRunPush(context.Background(), req); connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("got %v, want invalid argument", err)
+	}
+	if _, err := w.Push(context.Background(), req); connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("got %v, want invalid argument", err)
+	}
+	if got := w.GetHead("e1").GetLabel(); got != "tank" {
+		t.Errorf("head label %q changed by dry run", got)
+	}
+}
+
+func TestDryRunPush_LeaseConflict(t *testing.T) {
+	w := leasedWorld()
+
+	_, err := w.Push(context.Background(), dryRunRequest(conflictingBatch()))
+	if connect.CodeOf(err) != connect.CodeFailedPrecondition {
+		t.Fatalf("got %v, want failed precondition", err)
+	}
+	if w.GetHead("dev.ttyACM1") != nil {
+		t.Error("dry run applied dev.ttyACM1")
+	}
+}
+
+func TestDryRunPush_Frozen(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	ctx := context.Background()
+	w.SetFrozen(ctx, SetFrozenRequest{Frozen: true, Reason: "restoring backup"})
+
+	req := dryRunRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{{Id: "e1"}}})
+	if _, err := w.DryRunPush(ctx, req); connect.CodeOf(err) != connect.CodeFailedPrecondition {
+		t.Errorf("got %v, want failed precondition", err)
+	}
+	resp, err := w.Push(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Msg.Accepted || resp.Msg.Debug != "world is frozen: restoring backup" {
+		t.Errorf("dry run against a frozen world: %v", resp.Msg)
+	}
+}
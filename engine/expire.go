@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"context"
+	"net"
+
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydris/pkg/projection"
+	pb "github.com/projectqai/proto/go"
+)
+
+// ExpireAuthorizer decides whether a client may expire an entity. peerIP is
+// the address of the calling connection without the port and components the
+// entity field numbers currently set on the target. A non-nil error rejects
+// the request.
+type ExpireAuthorizer func(peerIP string, entity *pb.Entity, components []uint32) error
+
+// SetExpireAuthorizer installs an authorizer that is consulted by
+// ExpireEntity before an entity is marked for removal. Being allowed to push
+// does not imply being allowed to expire. It must be set before the server
+// starts serving.
+func (s *WorldServer) SetExpireAuthorizer(a ExpireAuthorizer) {
+	s.expireAuth = a
+}
+
+// authorizeExpire checks whether peerAddr may expire entity. A denial is
+// recorded in the access log; the RPC itself was already recorded as allowed.
+func (s *WorldServer) authorizeExpire(ctx context.Context, peerAddr string, entity *pb.Entity) error {
+	if s.expireAuth == nil {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(peerAddr)
+	if err != nil {
+		host = peerAddr
+	}
+
+	if err := s.expireAuth(host, entity, projection.Components(entity)); err != nil {
+		err = connect.NewError(connect.CodePermissionDenied, err)
+		s.accessLog.record(ctx, AuthInput{
+			Method:   "ExpireEntity",
+			PeerAddr: peerAddr,
+			Identity: IdentityFromContext(ctx),
+		}, []string{entity.Id}, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+)
+
+// denyGeoExpire lets everyone write but refuses to expire entities with a
+// geo component.
+func denyGeoExpire(peerIP string, entity *pb.Entity, components []uint32) error {
+	if slices.Contains(components, uint32(pb.EntityComponent_EntityComponentGeo)) {
+		return errors.New("geo entities may not be expired")
+	}
+	return nil
+}
+
+func TestExpireEntity_AuthorizerDeniesGeo(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"track": {Id: "track", Geo: &pb.GeoSpatialComponent{Latitude: 52, Longitude: 13}},
+		"note":  {Id: "note", Label: ptr("note")},
+	})
+	w.SetExpireAuthorizer(denyGeoExpire)
+	ctx := context.Background()
+
+	// Writing the geo entity is still allowed.
+	if _, err := w.Push(ctx, peerRequest(&pb.EntityChangeRequest{
+		Changes: []*pb.Entity{{Id: "track", Label: ptr("updated")}},
+	})); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	_, err := w.ExpireEntity(ctx, peerRequest(&pb.ExpireEntityRequest{Id: "track"}))
+	if connect.CodeOf(err) != connect.CodePermissionDenied {
+		t.Fatalf("expire geo entity: got %v, want permission denied", err)
+	}
+	if e := w.GetHead("track"); e.GetLifetime().GetUntil() != nil {
+		t.Error("denied expire must not set Lifetime.Until")
+	}
+	w.l.RLock()
+	hard := w.head["track"].hardExpire
+	w.l.RUnlock()
+	if hard {
+		t.Error("denied expire must not mark the entity for removal")
+	}
+
+	if _, err := w.ExpireEntity(ctx, peerRequest(&pb.ExpireEntityRequest{Id: "note"})); err != nil {
+		t.Fatalf("expire non-geo entity: %v", err)
+	}
+	if e := w.GetHead("note"); e.GetLifetime().GetUntil() == nil {
+		t.Error("allowed expire should set Lifetime.Until")
+	}
+}
+
+func TestExpireEntity_AuthorizerSeesComponents(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"track": {
+			Id:    "track",
+			Geo:   &pb.GeoSpatialComponent{Latitude: 52, Longitude: 13},
+			Power: &pb.PowerComponent{},
+		},
+	})
+	var got []uint32
+	w.SetExpireAuthorizer(func(peerIP string, entity *pb.Entity, components []uint32) error {
+		got = components
+		return nil
+	})
+
+	if _, err := w.ExpireEntity(context.Background(), peerRequest(&pb.ExpireEntityRequest{Id: "track"})); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []pb.EntityComponent{pb.EntityComponent_EntityComponentGeo, pb.EntityComponent_EntityComponentPower} {
+		if !slices.Contains(got, uint32(c)) {
+			t.Errorf("components %v missing %v", got, c)
+		}
+	}
+}
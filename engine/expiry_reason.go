@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// ExpiryReason tells why an entity was removed from the world.
+type ExpiryReason string
+
+const (
+	// ExpiryTimeout: the entity's lifetime ran out and the GC removed it.
+	ExpiryTimeout ExpiryReason = "timeout"
+	// ExpiryExplicit: a client called ExpireEntity, or the world was reset.
+	ExpiryExplicit ExpiryReason = "explicit"
+	// ExpiryPruned: SyncController dropped it from its controller's set.
+	ExpiryPruned ExpiryReason = "pruned"
+)
+
+// expiryReasonRetention is how long the reason for a removal is kept. It
+// only has to outlive the Expired event reaching the watchers.
+const expiryReasonRetention = 5 * time.Minute
+
+type expiryRecord struct {
+	reason ExpiryReason
+	at     time.Time
+}
+
+// recordExpiry remembers why id was removed. Caller must hold s.l.
+func (s *WorldServer) recordExpiry(id string, reason ExpiryReason, now time.Time) {
+	if s.expiryReasons == nil {
+		s.expiryReasons = make(map[string]expiryRecord)
+	}
+	s.expiryReasons[id] = expiryRecord{reason: reason, at: now}
+}
+
+// pruneExpiryReasons forgets reasons older than expiryReasonRetention.
+// Caller must hold s.l.
+func (s *WorldServer) pruneExpiryReasons(now time.Time) {
+	for id, r := range s.expiryReasons {
+		if now.Sub(r.at) > expiryReasonRetention {
+			delete(s.expiryReasons, id)
+		}
+	}
+}
+
+// ExpiryReason returns why the entity with the given id was last removed
+// and when. ok is false if the entity was not removed within the
+// retention window or has been pushed again since.
+//
+// EntityChangeEvent has no field for the reason, so a watcher that needs
+// it, for example to tell a deletion from a track going stale, looks it
+// up after receiving EntityChangeExpired.
+func (s *WorldServer) ExpiryReason(id string) (reason ExpiryReason, at time.Time, ok bool) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	r, ok := s.expiryReasons[id]
+	return r.reason, r.at, ok
+}
+
+// expiryReasonResponse is the body of GET /expiry-reason.
+type expiryReasonResponse struct {
+	ID     string       `json:"id"`
+	Reason ExpiryReason `json:"reason"`
+	At     time.Time    `json:"at"`
+}
+
+// handleExpiryReason serves ExpiryReason over HTTP:
+//
+//	GET /expiry-reason?id=adsb.3c6444
+//
+// It answers 404 if no reason is known. The request is checked by the
+// authorizer as method "GetExpiryReason".
+func (s *WorldServer) handleExpiryReason(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorizeHTTP(r, "GetExpiryReason"); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id must be set", http.StatusBadRequest)
+		return
+	}
+	reason, at, ok := s.ExpiryReason(id)
+	if !ok {
+		http.Error(w, "no expiry recorded for "+id, http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(expiryReasonResponse{ID: id, Reason: reason, At: at})
+}
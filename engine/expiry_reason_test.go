@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func assertExpiryReason(t *testing.T, w *WorldServer, id string, want ExpiryReason) {
+	t.Helper()
+	if w.GetHead(id) != nil {
+		t.Fatalf("%s still in head", id)
+	}
+	got, _, ok := w.ExpiryReason(id)
+	if !ok {
+		t.Fatalf("no expiry reason for %s", id)
+	}
+	if got != want {
+		t.Errorf("%s expired with reason %q, want %q", id, got, want)
+	}
+}
+
+func TestExpiryReason_Timeout(t *testing.T) {
+	past := timestamppb.New(time.Now().Add(-time.Second))
+	w := testWorld(map[string]*pb.Entity{
+		"adsb.1": {Id: "adsb.1", Geo: &pb.GeoSpatialComponent{}, Lifetime: &pb.Lifetime{Until: past}},
+	})
+	// Bypasses Push, so it has no component lifetimes (GC phase 2).
+	w.head["derived.1"] = &entityState{entity: &pb.Entity{Id: "derived.1", Lifetime: &pb.Lifetime{Until: past}}}
+	w.headView["derived.1"] = w.head["derived.1"].entity
+
+	w.GC()
+
+	assertExpiryReason(t, w, "adsb.1", ExpiryTimeout)
+	assertExpiryReason(t, w, "derived.1", ExpiryTimeout)
+}
+
+func TestExpiryReason_Explicit(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"adsb.1": {Id: "adsb.1", Geo: &pb.GeoSpatialComponent{}},
+	})
+
+	if _, err := w.ExpireEntity(context.Background(), peerRequest(&pb.ExpireEntityRequest{Id: "adsb.1"})); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := w.ExpiryReason("adsb.1"); ok {
+		t.Error("reason recorded before the GC removed the entity")
+	}
+	w.GC()
+
+	assertExpiryReason(t, w, "adsb.1", ExpiryExplicit)
+}
+
+func TestExpiryReason_Pruned(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"adsb.1": {Id: "adsb.1", Controller: &pb.Controller{Id: ptr("adsb")}},
+		"adsb.2": {Id: "adsb.2", Controller: &pb.Controller{Id: ptr("adsb")}},
+	})
+
+	if _, err := w.SyncController(context.Background(), SyncControllerRequest{
+		Controller: "adsb",
+		Entities:   []*pb.Entity{{Id: "adsb.1"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	w.GC()
+
+	assertExpiryReason(t, w, "adsb.2", ExpiryPruned)
+	if _, _, ok := w.ExpiryReason("adsb.1"); ok {
+		t.Error("kept entity has an expiry reason")
+	}
+}
+
+func TestExpiryReason_ClearedOnRepush(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"adsb.1": {Id: "adsb.1", Geo: &pb.GeoSpatialComponent{}},
+	})
+	if _, err := w.ExpireEntity(context.Background(), peerRequest(&pb.ExpireEntityRequest{Id: "adsb.1"})); err != nil {
+		t.Fatal(err)
+	}
+	w.GC()
+
+	w.l.Lock()
+	w.initEntity(&pb.Entity{Id: "adsb.1", Geo: &pb.GeoSpatialComponent{}})
+	w.l.Unlock()
+	if reason, _, ok := w.ExpiryReason("adsb.1"); ok {
+		t.Errorf("pushed entity still has expiry reason %q", reason)
+	}
+
+	// Old reasons are dropped by the GC.
+	w.l.Lock()
+	w.recordExpiry("adsb.old", ExpiryTimeout, time.Now().Add(-2*expiryReasonRetention))
+	w.l.Unlock()
+	w.GC()
+	if _, _, ok := w.ExpiryReason("adsb.old"); ok {
+		t.Error("reason kept past the retention")
+	}
+}
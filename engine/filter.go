@@ -100,6 +100,19 @@ func matchesComponentList(entity *pb.Entity, components []uint32) bool {
 	return true
 }
 
+// requiredComponents returns the components an entity must have to match
+// filter, or nil if that can't be told from the component list alone: for
+// Or and Not, and for Geo, which is left to the full filter.
+func requiredComponents(filter *pb.EntityFilter) []uint32 {
+	if filter == nil || len(filter.Or) > 0 || filter.Not != nil || filter.Geo != nil {
+		return nil
+	}
+	if len(filter.Component) == 0 {
+		return nil
+	}
+	return filter.Component
+}
+
 // matchesAnyServiceUUID returns true if the entity's service UUIDs contain
 // any of the filter's service UUIDs.
 func matchesAnyServiceUUID(entityUUIDs, filterUUIDs []string) bool {
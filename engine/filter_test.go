@@ -3,6 +3,7 @@ package engine
 import (
 	"testing"
 
+	"github.com/projectqai/hydris/goclient"
 	pb "github.com/projectqai/proto/go"
 	"google.golang.org/protobuf/proto"
 )
@@ -663,3 +664,55 @@ func TestMatchesEntityFilter_CombinedFilters(t *testing.T) {
 		t.Error("label mismatch, should return false")
 	}
 }
+
+func TestMatchesEntityFilter_AndInsideOr(t *testing.T) {
+	tank := ptr("tank")
+	plane := ptr("plane")
+	controllerA := &pb.ControllerFilter{Id: ptr("a")}
+	controllerB := &pb.ControllerFilter{Id: ptr("b")}
+
+	// (label=tank AND controller=a) OR (label=plane AND controller=b)
+	filter := &pb.EntityFilter{Or: []*pb.EntityFilter{
+		goclient.And(&pb.EntityFilter{Label: tank}, &pb.EntityFilter{Controller: controllerA}),
+		goclient.And(&pb.EntityFilter{Label: plane}, &pb.EntityFilter{Controller: controllerB}),
+	}}
+
+	w := testWorld(nil)
+	tests := []struct {
+		label, controller string
+		want              bool
+	}{
+		{"tank", "a", true},
+		{"plane", "b", true},
+		{"tank", "b", false},
+		{"plane", "a", false},
+	}
+	for _, tt := range tests {
+		e := &pb.Entity{Id: "e1", Label: ptr(tt.label), Controller: &pb.Controller{Id: ptr(tt.controller)}}
+		if got := w.matchesEntityFilter(e, filter); got != tt.want {
+			t.Errorf("%s/%s: got %v, want %v", tt.label, tt.controller, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesEntityFilter_AndOfOrs(t *testing.T) {
+	// (label=tank OR label=plane) AND (controller=a OR controller=b)
+	filter := goclient.And(
+		&pb.EntityFilter{Or: []*pb.EntityFilter{{Label: ptr("tank")}, {Label: ptr("plane")}}},
+		&pb.EntityFilter{Or: []*pb.EntityFilter{
+			{Controller: &pb.ControllerFilter{Id: ptr("a")}},
+			{Controller: &pb.ControllerFilter{Id: ptr("b")}},
+		}},
+	)
+
+	w := testWorld(nil)
+	if !w.matchesEntityFilter(&pb.Entity{Label: ptr("plane"), Controller: &pb.Controller{Id: ptr("b")}}, filter) {
+		t.Error("plane/b should match")
+	}
+	if w.matchesEntityFilter(&pb.Entity{Label: ptr("ship"), Controller: &pb.Controller{Id: ptr("a")}}, filter) {
+		t.Error("ship/a should not match")
+	}
+	if w.matchesEntityFilter(&pb.Entity{Label: ptr("tank"), Controller: &pb.Controller{Id: ptr("c")}}, filter) {
+		t.Error("tank/c should not match")
+	}
+}
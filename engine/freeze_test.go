@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestSetFrozen_Toggle(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	ctx := context.Background()
+
+	w.SetFrozen(ctx, SetFrozenRequest{Frozen: true, Reason: "restoring backup"})
+	if !w.frozen.Load() {
+		t.Fatal("world not frozen")
+	}
+	if status, _ := w.healthStatus(""); status != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("health %v while frozen", status)
+	}
+
+	w.SetFrozen(ctx, SetFrozenRequest{Frozen: false, Reason: "ignored"})
+	if w.frozen.Load() {
+		t.Fatal("world still frozen")
+	}
+	if got := w.GetStatus(ctx).Reason; got != "" {
+		t.Errorf("reason %q kept after unfreeze", got)
+	}
+}
+
+func TestPush_FrozenReturnsReason(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	ctx := context.Background()
+	w.SetFrozen(ctx, SetFrozenRequest{Frozen: true, Reason: "restoring backup"})
+
+	resp, err := w.Push(ctx, peerRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{{Id: "e1"}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Msg.Accepted {
+		t.Error("push accepted while frozen")
+	}
+	if resp.Msg.Debug != "world is frozen: restoring backup" {
+		t.Errorf("debug %q", resp.Msg.Debug)
+	}
+	if w.GetHead("e1") != nil {
+		t.Error("entity applied while frozen")
+	}
+
+	w.SetFrozen(ctx, SetFrozenRequest{})
+	resp, err = w.Push(ctx, peerRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{{Id: "e1"}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Msg.Accepted || w.GetHead("e1") == nil {
+		t.Error("push not applied after unfreeze")
+	}
+}
+
+func TestGetStatus(t *testing.T) {
+	w := NewWorldServer()
+	ctx := context.Background()
+	if _, err := w.Push(ctx, peerRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{{Id: "e1"}, {Id: "e2"}}})); err != nil {
+		t.Fatal(err)
+	}
+
+	status := w.GetStatus(ctx)
+	if status.Frozen || status.Reason != "" {
+		t.Errorf("fresh world reported frozen: %+v", status)
+	}
+	if status.Entities != 2 {
+		t.Errorf("entities %d, want 2", status.Entities)
+	}
+	if status.Uptime <= 0 {
+		t.Errorf("uptime %v", status.Uptime)
+	}
+
+	w.SetFrozen(ctx, SetFrozenRequest{Frozen: true, Reason: "maintenance"})
+	if status := w.GetStatus(ctx); !status.Frozen || status.Reason != "maintenance" {
+		t.Errorf("frozen status %+v", status)
+	}
+}
@@ -11,29 +11,99 @@ import (
 	goproto "google.golang.org/protobuf/proto"
 )
 
+// DefaultGCInterval is the time between world GC sweeps unless configured.
+const DefaultGCInterval = time.Second
+
+// GCConfig tunes the world GC.
+type GCConfig struct {
+	// Interval is the time between sweeps; DefaultGCInterval when zero.
+	Interval time.Duration
+	// MaxPerSweep caps how many entities one sweep expires or updates,
+	// which bounds how long it holds the world lock. The remaining
+	// entities are handled by the following sweeps. Zero means no cap.
+	MaxPerSweep int
+}
+
+// SetGCConfig applies cfg to the running GC.
+func (s *WorldServer) SetGCConfig(cfg GCConfig) {
+	s.l.Lock()
+	s.gcMaxPerSweep = cfg.MaxPerSweep
+	s.l.Unlock()
+
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = DefaultGCInterval
+	}
+	select {
+	case s.gcInterval <- interval:
+	default:
+	}
+}
+
+// runGC sweeps every GC interval until Shutdown.
+func (s *WorldServer) runGC() {
+	ticker := time.NewTicker(DefaultGCInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case interval := <-s.gcInterval:
+			ticker.Reset(interval)
+		case <-ticker.C:
+			s.GC()
+		}
+	}
+}
+
 func (s *WorldServer) GC() {
 	now := time.Now()
+	defer func() {
+		took := time.Since(now)
+		s.counters.gcRuns.Add(1)
+		s.counters.gcDuration.Add(int64(took))
+		s.counters.gcLastDuration.Store(int64(took))
+	}()
 
 	s.l.Lock()
 	var changed []string
 	var expired []string
 
+	// budget is the number of entities this sweep may still expire or
+	// update; negative means unlimited.
+	budget := -1
+	if s.gcMaxPerSweep > 0 {
+		budget = s.gcMaxPerSweep
+	}
+	take := func() bool {
+		if budget == 0 {
+			return false
+		}
+		budget--
+		return true
+	}
+
 	// Phase 0: Hard-expire entities marked by ExpireEntity.
 	// These are removed unconditionally regardless of component lifetimes.
 	for entityID, es := range s.head {
 		if !es.hardExpire {
 			continue
 		}
+		if !take() {
+			break
+		}
 		entity := es.entity
 		deleteArtifactBlob(entity)
 		s.deleteEntity(entityID)
+		s.recordExpiry(entityID, es.expireReason, now)
 		s.bus.Dirty(entityID, entity, proto.EntityChange_EntityChangeExpired)
 		expired = append(expired, entityID)
 	}
 
 	// Phase 1: Per-component expiry for entities with lifetimes.
+	// Pinned entities are skipped here and in phase 2.
 	for entityID, es := range s.head {
-		if len(es.lifetimes) == 0 {
+		if len(es.lifetimes) == 0 || s.pinned[entityID] {
 			continue
 		}
 
@@ -56,6 +126,9 @@ func (s *WorldServer) GC() {
 		if len(expiringFields) == 0 {
 			continue
 		}
+		if !take() {
+			break
+		}
 
 		allExpiring := len(expiringFields) >= tracked
 		if allExpiring {
@@ -66,6 +139,7 @@ func (s *WorldServer) GC() {
 			entity := es.entity
 			deleteArtifactBlob(entity)
 			s.deleteEntity(entityID)
+			s.recordExpiry(entityID, ExpiryTimeout, now)
 			s.bus.Dirty(entityID, entity, proto.EntityChange_EntityChangeExpired)
 			expired = append(expired, entityID)
 		} else {
@@ -91,18 +165,25 @@ func (s *WorldServer) GC() {
 	// Phase 2: Fallback entity-level expiry for entities without lifetimes
 	// (e.g., transformer-generated entities that bypass Push).
 	for k, es := range s.head {
-		if len(es.lifetimes) > 0 {
+		if len(es.lifetimes) > 0 || s.pinned[k] {
 			continue
 		}
 		e := es.entity
 		if e.Lifetime != nil && e.Lifetime.Until.IsValid() && now.After(e.Lifetime.Until.AsTime()) {
+			if !take() {
+				break
+			}
 			deleteArtifactBlob(e)
 			s.deleteEntity(k)
+			s.recordExpiry(k, ExpiryTimeout, now)
 			s.bus.Dirty(k, e, proto.EntityChange_EntityChangeExpired)
 			expired = append(expired, k)
 		}
 	}
 
+	s.pruneExpiryReasons(now)
+
+	s.counters.expired.Add(uint64(len(expired)))
 	for _, id := range expired {
 		upserted, removed := transform.RunTransformers(s.transformers, s.headView, s.bus, id)
 		s.syncTransformerResults(upserted, removed)
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -155,3 +156,73 @@ func TestGC_NoLifetimeUntil(t *testing.T) {
 		t.Error("entity with lifetime.from but no until should not be removed")
 	}
 }
+
+func TestGC_MaxPerSweepBoundsWork(t *testing.T) {
+	past := timestamppb.New(time.Now().Add(-time.Hour))
+	entities := make(map[string]*pb.Entity)
+	for i := range 25 {
+		id := fmt.Sprintf("e%d", i)
+		entities[id] = &pb.Entity{Id: id, Lifetime: &pb.Lifetime{Until: past}}
+	}
+	entities["alive"] = &pb.Entity{Id: "alive", Lifetime: &pb.Lifetime{Until: timestamppb.New(time.Now().Add(time.Hour))}}
+
+	w := testWorld(entities)
+	w.SetGCConfig(GCConfig{MaxPerSweep: 10})
+
+	for sweep, remaining := range []int{16, 6, 1, 1} {
+		before := w.Stats().EntitiesExpired
+		w.GC()
+		if n := len(w.head); n != remaining {
+			t.Fatalf("after sweep %d: %d entities left, want %d", sweep+1, n, remaining)
+		}
+		if collected := w.Stats().EntitiesExpired - before; collected > 10 {
+			t.Errorf("sweep %d collected %d entities, cap is 10", sweep+1, collected)
+		}
+	}
+	if w.GetHead("alive") == nil {
+		t.Error("alive entity should remain")
+	}
+	if w.Stats().GCLastDuration <= 0 {
+		t.Error("last sweep duration should be recorded")
+	}
+}
+
+func TestGC_MaxPerSweepCountsComponentExpiry(t *testing.T) {
+	past := time.Now().Add(-time.Second)
+	future := time.Now().Add(time.Hour)
+
+	entities := make(map[string]*pb.Entity)
+	for i := range 4 {
+		id := fmt.Sprintf("e%d", i)
+		entities[id] = &pb.Entity{
+			Id:    id,
+			Geo:   &pb.GeoSpatialComponent{Latitude: 48},
+			Track: &pb.TrackComponent{Tracker: ptr("t1")},
+		}
+	}
+	w := testWorld(entities)
+	for _, es := range w.head {
+		es.lifetimes[int32(pb.EntityComponent_EntityComponentGeo)] = componentMeta{fresh: past, until: past}
+		es.lifetimes[int32(pb.EntityComponent_EntityComponentTrack)] = componentMeta{fresh: past, until: future}
+	}
+	w.SetGCConfig(GCConfig{MaxPerSweep: 3})
+
+	withGeo := func() int {
+		n := 0
+		for _, es := range w.head {
+			if es.entity.Geo != nil {
+				n++
+			}
+		}
+		return n
+	}
+
+	w.GC()
+	if n := withGeo(); n != 1 {
+		t.Fatalf("after first sweep %d entities still have Geo, want 1", n)
+	}
+	w.GC()
+	if n := withGeo(); n != 0 {
+		t.Errorf("after second sweep %d entities still have Geo, want 0", n)
+	}
+}
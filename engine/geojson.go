@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/projectqai/hydris/pkg/coords"
+	"github.com/projectqai/hydris/pkg/ellipse"
+	"github.com/projectqai/hydris/pkg/geojson"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// handleGeoJSON serves GET /geojson: every entity with a position or shape
+// as a GeoJSON FeatureCollection. The optional "filter" query parameter
+// holds an EntityFilter in protojson, and the List/Watch filter headers
+// apply as well. With ?format=ndjson, or when the client accepts
+// application/x-ndjson, one feature per line is streamed instead. With
+// ?uncertainty=true, the 95% error ellipse of each position covariance is
+// added as a feature of component "uncertainty". With ?frame=ecef or
+// ?frame=utm, each position is also given in that frame as a property.
+// The request is checked by the authorizer as method "ListEntities".
+func (s *WorldServer) handleGeoJSON(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorizeHTTP(r, "ListEntities"); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	entities, err := s.geoSnapshot(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var uncertainty bool
+	if raw := r.URL.Query().Get("uncertainty"); raw != "" {
+		if uncertainty, err = strconv.ParseBool(raw); err != nil {
+			http.Error(w, "invalid uncertainty: "+raw, http.StatusBadRequest)
+			return
+		}
+	}
+	frame, err := coords.ParseFrame(r.URL.Query().Get("frame"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	features := func(e *pb.Entity) []*geojson.Feature {
+		fs := geojson.FromEntity(e)
+		geojson.AddFrame(fs, e, frame)
+		if uncertainty {
+			if f := geojson.Uncertainty(e, ellipse.Confidence95); f != nil {
+				fs = append(fs, f)
+			}
+		}
+		return fs
+	}
+
+	if r.URL.Query().Get("format") == "ndjson" || strings.Contains(r.Header.Get("Accept"), "application/x-ndjson") {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		flusher, _ := w.(http.Flusher)
+		for _, e := range entities {
+			for _, f := range features(e) {
+				if err := enc.Encode(f); err != nil {
+					return
+				}
+			}
+			if bw.Buffered() >= 32<<10 {
+				if bw.Flush() != nil {
+					return
+				}
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+		}
+		_ = bw.Flush()
+		return
+	}
+
+	var all []*geojson.Feature
+	for _, e := range entities {
+		all = append(all, features(e)...)
+	}
+	w.Header().Set("Content-Type", "application/geo+json")
+	_ = json.NewEncoder(w).Encode(geojson.NewFeatureCollection(all))
+}
+
+// geoSnapshot returns the entities with a position or shape that match the
+// request's "filter" query parameter and filter headers, redacted for the
+// peer and sorted by id.
+func (s *WorldServer) geoSnapshot(r *http.Request) ([]*pb.Entity, error) {
+	var filter *pb.EntityFilter
+	if raw := r.URL.Query().Get("filter"); raw != "" {
+		filter = &pb.EntityFilter{}
+		if err := protojson.Unmarshal([]byte(raw), filter); err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+	}
+	extra, err := headerFilterFromRequest(r.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	s.l.RLock()
+	now := time.Now()
+	var entities []*pb.Entity
+	for _, es := range s.head {
+		e := es.entity
+		if (e.Geo == nil && e.Shape == nil) || !s.matchesEntityFilter(e, filter) || !extra.matches(e, now) {
+			continue
+		}
+		entities = append(entities, s.redactForPeer(r.RemoteAddr, e))
+	}
+	s.l.RUnlock()
+	sortEntities(entities, nil)
+	return entities, nil
+}
@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+func geoJSONTestWorld() *WorldServer {
+	return testWorld(map[string]*pb.Entity{
+		"b-ship": {Id: "b-ship", Label: ptr("ship"), Geo: &pb.GeoSpatialComponent{Latitude: 54, Longitude: 10}},
+		"a-zone": {Id: "a-zone", Label: ptr("zone"), Shape: &pb.GeoShapeComponent{Geometry: &pb.Geometry{Planar: &pb.PlanarGeometry{
+			Plane: &pb.PlanarGeometry_Circle{Circle: &pb.PlanarCircle{Center: &pb.PlanarPoint{Latitude: 54, Longitude: 10}, RadiusM: 500}},
+		}}}},
+		"config": {Id: "config", Label: ptr("ship")},
+	})
+}
+
+func TestHandleGeoJSON_FeatureCollection(t *testing.T) {
+	w := geoJSONTestWorld()
+
+	rec := httptest.NewRecorder()
+	w.handleGeoJSON(rec, httptest.NewRequest("GET", "/geojson", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/geo+json" {
+		t.Fatalf("status %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	var fc struct {
+		Type     string
+		Features []struct {
+			ID       string
+			Geometry struct{ Type string }
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &fc); err != nil {
+		t.Fatal(err)
+	}
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 2 {
+		t.Fatalf("got %+v", fc)
+	}
+	if fc.Features[0].ID != "a-zone" || fc.Features[0].Geometry.Type != "Polygon" ||
+		fc.Features[1].ID != "b-ship" || fc.Features[1].Geometry.Type != "Point" {
+		t.Errorf("features %+v, want zone polygon then ship point", fc.Features)
+	}
+}
+
+func TestHandleGeoJSON_FilterAndNDJSON(t *testing.T) {
+	w := geoJSONTestWorld()
+
+	rec := httptest.NewRecorder()
+	w.handleGeoJSON(rec, httptest.NewRequest("GET", "/geojson?format=ndjson&filter="+url.QueryEscape(`{"label":"ship"}`), nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("status %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var f struct{ Type, ID string }
+		if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		if f.Type != "Feature" {
+			t.Errorf("line %q is not a feature", scanner.Text())
+		}
+		lines = append(lines, f.ID)
+	}
+	if len(lines) != 1 || lines[0] != "b-ship" {
+		t.Errorf("got %v, want only the ship", lines)
+	}
+
+	rec = httptest.NewRecorder()
+	w.handleGeoJSON(rec, httptest.NewRequest("GET", "/geojson?filter=nope", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid filter: status %d, want 400", rec.Code)
+	}
+}
+
+func TestHandleGeoJSON_Authorized(t *testing.T) {
+	w := geoJSONTestWorld()
+	assertHTTPDenied(t, w, http.HandlerFunc(w.handleGeoJSON), httptest.NewRequest("GET", "/geojson", nil), "ListEntities")
+}
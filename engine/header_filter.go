@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"net/http"
+	"time"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+)
+
+// headerFilter holds the selections that EntityFilter cannot express and
+// that clients pass as request headers instead (see LifetimeFilterHeader,
+// RangeFilterHeader and LabelPrefixHeader). A nil headerFilter matches
+// everything.
+type headerFilter struct {
+	lifetime *LifetimeFilter
+	ranges   []RangeFilter
+	label    *labelFilter
+}
+
+// headerFilterFromRequest parses the filter headers of a request. It returns
+// nil if none are set.
+func headerFilterFromRequest(h http.Header) (*headerFilter, error) {
+	lifetime, err := ParseLifetimeFilter(h.Get(LifetimeFilterHeader))
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	ranges, err := ParseRangeFilters(h.Get(RangeFilterHeader))
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	var label *labelFilter
+	prefix, pattern := h.Get(LabelPrefixHeader), h.Get(LabelRegexHeader)
+	if prefix != "" || pattern != "" {
+		label = &labelFilter{prefix: prefix}
+		if pattern != "" {
+			if label.regex, err = compileLabelRegex(pattern); err != nil {
+				return nil, connect.NewError(connect.CodeInvalidArgument, err)
+			}
+		}
+	}
+
+	if lifetime == nil && len(ranges) == 0 && label == nil {
+		return nil, nil
+	}
+	return &headerFilter{lifetime: lifetime, ranges: ranges, label: label}, nil
+}
+
+// matches reports whether entity passes every header filter at time now.
+func (f *headerFilter) matches(entity *pb.Entity, now time.Time) bool {
+	if f == nil {
+		return true
+	}
+	if !f.lifetime.Matches(entity, now) || !f.label.matches(entity) {
+		return false
+	}
+	for _, r := range f.ranges {
+		if !r.Matches(entity) {
+			return false
+		}
+	}
+	return true
+}
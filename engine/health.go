@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"connectrpc.com/connect"
+	"connectrpc.com/grpchealth"
+	"github.com/projectqai/proto/go/_goconnect"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const healthWatchProcedure = grpc_health_v1.Health_Watch_FullMethodName
+
+// healthWatchInterval is how often Watch looks for a change of status.
+const healthWatchInterval = time.Second
+
+// healthServices are the services Check knows about. The empty name stands
+// for the server as a whole.
+var healthServices = map[string]bool{
+	"":                             true,
+	_goconnect.WorldServiceName:    true,
+	_goconnect.ArtifactServiceName: true,
+}
+
+// newHealthHandler returns the grpc.health.v1.Health service, so load
+// balancers and tools like grpc-health-probe can check the engine. It
+// reports NOT_SERVING while the world is frozen. Check is served by
+// grpchealth, which does not implement Watch, so Watch is served here.
+func newHealthHandler(s *WorldServer, opts ...connect.HandlerOption) (string, http.Handler) {
+	path, check := grpchealth.NewHandler(healthChecker{s}, opts...)
+	mux := http.NewServeMux()
+	mux.Handle(path, check)
+	mux.Handle(healthWatchProcedure, connect.NewServerStreamHandler(healthWatchProcedure, s.watchHealth, opts...))
+	return path, mux
+}
+
+// healthChecker is the grpchealth.Checker of the engine.
+type healthChecker struct{ s *WorldServer }
+
+func (c healthChecker) Check(ctx context.Context, req *grpchealth.CheckRequest) (*grpchealth.CheckResponse, error) {
+	status, err := c.s.healthStatus(req.Service)
+	if err != nil {
+		return nil, err
+	}
+	// grpchealth.Status has the values of the protocol's enum.
+	return &grpchealth.CheckResponse{Status: grpchealth.Status(status)}, nil
+}
+
+// watchHealth sends the health of the requested service, and again every
+// time it changes, until the client goes away. An unknown service is
+// reported as SERVICE_UNKNOWN rather than an error, as the protocol asks.
+func (s *WorldServer) watchHealth(ctx context.Context, req *connect.Request[grpc_health_v1.HealthCheckRequest], stream *connect.ServerStream[grpc_health_v1.HealthCheckResponse]) error {
+	ticker := time.NewTicker(healthWatchInterval)
+	defer ticker.Stop()
+
+	last := grpc_health_v1.HealthCheckResponse_ServingStatus(-1)
+	for {
+		status, err := s.healthStatus(req.Msg.GetService())
+		if err != nil {
+			status = grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+		if status != last {
+			if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: status}); err != nil {
+				return err
+			}
+			last = status
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// healthStatus is the health of service; unknown services are NotFound
+// as required by the health protocol.
+func (s *WorldServer) healthStatus(service string) (grpc_health_v1.HealthCheckResponse_ServingStatus, error) {
+	if !healthServices[service] {
+		return grpc_health_v1.HealthCheckResponse_UNKNOWN,
+			connect.NewError(connect.CodeNotFound, fmt.Errorf("unknown service %q", service))
+	}
+	if s.frozen.Load() {
+		return grpc_health_v1.HealthCheckResponse_NOT_SERVING, nil
+	}
+	return grpc_health_v1.HealthCheckResponse_SERVING, nil
+}
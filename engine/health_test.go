@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"context"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"github.com/projectqai/proto/go/_goconnect"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+)
+
+// grpcTestServer serves the API mux of w over HTTP/2, as gRPC needs.
+func grpcTestServer(t *testing.T, w *WorldServer) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(NewAPIMux(w, nil, nil))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHealth_FollowsFrozen(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	srv := grpcTestServer(t, w)
+	client := connect.NewClient[grpc_health_v1.HealthCheckRequest, grpc_health_v1.HealthCheckResponse](
+		srv.Client(), srv.URL+grpc_health_v1.Health_Check_FullMethodName, connect.WithGRPC())
+
+	check := func(service string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		resp, err := client.CallUnary(context.Background(), connect.NewRequest(&grpc_health_v1.HealthCheckRequest{Service: service}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Msg.GetStatus()
+	}
+
+	for _, service := range []string{"", _goconnect.WorldServiceName} {
+		if got := check(service); got != grpc_health_v1.HealthCheckResponse_SERVING {
+			t.Errorf("%q: got %v, want SERVING", service, got)
+		}
+	}
+
+	w.frozen.Store(true)
+	if got := check(_goconnect.WorldServiceName); got != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("frozen: got %v, want NOT_SERVING", got)
+	}
+
+	w.frozen.Store(false)
+	if got := check(""); got != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Errorf("unfrozen: got %v, want SERVING", got)
+	}
+
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(&grpc_health_v1.HealthCheckRequest{Service: "nope.Service"}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("unknown service: got %v, want not found", err)
+	}
+}
+
+func TestHealth_WatchReportsChanges(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	srv := grpcTestServer(t, w)
+	client := connect.NewClient[grpc_health_v1.HealthCheckRequest, grpc_health_v1.HealthCheckResponse](
+		srv.Client(), srv.URL+healthWatchProcedure, connect.WithGRPC())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.CallServerStream(ctx, connect.NewRequest(&grpc_health_v1.HealthCheckRequest{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := func() grpc_health_v1.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		if !stream.Receive() {
+			t.Fatalf("watch ended: %v", stream.Err())
+		}
+		return stream.Msg().GetStatus()
+	}
+
+	if got := next(); got != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Errorf("initial: got %v, want SERVING", got)
+	}
+	w.frozen.Store(true)
+	if got := next(); got != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("frozen: got %v, want NOT_SERVING", got)
+	}
+}
+
+func TestReflection_ListsWorldService(t *testing.T) {
+	srv := grpcTestServer(t, testWorld(map[string]*pb.Entity{}))
+	client := connect.NewClient[rpb.ServerReflectionRequest, rpb.ServerReflectionResponse](
+		srv.Client(), srv.URL+"/"+rpb.ServerReflection_ServiceDesc.ServiceName+"/ServerReflectionInfo", connect.WithGRPC())
+
+	stream := client.CallBidiStream(context.Background())
+	roundTrip := func(req *rpb.ServerReflectionRequest) *rpb.ServerReflectionResponse {
+		t.Helper()
+		if err := stream.Send(req); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := stream.Receive()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := roundTrip(&rpb.ServerReflectionRequest{MessageRequest: &rpb.ServerReflectionRequest_ListServices{}})
+	var names []string
+	for _, s := range resp.GetListServicesResponse().GetService() {
+		names = append(names, s.GetName())
+	}
+	for _, want := range []string{_goconnect.WorldServiceName, grpc_health_v1.Health_ServiceDesc.ServiceName} {
+		if !slices.Contains(names, want) {
+			t.Errorf("services %v do not include %s", names, want)
+		}
+	}
+
+	resp = roundTrip(&rpb.ServerReflectionRequest{MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{
+		FileContainingSymbol: _goconnect.WorldServiceName,
+	}})
+	if len(resp.GetFileDescriptorResponse().GetFileDescriptorProto()) == 0 {
+		t.Errorf("no descriptor for %s: %v", _goconnect.WorldServiceName, resp.GetErrorResponse())
+	}
+
+	resp = roundTrip(&rpb.ServerReflectionRequest{MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{
+		FileContainingSymbol: "nope.Service",
+	}})
+	if resp.GetErrorResponse() == nil {
+		t.Error("unknown symbol should return an error response")
+	}
+
+	if err := stream.CloseRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.CloseResponse(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Heartbeat keeps entities of a controller alive without re-pushing them:
+// the Lifetime.Until of each entity and of its components is moved to
+// ttl from now. Lifetimes that already end later, and entities that never
+// expire, are left alone. Nothing else about the entities changes, and
+// consumers are notified at the lowest priority.
+//
+// All ids must exist and be owned by controllerID; otherwise no entity is
+// touched and the error names the first offending id. controllerID is
+// whatever the caller claims, so the ownership check only catches mistakes,
+// like a controller heartbeating ids it does not push; keeping clients from
+// extending other controllers' entities is up to the authorizer.
+//
+// While the world is frozen, nothing is extended and Heartbeat fails with
+// CodeFailedPrecondition, as lifetimes are part of the frozen state.
+func (s *WorldServer) Heartbeat(ctx context.Context, controllerID string, ids []string, ttl time.Duration) error {
+	if controllerID == "" {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("controller must be set"))
+	}
+	if ttl <= 0 {
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("ttl must be positive, got %v", ttl))
+	}
+
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.frozen.Load() {
+		return connect.NewError(connect.CodeFailedPrecondition, errors.New(s.frozenMessage()))
+	}
+
+	for _, id := range ids {
+		es, ok := s.head[id]
+		if !ok {
+			return connect.NewError(connect.CodeNotFound, fmt.Errorf("entity with id %s not found", id))
+		}
+		if owner := es.entity.Controller.GetId(); owner != controllerID {
+			return connect.NewError(connect.CodePermissionDenied,
+				fmt.Errorf("entity %s is owned by controller %q, not %q", id, owner, controllerID))
+		}
+	}
+
+	until := time.Now().Add(ttl)
+	for _, id := range ids {
+		es := s.head[id]
+		if es.hardExpire {
+			continue
+		}
+		extended := false
+		for protoNum, cm := range es.lifetimes {
+			if cm.noLifetime || cm.until.IsZero() || !cm.until.Before(until) {
+				continue
+			}
+			cm.until = until
+			es.lifetimes[protoNum] = cm
+			extended = true
+		}
+
+		lt := es.entity.GetLifetime()
+		if lt.GetUntil().IsValid() && lt.GetUntil().AsTime().Before(until) {
+			// Clone so we don't mutate the pointer already shared with the bus.
+			updated := proto.Clone(es.entity).(*pb.Entity)
+			updated.Lifetime.Until = timestamppb.New(until)
+			es.entity = updated
+			s.headView[id] = updated
+			extended = true
+		}
+		if extended {
+			s.bus.Touch(id)
+		}
+	}
+	return nil
+}
+
+// heartbeatRequest is the body of POST /heartbeat.
+type heartbeatRequest struct {
+	Controller string   `json:"controller"`
+	IDs        []string `json:"ids"`
+	TTLSeconds float64  `json:"ttl_seconds"`
+}
+
+// handleHeartbeat serves Heartbeat over HTTP:
+//
+//	POST /heartbeat {"controller": "adsb", "ids": ["adsb.3c6444"], "ttl_seconds": 30}
+//
+// The request is checked by the authorizer as method "Heartbeat". The
+// controller in the body is not authenticated, see Heartbeat.
+func (s *WorldServer) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorizeHTTP(r, "Heartbeat"); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	var req heartbeatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid heartbeat request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	ttl := time.Duration(req.TTLSeconds * float64(time.Second))
+	if err := s.Heartbeat(r.Context(), req.Controller, req.IDs, ttl); err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// httpStatus maps the connect code of err to an HTTP status.
+func httpStatus(err error) int {
+	switch connect.CodeOf(err) {
+	case connect.CodeInvalidArgument:
+		return http.StatusBadRequest
+	case connect.CodeNotFound:
+		return http.StatusNotFound
+	case connect.CodePermissionDenied:
+		return http.StatusForbidden
+	case connect.CodeFailedPrecondition:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
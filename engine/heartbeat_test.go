@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func heartbeatWorld(until time.Time) *WorldServer {
+	return testWorld(map[string]*pb.Entity{
+		"adsb.1": {
+			Id:         "adsb.1",
+			Controller: &pb.Controller{Id: ptr("adsb")},
+			Geo:        &pb.GeoSpatialComponent{Latitude: 48, Longitude: 11},
+			Lifetime:   &pb.Lifetime{From: timestamppb.Now(), Until: timestamppb.New(until)},
+		},
+		"ais.1": {
+			Id:         "ais.1",
+			Controller: &pb.Controller{Id: ptr("ais")},
+			Geo:        &pb.GeoSpatialComponent{Latitude: 54, Longitude: 10},
+			Lifetime:   &pb.Lifetime{From: timestamppb.Now(), Until: timestamppb.New(until)},
+		},
+	})
+}
+
+func TestHeartbeat_ExtendsLifetime(t *testing.T) {
+	w := heartbeatWorld(time.Now().Add(-time.Millisecond))
+	before := w.GetHead("adsb.1")
+
+	c := NewConsumer(w, nil, nil)
+	w.bus.Register(c)
+	defer w.bus.Unregister(c)
+
+	if err := w.Heartbeat(context.Background(), "adsb", []string{"adsb.1"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	e := w.GetHead("adsb.1")
+	if until := e.GetLifetime().GetUntil().AsTime(); time.Until(until) < 50*time.Second {
+		t.Errorf("until %v not extended", until)
+	}
+	if before.GetLifetime().GetUntil().AsTime().After(time.Now()) {
+		t.Error("heartbeat modified the previous head entity")
+	}
+	if id, change, _, ok := c.popNext(); !ok || id != "adsb.1" || change != pb.EntityChange_EntityChangeUpdated {
+		t.Errorf("got notification %q %v %v, want update of adsb.1", id, change, ok)
+	}
+
+	w.GC()
+	if w.GetHead("adsb.1") == nil {
+		t.Fatal("heartbeat entity expired")
+	}
+	if w.GetHead("adsb.1").Geo == nil {
+		t.Error("heartbeat should keep the component lifetimes alive")
+	}
+	if w.GetHead("ais.1") != nil {
+		t.Error("entity without heartbeat should expire")
+	}
+}
+
+func TestHeartbeat_NeverShortens(t *testing.T) {
+	later := time.Now().Add(time.Hour)
+	w := heartbeatWorld(later)
+
+	if err := w.Heartbeat(context.Background(), "adsb", []string{"adsb.1"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if until := w.GetHead("adsb.1").GetLifetime().GetUntil().AsTime(); until.Before(later) {
+		t.Errorf("until shortened to %v", until)
+	}
+}
+
+func TestHeartbeat_RejectsForeignEntities(t *testing.T) {
+	until := time.Now().Add(time.Second)
+	w := heartbeatWorld(until)
+
+	err := w.Heartbeat(context.Background(), "adsb", []string{"adsb.1", "ais.1"}, time.Hour)
+	if connect.CodeOf(err) != connect.CodePermissionDenied {
+		t.Fatalf("got %v, want permission denied", err)
+	}
+	for _, id := range []string{"adsb.1", "ais.1"} {
+		if got := w.GetHead(id).GetLifetime().GetUntil().AsTime(); !got.Equal(until) {
+			t.Errorf("%s: until changed to %v after rejected heartbeat", id, got)
+		}
+	}
+
+	err = w.Heartbeat(context.Background(), "adsb", []string{"adsb.2"}, time.Hour)
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("got %v, want not found", err)
+	}
+}
+
+func TestHeartbeat_Frozen(t *testing.T) {
+	until := time.Now().Add(time.Second)
+	w := heartbeatWorld(until)
+	w.SetFrozen(context.Background(), SetFrozenRequest{Frozen: true, Reason: "restoring backup"})
+
+	err := w.Heartbeat(context.Background(), "adsb", []string{"adsb.1"}, time.Hour)
+	if connect.CodeOf(err) != connect.CodeFailedPrecondition {
+		t.Fatalf("got %v, want failed precondition while frozen", err)
+	}
+	if got := w.GetHead("adsb.1").GetLifetime().GetUntil().AsTime(); !got.Equal(until) {
+		t.Errorf("until changed to %v while frozen", got)
+	}
+}
@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// Defaults for KeepaliveConfig.
+const (
+	DefaultKeepalivePing     = 30 * time.Second
+	DefaultKeepaliveTimeout  = 15 * time.Second
+	DefaultMaxConnectionIdle = 5 * time.Minute
+)
+
+// KeepaliveConfig tunes how the server detects dead client connections.
+// Watch streams behind NAT or firewalls are often dropped without a FIN;
+// pinging the client lets the server notice, which cancels the streams on
+// the connection and unregisters their consumers.
+type KeepaliveConfig struct {
+	// Ping is how long a connection may be silent before the server pings
+	// the client; DefaultKeepalivePing when zero, never when negative.
+	Ping time.Duration
+	// Timeout is how long the server waits for the ping to be answered
+	// before closing the connection; DefaultKeepaliveTimeout when zero.
+	Timeout time.Duration
+	// MaxConnectionIdle closes connections without any open stream after
+	// this long; DefaultMaxConnectionIdle when zero, never when negative.
+	MaxConnectionIdle time.Duration
+}
+
+func (c KeepaliveConfig) withDefaults() KeepaliveConfig {
+	if c.Ping == 0 {
+		c.Ping = DefaultKeepalivePing
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultKeepaliveTimeout
+	}
+	if c.MaxConnectionIdle == 0 {
+		c.MaxConnectionIdle = DefaultMaxConnectionIdle
+	}
+	return c
+}
+
+// newHTTP2Server returns the HTTP/2 settings for the API server.
+func newHTTP2Server(cfg KeepaliveConfig) *http2.Server {
+	cfg = cfg.withDefaults()
+	srv := &http2.Server{PingTimeout: cfg.Timeout}
+	if cfg.Ping > 0 {
+		srv.ReadIdleTimeout = cfg.Ping
+	}
+	if cfg.MaxConnectionIdle > 0 {
+		srv.IdleTimeout = cfg.MaxConnectionIdle
+	}
+	return srv
+}
@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"github.com/projectqai/proto/go/_goconnect"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// stallConn stops reading once stalled, like a client that vanished
+// behind a NAT: the server's pings are never answered.
+type stallConn struct {
+	net.Conn
+	stalled   chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *stallConn) Read(b []byte) (int, error) {
+	select {
+	case <-c.stalled:
+		<-c.closed
+		return 0, net.ErrClosed
+	default:
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *stallConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return c.Conn.Close()
+}
+
+func TestKeepalive_UnregistersAbandonedWatch(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2 := newHTTP2Server(KeepaliveConfig{Ping: 100 * time.Millisecond, Timeout: 100 * time.Millisecond})
+	srv := &http.Server{Handler: h2c.NewHandler(NewAPIMux(w, nil, nil), h2)}
+	go func() { _ = srv.Serve(ln) }()
+	defer func() { _ = srv.Close() }()
+
+	conn := &stallConn{stalled: make(chan struct{}), closed: make(chan struct{})}
+	transport := &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			c, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			conn.Conn = c
+			return conn, nil
+		},
+	}
+	defer func() { _ = conn.Close() }()
+
+	client := _goconnect.NewWorldServiceClient(&http.Client{Transport: transport}, "http://"+ln.Addr().String(), connect.WithGRPC())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.WatchEntities(ctx, connect.NewRequest(&pb.ListEntitiesRequest{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stream.Receive() {
+		t.Fatalf("no ready event: %v", stream.Err())
+	}
+	if n := w.bus.Len(); n != 1 {
+		t.Fatalf("%d consumers registered, want 1", n)
+	}
+
+	close(conn.stalled)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for w.bus.Len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("consumer of abandoned stream still registered")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/projectqai/hydris/pkg/kml"
+)
+
+const defaultKMLRefresh = 10 * time.Second
+
+// kmlRefresh reads the "refresh" query parameter in seconds.
+func kmlRefresh(r *http.Request) (time.Duration, bool) {
+	raw := r.URL.Query().Get("refresh")
+	if raw == "" {
+		return defaultKMLRefresh, true
+	}
+	sec, err := strconv.ParseFloat(raw, 64)
+	if err != nil || sec < 1 {
+		return 0, false
+	}
+	return time.Duration(sec * float64(time.Second)), true
+}
+
+// handleKML serves GET /kml: the entities with a position or shape as a KML
+// document, or zipped as KMZ with ?format=kmz. It takes the same filters as
+// /geojson. The document tells network links not to refresh faster than
+// ?refresh seconds. The request is checked by the authorizer as method
+// "ListEntities".
+func (s *WorldServer) handleKML(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorizeHTTP(r, "ListEntities"); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	refresh, ok := kmlRefresh(r)
+	if !ok {
+		http.Error(w, "invalid refresh", http.StatusBadRequest)
+		return
+	}
+	entities, err := s.geoSnapshot(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	doc := kml.NewDocument("Hydris", entities, refresh)
+	if r.URL.Query().Get("format") == "kmz" {
+		w.Header().Set("Content-Type", "application/vnd.google-earth.kmz")
+		w.Header().Set("Content-Disposition", `attachment; filename="hydris.kmz"`)
+		_ = doc.EncodeKMZ(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.google-earth.kml+xml")
+	_ = doc.Encode(w)
+}
+
+// handleKMLLink serves GET /kml/link: a NetworkLink document that makes
+// Google Earth re-fetch /kml with the same query every ?refresh seconds.
+// Opening it once is enough to get a live view. It is checked as
+// "ListEntities" like the document it links to.
+func (s *WorldServer) handleKMLLink(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorizeHTTP(r, "ListEntities"); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	refresh, ok := kmlRefresh(r)
+	if !ok {
+		http.Error(w, "invalid refresh", http.StatusBadRequest)
+		return
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	href := url.URL{Scheme: scheme, Host: r.Host, Path: "/kml", RawQuery: r.URL.RawQuery}
+
+	w.Header().Set("Content-Type", "application/vnd.google-earth.kml+xml")
+	w.Header().Set("Content-Disposition", `attachment; filename="hydris.kml"`)
+	_ = kml.NewNetworkLink("Hydris", href.String(), refresh).Encode(w)
+}
@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleKML(t *testing.T) {
+	w := geoJSONTestWorld()
+
+	rec := httptest.NewRecorder()
+	w.handleKML(rec, httptest.NewRequest("GET", "/kml", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<Placemark") {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	w.handleKML(rec, httptest.NewRequest("GET", "/kml?refresh=0", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("refresh below 1s: status %d, want 400", rec.Code)
+	}
+}
+
+func TestHandleKMLLink(t *testing.T) {
+	w := geoJSONTestWorld()
+
+	rec := httptest.NewRecorder()
+	w.handleKMLLink(rec, httptest.NewRequest("GET", "http://hydris:50051/kml/link?refresh=30&format=kmz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "<href>http://hydris:50051/kml?refresh=30&amp;format=kmz</href>") ||
+		!strings.Contains(body, "<refreshInterval>30</refreshInterval>") {
+		t.Errorf("network link does not point back at /kml: %s", body)
+	}
+}
+
+func TestHandleKML_Authorized(t *testing.T) {
+	w := geoJSONTestWorld()
+	assertHTTPDenied(t, w, http.HandlerFunc(w.handleKML), httptest.NewRequest("GET", "/kml", nil), "ListEntities")
+	assertHTTPDenied(t, w, http.HandlerFunc(w.handleKMLLink), httptest.NewRequest("GET", "/kml/link", nil), "ListEntities")
+}
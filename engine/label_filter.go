@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+// LabelPrefixHeader and LabelRegexHeader select entities by label on
+// ListEntities and WatchEntities, in addition to the exact match of
+// EntityFilter.Label. Entities without a label never match.
+const (
+	LabelPrefixHeader = "Hydris-Label-Prefix"
+	LabelRegexHeader  = "Hydris-Label-Regex"
+)
+
+// Limits on label regexes. Go regexps run in linear time, so there is no
+// catastrophic backtracking, but a huge pattern still costs memory and time
+// per match on every event of a watch.
+const (
+	maxLabelRegexLen   = 256
+	maxLabelRegexInsts = 2000
+)
+
+// compileLabelRegex compiles a label regex once per filter, rejecting
+// patterns that exceed the size limits.
+func compileLabelRegex(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > maxLabelRegexLen {
+		return nil, fmt.Errorf("label regex longer than %d characters", maxLabelRegexLen)
+	}
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label regex: %w", err)
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return nil, fmt.Errorf("invalid label regex: %w", err)
+	}
+	if len(prog.Inst) > maxLabelRegexInsts {
+		return nil, fmt.Errorf("label regex too complex")
+	}
+	return regexp.Compile(pattern)
+}
+
+// labelFilter matches an entity label by prefix and/or regex.
+type labelFilter struct {
+	prefix string
+	regex  *regexp.Regexp
+}
+
+func (f *labelFilter) matches(entity *pb.Entity) bool {
+	if f == nil {
+		return true
+	}
+	if entity.Label == nil {
+		return false
+	}
+	if f.prefix != "" && !strings.HasPrefix(*entity.Label, f.prefix) {
+		return false
+	}
+	if f.regex != nil && !f.regex.MatchString(*entity.Label) {
+		return false
+	}
+	return true
+}
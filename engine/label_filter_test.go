@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+
+	"connectrpc.com/connect"
+)
+
+func labelWorld() *WorldServer {
+	return testWorld(map[string]*pb.Entity{
+		"t1": {Id: "t1", Label: ptr("TANK-01")},
+		"t2": {Id: "t2", Label: ptr("TANK-22")},
+		"a1": {Id: "a1", Label: ptr("APC-01")},
+		"x1": {Id: "x1"},
+	})
+}
+
+func listWithHeaders(t *testing.T, w *WorldServer, headers map[string]string) ([]string, error) {
+	t.Helper()
+	req := peerRequest(&pb.ListEntitiesRequest{})
+	for k, v := range headers {
+		req.Header().Set(k, v)
+	}
+	resp, err := w.ListEntities(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return ids(resp.Msg.Entities), nil
+}
+
+func TestLabelFilter_Prefix(t *testing.T) {
+	got, err := listWithHeaders(t, labelWorld(), map[string]string{LabelPrefixHeader: "TANK"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "t1" || got[1] != "t2" {
+		t.Errorf("got %v, want t1 and t2", got)
+	}
+}
+
+func TestLabelFilter_Regex(t *testing.T) {
+	got, err := listWithHeaders(t, labelWorld(), map[string]string{LabelRegexHeader: `-01$`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "a1" || got[1] != "t1" {
+		t.Errorf("got %v, want [a1 t1]", got)
+	}
+
+	got, err = listWithHeaders(t, labelWorld(), map[string]string{
+		LabelPrefixHeader: "TANK",
+		LabelRegexHeader:  `-01$`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "t1" {
+		t.Errorf("prefix and regex combined: got %v, want [t1]", got)
+	}
+}
+
+func TestLabelFilter_InvalidRegexRejected(t *testing.T) {
+	for _, pattern := range []string{
+		`TANK(`,
+		strings.Repeat("a", maxLabelRegexLen+1),
+		`(a{1,100}){1,100}`,
+	} {
+		_, err := listWithHeaders(t, labelWorld(), map[string]string{LabelRegexHeader: pattern})
+		if connect.CodeOf(err) != connect.CodeInvalidArgument {
+			t.Errorf("%.20q: got %v, want invalid argument", pattern, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+// LifetimeFilterHeader selects entities by lifetime state on ListEntities
+// and WatchEntities. The value is a comma separated list of terms, all of
+// which must match:
+//
+//	alive               Lifetime.Until is unset or in the future
+//	expired             Lifetime.Until is in the past
+//	has_until           Lifetime.Until is set
+//	expires_before=60s  Lifetime.Until is set and earlier than now+60s
+//
+// "alive,expires_before=60s" selects entities that expire within a minute.
+// EntityFilter is defined in the proto module and has no lifetime field, so
+// this is carried as a header and applied on top of the request filter.
+const LifetimeFilterHeader = "Hydris-Lifetime-Filter"
+
+// LifetimeFilter matches entities by the state of their Lifetime.Until
+// relative to the time of the match.
+type LifetimeFilter struct {
+	Alive         bool
+	Expired       bool
+	HasUntil      bool
+	ExpiresBefore time.Duration // 0 = no limit
+}
+
+// ParseLifetimeFilter parses the value of LifetimeFilterHeader. An empty
+// value returns nil, which matches everything.
+func ParseLifetimeFilter(v string) (*LifetimeFilter, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil, nil
+	}
+
+	f := &LifetimeFilter{}
+	for _, term := range strings.Split(v, ",") {
+		key, value, hasValue := strings.Cut(strings.TrimSpace(term), "=")
+		switch {
+		case key == "alive" && !hasValue:
+			f.Alive = true
+		case key == "expired" && !hasValue:
+			f.Expired = true
+		case key == "has_until" && !hasValue:
+			f.HasUntil = true
+		case key == "expires_before" && hasValue:
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid expires_before %q", value)
+			}
+			f.ExpiresBefore = d
+		default:
+			return nil, fmt.Errorf("unknown lifetime filter term %q", term)
+		}
+	}
+	if f.Alive && f.Expired {
+		return nil, fmt.Errorf("lifetime filter cannot be both alive and expired")
+	}
+	return f, nil
+}
+
+// Matches reports whether entity matches the filter at time now. Callers
+// pass the current time for every evaluation, so an entity can start or stop
+// matching without changing.
+func (f *LifetimeFilter) Matches(entity *pb.Entity, now time.Time) bool {
+	if f == nil {
+		return true
+	}
+
+	until := entity.GetLifetime().GetUntil()
+	hasUntil := until.IsValid()
+	expired := hasUntil && !until.AsTime().After(now)
+
+	if f.Alive && expired {
+		return false
+	}
+	if f.Expired && !expired {
+		return false
+	}
+	if (f.HasUntil || f.ExpiresBefore > 0) && !hasUntil {
+		return false
+	}
+	if f.ExpiresBefore > 0 && !until.AsTime().Before(now.Add(f.ExpiresBefore)) {
+		return false
+	}
+	return true
+}
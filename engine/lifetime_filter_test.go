@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"connectrpc.com/connect"
+)
+
+func untilEntity(id string, until time.Time) *pb.Entity {
+	return &pb.Entity{Id: id, Lifetime: &pb.Lifetime{Until: timestamppb.New(until)}}
+}
+
+func TestLifetimeFilter_Matches(t *testing.T) {
+	now := time.Unix(1000, 0)
+	forever := &pb.Entity{Id: "forever"}
+	alive := untilEntity("alive", now.Add(time.Hour))
+	soon := untilEntity("soon", now.Add(30*time.Second))
+	expired := untilEntity("expired", now.Add(-time.Second))
+
+	tests := []struct {
+		filter string
+		want   map[string]bool
+	}{
+		{"", map[string]bool{"forever": true, "alive": true, "soon": true, "expired": true}},
+		{"alive", map[string]bool{"forever": true, "alive": true, "soon": true}},
+		{"expired", map[string]bool{"expired": true}},
+		{"has_until", map[string]bool{"alive": true, "soon": true, "expired": true}},
+		{"alive,expires_before=60s", map[string]bool{"soon": true}},
+		{"expires_before=60s", map[string]bool{"soon": true, "expired": true}},
+	}
+
+	for _, tt := range tests {
+		f, err := ParseLifetimeFilter(tt.filter)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.filter, err)
+		}
+		for _, e := range []*pb.Entity{forever, alive, soon, expired} {
+			if got := f.Matches(e, now); got != tt.want[e.Id] {
+				t.Errorf("%q matches %s = %v, want %v", tt.filter, e.Id, got, tt.want[e.Id])
+			}
+		}
+	}
+}
+
+func TestParseLifetimeFilter_Invalid(t *testing.T) {
+	for _, v := range []string{"bogus", "alive=1", "expires_before", "expires_before=soon", "expires_before=-5s", "alive,expired"} {
+		if _, err := ParseLifetimeFilter(v); err == nil {
+			t.Errorf("%q: expected error", v)
+		}
+	}
+}
+
+// TestLifetimeFilter_EvaluatedAtMatchTime checks that the consumer uses the
+// current time for every match rather than the time the watch started.
+func TestLifetimeFilter_EvaluatedAtMatchTime(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	c := NewConsumer(w, nil, nil)
+	c.extra = &headerFilter{lifetime: &LifetimeFilter{Expired: true}}
+
+	e := untilEntity("e1", time.Now().Add(50*time.Millisecond))
+	if c.matches(e) {
+		t.Fatal("entity should not match expired before its until")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if !c.matches(e) {
+		t.Error("entity should match expired once its until has passed")
+	}
+}
+
+func TestListEntities_LifetimeHeader(t *testing.T) {
+	now := time.Now()
+	w := testWorld(map[string]*pb.Entity{
+		"alive":   untilEntity("alive", now.Add(time.Hour)),
+		"soon":    untilEntity("soon", now.Add(30*time.Second)),
+		"expired": untilEntity("expired", now.Add(-time.Minute)),
+	})
+
+	req := peerRequest(&pb.ListEntitiesRequest{})
+	req.Header().Set(LifetimeFilterHeader, "alive,expires_before=60s")
+	resp, err := w.ListEntities(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Msg.Entities) != 1 || resp.Msg.Entities[0].Id != "soon" {
+		t.Errorf("got %v, want only soon", ids(resp.Msg.Entities))
+	}
+
+	req = peerRequest(&pb.ListEntitiesRequest{})
+	req.Header().Set(LifetimeFilterHeader, "bogus")
+	if _, err := w.ListEntities(context.Background(), req); connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("invalid filter: got %v, want invalid argument", err)
+	}
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// MergeModeHeader selects how Push merges a component into an entity that
+// already has it:
+//
+//   - REPLACE_COMPONENT (default) replaces the whole component.
+//   - MERGE_FIELDS merges the populated fields of the incoming component
+//     into the existing one, recursing into nested messages. Repeated and
+//     map fields and well-known types such as timestamps are replaced as a
+//     whole. Proto3 scalars without presence cannot be told apart from
+//     unset when zero, so they cannot be cleared in this mode.
+const MergeModeHeader = "Hydris-Merge-Mode"
+
+type MergeMode int
+
+const (
+	MergeReplaceComponent MergeMode = iota
+	MergeFields
+)
+
+// mergeModeFromHeader parses MergeModeHeader; a missing header selects
+// MergeReplaceComponent.
+func mergeModeFromHeader(h http.Header) (MergeMode, error) {
+	switch v := strings.ToUpper(strings.TrimSpace(h.Get(MergeModeHeader))); v {
+	case "", "REPLACE_COMPONENT":
+		return MergeReplaceComponent, nil
+	case "MERGE_FIELDS":
+		return MergeFields, nil
+	default:
+		return 0, connect.NewError(connect.CodeInvalidArgument,
+			fmt.Errorf("%s: unknown merge mode %q, want REPLACE_COMPONENT or MERGE_FIELDS", MergeModeHeader, v))
+	}
+}
+
+// mergeFields merges the populated fields of src into dst.
+func mergeFields(dst, src protoreflect.Message) {
+	src.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap() &&
+			!isWellKnown(fd.Message()) && dst.Has(fd) {
+			mergeFields(dst.Mutable(fd).Message(), v.Message())
+			return true
+		}
+		dst.Set(fd, v)
+		return true
+	})
+}
+
+// isWellKnown reports whether md is a google.protobuf type; those are
+// values (e.g. a Timestamp's seconds and nanos belong together) and are
+// never merged field by field.
+func isWellKnown(md protoreflect.MessageDescriptor) bool {
+	return md.ParentFile().Package() == "google.protobuf"
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+)
+
+func pushWithMergeMode(t *testing.T, w *WorldServer, mode string, e *pb.Entity) error {
+	t.Helper()
+	req := peerRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{e}})
+	if mode != "" {
+		req.Header().Set(MergeModeHeader, mode)
+	}
+	_, err := w.Push(context.Background(), req)
+	return err
+}
+
+func mergeModeWorld() *WorldServer {
+	return testWorld(map[string]*pb.Entity{
+		"e1": {
+			Id:  "e1",
+			Geo: &pb.GeoSpatialComponent{Latitude: 54, Longitude: 10, Altitude: ptr(100.0)},
+		},
+	})
+}
+
+func TestPush_MergeFieldsKeepsOtherGeoFields(t *testing.T) {
+	w := mergeModeWorld()
+	if err := pushWithMergeMode(t, w, "MERGE_FIELDS", &pb.Entity{Id: "e1", Geo: &pb.GeoSpatialComponent{Altitude: ptr(250.0)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	geo := w.head["e1"].entity.Geo
+	if geo.Latitude != 54 || geo.Longitude != 10 || geo.GetAltitude() != 250 {
+		t.Errorf("got lat %v lon %v alt %v, want 54 10 250", geo.Latitude, geo.Longitude, geo.GetAltitude())
+	}
+}
+
+func TestPush_ReplaceComponentReplacesGeo(t *testing.T) {
+	for _, mode := range []string{"", "REPLACE_COMPONENT"} {
+		w := mergeModeWorld()
+		if err := pushWithMergeMode(t, w, mode, &pb.Entity{Id: "e1", Geo: &pb.GeoSpatialComponent{Altitude: ptr(250.0)}}); err != nil {
+			t.Fatal(err)
+		}
+
+		geo := w.head["e1"].entity.Geo
+		if geo.Latitude != 0 || geo.Longitude != 0 || geo.GetAltitude() != 250 {
+			t.Errorf("mode %q: got lat %v lon %v alt %v, want 0 0 250", mode, geo.Latitude, geo.Longitude, geo.GetAltitude())
+		}
+	}
+}
+
+func TestPush_MergeFieldsNewComponent(t *testing.T) {
+	w := mergeModeWorld()
+	if err := pushWithMergeMode(t, w, "merge_fields", &pb.Entity{Id: "e1", Label: ptr("ship")}); err != nil {
+		t.Fatal(err)
+	}
+	e := w.head["e1"].entity
+	if e.GetLabel() != "ship" || e.Geo.Latitude != 54 {
+		t.Errorf("got %v", e)
+	}
+}
+
+func TestPush_InvalidMergeMode(t *testing.T) {
+	w := mergeModeWorld()
+	err := pushWithMergeMode(t, w, "DEEP", &pb.Entity{Id: "e1", Label: ptr("ship")})
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("got %v, want invalid argument", err)
+	}
+	if w.head["e1"].entity.Label != nil {
+		t.Error("rejected push must not change the entity")
+	}
+}
+
+func TestMergeModeFromHeader(t *testing.T) {
+	h := http.Header{}
+	if m, err := mergeModeFromHeader(h); err != nil || m != MergeReplaceComponent {
+		t.Errorf("default: got %v, %v", m, err)
+	}
+	h.Set(MergeModeHeader, " Merge_Fields ")
+	if m, err := mergeModeFromHeader(h); err != nil || m != MergeFields {
+		t.Errorf("merge fields: got %v, %v", m, err)
+	}
+}
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"context"
+	"time"
 
 	pb "github.com/projectqai/proto/go"
 
@@ -12,24 +13,52 @@ func (s *WorldServer) WatchEntities(ctx context.Context, req *connect.Request[pb
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	peerAddr := req.Peer().Addr
+	send := func(event *pb.EntityChangeEvent) error {
+		return stream.Send(s.redactEventForPeer(peerAddr, event))
+	}
+
+	extra, err := headerFilterFromRequest(req.Header())
+	if err != nil {
+		return err
+	}
+
+	changes, err := parseChangeFilter(req.Header().Get(ChangeFilterHeader))
+	if err != nil {
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	componentFilter, err := parseComponentChanges(req.Header().Get(ComponentChangesHeader))
+	if err != nil {
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
 	consumer := NewConsumer(s, req.Msg.Behaviour, req.Msg.Filter)
+	consumer.extra = extra
+	consumer.changes = changes
+	consumer.componentFilter = componentFilter
 	consumer.cancel = cancel
 	s.bus.Register(consumer)
 	defer s.bus.Unregister(consumer)
 
 	// UI workaround - send an initial invalid event to signal stream is ready
-	if err := stream.Send(&pb.EntityChangeEvent{
-		T: pb.EntityChange_EntityChangeInvalid,
+	if err := consumer.watchSend(func() error {
+		return stream.Send(&pb.EntityChangeEvent{T: pb.EntityChange_EntityChangeInvalid})
 	}); err != nil {
 		return err
 	}
 
+	if !changes.wants(pb.EntityChange_EntityChangeUpdated) {
+		return consumer.SenderLoop(ctx, send)
+	}
+
 	// Send initial snapshot sorted by Lifetime.From
 	s.l.RLock()
+	now := time.Now()
 	var snapshot []*pb.Entity
 	for _, es := range s.head {
 		e := es.entity
-		if s.matchesEntityFilter(e, req.Msg.Filter) {
+		if s.matchesEntityFilter(e, req.Msg.Filter) && extra.matches(e, now) {
 			snapshot = append(snapshot, e)
 		}
 	}
@@ -42,13 +71,12 @@ func (s *WorldServer) WatchEntities(ctx context.Context, req *connect.Request[pb
 	}
 
 	for _, e := range snapshot {
-		if err := stream.Send(&pb.EntityChangeEvent{
-			Entity: e,
-			T:      pb.EntityChange_EntityChangeUpdated,
+		if err := consumer.watchSend(func() error {
+			return send(&pb.EntityChangeEvent{Entity: e, T: pb.EntityChange_EntityChangeUpdated})
 		}); err != nil {
 			return err
 		}
 	}
 
-	return consumer.SenderLoop(ctx, stream.Send)
+	return consumer.SenderLoop(ctx, send)
 }
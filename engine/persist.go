@@ -2,12 +2,12 @@ package engine
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
 	"slices"
 	"strings"
 	"time"
@@ -82,6 +82,10 @@ func (s *WorldServer) LoadDefaults(b []byte) error {
 }
 
 func (s *WorldServer) LoadFromFile(path string) error {
+	if err := s.loadPins(pinsFile(path)); err != nil {
+		return err
+	}
+
 	inputBytes, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -94,14 +98,22 @@ func (s *WorldServer) LoadFromFile(path string) error {
 		return nil
 	}
 
-	entities, err := ParseEntities(inputBytes)
+	entities, err := parseEntitiesFormat(inputBytes, worldFormatOf(path))
 	if err != nil {
 		return err
 	}
 
 	s.l.Lock()
-	defer s.l.Unlock()
+	s.loadEntities(entities)
+	s.l.Unlock()
 
+	slog.Info("loaded entities from file", "count", len(entities), "path", path)
+	return nil
+}
+
+// loadEntities inserts entities read from persistence, stamping a missing
+// Lifetime.From with now. The caller must hold s.l.
+func (s *WorldServer) loadEntities(entities []*pb.Entity) {
 	for _, e := range entities {
 		if e.Lifetime == nil {
 			e.Lifetime = &pb.Lifetime{}
@@ -115,45 +127,25 @@ func (s *WorldServer) LoadFromFile(path string) error {
 		s.initEntity(e)
 		s.bus.Dirty(e.Id, e, pb.EntityChange_EntityChangeUpdated)
 	}
-
-	slog.Info("loaded entities from file", "count", len(entities), "path", path)
-	return nil
 }
 
 func ParseEntities(b []byte) ([]*pb.Entity, error) {
-	decoder := yaml.NewDecoder(bytes.NewReader(b))
-	var entities []*pb.Entity
-
-	for {
-		var data map[string]interface{}
-		err := decoder.Decode(&data)
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, fmt.Errorf("failed to decode YAML document: %w", err)
-		}
-
-		// Skip empty documents
-		if len(data) == 0 {
-			continue
-		}
+	docs, err := decodeYAMLDocuments(b)
+	if err != nil {
+		return nil, err
+	}
 
-		// Convert to JSON then to protobuf
-		jsonBytes, err := json.Marshal(data)
+	entities := make([]*pb.Entity, 0, len(docs))
+	for _, doc := range docs {
+		jsonBytes, err := yamlNodeToJSON(doc)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal to JSON: %w", err)
+			return nil, fmt.Errorf("failed to convert YAML to JSON: %w", err)
 		}
 
 		entity := &pb.Entity{}
-		unmarshaler := protojson.UnmarshalOptions{
-			DiscardUnknown: false,
-		}
-
-		if err := unmarshaler.Unmarshal(jsonBytes, entity); err != nil {
+		if err := protojson.Unmarshal(jsonBytes, entity); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal entity: %w", err)
 		}
-
 		entities = append(entities, entity)
 	}
 
@@ -165,81 +157,72 @@ func (s *WorldServer) isLocal(e *pb.Entity) bool {
 	return s.nodeID != "" && e.Controller != nil && e.Controller.Node != nil && *e.Controller.Node == s.nodeID
 }
 
-// FlushToFile writes the current head state to the world file atomically.
-// Only local entities (controller.node == this node) are persisted, and only
-// the config and device components are kept. Entities with lifetime.until
-// (expiring/temporary) are skipped entirely.
+// SetPersistFsync controls whether flushes sync the world file and its
+// directory to disk. Syncing is on by default; turning it off trades
+// durability on power loss for less I/O on slow storage. Flushes are
+// atomic either way.
+func (s *WorldServer) SetPersistFsync(fsync bool) {
+	s.persistFsync = fsync
+}
+
+// FlushToFile writes the current head state to the world file atomically,
+// in the format given by its extension (see worldFormatOf). With a world
+// directory it writes the fragments instead (see SetWorldDir). The pins
+// are saved along with it, see SetPinned.
 func (s *WorldServer) FlushToFile() error {
+	if s.worldDir != "" {
+		if err := s.flushPins(pinsFile(s.worldDir)); err != nil {
+			return err
+		}
+		return s.flushToDir()
+	}
 	if s.worldFile == "" {
 		return nil
 	}
+	if err := s.flushPins(pinsFile(s.worldFile)); err != nil {
+		return err
+	}
 
-	s.l.RLock()
-	entities := make([]*pb.Entity, 0, len(s.head))
-	for _, es := range s.head {
-		e := es.entity
-		if !s.isLocal(e) {
-			continue
-		}
-
-		hasSomething := false
-
-		stub := &pb.Entity{Id: e.Id, Label: e.Label, Controller: e.Controller, Lifetime: e.Lifetime}
-		if e.Config != nil {
-			stub.Config = e.Config
-			hasSomething = true
-		}
-		if e.Device != nil {
-			stub.Device = e.Device
-			hasSomething = true
-		}
-		if e.Artifact != nil {
-			stub.Artifact = e.Artifact
-			hasSomething = true
-		}
-		if e.Geo != nil && es.isInfinite(11) {
-			stub.Geo = e.Geo
-			hasSomething = true
-		}
-
-		if !hasSomething {
-			continue
-		}
-
-		entities = append(entities, stub)
+	out, err := marshalEntities(s.persistedEntities(), worldFormatOf(s.worldFile))
+	if err != nil {
+		return fmt.Errorf("failed to marshal entities: %w", err)
 	}
-	s.l.RUnlock()
 
-	// Sort entities by ID for consistent output
-	slices.SortFunc(entities, func(a, b *pb.Entity) int {
-		return strings.Compare(a.Id, b.Id)
+	err = writeFileAtomic(s.worldFile, s.persistFsync, func(w io.Writer) error {
+		_, err := w.Write(out)
+		return err
 	})
-
-	// Convert to YAML
-	yamlBytes, err := entitiesToYAML(entities)
-	if err != nil {
-		return fmt.Errorf("failed to marshal entities to YAML: %w", err)
+	if err == nil {
+		s.counters.flushes.Add(1)
 	}
+	return err
+}
 
-	// Write atomically: write to temp file, then rename
-	dir := filepath.Dir(s.worldFile)
-	tmpFile, err := os.CreateTemp(dir, ".hydris-world-*.yaml.tmp")
+// writeFileAtomic replaces path with what write produces. It writes to a
+// temp file in the same directory and renames it into place, so readers
+// see either the old or the new file, never a partial one. With fsync the
+// file and its directory are synced as well, so the new file also
+// survives a power loss.
+func writeFileAtomic(path string, fsync bool, write func(io.Writer) error) error {
+	dir := filepath.Dir(path)
+	tmpFile, err := os.CreateTemp(dir, ".hydris-world-*"+filepath.Ext(path)+".tmp")
 	if err != nil {
 		return fmt.Errorf("failed to create temp file: %w", err)
 	}
 	tmpPath := tmpFile.Name()
 
-	_, err = tmpFile.Write(yamlBytes)
-	if err != nil {
+	if err := write(tmpFile); err != nil {
 		_ = tmpFile.Close()
 		_ = os.Remove(tmpPath)
 		return fmt.Errorf("failed to write temp file: %w", err)
 	}
 
-	if err := tmpFile.Sync(); err != nil {
-		_ = tmpFile.Close()
-		_ = os.Remove(tmpPath)
-		return fmt.Errorf("failed to sync temp file: %w", err)
+	if fsync {
+		if err := tmpFile.Sync(); err != nil {
+			_ = tmpFile.Close()
+			_ = os.Remove(tmpPath)
+			return fmt.Errorf("failed to sync temp file: %w", err)
+		}
 	}
 
 	if err := tmpFile.Close(); err != nil {
@@ -248,15 +231,89 @@ func (s *WorldServer) FlushToFile() error {
 	}
 
 	// Atomic rename
-	if err := os.Rename(tmpPath, s.worldFile); err != nil {
+	if err := os.Rename(tmpPath, path); err != nil {
 		_ = os.Remove(tmpPath)
-		return fmt.Errorf("failed to rename temp file to %s: %w", s.worldFile, err)
+		return fmt.Errorf("failed to rename temp file to %s: %w", path, err)
 	}
 
+	if fsync {
+		if err := syncDir(dir); err != nil {
+			return fmt.Errorf("failed to sync %s: %w", dir, err)
+		}
+	}
 	return nil
 }
 
-// Canonical field order for YAML output
+// syncDir makes a rename in dir durable. Windows can't sync directories
+// and doesn't need to.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+	return d.Sync()
+}
+
+// persistedEntities returns what FlushToFile writes, sorted by ID. Only
+// local entities (controller.node == this node) are persisted, and only
+// the config, device, artifact and permanent geo components are kept.
+func (s *WorldServer) persistedEntities() []*pb.Entity {
+	s.l.RLock()
+	entities := make([]*pb.Entity, 0, len(s.head))
+	for _, es := range s.head {
+		if stub := s.persistStub(es); stub != nil {
+			entities = append(entities, stub)
+		}
+	}
+	s.l.RUnlock()
+
+	// Sort entities by ID for consistent output
+	slices.SortFunc(entities, func(a, b *pb.Entity) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	return entities
+}
+
+// persistStub returns the part of an entity that is persisted, or nil if
+// nothing of it is. The caller must hold s.l.
+func (s *WorldServer) persistStub(es *entityState) *pb.Entity {
+	e := es.entity
+	if !s.isLocal(e) {
+		return nil
+	}
+
+	hasSomething := false
+
+	stub := &pb.Entity{Id: e.Id, Label: e.Label, Controller: e.Controller, Lifetime: e.Lifetime}
+	if e.Config != nil {
+		stub.Config = e.Config
+		hasSomething = true
+	}
+	if e.Device != nil {
+		stub.Device = e.Device
+		hasSomething = true
+	}
+	if e.Artifact != nil {
+		stub.Artifact = e.Artifact
+		hasSomething = true
+	}
+	if e.Geo != nil && es.isInfinite(11) {
+		stub.Geo = e.Geo
+		hasSomething = true
+	}
+
+	if !hasSomething {
+		return nil
+	}
+	return stub
+}
+
+// Canonical field order for world file output
 var canonicalFieldOrder = []string{"id", "label", "controller", "lifetime", "priority", "symbol", "geo"}
 
 // entitiesToYAML converts entities to multi-document YAML format with canonical field order.
@@ -277,22 +334,14 @@ func entitiesToYAML(entities []*pb.Entity) ([]byte, error) {
 			buf.WriteString("---\n")
 		}
 
-		// Convert proto to JSON first
 		jsonBytes, err := marshaler.Marshal(entity)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal entity %s to JSON: %w", entity.Id, err)
 		}
-
-		// Convert JSON to map
-		var data map[string]interface{}
-		if err := json.Unmarshal(jsonBytes, &data); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal JSON for entity %s: %w", entity.Id, err)
+		node, err := jsonToYAMLNode(jsonBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert entity %s to YAML: %w", entity.Id, err)
 		}
-
-		// Build ordered YAML node
-		node := buildOrderedYAMLNode(data)
-
-		// Marshal to YAML
 		yamlBytes, err := yaml.Marshal(node)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal entity %s to YAML: %w", entity.Id, err)
@@ -304,20 +353,16 @@ func entitiesToYAML(entities []*pb.Entity) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// buildOrderedYAMLNode creates a yaml.Node with fields in canonical order.
-func buildOrderedYAMLNode(data map[string]interface{}) *yaml.Node {
-	node := &yaml.Node{
-		Kind: yaml.MappingNode,
-	}
-
-	// First add canonical fields in order
+// orderedKeys returns the keys of data in canonical order: the fields of
+// canonicalFieldOrder first, then the rest sorted.
+func orderedKeys[V any](data map[string]V) []string {
+	keys := make([]string, 0, len(data))
 	for _, key := range canonicalFieldOrder {
-		if val, ok := data[key]; ok {
-			addKeyValue(node, key, val)
+		if _, ok := data[key]; ok {
+			keys = append(keys, key)
 		}
 	}
 
-	// Collect remaining keys and sort them
 	var remainingKeys []string
 	for key := range data {
 		if !slices.Contains(canonicalFieldOrder, key) {
@@ -326,51 +371,56 @@ func buildOrderedYAMLNode(data map[string]interface{}) *yaml.Node {
 	}
 	slices.Sort(remainingKeys)
 
-	// Add remaining fields in sorted order
-	for _, key := range remainingKeys {
-		addKeyValue(node, key, data[key])
-	}
-
-	return node
+	return append(keys, remainingKeys...)
 }
 
-// addKeyValue adds a key-value pair to a yaml mapping node.
-func addKeyValue(node *yaml.Node, key string, val interface{}) {
-	keyNode := &yaml.Node{
-		Kind:  yaml.ScalarNode,
-		Value: key,
-	}
-	var valNode yaml.Node
-	_ = valNode.Encode(val)
-	node.Content = append(node.Content, keyNode, &valNode)
+// DefaultPersistDebounce is how long autosave waits after a persistable
+// change for more changes before flushing.
+const DefaultPersistDebounce = 2 * time.Second
+
+// SetPersistDebounce sets how long autosave coalesces persistable changes
+// before flushing; DefaultPersistDebounce when zero or negative. It must
+// be called before StartPeriodicFlush.
+func (s *WorldServer) SetPersistDebounce(d time.Duration) {
+	s.persistDebounce = d
 }
 
 // StartPeriodicFlush starts a goroutine that periodically flushes the head to the world file.
-// It also starts a debounce goroutine that flushes shortly after config changes.
+// It also starts an autosave goroutine that flushes once changes to
+// persisted entities have settled for the persist debounce window, so a
+// burst of pushes results in a single write.
 func (s *WorldServer) StartPeriodicFlush(interval time.Duration) {
-	if s.worldFile == "" {
+	if s.worldFile == "" && s.worldDir == "" {
 		return
 	}
 
 	s.persistNotify = make(chan struct{}, 1)
+	debounce := s.persistDebounce
+	if debounce <= 0 {
+		debounce = DefaultPersistDebounce
+	}
 
 	go func() {
 		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 
-		for range ticker.C {
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
 			if err := s.FlushToFile(); err != nil {
 				slog.Warn("failed to flush world state", "error", err)
 			}
 		}
 	}()
 
-	// Debounced flush: wait for a config change signal, then debounce
+	// Autosave: wait for a change to a persisted entity, then debounce
 	// additional signals within a short window before flushing.
 	go func() {
-		const debounce = 2 * time.Second
 		for {
-			// Block until a config change is signalled.
+			// Block until a change is signalled.
 			_, ok := <-s.persistNotify
 			if !ok {
 				return
@@ -396,7 +446,7 @@ func (s *WorldServer) StartPeriodicFlush(interval time.Duration) {
 	}()
 }
 
-// notifyPersist signals the debounced flush goroutine that a config change occurred.
+// notifyPersist signals the autosave goroutine that a persisted entity changed.
 func (s *WorldServer) notifyPersist() {
 	if s.persistNotify == nil {
 		return
@@ -0,0 +1,264 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+// defaultFragment is the file in a world directory that gets the entities
+// no other fragment claims.
+const defaultFragment = "world.yaml"
+
+// WorldGrouping decides which fragment of a world directory an entity is
+// written back to.
+type WorldGrouping int
+
+const (
+	// GroupBySource writes each entity back to the fragment it was loaded
+	// from. New entities go to world.yaml.
+	GroupBySource WorldGrouping = iota
+	// GroupByController writes one fragment per controller, named after
+	// it, e.g. adsb.yaml. Entities without a controller go to world.yaml.
+	GroupByController
+)
+
+// ParseWorldGrouping parses "source" or "controller"; empty is
+// GroupBySource.
+func ParseWorldGrouping(s string) (WorldGrouping, error) {
+	switch s {
+	case "", "source":
+		return GroupBySource, nil
+	case "controller":
+		return GroupByController, nil
+	}
+	return 0, fmt.Errorf("unknown world grouping %q, want source or controller", s)
+}
+
+// worldDirState is what a world directory remembers between flushes.
+type worldDirState struct {
+	mu sync.Mutex
+	// sources maps entity ids to the fragment they were loaded from or
+	// last written to.
+	sources map[string]string
+	// fragments are the fragment names seen in the directory, so one
+	// whose entities are all gone is emptied rather than left to bring
+	// them back on the next start.
+	fragments map[string]bool
+}
+
+func (st *worldDirState) init() {
+	if st.sources == nil {
+		st.sources = make(map[string]string)
+		st.fragments = make(map[string]bool)
+	}
+}
+
+// IDCollisionError reports entity ids defined in more than one fragment of
+// a world directory.
+type IDCollisionError struct {
+	// Files maps each colliding id to the fragments defining it, sorted.
+	Files map[string][]string
+}
+
+func (e *IDCollisionError) Error() string {
+	ids := slices.Sorted(maps.Keys(e.Files))
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("%s (%s)", id, strings.Join(e.Files[id], ", "))
+	}
+	return "entity ids defined in more than one world file: " + strings.Join(parts, "; ")
+}
+
+// isFragment reports whether name is a world fragment: a YAML or JSON
+// file that isn't hidden, which also skips our own temp files.
+func isFragment(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json", ".ndjson", ".jsonl":
+		return true
+	}
+	return false
+}
+
+// SetWorldDir persists world state as fragments in dir instead of a single
+// world file, grouped by grouping. See LoadFromDir.
+func (s *WorldServer) SetWorldDir(dir string, grouping WorldGrouping) {
+	s.worldDir = dir
+	s.worldGrouping = grouping
+}
+
+// LoadFromDir loads every world fragment in dir, in name order. Fragments
+// are YAML or JSON files in any of the world file formats; subdirectories
+// and hidden files are skipped. An entity id defined in more than one
+// fragment is an *IDCollisionError, and nothing is loaded.
+//
+// The fragment each entity came from is remembered, so FlushToFile can
+// write it back there.
+func (s *WorldServer) LoadFromDir(dir string) error {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read world directory: %w", err)
+	}
+	if err := s.loadPins(pinsFile(dir)); err != nil {
+		return err
+	}
+
+	var all []*pb.Entity
+	sources := make(map[string]string)
+	fragments := make(map[string]bool)
+	collisions := make(map[string][]string)
+	for _, de := range dirEntries {
+		name := de.Name()
+		if de.IsDir() || !isFragment(name) {
+			continue
+		}
+		fragments[name] = true
+
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		entities, err := parseEntitiesFormat(b, worldFormatOf(name))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		for _, e := range entities {
+			if prev, ok := sources[e.Id]; ok && prev != name {
+				if len(collisions[e.Id]) == 0 {
+					collisions[e.Id] = []string{prev}
+				}
+				if !slices.Contains(collisions[e.Id], name) {
+					collisions[e.Id] = append(collisions[e.Id], name)
+				}
+				continue
+			}
+			sources[e.Id] = name
+		}
+		all = append(all, entities...)
+	}
+	if len(collisions) > 0 {
+		return &IDCollisionError{Files: collisions}
+	}
+
+	s.worldDirState.mu.Lock()
+	s.worldDirState.sources = sources
+	s.worldDirState.fragments = fragments
+	s.worldDirState.mu.Unlock()
+
+	s.l.Lock()
+	s.loadEntities(all)
+	s.l.Unlock()
+
+	slog.Info("loaded entities from directory", "count", len(all), "fragments", len(fragments), "path", dir)
+	return nil
+}
+
+// fragmentFor returns the fragment e is written to. The caller must hold
+// worldDirState.mu.
+func (s *WorldServer) fragmentFor(e *pb.Entity) string {
+	if s.worldGrouping == GroupByController {
+		if c := e.Controller.GetId(); c != "" {
+			return fragmentName(c) + ".yaml"
+		}
+		return defaultFragment
+	}
+	if src, ok := s.worldDirState.sources[e.Id]; ok {
+		return src
+	}
+	return defaultFragment
+}
+
+// fragmentName makes a controller id safe to use as a file name.
+func fragmentName(id string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, id)
+}
+
+// flushToDir writes the persisted entities to their fragments in
+// worldDir, each atomically and in the format of its extension. Fragments
+// left without entities are emptied.
+func (s *WorldServer) flushToDir() error {
+	entities := s.persistedEntities()
+
+	st := &s.worldDirState
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.init()
+
+	groups := make(map[string][]*pb.Entity)
+	for name := range st.fragments {
+		groups[name] = nil
+	}
+	for _, e := range entities {
+		name := s.fragmentFor(e)
+		groups[name] = append(groups[name], e)
+		st.sources[e.Id] = name
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(groups)) {
+		out, err := marshalEntities(groups[name], worldFormatOf(name))
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", name, err)
+		}
+		err = writeFileAtomic(filepath.Join(s.worldDir, name), s.persistFsync, func(w io.Writer) error {
+			_, err := w.Write(out)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		st.fragments[name] = true
+	}
+	s.counters.flushes.Add(1)
+	return nil
+}
+
+// resetWorldDir empties every fragment of the world directory and writes
+// back keep, if set, for a hard reset. The caller must hold s.l.
+func (s *WorldServer) resetWorldDir(keep *pb.Entity) {
+	st := &s.worldDirState
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.init()
+
+	keepIn := ""
+	if keep != nil {
+		keepIn = s.fragmentFor(keep)
+		st.fragments[keepIn] = true
+	}
+	for name := range st.fragments {
+		var out []byte
+		if name == keepIn {
+			var err error
+			if out, err = marshalEntities([]*pb.Entity{keep}, worldFormatOf(name)); err != nil {
+				slog.Warn("failed to marshal mission entity during hard reset", "error", err)
+			}
+		}
+		if err := os.WriteFile(filepath.Join(s.worldDir, name), out, 0644); err != nil {
+			slog.Warn("failed to truncate world fragment during hard reset", "file", name, "error", err)
+		}
+	}
+	clear(st.sources)
+	if keep != nil {
+		st.sources[keep.Id] = keepIn
+	}
+}
@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func writeFragments(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadFromDir_MergesFragments(t *testing.T) {
+	dir := writeFragments(t, map[string]string{
+		"10-sensors.yaml": "id: radar.1\nlabel: north radar\n---\nid: radar.2\n",
+		"20-cameras.json": `[{"id": "cam.1", "label": "gate"}]`,
+		"30-assets.jsonl": `{"id": "asset.1"}` + "\n",
+		"notes.txt":       "not a world file",
+		".hidden.yaml":    "id: hidden.1\n",
+	})
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "nested.yaml"), []byte("id: nested.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := testWorld(map[string]*pb.Entity{})
+	if err := w.LoadFromDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"radar.1", "radar.2", "cam.1", "asset.1"} {
+		if w.GetHead(id) == nil {
+			t.Errorf("%s not loaded", id)
+		}
+	}
+	for _, id := range []string{"hidden.1", "nested.1"} {
+		if w.GetHead(id) != nil {
+			t.Errorf("%s should be skipped", id)
+		}
+	}
+	if got := w.GetHead("cam.1").GetLabel(); got != "gate" {
+		t.Errorf("cam.1 label %q", got)
+	}
+	if src := w.worldDirState.sources["cam.1"]; src != "20-cameras.json" {
+		t.Errorf("cam.1 source %q", src)
+	}
+}
+
+func TestLoadFromDir_ReportsCollisions(t *testing.T) {
+	dir := writeFragments(t, map[string]string{
+		"a.yaml": "id: radar.1\n---\nid: cam.1\n",
+		"b.yaml": "id: radar.1\n",
+		"c.json": `[{"id": "radar.1"}, {"id": "cam.1"}, {"id": "asset.1"}]`,
+	})
+
+	w := testWorld(map[string]*pb.Entity{})
+	err := w.LoadFromDir(dir)
+	var collision *IDCollisionError
+	if !errors.As(err, &collision) {
+		t.Fatalf("expected IDCollisionError, got %v", err)
+	}
+	if got := collision.Files["radar.1"]; !slices.Equal(got, []string{"a.yaml", "b.yaml", "c.json"}) {
+		t.Errorf("radar.1 collides in %v", got)
+	}
+	if got := collision.Files["cam.1"]; !slices.Equal(got, []string{"a.yaml", "c.json"}) {
+		t.Errorf("cam.1 collides in %v", got)
+	}
+	if _, ok := collision.Files["asset.1"]; ok {
+		t.Error("asset.1 doesn't collide")
+	}
+	if !strings.Contains(err.Error(), "cam.1 (a.yaml, c.json)") {
+		t.Errorf("error %q should name the files", err)
+	}
+	if w.GetHead("asset.1") != nil {
+		t.Error("nothing should load when fragments collide")
+	}
+}
+
+func TestLoadFromDir_NonExistent(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	if err := w.LoadFromDir(filepath.Join(t.TempDir(), "missing")); err != nil {
+		t.Errorf("missing directory should return nil, got %v", err)
+	}
+}
+
+func localConfigEntity(id, controller string) *pb.Entity {
+	value, _ := structpb.NewStruct(map[string]interface{}{"key": id})
+	return &pb.Entity{
+		Id:         id,
+		Controller: &pb.Controller{Id: proto.String(controller), Node: proto.String("n1")},
+		Config:     &pb.ConfigurationComponent{Value: value},
+	}
+}
+
+func TestFlushToDir_WritesBackToSource(t *testing.T) {
+	dir := writeFragments(t, map[string]string{
+		"sensors.yaml": "id: radar.1\ncontroller:\n  id: radar\n  node: n1\nconfig:\n  value:\n    key: radar.1\n",
+		"cameras.json": `[{"id": "cam.1", "controller": {"id": "camera", "node": "n1"}, "config": {"value": {"key": "cam.1"}}}]`,
+		"old.yaml":     "id: gone.1\ncontroller:\n  id: radar\n  node: n1\nconfig:\n  value:\n    key: gone.1\n",
+	})
+
+	w := testWorld(map[string]*pb.Entity{})
+	w.nodeID = "n1"
+	w.SetWorldDir(dir, GroupBySource)
+	if err := w.LoadFromDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	w.deleteEntity("gone.1")
+	w.initEntity(localConfigEntity("new.1", "radar"))
+
+	if err := w.FlushToFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) string {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	if s := read("sensors.yaml"); !strings.Contains(s, "radar.1") || strings.Contains(s, "cam.1") {
+		t.Errorf("sensors.yaml = %q", s)
+	}
+	if s := read("cameras.json"); !strings.HasPrefix(s, "[") || !strings.Contains(s, `"cam.1"`) {
+		t.Errorf("cameras.json should stay JSON with cam.1, got %q", s)
+	}
+	if s := read("old.yaml"); s != "" {
+		t.Errorf("old.yaml should be emptied, got %q", s)
+	}
+	if s := read(defaultFragment); !strings.Contains(s, "new.1") {
+		t.Errorf("new entity should go to %s, got %q", defaultFragment, s)
+	}
+
+	// What was written loads back without collisions.
+	w2 := testWorld(map[string]*pb.Entity{})
+	if err := w2.LoadFromDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"radar.1", "cam.1", "new.1"} {
+		if w2.GetHead(id) == nil {
+			t.Errorf("%s not reloaded", id)
+		}
+	}
+	if w2.GetHead("gone.1") != nil {
+		t.Error("deleted entity came back")
+	}
+}
+
+func TestFlushToDir_GroupByController(t *testing.T) {
+	dir := t.TempDir()
+	w := testWorld(map[string]*pb.Entity{
+		"radar.1": localConfigEntity("radar.1", "radar"),
+		"radar.2": localConfigEntity("radar.2", "radar"),
+		"cam.1":   localConfigEntity("cam.1", "onvif/camera"),
+	})
+	w.nodeID = "n1"
+	w.SetWorldDir(dir, GroupByController)
+
+	if err := w.FlushToFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "radar.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); !strings.Contains(s, "radar.1") || !strings.Contains(s, "radar.2") {
+		t.Errorf("radar.yaml = %q", s)
+	}
+	b, err = os.ReadFile(filepath.Join(dir, "onvif_camera.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); !strings.Contains(s, "cam.1") {
+		t.Errorf("onvif_camera.yaml = %q", s)
+	}
+}
+
+func TestParseWorldGrouping(t *testing.T) {
+	for in, want := range map[string]WorldGrouping{"": GroupBySource, "source": GroupBySource, "controller": GroupByController} {
+		if got, err := ParseWorldGrouping(in); err != nil || got != want {
+			t.Errorf("ParseWorldGrouping(%q) = %v, %v", in, got, err)
+		}
+	}
+	if _, err := ParseWorldGrouping("file"); err == nil {
+		t.Error("expected error for unknown grouping")
+	}
+}
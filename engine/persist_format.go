@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// worldFormat is the encoding of a world file.
+type worldFormat int
+
+const (
+	// formatYAML is multi-document YAML, one entity per document.
+	formatYAML worldFormat = iota
+	// formatJSON is a JSON array of entities.
+	formatJSON
+	// formatNDJSON is one JSON entity per line.
+	formatNDJSON
+)
+
+// worldFormatOf picks the format of a world file by its extension; anything
+// that isn't .json, .ndjson or .jsonl is YAML.
+func worldFormatOf(path string) worldFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return formatJSON
+	case ".ndjson", ".jsonl":
+		return formatNDJSON
+	default:
+		return formatYAML
+	}
+}
+
+// parseEntitiesFormat parses a world file of the given format.
+func parseEntitiesFormat(b []byte, format worldFormat) ([]*pb.Entity, error) {
+	switch format {
+	case formatJSON:
+		var docs []json.RawMessage
+		if err := json.Unmarshal(b, &docs); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON array: %w", err)
+		}
+		return unmarshalEntities(docs)
+	case formatNDJSON:
+		var docs []json.RawMessage
+		dec := json.NewDecoder(bytes.NewReader(b))
+		for {
+			var doc json.RawMessage
+			err := dec.Decode(&doc)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode JSON line %d: %w", len(docs)+1, err)
+			}
+			docs = append(docs, doc)
+		}
+		return unmarshalEntities(docs)
+	default:
+		return ParseEntities(b)
+	}
+}
+
+func unmarshalEntities(docs []json.RawMessage) ([]*pb.Entity, error) {
+	entities := make([]*pb.Entity, 0, len(docs))
+	for _, doc := range docs {
+		entity := &pb.Entity{}
+		if err := protojson.Unmarshal(doc, entity); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal entity: %w", err)
+		}
+		entities = append(entities, entity)
+	}
+	return entities, nil
+}
+
+// marshalEntities encodes entities in the given format, with the same
+// canonical field order in all of them.
+func marshalEntities(entities []*pb.Entity, format worldFormat) ([]byte, error) {
+	switch format {
+	case formatJSON:
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		for i, entity := range entities {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			obj, err := entityToOrderedJSON(entity)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(obj)
+		}
+		buf.WriteByte(']')
+
+		var out bytes.Buffer
+		if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+			return nil, err
+		}
+		out.WriteByte('\n')
+		return out.Bytes(), nil
+	case formatNDJSON:
+		var buf bytes.Buffer
+		for _, entity := range entities {
+			obj, err := entityToOrderedJSON(entity)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(obj)
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), nil
+	default:
+		return entitiesToYAML(entities)
+	}
+}
+
+// entityToOrderedJSON encodes entity as a compact JSON object with its
+// fields in canonical order.
+func entityToOrderedJSON(entity *pb.Entity) ([]byte, error) {
+	jsonBytes, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(entity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal entity %s to JSON: %w", entity.Id, err)
+	}
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(jsonBytes, &data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON for entity %s: %w", entity.Id, err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range orderedKeys(data) {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		if err := json.Compact(&buf, data[key]); err != nil {
+			return nil, fmt.Errorf("failed to compact field %s of entity %s: %w", key, entity.Id, err)
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -1,14 +1,20 @@
 package engine
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	pb "github.com/projectqai/proto/go"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestParseEntities_SingleEntity(t *testing.T) {
@@ -461,3 +467,257 @@ func TestFlushToFile_SortsByID(t *testing.T) {
 		t.Error("entities should be sorted by ID")
 	}
 }
+
+func TestWorldFormatOf(t *testing.T) {
+	for path, want := range map[string]worldFormat{
+		"world.yaml":   formatYAML,
+		"world.yml":    formatYAML,
+		"world":        formatYAML,
+		"world.json":   formatJSON,
+		"WORLD.JSON":   formatJSON,
+		"world.ndjson": formatNDJSON,
+		"world.jsonl":  formatNDJSON,
+	} {
+		if got := worldFormatOf(path); got != want {
+			t.Errorf("%s: got %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestFlushToFile_RoundtripFormats(t *testing.T) {
+	config, _ := structpb.NewStruct(map[string]interface{}{"name": "alpha", "port": 8080})
+
+	for _, name := range []string{"world.yaml", "world.json", "world.ndjson"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			w := testWorld(map[string]*pb.Entity{
+				"e1":     {Id: "e1", Label: proto.String("tank"), Controller: &pb.Controller{Node: proto.String("n1")}, Config: &pb.ConfigurationComponent{Value: config}},
+				"e2":     {Id: "e2", Controller: &pb.Controller{Node: proto.String("n1")}, Device: &pb.DeviceComponent{State: pb.DeviceState_DeviceStateActive}},
+				"remote": {Id: "remote", Controller: &pb.Controller{Node: proto.String("n2")}, Config: &pb.ConfigurationComponent{Value: config}},
+			})
+			w.worldFile = path
+			w.nodeID = "n1"
+
+			if err := w.FlushToFile(); err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(b), "remote") {
+				t.Error("non-local entity persisted")
+			}
+			if strings.Index(string(b), "id") > strings.Index(string(b), "label") {
+				t.Errorf("fields not in canonical order:\n%s", b)
+			}
+			if strings.HasSuffix(name, ".ndjson") && strings.Count(string(b), "\n") != 2 {
+				t.Errorf("expected one line per entity:\n%s", b)
+			}
+
+			w2 := testWorld(map[string]*pb.Entity{})
+			if err := w2.LoadFromFile(path); err != nil {
+				t.Fatal(err)
+			}
+			if w2.EntityCount() != 2 {
+				t.Fatalf("expected 2 entities after roundtrip, got %d", w2.EntityCount())
+			}
+			e1 := w2.GetHead("e1")
+			if e1.GetLabel() != "tank" || !proto.Equal(e1.GetConfig().GetValue(), config) {
+				t.Errorf("e1 changed in roundtrip: %v", e1)
+			}
+			if w2.GetHead("e2").GetDevice().GetState() != pb.DeviceState_DeviceStateActive {
+				t.Error("e2 device lost in roundtrip")
+			}
+		})
+	}
+}
+
+func TestParseEntitiesFormat_Invalid(t *testing.T) {
+	if _, err := parseEntitiesFormat([]byte(`{"id": "e1"}`), formatJSON); err == nil {
+		t.Error("JSON world file must be an array")
+	}
+	if _, err := parseEntitiesFormat([]byte("{\"id\": \"e1\"}\n{\"id\":"), formatNDJSON); err == nil {
+		t.Error("truncated line should fail")
+	}
+	if _, err := parseEntitiesFormat([]byte(`[{"bogus": 1}]`), formatJSON); err == nil {
+		t.Error("unknown field should fail like in YAML")
+	}
+}
+
+func TestWriteFileAtomic_InterruptedWriteKeepsOriginal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "world.yaml")
+	original := []byte("id: e1\n")
+	if err := os.WriteFile(path, original, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	errCrash := errors.New("disk full")
+	err := writeFileAtomic(path, true, func(w io.Writer) error {
+		_, _ = w.Write([]byte("id: e2\nlab"))
+		return errCrash
+	})
+	if !errors.Is(err, errCrash) {
+		t.Fatalf("got %v, want the write error", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(original) {
+		t.Errorf("world file changed by failed write: %q", b)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("temp file left behind: %v", entries)
+	}
+
+	if err := writeFileAtomic(path, true, func(w io.Writer) error {
+		_, err := w.Write([]byte("id: e2\n"))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "id: e2\n" {
+		t.Errorf("world file not replaced: %q", b)
+	}
+}
+
+func TestAutosave_CoalescesBursts(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	w.worldFile = filepath.Join(t.TempDir(), "world.yaml")
+	w.nodeID = "n1"
+	const debounce = 100 * time.Millisecond
+	w.SetPersistDebounce(debounce)
+	w.StartPeriodicFlush(time.Hour)
+
+	config, _ := structpb.NewStruct(map[string]interface{}{"name": "alpha"})
+	start := time.Now()
+	for i := range 200 {
+		e := &pb.Entity{Id: fmt.Sprintf("e%d", i%10), Config: &pb.ConfigurationComponent{Value: config}}
+		if _, err := w.Push(context.Background(), peerRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{e}})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// At most one write per debounce window, plus the one closing it.
+	maxWrites := uint64(time.Since(start)/debounce) + 2
+
+	deadline := time.Now().Add(2 * time.Second)
+	for w.counters.flushes.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no autosave after pushes")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if n := w.counters.flushes.Load(); n > maxWrites {
+		t.Errorf("200 pushes caused %d writes, want at most %d", n, maxWrites)
+	}
+
+	w2 := testWorld(map[string]*pb.Entity{})
+	if err := w2.LoadFromFile(w.worldFile); err != nil {
+		t.Fatal(err)
+	}
+	if w2.EntityCount() != 10 {
+		t.Errorf("autosaved %d entities, want 10", w2.EntityCount())
+	}
+
+	// Pushes of entities that aren't persisted don't trigger a save.
+	before := w.counters.flushes.Load()
+	if _, err := w.Push(context.Background(), peerRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{
+		{Id: "track", Geo: &pb.GeoSpatialComponent{}, Lifetime: &pb.Lifetime{Until: timestamppb.New(time.Now().Add(time.Minute))}},
+	}})); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if n := w.counters.flushes.Load(); n != before {
+		t.Errorf("transient entity caused %d writes", n-before)
+	}
+}
+
+func TestEntitiesToYAML_RoundTripComponents(t *testing.T) {
+	config, err := structpb.NewStruct(map[string]any{"port": 5631, "rate": 0.1, "hosts": []any{"a", "b"}, "on": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	icao := uint32(0x3c6444)
+	mmsi := uint32(211234560)
+	entity := &pb.Entity{
+		Id:    "e1",
+		Label: ptr("2026-01-01"),
+		Lifetime: &pb.Lifetime{
+			From:  timestamppb.New(time.Date(2026, 1, 1, 12, 0, 0, 123456789, time.UTC)),
+			Until: timestamppb.New(time.Date(2026, 1, 1, 13, 0, 0, 0, time.UTC)),
+		},
+		Geo: &pb.GeoSpatialComponent{
+			Latitude:   48.123456789012345,
+			Longitude:  -0.000001,
+			Altitude:   proto.Float64(1e21),
+			Covariance: &pb.CovarianceMatrix{Mxx: proto.Float64(25), Myy: proto.Float64(2500)},
+		},
+		Shape: &pb.GeoShapeComponent{Geometry: &pb.Geometry{Planar: &pb.PlanarGeometry{
+			Plane: &pb.PlanarGeometry_Polygon{Polygon: &pb.PlanarPolygon{Outer: &pb.PlanarRing{Points: []*pb.PlanarPoint{
+				{Longitude: 11, Latitude: 48},
+				{Longitude: 12, Latitude: 48},
+				{Longitude: 12, Latitude: 49},
+				{Longitude: 11, Latitude: 48},
+			}}}},
+		}}},
+		Transponder: &pb.TransponderComponent{
+			Adsb: &pb.TransponderADSB{IcaoAddress: &icao, FlightId: ptr("0123")},
+			Ais:  &pb.TransponderAIS{Mmsi: &mmsi, Callsign: ptr("yes")},
+		},
+		Kinematics: &pb.KinematicsComponent{VelocityEnu: &pb.KinematicsEnu{
+			East:  proto.Float64(12.5),
+			North: proto.Float64(-3.25),
+			Covariance: &pb.CovarianceMatrix{
+				Mxx: proto.Float64(0.1),
+				Myy: proto.Float64(1.0 / 3),
+				Mzz: proto.Float64(4),
+			},
+		}},
+		Config: &pb.ConfigurationComponent{Value: config},
+	}
+
+	for _, format := range []worldFormat{formatYAML, formatJSON, formatNDJSON} {
+		b, err := marshalEntities([]*pb.Entity{entity}, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := parseEntitiesFormat(b, format)
+		if err != nil {
+			t.Fatalf("format %d: %v\n%s", format, err, b)
+		}
+		if len(got) != 1 || !proto.Equal(got[0], entity) {
+			t.Errorf("format %d does not round-trip:\n%s", format, b)
+		}
+	}
+}
+
+func TestParseEntities_HandWrittenScalars(t *testing.T) {
+	// Unquoted timestamps and YAML-only number forms reach protojson as
+	// written.
+	entities, err := ParseEntities([]byte(`id: e1
+lifetime:
+  from: 2026-01-01T12:00:00Z
+transponder:
+  adsb:
+    icao_address: 0x3c6444
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := entities[0]
+	if got := e.GetLifetime().GetFrom().AsTime(); !got.Equal(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("from %v", got)
+	}
+	if got := e.GetTransponder().GetAdsb().GetIcaoAddress(); got != 0x3c6444 {
+		t.Errorf("icao %x", got)
+	}
+}
@@ -0,0 +1,238 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The YAML world format is a front-end to protojson: an entity is written
+// by converting its protojson encoding to YAML and read by converting the
+// YAML back to JSON for protojson. Both conversions go node by node rather
+// than through Go maps, so numbers keep their exact literal, nested fields
+// keep protojson's order, and scalars that YAML would resolve to other
+// types, such as timestamps, reach protojson as they were written.
+
+// jsonToYAMLNode converts a protojson encoded entity to a YAML mapping.
+// Top-level fields are put in canonical order (see orderedKeys); nested
+// objects keep the order protojson wrote them in.
+func jsonToYAMLNode(b []byte) (*yaml.Node, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	node, err := decodeJSONNode(dec)
+	if err != nil {
+		return nil, err
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, errors.New("entity is not a JSON object")
+	}
+
+	fields := make(map[string][2]*yaml.Node, len(node.Content)/2)
+	for i := 0; i < len(node.Content); i += 2 {
+		fields[node.Content[i].Value] = [2]*yaml.Node{node.Content[i], node.Content[i+1]}
+	}
+	node.Content = node.Content[:0]
+	for _, key := range orderedKeys(fields) {
+		node.Content = append(node.Content, fields[key][0], fields[key][1])
+	}
+	return node, nil
+}
+
+// decodeJSONNode reads the next JSON value from dec as a YAML node.
+func decodeJSONNode(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		switch v {
+		case '{':
+			node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				val, err := decodeJSONNode(dec)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, stringNode(key.(string)), val)
+			}
+			_, err := dec.Token() // '}'
+			return node, err
+		case '[':
+			node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			for dec.More() {
+				val, err := decodeJSONNode(dec)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, val)
+			}
+			_, err := dec.Token() // ']'
+			return node, err
+		}
+		return nil, fmt.Errorf("unexpected %v", v)
+	case string:
+		return stringNode(v), nil
+	case json.Number:
+		tag := "!!float"
+		if _, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			tag = "!!int"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: string(v)}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}, nil
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+	return nil, fmt.Errorf("unexpected JSON token %v", tok)
+}
+
+// stringNode is a string scalar. The encoder quotes it if it would read
+// back as another type.
+func stringNode(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
+
+// yamlNodeToJSON converts a YAML document to JSON for protojson.
+func yamlNodeToJSON(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeYAMLNodeJSON(&buf, node); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeYAMLNodeJSON(w *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			w.WriteString("null")
+			return nil
+		}
+		return writeYAMLNodeJSON(w, node.Content[0])
+	case yaml.AliasNode:
+		return writeYAMLNodeJSON(w, node.Alias)
+	case yaml.MappingNode:
+		w.WriteByte('{')
+		for i := 0; i < len(node.Content); i += 2 {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: mapping key is not a scalar", key.Line)
+			}
+			writeJSONString(w, key.Value)
+			w.WriteByte(':')
+			if err := writeYAMLNodeJSON(w, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		w.WriteByte('}')
+		return nil
+	case yaml.SequenceNode:
+		w.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := writeYAMLNodeJSON(w, item); err != nil {
+				return err
+			}
+		}
+		w.WriteByte(']')
+		return nil
+	case yaml.ScalarNode:
+		return writeYAMLScalarJSON(w, node)
+	}
+	return fmt.Errorf("line %d: unsupported YAML node", node.Line)
+}
+
+func writeYAMLScalarJSON(w *bytes.Buffer, node *yaml.Node) error {
+	switch node.ShortTag() {
+	case "!!null":
+		w.WriteString("null")
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err != nil {
+			return err
+		}
+		w.WriteString(strconv.FormatBool(b))
+	case "!!int":
+		// Decoding handles 0x, 0o, 0b and _ separators; the result is
+		// exact whether it fits int64 or only uint64.
+		var v any
+		if err := node.Decode(&v); err != nil {
+			return err
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		w.Write(b)
+	case "!!float":
+		var f float64
+		if err := node.Decode(&f); err != nil {
+			return err
+		}
+		// protojson spells the special values as strings.
+		switch {
+		case math.IsNaN(f):
+			writeJSONString(w, "NaN")
+		case math.IsInf(f, 1):
+			writeJSONString(w, "Infinity")
+		case math.IsInf(f, -1):
+			writeJSONString(w, "-Infinity")
+		default:
+			w.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+	default:
+		// !!str, and !!timestamp and !!binary as written: protojson
+		// parses both from their text.
+		writeJSONString(w, node.Value)
+	}
+	return nil
+}
+
+func writeJSONString(w *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	w.Write(b)
+}
+
+// decodeYAMLDocuments splits multi-document YAML into its documents,
+// skipping empty ones.
+func decodeYAMLDocuments(b []byte) ([]*yaml.Node, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	var docs []*yaml.Node
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode YAML document: %w", err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		root := doc.Content[0]
+		if root.ShortTag() == "!!null" || (root.Kind == yaml.MappingNode && len(root.Content) == 0) {
+			continue
+		}
+		if root.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("line %d: YAML document is not a mapping", root.Line)
+		}
+		docs = append(docs, root)
+	}
+}
@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"connectrpc.com/connect"
+)
+
+// SetPinned pins or unpins entities. The GC never expires a pinned entity
+// or any of its components, even past their Until; the lifetimes are
+// left as they are, so the entity still federates and reads with the
+// lifetime it was pushed with. ExpireEntity removes a pinned entity as
+// usual, and the pin goes with it: an entity pushed again under the same
+// id is not pinned.
+//
+// With persistence, pins are saved next to the world (see pinsFile) and
+// read back before anything is loaded, so a pinned entity that is loaded
+// or pushed again after a restart is pinned before the GC first runs.
+//
+// All ids must exist; otherwise nothing is pinned and the error names the
+// first missing id.
+func (s *WorldServer) SetPinned(ctx context.Context, ids []string, pinned bool) error {
+	if len(ids) == 0 {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("ids must be set"))
+	}
+
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	for _, id := range ids {
+		if _, ok := s.head[id]; !ok {
+			return connect.NewError(connect.CodeNotFound, fmt.Errorf("entity with id %s not found", id))
+		}
+	}
+	if s.pinned == nil {
+		s.pinned = make(map[string]bool)
+	}
+	for _, id := range ids {
+		if pinned {
+			s.pinned[id] = true
+		} else {
+			delete(s.pinned, id)
+		}
+	}
+	s.notifyPersist()
+	slog.DebugContext(ctx, "entities pinned", "ids", ids, "pinned", pinned)
+	return nil
+}
+
+// pinsFile returns where the pins of the world at path are saved: a
+// hidden file in a world directory, which is not taken for a fragment, or
+// a file next to a world file.
+func pinsFile(path string) string {
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		return filepath.Join(path, ".pins.json")
+	}
+	return path + ".pins.json"
+}
+
+// loadPins pins the ids saved in path, if it exists.
+func (s *WorldServer) loadPins(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read pins: %w", err)
+	}
+	var ids []string
+	if err := json.Unmarshal(b, &ids); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	s.l.Lock()
+	defer s.l.Unlock()
+	if s.pinned == nil {
+		s.pinned = make(map[string]bool)
+	}
+	for _, id := range ids {
+		s.pinned[id] = true
+	}
+	return nil
+}
+
+// flushPins saves the pinned ids to path, or removes it if there are none.
+func (s *WorldServer) flushPins(path string) error {
+	s.l.RLock()
+	ids := make([]string, 0, len(s.pinned))
+	for id := range s.pinned {
+		ids = append(ids, id)
+	}
+	s.l.RUnlock()
+
+	if len(ids) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove pins: %w", err)
+		}
+		return nil
+	}
+	slices.Sort(ids)
+	b, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, s.persistFsync, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+}
+
+// IsPinned reports whether the entity with the given id is pinned.
+func (s *WorldServer) IsPinned(id string) bool {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	return s.pinned[id]
+}
+
+// pinRequest is the body of POST /pin.
+type pinRequest struct {
+	IDs    []string `json:"ids"`
+	Pinned bool     `json:"pinned"`
+}
+
+// handlePin serves SetPinned over HTTP:
+//
+//	POST /pin {"ids": ["base.home"], "pinned": true}
+//
+// The request is checked by the authorizer as method "SetPinned".
+func (s *WorldServer) handlePin(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorizeHTTP(r, "SetPinned"); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	var req pinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid pin request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.SetPinned(r.Context(), req.IDs, req.Pinned); err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
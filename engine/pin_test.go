@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func pinWorld(until time.Time) *WorldServer {
+	return testWorld(map[string]*pb.Entity{
+		"base.home": {
+			Id:       "base.home",
+			Geo:      &pb.GeoSpatialComponent{Latitude: 48, Longitude: 11},
+			Lifetime: &pb.Lifetime{From: timestamppb.Now(), Until: timestamppb.New(until)},
+		},
+		"base.away": {
+			Id:       "base.away",
+			Geo:      &pb.GeoSpatialComponent{Latitude: 54, Longitude: 10},
+			Lifetime: &pb.Lifetime{From: timestamppb.Now(), Until: timestamppb.New(until)},
+		},
+	})
+}
+
+func TestPin_SurvivesGC(t *testing.T) {
+	until := time.Now().Add(-time.Second)
+	w := pinWorld(until)
+	if err := w.SetPinned(context.Background(), []string{"base.home"}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	w.GC()
+
+	home := w.GetHead("base.home")
+	if home == nil {
+		t.Fatal("pinned entity expired")
+	}
+	if home.Geo == nil {
+		t.Error("pinned entity lost its components")
+	}
+	if got := home.GetLifetime().GetUntil().AsTime(); !got.Equal(until) {
+		t.Errorf("pinned entity's until changed to %v", got)
+	}
+	if w.GetHead("base.away") != nil {
+		t.Error("unpinned entity survived past its until")
+	}
+
+	// Unpinning hands the entity back to the GC.
+	if err := w.SetPinned(context.Background(), []string{"base.home"}, false); err != nil {
+		t.Fatal(err)
+	}
+	w.GC()
+	if w.GetHead("base.home") != nil {
+		t.Error("unpinned entity survived past its until")
+	}
+}
+
+func TestPin_ExpireEntityStillRemoves(t *testing.T) {
+	w := pinWorld(time.Now().Add(time.Hour))
+	if err := w.SetPinned(context.Background(), []string{"base.home"}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.ExpireEntity(context.Background(), peerRequest(&pb.ExpireEntityRequest{Id: "base.home"})); err != nil {
+		t.Fatal(err)
+	}
+	w.GC()
+	if w.GetHead("base.home") != nil {
+		t.Fatal("explicit expire didn't remove the pinned entity")
+	}
+	if w.IsPinned("base.home") {
+		t.Error("pin outlived the entity")
+	}
+}
+
+func TestPin_UnknownID(t *testing.T) {
+	w := pinWorld(time.Now().Add(-time.Second))
+	err := w.SetPinned(context.Background(), []string{"base.home", "missing"}, true)
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Fatalf("got %v, want NotFound", err)
+	}
+	if w.IsPinned("base.home") {
+		t.Error("nothing should be pinned when an id is missing")
+	}
+}
+
+func TestPin_SurvivesReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "world.yaml")
+	w := pinWorld(time.Now().Add(-time.Second))
+	w.worldFile = path
+	if err := w.SetPinned(context.Background(), []string{"base.home"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.FlushToFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	// After a restart the reference entities come back with their until
+	// long past, e.g. from the defaults.
+	restarted := testWorld(nil)
+	restarted.worldFile = path
+	if err := restarted.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	defaults := `id: base.home
+geo: {latitude: 48, longitude: 11}
+lifetime: {until: "2000-01-01T00:00:00Z"}
+---
+id: base.away
+geo: {latitude: 54, longitude: 10}
+lifetime: {until: "2000-01-01T00:00:00Z"}
+`
+	if err := restarted.LoadDefaults([]byte(defaults)); err != nil {
+		t.Fatal(err)
+	}
+	restarted.GC()
+
+	if restarted.GetHead("base.home") == nil {
+		t.Error("pinned entity expired after a reload")
+	}
+	if restarted.GetHead("base.away") != nil {
+		t.Error("unpinned entity survived past its until")
+	}
+}
@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+var trackFilter = &pb.EntityFilter{Component: []uint32{uint32(pb.EntityComponent_EntityComponentTrack)}}
+
+func TestRequiredComponents(t *testing.T) {
+	if got := requiredComponents(trackFilter); len(got) != 1 {
+		t.Errorf("component filter: %v", got)
+	}
+	for name, f := range map[string]*pb.EntityFilter{
+		"nil":  nil,
+		"or":   {Component: trackFilter.Component, Or: []*pb.EntityFilter{{Id: ptr("a")}}},
+		"not":  {Component: trackFilter.Component, Not: &pb.EntityFilter{Id: ptr("a")}},
+		"geo":  {Component: trackFilter.Component, Geo: &pb.GeoFilter{}},
+		"none": {Id: ptr("a")},
+	} {
+		if got := requiredComponents(f); got != nil {
+			t.Errorf("%s: got %v, want full evaluation", name, got)
+		}
+	}
+}
+
+func TestConsumer_Prefilter(t *testing.T) {
+	geoOnly := &pb.Entity{Id: "e1", Geo: &pb.GeoSpatialComponent{}}
+	w := testWorld(map[string]*pb.Entity{"e1": geoOnly})
+
+	c := NewConsumer(w, nil, trackFilter)
+	w.bus.Register(c)
+	defer w.bus.Unregister(c)
+
+	w.bus.Dirty("e1", geoOnly, pb.EntityChange_EntityChangeUpdated)
+	if n := c.queueDepth(); n != 0 {
+		t.Errorf("change without required component queued (%d)", n)
+	}
+
+	w.bus.Dirty("e1", &pb.Entity{Id: "e1", Priority: ptr(pb.Priority_PriorityFlash)}, pb.EntityChange_EntityChangeUpdated)
+	if n := c.queueDepth(); n != 1 {
+		t.Errorf("flash change should bypass the prefilter (%d queued)", n)
+	}
+	c.popNext()
+
+	// Once sent, losing the component must still reach the sender so it
+	// can send Unobserved.
+	c.observed["e1"] = struct{}{}
+	w.bus.Dirty("e1", geoOnly, pb.EntityChange_EntityChangeUpdated)
+	if n := c.queueDepth(); n != 1 {
+		t.Errorf("change to an observed entity not queued (%d)", n)
+	}
+
+	// Nor may a change to an entity the sender has popped but not yet
+	// marked observed.
+	delete(c.observed, "e1")
+	c.popNext()
+	w.bus.Dirty("e1", geoOnly, pb.EntityChange_EntityChangeUpdated)
+	if id, _, _, ok := c.popNext(); !ok || id != "e1" {
+		t.Error("change to the popped entity was prefiltered")
+	}
+
+	or := NewConsumer(w, nil, &pb.EntityFilter{Or: []*pb.EntityFilter{trackFilter}})
+	or.markDirty("e1", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated, geoOnly)
+	if n := or.queueDepth(); n != 1 {
+		t.Errorf("or filter must not be prefiltered (%d queued)", n)
+	}
+}
+
+// BenchmarkBus_ComponentFilteredConsumers dirties entities without the
+// component 100 consumers filter on and reports how many full filter
+// evaluations the senders then run.
+func BenchmarkBus_ComponentFilteredConsumers(b *testing.B) {
+	for _, prefilter := range []bool{true, false} {
+		b.Run(fmt.Sprintf("prefilter=%v", prefilter), func(b *testing.B) {
+			entities := make(map[string]*pb.Entity)
+			for i := range 1000 {
+				id := fmt.Sprintf("e%d", i)
+				entities[id] = &pb.Entity{Id: id, Geo: &pb.GeoSpatialComponent{Latitude: 1}}
+			}
+			w := testWorld(entities)
+
+			consumers := make([]*Consumer, 100)
+			for i := range consumers {
+				c := NewConsumer(w, nil, trackFilter)
+				if !prefilter {
+					c.required = nil
+				}
+				w.bus.Register(c)
+				consumers[i] = c
+			}
+
+			matches := 0
+			b.ResetTimer()
+			for range b.N {
+				for id, e := range entities {
+					w.bus.Dirty(id, e, pb.EntityChange_EntityChangeUpdated)
+				}
+				for _, c := range consumers {
+					for {
+						id, _, _, ok := c.popNext()
+						if !ok {
+							break
+						}
+						matches++
+						c.matches(w.GetHead(id))
+					}
+				}
+			}
+			b.ReportMetric(float64(matches)/float64(b.N), "matches/op")
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydris/pkg/projection"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// PushLimits bound the size and content of each pushed entity, so that a
+// misbehaving client cannot exhaust memory with a single huge entity. A
+// zero field means no limit.
+type PushLimits struct {
+	// MaxEntityBytes caps the serialized size of an entity.
+	MaxEntityBytes int
+	// MaxShapePoints caps the number of vertices in an entity's geometry,
+	// summed over all polygon rings and lines.
+	MaxShapePoints int
+	// AllowedComponents, if not empty, are the field numbers of the only
+	// components an entity may carry. Structural fields such as id and
+	// lifetime are always allowed.
+	AllowedComponents []uint32
+}
+
+// SetPushLimits applies limits to every following push.
+func (s *WorldServer) SetPushLimits(limits PushLimits) {
+	var allowed map[uint32]bool
+	if len(limits.AllowedComponents) > 0 {
+		allowed = make(map[uint32]bool, len(limits.AllowedComponents))
+		for _, n := range limits.AllowedComponents {
+			allowed[n] = true
+		}
+	}
+
+	s.l.Lock()
+	s.pushLimits = limits
+	s.allowedComponents = allowed
+	s.l.Unlock()
+}
+
+// checkPushLimits returns a CodeInvalidArgument error if e exceeds the
+// configured push limits. The caller holds s.l.
+func (s *WorldServer) checkPushLimits(e *pb.Entity) error {
+	invalid := func(format string, args ...any) error {
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf(format, args...))
+	}
+
+	if limit := s.pushLimits.MaxEntityBytes; limit > 0 {
+		if size := proto.Size(e); size > limit {
+			return invalid("entity %s is %d bytes, more than the limit of %d", e.Id, size, limit)
+		}
+	}
+	if limit := s.pushLimits.MaxShapePoints; limit > 0 {
+		if n := countShapePoints(e.ProtoReflect()); n > limit {
+			return invalid("entity %s has %d shape points, more than the limit of %d", e.Id, n, limit)
+		}
+	}
+	if s.allowedComponents != nil {
+		fields := e.ProtoReflect().Descriptor().Fields()
+		for _, n := range projection.Components(e) {
+			if !s.allowedComponents[n] {
+				name := fields.ByNumber(protoreflect.FieldNumber(n)).Name()
+				return invalid("entity %s component %s is not allowed", e.Id, name)
+			}
+		}
+	}
+	return nil
+}
+
+// countShapePoints counts the vertices of the rings and lines in m and its
+// nested messages, i.e. the elements of every repeated "points" field.
+func countShapePoints(m protoreflect.Message) int {
+	n := 0
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.IsMap() || fd.Kind() != protoreflect.MessageKind {
+			return true
+		}
+		if !fd.IsList() {
+			n += countShapePoints(v.Message())
+			return true
+		}
+		l := v.List()
+		if fd.Name() == "points" {
+			n += l.Len()
+			return true
+		}
+		for i := 0; i < l.Len(); i++ {
+			n += countShapePoints(l.Get(i).Message())
+		}
+		return true
+	})
+	return n
+}
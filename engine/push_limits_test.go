@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func polygonEntity(id string, n int) *pb.Entity {
+	points := make([]*pb.PlanarPoint, n)
+	for i := range points {
+		points[i] = &pb.PlanarPoint{Longitude: 13 + float64(i)*1e-6, Latitude: 52}
+	}
+	return &pb.Entity{
+		Id: id,
+		Shape: &pb.GeoShapeComponent{Geometry: &pb.Geometry{Planar: &pb.PlanarGeometry{
+			Plane: &pb.PlanarGeometry_Polygon{Polygon: &pb.PlanarPolygon{
+				Outer: &pb.PlanarRing{Points: points},
+			}},
+		}}},
+	}
+}
+
+func TestPush_RejectsOversizedShape(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	w.SetPushLimits(PushLimits{MaxShapePoints: 100})
+
+	_, err := w.Push(context.Background(), peerRequest(&pb.EntityChangeRequest{
+		Changes: []*pb.Entity{polygonEntity("big", 101)},
+	}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Fatalf("got %v, want invalid argument", err)
+	}
+	if w.GetHead("big") != nil {
+		t.Error("rejected entity was applied")
+	}
+
+	if _, err := w.Push(context.Background(), peerRequest(&pb.EntityChangeRequest{
+		Changes: []*pb.Entity{polygonEntity("small", 100)},
+	})); err != nil {
+		t.Fatalf("shape at the limit: %v", err)
+	}
+}
+
+func TestPush_RejectsOversizedEntity(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	e := polygonEntity("big", 1000)
+	w.SetPushLimits(PushLimits{MaxEntityBytes: proto.Size(e) - 1})
+
+	_, err := w.Push(context.Background(), peerRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{e}}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Fatalf("got %v, want invalid argument", err)
+	}
+}
+
+func TestPush_RejectsDisallowedComponent(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	w.SetPushLimits(PushLimits{AllowedComponents: []uint32{2, 11}}) // label, geo
+
+	_, err := w.Push(context.Background(), peerRequest(&pb.EntityChangeRequest{
+		Changes: []*pb.Entity{
+			{Id: "ok", Label: proto.String("ok"), Geo: &pb.GeoSpatialComponent{Latitude: 52, Longitude: 13}},
+			polygonEntity("shape", 4),
+		},
+	}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Fatalf("got %v, want invalid argument", err)
+	}
+	if w.GetHead("ok") != nil {
+		t.Error("a rejected push must not apply any of its entities")
+	}
+
+	if _, err := w.Push(context.Background(), peerRequest(&pb.EntityChangeRequest{
+		Changes: []*pb.Entity{
+			{Id: "ok", Label: proto.String("ok"), Geo: &pb.GeoSpatialComponent{Latitude: 52, Longitude: 13}},
+		},
+	})); err != nil {
+		t.Fatalf("allowed components: %v", err)
+	}
+}
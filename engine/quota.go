@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+)
+
+// WriteQuota returns the number of entities per second a peer may push and
+// the burst it may push at once. A rate of zero or less means unlimited. A
+// burst of zero defaults to one second worth of rate.
+type WriteQuota func(peerIP string) (rate float64, burst int)
+
+// SetWriteQuota installs a per-peer push quota. Each source IP gets its own
+// token bucket; a push that does not fit is rejected with
+// CodeResourceExhausted, and one larger than the burst with
+// CodeInvalidArgument. It must be set before the server starts serving.
+func (s *WorldServer) SetWriteQuota(q WriteQuota) {
+	s.quota = newQuotaLimiter(q)
+}
+
+// maxIdleBuckets is the number of buckets above which full (idle) buckets
+// are dropped, so that a scan over many source addresses does not grow the
+// map without bound.
+const maxIdleBuckets = 1024
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	// rate and burst are the quota of the bucket's host as of the last
+	// push, used to tell when it has refilled
+	rate  float64
+	burst int
+}
+
+type quotaLimiter struct {
+	mu      sync.Mutex
+	quota   WriteQuota
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+func newQuotaLimiter(q WriteQuota) *quotaLimiter {
+	if q == nil {
+		return nil
+	}
+	return &quotaLimiter{
+		quota:   q,
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// check takes n tokens from the bucket of peerAddr. A push larger than the
+// burst could never succeed and fails with CodeInvalidArgument naming the
+// limit; one that only does not fit now fails with CodeResourceExhausted.
+// A dry run is checked against the burst but takes no tokens.
+func (q *quotaLimiter) check(peerAddr string, n int, dryRun bool) error {
+	if q == nil {
+		return nil
+	}
+	host, rate, burst := q.limits(peerAddr)
+	if rate <= 0 {
+		return nil
+	}
+	if n > burst {
+		return connect.NewError(connect.CodeInvalidArgument,
+			fmt.Errorf("push of %d entities exceeds the write quota burst of %d, split it into smaller batches", n, burst))
+	}
+	if dryRun {
+		return nil
+	}
+	if !q.allow(host, n, rate, burst) {
+		return connect.NewError(connect.CodeResourceExhausted,
+			fmt.Errorf("push rate limit exceeded for %s", peerAddr))
+	}
+	return nil
+}
+
+// limits returns the host of peerAddr and its quota, with the burst
+// defaulted.
+func (q *quotaLimiter) limits(peerAddr string) (host string, rate float64, burst int) {
+	host, _, err := net.SplitHostPort(peerAddr)
+	if err != nil {
+		host = peerAddr
+	}
+	rate, burst = q.quota(host)
+	if burst <= 0 {
+		burst = max(1, int(rate))
+	}
+	return host, rate, burst
+}
+
+// allow takes n tokens from the bucket of host, which has the given
+// quota, and reports whether there were enough. Nothing is taken when the
+// push is rejected.
+func (q *quotaLimiter) allow(host string, n int, rate float64, burst int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	b, ok := q.buckets[host]
+	if !ok {
+		if len(q.buckets) >= maxIdleBuckets {
+			q.pruneLocked(now)
+		}
+		b = &tokenBucket{tokens: float64(burst), last: now}
+		q.buckets[host] = b
+	}
+	b.rate, b.burst = rate, burst
+
+	// Refill for the time elapsed since the last push
+	b.tokens = min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens < float64(n) {
+		return false
+	}
+	b.tokens -= float64(n)
+	return true
+}
+
+// pruneLocked drops buckets that have refilled completely, each by its own
+// quota. Caller must hold q.mu.
+func (q *quotaLimiter) pruneLocked(now time.Time) {
+	for host, b := range q.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.rate >= float64(b.burst) {
+			delete(q.buckets, host)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+)
+
+// allowed reports whether q lets peerAddr push n entities now.
+func allowed(q *quotaLimiter, peerAddr string, n int) bool {
+	return q.check(peerAddr, n, false) == nil
+}
+
+func TestQuotaLimiter_BurstAndRecovery(t *testing.T) {
+	now := time.Unix(1000, 0)
+	q := newQuotaLimiter(func(peerIP string) (float64, int) {
+		if peerIP == "10.0.0.5" {
+			return 10, 10
+		}
+		return 0, 0
+	})
+	q.now = func() time.Time { return now }
+
+	// A burst of 10 fits, the 11th entity does not.
+	for i := 0; i < 10; i++ {
+		if !allowed(q, "10.0.0.5:4000", 1) {
+			t.Fatalf("push %d within burst was rejected", i)
+		}
+	}
+	if allowed(q, "10.0.0.5:4000", 1) {
+		t.Fatal("push past the burst should be rejected")
+	}
+
+	// Other peers are unaffected.
+	if !allowed(q, "10.0.0.6:4000", 100) {
+		t.Error("unlimited peer was rejected")
+	}
+
+	// After 500ms, 5 tokens are back.
+	now = now.Add(500 * time.Millisecond)
+	if allowed(q, "10.0.0.5:4000", 6) {
+		t.Error("push larger than the refilled tokens should be rejected")
+	}
+	if !allowed(q, "10.0.0.5:4000", 5) {
+		t.Error("push should succeed after a pause")
+	}
+
+	// Refill never exceeds the burst.
+	now = now.Add(time.Hour)
+	if allowed(q, "10.0.0.5:4000", 11) {
+		t.Error("bucket should be capped at the burst size")
+	}
+}
+
+func TestQuotaLimiter_DefaultBurst(t *testing.T) {
+	now := time.Unix(1000, 0)
+	q := newQuotaLimiter(func(string) (float64, int) { return 3, 0 })
+	q.now = func() time.Time { return now }
+
+	if !allowed(q, "10.0.0.5:1", 3) {
+		t.Error("default burst should be one second of rate")
+	}
+	if allowed(q, "10.0.0.5:1", 1) {
+		t.Error("bucket should be empty")
+	}
+}
+
+func TestQuotaLimiter_Nil(t *testing.T) {
+	var q *quotaLimiter
+	if !allowed(q, "10.0.0.5:1", 1000) {
+		t.Error("nil limiter allows everything")
+	}
+	if newQuotaLimiter(nil) != nil {
+		t.Error("nil quota should yield a nil limiter")
+	}
+}
+
+func TestPush_QuotaExceeded(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	w.SetWriteQuota(func(string) (float64, int) { return 1, 2 })
+	ctx := context.Background()
+
+	push := func(ids ...string) error {
+		var changes []*pb.Entity
+		for _, id := range ids {
+			changes = append(changes, &pb.Entity{Id: id})
+		}
+		_, err := w.Push(ctx, peerRequest(&pb.EntityChangeRequest{Changes: changes}))
+		return err
+	}
+
+	if err := push("a", "b"); err != nil {
+		t.Fatalf("push within burst failed: %v", err)
+	}
+	err := push("c")
+	if connect.CodeOf(err) != connect.CodeResourceExhausted {
+		t.Fatalf("expected CodeResourceExhausted, got %v", err)
+	}
+	if w.GetHead("c") != nil {
+		t.Error("rejected entity must not be stored")
+	}
+}
+
+func TestPush_QuotaOversizedBatch(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	w.SetWriteQuota(func(string) (float64, int) { return 1, 2 })
+
+	_, err := w.Push(context.Background(), peerRequest(&pb.EntityChangeRequest{
+		Changes: []*pb.Entity{{Id: "a"}, {Id: "b"}, {Id: "c"}},
+	}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Fatalf("expected CodeInvalidArgument for a batch above the burst, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "burst of 2") {
+		t.Errorf("error %q does not name the limit", err)
+	}
+
+	// The rejected batch took no tokens.
+	if _, err := w.Push(context.Background(), peerRequest(&pb.EntityChangeRequest{
+		Changes: []*pb.Entity{{Id: "a"}, {Id: "b"}},
+	})); err != nil {
+		t.Errorf("push within burst after an oversized one failed: %v", err)
+	}
+}
+
+func TestQuotaLimiter_PrunesByOwnQuota(t *testing.T) {
+	now := time.Unix(1000, 0)
+	calls := 0
+	q := newQuotaLimiter(func(peerIP string) (float64, int) {
+		calls++
+		if peerIP == "10.0.0.1" {
+			return 1, 100
+		}
+		return 1000, 1
+	})
+	q.now = func() time.Time { return now }
+
+	// The slow peer needs 100s to refill; a fast peer pruning the map
+	// must not judge it by its own quota.
+	if !allowed(q, "10.0.0.1:1", 100) {
+		t.Fatal("push within burst was rejected")
+	}
+	if calls != 1 {
+		t.Errorf("quota called %d times for one push, want 1", calls)
+	}
+	for i := 2; len(q.buckets) < maxIdleBuckets; i++ {
+		allowed(q, fmt.Sprintf("10.0.%d.%d:1", i/256, i%256), 1)
+	}
+	now = now.Add(time.Second)
+	allowed(q, "10.1.0.0:1", 1)
+	if _, ok := q.buckets["10.0.0.1"]; !ok {
+		t.Fatal("bucket of the slow peer pruned before it refilled")
+	}
+	if allowed(q, "10.0.0.1:1", 2) {
+		t.Error("slow peer got its burst back early")
+	}
+}
+
+func TestPush_DryRunTakesNoQuota(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	w.SetWriteQuota(func(string) (float64, int) { return 1, 2 })
+
+	dryRun := peerRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{{Id: "a"}, {Id: "b"}}})
+	dryRun.Header().Set(DryRunHeader, "true")
+	for range 3 {
+		if _, err := w.Push(context.Background(), dryRun); err != nil {
+			t.Fatalf("dry run failed: %v", err)
+		}
+	}
+	if _, err := w.Push(context.Background(), peerRequest(&pb.EntityChangeRequest{
+		Changes: []*pb.Entity{{Id: "a"}, {Id: "b"}},
+	})); err != nil {
+		t.Errorf("push after dry runs failed: %v", err)
+	}
+}
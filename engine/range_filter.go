@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+// RangeFilterHeader selects entities by numeric range on ListEntities and
+// WatchEntities. The value is a comma separated list of terms, all of which
+// must match. Each term is "<field> gt <v>", "<field> lt <v>" or
+// "<field> between <lo> <hi>" (inclusive), where field is one of:
+//
+//	altitude  Geo.Altitude in meters
+//	speed     magnitude of Kinematics.VelocityEnu in m/s
+//
+// "altitude gt 10000, speed between 100 300" selects fast, high aircraft.
+// Entities without the field never match.
+const RangeFilterHeader = "Hydris-Range-Filter"
+
+// RangeOp is the comparison of a RangeFilter.
+type RangeOp int
+
+const (
+	RangeGreater RangeOp = iota
+	RangeLess
+	RangeBetween
+)
+
+// RangeFilter compares a numeric entity field against bounds.
+type RangeFilter struct {
+	Field string
+	Op    RangeOp
+	Lo    float64 // bound for gt and lt, lower bound for between
+	Hi    float64 // upper bound for between
+}
+
+// rangeFields extracts the numeric fields a RangeFilter can select on. The
+// second return value is false if the entity does not carry the field.
+var rangeFields = map[string]func(*pb.Entity) (float64, bool){
+	"altitude": func(e *pb.Entity) (float64, bool) {
+		if e.GetGeo() == nil || e.Geo.Altitude == nil {
+			return 0, false
+		}
+		return *e.Geo.Altitude, true
+	},
+	"speed": func(e *pb.Entity) (float64, bool) {
+		v := e.GetKinematics().GetVelocityEnu()
+		if v == nil {
+			return 0, false
+		}
+		return velocityMagnitude(v), true
+	},
+}
+
+// velocityMagnitude returns the length of an ENU vector; unset axes count as 0.
+func velocityMagnitude(v *pb.KinematicsEnu) float64 {
+	return math.Sqrt(v.GetEast()*v.GetEast() + v.GetNorth()*v.GetNorth() + v.GetUp()*v.GetUp())
+}
+
+// ParseRangeFilters parses the value of RangeFilterHeader.
+func ParseRangeFilters(v string) ([]RangeFilter, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil, nil
+	}
+
+	var filters []RangeFilter
+	for _, term := range strings.Split(v, ",") {
+		parts := strings.Fields(term)
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("invalid range filter term %q", term)
+		}
+		if _, ok := rangeFields[parts[0]]; !ok {
+			return nil, fmt.Errorf("unknown range filter field %q", parts[0])
+		}
+
+		f := RangeFilter{Field: parts[0]}
+		var want int
+		switch parts[1] {
+		case "gt":
+			f.Op, want = RangeGreater, 3
+		case "lt":
+			f.Op, want = RangeLess, 3
+		case "between":
+			f.Op, want = RangeBetween, 4
+		default:
+			return nil, fmt.Errorf("unknown range filter operator %q", parts[1])
+		}
+		if len(parts) != want {
+			return nil, fmt.Errorf("invalid range filter term %q", term)
+		}
+
+		bounds := make([]float64, 0, 2)
+		for _, p := range parts[2:] {
+			b, err := strconv.ParseFloat(p, 64)
+			if err != nil || math.IsNaN(b) {
+				return nil, fmt.Errorf("invalid range filter bound %q", p)
+			}
+			bounds = append(bounds, b)
+		}
+		f.Lo = bounds[0]
+		if f.Op == RangeBetween {
+			f.Hi = bounds[1]
+			if f.Lo > f.Hi {
+				return nil, fmt.Errorf("range filter bounds %v > %v", f.Lo, f.Hi)
+			}
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// Matches reports whether the entity's field satisfies the comparison.
+func (f RangeFilter) Matches(entity *pb.Entity) bool {
+	get, ok := rangeFields[f.Field]
+	if !ok {
+		return false
+	}
+	v, ok := get(entity)
+	if !ok {
+		return false
+	}
+	switch f.Op {
+	case RangeGreater:
+		return v > f.Lo
+	case RangeLess:
+		return v < f.Lo
+	case RangeBetween:
+		return v >= f.Lo && v <= f.Hi
+	}
+	return false
+}
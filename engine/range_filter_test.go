@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+
+	"connectrpc.com/connect"
+)
+
+func kinematicsEntity(id string, alt *float64, east, north, up float64) *pb.Entity {
+	return &pb.Entity{
+		Id:  id,
+		Geo: &pb.GeoSpatialComponent{Latitude: 52, Longitude: 13, Altitude: alt},
+		Kinematics: &pb.KinematicsComponent{
+			VelocityEnu: &pb.KinematicsEnu{East: &east, North: &north, Up: &up},
+		},
+	}
+}
+
+func mixedFleet() map[string]*pb.Entity {
+	return map[string]*pb.Entity{
+		"airliner": kinematicsEntity("airliner", ptrFloat(11000), 180, 160, 0), // ~240 m/s
+		"cessna":   kinematicsEntity("cessna", ptrFloat(1500), 40, 30, 2),      // ~50 m/s
+		"vessel":   kinematicsEntity("vessel", ptrFloat(0), 6, 8, 0),           // 10 m/s
+		"buoy":     {Id: "buoy", Geo: &pb.GeoSpatialComponent{Latitude: 52, Longitude: 13}},
+	}
+}
+
+func TestVelocityMagnitude(t *testing.T) {
+	e, n, u := 3.0, 4.0, 12.0
+	if got := velocityMagnitude(&pb.KinematicsEnu{East: &e, North: &n, Up: &u}); got != 13 {
+		t.Errorf("magnitude = %v, want 13", got)
+	}
+	if got := velocityMagnitude(&pb.KinematicsEnu{East: &e, North: &n}); got != 5 {
+		t.Errorf("magnitude without up = %v, want 5", got)
+	}
+}
+
+func TestRangeFilter_Matches(t *testing.T) {
+	fleet := mixedFleet()
+
+	tests := []struct {
+		filter string
+		want   map[string]bool
+	}{
+		{"altitude gt 10000", map[string]bool{"airliner": true}},
+		{"altitude lt 2000", map[string]bool{"cessna": true, "vessel": true}},
+		{"speed gt 10.3", map[string]bool{"airliner": true, "cessna": true}},
+		{"speed between 5 60", map[string]bool{"cessna": true, "vessel": true}},
+		{"altitude lt 2000, speed gt 20", map[string]bool{"cessna": true}},
+	}
+
+	for _, tt := range tests {
+		filters, err := ParseRangeFilters(tt.filter)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.filter, err)
+		}
+		f := &headerFilter{ranges: filters}
+		for id, e := range fleet {
+			if got := f.matches(e, time.Now()); got != tt.want[id] {
+				t.Errorf("%q matches %s = %v, want %v", tt.filter, id, got, tt.want[id])
+			}
+		}
+	}
+}
+
+func TestParseRangeFilters_Invalid(t *testing.T) {
+	for _, v := range []string{
+		"altitude",
+		"heading gt 5",
+		"altitude eq 5",
+		"altitude gt",
+		"altitude gt x",
+		"speed between 5",
+		"speed between 10 5",
+		"altitude gt 5 6",
+	} {
+		if _, err := ParseRangeFilters(v); err == nil {
+			t.Errorf("%q: expected error", v)
+		}
+	}
+}
+
+func TestListEntities_RangeHeader(t *testing.T) {
+	w := testWorld(mixedFleet())
+
+	req := peerRequest(&pb.ListEntitiesRequest{})
+	req.Header().Set(RangeFilterHeader, "altitude gt 10000")
+	resp, err := w.ListEntities(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(resp.Msg.Entities); len(got) != 1 || got[0] != "airliner" {
+		t.Errorf("got %v, want [airliner]", got)
+	}
+
+	req = peerRequest(&pb.ListEntitiesRequest{})
+	req.Header().Set(RangeFilterHeader, "heading gt 5")
+	if _, err := w.ListEntities(context.Background(), req); connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("invalid filter: got %v, want invalid argument", err)
+	}
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"net"
+
+	"github.com/projectqai/hydris/pkg/projection"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// ReadRedactor decides which components of an entity a reader may not see.
+// peerIP is the address of the reading connection without the port. It
+// returns the entity field numbers to strip, or nil to send the entity as is.
+type ReadRedactor func(peerIP string, entity *pb.Entity) []uint32
+
+// SetReadRedactor installs a redactor that is applied to every entity sent
+// by ListEntities, GetEntity and WatchEntities. It must be set before the
+// server starts serving.
+func (s *WorldServer) SetReadRedactor(r ReadRedactor) {
+	s.redactor = r
+}
+
+// redactForPeer returns entity as seen by peerAddr. The stored entity is never
+// modified; if anything is stripped a redacted copy is returned.
+func (s *WorldServer) redactForPeer(peerAddr string, entity *pb.Entity) *pb.Entity {
+	if s.redactor == nil || entity == nil {
+		return entity
+	}
+
+	host, _, err := net.SplitHostPort(peerAddr)
+	if err != nil {
+		host = peerAddr
+	}
+
+	strip := s.redactor(host, entity)
+	if len(strip) == 0 {
+		return entity
+	}
+
+	redacted := proto.Clone(entity).(*pb.Entity)
+	projection.Drop(redacted, strip)
+	return redacted
+}
+
+// redactEventForPeer applies redactForPeer to the entity of an event.
+func (s *WorldServer) redactEventForPeer(peerAddr string, event *pb.EntityChangeEvent) *pb.EntityChangeEvent {
+	if s.redactor == nil || event.Entity == nil {
+		return event
+	}
+	redacted := s.redactForPeer(peerAddr, event.Entity)
+	if redacted == event.Entity {
+		return event
+	}
+	out := proto.Clone(event).(*pb.EntityChangeEvent)
+	out.Entity = redacted
+	return out
+}
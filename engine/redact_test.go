@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+// untrustedRedactor strips Transponder (field 27) for 10.0.0.5 only.
+func untrustedRedactor(peerIP string, entity *pb.Entity) []uint32 {
+	if peerIP == "10.0.0.5" {
+		return []uint32{uint32(pb.EntityComponent_EntityComponentTransponder)}
+	}
+	return nil
+}
+
+func redactTestWorld() *WorldServer {
+	w := testWorld(map[string]*pb.Entity{
+		"ac1": {
+			Id:          "ac1",
+			Geo:         &pb.GeoSpatialComponent{Latitude: 52, Longitude: 13},
+			Transponder: &pb.TransponderComponent{},
+		},
+	})
+	w.SetReadRedactor(untrustedRedactor)
+	return w
+}
+
+func TestRedactForPeer(t *testing.T) {
+	w := redactTestWorld()
+	stored := w.GetHead("ac1")
+
+	untrusted := w.redactForPeer("10.0.0.5:40000", stored)
+	if untrusted.Transponder != nil {
+		t.Error("transponder should be stripped for 10.0.0.5")
+	}
+	if untrusted.Geo == nil {
+		t.Error("geo should still be visible for 10.0.0.5")
+	}
+
+	trusted := w.redactForPeer("10.0.0.6:40000", stored)
+	if trusted.Transponder == nil {
+		t.Error("transponder should be visible for 10.0.0.6")
+	}
+
+	if stored.Transponder == nil {
+		t.Error("redaction must not modify the stored entity")
+	}
+}
+
+func TestRedactEventForPeer(t *testing.T) {
+	w := redactTestWorld()
+	event := &pb.EntityChangeEvent{Entity: w.GetHead("ac1"), T: pb.EntityChange_EntityChangeUpdated}
+
+	if got := w.redactEventForPeer("10.0.0.5:1", event); got.Entity.Transponder != nil || got.T != event.T {
+		t.Errorf("event for 10.0.0.5 not redacted: %v", got)
+	}
+	if got := w.redactEventForPeer("10.0.0.6:1", event); got != event {
+		t.Error("event for 10.0.0.6 should be passed through unchanged")
+	}
+
+	invalid := &pb.EntityChangeEvent{T: pb.EntityChange_EntityChangeInvalid}
+	if got := w.redactEventForPeer("10.0.0.5:1", invalid); got != invalid {
+		t.Error("events without entity should be passed through")
+	}
+}
+
+func TestListEntities_NoRedactor(t *testing.T) {
+	w := redactTestWorld()
+	w.SetReadRedactor(nil)
+
+	resp, err := w.ListEntities(context.Background(), peerRequest(&pb.ListEntitiesRequest{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Msg.Entities) != 1 || resp.Msg.Entities[0].Transponder == nil {
+		t.Errorf("without a redactor entities are sent as is, got %v", resp.Msg.Entities)
+	}
+}
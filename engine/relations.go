@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// maxRelationDepth caps how far GetEntityWithRelations follows references.
+const maxRelationDepth = 8
+
+// relatedIDs returns the ids of the entities e refers to: its device parent,
+// its track prediction and its taskable context and assignees.
+func relatedIDs(e *pb.Entity) []string {
+	var ids []string
+	if p := e.GetDevice().GetParent(); p != "" {
+		ids = append(ids, p)
+	}
+	if p := e.GetTrack().GetPrediction(); p != "" {
+		ids = append(ids, p)
+	}
+	for _, c := range e.GetTaskable().GetContext() {
+		if id := c.GetEntityId(); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	for _, a := range e.GetTaskable().GetAssignee() {
+		if id := a.GetEntityId(); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// GetEntityWithRelations returns the entity with the given id followed by the
+// entities it references, directly or through other related entities, up to
+// depth hops away. Each entity is returned once, in breadth-first order.
+// References to entities that are not in the world are skipped.
+func (s *WorldServer) GetEntityWithRelations(ctx context.Context, id string, depth int) ([]*pb.Entity, error) {
+	if depth < 0 {
+		depth = 0
+	}
+	if depth > maxRelationDepth {
+		depth = maxRelationDepth
+	}
+
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	root, ok := s.head[id]
+	if !ok {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("entity with id %s not found", id))
+	}
+
+	result := []*pb.Entity{root.entity}
+	seen := map[string]bool{id: true}
+	frontier := []*pb.Entity{root.entity}
+
+	for hop := 0; hop < depth && len(frontier) > 0; hop++ {
+		var next []*pb.Entity
+		for _, e := range frontier {
+			for _, rid := range relatedIDs(e) {
+				if seen[rid] {
+					continue
+				}
+				seen[rid] = true
+				if es, ok := s.head[rid]; ok {
+					next = append(next, es.entity)
+				}
+			}
+		}
+		result = append(result, next...)
+		frontier = next
+	}
+
+	return result, nil
+}
+
+// handleRelations serves GetEntityWithRelations as JSON:
+//
+//	GET /relations/{entityId}?depth=N
+//
+// The response is a ListEntitiesResponse; depth defaults to 1. The request
+// is checked by the authorizer as method "GetEntity".
+func (s *WorldServer) handleRelations(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorizeHTTP(r, "GetEntity"); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	depth := 1
+	if v := r.URL.Query().Get("depth"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "invalid depth", http.StatusBadRequest)
+			return
+		}
+		depth = d
+	}
+
+	entities, err := s.GetEntityWithRelations(r.Context(), r.PathValue("entityId"), depth)
+	if err != nil {
+		if connect.CodeOf(err) == connect.CodeNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for i, e := range entities {
+		entities[i] = s.redactForPeer(r.RemoteAddr, e)
+	}
+
+	data, err := protojson.Marshal(&pb.ListEntitiesResponse{Entities: entities})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"connectrpc.com/connect"
+)
+
+func relationsWorld() *WorldServer {
+	return testWorld(map[string]*pb.Entity{
+		"host":         {Id: "host", Device: &pb.DeviceComponent{}},
+		"host.usb":     {Id: "host.usb", Device: &pb.DeviceComponent{Parent: ptr("host")}},
+		"host.usb.gps": {Id: "host.usb.gps", Device: &pb.DeviceComponent{Parent: ptr("host.usb")}},
+		"sat":          {Id: "sat", Track: &pb.TrackComponent{Prediction: ptr("sat.orbit")}},
+		"sat.orbit":    {Id: "sat.orbit", Label: ptr("orbit")},
+	})
+}
+
+func ids(entities []*pb.Entity) []string {
+	out := make([]string, len(entities))
+	for i, e := range entities {
+		out[i] = e.Id
+	}
+	return out
+}
+
+func TestGetEntityWithRelations_DeviceParentChain(t *testing.T) {
+	w := relationsWorld()
+	ctx := context.Background()
+
+	tests := []struct {
+		depth int
+		want  []string
+	}{
+		{0, []string{"host.usb.gps"}},
+		{1, []string{"host.usb.gps", "host.usb"}},
+		{2, []string{"host.usb.gps", "host.usb", "host"}},
+		{5, []string{"host.usb.gps", "host.usb", "host"}},
+	}
+	for _, tt := range tests {
+		got, err := w.GetEntityWithRelations(ctx, "host.usb.gps", tt.depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g := ids(got); len(g) != len(tt.want) {
+			t.Errorf("depth %d: got %v, want %v", tt.depth, g, tt.want)
+		} else {
+			for i := range g {
+				if g[i] != tt.want[i] {
+					t.Errorf("depth %d: got %v, want %v", tt.depth, g, tt.want)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestGetEntityWithRelations_SatelliteOrbit(t *testing.T) {
+	w := relationsWorld()
+
+	got, err := w.GetEntityWithRelations(context.Background(), "sat", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := ids(got); len(g) != 2 || g[0] != "sat" || g[1] != "sat.orbit" {
+		t.Errorf("got %v, want [sat sat.orbit]", g)
+	}
+}
+
+func TestGetEntityWithRelations_TaskableAndCycles(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"task": {Id: "task", Taskable: &pb.TaskableComponent{
+			Context:  []*pb.TaskableContext{{EntityId: ptr("area")}, {EntityId: ptr("missing")}},
+			Assignee: []*pb.TaskableAssignee{{EntityId: ptr("drone")}},
+		}},
+		"area":  {Id: "area"},
+		"drone": {Id: "drone", Device: &pb.DeviceComponent{Parent: ptr("task")}},
+	})
+
+	got, err := w.GetEntityWithRelations(context.Background(), "task", maxRelationDepth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := ids(got); len(g) != 3 || g[0] != "task" || g[1] != "area" || g[2] != "drone" {
+		t.Errorf("got %v, want [task area drone]", g)
+	}
+}
+
+func TestGetEntityWithRelations_NotFound(t *testing.T) {
+	w := relationsWorld()
+	_, err := w.GetEntityWithRelations(context.Background(), "nope", 1)
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("got %v, want not found", err)
+	}
+}
+
+func TestHandleRelations(t *testing.T) {
+	w := relationsWorld()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /relations/{entityId...}", w.handleRelations)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/relations/host.usb.gps?depth=2", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp pb.ListEntitiesResponse
+	if err := protojson.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Entities) != 3 {
+		t.Errorf("got %d entities, want 3", len(resp.Entities))
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/relations/nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("missing entity: status %d, want 404", rec.Code)
+	}
+
+	assertHTTPDenied(t, w, mux, httptest.NewRequest("GET", "/relations/host.usb.gps", nil), "GetEntity")
+}
@@ -0,0 +1,178 @@
+package engine
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// versionLedger numbers the changes of every entity. Versions come from a
+// single counter, so they only grow, also across an entity being removed
+// and pushed again. The counter starts over with the process; epoch tells
+// versions of different runs apart.
+type versionLedger struct {
+	epoch string
+
+	mu       sync.Mutex
+	last     uint64
+	versions map[string]uint64
+}
+
+func newVersionLedger() *versionLedger {
+	return &versionLedger{epoch: rand.Text(), versions: make(map[string]uint64)}
+}
+
+// bump gives id the next version, or forgets it if the change removed it.
+func (l *versionLedger) bump(id string, change pb.EntityChange) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if change == pb.EntityChange_EntityChangeExpired {
+		delete(l.versions, id)
+		return
+	}
+	l.last++
+	l.versions[id] = l.last
+}
+
+func (l *versionLedger) version(id string) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.versions[id]
+}
+
+// ResyncRequest is what a reconnecting client knows about the world.
+type ResyncRequest struct {
+	// Epoch is the epoch of the response Known was learned from. If it is
+	// not the server's current one, Known versions are ignored and every
+	// entity is sent, while tombstones are still computed from Known.
+	Epoch string
+	// Known maps the ids of the entities the client has to their version.
+	Known map[string]uint64
+	// Filter, if set, restricts the entities sent as in ListEntities.
+	Filter *pb.ListEntitiesRequest
+}
+
+// ResyncResponse brings a client from ResyncRequest.Known up to date.
+type ResyncResponse struct {
+	// Epoch is to be passed back with the next ResyncRequest.
+	Epoch string
+	// Entities are those the client does not know, or knows in an older
+	// version, sorted by id.
+	Entities []*pb.Entity
+	// Versions are the versions of Entities, by id.
+	Versions map[string]uint64
+	// Tombstones are the ids in Known that no longer exist, sorted.
+	Tombstones []string
+}
+
+// Resync returns the changes since the client last synced: the entities
+// that are newer than its known version or unknown to it, and tombstones
+// for the ones it knows that were removed. A client without knowledge gets
+// the full world, like ListEntities, but with versions to resync from.
+func (s *WorldServer) Resync(ctx context.Context, req *connect.Request[ResyncRequest]) (*ResyncResponse, error) {
+	return s.resync(req.Msg, req.Peer().Addr), nil
+}
+
+func (s *WorldServer) resync(req *ResyncRequest, peerAddr string) *ResyncResponse {
+	filter := req.Filter
+	if filter == nil {
+		filter = &pb.ListEntitiesRequest{}
+	}
+	sameEpoch := req.Epoch == s.bus.versions.epoch
+
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	resp := &ResyncResponse{Epoch: s.bus.versions.epoch, Versions: make(map[string]uint64)}
+	for id, es := range s.head {
+		if !s.matchesListEntitiesRequest(es.entity, filter) {
+			continue
+		}
+		v := s.bus.versions.version(id)
+		if kv, ok := req.Known[id]; ok && sameEpoch && kv >= v {
+			continue
+		}
+		resp.Entities = append(resp.Entities, s.redactForPeer(peerAddr, es.entity))
+		resp.Versions[id] = v
+	}
+	for id := range req.Known {
+		if _, ok := s.head[id]; !ok {
+			resp.Tombstones = append(resp.Tombstones, id)
+		}
+	}
+	slices.SortFunc(resp.Entities, func(a, b *pb.Entity) int { return strings.Compare(a.Id, b.Id) })
+	slices.Sort(resp.Tombstones)
+	return resp
+}
+
+// resyncRequestJSON is the body of POST /resync. Filter is a
+// ListEntitiesRequest as protojson.
+type resyncRequestJSON struct {
+	Epoch  string            `json:"epoch"`
+	Known  map[string]uint64 `json:"known"`
+	Filter json.RawMessage   `json:"filter"`
+}
+
+// resyncResponseJSON is the reply of POST /resync; entities are protojson.
+type resyncResponseJSON struct {
+	Epoch      string            `json:"epoch"`
+	Entities   []json.RawMessage `json:"entities"`
+	Versions   map[string]uint64 `json:"versions"`
+	Tombstones []string          `json:"tombstones"`
+}
+
+// handleResync serves Resync over HTTP:
+//
+//	POST /resync {"epoch": "...", "known": {"base.home": 41}}
+//
+// The request is checked by the authorizer as method "Resync".
+func (s *WorldServer) handleResync(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorizeHTTP(r, "Resync"); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	var body resyncRequestJSON
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	req := &ResyncRequest{Epoch: body.Epoch, Known: body.Known}
+	if len(body.Filter) > 0 {
+		req.Filter = &pb.ListEntitiesRequest{}
+		if err := protojson.Unmarshal(body.Filter, req.Filter); err != nil {
+			http.Error(w, fmt.Sprintf("invalid filter: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	resp := s.resync(req, r.RemoteAddr)
+	out := resyncResponseJSON{
+		Epoch:      resp.Epoch,
+		Entities:   make([]json.RawMessage, 0, len(resp.Entities)),
+		Versions:   resp.Versions,
+		Tombstones: resp.Tombstones,
+	}
+	if out.Tombstones == nil {
+		out.Tombstones = []string{}
+	}
+	for _, e := range resp.Entities {
+		b, err := protojson.Marshal(e)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out.Entities = append(out.Entities, b)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
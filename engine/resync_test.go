@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+func resyncIDs(resp *ResyncResponse) []string {
+	var ids []string
+	for _, e := range resp.Entities {
+		ids = append(ids, e.Id)
+	}
+	return ids
+}
+
+func pushLabels(t *testing.T, w *WorldServer, labels map[string]string) {
+	t.Helper()
+	req := &pb.EntityChangeRequest{}
+	for id, label := range labels {
+		req.Changes = append(req.Changes, &pb.Entity{Id: id, Label: ptr(label)})
+	}
+	if _, err := w.Push(context.Background(), peerRequest(req)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResync_DeltasAndTombstones(t *testing.T) {
+	w := testWorld(nil)
+	pushLabels(t, w, map[string]string{"a": "A", "b": "B", "c": "C"})
+
+	first, err := w.Resync(context.Background(), peerRequest(&ResyncRequest{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resyncIDs(first); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Fatalf("full resync sent %v, want a b c", got)
+	}
+	if len(first.Tombstones) != 0 {
+		t.Errorf("full resync has tombstones %v", first.Tombstones)
+	}
+
+	pushLabels(t, w, map[string]string{"b": "B2", "d": "D"})
+	if _, err := w.ExpireEntity(context.Background(), peerRequest(&pb.ExpireEntityRequest{Id: "c"})); err != nil {
+		t.Fatal(err)
+	}
+	w.GC()
+
+	resp, err := w.Resync(context.Background(), peerRequest(&ResyncRequest{Epoch: first.Epoch, Known: first.Versions}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resyncIDs(resp); !slices.Equal(got, []string{"b", "d"}) {
+		t.Errorf("resync sent %v, want b d", got)
+	}
+	if resp.Entities[0].GetLabel() != "B2" {
+		t.Errorf("b sent with label %q, want B2", resp.Entities[0].GetLabel())
+	}
+	if resp.Versions["b"] <= first.Versions["b"] {
+		t.Errorf("b version %d did not grow from %d", resp.Versions["b"], first.Versions["b"])
+	}
+	if !slices.Equal(resp.Tombstones, []string{"c"}) {
+		t.Errorf("tombstones %v, want c", resp.Tombstones)
+	}
+
+	// Caught up, nothing is sent again.
+	known := first.Versions
+	for id, v := range resp.Versions {
+		known[id] = v
+	}
+	delete(known, "c")
+	resp, err = w.Resync(context.Background(), peerRequest(&ResyncRequest{Epoch: first.Epoch, Known: known}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Entities) != 0 || len(resp.Tombstones) != 0 {
+		t.Errorf("caught up client got %v and tombstones %v", resyncIDs(resp), resp.Tombstones)
+	}
+}
+
+func TestResync_PartialAndStale(t *testing.T) {
+	w := testWorld(nil)
+	pushLabels(t, w, map[string]string{"a": "A", "b": "B", "c": "C"})
+	full, err := w.Resync(context.Background(), peerRequest(&ResyncRequest{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The client knows a up to date, b in an older version and not c.
+	known := map[string]uint64{"a": full.Versions["a"], "b": full.Versions["b"] - 1, "gone": 1}
+	resp, err := w.Resync(context.Background(), peerRequest(&ResyncRequest{Epoch: full.Epoch, Known: known}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resyncIDs(resp); !slices.Equal(got, []string{"b", "c"}) {
+		t.Errorf("resync sent %v, want b c", got)
+	}
+	if !slices.Equal(resp.Tombstones, []string{"gone"}) {
+		t.Errorf("tombstones %v, want gone", resp.Tombstones)
+	}
+
+	// Versions of another run mean nothing: everything is sent.
+	resp, err = w.Resync(context.Background(), peerRequest(&ResyncRequest{Epoch: "previous", Known: full.Versions}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resyncIDs(resp); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("resync across epochs sent %v, want a b c", got)
+	}
+}
+
+func TestResync_RecreatedEntity(t *testing.T) {
+	w := testWorld(nil)
+	pushLabels(t, w, map[string]string{"a": "A"})
+	full, err := w.Resync(context.Background(), peerRequest(&ResyncRequest{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.ExpireEntity(context.Background(), peerRequest(&pb.ExpireEntityRequest{Id: "a"})); err != nil {
+		t.Fatal(err)
+	}
+	w.GC()
+	pushLabels(t, w, map[string]string{"a": "A again"})
+
+	resp, err := w.Resync(context.Background(), peerRequest(&ResyncRequest{Epoch: full.Epoch, Known: full.Versions}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resyncIDs(resp); !slices.Equal(got, []string{"a"}) || resp.Entities[0].GetLabel() != "A again" {
+		t.Errorf("recreated entity not resent: %v", resp.Entities)
+	}
+	if len(resp.Tombstones) != 0 {
+		t.Errorf("recreated entity reported as removed: %v", resp.Tombstones)
+	}
+}
@@ -0,0 +1,31 @@
+package engine
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// DefaultShutdownTimeout is how long shutdown waits for watch streams and
+// in-flight requests to finish unless configured.
+const DefaultShutdownTimeout = 5 * time.Second
+
+// Shutdown ends all watch streams, waits until they are gone or ctx is
+// done, stops the GC and periodic flushes, and flushes the world file a
+// last time. Streams still running when ctx is done are left to end on
+// their own; the flush happens regardless. Shutdown may be called more
+// than once.
+func (s *WorldServer) Shutdown(ctx context.Context) error {
+	s.bus.CloseAll()
+	if err := s.bus.Wait(ctx); err != nil {
+		slog.Warn("watch streams did not end before shutdown deadline", "remaining", s.bus.Len(), "error", err)
+	}
+
+	s.stopOnce.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+		}
+	})
+
+	return s.FlushToFile()
+}
@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"github.com/projectqai/proto/go/_goconnect"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestShutdown_EndsWatchesAndFlushes(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"e1": {Id: "e1", Controller: &pb.Controller{Node: proto.String("n1")}, Device: &pb.DeviceComponent{State: pb.DeviceState_DeviceStateActive}},
+	})
+	w.worldFile = filepath.Join(t.TempDir(), "world.yaml")
+	w.nodeID = "n1"
+	w.stop = make(chan struct{})
+
+	srv := grpcTestServer(t, w)
+	client := _goconnect.NewWorldServiceClient(srv.Client(), srv.URL, connect.WithGRPC())
+
+	var streams []*connect.ServerStreamForClient[pb.EntityChangeEvent]
+	for range 3 {
+		stream, err := client.WatchEntities(context.Background(), connect.NewRequest(&pb.ListEntitiesRequest{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = stream.Close() }()
+		// The first event is sent once the consumer is registered.
+		if !stream.Receive() {
+			t.Fatalf("stream ended early: %v", stream.Err())
+		}
+		streams = append(streams, stream)
+	}
+	if n := w.bus.Len(); n != 3 {
+		t.Fatalf("%d consumers registered, want 3", n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := w.bus.Len(); n != 0 {
+		t.Errorf("%d consumers still registered after shutdown", n)
+	}
+	for _, stream := range streams {
+		for stream.Receive() {
+		}
+	}
+	select {
+	case <-w.stop:
+	default:
+		t.Error("GC and periodic flushes were not stopped")
+	}
+
+	b, err := os.ReadFile(w.worldFile)
+	if err != nil {
+		t.Fatalf("world file not flushed: %v", err)
+	}
+	if !strings.Contains(string(b), "e1") {
+		t.Errorf("flushed world file lacks e1: %q", b)
+	}
+
+	// A second shutdown is harmless.
+	if err := w.Shutdown(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestShutdown_DeadlineStillFlushes(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"e1": {Id: "e1", Controller: &pb.Controller{Node: proto.String("n1")}, Device: &pb.DeviceComponent{State: pb.DeviceState_DeviceStateActive}},
+	})
+	w.worldFile = filepath.Join(t.TempDir(), "world.yaml")
+	w.nodeID = "n1"
+
+	// A consumer without a stream behind it never unregisters.
+	c := NewConsumer(w, nil, nil)
+	w.bus.Register(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := w.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(w.worldFile); err != nil {
+		t.Errorf("world file not flushed after deadline: %v", err)
+	}
+}
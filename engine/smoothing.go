@@ -0,0 +1,18 @@
+package engine
+
+import "github.com/projectqai/hydris/engine/transform"
+
+// EnableSmoothing turns on track smoothing for the entities of the given
+// controllers, or of all controllers if none are given: their pushed
+// positions are run through a Kalman filter before anything else sees
+// them. accelVar is the process noise in (m/s²)²; zero uses
+// transform.DefaultSmoothingAccelVar. It must be called before the server
+// handles requests.
+func (s *WorldServer) EnableSmoothing(accelVar float64, controllers ...string) {
+	if accelVar <= 0 {
+		accelVar = transform.DefaultSmoothingAccelVar
+	}
+	// Smoothing comes first so that derived geometry uses the filtered
+	// position.
+	s.transformers = append([]transform.Transformer{transform.NewSmoothingTransformer(accelVar, controllers...)}, s.transformers...)
+}
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/projectqai/hydris/pkg/metrics"
+	"github.com/projectqai/hydris/pkg/projection"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// worldCounters accumulate since the world server was created.
+type worldCounters struct {
+	pushed     atomic.Uint64
+	expired    atomic.Uint64
+	gcRuns     atomic.Uint64
+	gcDuration atomic.Int64 // nanoseconds
+
+	gcLastDuration atomic.Int64 // nanoseconds
+
+	flushes atomic.Uint64 // world file writes
+}
+
+// Stats returns a snapshot of the world for metrics exporters.
+func (s *WorldServer) Stats() metrics.Stats {
+	stats := metrics.Stats{
+		EntitiesByController: make(map[string]int),
+		EntitiesByComponent:  make(map[string]int),
+		ConsumerQueueDepths:  s.bus.QueueDepths(),
+		ConsumerFilters:      s.bus.FilterStats(),
+		EntitiesPushed:       s.counters.pushed.Load(),
+		EntitiesExpired:      s.counters.expired.Load(),
+		GCRuns:               s.counters.gcRuns.Load(),
+		GCDuration:           time.Duration(s.counters.gcDuration.Load()),
+		GCLastDuration:       time.Duration(s.counters.gcLastDuration.Load()),
+	}
+
+	s.l.RLock()
+	defer s.l.RUnlock()
+	for _, es := range s.head {
+		stats.EntitiesByController[es.entity.Controller.GetId()]++
+		for _, name := range componentNames(es.entity) {
+			stats.EntitiesByComponent[name]++
+		}
+	}
+	return stats
+}
+
+// entityFields describes the fields of pb.Entity.
+var entityFields = (&pb.Entity{}).ProtoReflect().Descriptor().Fields()
+
+// componentNames returns the proto names of the components present on e.
+func componentNames(e *pb.Entity) []string {
+	components := projection.Components(e)
+	names := make([]string, 0, len(components))
+	for _, c := range components {
+		names = append(names, string(entityFields.ByNumber(protoreflect.FieldNumber(c)).Name()))
+	}
+	return names
+}
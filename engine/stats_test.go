@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestStats(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"ac1": {Id: "ac1", Controller: &pb.Controller{Id: proto.String("adsblol")}, Geo: &pb.GeoSpatialComponent{}},
+		"ac2": {Id: "ac2", Controller: &pb.Controller{Id: proto.String("adsblol")}, Geo: &pb.GeoSpatialComponent{}, Transponder: &pb.TransponderComponent{}},
+	})
+
+	consumer := NewConsumer(w, nil, nil)
+	w.bus.Register(consumer)
+	defer w.bus.Unregister(consumer)
+
+	if _, err := w.Push(context.Background(), peerRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{
+		{Id: "old", Lifetime: &pb.Lifetime{Until: timestamppb.New(time.Now().Add(-time.Second))}},
+	}})); err != nil {
+		t.Fatal(err)
+	}
+	w.GC()
+
+	stats := w.Stats()
+	if stats.EntitiesByController["adsblol"] != 2 {
+		t.Errorf("by controller: %v", stats.EntitiesByController)
+	}
+	if stats.EntitiesByComponent["geo"] != 2 || stats.EntitiesByComponent["transponder"] != 1 {
+		t.Errorf("by component: %v", stats.EntitiesByComponent)
+	}
+	if stats.EntitiesPushed != 1 || stats.EntitiesExpired != 1 || stats.GCRuns != 1 {
+		t.Errorf("counters: pushed=%d expired=%d gc=%d", stats.EntitiesPushed, stats.EntitiesExpired, stats.GCRuns)
+	}
+	if depth := stats.ConsumerQueueDepths[consumer.id]; depth != 1 {
+		t.Errorf("queue depth %d, want 1 pending change for %q", depth, "old")
+	}
+}
+
+func TestStats_FilterCost(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+
+	// A detailed area of interest: every evaluation converts and bounds
+	// all of its vertices.
+	ring := make([]*pb.PlanarPoint, 0, 2001)
+	for i := range 2000 {
+		a := 2 * math.Pi * float64(i) / 2000
+		ring = append(ring, &pb.PlanarPoint{Latitude: 48 + math.Sin(a), Longitude: 11 + math.Cos(a)})
+	}
+	ring = append(ring, ring[0])
+	geoFiltered := NewConsumer(w, nil, &pb.EntityFilter{Geo: &pb.GeoFilter{
+		Geo: &pb.GeoFilter_Geometry{Geometry: &pb.Geometry{Planar: &pb.PlanarGeometry{
+			Plane: &pb.PlanarGeometry_Polygon{Polygon: &pb.PlanarPolygon{Outer: &pb.PlanarRing{Points: ring}}},
+		}}},
+	}})
+	idFiltered := NewConsumer(w, nil, &pb.EntityFilter{Id: proto.String("track.0")})
+	for _, c := range []*Consumer{geoFiltered, idFiltered} {
+		w.bus.Register(c)
+		defer w.bus.Unregister(c)
+	}
+
+	const n = 500
+	for i := range n {
+		e := &pb.Entity{Id: fmt.Sprintf("track.%d", i), Geo: &pb.GeoSpatialComponent{Latitude: 48, Longitude: 11}}
+		geoFiltered.matches(e)
+		idFiltered.matches(e)
+	}
+
+	stats := w.Stats().ConsumerFilters
+	geo, id := stats[geoFiltered.id], stats[idFiltered.id]
+	if geo.Evaluations != n || geo.Matches != n {
+		t.Errorf("geo filter: %d evaluations, %d matches, want %d each", geo.Evaluations, geo.Matches, n)
+	}
+	if id.Evaluations != n || id.Matches != 1 {
+		t.Errorf("id filter: %d evaluations, %d matches, want %d and 1", id.Evaluations, id.Matches, n)
+	}
+	if geo.Duration <= 0 {
+		t.Fatal("geo filter accrued no evaluation time")
+	}
+	if geo.Duration < 10*id.Duration {
+		t.Errorf("geo filter took %v, id filter %v: want the geo filter at least 10x costlier", geo.Duration, id.Duration)
+	}
+}
@@ -0,0 +1,215 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydris/engine/transform"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// SyncControllerRequest is the full set of entities a controller wants to
+// own.
+type SyncControllerRequest struct {
+	Controller string
+	Entities   []*pb.Entity
+	// PeerAddr is the address of the caller, which the expire authorizer
+	// checks for every entity the sync would prune.
+	PeerAddr string
+}
+
+// SyncControllerResponse lists the ids SyncController changed, sorted.
+type SyncControllerResponse struct {
+	Added   []string `json:"added"`
+	Updated []string `json:"updated"`
+	Pruned  []string `json:"pruned"`
+}
+
+// SyncController makes req.Entities the complete set of entities owned by
+// req.Controller, like kubectl apply --prune: entities that don't exist
+// are added, entities that differ apart from their Lifetime are replaced
+// as a whole, and entities of the controller missing from the set are
+// expired as by ExpireEntity. Entities that are already up to date are
+// left alone and their consumers are not notified.
+//
+// The sync is atomic. Every entity is validated, and its Controller.Id
+// must be unset or req.Controller. An id already owned by a different
+// controller fails the whole sync with CodePermissionDenied, and so does
+// an entity to prune that the expire authorizer refuses.
+func (s *WorldServer) SyncController(ctx context.Context, req SyncControllerRequest) (*SyncControllerResponse, error) {
+	if req.Controller == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("controller must be set"))
+	}
+
+	// Validation may fill in defaults, so work on copies.
+	desired := make(map[string]*pb.Entity, len(req.Entities))
+	for _, e := range req.Entities {
+		if _, dup := desired[e.Id]; dup {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("entity %s listed twice", e.Id))
+		}
+		e = proto.Clone(e).(*pb.Entity)
+		if e.Controller == nil {
+			e.Controller = &pb.Controller{}
+		}
+		if id := e.Controller.GetId(); id != "" && id != req.Controller {
+			return nil, connect.NewError(connect.CodeInvalidArgument,
+				fmt.Errorf("entity %s names controller %q, not %q", e.Id, id, req.Controller))
+		}
+		e.Controller.Id = proto.String(req.Controller)
+		desired[e.Id] = e
+	}
+
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.frozen.Load() {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New(s.frozenMessage()))
+	}
+
+	for id, e := range desired {
+		if err := s.validateIncoming(e); err != nil {
+			return nil, err
+		}
+		if err := s.validateConfigSchema(e); err != nil {
+			return nil, err
+		}
+		if es, ok := s.head[id]; ok {
+			if owner := es.entity.Controller.GetId(); owner != req.Controller {
+				return nil, connect.NewError(connect.CodePermissionDenied,
+					fmt.Errorf("entity %s is owned by controller %q, not %q", id, owner, req.Controller))
+			}
+		}
+	}
+
+	var prune []string
+	for id, es := range s.head {
+		if _, keep := desired[id]; keep || es.hardExpire || es.entity.Controller.GetId() != req.Controller {
+			continue
+		}
+		if err := s.authorizeExpire(ctx, req.PeerAddr, es.entity); err != nil {
+			return nil, err
+		}
+		prune = append(prune, id)
+	}
+
+	resp := &SyncControllerResponse{}
+	var changedIDs []string
+	persistChanged := false
+
+	for id, e := range desired {
+		s.stampNode(e)
+		es, exists := s.head[id]
+		if exists && !es.hardExpire && equalIgnoringLifetime(es.entity, e) {
+			continue
+		}
+		if exists {
+			resp.Updated = append(resp.Updated, id)
+		} else {
+			resp.Added = append(resp.Added, id)
+		}
+		fillLifetime(e)
+		s.initEntity(e)
+		changedIDs = append(changedIDs, id)
+		if s.persistStub(s.head[id]) != nil {
+			persistChanged = true
+		}
+	}
+
+	now := timestamppb.Now()
+	for _, id := range prune {
+		es := s.head[id]
+		es.hardExpire = true
+		es.expireReason = ExpiryPruned
+		// Clone so we don't mutate the pointer already shared with the bus.
+		expired := proto.Clone(es.entity).(*pb.Entity)
+		if expired.Lifetime == nil {
+			expired.Lifetime = &pb.Lifetime{}
+		}
+		expired.Lifetime.Until = now
+		es.entity = expired
+		s.headView[id] = expired
+		resp.Pruned = append(resp.Pruned, id)
+	}
+
+	for _, id := range changedIDs {
+		upserted, removed := transform.RunTransformers(s.transformers, s.headView, s.bus, id)
+		s.syncTransformerResults(upserted, removed)
+	}
+	for _, id := range changedIDs {
+		s.bus.DirtyContext(ctx, id, s.head[id].entity, pb.EntityChange_EntityChangeUpdated)
+	}
+	for _, id := range resp.Pruned {
+		s.bus.DirtyContext(ctx, id, s.head[id].entity, pb.EntityChange_EntityChangeUpdated)
+	}
+	slog.DebugContext(ctx, "controller synced", "controller", req.Controller,
+		"added", len(resp.Added), "updated", len(resp.Updated), "pruned", len(resp.Pruned))
+
+	if persistChanged {
+		s.notifyPersist()
+	}
+	s.counters.pushed.Add(uint64(len(changedIDs)))
+
+	slices.Sort(resp.Added)
+	slices.Sort(resp.Updated)
+	slices.Sort(resp.Pruned)
+	return resp, nil
+}
+
+// equalIgnoringLifetime reports whether a and b are equal apart from their
+// Lifetime, which a resync sets afresh on every entity.
+func equalIgnoringLifetime(a, b *pb.Entity) bool {
+	a = proto.Clone(a).(*pb.Entity)
+	b = proto.Clone(b).(*pb.Entity)
+	a.Lifetime, b.Lifetime = nil, nil
+	return proto.Equal(a, b)
+}
+
+// syncControllerRequest is the body of POST /sync-controller.
+type syncControllerRequest struct {
+	Controller string            `json:"controller"`
+	Entities   []json.RawMessage `json:"entities"`
+}
+
+// handleSyncController serves SyncController over HTTP:
+//
+//	POST /sync-controller {"controller": "adsb", "entities": [{"id": "adsb.3c6444", ...}]}
+//
+// Entities are protojson. The request is checked by the authorizer as
+// method "SyncController".
+func (s *WorldServer) handleSyncController(w http.ResponseWriter, r *http.Request) {
+	if err := s.authorizeHTTP(r, "SyncController"); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	var body syncControllerRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid sync request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	entities, err := unmarshalEntities(body.Entities)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := s.SyncController(r.Context(), SyncControllerRequest{
+		Controller: body.Controller,
+		Entities:   entities,
+		PeerAddr:   r.RemoteAddr,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestSyncController_AddUpdatePrune(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"adsb.same":  {Id: "adsb.same", Controller: &pb.Controller{Id: ptr("adsb")}, Label: ptr("same")},
+		"adsb.moved": {Id: "adsb.moved", Controller: &pb.Controller{Id: ptr("adsb")}, Geo: &pb.GeoSpatialComponent{Latitude: 1}},
+		"adsb.gone":  {Id: "adsb.gone", Controller: &pb.Controller{Id: ptr("adsb")}},
+		"ais.1":      {Id: "ais.1", Controller: &pb.Controller{Id: ptr("ais")}},
+	})
+
+	c := NewConsumer(w, nil, nil)
+	w.bus.Register(c)
+	defer w.bus.Unregister(c)
+
+	resp, err := w.SyncController(context.Background(), SyncControllerRequest{
+		Controller: "adsb",
+		Entities: []*pb.Entity{
+			{Id: "adsb.same", Label: ptr("same")},
+			{Id: "adsb.moved", Geo: &pb.GeoSpatialComponent{Latitude: 2}},
+			{Id: "adsb.new", Label: ptr("new")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(resp.Added, []string{"adsb.new"}) ||
+		!slices.Equal(resp.Updated, []string{"adsb.moved"}) ||
+		!slices.Equal(resp.Pruned, []string{"adsb.gone"}) {
+		t.Errorf("got %+v", resp)
+	}
+	if got := w.GetHead("adsb.moved").Geo.GetLatitude(); got != 2 {
+		t.Errorf("adsb.moved latitude %v, want 2", got)
+	}
+	if got := w.GetHead("adsb.new").Controller.GetId(); got != "adsb" {
+		t.Errorf("adsb.new controller %q, want adsb", got)
+	}
+	if n := c.queueDepth(); n != 3 {
+		t.Errorf("%d notifications, want 3 (unchanged entity must not be dirtied)", n)
+	}
+
+	w.GC()
+	if w.GetHead("adsb.gone") != nil {
+		t.Error("pruned entity still in head after GC")
+	}
+	if w.GetHead("ais.1") == nil {
+		t.Error("entity of another controller was pruned")
+	}
+}
+
+func TestSyncController_RejectsForeignEntity(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"adsb.1": {Id: "adsb.1", Controller: &pb.Controller{Id: ptr("adsb")}},
+		"ais.1":  {Id: "ais.1", Controller: &pb.Controller{Id: ptr("ais")}},
+	})
+
+	_, err := w.SyncController(context.Background(), SyncControllerRequest{
+		Controller: "adsb",
+		Entities:   []*pb.Entity{{Id: "ais.1", Label: ptr("stolen")}},
+	})
+	if connect.CodeOf(err) != connect.CodePermissionDenied {
+		t.Fatalf("got %v, want PermissionDenied", err)
+	}
+	if w.GetHead("adsb.1").GetLifetime().GetUntil() != nil {
+		t.Error("failed sync pruned entities")
+	}
+}
+
+func TestSyncController_PruneNeedsExpireAuthorization(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"adsb.1": {Id: "adsb.1", Controller: &pb.Controller{Id: ptr("adsb")}},
+		"adsb.2": {Id: "adsb.2", Controller: &pb.Controller{Id: ptr("adsb")}, Label: ptr("protected")},
+	})
+	w.SetExpireAuthorizer(func(peerIP string, entity *pb.Entity, components []uint32) error {
+		if entity.GetLabel() == "protected" {
+			return errors.New("protected")
+		}
+		return nil
+	})
+
+	_, err := w.SyncController(context.Background(), SyncControllerRequest{
+		Controller: "adsb",
+		Entities:   []*pb.Entity{{Id: "adsb.new"}},
+		PeerAddr:   "192.0.2.1:1234",
+	})
+	if connect.CodeOf(err) != connect.CodePermissionDenied {
+		t.Fatalf("got %v, want PermissionDenied", err)
+	}
+	if w.GetHead("adsb.new") != nil {
+		t.Error("refused sync added entities")
+	}
+	for _, id := range []string{"adsb.1", "adsb.2"} {
+		if w.GetHead(id).GetLifetime().GetUntil() != nil {
+			t.Errorf("refused sync pruned %s", id)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSConfig enables TLS on the engine's server port. The zero value serves
+// plaintext, which is the default for local use. The in-process listener
+// of the builtins is never encrypted.
+type TLSConfig struct {
+	// CertFile and KeyFile are the PEM encoded server certificate and
+	// key. Setting them enables TLS.
+	CertFile string
+	KeyFile  string
+	// ClientCAFile, if set, is a PEM bundle of the CAs client certificates
+	// must be signed by. Clients without such a certificate are rejected
+	// during the handshake, and the identity of those accepted is passed to
+	// the authorizer.
+	ClientCAFile string
+}
+
+// Enabled reports whether TLS is configured.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// serverConfig builds the tls.Config of the server port. It offers HTTP/2
+// so that gRPC clients can connect.
+func (c TLSConfig) serverConfig() (*tls.Config, error) {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, errors.New("TLS needs both a certificate and a key")
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in client CA %s", c.ClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
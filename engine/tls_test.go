@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	_goconnect "github.com/projectqai/proto/go/_goconnect"
+	"golang.org/x/net/http2"
+)
+
+// startTLSWorld serves w over TLS as StartEngine does and returns its URL.
+func startTLSWorld(t *testing.T, w *WorldServer, cfg TLSConfig) string {
+	t.Helper()
+	tlsConfig, err := cfg.serverConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	path, h := _goconnect.NewWorldServiceHandler(w, connect.WithInterceptors(&authInterceptor{authorize: w.authorizer}))
+	mux.Handle(path, withClientIdentity(h))
+
+	srv := &http.Server{Handler: mux}
+	if err := http2.ConfigureServer(srv, &http2.Server{}); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Serve(tls.NewListener(ln, tlsConfig)) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return "https://" + ln.Addr().String()
+}
+
+func tlsClient(url string, roots *x509.CertPool, certs ...tls.Certificate) _goconnect.WorldServiceClient {
+	tr := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}
+	return _goconnect.NewWorldServiceClient(&http.Client{Transport: tr}, url)
+}
+
+func TestTLS_ServerOnly(t *testing.T) {
+	ca := newTestCA(t)
+	certFile, keyFile := writeTLSFiles(t, t.TempDir(), "server", ca.issue(t, "hydris", true))
+	url := startTLSWorld(t, NewWorldServer(), TLSConfig{CertFile: certFile, KeyFile: keyFile})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	_, err := tlsClient(url, roots).GetEntity(context.Background(), connect.NewRequest(&pb.GetEntityRequest{Id: "missing"}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Fatalf("got %v, want the request to reach the server and find nothing", err)
+	}
+}
+
+func TestTLS_RequiredClientCert(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := writeTLSFiles(t, dir, "server", ca.issue(t, "hydris", true))
+	caFile := ca.writePEM(t, dir)
+
+	var identity atomic.Pointer[Identity]
+	w := NewWorldServer()
+	w.SetAuthorizer(func(ctx context.Context, in AuthInput) error {
+		identity.Store(in.Identity)
+		return nil
+	})
+	url := startTLSWorld(t, w, TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(c _goconnect.WorldServiceClient) error {
+		_, err := c.GetEntity(context.Background(), connect.NewRequest(&pb.GetEntityRequest{Id: "missing"}))
+		return err
+	}
+
+	if err := get(tlsClient(url, roots, ca.issue(t, "ops", false))); connect.CodeOf(err) != connect.CodeNotFound {
+		t.Fatalf("trusted client: got %v, want not found", err)
+	}
+	if id := identity.Load(); id == nil || id.CommonName != "ops" {
+		t.Errorf("authorizer saw identity %+v, want ops", id)
+	}
+
+	if err := get(tlsClient(url, roots, newTestCA(t).issue(t, "ops", false))); err == nil || connect.CodeOf(err) == connect.CodeNotFound {
+		t.Errorf("untrusted client: got %v, want the handshake to fail", err)
+	}
+	if err := get(tlsClient(url, roots)); err == nil || connect.CodeOf(err) == connect.CodeNotFound {
+		t.Errorf("client without certificate: got %v, want the handshake to fail", err)
+	}
+}
+
+func TestTLSConfig_Invalid(t *testing.T) {
+	if (TLSConfig{}).Enabled() {
+		t.Error("zero TLSConfig should be disabled")
+	}
+	if _, err := (TLSConfig{CertFile: "server.pem"}).serverConfig(); err == nil {
+		t.Error("expected an error without key")
+	}
+	if _, err := (TLSConfig{CertFile: "missing.pem", KeyFile: "missing.key"}).serverConfig(); err == nil {
+		t.Error("expected an error for missing files")
+	}
+}
@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+
+	"connectrpc.com/connect"
+)
+
+// TraceIDHeader carries the trace id of an RPC. Clients may set it to
+// correlate their own logs with the engine's; otherwise the engine
+// generates one. It is echoed in the response headers either way.
+const TraceIDHeader = "Hydris-Trace-Id"
+
+// maxTraceIDLength bounds client supplied trace ids.
+const maxTraceIDLength = 64
+
+type traceIDKey struct{}
+
+// WithTraceID returns a copy of ctx carrying the trace id.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceIDFromContext returns the trace id of the current request, or ""
+// if there is none.
+func TraceIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	return id
+}
+
+func newTraceID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validTraceID reports whether a client supplied trace id is safe to put
+// into log lines.
+func validTraceID(id string) bool {
+	if id == "" || len(id) > maxTraceIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// traceIDFromHeader returns the trace id the client sent, or a new one.
+func traceIDFromHeader(h interface{ Get(string) string }) string {
+	if id := h.Get(TraceIDHeader); validTraceID(id) {
+		return id
+	}
+	return newTraceID()
+}
+
+// traceInterceptor assigns every RPC a trace id and stores it in the
+// request context.
+type traceInterceptor struct{}
+
+// NewTraceInterceptor returns a connect interceptor that takes the trace id
+// of each RPC from TraceIDHeader, or generates one, and stores it in the
+// handler context. On the client side it forwards the trace id of the
+// calling context, if any.
+func NewTraceInterceptor() connect.Interceptor {
+	return traceInterceptor{}
+}
+
+func (traceInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			if id := TraceIDFromContext(ctx); id != "" {
+				req.Header().Set(TraceIDHeader, id)
+			}
+			return next(ctx, req)
+		}
+
+		id := traceIDFromHeader(req.Header())
+		resp, err := next(WithTraceID(ctx, id), req)
+		if resp != nil {
+			resp.Header().Set(TraceIDHeader, id)
+		}
+		return resp, err
+	}
+}
+
+func (traceInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		if id := TraceIDFromContext(ctx); id != "" {
+			conn.RequestHeader().Set(TraceIDHeader, id)
+		}
+		return conn
+	}
+}
+
+func (traceInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		id := traceIDFromHeader(conn.RequestHeader())
+		conn.ResponseHeader().Set(TraceIDHeader, id)
+		return next(WithTraceID(ctx, id), conn)
+	}
+}
+
+// traceHandler adds the trace id of the record's context to every record.
+type traceHandler struct {
+	slog.Handler
+}
+
+// NewTraceHandler wraps h so that records logged with a context carrying
+// a trace id get a "traceID" attribute.
+func NewTraceHandler(h slog.Handler) slog.Handler {
+	return traceHandler{h}
+}
+
+func (h traceHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := TraceIDFromContext(ctx); id != "" {
+		r = r.Clone()
+		r.AddAttrs(slog.String("traceID", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h traceHandler) WithGroup(name string) slog.Handler {
+	return traceHandler{h.Handler.WithGroup(name)}
+}
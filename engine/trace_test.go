@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+
+	"connectrpc.com/connect"
+)
+
+// captureHandler records the message and trace id of every log record.
+type captureHandler struct {
+	mu    sync.Mutex
+	lines map[string]string // message -> traceID attribute
+}
+
+func (h *captureHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *captureHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *captureHandler) WithGroup(string) slog.Handler            { return h }
+
+func (h *captureHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	traceID := ""
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "traceID" {
+			traceID = a.Value.String()
+		}
+		return true
+	})
+	h.lines[r.Message] = traceID
+	return nil
+}
+
+func (h *captureHandler) traceOf(msg string) (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	id, ok := h.lines[msg]
+	return id, ok
+}
+
+func captureLogs(t *testing.T) *captureHandler {
+	h := &captureHandler{lines: make(map[string]string)}
+	prev := slog.Default()
+	slog.SetDefault(slog.New(NewTraceHandler(h)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return h
+}
+
+func TestTraceInterceptor_Unary(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"honors header", "client-req.42", true},
+		{"generates id", "", false},
+		{"replaces invalid id", "bad id\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			next := func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+				seen = TraceIDFromContext(ctx)
+				return connect.NewResponse(&pb.EntityChangeResponse{}), nil
+			}
+			req := peerRequest(&pb.EntityChangeRequest{})
+			if tt.header != "" {
+				req.Header().Set(TraceIDHeader, tt.header)
+			}
+			resp, err := NewTraceInterceptor().WrapUnary(next)(context.Background(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if seen == "" || (tt.keep && seen != tt.header) || (!tt.keep && seen == tt.header) {
+				t.Errorf("trace id %q for header %q", seen, tt.header)
+			}
+			if got := resp.Header().Get(TraceIDHeader); got != seen {
+				t.Errorf("response header %q, want %q", got, seen)
+			}
+		})
+	}
+}
+
+func TestTraceID_PropagatesToSender(t *testing.T) {
+	logs := captureLogs(t)
+	w := testWorld(nil)
+
+	c := NewConsumer(w, nil, nil)
+	w.bus.Register(c)
+	defer w.bus.Unregister(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sent := make(chan struct{}, 1)
+	go func() {
+		_ = c.SenderLoop(ctx, func(ev *pb.EntityChangeEvent) error {
+			select {
+			case sent <- struct{}{}:
+			default:
+			}
+			return nil
+		})
+	}()
+
+	push := NewTraceInterceptor().WrapUnary(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		return w.Push(ctx, req.(*connect.Request[pb.EntityChangeRequest]))
+	})
+	req := peerRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{{Id: "e1"}}})
+	req.Header().Set(TraceIDHeader, "trace-1")
+	if _, err := push(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("change was not sent")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for _, msg := range []string{"push applied", "bus: entity dirty", "consumer: sent change"} {
+		id, ok := logs.traceOf(msg)
+		for !ok && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+			id, ok = logs.traceOf(msg)
+		}
+		if id != "trace-1" {
+			t.Errorf("%q logged with trace id %q, want trace-1", msg, id)
+		}
+	}
+}
+
+func TestTraceID_UntracedChangeNotLogged(t *testing.T) {
+	logs := captureLogs(t)
+	w := testWorld(map[string]*pb.Entity{"e1": {Id: "e1"}})
+
+	c := NewConsumer(w, nil, nil)
+	w.bus.Register(c)
+	w.bus.DirtyContext(WithTraceID(context.Background(), "trace-1"), "e1", w.GetHead("e1"), pb.EntityChange_EntityChangeUpdated)
+	w.bus.Dirty("e1", w.GetHead("e1"), pb.EntityChange_EntityChangeUpdated)
+
+	if _, _, _, ok := c.popNext(); !ok {
+		t.Fatal("expected a dirty entity")
+	}
+	if id := c.takeTrace("e1"); id != "" {
+		t.Errorf("later untraced change kept trace id %q", id)
+	}
+	if _, ok := logs.traceOf("consumer: sent change"); ok {
+		t.Error("nothing was sent")
+	}
+}
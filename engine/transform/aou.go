@@ -3,6 +3,7 @@ package transform
 import (
 	"math"
 
+	"github.com/projectqai/hydris/pkg/ellipse"
 	pb "github.com/projectqai/proto/go"
 )
 
@@ -87,36 +88,17 @@ func (t *AOUTransformer) Resolve(head map[string]*pb.Entity, changedID string) (
 // computeEllipseAOU sets entity.Shape to a polygon approximating the AOU
 // ellipse derived from the 2×2 position covariance matrix.
 func (t *AOUTransformer) computeEllipseAOU(entity *pb.Entity) {
-	cov := entity.Geo.Covariance
-	mxx := cov.GetMxx()
-	myy := cov.GetMyy()
-	mxy := cov.GetMxy()
-
-	// Eigenvalues of 2×2 symmetric matrix [[mxx, mxy], [mxy, myy]]
-	avg := (mxx + myy) / 2.0
-	diff := (mxx - myy) / 2.0
-	disc := math.Sqrt(diff*diff + mxy*mxy)
-
-	lambda1 := avg + disc // larger eigenvalue
-	lambda2 := avg - disc // smaller eigenvalue
-
-	if lambda1 <= 0 {
+	e, ok := ellipse.FromCovariance(entity.Geo.Covariance)
+	if !ok {
 		return
 	}
-	if lambda2 < 0 {
-		lambda2 = 0
-	}
-
-	// Semi-axes in meters (1-sigma)
-	semiMajor := math.Sqrt(lambda1)
-	semiMinor := math.Sqrt(lambda2)
 
 	lat := entity.Geo.Latitude
 	lon := entity.Geo.Longitude
 
 	// Use a circle when the ellipse is nearly isotropic (axes within 5%)
-	if semiMajor > 0 && (semiMajor-semiMinor)/semiMajor < 0.05 {
-		radius := (semiMajor + semiMinor) / 2.0
+	if (e.SemiMajorM-e.SemiMinorM)/e.SemiMajorM < 0.05 {
+		radius := (e.SemiMajorM + e.SemiMinorM) / 2.0
 		entity.Shape = &pb.GeoShapeComponent{
 			Geometry: &pb.Geometry{
 				Planar: &pb.PlanarGeometry{
@@ -132,26 +114,11 @@ func (t *AOUTransformer) computeEllipseAOU(entity *pb.Entity) {
 		return
 	}
 
-	// Rotation angle of the major axis.
-	// Covariance is in ENU frame: x=East, y=North.
-	// Eigenvector for lambda1 is (mxy, lambda1-mxx).
-	// atan2(east, north) gives bearing from north, clockwise.
-	var theta float64
-	if mxy != 0 {
-		theta = math.Atan2(mxy, lambda1-mxx)
-	} else if mxx >= myy {
-		theta = math.Pi / 2 // major axis along East
-	}
-
-	points := make([]*pb.PlanarPoint, ellipseSegments+1)
-	for i := range ellipseSegments {
-		angle := 2.0 * math.Pi * float64(i) / float64(ellipseSegments)
-		// Parametric ellipse rotated by theta in ENU frame
-		eastM := semiMajor*math.Cos(angle)*math.Sin(theta) + semiMinor*math.Sin(angle)*math.Cos(theta)
-		northM := semiMajor*math.Cos(angle)*math.Cos(theta) - semiMinor*math.Sin(angle)*math.Sin(theta)
-		points[i] = enuToWGS84(eastM, northM, 0, false, lat, lon)
+	points := make([]*pb.PlanarPoint, 0, ellipseSegments+1)
+	for _, o := range e.Offsets(ellipseSegments) {
+		points = append(points, enuToWGS84(o[0], o[1], 0, false, lat, lon))
 	}
-	points[ellipseSegments] = points[0] // close the ring
+	points = append(points, points[0]) // close the ring
 
 	entity.Shape = &pb.GeoShapeComponent{
 		Geometry: &pb.Geometry{
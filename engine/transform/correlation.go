@@ -0,0 +1,335 @@
+package transform
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// CorrelationTrackerPrefix starts the group id stamped into Track.Tracker of
+// correlated entities. The rest is the lowest entity id of the group.
+const CorrelationTrackerPrefix = "correlation."
+
+// CorrelationTransformer links entities from different controllers that
+// probably report the same physical object, e.g. a ship seen by AIS and by
+// a radar. Two entities correlate when they are within maxDistance of each
+// other, were last observed within window of each other and their battle
+// dimensions do not contradict. Correlated entities form groups. Entities
+// are not merged; every member gets the group id as its Track.Tracker, so
+// Watch consumers and the Track filter see which entities belong together.
+// The Track an entity had before is put back once it leaves its group.
+//
+// Positions are kept in a grid of cells about maxDistance wide, so a
+// change is only compared with the entities in the neighbouring cells.
+type CorrelationTransformer struct {
+	maxDistance float64
+	window      time.Duration
+
+	// links holds, per entity, the entities it correlated with when either
+	// last changed. Links are symmetric.
+	links map[string]map[string]struct{}
+
+	// cellHeight is the height of a grid row in degrees of latitude.
+	cellHeight float64
+	cells      map[string]gridCell
+	grid       map[gridCell]map[string]struct{}
+
+	// stamped is the group id written into an entity's Track.Tracker, and
+	// tracks the Track it had before, nil if it had none.
+	stamped map[string]string
+	tracks  map[string]*pb.TrackComponent
+}
+
+type gridCell struct{ row, col int }
+
+func NewCorrelationTransformer(maxDistanceM float64, window time.Duration) *CorrelationTransformer {
+	// One degree of latitude is the same distance everywhere; the margin
+	// keeps rounding from pushing a match past the neighbouring cell.
+	cellHeight := 1.01 * maxDistanceM / (orb.EarthRadius * math.Pi / 180)
+	if cellHeight < 1e-6 {
+		cellHeight = 1e-6
+	}
+	return &CorrelationTransformer{
+		maxDistance: maxDistanceM,
+		window:      window,
+		links:       make(map[string]map[string]struct{}),
+		cellHeight:  cellHeight,
+		cells:       make(map[string]gridCell),
+		grid:        make(map[gridCell]map[string]struct{}),
+		stamped:     make(map[string]string),
+		tracks:      make(map[string]*pb.TrackComponent),
+	}
+}
+
+func (t *CorrelationTransformer) Validate(_ map[string]*pb.Entity, _ *pb.Entity) error {
+	return nil
+}
+
+func (t *CorrelationTransformer) Resolve(head map[string]*pb.Entity, changedID string) (upsert []*pb.Entity, remove []string) {
+	entity := head[changedID]
+	// A Track that is not our stamp was pushed by the source since; it is
+	// what to restore from now on.
+	if st, ok := t.stamped[changedID]; ok && (entity == nil || entity.Track.GetTracker() != st) {
+		delete(t.stamped, changedID)
+		delete(t.tracks, changedID)
+	}
+
+	affected := []string{changedID}
+	for id := range t.links[changedID] {
+		affected = append(affected, id)
+	}
+	t.unlink(changedID)
+	t.unplace(changedID)
+
+	if entity != nil && entity.Geo != nil {
+		t.place(changedID, entity.Geo)
+		for _, id := range t.nearby(entity.Geo) {
+			if other := head[id]; id != changedID && other != nil && t.correlates(entity, other) {
+				t.link(changedID, id)
+				affected = append(affected, id)
+			}
+		}
+	}
+
+	for id, group := range t.groupIDs(affected) {
+		e := head[id]
+		if e == nil {
+			continue
+		}
+		if id == changedID {
+			t.stamp(e, group)
+			continue
+		}
+		if group == t.stamped[id] {
+			continue
+		}
+		e = proto.Clone(e).(*pb.Entity)
+		t.stamp(e, group)
+		upsert = append(upsert, e)
+	}
+	slices.SortFunc(upsert, func(a, b *pb.Entity) int { return strings.Compare(a.Id, b.Id) })
+	return upsert, nil
+}
+
+// groupIDs returns the group id of every entity in the groups of ids, or
+// "" for those that are in no group.
+func (t *CorrelationTransformer) groupIDs(ids []string) map[string]string {
+	out := make(map[string]string)
+	for _, id := range ids {
+		if _, done := out[id]; done {
+			continue
+		}
+		group := t.group(id)
+		if len(group) == 1 {
+			out[id] = ""
+			continue
+		}
+		for _, member := range group {
+			out[member] = CorrelationTrackerPrefix + group[0]
+		}
+	}
+	return out
+}
+
+// stamp sets the group id as e's tracker, or restores e's own Track if
+// group is empty.
+func (t *CorrelationTransformer) stamp(e *pb.Entity, group string) {
+	st, ok := t.stamped[e.Id]
+	if group == "" {
+		if ok {
+			e.Track = t.tracks[e.Id]
+			delete(t.stamped, e.Id)
+			delete(t.tracks, e.Id)
+		}
+		return
+	}
+	if ok && st == group {
+		return
+	}
+	if !ok {
+		t.tracks[e.Id] = e.Track
+	}
+	track := &pb.TrackComponent{}
+	if e.Track != nil {
+		track = proto.Clone(e.Track).(*pb.TrackComponent)
+	}
+	track.Tracker = &group
+	e.Track = track
+	t.stamped[e.Id] = group
+}
+
+// cellOf returns the grid cell of a position. Rows are cellHeight high;
+// each row has as many columns as fit while every column stays at least
+// maxDistance wide at the pole-ward edge of the row and its neighbours.
+func (t *CorrelationTransformer) cellOf(lat, lon float64) gridCell {
+	row := int(math.Floor(lat / t.cellHeight))
+	return gridCell{row, t.column(row, lon)}
+}
+
+func (t *CorrelationTransformer) column(row int, lon float64) int {
+	n := t.columns(row)
+	col := int(math.Floor((lon + 180) / 360 * float64(n)))
+	return ((col % n) + n) % n
+}
+
+func (t *CorrelationTransformer) columns(row int) int {
+	edge := float64(max(abs(row), abs(row+1))+1) * t.cellHeight
+	if edge >= 90 {
+		return 1
+	}
+	width := t.cellHeight / math.Cos(edge*math.Pi/180)
+	return max(1, int(360/width))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// nearby returns the entities in the cell of g and its neighbours, which
+// includes every entity within maxDistance of g.
+func (t *CorrelationTransformer) nearby(g *pb.GeoSpatialComponent) []string {
+	row := int(math.Floor(g.Latitude / t.cellHeight))
+	seen := make(map[gridCell]bool, 9)
+	var ids []string
+	for r := row - 1; r <= row+1; r++ {
+		n := t.columns(r)
+		col := t.column(r, g.Longitude)
+		for dc := -1; dc <= 1; dc++ {
+			cell := gridCell{r, ((col+dc)%n + n) % n}
+			if seen[cell] {
+				continue
+			}
+			seen[cell] = true
+			for id := range t.grid[cell] {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+func (t *CorrelationTransformer) place(id string, g *pb.GeoSpatialComponent) {
+	cell := t.cellOf(g.Latitude, g.Longitude)
+	if t.grid[cell] == nil {
+		t.grid[cell] = make(map[string]struct{})
+	}
+	t.grid[cell][id] = struct{}{}
+	t.cells[id] = cell
+}
+
+func (t *CorrelationTransformer) unplace(id string) {
+	cell, ok := t.cells[id]
+	if !ok {
+		return
+	}
+	delete(t.grid[cell], id)
+	if len(t.grid[cell]) == 0 {
+		delete(t.grid, cell)
+	}
+	delete(t.cells, id)
+}
+
+func (t *CorrelationTransformer) correlates(a, b *pb.Entity) bool {
+	if b.Geo == nil {
+		return false
+	}
+	// A single source reports distinct objects as distinct entities.
+	if ca, cb := a.Controller.GetId(), b.Controller.GetId(); ca != "" && ca == cb {
+		return false
+	}
+	if !compatibleDimensions(a.Classification.GetDimension(), b.Classification.GetDimension()) {
+		return false
+	}
+	if ta, tb := observedAt(a), observedAt(b); !ta.IsZero() && !tb.IsZero() {
+		if d := ta.Sub(tb); d > t.window || d < -t.window {
+			return false
+		}
+	}
+	return geo.Distance(orb.Point{a.Geo.Longitude, a.Geo.Latitude}, orb.Point{b.Geo.Longitude, b.Geo.Latitude}) <= t.maxDistance
+}
+
+// compatibleDimensions reports whether two battle dimensions may describe
+// the same object; an unset dimension is compatible with any.
+func compatibleDimensions(a, b pb.ClassificationBattleDimension) bool {
+	invalid := pb.ClassificationBattleDimension_ClassificationBattleDimensionInvalid
+	return a == invalid || b == invalid || a == b
+}
+
+// observedAt is when the entity was last seen fresh, or zero if unknown.
+func observedAt(e *pb.Entity) time.Time {
+	if e.Lifetime == nil {
+		return time.Time{}
+	}
+	if e.Lifetime.Fresh.IsValid() {
+		return e.Lifetime.Fresh.AsTime()
+	}
+	if e.Lifetime.From.IsValid() {
+		return e.Lifetime.From.AsTime()
+	}
+	return time.Time{}
+}
+
+func (t *CorrelationTransformer) link(a, b string) {
+	for _, pair := range [][2]string{{a, b}, {b, a}} {
+		if t.links[pair[0]] == nil {
+			t.links[pair[0]] = make(map[string]struct{})
+		}
+		t.links[pair[0]][pair[1]] = struct{}{}
+	}
+}
+
+func (t *CorrelationTransformer) unlink(id string) {
+	for other := range t.links[id] {
+		delete(t.links[other], id)
+		if len(t.links[other]) == 0 {
+			delete(t.links, other)
+		}
+	}
+	delete(t.links, id)
+}
+
+// Groups returns the sets of correlated entities, each sorted by id, in
+// order of their first id. Entities correlate transitively: if A is next
+// to B and B next to C, all three are one group.
+func (t *CorrelationTransformer) Groups() [][]string {
+	seen := make(map[string]bool, len(t.links))
+	var groups [][]string
+	for id := range t.links {
+		if seen[id] {
+			continue
+		}
+		group := t.group(id)
+		for _, member := range group {
+			seen[member] = true
+		}
+		groups = append(groups, group)
+	}
+	slices.SortFunc(groups, func(a, b []string) int { return strings.Compare(a[0], b[0]) })
+	return groups
+}
+
+// group returns the entities transitively linked to id, id included,
+// sorted by id.
+func (t *CorrelationTransformer) group(id string) []string {
+	seen := map[string]bool{id: true}
+	group := []string{id}
+	for i := 0; i < len(group); i++ {
+		for other := range t.links[group[i]] {
+			if !seen[other] {
+				seen[other] = true
+				group = append(group, other)
+			}
+		}
+	}
+	slices.Sort(group)
+	return group
+}
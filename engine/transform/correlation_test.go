@@ -0,0 +1,209 @@
+package transform
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func correlationEntity(id, controller string, lat, lon float64, fresh time.Time) *pb.Entity {
+	return &pb.Entity{
+		Id:         id,
+		Controller: &pb.Controller{Id: &controller},
+		Geo:        &pb.GeoSpatialComponent{Latitude: lat, Longitude: lon},
+		Lifetime:   &pb.Lifetime{Fresh: timestamppb.New(fresh)},
+	}
+}
+
+func TestCorrelation_NearbyEntitiesCorrelate(t *testing.T) {
+	now := time.Now()
+	ct := NewCorrelationTransformer(100, 10*time.Second)
+	head := map[string]*pb.Entity{
+		"ais.1":   correlationEntity("ais.1", "ais", 54.0, 10.0, now),
+		"radar.7": correlationEntity("radar.7", "radar", 54.0003, 10.0, now.Add(-2*time.Second)),
+		"adsb.9":  correlationEntity("adsb.9", "adsb", 55.0, 11.0, now),
+	}
+	for id := range head {
+		ct.Resolve(head, id)
+	}
+
+	groups := ct.Groups()
+	if len(groups) != 1 || len(groups[0]) != 2 || groups[0][0] != "ais.1" || groups[0][1] != "radar.7" {
+		t.Fatalf("expected ais.1 and radar.7 correlated, got %v", groups)
+	}
+
+	// Moving away breaks the link.
+	head["radar.7"].Geo.Latitude = 54.1
+	ct.Resolve(head, "radar.7")
+	if groups := ct.Groups(); len(groups) != 0 {
+		t.Errorf("expected no groups after moving apart, got %v", groups)
+	}
+}
+
+func TestCorrelation_FarApartDoNotCorrelate(t *testing.T) {
+	now := time.Now()
+	ct := NewCorrelationTransformer(100, 10*time.Second)
+	head := map[string]*pb.Entity{
+		"ais.1":   correlationEntity("ais.1", "ais", 54.0, 10.0, now),
+		"radar.7": correlationEntity("radar.7", "radar", 54.01, 10.0, now),
+	}
+	ct.Resolve(head, "ais.1")
+	ct.Resolve(head, "radar.7")
+
+	if groups := ct.Groups(); len(groups) != 0 {
+		t.Errorf("expected no groups, got %v", groups)
+	}
+}
+
+func TestCorrelation_Rules(t *testing.T) {
+	now := time.Now()
+	air := pb.ClassificationBattleDimension_ClassificationBattleDimensionAir
+	sea := pb.ClassificationBattleDimension_ClassificationBattleDimensionSeaSurface
+
+	tests := []struct {
+		name   string
+		modify func(b *pb.Entity)
+		want   bool
+	}{
+		{"same position", func(b *pb.Entity) {}, true},
+		{"same controller", func(b *pb.Entity) { *b.Controller.Id = "ais" }, false},
+		{"outside time window", func(b *pb.Entity) { b.Lifetime.Fresh = timestamppb.New(now.Add(-time.Minute)) }, false},
+		{"no timestamps", func(b *pb.Entity) { b.Lifetime = nil }, true},
+		{"conflicting dimensions", func(b *pb.Entity) { b.Classification = &pb.ClassificationComponent{Dimension: &air} }, false},
+		{"no position", func(b *pb.Entity) { b.Geo = nil }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := correlationEntity("ais.1", "ais", 54, 10, now)
+			a.Classification = &pb.ClassificationComponent{Dimension: &sea}
+			b := correlationEntity("radar.7", "radar", 54, 10, now)
+			tt.modify(b)
+
+			ct := NewCorrelationTransformer(100, 10*time.Second)
+			if got := ct.correlates(a, b); got != tt.want {
+				t.Errorf("correlates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCorrelation_ExpiryAndTransitiveGroups(t *testing.T) {
+	now := time.Now()
+	ct := NewCorrelationTransformer(100, 10*time.Second)
+	// a-b and b-c are within 100 m, a-c is not.
+	head := map[string]*pb.Entity{
+		"a": correlationEntity("a", "ais", 54.0, 10.0, now),
+		"b": correlationEntity("b", "radar", 54.0006, 10.0, now),
+		"c": correlationEntity("c", "adsb", 54.0012, 10.0, now),
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		ct.Resolve(head, id)
+	}
+	if groups := ct.Groups(); len(groups) != 1 || len(groups[0]) != 3 {
+		t.Fatalf("expected one transitive group, got %v", groups)
+	}
+
+	delete(head, "b")
+	ct.Resolve(head, "b")
+	if groups := ct.Groups(); len(groups) != 0 {
+		t.Errorf("expected no groups after b expired, got %v", groups)
+	}
+}
+
+func TestCorrelation_StampsGroupOnTrack(t *testing.T) {
+	now := time.Now()
+	ct := NewCorrelationTransformer(100, 10*time.Second)
+	radar := correlationEntity("radar.7", "radar", 54.0003, 10.0, now)
+	radar.Track = &pb.TrackComponent{Tracker: proto.String("radar")}
+	head := map[string]*pb.Entity{
+		"ais.1":   correlationEntity("ais.1", "ais", 54.0, 10.0, now),
+		"radar.7": radar,
+	}
+	ct.Resolve(head, "ais.1")
+	upsert, _ := ct.Resolve(head, "radar.7")
+
+	// The changed entity is stamped in place, its partner is upserted.
+	if got := head["radar.7"].Track.GetTracker(); got != "correlation.ais.1" {
+		t.Errorf("radar.7 tracker = %q, want correlation.ais.1", got)
+	}
+	if len(upsert) != 1 || upsert[0].Id != "ais.1" || upsert[0].Track.GetTracker() != "correlation.ais.1" {
+		t.Fatalf("expected ais.1 upserted with the group id, got %v", upsert)
+	}
+	if head["ais.1"].Track != nil {
+		t.Error("upsert modified the entity in head")
+	}
+	head["ais.1"] = upsert[0]
+
+	// Moving apart restores what each entity had before.
+	head["radar.7"].Geo.Latitude = 54.1
+	upsert, _ = ct.Resolve(head, "radar.7")
+	if got := head["radar.7"].Track.GetTracker(); got != "radar" {
+		t.Errorf("radar.7 tracker = %q after leaving the group, want radar", got)
+	}
+	if len(upsert) != 1 || upsert[0].Id != "ais.1" || upsert[0].Track != nil {
+		t.Errorf("expected ais.1 upserted without track, got %v", upsert)
+	}
+}
+
+func TestCorrelation_SourceTrackReplacesStamp(t *testing.T) {
+	now := time.Now()
+	ct := NewCorrelationTransformer(100, 10*time.Second)
+	head := map[string]*pb.Entity{
+		"ais.1":   correlationEntity("ais.1", "ais", 54.0, 10.0, now),
+		"radar.7": correlationEntity("radar.7", "radar", 54.0003, 10.0, now),
+	}
+	ct.Resolve(head, "ais.1")
+	ct.Resolve(head, "radar.7")
+
+	// The source pushes its own track while correlated; the stamp wins
+	// again, and the pushed track is what is restored later.
+	head["radar.7"].Track = &pb.TrackComponent{Tracker: proto.String("fused")}
+	ct.Resolve(head, "radar.7")
+	if got := head["radar.7"].Track.GetTracker(); got != "correlation.ais.1" {
+		t.Errorf("tracker = %q, want correlation.ais.1", got)
+	}
+	delete(head, "ais.1")
+	upsert, _ := ct.Resolve(head, "ais.1")
+	if len(upsert) != 1 || upsert[0].Id != "radar.7" {
+		t.Fatalf("expected radar.7 upserted, got %v", upsert)
+	}
+	if got := upsert[0].Track.GetTracker(); got != "fused" {
+		t.Errorf("tracker = %q after ais.1 expired, want fused", got)
+	}
+}
+
+func TestCorrelation_GridFindsAllNeighbours(t *testing.T) {
+	now := time.Now()
+	rng := rand.New(rand.NewPCG(1, 2))
+	// Clusters on the equator, at high latitude and across the
+	// antimeridian, each point within a few hundred metres of others.
+	centers := [][2]float64{{0, 0}, {78, 20}, {-60, 179.999}, {89.999, 0}}
+	head := make(map[string]*pb.Entity)
+	for i := range 400 {
+		c := centers[i%len(centers)]
+		lat := c[0] + (rng.Float64()-0.5)*0.006
+		lon := c[1] + (rng.Float64()-0.5)*0.006/math.Cos(c[0]*math.Pi/180)
+		lon = math.Mod(lon+540, 360) - 180
+		id := fmt.Sprintf("e%03d", i)
+		head[id] = correlationEntity(id, id, min(lat, 90), lon, now)
+	}
+
+	ct := NewCorrelationTransformer(150, 10*time.Second)
+	for id := range head {
+		ct.Resolve(head, id)
+	}
+	for id, e := range head {
+		for oid, o := range head {
+			_, linked := ct.links[id][oid]
+			if want := id != oid && ct.correlates(e, o); linked != want {
+				t.Fatalf("%s-%s linked = %v, want %v", id, oid, linked, want)
+			}
+		}
+	}
+}
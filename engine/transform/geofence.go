@@ -0,0 +1,184 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/paulmach/orb/planar"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// GeofencePrefix marks an entity as a geofence: any entity whose id
+	// starts with it and that has a polygon or circle GeoShapeComponent.
+	// There is no geofence component in the proto module, so the id
+	// namespace is the marker.
+	GeofencePrefix = "geofence."
+
+	// GeofenceAlertPrefix starts the ids of the alert entities the
+	// transformer emits, one per fence and target:
+	// "alert.geofence.<fence id>:<target id>". The separator is one the
+	// engine accepts in entity ids, so alerts can be pushed and federated.
+	GeofenceAlertPrefix = "alert.geofence."
+
+	// geofenceAlertTTL is how long an enter or exit alert stays in the world.
+	geofenceAlertTTL = 5 * time.Minute
+)
+
+// GeofenceTransformer watches entities with a GeoSpatialComponent against
+// geofences and emits a short-lived alert entity whenever one enters or
+// leaves a fence. The alert carries the target's position and says what
+// happened in its label; a later transition of the same target and fence
+// replaces it.
+//
+// Only transitions fire. Entities already inside a fence when it is drawn
+// don't, and neither do targets or fences that expire.
+type GeofenceTransformer struct {
+	now func() time.Time
+
+	// inside holds, per fence, the targets currently inside it.
+	inside map[string]map[string]struct{}
+}
+
+func NewGeofenceTransformer() *GeofenceTransformer {
+	return &GeofenceTransformer{
+		now:    time.Now,
+		inside: make(map[string]map[string]struct{}),
+	}
+}
+
+func (t *GeofenceTransformer) Validate(_ map[string]*pb.Entity, _ *pb.Entity) error {
+	return nil
+}
+
+func (t *GeofenceTransformer) Resolve(head map[string]*pb.Entity, changedID string) (upsert []*pb.Entity, remove []string) {
+	if strings.HasPrefix(changedID, GeofenceAlertPrefix) {
+		return nil, nil
+	}
+	entity := head[changedID]
+
+	if strings.HasPrefix(changedID, GeofencePrefix) {
+		if entity == nil || fenceContains(entity.Shape) == nil {
+			delete(t.inside, changedID)
+			return nil, nil
+		}
+		members, known := t.inside[changedID]
+		if !known {
+			members = make(map[string]struct{})
+			t.inside[changedID] = members
+		}
+		for id, target := range head {
+			if isGeofenceTarget(id, target) {
+				if a := t.update(entity, members, target, known); a != nil {
+					upsert = append(upsert, a)
+				}
+			}
+		}
+		return upsert, nil
+	}
+
+	if !isGeofenceTarget(changedID, entity) {
+		for _, members := range t.inside {
+			delete(members, changedID)
+		}
+		return nil, nil
+	}
+	for fenceID, members := range t.inside {
+		if fence := head[fenceID]; fence != nil {
+			if a := t.update(fence, members, entity, true); a != nil {
+				upsert = append(upsert, a)
+			}
+		}
+	}
+	return upsert, nil
+}
+
+// update records whether target is inside fence and, if alert is set and
+// that changed, returns the alert entity for the transition.
+func (t *GeofenceTransformer) update(fence *pb.Entity, members map[string]struct{}, target *pb.Entity, alert bool) *pb.Entity {
+	contains := fenceContains(fence.Shape)
+	if contains == nil {
+		return nil
+	}
+	_, was := members[target.Id]
+	is := contains(orb.Point{target.Geo.Longitude, target.Geo.Latitude})
+	if was == is {
+		return nil
+	}
+	if is {
+		members[target.Id] = struct{}{}
+	} else {
+		delete(members, target.Id)
+	}
+	if !alert {
+		return nil
+	}
+
+	verb := "left"
+	if is {
+		verb = "entered"
+	}
+	now := t.now()
+	return &pb.Entity{
+		Id:         GeofenceAlertPrefix + fence.Id + ":" + target.Id,
+		Label:      proto.String(fmt.Sprintf("%s %s %s", displayName(target), verb, displayName(fence))),
+		Controller: &pb.Controller{Id: proto.String("geofence")},
+		Geo:        &pb.GeoSpatialComponent{Latitude: target.Geo.Latitude, Longitude: target.Geo.Longitude},
+		Lifetime: &pb.Lifetime{
+			From:  timestamppb.New(now),
+			Until: timestamppb.New(now.Add(geofenceAlertTTL)),
+		},
+	}
+}
+
+// isGeofenceTarget reports whether entity is checked against geofences.
+func isGeofenceTarget(id string, entity *pb.Entity) bool {
+	return entity != nil && entity.Geo != nil &&
+		!strings.HasPrefix(id, GeofencePrefix) && !strings.HasPrefix(id, GeofenceAlertPrefix)
+}
+
+// fenceContains returns the point-in-fence test for shape, or nil if the
+// shape is not a polygon or circle.
+func fenceContains(shape *pb.GeoShapeComponent) func(orb.Point) bool {
+	switch g := shape.GetGeometry().GetPlanar().GetPlane().(type) {
+	case *pb.PlanarGeometry_Polygon:
+		outer := g.Polygon.GetOuter().GetPoints()
+		if len(outer) < 3 {
+			return nil
+		}
+		poly := orb.Polygon{planarRing(outer)}
+		for _, hole := range g.Polygon.Holes {
+			poly = append(poly, planarRing(hole.GetPoints()))
+		}
+		return func(p orb.Point) bool { return planar.PolygonContains(poly, p) }
+	case *pb.PlanarGeometry_Circle:
+		center := g.Circle.GetCenter()
+		if center == nil {
+			return nil
+		}
+		c, radius := orb.Point{center.Longitude, center.Latitude}, g.Circle.RadiusM
+		return func(p orb.Point) bool { return geo.Distance(c, p) <= radius }
+	default:
+		return nil
+	}
+}
+
+func planarRing(points []*pb.PlanarPoint) orb.Ring {
+	ring := make(orb.Ring, len(points))
+	for i, pt := range points {
+		ring[i] = orb.Point{pt.Longitude, pt.Latitude}
+	}
+	return ring
+}
+
+func displayName(e *pb.Entity) string {
+	if e.GetLabel() != "" {
+		return e.GetLabel()
+	}
+	return e.Id
+}
@@ -0,0 +1,93 @@
+package transform
+
+import (
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func squareFence(id string) *pb.Entity {
+	ring := []*pb.PlanarPoint{
+		{Latitude: 0, Longitude: 0},
+		{Latitude: 0, Longitude: 1},
+		{Latitude: 1, Longitude: 1},
+		{Latitude: 1, Longitude: 0},
+		{Latitude: 0, Longitude: 0},
+	}
+	return &pb.Entity{
+		Id:    id,
+		Label: proto.String("harbour"),
+		Shape: &pb.GeoShapeComponent{Geometry: &pb.Geometry{Planar: &pb.PlanarGeometry{
+			Plane: &pb.PlanarGeometry_Polygon{Polygon: &pb.PlanarPolygon{Outer: &pb.PlanarRing{Points: ring}}},
+		}}},
+	}
+}
+
+func TestGeofence_EnterThenExit(t *testing.T) {
+	gt := NewGeofenceTransformer()
+	head := map[string]*pb.Entity{
+		"geofence.harbour": squareFence("geofence.harbour"),
+		"ship.1":           {Id: "ship.1", Geo: &pb.GeoSpatialComponent{Latitude: 2, Longitude: 0.5}},
+	}
+	if upsert, _ := gt.Resolve(head, "geofence.harbour"); len(upsert) != 0 {
+		t.Fatalf("drawing the fence fired %d alerts", len(upsert))
+	}
+
+	move := func(lat float64) []*pb.Entity {
+		head["ship.1"].Geo.Latitude = lat
+		upsert, _ := gt.Resolve(head, "ship.1")
+		return upsert
+	}
+
+	alerts := move(0.5)
+	if len(alerts) != 1 || alerts[0].GetLabel() != "ship.1 entered harbour" {
+		t.Fatalf("expected enter alert, got %v", alerts)
+	}
+	if alerts[0].Id != GeofenceAlertPrefix+"geofence.harbour:ship.1" {
+		t.Errorf("alert id %q", alerts[0].Id)
+	}
+	if !alerts[0].Lifetime.GetUntil().IsValid() {
+		t.Error("alert should expire")
+	}
+
+	if alerts := move(0.6); len(alerts) != 0 {
+		t.Errorf("moving inside fired %d alerts", len(alerts))
+	}
+
+	alerts = move(-0.5)
+	if len(alerts) != 1 || alerts[0].GetLabel() != "ship.1 left harbour" {
+		t.Fatalf("expected exit alert, got %v", alerts)
+	}
+
+	// The alert itself has a position but is never a target.
+	head[alerts[0].Id] = alerts[0]
+	if upsert, _ := gt.Resolve(head, alerts[0].Id); len(upsert) != 0 {
+		t.Errorf("alert triggered %d alerts", len(upsert))
+	}
+}
+
+func TestGeofence_Circle(t *testing.T) {
+	gt := NewGeofenceTransformer()
+	head := map[string]*pb.Entity{
+		"geofence.zone": {Id: "geofence.zone", Shape: &pb.GeoShapeComponent{Geometry: &pb.Geometry{Planar: &pb.PlanarGeometry{
+			Plane: &pb.PlanarGeometry_Circle{Circle: &pb.PlanarCircle{Center: &pb.PlanarPoint{Latitude: 48, Longitude: 11}, RadiusM: 1000}},
+		}}}},
+		"drone.1": {Id: "drone.1", Geo: &pb.GeoSpatialComponent{Latitude: 48.1, Longitude: 11}},
+	}
+	gt.Resolve(head, "geofence.zone")
+
+	head["drone.1"].Geo.Latitude = 48.001
+	if upsert, _ := gt.Resolve(head, "drone.1"); len(upsert) != 1 {
+		t.Fatalf("expected enter alert, got %d", len(upsert))
+	}
+
+	// Removing the fence forgets its state without alerts.
+	delete(head, "geofence.zone")
+	if upsert, _ := gt.Resolve(head, "geofence.zone"); len(upsert) != 0 {
+		t.Errorf("removing the fence fired %d alerts", len(upsert))
+	}
+	if len(gt.inside) != 0 {
+		t.Errorf("state kept for removed fence: %v", gt.inside)
+	}
+}
@@ -0,0 +1,262 @@
+package transform
+
+import (
+	"math"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// smoothEarthRadiusM is the radius used to convert between degrees and
+	// meters around the current estimate.
+	smoothEarthRadiusM = 6378137.0
+
+	// DefaultSmoothingAccelVar is the process noise of the smoother: the
+	// variance, in (m/s²)², of the unmodelled acceleration of a track.
+	DefaultSmoothingAccelVar = 1.0
+
+	// defaultPositionVar is the measurement variance in m² assumed for a
+	// position without a Geo.Covariance.
+	defaultPositionVar = 25.0
+
+	// initialVelocityVar is the variance in (m/s)² of the velocity of a new
+	// track whose source doesn't report one.
+	initialVelocityVar = 100.0 * 100.0
+
+	// smoothMaxGap restarts a track that was silent for longer, rather
+	// than extrapolating its old velocity across the gap.
+	smoothMaxGap = 2 * time.Minute
+)
+
+// SmoothingTransformer smooths the positions of tracks with a constant
+// velocity Kalman filter per axis (east, north, up). Each Geo update of an
+// entity is a measurement weighted by the entity's Geo.Covariance; the
+// entity's Geo is replaced with the filtered position and its covariance.
+// The filtered velocity is written to Kinematics.VelocityEnu unless the
+// source reports a velocity of its own, which is used as given.
+//
+// The filter runs on the pushed entity before the other transformers, so
+// shapes, areas of uncertainty and geofences all see the smoothed track.
+type SmoothingTransformer struct {
+	// controllers limits smoothing to entities of these controllers; nil
+	// smooths every entity with a GeoSpatialComponent.
+	controllers map[string]bool
+	accelVar    float64
+
+	tracks map[string]*smoothTrack
+}
+
+// NewSmoothingTransformer returns a smoother for the entities of the given
+// controllers, or of all controllers if none are given.
+func NewSmoothingTransformer(accelVar float64, controllers ...string) *SmoothingTransformer {
+	t := &SmoothingTransformer{accelVar: accelVar, tracks: make(map[string]*smoothTrack)}
+	if len(controllers) > 0 {
+		t.controllers = make(map[string]bool, len(controllers))
+		for _, c := range controllers {
+			t.controllers[c] = true
+		}
+	}
+	return t
+}
+
+func (t *SmoothingTransformer) Validate(_ map[string]*pb.Entity, _ *pb.Entity) error {
+	return nil
+}
+
+// Resolve filters the entity in place, like PolarNormalizeTransformer: it
+// runs after the merge and before subscribers are notified, on an entity
+// no subscriber has seen yet.
+func (t *SmoothingTransformer) Resolve(head map[string]*pb.Entity, changedID string) (upsert []*pb.Entity, remove []string) {
+	entity := head[changedID]
+	if entity == nil || entity.Geo == nil {
+		delete(t.tracks, changedID)
+		return nil, nil
+	}
+	if t.controllers != nil && !t.controllers[entity.Controller.GetId()] {
+		return nil, nil
+	}
+
+	track := t.tracks[changedID]
+	// A push that didn't touch Geo leaves our last output in place.
+	if track != nil && proto.Equal(entity.Geo, track.geo) {
+		return nil, nil
+	}
+
+	at := measurementTime(entity.Lifetime)
+	if at.IsZero() {
+		return nil, nil
+	}
+
+	sourceVel := entity.GetKinematics().GetVelocityEnu()
+	if track != nil && proto.Equal(sourceVel, track.vel) {
+		sourceVel = nil
+	}
+
+	if track == nil || at.Before(track.at) || at.Sub(track.at) > smoothMaxGap {
+		track = newSmoothTrack(entity.Geo, sourceVel, at)
+		t.tracks[changedID] = track
+	} else {
+		track.update(entity.Geo, at.Sub(track.at).Seconds(), t.accelVar, at)
+	}
+
+	track.geo = track.geoComponent(entity.Geo)
+	entity.Geo = track.geo
+	if sourceVel == nil {
+		track.vel = track.velocity()
+		kin := &pb.KinematicsComponent{}
+		if entity.Kinematics != nil {
+			kin = proto.Clone(entity.Kinematics).(*pb.KinematicsComponent)
+		}
+		kin.VelocityEnu = track.vel
+		entity.Kinematics = kin
+	} else {
+		track.vel = nil
+	}
+	return nil, nil
+}
+
+// measurementTime is when a pushed entity was observed: Lifetime.Fresh,
+// else Lifetime.From.
+func measurementTime(l *pb.Lifetime) time.Time {
+	if l.GetFresh().IsValid() {
+		return l.GetFresh().AsTime()
+	}
+	if l.GetFrom().IsValid() {
+		return l.GetFrom().AsTime()
+	}
+	return time.Time{}
+}
+
+// smoothTrack is the filter state of one entity. Positions are kept as
+// lat/lon/alt; the filter works in meters on a plane tangent at the
+// current estimate, which moves with the track.
+type smoothTrack struct {
+	lat, lon, alt float64
+	hasAlt        bool
+	axes          [3]smoothAxis // east, north, up
+	at            time.Time
+
+	// geo and vel are the components last written to the entity.
+	geo *pb.GeoSpatialComponent
+	vel *pb.KinematicsEnu
+}
+
+// smoothAxis is a constant velocity Kalman filter along one axis. The
+// position is relative to the track's current estimate, so it is zero
+// between updates.
+type smoothAxis struct {
+	v float64
+	p [2][2]float64 // covariance of (position, velocity)
+}
+
+func newSmoothTrack(geo *pb.GeoSpatialComponent, vel *pb.KinematicsEnu, at time.Time) *smoothTrack {
+	t := &smoothTrack{lat: geo.Latitude, lon: geo.Longitude, at: at}
+	if geo.Altitude != nil {
+		t.alt, t.hasAlt = *geo.Altitude, true
+	}
+	r := measurementVar(geo.Covariance)
+	v := [3]float64{vel.GetEast(), vel.GetNorth(), vel.GetUp()}
+	for i := range t.axes {
+		t.axes[i] = smoothAxis{v: v[i], p: [2][2]float64{{r[i], 0}, {0, initialVelocityVar}}}
+	}
+	return t
+}
+
+// update predicts the track dt seconds ahead and corrects it with the
+// measured position.
+func (t *smoothTrack) update(geo *pb.GeoSpatialComponent, dt, accelVar float64, at time.Time) {
+	cosLat := math.Cos(t.lat * math.Pi / 180)
+	z := [3]float64{
+		(geo.Longitude - t.lon) * math.Pi / 180 * smoothEarthRadiusM * cosLat,
+		(geo.Latitude - t.lat) * math.Pi / 180 * smoothEarthRadiusM,
+	}
+	if geo.Altitude != nil && t.hasAlt {
+		z[2] = *geo.Altitude - t.alt
+	} else if geo.Altitude != nil {
+		t.alt, t.hasAlt = *geo.Altitude, true
+	}
+	r := measurementVar(geo.Covariance)
+
+	var x [3]float64
+	for i := range t.axes {
+		x[i] = t.axes[i].step(z[i], r[i], dt, accelVar)
+	}
+	t.lon += x[0] / (smoothEarthRadiusM * cosLat) * 180 / math.Pi
+	t.lat += x[1] / smoothEarthRadiusM * 180 / math.Pi
+	t.alt += x[2]
+	t.at = at
+}
+
+// step runs one predict/correct cycle with measurement z of variance r and
+// returns the new position.
+func (a *smoothAxis) step(z, r, dt, accelVar float64) float64 {
+	// Predict: x' = F x, P' = F P Fᵀ + Q for F = [1 dt; 0 1] and white
+	// acceleration noise.
+	x := a.v * dt
+	p := a.p
+	p00 := p[0][0] + dt*(p[1][0]+p[0][1]) + dt*dt*p[1][1] + accelVar*dt*dt*dt*dt/4
+	p01 := p[0][1] + dt*p[1][1] + accelVar*dt*dt*dt/2
+	p10 := p[1][0] + dt*p[1][1] + accelVar*dt*dt*dt/2
+	p11 := p[1][1] + accelVar*dt*dt
+
+	// Correct with a position measurement: H = [1 0].
+	s := p00 + r
+	k0, k1 := p00/s, p10/s
+	y := z - x
+	x += k0 * y
+	a.v += k1 * y
+	a.p = [2][2]float64{
+		{(1 - k0) * p00, (1 - k0) * p01},
+		{p10 - k1*p00, p11 - k1*p01},
+	}
+	return x
+}
+
+// measurementVar returns the east, north and up variance of a position,
+// falling back to defaultPositionVar where the covariance is unset.
+func measurementVar(cov *pb.CovarianceMatrix) [3]float64 {
+	r := [3]float64{cov.GetMxx(), cov.GetMyy(), cov.GetMzz()}
+	for i := range r {
+		if r[i] <= 0 {
+			r[i] = defaultPositionVar
+		}
+	}
+	return r
+}
+
+// geoComponent returns src with the smoothed position and its covariance.
+func (t *smoothTrack) geoComponent(src *pb.GeoSpatialComponent) *pb.GeoSpatialComponent {
+	geo := proto.Clone(src).(*pb.GeoSpatialComponent)
+	geo.Latitude = t.lat
+	geo.Longitude = t.lon
+	if t.hasAlt && src.Altitude != nil {
+		geo.Altitude = proto.Float64(t.alt)
+	}
+	geo.Covariance = &pb.CovarianceMatrix{
+		Mxx: proto.Float64(t.axes[0].p[0][0]),
+		Myy: proto.Float64(t.axes[1].p[0][0]),
+	}
+	if geo.Altitude != nil {
+		geo.Covariance.Mzz = proto.Float64(t.axes[2].p[0][0])
+	}
+	return geo
+}
+
+// velocity returns the smoothed velocity and its covariance.
+func (t *smoothTrack) velocity() *pb.KinematicsEnu {
+	v := &pb.KinematicsEnu{
+		East:  proto.Float64(t.axes[0].v),
+		North: proto.Float64(t.axes[1].v),
+		Covariance: &pb.CovarianceMatrix{
+			Mxx: proto.Float64(t.axes[0].p[1][1]),
+			Myy: proto.Float64(t.axes[1].p[1][1]),
+		},
+	}
+	if t.hasAlt {
+		v.Up = proto.Float64(t.axes[2].v)
+		v.Covariance.Mzz = proto.Float64(t.axes[2].p[1][1])
+	}
+	return v
+}
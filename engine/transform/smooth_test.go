@@ -0,0 +1,167 @@
+package transform
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// metersPerDegree is the length of a degree of latitude, and of longitude
+// at the equator.
+const metersPerDegree = smoothEarthRadiusM * math.Pi / 180
+
+func vesselReport(id string, lat, lon, variance float64, at time.Time) *pb.Entity {
+	return &pb.Entity{
+		Id:         id,
+		Controller: &pb.Controller{Id: proto.String("ais")},
+		Geo: &pb.GeoSpatialComponent{
+			Latitude:   lat,
+			Longitude:  lon,
+			Covariance: &pb.CovarianceMatrix{Mxx: proto.Float64(variance), Myy: proto.Float64(variance)},
+		},
+		Lifetime: &pb.Lifetime{Fresh: timestamppb.New(at)},
+	}
+}
+
+func TestSmoothing_ReducesNoiseOnStraightTrack(t *testing.T) {
+	st := NewSmoothingTransformer(DefaultSmoothingAccelVar)
+	rng := rand.New(rand.NewPCG(1, 2))
+	const (
+		sigma = 20.0 // m
+		speed = 10.0 // m/s, due east along the equator
+		steps = 300
+	)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var rawSq, smoothSq float64
+	var n int
+	var last *pb.Entity
+	for i := range steps {
+		trueEast := speed * float64(i)
+		e := vesselReport("ship.1",
+			rng.NormFloat64()*sigma/metersPerDegree,
+			(trueEast+rng.NormFloat64()*sigma)/metersPerDegree,
+			sigma*sigma, start.Add(time.Duration(i)*time.Second))
+		rawEast, rawNorth := e.Geo.Longitude*metersPerDegree-trueEast, e.Geo.Latitude*metersPerDegree
+
+		head := map[string]*pb.Entity{"ship.1": e}
+		st.Resolve(head, "ship.1")
+		last = head["ship.1"]
+
+		// Let the filter settle before scoring it.
+		if i < 30 {
+			continue
+		}
+		smoothEast, smoothNorth := last.Geo.Longitude*metersPerDegree-trueEast, last.Geo.Latitude*metersPerDegree
+		rawSq += rawEast*rawEast + rawNorth*rawNorth
+		smoothSq += smoothEast*smoothEast + smoothNorth*smoothNorth
+		n++
+	}
+
+	rawVar, smoothVar := rawSq/float64(n), smoothSq/float64(n)
+	if smoothVar > rawVar/3 {
+		t.Errorf("smoothed error variance %.1f m², raw %.1f m²: want at least 3x less", smoothVar, rawVar)
+	}
+
+	v := last.GetKinematics().GetVelocityEnu()
+	if v == nil {
+		t.Fatal("no velocity estimate")
+	}
+	if math.Abs(v.GetEast()-speed) > 2 || math.Abs(v.GetNorth()) > 2 {
+		t.Errorf("velocity (%.2f, %.2f) m/s, want (%.0f, 0)", v.GetEast(), v.GetNorth(), speed)
+	}
+	if cov := last.Geo.Covariance.GetMxx(); cov <= 0 || cov >= sigma*sigma {
+		t.Errorf("smoothed position variance %.1f, want in (0, %.0f)", cov, sigma*sigma)
+	}
+}
+
+func TestSmoothing_WeighsByCovariance(t *testing.T) {
+	// Two tracks see the same jump; the one that reports it as precise
+	// follows it further.
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	follow := func(variance float64) float64 {
+		st := NewSmoothingTransformer(DefaultSmoothingAccelVar)
+		head := map[string]*pb.Entity{}
+		for i := range 20 {
+			head["ship.1"] = vesselReport("ship.1", 0, 0, 100, start.Add(time.Duration(i)*time.Second))
+			st.Resolve(head, "ship.1")
+		}
+		head["ship.1"] = vesselReport("ship.1", 0, 100/metersPerDegree, variance, start.Add(20*time.Second))
+		st.Resolve(head, "ship.1")
+		return head["ship.1"].Geo.Longitude * metersPerDegree
+	}
+
+	precise, vague := follow(1), follow(10000)
+	if precise <= vague {
+		t.Errorf("precise report moved the track %.1f m, vague one %.1f m", precise, vague)
+	}
+	if precise < 50 || vague > 50 {
+		t.Errorf("precise report moved the track %.1f m, vague one %.1f m, want > 50 and < 50", precise, vague)
+	}
+}
+
+func TestSmoothing_KeepsSourceVelocity(t *testing.T) {
+	st := NewSmoothingTransformer(DefaultSmoothingAccelVar)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	head := map[string]*pb.Entity{}
+	for i := range 5 {
+		e := vesselReport("plane.1", 0, float64(i)*100/metersPerDegree, 25, start.Add(time.Duration(i)*time.Second))
+		e.Kinematics = &pb.KinematicsComponent{VelocityEnu: &pb.KinematicsEnu{East: proto.Float64(101)}}
+		head["plane.1"] = e
+		st.Resolve(head, "plane.1")
+	}
+	if got := head["plane.1"].Kinematics.VelocityEnu.GetEast(); got != 101 {
+		t.Errorf("velocity east %v, want the reported 101", got)
+	}
+}
+
+func TestSmoothing_OnlyListedControllers(t *testing.T) {
+	st := NewSmoothingTransformer(DefaultSmoothingAccelVar, "adsb")
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	head := map[string]*pb.Entity{}
+	for i := range 5 {
+		head["ship.1"] = vesselReport("ship.1", 0, float64(i%2)*100/metersPerDegree, 25, start.Add(time.Duration(i)*time.Second))
+		st.Resolve(head, "ship.1")
+	}
+	if got := head["ship.1"].Geo.Longitude; got != 0 {
+		t.Errorf("unlisted controller's track was smoothed to %v", got)
+	}
+	if head["ship.1"].Kinematics != nil {
+		t.Error("unlisted controller's track got a velocity")
+	}
+}
+
+func TestSmoothing_UntouchedGeoIsNotRefiltered(t *testing.T) {
+	st := NewSmoothingTransformer(DefaultSmoothingAccelVar)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	head := map[string]*pb.Entity{}
+	for i := range 3 {
+		head["ship.1"] = vesselReport("ship.1", 0, float64(i)*10/metersPerDegree, 25, start.Add(time.Duration(i)*time.Second))
+		st.Resolve(head, "ship.1")
+	}
+	geo := proto.Clone(head["ship.1"].Geo)
+
+	// A label-only push keeps the merged, already smoothed Geo.
+	head["ship.1"].Label = proto.String("Evergreen")
+	head["ship.1"].Lifetime.Fresh = timestamppb.New(start.Add(10 * time.Second))
+	st.Resolve(head, "ship.1")
+	if !proto.Equal(head["ship.1"].Geo, geo) {
+		t.Error("smoothed Geo was filtered again without a new measurement")
+	}
+}
+
+func TestSmoothing_ForgetsExpiredTrack(t *testing.T) {
+	st := NewSmoothingTransformer(DefaultSmoothingAccelVar)
+	head := map[string]*pb.Entity{"ship.1": vesselReport("ship.1", 0, 0, 25, time.Now())}
+	st.Resolve(head, "ship.1")
+	delete(head, "ship.1")
+	st.Resolve(head, "ship.1")
+	if len(st.tracks) != 0 {
+		t.Errorf("%d tracks left after expiry", len(st.tracks))
+	}
+}
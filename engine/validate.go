@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// quaternionTolerance is how far the norm of an orientation quaternion may
+// deviate from 1 before it counts as unnormalized.
+const quaternionTolerance = 1e-3
+
+// SetStrictValidation controls how pushed orientation quaternions that are
+// not unit length are handled. When strict they are rejected, otherwise they
+// are normalized in place. All other validation applies in both modes.
+func (s *WorldServer) SetStrictValidation(strict bool) {
+	s.strictValidation = strict
+}
+
+// validateEntity checks an incoming entity before it is merged. It returns a
+// CodeInvalidArgument error describing the first problem found. Unless strict
+// is set, an unnormalized orientation is fixed up instead of rejected.
+func validateEntity(e *pb.Entity, strict bool) error {
+	invalid := func(format string, args ...any) error {
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf(format, args...))
+	}
+
+	if e.Id == "" {
+		return invalid("entity id must not be empty")
+	}
+	if !isURLSafeID(e.Id) {
+		return invalid("entity id %q must be url safe", e.Id)
+	}
+	if e.Routing != nil {
+		for _, ch := range e.Routing.Channels {
+			if ch.Name != "" && !isURLSafeID(ch.Name) {
+				return invalid("entity %s routing channel name %q must be url safe", e.Id, ch.Name)
+			}
+		}
+	}
+
+	if geo := e.Geo; geo != nil {
+		if field, ok := allFinite(geo.ProtoReflect()); !ok {
+			return invalid("entity %s geo.%s is not finite", e.Id, field)
+		}
+		if lat := geo.GetLatitude(); lat < -90 || lat > 90 {
+			return invalid("entity %s latitude %v out of range", e.Id, lat)
+		}
+		if lon := geo.GetLongitude(); lon < -180 || lon > 180 {
+			return invalid("entity %s longitude %v out of range", e.Id, lon)
+		}
+	}
+
+	if q := e.GetOrientation().GetOrientation(); q != nil {
+		if _, ok := allFinite(q.ProtoReflect()); !ok {
+			return invalid("entity %s orientation is not finite", e.Id)
+		}
+		norm := math.Sqrt(q.X*q.X + q.Y*q.Y + q.Z*q.Z + q.W*q.W)
+		if norm == 0 {
+			return invalid("entity %s orientation is a zero quaternion", e.Id)
+		}
+		if math.Abs(norm-1) > quaternionTolerance {
+			if strict {
+				return invalid("entity %s orientation quaternion is not normalized (norm %v)", e.Id, norm)
+			}
+			q.X /= norm
+			q.Y /= norm
+			q.Z /= norm
+			q.W /= norm
+		}
+	}
+
+	return nil
+}
+
+// allFinite reports whether every float and double field set on m, including
+// those of nested messages, is finite. If not, it returns the offending field
+// path.
+func allFinite(m protoreflect.Message) (string, bool) {
+	bad := ""
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList():
+			if fd.Kind() != protoreflect.DoubleKind && fd.Kind() != protoreflect.FloatKind {
+				return true
+			}
+			l := v.List()
+			for i := 0; i < l.Len(); i++ {
+				if f := l.Get(i).Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+					bad = string(fd.Name())
+					return false
+				}
+			}
+		case fd.IsMap():
+		case fd.Kind() == protoreflect.MessageKind:
+			if field, ok := allFinite(v.Message()); !ok {
+				bad = string(fd.Name()) + "." + field
+				return false
+			}
+		case fd.Kind() == protoreflect.DoubleKind || fd.Kind() == protoreflect.FloatKind:
+			if f := v.Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+				bad = string(fd.Name())
+				return false
+			}
+		}
+		return true
+	})
+	return bad, bad == ""
+}
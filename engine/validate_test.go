@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestValidateEntity_Rejects(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name   string
+		entity *pb.Entity
+	}{
+		{"empty id", &pb.Entity{}},
+		{"unsafe id", &pb.Entity{Id: "a b"}},
+		{"nan latitude", &pb.Entity{Id: "e1", Geo: &pb.GeoSpatialComponent{Latitude: nan, Longitude: 13}}},
+		{"inf longitude", &pb.Entity{Id: "e1", Geo: &pb.GeoSpatialComponent{Latitude: 52, Longitude: math.Inf(1)}}},
+		{"latitude out of range", &pb.Entity{Id: "e1", Geo: &pb.GeoSpatialComponent{Latitude: 91, Longitude: 13}}},
+		{"longitude out of range", &pb.Entity{Id: "e1", Geo: &pb.GeoSpatialComponent{Latitude: 52, Longitude: -181}}},
+		{"nan covariance", &pb.Entity{Id: "e1", Geo: &pb.GeoSpatialComponent{
+			Latitude: 52, Longitude: 13, Covariance: &pb.CovarianceMatrix{Mxx: &nan},
+		}}},
+		{"nan quaternion", &pb.Entity{Id: "e1", Orientation: &pb.OrientationComponent{
+			Orientation: &pb.Quaternion{W: nan},
+		}}},
+		{"zero quaternion", &pb.Entity{Id: "e1", Orientation: &pb.OrientationComponent{
+			Orientation: &pb.Quaternion{},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateEntity(tt.entity, false)
+			if connect.CodeOf(err) != connect.CodeInvalidArgument {
+				t.Errorf("got %v, want invalid argument", err)
+			}
+		})
+	}
+}
+
+func TestValidateEntity_UnnormalizedQuaternion(t *testing.T) {
+	entity := func() *pb.Entity {
+		return &pb.Entity{Id: "e1", Orientation: &pb.OrientationComponent{
+			Orientation: &pb.Quaternion{Z: 2, W: 2},
+		}}
+	}
+
+	if err := validateEntity(entity(), true); connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("strict: got %v, want invalid argument", err)
+	}
+
+	e := entity()
+	if err := validateEntity(e, false); err != nil {
+		t.Fatalf("lenient: %v", err)
+	}
+	q := e.Orientation.Orientation
+	if norm := math.Sqrt(q.X*q.X + q.Y*q.Y + q.Z*q.Z + q.W*q.W); math.Abs(norm-1) > 1e-9 {
+		t.Errorf("quaternion not normalized, norm %v", norm)
+	}
+	if math.Abs(q.Z-q.W) > 1e-9 {
+		t.Errorf("normalization changed the rotation: %v", q)
+	}
+}
+
+func TestValidateEntity_ValidPassthrough(t *testing.T) {
+	e := &pb.Entity{
+		Id: "e1",
+		Geo: &pb.GeoSpatialComponent{
+			Latitude:   -90,
+			Longitude:  180,
+			Covariance: &pb.CovarianceMatrix{Mxx: ptrFloat(4), Myy: ptrFloat(9)},
+		},
+		Orientation: &pb.OrientationComponent{
+			Orientation: &pb.Quaternion{W: 1},
+		},
+	}
+	if err := validateEntity(e, true); err != nil {
+		t.Fatalf("valid entity rejected: %v", err)
+	}
+	if e.Orientation.Orientation.W != 1 {
+		t.Error("unit quaternion should be left untouched")
+	}
+}
+
+func TestPush_RejectsMalformedEntity(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	w.SetStrictValidation(true)
+
+	_, err := w.Push(context.Background(), peerRequest(&pb.EntityChangeRequest{
+		Changes: []*pb.Entity{
+			{Id: "ok", Geo: &pb.GeoSpatialComponent{Latitude: 52, Longitude: 13}},
+			{Id: "bad", Geo: &pb.GeoSpatialComponent{Latitude: math.NaN(), Longitude: 13}},
+		},
+	}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Fatalf("got %v, want invalid argument", err)
+	}
+	if w.GetHead("ok") != nil {
+		t.Error("a rejected push must not apply any of its entities")
+	}
+}
+
+func TestPush_RejectsMalformedReplacement(t *testing.T) {
+	for name, bad := range map[string]*pb.Entity{
+		"empty id":     {},
+		"unsafe id":    {Id: "a/b"},
+		"nan latitude": {Id: "bad", Geo: &pb.GeoSpatialComponent{Latitude: math.NaN()}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := testWorld(map[string]*pb.Entity{})
+			_, err := w.Push(context.Background(), peerRequest(&pb.EntityChangeRequest{
+				Changes:      []*pb.Entity{{Id: "ok", Label: ptr("ok")}},
+				Replacements: []*pb.Entity{bad},
+			}))
+			if connect.CodeOf(err) != connect.CodeInvalidArgument {
+				t.Fatalf("got %v, want invalid argument", err)
+			}
+			if w.GetHead("ok") != nil {
+				t.Error("a rejected push must not apply any of its entities")
+			}
+		})
+	}
+}
+
+func ptrFloat(f float64) *float64 { return &f }
@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"log/slog"
+	"time"
+)
+
+// DefaultSendTimeout is how long a send to a watch stream may block before
+// its consumer is evicted, unless configured.
+const DefaultSendTimeout = 30 * time.Second
+
+// sendWatchdogInterval is the time between checks for stalled sends.
+const sendWatchdogInterval = time.Second
+
+// SetSendTimeout sets how long a send to a watch stream may block before
+// its consumer is evicted. Zero or less disables eviction.
+func (s *WorldServer) SetSendTimeout(d time.Duration) {
+	s.sendTimeout.Store(int64(d))
+}
+
+// runSendWatchdog evicts stalled consumers until Shutdown.
+func (s *WorldServer) runSendWatchdog() {
+	ticker := time.NewTicker(sendWatchdogInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.evictStalledConsumers(time.Now())
+		}
+	}
+}
+
+// evictStalledConsumers evicts the consumers whose send has been blocked
+// for longer than the send timeout at now.
+func (s *WorldServer) evictStalledConsumers(now time.Time) {
+	timeout := time.Duration(s.sendTimeout.Load())
+	if timeout <= 0 {
+		return
+	}
+	for _, c := range s.bus.EvictStalled(timeout, now) {
+		slog.Warn("evicted watch consumer blocked in send", "consumer", c.id, "blocked", now.Sub(c.sendingSince()).Round(time.Second))
+	}
+}
@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestSendWatchdog_EvictsStalledConsumer(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{"e1": {Id: "e1"}, "e2": {Id: "e2"}})
+	w.SetSendTimeout(50 * time.Millisecond)
+
+	// stuck hangs in its first send until the test ends.
+	release := make(chan struct{})
+	defer close(release)
+	stuck := NewConsumer(w, nil, nil)
+	stuckCtx, stuckCancel := context.WithCancel(context.Background())
+	stuck.cancel = stuckCancel
+	w.bus.Register(stuck)
+	stuckDone := make(chan error, 1)
+	go func() {
+		stuckDone <- stuck.SenderLoop(stuckCtx, func(*pb.EntityChangeEvent) error {
+			<-release
+			return nil
+		})
+	}()
+
+	var mu sync.Mutex
+	var received []string
+	healthy := NewConsumer(w, nil, nil)
+	healthyCtx, healthyCancel := context.WithCancel(context.Background())
+	defer healthyCancel()
+	healthy.cancel = healthyCancel
+	w.bus.Register(healthy)
+	go func() {
+		_ = healthy.SenderLoop(healthyCtx, func(ev *pb.EntityChangeEvent) error {
+			mu.Lock()
+			received = append(received, ev.Entity.Id)
+			mu.Unlock()
+			return nil
+		})
+	}()
+
+	w.bus.Dirty("e1", nil, pb.EntityChange_EntityChangeUpdated)
+	deadline := time.Now().Add(time.Second)
+	for stuck.sendingSince().IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatal("stuck consumer never started sending")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Marking consumers dirty never waits for a blocked send.
+	dirtied := make(chan struct{})
+	go func() {
+		for range 1000 {
+			w.bus.Dirty("e2", nil, pb.EntityChange_EntityChangeUpdated)
+		}
+		close(dirtied)
+	}()
+	select {
+	case <-dirtied:
+	case <-time.After(time.Second):
+		t.Fatal("Dirty blocked on a consumer stuck in send")
+	}
+
+	// Within the timeout, nothing is evicted.
+	w.evictStalledConsumers(time.Now())
+	if n := w.bus.Len(); n != 2 {
+		t.Fatalf("%d consumers registered before the timeout, want 2", n)
+	}
+
+	w.evictStalledConsumers(time.Now().Add(100 * time.Millisecond))
+	if n := w.bus.Len(); n != 1 {
+		t.Fatalf("%d consumers registered after eviction, want 1", n)
+	}
+	if stuckCtx.Err() == nil {
+		t.Error("evicted consumer was not cancelled")
+	}
+
+	// The other consumer keeps receiving.
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	received = nil
+	mu.Unlock()
+	w.bus.Dirty("e1", nil, pb.EntityChange_EntityChangeUpdated)
+	deadline = time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(received)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("healthy consumer received %v, want updates to keep flowing", received)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	release <- struct{}{}
+	select {
+	case err := <-stuckDone:
+		assertContextErr(t, err)
+	case <-time.After(time.Second):
+		t.Error("evicted consumer's sender loop did not end once its send returned")
+	}
+}
+
+func TestSendWatchdog_Disabled(t *testing.T) {
+	w := testWorld(nil)
+	c := NewConsumer(w, nil, nil)
+	w.bus.Register(c)
+	c.sendStarted.Store(time.Now().Add(-time.Hour).UnixNano())
+
+	w.SetSendTimeout(0)
+	w.evictStalledConsumers(time.Now())
+	if w.bus.Len() != 1 {
+		t.Error("consumer evicted with the watchdog disabled")
+	}
+}
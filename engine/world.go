@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/tls"
 	"encoding/hex"
 	"fmt"
 	"log/slog"
@@ -18,9 +19,11 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"connectrpc.com/connect"
+	"connectrpc.com/grpcreflect"
 	"github.com/fatih/color"
 	"github.com/projectqai/hydris/builtin"
 	"github.com/projectqai/hydris/builtin/artifacts"
@@ -37,6 +40,7 @@ import (
 	"github.com/rs/cors"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -53,6 +57,8 @@ type entityState struct {
 	entity     *pb.Entity
 	lifetimes  map[int32]componentMeta // proto field number → meta
 	hardExpire bool                    // set by ExpireEntity; GC removes unconditionally
+	// expireReason is why hardExpire was set
+	expireReason ExpiryReason
 }
 
 func (es *entityState) isInfinite(protoNum int32) bool {
@@ -99,10 +105,20 @@ type WorldServer struct {
 
 	// worldFile is the path to persist world state (if set)
 	worldFile string
+	// worldDir, if set, persists world state as fragment files in a
+	// directory instead of worldFile
+	worldDir      string
+	worldGrouping WorldGrouping
+	worldDirState worldDirState
 
 	// persistNotify is signalled when a config change requires a debounced flush
 	persistNotify chan struct{}
 
+	// persistFsync syncs the world file and its directory on every flush
+	persistFsync bool
+	// persistDebounce coalesces changes before an autosave
+	persistDebounce time.Duration
+
 	// nodeID is the stable unique identifier for this node
 	nodeID     string
 	nodeEntity *pb.Entity
@@ -111,6 +127,66 @@ type WorldServer struct {
 	transformers     []transform.Transformer
 	mediaTransformer *transform.MediaTransformer
 	chatTransformer  *transform.ChatTransformer
+
+	// redactor strips components from entities before they are read
+	redactor ReadRedactor
+
+	// quota limits the push rate per source IP
+	quota *quotaLimiter
+
+	// authorizer is consulted for every WorldService RPC
+	authorizer Authorizer
+
+	// expireAuth is consulted by ExpireEntity
+	expireAuth ExpireAuthorizer
+
+	// accessLog records authorization decisions; nil unless enabled
+	accessLog *AccessLog
+
+	// strictValidation rejects unnormalized quaternions instead of fixing them
+	strictValidation bool
+
+	// pushLimits bound each pushed entity; allowedComponents is the set
+	// form of pushLimits.AllowedComponents, nil if any is allowed
+	pushLimits        PushLimits
+	allowedComponents map[uint32]bool
+
+	// counters feed Stats
+	counters worldCounters
+
+	// correlation groups nearby entities of different controllers; nil
+	// unless enabled
+	correlation *transform.CorrelationTransformer
+
+	// gcInterval hands a new sweep interval to runGC
+	gcInterval chan time.Duration
+	// gcMaxPerSweep caps the entities one GC sweep handles; 0 is unlimited
+	gcMaxPerSweep int
+
+	// sendTimeout is how long a watch stream send may block before the
+	// watchdog evicts its consumer, as a time.Duration; 0 disables it
+	sendTimeout atomic.Int64
+
+	// stop is closed by Shutdown to end the GC and periodic flushes
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	// frozen marks the world as not accepting changes; health checks
+	// report NOT_SERVING while it is set
+	frozen atomic.Bool
+	// frozenReason explains frozen to clients; guarded by l
+	frozenReason string
+
+	// startedAt is when the server was created, for GetStatus
+	startedAt time.Time
+
+	// pinned are the ids of entities the GC never expires, nil until the
+	// first SetPinned; guarded by l
+	pinned map[string]bool
+
+	// expiryReasons are why recently removed entities were removed, nil
+	// until the first removal; guarded by l
+	expiryReasons map[string]expiryRecord
 }
 
 func NewWorldServer() *WorldServer {
@@ -127,20 +203,22 @@ func NewWorldServer() *WorldServer {
 			transform.NewCameraTransformer(),
 			transform.NewAOUTransformer(),
 			transform.NewShapeTransformer(),
+			transform.NewGeofenceTransformer(),
 			transform.NewClassificationTransformer(),
 			mediaTransformer,
 		},
+		gcInterval: make(chan time.Duration, 1),
+		stop:       make(chan struct{}),
+		startedAt:  time.Now(),
+
+		persistFsync: true,
 	}
 	server.transformers = append(server.transformers, server.chatTransformer)
 
-	// Start garbage collection ticker
-	go func() {
-		ticker := time.NewTicker(time.Second)
-		defer ticker.Stop()
-		for range ticker.C {
-			server.GC()
-		}
-	}()
+	server.sendTimeout.Store(int64(DefaultSendTimeout))
+
+	go server.runGC()
+	go server.runSendWatchdog()
 
 	return server
 }
@@ -336,15 +414,21 @@ func (s *WorldServer) GetHead(id string) *pb.Entity {
 }
 
 func (s *WorldServer) ListEntities(ctx context.Context, req *connect.Request[pb.ListEntitiesRequest]) (*connect.Response[pb.ListEntitiesResponse], error) {
+	extra, err := headerFilterFromRequest(req.Header())
+	if err != nil {
+		return nil, err
+	}
+
 	s.l.RLock()
 	defer s.l.RUnlock()
 
+	now := time.Now()
 	el := make([]*pb.Entity, 0, len(s.head))
 	for _, es := range s.head {
-		if !s.matchesListEntitiesRequest(es.entity, req.Msg) {
+		if !s.matchesListEntitiesRequest(es.entity, req.Msg) || !extra.matches(es.entity, now) {
 			continue
 		}
-		el = append(el, es.entity)
+		el = append(el, s.redactForPeer(req.Peer().Addr, es.entity))
 	}
 	sortEntities(el, req.Msg.Sort)
 
@@ -387,7 +471,7 @@ func (s *WorldServer) GetEntity(ctx context.Context, req *connect.Request[pb.Get
 	}
 
 	response := &pb.GetEntityResponse{
-		Entity: entity,
+		Entity: s.redactForPeer(req.Peer().Addr, entity),
 	}
 	return connect.NewResponse(response), nil
 }
@@ -409,32 +493,61 @@ func (s *WorldServer) TimeSync(_ context.Context, req *connect.Request[pb.TimeSy
 }
 
 func (s *WorldServer) Push(ctx context.Context, req *connect.Request[pb.EntityChangeRequest]) (*connect.Response[pb.EntityChangeResponse], error) {
+	if err := s.quota.check(req.Peer().Addr, len(req.Msg.Changes)+len(req.Msg.Replacements), isDryRun(req.Header())); err != nil {
+		return nil, err
+	}
+
+	if isDryRun(req.Header()) {
+		return s.dryRunPush(ctx, req)
+	}
+
 	s.l.Lock()
 	defer s.l.Unlock()
 
+	if s.frozen.Load() {
+		return connect.NewResponse(&pb.EntityChangeResponse{Debug: s.frozenMessage()}), nil
+	}
+
 	// Validate incoming entities before any merge.
-	for _, e := range req.Msg.Changes {
-		if !isURLSafeID(e.Id) {
-			return nil, connect.NewError(connect.CodeInvalidArgument,
-				fmt.Errorf("entity id %q must be url safe", e.Id))
-		}
-		if e.Routing != nil {
-			for _, ch := range e.Routing.Channels {
-				if ch.Name != "" && !isURLSafeID(ch.Name) {
-					return nil, connect.NewError(connect.CodeInvalidArgument,
-						fmt.Errorf("entity %s routing channel name %q must be url safe", e.Id, ch.Name))
-				}
-			}
+	for _, e := range slices.Concat(req.Msg.Changes, req.Msg.Replacements) {
+		if err := s.validateIncoming(e); err != nil {
+			return nil, err
 		}
-		for _, tr := range s.transformers {
-			if err := tr.Validate(s.headView, e); err != nil {
-				return nil, err
-			}
+	}
+	if err := s.validateConfigSchemas(req.Msg); err != nil {
+		return nil, err
+	}
+
+	mergeMode, err := mergeModeFromHeader(req.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	// In transactional mode, stage the batch first so that the apply loop
+	// cannot fail half way through.
+	if isAtomicPush(req.Header()) {
+		if _, err := s.stagePush(req.Msg.Changes, nil, mergeMode); err != nil {
+			return nil, err
 		}
 	}
 
-	configChanged := false
+	persistChanged := false
 	var changedIDs []string
+	// before holds the components each changed entity had before the
+	// push, to tell consumers which were added or removed. It is only
+	// needed by consumers that filter on them.
+	trackComponents := s.bus.WantsComponents()
+	before := make(map[string]componentSet)
+	snapshotComponents := func(id string) {
+		if _, ok := before[id]; ok || !trackComponents {
+			return
+		}
+		if es, ok := s.head[id]; ok {
+			before[id] = presentComponents(es.entity)
+		} else {
+			before[id] = nil
+		}
+	}
 
 	for _, e := range req.Msg.Changes {
 
@@ -448,8 +561,9 @@ func (s *WorldServer) Push(ctx context.Context, req *connect.Request[pb.EntityCh
 			}
 		}
 
+		snapshotComponents(e.Id)
 		if es, ok := s.head[e.Id]; ok {
-			merged, accepted := s.mergeEntityComponents(e.Id, es, e)
+			merged, accepted := s.mergeEntityComponentsMode(e.Id, es, e, mergeMode)
 			if !accepted {
 				continue
 			}
@@ -457,59 +571,29 @@ func (s *WorldServer) Push(ctx context.Context, req *connect.Request[pb.EntityCh
 			s.headView[e.Id] = merged
 		} else {
 			hadNoLifetime := e.Lifetime == nil
-			if hadNoLifetime {
-				e.Lifetime = &pb.Lifetime{}
-			}
-			if !e.Lifetime.From.IsValid() {
-				e.Lifetime.From = timestamppb.Now()
-			}
-			if e.Lifetime.Fresh == nil || !e.Lifetime.Fresh.IsValid() {
-				e.Lifetime.Fresh = e.Lifetime.From
-			}
+			fillLifetime(e)
 			s.initEntity(e, hadNoLifetime)
 		}
 
 		// Stamp controller node after merge so we never clobber an
 		// existing Controller.Id with a synthetic empty Controller.
-		stored := s.head[e.Id].entity
-		if s.nodeID != "" {
-			if stored.Controller == nil {
-				stored.Controller = &pb.Controller{}
-			}
-			if stored.Controller.Node == nil {
-				stored.Controller.Node = &s.nodeID
-			}
-		}
+		s.stampNode(s.head[e.Id].entity)
 		changedIDs = append(changedIDs, e.Id)
-		if e.Config != nil {
-			configChanged = true
+		if s.persistStub(s.head[e.Id]) != nil {
+			persistChanged = true
 		}
 	}
 
 	// Process replacements (full entity swap, no merge)
 	for _, e := range req.Msg.Replacements {
-		if e.Lifetime == nil {
-			e.Lifetime = &pb.Lifetime{}
-		}
-		if !e.Lifetime.From.IsValid() {
-			e.Lifetime.From = timestamppb.Now()
-		}
-		if e.Lifetime.Fresh == nil || !e.Lifetime.Fresh.IsValid() {
-			e.Lifetime.Fresh = e.Lifetime.From
-		}
-		if s.nodeID != "" {
-			if e.Controller == nil {
-				e.Controller = &pb.Controller{}
-			}
-			if e.Controller.Node == nil {
-				e.Controller.Node = &s.nodeID
-			}
-		}
+		snapshotComponents(e.Id)
+		fillLifetime(e)
+		s.stampNode(e)
 
 		s.initEntity(e)
 		changedIDs = append(changedIDs, e.Id)
-		if e.Config != nil {
-			configChanged = true
+		if s.persistStub(s.head[e.Id]) != nil {
+			persistChanged = true
 		}
 	}
 
@@ -521,12 +605,19 @@ func (s *WorldServer) Push(ctx context.Context, req *connect.Request[pb.EntityCh
 		s.syncTransformerResults(upserted, removed)
 	}
 	for _, id := range changedIDs {
-		s.bus.Dirty(id, s.head[id].entity, pb.EntityChange_EntityChangeUpdated)
+		entity := s.head[id].entity
+		var components []pb.EntityComponent
+		if trackComponents {
+			components = changedComponents(before[id], entity)
+		}
+		s.bus.DirtyComponents(ctx, id, entity, components)
 	}
+	slog.DebugContext(ctx, "push applied", "peer", req.Peer().Addr, "changes", len(changedIDs))
 
-	if configChanged {
+	if persistChanged {
 		s.notifyPersist()
 	}
+	s.counters.pushed.Add(uint64(len(changedIDs)))
 
 	response := &pb.EntityChangeResponse{
 		Accepted: true,
@@ -544,11 +635,16 @@ func (s *WorldServer) ExpireEntity(ctx context.Context, req *connect.Request[pb.
 		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("entity with id %s not found", req.Msg.Id))
 	}
 
+	if err := s.authorizeExpire(ctx, req.Peer().Addr, es.entity); err != nil {
+		return nil, err
+	}
+
 	now := timestamppb.Now()
 
 	// Mark for unconditional removal by the GC. Subsequent pushes
 	// cannot revive an entity once hard-expired.
 	es.hardExpire = true
+	es.expireReason = ExpiryExplicit
 
 	// Also set entity-level lifetime for backward compat / visibility.
 	if es.entity.Lifetime == nil {
@@ -556,7 +652,7 @@ func (s *WorldServer) ExpireEntity(ctx context.Context, req *connect.Request[pb.
 	}
 	es.entity.Lifetime.Until = now
 
-	s.bus.Dirty(es.entity.Id, es.entity, pb.EntityChange_EntityChangeUpdated)
+	s.bus.DirtyContext(ctx, es.entity.Id, es.entity, pb.EntityChange_EntityChangeUpdated)
 
 	return connect.NewResponse(&pb.ExpireEntityResponse{}), nil
 }
@@ -592,6 +688,7 @@ func (s *WorldServer) HardReset(ctx context.Context, req *connect.Request[pb.Har
 	}
 
 	// Expire every entity (except the mission) and let transformers see the removal.
+	now := time.Now()
 	for id, es := range s.head {
 		if missionEntity != nil && id == missionID {
 			continue
@@ -599,6 +696,7 @@ func (s *WorldServer) HardReset(ctx context.Context, req *connect.Request[pb.Har
 		snapshot := es.entity
 		deleteArtifactBlob(snapshot)
 		s.deleteEntity(id)
+		s.recordExpiry(id, ExpiryExplicit, now)
 		for _, t := range s.transformers {
 			t.Resolve(s.headView, id)
 		}
@@ -612,7 +710,9 @@ func (s *WorldServer) HardReset(ctx context.Context, req *connect.Request[pb.Har
 	}
 
 	// Truncate persistence file, then write back the mission entity if present.
-	if s.worldFile != "" {
+	if s.worldDir != "" {
+		s.resetWorldDir(missionEntity)
+	} else if s.worldFile != "" {
 		if missionEntity != nil {
 			yamlBytes, err := entitiesToYAML([]*pb.Entity{missionEntity})
 			if err != nil {
@@ -654,13 +754,50 @@ func (s *WorldServer) HardReset(ctx context.Context, req *connect.Request[pb.Har
 func NewAPIMux(engine *WorldServer, promHandler http.Handler, bridges *media.BridgeManager, logHandler ...http.Handler) *http.ServeMux {
 	mux := http.NewServeMux()
 
-	worldPath, worldHandler := _goconnect.NewWorldServiceHandler(engine)
-	mux.Handle(worldPath, worldHandler)
+	// The trace interceptor runs first so that rejected RPCs are traced too.
+	interceptors := []connect.Interceptor{NewTraceInterceptor()}
+	if engine.authorizer != nil || engine.accessLog != nil {
+		interceptors = append(interceptors, &authInterceptor{authorize: engine.authorizer, log: engine.accessLog})
+	}
+	worldPath, worldHandler := _goconnect.NewWorldServiceHandler(engine, connect.WithInterceptors(interceptors...))
+	mux.Handle(worldPath, withClientIdentity(worldHandler))
 
+	services := []string{_goconnect.WorldServiceName}
 	if artifacts.Server != nil {
 		artPath, artHandler := _goconnect.NewArtifactServiceHandler(artifacts.Server)
 		mux.Handle(artPath, artHandler)
-	}
+		services = append(services, _goconnect.ArtifactServiceName)
+	}
+
+	// Health is left open like /healthz so load balancers can probe it.
+	healthPath, healthHandler := newHealthHandler(engine)
+	mux.Handle(healthPath, healthHandler)
+	services = append(services, grpc_health_v1.Health_ServiceDesc.ServiceName)
+	services = append(services, grpcreflect.ReflectV1ServiceName, grpcreflect.ReflectV1AlphaServiceName)
+
+	// Reflection lets grpcurl and friends list and call the services without
+	// local proto files. v1alpha is what older grpcurl versions ask for.
+	reflector := grpcreflect.NewStaticReflector(services...)
+	reflectPath, reflectHandler := grpcreflect.NewHandlerV1(reflector, connect.WithInterceptors(interceptors...))
+	mux.Handle(reflectPath, withClientIdentity(reflectHandler))
+	reflectPath, reflectHandler = grpcreflect.NewHandlerV1Alpha(reflector, connect.WithInterceptors(interceptors...))
+	mux.Handle(reflectPath, withClientIdentity(reflectHandler))
+
+	mux.Handle("GET /relations/{entityId...}", withClientIdentity(http.HandlerFunc(engine.handleRelations)))
+	mux.Handle("GET /geojson", withClientIdentity(http.HandlerFunc(engine.handleGeoJSON)))
+	mux.Handle("GET /kml", withClientIdentity(http.HandlerFunc(engine.handleKML)))
+	mux.Handle("GET /kml/link", withClientIdentity(http.HandlerFunc(engine.handleKMLLink)))
+	mux.Handle("GET /correlations", withClientIdentity(http.HandlerFunc(engine.handleCorrelations)))
+	mux.Handle("GET /aggregate", withClientIdentity(http.HandlerFunc(engine.handleAggregate)))
+	mux.Handle("POST /heartbeat", withClientIdentity(http.HandlerFunc(engine.handleHeartbeat)))
+	mux.Handle("POST /sync-controller", withClientIdentity(http.HandlerFunc(engine.handleSyncController)))
+	mux.Handle("POST /pin", withClientIdentity(http.HandlerFunc(engine.handlePin)))
+	mux.Handle("POST /diff-entity", withClientIdentity(http.HandlerFunc(engine.handleDiffEntity)))
+	mux.Handle("GET /expiry-reason", withClientIdentity(http.HandlerFunc(engine.handleExpiryReason)))
+	mux.Handle("POST /frozen", withClientIdentity(http.HandlerFunc(engine.handleSetFrozen)))
+	mux.Handle("GET /status", withClientIdentity(http.HandlerFunc(engine.handleStatus)))
+	mux.Handle("GET /configurables", withClientIdentity(http.HandlerFunc(engine.handleConfigurables)))
+	mux.Handle("POST /resync", withClientIdentity(http.HandlerFunc(engine.handleResync)))
 
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain")
@@ -730,6 +867,69 @@ type EngineConfig struct {
 	PolicyFile string
 	NoDefaults bool
 	LogHandler http.Handler
+
+	// WorldGroupBy picks the fragment entities are written back to when
+	// WorldFile is a directory: "source" (the default) or "controller".
+	WorldGroupBy string
+
+	// StrictValidation rejects pushed entities with unnormalized orientation
+	// quaternions instead of normalizing them.
+	StrictValidation bool
+
+	// PushLimits bound the size and components of pushed entities.
+	PushLimits PushLimits
+
+	// AccessLog enables logging authorization decisions: every denied
+	// request and AccessLogSampleRate of the allowed ones.
+	AccessLog           bool
+	AccessLogSampleRate float64
+
+	// Correlate enables the correlation pass, which groups entities of
+	// different controllers within CorrelationDistance meters and
+	// CorrelationWindow of each other.
+	Correlate           bool
+	CorrelationDistance float64
+	CorrelationWindow   time.Duration
+
+	// Smooth enables track smoothing for all controllers, or for
+	// SmoothControllers only if any are listed.
+	Smooth            bool
+	SmoothControllers []string
+
+	// NoFsync skips syncing the world file to disk on flush.
+	NoFsync bool
+	// PersistDebounce is how long autosave coalesces changes before
+	// writing the world file; DefaultPersistDebounce when zero.
+	PersistDebounce time.Duration
+
+	// Stopped, if set, is closed once the engine has shut down after ctx
+	// is done and the world file has been flushed a last time.
+	Stopped chan<- struct{}
+	// ShutdownTimeout bounds how long shutdown waits for watch streams
+	// and in-flight requests; DefaultShutdownTimeout when zero.
+	ShutdownTimeout time.Duration
+
+	// GC tunes the sweep that expires entities.
+	GC GCConfig
+
+	// SendTimeout is how long a send to a watch stream may block before
+	// its consumer is evicted; DefaultSendTimeout when zero, never when
+	// negative.
+	SendTimeout time.Duration
+
+	// Keepalive tunes how dead client connections are detected.
+	Keepalive KeepaliveConfig
+
+	// TLS, if enabled, encrypts the server port and can require client
+	// certificates.
+	TLS TLSConfig
+
+	// Hooks for programs that embed the engine; there are no flags for
+	// them. Each is installed with its Set method on the WorldServer.
+	Authorizer       Authorizer
+	ExpireAuthorizer ExpireAuthorizer
+	ReadRedactor     ReadRedactor
+	WriteQuota       WriteQuota
 }
 
 // StartEngine starts the Hydris engine and returns the server address.
@@ -737,6 +937,27 @@ type EngineConfig struct {
 // and periodically flushes the current state back to the file.
 func StartEngine(ctx context.Context, cfg EngineConfig) (string, error) {
 	engine := NewWorldServer()
+	engine.SetStrictValidation(cfg.StrictValidation)
+	engine.SetPushLimits(cfg.PushLimits)
+	engine.SetAuthorizer(cfg.Authorizer)
+	engine.SetExpireAuthorizer(cfg.ExpireAuthorizer)
+	engine.SetReadRedactor(cfg.ReadRedactor)
+	engine.SetWriteQuota(cfg.WriteQuota)
+	if cfg.AccessLog {
+		engine.SetAccessLog(NewAccessLog(slog.Default(), cfg.AccessLogSampleRate))
+	}
+	engine.SetPersistFsync(!cfg.NoFsync)
+	engine.SetPersistDebounce(cfg.PersistDebounce)
+	engine.SetGCConfig(cfg.GC)
+	if cfg.SendTimeout != 0 {
+		engine.SetSendTimeout(cfg.SendTimeout)
+	}
+	if cfg.Correlate {
+		engine.EnableCorrelation(cfg.CorrelationDistance, cfg.CorrelationWindow)
+	}
+	if cfg.Smooth || len(cfg.SmoothControllers) > 0 {
+		engine.EnableSmoothing(0, cfg.SmoothControllers...)
+	}
 
 	// Default to a platform-appropriate config directory when no world file is specified.
 	worldFile := cfg.WorldFile
@@ -749,8 +970,20 @@ func StartEngine(ctx context.Context, cfg EngineConfig) (string, error) {
 		}
 	}
 
-	// Set up world file persistence
-	if worldFile != "" {
+	// Set up world file persistence. A directory holds world fragments.
+	if fi, err := os.Stat(worldFile); err == nil && fi.IsDir() {
+		grouping, err := ParseWorldGrouping(cfg.WorldGroupBy)
+		if err != nil {
+			return "", err
+		}
+		engine.SetWorldDir(worldFile, grouping)
+
+		if err := engine.LoadFromDir(worldFile); err != nil {
+			return "", fmt.Errorf("failed to load world directory: %w", err)
+		}
+
+		engine.StartPeriodicFlush(10 * time.Second)
+	} else if worldFile != "" {
 		engine.worldFile = worldFile
 
 		// Load existing state from file
@@ -785,6 +1018,7 @@ func StartEngine(ctx context.Context, cfg EngineConfig) (string, error) {
 
 	// Start metrics updater
 	StartMetricsUpdater(engine)
+	metrics.SetStatsSource(engine.Stats)
 
 	// Get port from environment or use default
 	port := os.Getenv("PORT")
@@ -831,9 +1065,22 @@ func StartEngine(ctx context.Context, cfg EngineConfig) (string, error) {
 		AllowedHeaders: []string{"*"},
 	})
 
+	h2 := newHTTP2Server(cfg.Keepalive)
 	httpServer := &http.Server{
-		Addr:    ":" + port,
-		Handler: h2c.NewHandler(corsHandler.Handler(mux), &http2.Server{}),
+		Addr:        ":" + port,
+		Handler:     h2c.NewHandler(corsHandler.Handler(mux), h2),
+		IdleTimeout: h2.IdleTimeout,
+	}
+	scheme := "http"
+	var tlsConfig *tls.Config
+	if cfg.TLS.Enabled() {
+		if tlsConfig, err = cfg.TLS.serverConfig(); err != nil {
+			return "", err
+		}
+		if err := http2.ConfigureServer(httpServer, h2); err != nil {
+			return "", fmt.Errorf("failed to configure HTTP/2: %w", err)
+		}
+		scheme = "https"
 	}
 
 	// Create listener first to fail fast if port is in use
@@ -863,18 +1110,22 @@ func StartEngine(ctx context.Context, cfg EngineConfig) (string, error) {
 	fmt.Println(" running at:")
 	_, _ = green.Print("  ➜ ")
 	fmt.Print("Local:   ")
-	_, _ = cyan.Printf("http://localhost:%s\n", port)
+	_, _ = cyan.Printf("%s://localhost:%s\n", scheme, port)
 
 	for _, ip := range localIPs {
 		_, _ = green.Print("  ➜ ")
 		fmt.Print("Network: ")
-		_, _ = cyan.Printf("http://%s:%s\n", ip, port)
+		_, _ = cyan.Printf("%s://%s:%s\n", scheme, ip, port)
 	}
 	fmt.Println()
 
-	// Serve HTTP on the HTTP sub-listener.
+	// Serve HTTP on the HTTP sub-listener, behind TLS if configured.
+	httpLn := muxLn.HTTP()
+	if tlsConfig != nil {
+		httpLn = tls.NewListener(httpLn, tlsConfig)
+	}
 	go func() {
-		if err := httpServer.Serve(muxLn.HTTP()); err != nil && err != http.ErrServerClosed {
+		if err := httpServer.Serve(httpLn); err != nil && err != http.ErrServerClosed {
 			slog.Error("server error", "error", err)
 			os.Exit(1)
 		}
@@ -900,10 +1151,30 @@ func StartEngine(ctx context.Context, cfg EngineConfig) (string, error) {
 
 	go func() {
 		<-ctx.Done()
+		timeout := cfg.ShutdownTimeout
+		if timeout <= 0 {
+			timeout = DefaultShutdownTimeout
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
 		rtspServer.Close()
 		_ = muxLn.Close()
-		_ = httpServer.Shutdown(context.Background())
-		_ = builtinServer.Shutdown(context.Background())
+		// Watch streams never end on their own, so they are cancelled
+		// before the servers wait for open requests to finish.
+		engine.bus.CloseAll()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			_ = httpServer.Close()
+		}
+		if err := builtinServer.Shutdown(shutdownCtx); err != nil {
+			_ = builtinServer.Close()
+		}
+		if err := engine.Shutdown(shutdownCtx); err != nil {
+			slog.Error("failed to flush world state on shutdown", "error", err)
+		}
+		if cfg.Stopped != nil {
+			close(cfg.Stopped)
+		}
 	}()
 
 	return "localhost:" + port, nil
@@ -913,12 +1184,34 @@ func StartEngine(ctx context.Context, cfg EngineConfig) (string, error) {
 func (s *WorldServer) setEntity(id string, e *pb.Entity, lifetimes map[int32]componentMeta) {
 	s.head[id] = &entityState{entity: e, lifetimes: lifetimes}
 	s.headView[id] = e
+	delete(s.expiryReasons, id)
 }
 
 // deleteEntity removes an entity from head and headView.
 func (s *WorldServer) deleteEntity(id string) {
+	if es, ok := s.head[id]; ok && s.persistStub(es) != nil {
+		s.notifyPersist()
+	}
 	delete(s.head, id)
 	delete(s.headView, id)
+	delete(s.pinned, id)
+}
+
+// validateIncoming runs the checks every pushed entity must pass, whether
+// it is merged or replaces the one in head. The caller holds s.l.
+func (s *WorldServer) validateIncoming(e *pb.Entity) error {
+	if err := s.checkPushLimits(e); err != nil {
+		return err
+	}
+	if err := validateEntity(e, s.strictValidation); err != nil {
+		return err
+	}
+	for _, tr := range s.transformers {
+		if err := tr.Validate(s.headView, e); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // syncTransformerResults adds/removes transformer-generated entities in
@@ -959,14 +1252,47 @@ func lifetimeUntil(l *pb.Lifetime) time.Time {
 	return time.Time{}
 }
 
+// fillLifetime gives a pushed entity a Lifetime with From and Fresh set,
+// defaulting to now.
+func fillLifetime(e *pb.Entity) {
+	if e.Lifetime == nil {
+		e.Lifetime = &pb.Lifetime{}
+	}
+	if !e.Lifetime.From.IsValid() {
+		e.Lifetime.From = timestamppb.Now()
+	}
+	if e.Lifetime.Fresh == nil || !e.Lifetime.Fresh.IsValid() {
+		e.Lifetime.Fresh = e.Lifetime.From
+	}
+}
+
+// stampNode sets the controller node of an entity to this node unless it
+// already has one.
+func (s *WorldServer) stampNode(e *pb.Entity) {
+	if s.nodeID == "" {
+		return
+	}
+	if e.Controller == nil {
+		e.Controller = &pb.Controller{}
+	}
+	if e.Controller.Node == nil {
+		e.Controller.Node = &s.nodeID
+	}
+}
+
 // initEntity stores an entity in head with per-component lifetime metadata
 // derived from the entity's Lifetime field. If noLifetime is true, the
 // original push had no Lifetime set — components are marked accordingly so
 // they inherit the entity's lifetime from other components during merge.
 func (s *WorldServer) initEntity(e *pb.Entity, noLifetime ...bool) {
+	s.setEntity(e.Id, e, componentMetas(e, len(noLifetime) > 0 && noLifetime[0]))
+}
+
+// componentMetas returns the per-component lifetimes of a new entity, or
+// nil if it has no components.
+func componentMetas(e *pb.Entity, nl bool) map[int32]componentMeta {
 	fresh := lifetimeTime(e.Lifetime)
 	until := lifetimeUntil(e.Lifetime)
-	nl := len(noLifetime) > 0 && noLifetime[0]
 
 	v := reflect.ValueOf(e).Elem()
 	meta := make(map[int32]componentMeta)
@@ -980,9 +1306,9 @@ func (s *WorldServer) initEntity(e *pb.Entity, noLifetime ...bool) {
 		}
 	}
 	if len(meta) == 0 {
-		meta = nil
+		return nil
 	}
-	s.setEntity(e.Id, e, meta)
+	return meta
 }
 
 // componentAccepted checks whether an incoming component should replace an existing one.
@@ -1022,6 +1348,12 @@ func componentAccepted(incomingFresh, incomingUntil time.Time, existing componen
 // the existing component's lifetime. Returns the merged entity and whether
 // at least one component was accepted.
 func (s *WorldServer) mergeEntityComponents(entityID string, existing *entityState, incoming *pb.Entity) (*pb.Entity, bool) {
+	return s.mergeEntityComponentsMode(entityID, existing, incoming, MergeReplaceComponent)
+}
+
+// mergeEntityComponentsMode is mergeEntityComponents with the way accepted
+// components are combined with existing ones selected by mode.
+func (s *WorldServer) mergeEntityComponentsMode(entityID string, existing *entityState, incoming *pb.Entity, mode MergeMode) (*pb.Entity, bool) {
 	merged := proto.Clone(existing.entity).(*pb.Entity)
 
 	inFresh := lifetimeTime(incoming.Lifetime)
@@ -1052,7 +1384,11 @@ func (s *WorldServer) mergeEntityComponents(entityID string, existing *entitySta
 			continue
 		}
 
-		mf.Set(sf)
+		if msg, ok := mf.Interface().(proto.Message); ok && mode == MergeFields && !mf.IsNil() {
+			mergeFields(msg.ProtoReflect(), sf.Interface().(proto.Message).ProtoReflect())
+		} else {
+			mf.Set(sf)
+		}
 		applyComponentMergers(protoNum, merged, existing.entity)
 		if existing.lifetimes == nil {
 			existing.lifetimes = make(map[int32]componentMeta)
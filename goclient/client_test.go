@@ -0,0 +1,225 @@
+package goclient
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	proto "github.com/projectqai/proto/go"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeClock advances by every wait instead of sleeping.
+type fakeClock struct {
+	t     time.Time
+	waits []time.Duration
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) after(d time.Duration) <-chan time.Time {
+	c.t = c.t.Add(d)
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+	ch <- c.t
+	return ch
+}
+
+func withFakeClock(c *fakeClock) WatchOption {
+	return func(o *watchOptions) {
+		o.now = c.now
+		o.after = c.after
+	}
+}
+
+// withMaxJitter makes every delay its ceiling, so tests can assert on it.
+func withMaxJitter() WatchOption {
+	return func(o *watchOptions) {
+		o.rand = func(n int64) int64 { return n - 1 }
+	}
+}
+
+// fakeStream yields events, then fails with err (if set).
+type fakeStream struct {
+	grpc.ClientStream
+	events []*proto.EntityChangeEvent
+	err    error
+}
+
+func (s *fakeStream) Recv() (*proto.EntityChangeEvent, error) {
+	if len(s.events) > 0 {
+		e := s.events[0]
+		s.events = s.events[1:]
+		return e, nil
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	select {}
+}
+
+// flakyClient serves a stream that drops with Unavailable, refuses the first
+// reconnect and then recovers.
+type flakyClient struct {
+	proto.WorldServiceClient
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *flakyClient) WatchEntities(ctx context.Context, in *proto.ListEntitiesRequest, opts ...grpc.CallOption) (proto.WorldService_WatchEntitiesClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	switch c.calls {
+	case 1:
+		return &fakeStream{
+			events: []*proto.EntityChangeEvent{{Entity: &proto.Entity{Id: "first"}}},
+			err:    status.Error(codes.Unavailable, "server went away"),
+		}, nil
+	case 2:
+		return nil, status.Error(codes.Unavailable, "connection refused")
+	default:
+		return &fakeStream{
+			events: []*proto.EntityChangeEvent{{Entity: &proto.Entity{Id: "second"}}},
+		}, nil
+	}
+}
+
+func TestWatchEntitiesWithRetry_StateChanges(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var states []StreamStateChange
+	stream, err := WatchEntitiesWithRetry(ctx, &flakyClient{}, &proto.ListEntitiesRequest{},
+		OnStateChange(func(sc StreamStateChange) { states = append(states, sc) }),
+		withFakeClock(&fakeClock{}), withMaxJitter())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ev, err := stream.Recv()
+	if err != nil || ev.Entity.GetId() != "first" {
+		t.Fatalf("first Recv = %v, %v", ev, err)
+	}
+	ev, err = stream.Recv()
+	if err != nil || ev.Entity.GetId() != "second" {
+		t.Fatalf("second Recv = %v, %v", ev, err)
+	}
+
+	want := []struct {
+		state   StreamState
+		backoff time.Duration
+	}{
+		{StreamConnecting, 0},
+		{StreamConnected, 0},
+		{StreamBackoff, 1 * time.Second},
+		{StreamConnecting, 0},
+		{StreamBackoff, 2 * time.Second},
+		{StreamConnecting, 0},
+		{StreamConnected, 0},
+	}
+	if len(states) != len(want) {
+		t.Fatalf("got %d state changes, want %d: %v", len(states), len(want), states)
+	}
+	for i, w := range want {
+		if states[i].State != w.state || states[i].Backoff != w.backoff {
+			t.Errorf("state %d = %s/%v, want %s/%v", i, states[i].State, states[i].Backoff, w.state, w.backoff)
+		}
+	}
+	if states[2].Err == nil {
+		t.Error("backoff should carry the error that caused it")
+	}
+}
+
+// downClient serves one stream that drops with Unavailable and refuses
+// every reconnect after it.
+type downClient struct {
+	proto.WorldServiceClient
+	calls int
+}
+
+func (c *downClient) WatchEntities(ctx context.Context, in *proto.ListEntitiesRequest, opts ...grpc.CallOption) (proto.WorldService_WatchEntitiesClient, error) {
+	c.calls++
+	if c.calls == 1 {
+		return &fakeStream{err: status.Error(codes.Unavailable, "server went away")}, nil
+	}
+	return nil, status.Errorf(codes.Unavailable, "connection refused %d", c.calls)
+}
+
+func TestWatchEntitiesWithRetry_MaxRetries(t *testing.T) {
+	client := &downClient{}
+	clock := &fakeClock{}
+	stream, err := WatchEntitiesWithRetry(context.Background(), client, &proto.ListEntitiesRequest{},
+		MaxRetries(3), withFakeClock(clock), withMaxJitter())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = stream.Recv()
+	if status.Code(err) != codes.Unavailable || status.Convert(err).Message() != "connection refused 4" {
+		t.Fatalf("Recv error = %v, want the last reconnect error", err)
+	}
+	if client.calls != 4 {
+		t.Errorf("got %d WatchEntities calls, want 1 + 3 retries", client.calls)
+	}
+	want := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second}
+	if len(clock.waits) != len(want) {
+		t.Fatalf("waits = %v, want %v", clock.waits, want)
+	}
+	for i := range want {
+		if clock.waits[i] != want[i] {
+			t.Errorf("wait %d = %v, want %v", i, clock.waits[i], want[i])
+		}
+	}
+}
+
+func TestWatchEntitiesWithRetry_MaxElapsed(t *testing.T) {
+	client := &downClient{}
+	clock := &fakeClock{}
+	stream, err := WatchEntitiesWithRetry(context.Background(), client, &proto.ListEntitiesRequest{},
+		MaxElapsed(10*time.Second), withFakeClock(clock), withMaxJitter())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Fatalf("Recv error = %v, want Unavailable", err)
+	}
+	// 1s + 2s + 4s, then the 8s wait is cut to the 3s left of the budget.
+	want := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 3 * time.Second}
+	if len(clock.waits) != len(want) {
+		t.Fatalf("waits = %v, want %v", clock.waits, want)
+	}
+	for i := range want {
+		if clock.waits[i] != want[i] {
+			t.Errorf("wait %d = %v, want %v", i, clock.waits[i], want[i])
+		}
+	}
+}
+
+func TestWatchEntitiesWithRetry_NoOptions(t *testing.T) {
+	stream, err := WatchEntitiesWithRetry(context.Background(), &flakyClient{}, &proto.ListEntitiesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev, err := stream.Recv(); err != nil || ev.Entity.GetId() != "first" {
+		t.Fatalf("Recv = %v, %v", ev, err)
+	}
+}
+
+func TestStreamStateString(t *testing.T) {
+	for state, want := range map[StreamState]string{
+		StreamConnecting: "connecting",
+		StreamConnected:  "connected",
+		StreamBackoff:    "backoff",
+		StreamState(42):  "unknown",
+	} {
+		if got := state.String(); got != want {
+			t.Errorf("%d.String() = %q, want %q", state, got, want)
+		}
+	}
+}
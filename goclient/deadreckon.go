@@ -0,0 +1,46 @@
+package goclient
+
+import (
+	"math"
+	"time"
+
+	"github.com/paulmach/orb"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// Extrapolate returns a copy of entity moved dt ahead by dead reckoning,
+// for drawing smooth motion between updates. The position advances by
+// Kinematics.VelocityEnu and, if set, AccelerationEnu; orientation and
+// everything else are kept. Entities without Geo or velocity are returned
+// as an unchanged copy. A negative dt projects backwards.
+//
+// Over the few seconds between updates the local tangent plane of the
+// spherical earth used by orb/geo is a good approximation; it is not meant
+// for projecting minutes ahead.
+func Extrapolate(entity *pb.Entity, dt time.Duration) *pb.Entity {
+	out := proto.Clone(entity).(*pb.Entity)
+	v := entity.GetKinematics().GetVelocityEnu()
+	if out.Geo == nil || v == nil {
+		return out
+	}
+
+	t := dt.Seconds()
+	a := entity.GetKinematics().GetAccelerationEnu()
+	east := v.GetEast()*t + 0.5*a.GetEast()*t*t
+	north := v.GetNorth()*t + 0.5*a.GetNorth()*t*t
+	up := v.GetUp()*t + 0.5*a.GetUp()*t*t
+
+	lat := out.Geo.Latitude * math.Pi / 180
+	out.Geo.Latitude += north / orb.EarthRadius * 180 / math.Pi
+	out.Geo.Longitude += east / (orb.EarthRadius * math.Cos(lat)) * 180 / math.Pi
+	if out.Geo.Longitude > 180 {
+		out.Geo.Longitude -= 360
+	} else if out.Geo.Longitude < -180 {
+		out.Geo.Longitude += 360
+	}
+	if out.Geo.Altitude != nil {
+		out.Geo.Altitude = proto.Float64(*out.Geo.Altitude + up)
+	}
+	return out
+}
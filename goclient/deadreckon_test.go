@@ -0,0 +1,76 @@
+package goclient
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func movingEntity(east, north, up float64) *pb.Entity {
+	return &pb.Entity{
+		Id:  "track",
+		Geo: &pb.GeoSpatialComponent{Latitude: 48.137, Longitude: 11.575, Altitude: proto.Float64(500)},
+		Kinematics: &pb.KinematicsComponent{
+			VelocityEnu: &pb.KinematicsEnu{East: proto.Float64(east), North: proto.Float64(north), Up: proto.Float64(up)},
+		},
+	}
+}
+
+func TestExtrapolate_ConstantVelocity(t *testing.T) {
+	// 25 m/s on a bearing of 60 degrees, climbing at 2 m/s.
+	bearing := 60.0
+	speed := 25.0
+	e := movingEntity(speed*math.Sin(bearing*math.Pi/180), speed*math.Cos(bearing*math.Pi/180), 2)
+	start := orb.Point{e.Geo.Longitude, e.Geo.Latitude}
+
+	for _, dt := range []time.Duration{200 * time.Millisecond, time.Second, 5 * time.Second} {
+		got := Extrapolate(e, dt)
+		want := geo.PointAtBearingAndDistance(start, bearing, speed*dt.Seconds())
+
+		if d := geo.Distance(orb.Point{got.Geo.Longitude, got.Geo.Latitude}, want); d > 0.01 {
+			t.Errorf("dt %v: %.3f m off the constant velocity path", dt, d)
+		}
+		if wantAlt := 500 + 2*dt.Seconds(); math.Abs(got.Geo.GetAltitude()-wantAlt) > 1e-9 {
+			t.Errorf("dt %v: altitude %v, want %v", dt, got.Geo.GetAltitude(), wantAlt)
+		}
+	}
+
+	if e.Geo.Latitude != 48.137 || e.Geo.GetAltitude() != 500 {
+		t.Error("Extrapolate modified its input")
+	}
+}
+
+func TestExtrapolate_Acceleration(t *testing.T) {
+	e := movingEntity(0, 0, 0)
+	e.Kinematics.AccelerationEnu = &pb.KinematicsEnu{North: proto.Float64(2)}
+
+	got := Extrapolate(e, 10*time.Second)
+	d := geo.Distance(orb.Point{e.Geo.Longitude, e.Geo.Latitude}, orb.Point{got.Geo.Longitude, got.Geo.Latitude})
+	if math.Abs(d-100) > 0.01 || got.Geo.Latitude <= e.Geo.Latitude {
+		t.Errorf("moved %.3f m, want 100 m north", d)
+	}
+}
+
+func TestExtrapolate_Unchanged(t *testing.T) {
+	still := &pb.Entity{Id: "still", Geo: &pb.GeoSpatialComponent{Latitude: 1, Longitude: 2}}
+	if got := Extrapolate(still, time.Second); !proto.Equal(got, still) {
+		t.Errorf("entity without velocity moved: %v", got)
+	}
+	noGeo := &pb.Entity{Id: "nogeo", Kinematics: movingEntity(1, 1, 1).Kinematics}
+	if got := Extrapolate(noGeo, time.Second); got.Geo != nil {
+		t.Error("entity without geo got a position")
+	}
+}
+
+func TestExtrapolate_Antimeridian(t *testing.T) {
+	e := movingEntity(100, 0, 0)
+	e.Geo.Latitude, e.Geo.Longitude = 0, 179.9999
+	if got := Extrapolate(e, 10*time.Second); got.Geo.Longitude > -179 {
+		t.Errorf("longitude %v should wrap", got.Geo.Longitude)
+	}
+}
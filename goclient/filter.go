@@ -0,0 +1,27 @@
+package goclient
+
+import pb "github.com/projectqai/proto/go"
+
+// And returns a filter that matches entities matching all of filters.
+//
+// Sibling fields of one EntityFilter are already ANDed, but there is no And
+// field to nest a conjunction of filters inside an Or branch or to AND two
+// Or filters. And expresses it as Not(Or(Not f1, Not f2, ...)), which the
+// server evaluates with the same short-circuiting as Or: matching stops at
+// the first filter that does not match. Nil filters are skipped; And of no
+// filters is nil, which matches everything.
+func And(filters ...*pb.EntityFilter) *pb.EntityFilter {
+	var negated []*pb.EntityFilter
+	for _, f := range filters {
+		if f != nil {
+			negated = append(negated, &pb.EntityFilter{Not: f})
+		}
+	}
+	switch len(negated) {
+	case 0:
+		return nil
+	case 1:
+		return negated[0].Not
+	}
+	return &pb.EntityFilter{Not: &pb.EntityFilter{Or: negated}}
+}
@@ -0,0 +1,25 @@
+package goclient
+
+import (
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestAnd(t *testing.T) {
+	if And() != nil || And(nil, nil) != nil {
+		t.Error("And of no filters should be nil")
+	}
+
+	a := &pb.EntityFilter{Label: proto.String("a")}
+	if And(nil, a) != a {
+		t.Error("And of one filter should return it unchanged")
+	}
+
+	b := &pb.EntityFilter{Label: proto.String("b")}
+	got := And(a, b)
+	if got.Not == nil || len(got.Not.Or) != 2 || got.Not.Or[0].Not != a || got.Not.Or[1].Not != b {
+		t.Errorf("And(a, b) = %v, want Not(Or(Not a, Not b))", got)
+	}
+}
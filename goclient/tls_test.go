@@ -0,0 +1,71 @@
+package goclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestConnect_ClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	server := ca.issue(t, "server", true)
+
+	dir := t.TempDir()
+	caFile := ca.writePEM(t, dir)
+	certFile, keyFile := writeTLSFiles(t, dir, "client", ca.issue(t, "client", false))
+
+	// A server like the engine with --tls-client-ca.
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{server},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})))
+	grpc_health_v1.RegisterHealthServer(srv, health.NewServer())
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	check := func(serverURL string, opts ...ConnectOption) error {
+		t.Helper()
+		conn, err := Connect(serverURL, opts...)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = conn.Close() }()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		return err
+	}
+
+	addr := lis.Addr().String()
+	if err := check("https://"+addr, WithClientCertificate(certFile, keyFile), WithRootCA(caFile)); err != nil {
+		t.Errorf("https with client certificate: %v", err)
+	}
+	if err := check(addr, WithClientCertificate(certFile, keyFile), WithRootCA(caFile)); err != nil {
+		t.Errorf("host:port with TLS options: %v", err)
+	}
+	if err := check("https://"+addr, WithRootCA(caFile)); err == nil {
+		t.Error("server accepted a client without certificate")
+	}
+	if _, err := Connect("http://"+addr, WithRootCA(caFile)); err == nil {
+		t.Error("http:// with TLS options should be rejected")
+	}
+	if _, err := Connect(addr, WithClientCertificate(filepath.Join(dir, "missing.pem"), keyFile)); err == nil {
+		t.Error("missing client certificate should be an error")
+	}
+}
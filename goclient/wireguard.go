@@ -10,8 +10,10 @@ import (
 	"net"
 	"net/netip"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.zx2c4.com/wireguard/conn"
@@ -27,6 +29,12 @@ const (
 	dnsResolveInterval = 60 * time.Second
 	// dnsTimeout is the timeout for DNS resolution
 	dnsTimeout = 5 * time.Second
+	// healthCheckInterval is how often we check the handshake age
+	healthCheckInterval = 30 * time.Second
+	// handshakeStaleAfter is the handshake age after which the tunnel is
+	// considered dead. WireGuard re-handshakes every 2 minutes while traffic
+	// flows and rejects a session after 3.
+	handshakeStaleAfter = 3 * time.Minute
 )
 
 var dnsResolver = &net.Resolver{}
@@ -37,6 +45,9 @@ type WireGuardConfig struct {
 	Address       netip.Addr // client's IP in the WireGuard network
 	PeerPublicKey string     // server's WireGuard public key (base64)
 	Endpoint      string     // WireGuard endpoint (host:port)
+
+	AllowedIPs []netip.Prefix // prefixes routed through the peer; all traffic if empty
+	DNS        []netip.Addr   // DNS servers used inside the tunnel
 }
 
 // ParseWireGuardConfig parses a standard WireGuard config file
@@ -86,6 +97,15 @@ func ParseWireGuardConfig(path string) (*WireGuardConfig, error) {
 					return nil, fmt.Errorf("invalid Address: %w", err)
 				}
 				cfg.Address = addr
+			case "dns":
+				for _, item := range splitList(value) {
+					addr, err := netip.ParseAddr(item)
+					if err != nil {
+						// wg-quick allows search domains here; we have no use for them.
+						continue
+					}
+					cfg.DNS = append(cfg.DNS, addr)
+				}
 			}
 		case "peer":
 			switch key {
@@ -93,6 +113,14 @@ func ParseWireGuardConfig(path string) (*WireGuardConfig, error) {
 				cfg.PeerPublicKey = value
 			case "endpoint":
 				cfg.Endpoint = value
+			case "allowedips":
+				for _, item := range splitList(value) {
+					prefix, err := netip.ParsePrefix(item)
+					if err != nil {
+						return nil, fmt.Errorf("invalid AllowedIPs: %w", err)
+					}
+					cfg.AllowedIPs = append(cfg.AllowedIPs, prefix)
+				}
 			}
 		}
 	}
@@ -118,6 +146,30 @@ func ParseWireGuardConfig(path string) (*WireGuardConfig, error) {
 	return cfg, nil
 }
 
+// splitList splits a comma separated config value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// allowedIPLines renders the allowed_ip lines of the IPC config. Without any
+// configured prefixes all traffic goes through the tunnel.
+func allowedIPLines(prefixes []netip.Prefix) string {
+	if len(prefixes) == 0 {
+		return "allowed_ip=0.0.0.0/0\nallowed_ip=::/0\n"
+	}
+	var b strings.Builder
+	for _, p := range prefixes {
+		fmt.Fprintf(&b, "allowed_ip=%s\n", p.Masked())
+	}
+	return b.String()
+}
+
 func resolveEndpoint(endpoint string) (string, error) {
 	host, port, err := net.SplitHostPort(endpoint)
 	if err != nil {
@@ -165,6 +217,15 @@ type WireGuardTunnel struct {
 	dnsWg           sync.WaitGroup
 	privateKeyHex   string
 	peerKeyHex      string
+
+	// Health monitoring fields
+	ipcGet      func() (string, error)
+	ipcSet      func(string) error
+	createdAt   time.Time
+	healthy     atomic.Bool
+	stopHealth  chan struct{}
+	healthWg    sync.WaitGroup
+	resolveFunc func(string) (string, error)
 }
 
 // Close shuts down the WireGuard tunnel
@@ -174,13 +235,18 @@ func (t *WireGuardTunnel) Close() error {
 		close(t.stopDNS)
 		t.dnsWg.Wait()
 	}
+	// Stop health monitor
+	if t.stopHealth != nil {
+		close(t.stopHealth)
+		t.healthWg.Wait()
+	}
 	t.device.Close()
 	return nil
 }
 
 // updateEndpoint re-resolves the hostname and updates WireGuard if the IP changed
 func (t *WireGuardTunnel) updateEndpoint() error {
-	resolved, err := resolveEndpoint(net.JoinHostPort(t.originalHost, t.originalPort))
+	resolved, err := t.resolveFunc(net.JoinHostPort(t.originalHost, t.originalPort))
 	if err != nil {
 		return err
 	}
@@ -201,7 +267,7 @@ func (t *WireGuardTunnel) updateEndpoint() error {
 		resolved,
 	)
 
-	if err := t.device.IpcSet(config); err != nil {
+	if err := t.ipcSet(config); err != nil {
 		return fmt.Errorf("failed to update endpoint: %w", err)
 	}
 
@@ -209,6 +275,122 @@ func (t *WireGuardTunnel) updateEndpoint() error {
 	return nil
 }
 
+// Healthy reports whether the tunnel had a recent handshake with the peer.
+// A new tunnel counts as healthy until it had time for its first handshake.
+func (t *WireGuardTunnel) Healthy() bool {
+	return t.healthy.Load()
+}
+
+// parseLastHandshake extracts the last handshake time from IpcGet output.
+// It returns the zero time if no handshake happened yet.
+func parseLastHandshake(ipc string) (time.Time, error) {
+	var sec, nsec int64
+	for _, line := range strings.Split(ipc, "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "last_handshake_time_sec":
+			v, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("invalid last_handshake_time_sec: %w", err)
+			}
+			sec = v
+		case "last_handshake_time_nsec":
+			v, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("invalid last_handshake_time_nsec: %w", err)
+			}
+			nsec = v
+		}
+	}
+	if sec == 0 && nsec == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(sec, nsec), nil
+}
+
+// checkHealth inspects the handshake age and, if it is stale, re-applies the
+// peer endpoint to force a new handshake. This recovers from NAT rebinding
+// and peer restarts, where the session silently stops working.
+func (t *WireGuardTunnel) checkHealth(now time.Time) {
+	ipc, err := t.ipcGet()
+	if err != nil {
+		slog.Warn("failed to read WireGuard state", "error", err)
+		return
+	}
+	last, err := parseLastHandshake(ipc)
+	if err != nil {
+		slog.Warn("failed to parse WireGuard state", "error", err)
+		return
+	}
+
+	ref := last
+	if ref.IsZero() {
+		ref = t.createdAt
+	}
+	if now.Sub(ref) < handshakeStaleAfter {
+		t.healthy.Store(true)
+		return
+	}
+
+	t.healthy.Store(false)
+	slog.Warn("WireGuard handshake stale, re-applying endpoint", "lastHandshake", last, "endpoint", t.originalHost)
+
+	if err := t.forceHandshake(); err != nil {
+		slog.Warn("failed to re-apply WireGuard endpoint", "error", err)
+	}
+}
+
+// forceHandshake re-resolves the endpoint, re-applies it to the peer, even
+// if the address did not change, and makes the device initiate a handshake.
+// Setting the endpoint alone sends nothing; wireguard-go sends a keepalive,
+// which needs a fresh session once the old one is rejected, only when the
+// keepalive interval is turned on from 0, so it is turned off and on again.
+func (t *WireGuardTunnel) forceHandshake() error {
+	resolved, err := t.resolveFunc(net.JoinHostPort(t.originalHost, t.originalPort))
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	config := fmt.Sprintf("public_key=%s\nendpoint=%s\npersistent_keepalive_interval=0\npersistent_keepalive_interval=25\n",
+		t.peerKeyHex,
+		resolved,
+	)
+	if err := t.ipcSet(config); err != nil {
+		return fmt.Errorf("failed to re-apply endpoint: %w", err)
+	}
+
+	t.currentEndpoint = resolved
+	return nil
+}
+
+// startHealthMonitor starts a background goroutine that periodically checks
+// handshake freshness
+func (t *WireGuardTunnel) startHealthMonitor() {
+	t.stopHealth = make(chan struct{})
+	t.healthWg.Add(1)
+
+	go func() {
+		defer t.healthWg.Done()
+		ticker := time.NewTicker(healthCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-t.stopHealth:
+				return
+			case now := <-ticker.C:
+				t.checkHealth(now)
+			}
+		}
+	}()
+}
+
 // startDNSResolver starts a background goroutine that periodically re-resolves the endpoint
 func (t *WireGuardTunnel) startDNSResolver() {
 	t.stopDNS = make(chan struct{})
@@ -276,7 +458,7 @@ func NewWireGuardTunnel(cfg *WireGuardConfig) (*WireGuardTunnel, error) {
 
 	// Create the netstack TUN device
 	localAddrs := []netip.Addr{cfg.Address}
-	tun, tnet, err := netstack.CreateNetTUN(localAddrs, nil, device.DefaultMTU)
+	tun, tnet, err := netstack.CreateNetTUN(localAddrs, cfg.DNS, device.DefaultMTU)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create netstack TUN: %w", err)
 	}
@@ -287,17 +469,16 @@ func NewWireGuardTunnel(cfg *WireGuardConfig) (*WireGuardTunnel, error) {
 	privateKeyHex := hex.EncodeToString(privateKey)
 	peerKeyHex := hex.EncodeToString(peerPublicKey)
 
-	// Configure: allow all traffic through tunnel
+	// Configure the peer; route the allowed IPs (default: everything) through it
 	config := fmt.Sprintf(`private_key=%s
 public_key=%s
 endpoint=%s
-allowed_ip=0.0.0.0/0
-allowed_ip=::/0
-persistent_keepalive_interval=25
+%spersistent_keepalive_interval=25
 `,
 		privateKeyHex,
 		peerKeyHex,
 		resolvedEndpoint,
+		allowedIPLines(cfg.AllowedIPs),
 	)
 
 	if err := dev.IpcSet(config); err != nil {
@@ -319,12 +500,18 @@ persistent_keepalive_interval=25
 		currentEndpoint: resolvedEndpoint,
 		privateKeyHex:   privateKeyHex,
 		peerKeyHex:      peerKeyHex,
+		ipcGet:          dev.IpcGet,
+		ipcSet:          dev.IpcSet,
+		createdAt:       time.Now(),
+		resolveFunc:     resolveEndpoint,
 	}
+	tunnel.healthy.Store(true)
 
 	// Start DNS resolution goroutine if endpoint is a hostname (not an IP)
 	if net.ParseIP(host) == nil {
 		tunnel.startDNSResolver()
 	}
+	tunnel.startHealthMonitor()
 
 	return tunnel, nil
 }
@@ -0,0 +1,224 @@
+package goclient
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testWGConfig = `[Interface]
+PrivateKey = cHJpdmF0ZWtleXByaXZhdGVrZXlwcml2YXRla2V5MDA=
+Address = 10.8.0.2/32
+DNS = 10.8.0.1, 1.1.1.1, corp.example
+
+[Peer]
+PublicKey = cHVibGlja2V5cHVibGlja2V5cHVibGlja2V5cHViMDA=
+Endpoint = vpn.example.com:51820
+AllowedIPs = 10.8.0.0/24, 192.168.10.0/24,fd00::/64
+`
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "wg.conf")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseWireGuardConfig_AllowedIPsAndDNS(t *testing.T) {
+	cfg, err := ParseWireGuardConfig(writeConfig(t, testWGConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantAllowed := []netip.Prefix{
+		netip.MustParsePrefix("10.8.0.0/24"),
+		netip.MustParsePrefix("192.168.10.0/24"),
+		netip.MustParsePrefix("fd00::/64"),
+	}
+	if len(cfg.AllowedIPs) != len(wantAllowed) {
+		t.Fatalf("AllowedIPs = %v, want %v", cfg.AllowedIPs, wantAllowed)
+	}
+	for i, p := range wantAllowed {
+		if cfg.AllowedIPs[i] != p {
+			t.Errorf("AllowedIPs[%d] = %v, want %v", i, cfg.AllowedIPs[i], p)
+		}
+	}
+
+	wantDNS := []netip.Addr{netip.MustParseAddr("10.8.0.1"), netip.MustParseAddr("1.1.1.1")}
+	if len(cfg.DNS) != len(wantDNS) {
+		t.Fatalf("DNS = %v, want %v", cfg.DNS, wantDNS)
+	}
+	for i, a := range wantDNS {
+		if cfg.DNS[i] != a {
+			t.Errorf("DNS[%d] = %v, want %v", i, cfg.DNS[i], a)
+		}
+	}
+
+	if cfg.Address != netip.MustParseAddr("10.8.0.2") {
+		t.Errorf("Address = %v", cfg.Address)
+	}
+}
+
+func TestParseWireGuardConfig_InvalidAllowedIPs(t *testing.T) {
+	content := `[Interface]
+PrivateKey = a
+Address = 10.8.0.2
+
+[Peer]
+PublicKey = b
+Endpoint = 1.2.3.4:51820
+AllowedIPs = not-a-prefix
+`
+	if _, err := ParseWireGuardConfig(writeConfig(t, content)); err == nil {
+		t.Fatal("expected error for invalid AllowedIPs")
+	}
+}
+
+func TestAllowedIPLines(t *testing.T) {
+	if got, want := allowedIPLines(nil), "allowed_ip=0.0.0.0/0\nallowed_ip=::/0\n"; got != want {
+		t.Errorf("default = %q, want %q", got, want)
+	}
+
+	got := allowedIPLines([]netip.Prefix{
+		netip.MustParsePrefix("10.8.0.5/24"),
+		netip.MustParsePrefix("fd00::/64"),
+	})
+	if want := "allowed_ip=10.8.0.0/24\nallowed_ip=fd00::/64\n"; got != want {
+		t.Errorf("allowedIPLines = %q, want %q", got, want)
+	}
+}
+
+func TestParseLastHandshake(t *testing.T) {
+	got, err := parseLastHandshake("public_key=abcd\nlast_handshake_time_sec=1700000000\nlast_handshake_time_nsec=500\nrx_bytes=1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Unix(1700000000, 500); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	got, err = parseLastHandshake("last_handshake_time_sec=0\nlast_handshake_time_nsec=0\n")
+	if err != nil || !got.IsZero() {
+		t.Errorf("no handshake: got %v, %v", got, err)
+	}
+
+	if _, err := parseLastHandshake("last_handshake_time_sec=abc\n"); err == nil {
+		t.Error("expected error for malformed value")
+	}
+}
+
+// fakeDevice records the IPC configuration a tunnel sets. Like wireguard-go
+// (device/uapi.go) it sends a keepalive, and with it a handshake
+// initiation, only when a set turns persistent_keepalive_interval from 0
+// to non-zero; re-setting the same interval or the endpoint sends nothing.
+type fakeDevice struct {
+	sets        []string
+	keepalive   uint64
+	initiations int
+}
+
+func (d *fakeDevice) ipcSet(config string) error {
+	d.sets = append(d.sets, config)
+	pkaOn := false
+	for line := range strings.Lines(config) {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), "persistent_keepalive_interval=")
+		if !ok {
+			continue
+		}
+		secs, err := strconv.ParseUint(value, 10, 16)
+		if err != nil {
+			return err
+		}
+		pkaOn = d.keepalive == 0 && secs != 0
+		d.keepalive = secs
+	}
+	if pkaOn {
+		d.initiations++
+	}
+	return nil
+}
+
+// newFakeTunnel returns a tunnel whose device IPC is simulated, configured
+// with a keepalive as NewWireGuardTunnel does.
+func newFakeTunnel(lastHandshake time.Time, dev *fakeDevice) *WireGuardTunnel {
+	dev.keepalive = 25
+	tunnel := &WireGuardTunnel{
+		originalHost: "vpn.example.com",
+		originalPort: "51820",
+		peerKeyHex:   "peer",
+		createdAt:    lastHandshake.Add(-time.Hour),
+		ipcGet: func() (string, error) {
+			return fmt.Sprintf("last_handshake_time_sec=%d\nlast_handshake_time_nsec=0\n", lastHandshake.Unix()), nil
+		},
+		ipcSet:      dev.ipcSet,
+		resolveFunc: func(string) (string, error) { return "203.0.113.7:51820", nil },
+	}
+	tunnel.healthy.Store(true)
+	return tunnel
+}
+
+func TestCheckHealth_StaleHandshakeRekeys(t *testing.T) {
+	now := time.Now()
+	var dev fakeDevice
+	tunnel := newFakeTunnel(now.Add(-5*time.Minute), &dev)
+
+	tunnel.checkHealth(now)
+
+	if tunnel.Healthy() {
+		t.Error("tunnel with stale handshake should be unhealthy")
+	}
+	if len(dev.sets) != 1 {
+		t.Fatalf("expected endpoint to be re-applied once, got %d IpcSet calls", len(dev.sets))
+	}
+	if !strings.Contains(dev.sets[0], "endpoint=203.0.113.7:51820") || !strings.Contains(dev.sets[0], "public_key=peer") {
+		t.Errorf("unexpected config: %q", dev.sets[0])
+	}
+	if dev.initiations != 1 || dev.keepalive != 25 {
+		t.Errorf("got %d handshake initiations and keepalive %d, want 1 and 25", dev.initiations, dev.keepalive)
+	}
+	if tunnel.currentEndpoint != "203.0.113.7:51820" {
+		t.Errorf("currentEndpoint = %q", tunnel.currentEndpoint)
+	}
+
+	// Every stale check initiates again.
+	tunnel.checkHealth(now.Add(healthCheckInterval))
+	if dev.initiations != 2 {
+		t.Errorf("got %d handshake initiations after the second check, want 2", dev.initiations)
+	}
+}
+
+func TestCheckHealth_FreshHandshake(t *testing.T) {
+	now := time.Now()
+	var dev fakeDevice
+	tunnel := newFakeTunnel(now.Add(-30*time.Second), &dev)
+	tunnel.healthy.Store(false)
+
+	tunnel.checkHealth(now)
+
+	if !tunnel.Healthy() {
+		t.Error("tunnel with fresh handshake should be healthy")
+	}
+	if len(dev.sets) != 0 {
+		t.Errorf("fresh tunnel should not be re-keyed, got %v", dev.sets)
+	}
+}
+
+func TestCheckHealth_NoHandshakeYet(t *testing.T) {
+	now := time.Now()
+	var dev fakeDevice
+	tunnel := newFakeTunnel(time.Unix(0, 0), &dev)
+	tunnel.createdAt = now.Add(-10 * time.Second)
+
+	tunnel.checkHealth(now)
+
+	if !tunnel.Healthy() || len(dev.sets) != 0 {
+		t.Error("new tunnel without a handshake yet should get a grace period")
+	}
+}
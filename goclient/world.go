@@ -0,0 +1,112 @@
+package goclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	proto "github.com/projectqai/proto/go"
+	"google.golang.org/grpc"
+)
+
+// DefaultCallTimeout bounds each unary call made through World.
+const DefaultCallTimeout = 10 * time.Second
+
+// World wraps a WorldServiceClient with the calls builtins make most. Every
+// unary call gets its own timeout on top of the caller's context, and
+// errors name the call and keep their gRPC status for status.Code.
+type World struct {
+	client  proto.WorldServiceClient
+	timeout time.Duration
+}
+
+// WorldOption configures a World.
+type WorldOption func(*World)
+
+// WithCallTimeout sets the timeout of each unary call. Zero leaves calls
+// bounded by the caller's context only.
+func WithCallTimeout(d time.Duration) WorldOption {
+	return func(w *World) { w.timeout = d }
+}
+
+// NewWorld returns a World using conn, e.g. a *Connection or the builtin
+// client connection.
+func NewWorld(conn grpc.ClientConnInterface, opts ...WorldOption) *World {
+	w := &World{client: proto.NewWorldServiceClient(conn), timeout: DefaultCallTimeout}
+	for _, o := range opts {
+		o(w)
+	}
+	return w
+}
+
+// Client returns the underlying client for calls World doesn't wrap.
+func (w *World) Client() proto.WorldServiceClient {
+	return w.client
+}
+
+func (w *World) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if w.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, w.timeout)
+}
+
+// PushEntities pushes entities in one request. Pushing nothing is a no-op.
+func (w *World) PushEntities(ctx context.Context, entities ...*proto.Entity) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	ctx, cancel := w.callContext(ctx)
+	defer cancel()
+	if _, err := w.client.Push(ctx, &proto.EntityChangeRequest{Changes: entities}); err != nil {
+		if len(entities) == 1 {
+			return fmt.Errorf("push %s: %w", entities[0].Id, err)
+		}
+		return fmt.Errorf("push %d entities: %w", len(entities), err)
+	}
+	return nil
+}
+
+// Expire expires the entity with the given id.
+func (w *World) Expire(ctx context.Context, id string) error {
+	ctx, cancel := w.callContext(ctx)
+	defer cancel()
+	if _, err := w.client.ExpireEntity(ctx, &proto.ExpireEntityRequest{Id: id}); err != nil {
+		return fmt.Errorf("expire %s: %w", id, err)
+	}
+	return nil
+}
+
+// List returns the entities matching filter; a nil filter lists all.
+func (w *World) List(ctx context.Context, filter *proto.EntityFilter) ([]*proto.Entity, error) {
+	ctx, cancel := w.callContext(ctx)
+	defer cancel()
+	resp, err := w.client.ListEntities(ctx, &proto.ListEntitiesRequest{Filter: filter})
+	if err != nil {
+		return nil, fmt.Errorf("list entities: %w", err)
+	}
+	return resp.Entities, nil
+}
+
+// Get returns the entity with the given id. A missing entity is an error
+// with code NotFound.
+func (w *World) Get(ctx context.Context, id string) (*proto.Entity, error) {
+	ctx, cancel := w.callContext(ctx)
+	defer cancel()
+	resp, err := w.client.GetEntity(ctx, &proto.GetEntityRequest{Id: id})
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", id, err)
+	}
+	return resp.Entity, nil
+}
+
+// Watch streams changes to the entities matching filter, reconnecting on
+// transient errors like WatchEntitiesWithRetry. The stream lives as long as
+// ctx; it has no call timeout.
+func (w *World) Watch(ctx context.Context, filter *proto.EntityFilter, behavior *proto.WatchBehavior, opts ...WatchOption) (proto.WorldService_WatchEntitiesClient, error) {
+	stream, err := WatchEntitiesWithRetry(ctx, w.client, &proto.ListEntitiesRequest{Filter: filter, Behaviour: behavior}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("watch entities: %w", err)
+	}
+	return stream, nil
+}
@@ -0,0 +1,113 @@
+package goclient_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/projectqai/hydris/engine"
+	"github.com/projectqai/hydris/goclient"
+	pb "github.com/projectqai/proto/go"
+	"github.com/projectqai/proto/go/_goconnect"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// inProcessWorld serves a WorldServer on an in-memory listener and returns
+// a World connected to it.
+func inProcessWorld(t *testing.T) *goclient.World {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	path, handler := _goconnect.NewWorldServiceHandler(engine.NewWorldServer())
+	mux.Handle(path, handler)
+
+	ln := bufconn.Listen(1 << 20)
+	srv := &http.Server{Handler: h2c.NewHandler(mux, &http2.Server{})}
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return goclient.NewWorld(conn, goclient.WithCallTimeout(5*time.Second))
+}
+
+func TestWorld_PushGetListExpire(t *testing.T) {
+	w := inProcessWorld(t)
+	ctx := context.Background()
+
+	if err := w.PushEntities(ctx,
+		&pb.Entity{Id: "sat.1", Label: proto.String("ISS"), Geo: &pb.GeoSpatialComponent{Latitude: 1}},
+		&pb.Entity{Id: "sat.2", Label: proto.String("Hubble")},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := w.Get(ctx, "sat.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.GetLabel() != "ISS" {
+		t.Errorf("label %q", e.GetLabel())
+	}
+
+	geo := &pb.EntityFilter{Component: []uint32{uint32(pb.EntityComponent_EntityComponentGeo)}}
+	entities, err := w.List(ctx, geo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entities) != 1 || entities[0].Id != "sat.1" {
+		t.Errorf("listed %v, want sat.1 only", entities)
+	}
+
+	if err := w.Expire(ctx, "sat.2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Expire(ctx, "sat.3"); status.Code(err) != codes.NotFound {
+		t.Errorf("expire of missing entity: got %v, want NotFound", err)
+	}
+	if _, err := w.Get(ctx, "sat.3"); status.Code(err) != codes.NotFound {
+		t.Errorf("get of missing entity: got %v, want NotFound", err)
+	}
+}
+
+func TestWorld_Watch(t *testing.T) {
+	w := inProcessWorld(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := "sat.1"
+	stream, err := w.Watch(ctx, &pb.EntityFilter{Id: &id}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The server signals readiness with an empty event.
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.PushEntities(ctx, &pb.Entity{Id: "sat.2"}, &pb.Entity{Id: id}); err != nil {
+		t.Fatal(err)
+	}
+	ev, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Entity.GetId() != id || ev.T != pb.EntityChange_EntityChangeUpdated {
+		t.Errorf("got %v %s, want update of %s", ev.T, ev.Entity.GetId(), id)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"log/slog"
 	"os"
 	"os/exec"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/projectqai/hydris/pkg/logging"
@@ -23,32 +25,119 @@ import (
 
 func init() {
 	cli.CMD.Flags().Bool("view", false, "open builtin webview")
-	cli.CMD.Flags().StringP("world", "w", "", "world state file to load on startup and periodically flush to")
+	cli.CMD.Flags().StringP("world", "w", "", "world state file, or directory of world files, to load on startup and periodically flush to")
+	cli.CMD.Flags().String("world-group-by", "source", "with a world directory, write entities back to the file they came from (source) or to one file per controller (controller)")
 	cli.CMD.Flags().String("policy", "", "path to OPA policy file (.rego) for access control")
 	cli.CMD.Flags().Bool("disable-local-serial", false, "disable discovery of local serial ports")
 	cli.CMD.Flags().Bool("allow-netscan", false, "allow scanning the local network for devices")
 	cli.CMD.Flags().Bool("no-defaults", false, "do not load builtin default world entities")
+	cli.CMD.Flags().Bool("no-fsync", false, "do not sync the world file to disk on flush (faster, but may lose recent changes on power loss)")
+	cli.CMD.Flags().Duration("persist-debounce", engine.DefaultPersistDebounce, "time to coalesce changes before the world file is saved")
 	cli.CMD.Flags().StringSlice("allow-path", nil, "allow file access to additional paths (e.g. for TLS certificates)")
 	cli.CMD.Flags().StringSlice("plugin", nil, "plugins to run (local .ts/.js files or OCI image refs)")
+	cli.CMD.Flags().Bool("strict-validation", false, "reject pushed entities with unnormalized orientation quaternions instead of normalizing them")
+	cli.CMD.Flags().Int("max-entity-bytes", 0, "reject pushed entities larger than this many bytes when serialized (0 = no limit)")
+	cli.CMD.Flags().Int("max-shape-points", 0, "reject pushed entities whose shapes have more points than this (0 = no limit)")
+	cli.CMD.Flags().IntSlice("allowed-components", nil, "reject pushed entities with components other than these field numbers (e.g., 2=label, 11=geo)")
+	cli.CMD.Flags().Bool("access-log", false, "log who was allowed or denied which request, for audit")
+	cli.CMD.Flags().Float64("access-log-sample", engine.DefaultAccessLogSampleRate, "fraction of allowed requests the access log records; denied ones are always recorded")
+	cli.CMD.Flags().Bool("correlate", false, "group entities of different controllers that are close in space and time")
+	cli.CMD.Flags().Float64("correlate-distance", engine.DefaultCorrelationDistance, "maximum distance in meters between correlated entities")
+	cli.CMD.Flags().Duration("correlate-window", engine.DefaultCorrelationWindow, "maximum time between the last observations of correlated entities")
+	cli.CMD.Flags().Bool("smooth", false, "smooth noisy track positions and estimate their velocity with a Kalman filter")
+	cli.CMD.Flags().StringSlice("smooth-controller", nil, "smooth only the tracks of these controllers (implies --smooth)")
+	cli.CMD.Flags().Duration("gc-interval", engine.DefaultGCInterval, "time between sweeps that expire entities")
+	cli.CMD.Flags().Int("gc-max-per-sweep", 0, "maximum entities expired or updated per sweep, the rest waits for the next one (0 = no limit)")
+	cli.CMD.Flags().Duration("keepalive-ping", engine.DefaultKeepalivePing, "ping clients after this long without traffic (negative = never)")
+	cli.CMD.Flags().Duration("keepalive-timeout", engine.DefaultKeepaliveTimeout, "close connections whose ping is not answered within this time")
+	cli.CMD.Flags().Duration("send-timeout", engine.DefaultSendTimeout, "evict watch streams whose client has not accepted an event for this long (negative = never)")
+	cli.CMD.Flags().Duration("shutdown-timeout", engine.DefaultShutdownTimeout, "on SIGINT/SIGTERM, wait this long for watch streams and requests to finish before the final flush")
+	cli.CMD.Flags().String("tls-cert", os.Getenv("HYDRIS_TLS_CERT"), "PEM server certificate; with --tls-key serves TLS instead of plaintext (env HYDRIS_TLS_CERT)")
+	cli.CMD.Flags().String("tls-key", os.Getenv("HYDRIS_TLS_KEY"), "PEM server key (env HYDRIS_TLS_KEY)")
+	cli.CMD.Flags().String("tls-client-ca", os.Getenv("HYDRIS_TLS_CLIENT_CA"), "PEM CA bundle; if set, clients must present a certificate signed by it (env HYDRIS_TLS_CLIENT_CA)")
+	cli.CMD.Flags().Duration("max-connection-idle", engine.DefaultMaxConnectionIdle, "close connections without open streams after this long (negative = never)")
 
 	cli.CMD.RunE = func(cmd *cobra.Command, args []string) error {
 		all, _ := cmd.Flags().GetBool("all")
 		enableView, _ := cmd.Flags().GetBool("view")
 		worldFile, _ := cmd.Flags().GetString("world")
+		worldGroupBy, _ := cmd.Flags().GetString("world-group-by")
 		policyFile, _ := cmd.Flags().GetString("policy")
 		disableSerial, _ := cmd.Flags().GetBool("disable-local-serial")
 		allowNetscan, _ := cmd.Flags().GetBool("allow-netscan")
 		noDefaults, _ := cmd.Flags().GetBool("no-defaults")
+		noFsync, _ := cmd.Flags().GetBool("no-fsync")
+		persistDebounce, _ := cmd.Flags().GetDuration("persist-debounce")
 		allowPaths, _ := cmd.Flags().GetStringSlice("allow-path")
 		plugins, _ := cmd.Flags().GetStringSlice("plugin")
+		strictValidation, _ := cmd.Flags().GetBool("strict-validation")
+		maxEntityBytes, _ := cmd.Flags().GetInt("max-entity-bytes")
+		maxShapePoints, _ := cmd.Flags().GetInt("max-shape-points")
+		allowedComponents, _ := cmd.Flags().GetIntSlice("allowed-components")
+		accessLog, _ := cmd.Flags().GetBool("access-log")
+		accessLogSample, _ := cmd.Flags().GetFloat64("access-log-sample")
+		correlate, _ := cmd.Flags().GetBool("correlate")
+		correlateDistance, _ := cmd.Flags().GetFloat64("correlate-distance")
+		correlateWindow, _ := cmd.Flags().GetDuration("correlate-window")
+		smooth, _ := cmd.Flags().GetBool("smooth")
+		smoothControllers, _ := cmd.Flags().GetStringSlice("smooth-controller")
+		gcInterval, _ := cmd.Flags().GetDuration("gc-interval")
+		gcMaxPerSweep, _ := cmd.Flags().GetInt("gc-max-per-sweep")
+		keepalivePing, _ := cmd.Flags().GetDuration("keepalive-ping")
+		keepaliveTimeout, _ := cmd.Flags().GetDuration("keepalive-timeout")
+		maxConnectionIdle, _ := cmd.Flags().GetDuration("max-connection-idle")
+		shutdownTimeout, _ := cmd.Flags().GetDuration("shutdown-timeout")
+		sendTimeout, _ := cmd.Flags().GetDuration("send-timeout")
+		tlsCert, _ := cmd.Flags().GetString("tls-cert")
+		tlsKey, _ := cmd.Flags().GetString("tls-key")
+		tlsClientCA, _ := cmd.Flags().GetString("tls-client-ca")
 
-		ctx := context.Background()
+		// Stop on SIGINT/SIGTERM so the world file gets a final flush.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		stopped := make(chan struct{})
 
+		tlsConfig := engine.TLSConfig{
+			CertFile:     tlsCert,
+			KeyFile:      tlsKey,
+			ClientCAFile: tlsClientCA,
+		}
 		serverAddr, err := engine.StartEngine(ctx, engine.EngineConfig{
-			WorldFile:  worldFile,
-			PolicyFile: policyFile,
-			NoDefaults: noDefaults,
-			LogHandler: logging.Ring,
+			WorldFile:        worldFile,
+			WorldGroupBy:     worldGroupBy,
+			PolicyFile:       policyFile,
+			NoDefaults:       noDefaults,
+			NoFsync:          noFsync,
+			PersistDebounce:  persistDebounce,
+			Stopped:          stopped,
+			ShutdownTimeout:  shutdownTimeout,
+			LogHandler:       logging.Ring,
+			StrictValidation: strictValidation,
+			PushLimits: engine.PushLimits{
+				MaxEntityBytes:    maxEntityBytes,
+				MaxShapePoints:    maxShapePoints,
+				AllowedComponents: componentNumbers(allowedComponents),
+			},
+			AccessLog:           accessLog,
+			AccessLogSampleRate: accessLogSample,
+
+			Correlate:           correlate,
+			CorrelationDistance: correlateDistance,
+			CorrelationWindow:   correlateWindow,
+
+			Smooth:            smooth,
+			SmoothControllers: smoothControllers,
+
+			GC:          engine.GCConfig{Interval: gcInterval, MaxPerSweep: gcMaxPerSweep},
+			SendTimeout: sendTimeout,
+
+			Keepalive: engine.KeepaliveConfig{
+				Ping:              keepalivePing,
+				Timeout:           keepaliveTimeout,
+				MaxConnectionIdle: maxConnectionIdle,
+			},
+
+			TLS: tlsConfig,
 		})
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
@@ -66,11 +155,25 @@ func init() {
 		}
 
 		if all || enableView {
-			_ = browser.OpenURL("http://" + serverAddr)
+			scheme := "http://"
+			if tlsConfig.Enabled() {
+				scheme = "https://"
+			}
+			_ = browser.OpenURL(scheme + serverAddr)
 		}
 
-		select {}
+		<-stopped
+		return nil
+	}
+}
+
+// componentNumbers converts component field numbers given as flags.
+func componentNumbers(ints []int) []uint32 {
+	var out []uint32
+	for _, n := range ints {
+		out = append(out, uint32(n))
 	}
+	return out
 }
 
 // runPluginSubprocess runs a plugin as a child process using
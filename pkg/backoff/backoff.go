@@ -0,0 +1,73 @@
+// Package backoff computes the delays of reconnect and retry loops.
+package backoff
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Defaults used when Min or Max is zero.
+const (
+	DefaultMin = time.Second
+	DefaultMax = 30 * time.Second
+)
+
+// Backoff is capped exponential backoff with full jitter, so clients
+// dropped by the same failure don't all come back at once. The n-th delay
+// (from zero) is uniform in [0, min(Max, Min*2^n)]. The zero value uses
+// DefaultMin and DefaultMax. It is not safe for concurrent use.
+type Backoff struct {
+	Min, Max time.Duration
+	// Rand returns a uniform value in [0, n); rand.Int64N when nil.
+	Rand func(n int64) int64
+
+	attempt int
+}
+
+// Ceiling returns the upper bound of the next delay.
+func (b *Backoff) Ceiling() time.Duration {
+	lo, hi := b.bounds()
+	ceil := lo
+	for i := 0; i < b.attempt && ceil < hi; i++ {
+		ceil *= 2
+	}
+	return min(ceil, hi)
+}
+
+// Next returns the delay before the next attempt and doubles the ceiling
+// of the one after it.
+func (b *Backoff) Next() time.Duration {
+	ceil := b.Ceiling()
+	b.attempt++
+	random := b.Rand
+	if random == nil {
+		random = rand.Int64N
+	}
+	return time.Duration(random(int64(ceil) + 1))
+}
+
+// Reset starts over at Min, e.g. once a connection has been up for a
+// while.
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
+
+// ResetIfHealthy resets b if the connection that just ended lasted longer
+// than Max, so that a long healthy connection does not inherit the delay
+// of failures before it.
+func (b *Backoff) ResetIfHealthy(started time.Time) {
+	if _, hi := b.bounds(); time.Since(started) > hi {
+		b.Reset()
+	}
+}
+
+func (b *Backoff) bounds() (lo, hi time.Duration) {
+	lo, hi = b.Min, b.Max
+	if lo <= 0 {
+		lo = DefaultMin
+	}
+	if hi <= 0 {
+		hi = DefaultMax
+	}
+	return lo, max(lo, hi)
+}
@@ -0,0 +1,107 @@
+package backoff
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+// maxJitter makes every delay its ceiling, so tests can assert on it.
+func maxJitter(n int64) int64 { return n - 1 }
+
+func TestBackoff_GrowsToCap(t *testing.T) {
+	b := Backoff{Min: time.Second, Max: 10 * time.Second, Rand: maxJitter}
+	want := []time.Duration{1, 2, 4, 8, 10, 10, 10}
+	for i, w := range want {
+		if got := b.Next(); got != w*time.Second {
+			t.Fatalf("delay %d = %v, want %v", i, got, w*time.Second)
+		}
+	}
+
+	b.Reset()
+	if got := b.Next(); got != time.Second {
+		t.Errorf("after reset %v, want %v", got, time.Second)
+	}
+}
+
+func TestBackoff_Defaults(t *testing.T) {
+	b := Backoff{Rand: maxJitter}
+	if got := b.Next(); got != DefaultMin {
+		t.Errorf("first delay %v, want %v", got, DefaultMin)
+	}
+	for range 20 {
+		b.Next()
+	}
+	if got := b.Next(); got != DefaultMax {
+		t.Errorf("capped delay %v, want %v", got, DefaultMax)
+	}
+}
+
+func TestBackoff_MaxBelowMin(t *testing.T) {
+	b := Backoff{Min: 5 * time.Second, Max: time.Second, Rand: maxJitter}
+	if got := b.Next(); got != 5*time.Second {
+		t.Errorf("delay %v, want Min when Max is below it", got)
+	}
+}
+
+func TestBackoff_ResetIfHealthy(t *testing.T) {
+	b := Backoff{Min: time.Second, Max: 4 * time.Second, Rand: maxJitter}
+	b.Next()
+	b.Next()
+
+	b.ResetIfHealthy(time.Now())
+	if got := b.Next(); got != 4*time.Second {
+		t.Errorf("short connection reset the backoff: %v", got)
+	}
+
+	b.ResetIfHealthy(time.Now().Add(-time.Minute))
+	if got := b.Next(); got != time.Second {
+		t.Errorf("long connection kept the backoff: %v", got)
+	}
+}
+
+func TestBackoff_JitterWithinBounds(t *testing.T) {
+	b := Backoff{Min: time.Second, Max: 30 * time.Second, Rand: rand.New(rand.NewPCG(1, 2)).Int64N}
+	for i := range 1000 {
+		ceil := b.Ceiling()
+		got := b.Next()
+		if got < 0 || got > ceil {
+			t.Fatalf("delay %d = %v, outside [0, %v]", i, got, ceil)
+		}
+	}
+}
+
+func TestBackoff_JitterSpreads(t *testing.T) {
+	// Many clients at the same attempt must not all wait the same time.
+	seen := make(map[time.Duration]bool)
+	src := rand.New(rand.NewPCG(3, 4))
+	for range 100 {
+		b := Backoff{Min: time.Second, Max: 30 * time.Second, Rand: src.Int64N}
+		for range 3 {
+			b.Next()
+		}
+		seen[b.Next()] = true
+	}
+	if len(seen) < 90 {
+		t.Errorf("only %d distinct delays out of 100", len(seen))
+	}
+}
+
+func TestBackoff_NoOverflow(t *testing.T) {
+	b := Backoff{Min: time.Second, Max: 30 * time.Second, Rand: maxJitter}
+	for range 200 {
+		b.Next()
+	}
+	if got := b.Next(); got != 30*time.Second {
+		t.Errorf("delay after many attempts = %v, want cap", got)
+	}
+}
+
+func TestBackoff_DefaultRandIsJittered(t *testing.T) {
+	b := Backoff{Min: time.Second, Max: time.Second}
+	for range 100 {
+		if got := b.Next(); got < 0 || got > time.Second {
+			t.Fatalf("delay %v outside [0, 1s]", got)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Package coords converts WGS84 geodetic positions to and from other
+// coordinate frames. Latitude and longitude stay authoritative on
+// entities; these frames are derived for consumers that want them.
+package coords
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// WGS84 ellipsoid.
+const (
+	wgs84A  = 6378137.0
+	wgs84F  = 1 / 298.257223563
+	wgs84B  = wgs84A * (1 - wgs84F)
+	wgs84E2 = wgs84F * (2 - wgs84F)
+)
+
+// Frame is a coordinate frame positions can be expressed in.
+type Frame int
+
+const (
+	// WGS84 is latitude, longitude and altitude, as entities carry them.
+	WGS84 Frame = iota
+	// ECEF is earth-centered, earth-fixed cartesian coordinates in meters.
+	ECEF
+	// UTM is universal transverse Mercator zone, easting and northing.
+	UTM
+)
+
+var frameNames = map[Frame]string{WGS84: "wgs84", ECEF: "ecef", UTM: "utm"}
+
+func (f Frame) String() string {
+	return frameNames[f]
+}
+
+// ParseFrame parses a frame name, case insensitive. An empty name is WGS84.
+func ParseFrame(name string) (Frame, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return WGS84, nil
+	}
+	for f, n := range frameNames {
+		if n == name {
+			return f, nil
+		}
+	}
+	return WGS84, fmt.Errorf("unknown coordinate frame %q", name)
+}
+
+// ToECEF converts a geodetic position in degrees and meters above the
+// ellipsoid to geocentric coordinates in meters.
+func ToECEF(lat, lon, alt float64) (x, y, z float64) {
+	sinLat, cosLat := math.Sincos(lat * math.Pi / 180)
+	sinLon, cosLon := math.Sincos(lon * math.Pi / 180)
+	n := wgs84A / math.Sqrt(1-wgs84E2*sinLat*sinLat)
+	x = (n + alt) * cosLat * cosLon
+	y = (n + alt) * cosLat * sinLon
+	z = (n*(1-wgs84E2) + alt) * sinLat
+	return x, y, z
+}
+
+// FromECEF converts geocentric coordinates to WGS84 latitude and longitude
+// in degrees and altitude above the ellipsoid in meters, using Bowring's
+// method, which is accurate to well under a millimeter near the surface.
+func FromECEF(x, y, z float64) (lat, lon, alt float64) {
+	const ep2 = (wgs84A*wgs84A - wgs84B*wgs84B) / (wgs84B * wgs84B)
+	p := math.Hypot(x, y)
+	lonRad := math.Atan2(y, x)
+	theta := math.Atan2(z*wgs84A, p*wgs84B)
+	sinT, cosT := math.Sincos(theta)
+	latRad := math.Atan2(z+ep2*wgs84B*sinT*sinT*sinT, p-wgs84E2*wgs84A*cosT*cosT*cosT)
+
+	sinLat, cosLat := math.Sincos(latRad)
+	n := wgs84A / math.Sqrt(1-wgs84E2*sinLat*sinLat)
+	if math.Abs(cosLat) < 1e-9 {
+		alt = math.Abs(z) - wgs84B
+	} else {
+		alt = p/cosLat - n
+	}
+	return latRad * 180 / math.Pi, lonRad * 180 / math.Pi, alt
+}
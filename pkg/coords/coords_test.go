@@ -0,0 +1,109 @@
+package coords
+
+import (
+	"math"
+	"testing"
+)
+
+func TestToECEF_KnownPoints(t *testing.T) {
+	tests := []struct {
+		lat, lon, alt float64
+		x, y, z       float64
+	}{
+		{0, 0, 0, 6378137, 0, 0},
+		{0, 90, 0, 0, 6378137, 0},
+		{90, 0, 0, 0, 0, 6356752.314245},
+		{0, 180, 1000, -6379137, 0, 0},
+	}
+	for _, tt := range tests {
+		x, y, z := ToECEF(tt.lat, tt.lon, tt.alt)
+		if math.Abs(x-tt.x) > 0.5 || math.Abs(y-tt.y) > 0.5 || math.Abs(z-tt.z) > 0.5 {
+			t.Errorf("ToECEF(%v, %v, %v) = %.1f %.1f %.1f, want %.1f %.1f %.1f", tt.lat, tt.lon, tt.alt, x, y, z, tt.x, tt.y, tt.z)
+		}
+	}
+}
+
+func TestECEF_RoundTrip(t *testing.T) {
+	for _, p := range [][3]float64{{52.52, 13.405, 34}, {-33.86, 151.21, 0}, {89.9, -45, 10000}, {-12, -77, -50}} {
+		lat, lon, alt := FromECEF(ToECEF(p[0], p[1], p[2]))
+		if math.Abs(lat-p[0]) > 1e-9 || math.Abs(lon-p[1]) > 1e-9 || math.Abs(alt-p[2]) > 1e-3 {
+			t.Errorf("%v round trips to %v %v %v", p, lat, lon, alt)
+		}
+	}
+}
+
+func TestToUTM_KnownPoints(t *testing.T) {
+	// On the central meridian, the easting is the false easting and the
+	// northing the meridian arc, 4984944.378 m to 45°, scaled by 0.9996.
+	u, err := ToUTM(45, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Zone != 32 || !u.North || math.Abs(u.Easting-500000) > 1e-6 || math.Abs(u.Northing-4982950.400) > 0.01 {
+		t.Errorf("ToUTM(45, 9) = %+v", u)
+	}
+
+	u, _ = ToUTM(-45, 9)
+	if u.North || math.Abs(u.Northing-(10000000-4982950.400)) > 0.01 {
+		t.Errorf("ToUTM(-45, 9) = %+v", u)
+	}
+
+	u, _ = ToUTM(0, 3)
+	if u.Zone != 31 || math.Abs(u.Easting-500000) > 1e-6 || math.Abs(u.Northing) > 1e-6 {
+		t.Errorf("ToUTM(0, 3) = %+v", u)
+	}
+
+	// East and west of the central meridian mirror each other.
+	east, _ := ToUTM(48, 11)
+	west, _ := ToUTM(48, 7)
+	if math.Abs((east.Easting-500000)+(west.Easting-500000)) > 1e-6 || math.Abs(east.Northing-west.Northing) > 1e-6 {
+		t.Errorf("asymmetric: %+v %+v", east, west)
+	}
+
+	if _, err := ToUTM(85, 0); err == nil {
+		t.Error("expected an error north of 84°")
+	}
+}
+
+func TestUTMZone_Exceptions(t *testing.T) {
+	tests := []struct {
+		lat, lon float64
+		zone     int
+	}{
+		{52.52, 13.405, 33},
+		{40.71, -74.0, 18},
+		{60, 5, 32},  // Bergen, southern Norway
+		{78, 15, 33}, // Svalbard
+		{0, 180, 60},
+		{0, -180, 1},
+	}
+	for _, tt := range tests {
+		if got := UTMZone(tt.lat, tt.lon); got != tt.zone {
+			t.Errorf("UTMZone(%v, %v) = %d, want %d", tt.lat, tt.lon, got, tt.zone)
+		}
+	}
+}
+
+func TestUTM_RoundTrip(t *testing.T) {
+	for _, p := range [][2]float64{{52.52, 13.405}, {-33.86, 151.21}, {60.39, 5.32}, {78.22, 15.65}, {-79.9, -179.9}, {83.9, 40}} {
+		u, err := ToUTM(p[0], p[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		lat, lon := u.LatLon()
+		if math.Abs(lat-p[0]) > 1e-8 || math.Abs(lon-p[1]) > 1e-8 {
+			t.Errorf("%v round trips via %v to %v %v", p, u, lat, lon)
+		}
+	}
+}
+
+func TestParseFrame(t *testing.T) {
+	for name, want := range map[string]Frame{"": WGS84, "ECEF": ECEF, " utm ": UTM, "wgs84": WGS84} {
+		if got, err := ParseFrame(name); err != nil || got != want {
+			t.Errorf("ParseFrame(%q) = %v, %v", name, got, err)
+		}
+	}
+	if _, err := ParseFrame("mgrs"); err == nil {
+		t.Error("expected error for unknown frame")
+	}
+}
@@ -0,0 +1,146 @@
+package coords
+
+import (
+	"fmt"
+	"math"
+)
+
+// UTMPosition is a position in the universal transverse Mercator system.
+type UTMPosition struct {
+	Zone     int     `json:"zone"`
+	North    bool    `json:"north"`
+	Easting  float64 `json:"easting"`
+	Northing float64 `json:"northing"`
+}
+
+func (u UTMPosition) String() string {
+	hemisphere := "S"
+	if u.North {
+		hemisphere = "N"
+	}
+	return fmt.Sprintf("%d%s %.0f %.0f", u.Zone, hemisphere, u.Easting, u.Northing)
+}
+
+const (
+	utmScale         = 0.9996
+	utmFalseEasting  = 500000.0
+	utmFalseNorthing = 10000000.0
+)
+
+// Coefficients of Krüger's series to third order in the third flattening,
+// which is accurate to well under a millimeter within a zone.
+var (
+	utmN      = wgs84F / (2 - wgs84F)
+	utmRadius = wgs84A / (1 + utmN) * (1 + utmN*utmN/4 + utmN*utmN*utmN*utmN/64)
+	utmAlpha  = [3]float64{
+		utmN/2 - 2*utmN*utmN/3 + 5*utmN*utmN*utmN/16,
+		13*utmN*utmN/48 - 3*utmN*utmN*utmN/5,
+		61 * utmN * utmN * utmN / 240,
+	}
+	utmBeta = [3]float64{
+		utmN/2 - 2*utmN*utmN/3 + 37*utmN*utmN*utmN/96,
+		utmN*utmN/48 + utmN*utmN*utmN/15,
+		17 * utmN * utmN * utmN / 480,
+	}
+	utmDelta = [3]float64{
+		2*utmN - 2*utmN*utmN/3 - 2*utmN*utmN*utmN,
+		7*utmN*utmN/3 - 8*utmN*utmN*utmN/5,
+		56 * utmN * utmN * utmN / 15,
+	}
+)
+
+// UTMZone returns the zone of a position, with the exceptions for
+// southern Norway and Svalbard.
+func UTMZone(lat, lon float64) int {
+	if lon < -180 || lon > 180 {
+		lon = math.Remainder(lon, 360)
+	}
+	zone := int(math.Floor((lon+180)/6)) + 1
+	if zone > 60 {
+		zone = 60
+	}
+
+	switch {
+	case lat >= 56 && lat < 64 && lon >= 3 && lon < 12:
+		zone = 32
+	case lat >= 72 && lat < 84 && lon >= 0 && lon < 42:
+		switch {
+		case lon < 9:
+			zone = 31
+		case lon < 21:
+			zone = 33
+		case lon < 33:
+			zone = 35
+		default:
+			zone = 37
+		}
+	}
+	return zone
+}
+
+// ToUTM converts a WGS84 position in degrees to UTM in its zone. UTM is
+// defined between 80°S and 84°N.
+func ToUTM(lat, lon float64) (UTMPosition, error) {
+	if lat < -80 || lat > 84 {
+		return UTMPosition{}, fmt.Errorf("latitude %.6f is outside UTM", lat)
+	}
+	zone := UTMZone(lat, lon)
+	easting, northing := utmForward(lat, lon, zone)
+	u := UTMPosition{Zone: zone, North: lat >= 0, Easting: easting, Northing: northing}
+	if !u.North {
+		u.Northing += utmFalseNorthing
+	}
+	return u, nil
+}
+
+// LatLon converts u back to WGS84 latitude and longitude in degrees.
+func (u UTMPosition) LatLon() (lat, lon float64) {
+	northing := u.Northing
+	if !u.North {
+		northing -= utmFalseNorthing
+	}
+	xi := northing / (utmScale * utmRadius)
+	eta := (u.Easting - utmFalseEasting) / (utmScale * utmRadius)
+
+	xiP, etaP := xi, eta
+	for j, b := range utmBeta {
+		k := 2 * float64(j+1)
+		xiP -= b * math.Sin(k*xi) * math.Cosh(k*eta)
+		etaP -= b * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+
+	chi := math.Asin(math.Sin(xiP) / math.Cosh(etaP))
+	phi := chi
+	for j, d := range utmDelta {
+		phi += d * math.Sin(2*float64(j+1)*chi)
+	}
+	lambda := math.Atan2(math.Sinh(etaP), math.Cos(xiP))
+
+	return phi * 180 / math.Pi, centralMeridian(u.Zone) + lambda*180/math.Pi
+}
+
+// centralMeridian returns the longitude in degrees of the center of zone.
+func centralMeridian(zone int) float64 {
+	return float64(zone)*6 - 183
+}
+
+// utmForward projects a position onto zone, returning the easting and the
+// northing from the equator.
+func utmForward(lat, lon float64, zone int) (easting, northing float64) {
+	phi := lat * math.Pi / 180
+	dLambda := (lon - centralMeridian(zone)) * math.Pi / 180
+	dLambda = math.Remainder(dLambda, 2*math.Pi)
+
+	c := 2 * math.Sqrt(utmN) / (1 + utmN)
+	t := math.Sinh(math.Atanh(math.Sin(phi)) - c*math.Atanh(c*math.Sin(phi)))
+	xiP := math.Atan2(t, math.Cos(dLambda))
+	etaP := math.Atanh(math.Sin(dLambda) / math.Sqrt(1+t*t))
+
+	xi, eta := xiP, etaP
+	for j, a := range utmAlpha {
+		k := 2 * float64(j+1)
+		xi += a * math.Sin(k*xiP) * math.Cosh(k*etaP)
+		eta += a * math.Cos(k*xiP) * math.Sinh(k*etaP)
+	}
+	return utmFalseEasting + utmScale*utmRadius*eta, utmScale * utmRadius * xi
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/projectqai/hydris/pkg/symbol"
 	pb "github.com/projectqai/proto/go"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -19,6 +20,7 @@ type Event struct {
 	Version string   `xml:"version,attr"`
 	Type    string   `xml:"type,attr"`
 	How     string   `xml:"how,attr"`
+	Access  string   `xml:"access,attr,omitempty"`
 	UID     string   `xml:"uid,attr"`
 	Time    string   `xml:"time,attr"`
 	Start   string   `xml:"start,attr"`
@@ -47,6 +49,8 @@ type Detail struct {
 	StrokeColor  *ColorAttr   `xml:"strokeColor,omitempty"`
 	FillColor    *ColorAttr   `xml:"fillColor,omitempty"`
 	StrokeWeight *WeightAttr  `xml:"strokeWeight,omitempty"`
+	Emergency    *Emergency   `xml:"emergency,omitempty"`
+	Fileshare    *Fileshare   `xml:"fileshare,omitempty"`
 }
 
 type ChatDetail struct {
@@ -110,11 +114,36 @@ type Milsym struct {
 	ID string `xml:"id,attr"`
 }
 
+// How values of events Hydris sends: entities a person placed, and
+// entities generated by a controller such as a sensor feed.
+const (
+	HowHuman   = "h-g-i-g-o"
+	HowMachine = "m-g"
+)
+
+// Marking holds the event attributes an entity has no component for: how
+// the position was obtained and the access (classification marking) of
+// the event. Keep the one CoTToEntityWithMarking returns and pass it back
+// in Options to send the entity with the attributes it came with. An
+// entity sent without its Marking goes out with the default how and no
+// access attribute, i.e. unmarked, whatever its classification was.
+type Marking struct {
+	How    string
+	Access string
+}
+
 // CoTToEntity converts a CoT XML event to a Hydris entity
 func CoTToEntity(cotXML []byte, controllerName string, trackerID string) (*pb.Entity, error) {
+	entity, _, err := CoTToEntityWithMarking(cotXML, controllerName, trackerID)
+	return entity, err
+}
+
+// CoTToEntityWithMarking is CoTToEntity that also returns the how and
+// access attributes of the event.
+func CoTToEntityWithMarking(cotXML []byte, controllerName string, trackerID string) (*pb.Entity, Marking, error) {
 	var event Event
 	if err := xml.Unmarshal(cotXML, &event); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal CoT XML: %w", err)
+		return nil, Marking{}, fmt.Errorf("failed to unmarshal CoT XML: %w", err)
 	}
 
 	// Get callsign from contact detail
@@ -146,7 +175,7 @@ func CoTToEntity(cotXML []byte, controllerName string, trackerID string) (*pb.En
 		},
 	}
 
-	return entity, nil
+	return entity, Marking{How: event.How, Access: event.Access}, nil
 }
 
 func cotTypeToSIDC(cotType string) string {
@@ -193,18 +222,42 @@ func cotTypeToSIDC(cotType string) string {
 	return fmt.Sprintf("S%s%sP----------*", affiliation, dimension)
 }
 
+// DefaultStale is how long TAK clients keep an entity without an end of
+// lifetime unless Options.Stale says otherwise.
+const DefaultStale = 10 * 365 * 24 * time.Hour
+
+// Options tunes how entities are rendered as CoT.
+type Options struct {
+	// Symbol2525D puts the MIL-STD-2525D form of the entity's symbol into
+	// the __milsym detail instead of the 2525C code. The event type is
+	// derived from the 2525C code either way.
+	Symbol2525D bool
+	// Stale is how long after now an entity without Lifetime.Until goes
+	// stale; 0 means DefaultStale. Entities with Until go stale then, so
+	// TAK drops them when Hydris expires them.
+	Stale time.Duration
+	// Marking is the how and access of the event the entity came from.
+	// Without a How, it is HowMachine for entities with a controller and
+	// HowHuman for the others; without an Access, none is sent.
+	Marking Marking
+}
+
 // EntityToCoT converts a Hydris entity to a CoT XML event.
 func EntityToCoT(entity *pb.Entity) ([]byte, error) {
-	expired := entity.Lifetime != nil && entity.Lifetime.Until != nil &&
-		!entity.Lifetime.Until.AsTime().After(time.Now())
+	return EntityToCoTWithOptions(entity, Options{})
+}
 
-	if entity.Geo == nil && !expired {
-		return nil, nil
+// EntityToCoTWithOptions is EntityToCoT with opts applied. An entity whose
+// lifetime already ended is rendered as EntityDeleteCoT.
+func EntityToCoTWithOptions(entity *pb.Entity, opts Options) ([]byte, error) {
+	now := time.Now().UTC()
+	if until := entity.GetLifetime().GetUntil(); until != nil && !until.AsTime().After(now) {
+		return EntityDeleteCoT(entity)
 	}
 
 	geo := entity.Geo
 	if geo == nil {
-		geo = &pb.GeoSpatialComponent{}
+		return nil, nil
 	}
 
 	callsign := entity.Id
@@ -218,11 +271,21 @@ func EntityToCoT(entity *pb.Entity) ([]byte, error) {
 		sidc := entity.Symbol.GetMilStd2525C()
 		cotType = sidcToCoTType(sidc)
 		milsym = &Milsym{ID: padSIDC(sidc)}
+		if opts.Symbol2525D {
+			// Keep the 2525C code when there is no 2525D equivalent,
+			// e.g. for tactical graphics.
+			if d, err := symbol.To2525D(sidc); err == nil {
+				milsym.ID = d
+			}
+		}
 	}
 
-	now := time.Now().UTC()
 	startTime := now
-	staleTime := now.Add(10 * 365 * 24 * time.Hour).Format(time.RFC3339)
+	stale := opts.Stale
+	if stale <= 0 {
+		stale = DefaultStale
+	}
+	staleTime := now.Add(stale).Format(time.RFC3339)
 
 	if entity.Lifetime != nil {
 		if entity.Lifetime.From != nil {
@@ -233,20 +296,24 @@ func EntityToCoT(entity *pb.Entity) ([]byte, error) {
 		}
 	}
 
-	if expired {
-		startTime = now
-		staleTime = now.Add(-time.Minute).Format(time.RFC3339)
-	}
-
 	altitude := 0.0
 	if geo.Altitude != nil {
 		altitude = *geo.Altitude
 	}
 
+	how := opts.Marking.How
+	if how == "" {
+		how = HowHuman
+		if entity.GetController().GetId() != "" {
+			how = HowMachine
+		}
+	}
+
 	event := Event{
 		Version: "2.0",
 		Type:    cotType,
-		How:     "h-g-i-g-o",
+		How:     how,
+		Access:  opts.Marking.Access,
 		UID:     entity.Id,
 		Time:    now.Format(time.RFC3339),
 		Start:   startTime.Format(time.RFC3339),
@@ -284,7 +351,7 @@ func EntityDeleteCoT(entity *pb.Entity) ([]byte, error) {
 	event := Event{
 		Version: "2.0",
 		Type:    "t-x-d-d",
-		How:     "h-g-i-g-o",
+		How:     HowHuman,
 		UID:     entity.Id + "-delete",
 		Time:    now,
 		Start:   now,
@@ -502,7 +569,7 @@ func EntityToChatCoT(entity *pb.Entity) ([]byte, error) {
 	event := Event{
 		Version: "2.0",
 		Type:    "b-t-f",
-		How:     "h-g-i-g-o",
+		How:     HowHuman,
 		UID:     entity.Id,
 		Time:    now.Format(time.RFC3339),
 		Start:   startTime.Format(time.RFC3339),
@@ -601,7 +668,7 @@ func marshalCircleCoT(uid, callsign string, now, stale time.Time, circle *pb.Pla
 	event := Event{
 		Version: "2.0",
 		Type:    "u-d-c-c",
-		How:     "h-g-i-g-o",
+		How:     HowHuman,
 		UID:     uid,
 		Time:    now.Format(time.RFC3339),
 		Start:   now.Format(time.RFC3339),
@@ -670,7 +737,7 @@ func marshalPolygonCoT(uid, callsign string, now, stale time.Time, ring *pb.Plan
 	event := Event{
 		Version: "2.0",
 		Type:    cotType,
-		How:     "h-g-i-g-o",
+		How:     HowHuman,
 		UID:     uid,
 		Time:    now.Format(time.RFC3339),
 		Start:   now.Format(time.RFC3339),
@@ -0,0 +1,122 @@
+package cot
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func renderEvent(t *testing.T, entity *pb.Entity, opts Options) Event {
+	t.Helper()
+	out, err := EntityToCoTWithOptions(entity, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var event Event
+	if err := xml.Unmarshal(out, &event); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	return event
+}
+
+func parseStale(t *testing.T, event Event) time.Time {
+	t.Helper()
+	stale, err := time.Parse(time.RFC3339, event.Stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stale
+}
+
+func TestEntityToCoT_StaleFromUntil(t *testing.T) {
+	until := time.Now().Add(5 * time.Minute).Truncate(time.Second).UTC()
+	event := renderEvent(t, &pb.Entity{
+		Id:       "adsb.3c6444",
+		Geo:      &pb.GeoSpatialComponent{Latitude: 48.1, Longitude: 11.5},
+		Lifetime: &pb.Lifetime{Until: timestamppb.New(until)},
+	}, Options{Stale: time.Minute})
+
+	if got := parseStale(t, event); !got.Equal(until) {
+		t.Errorf("stale %v, want Until %v", got, until)
+	}
+}
+
+func TestEntityToCoT_DefaultStale(t *testing.T) {
+	entity := &pb.Entity{Id: "base.home", Geo: &pb.GeoSpatialComponent{Latitude: 48.1, Longitude: 11.5}}
+
+	for _, tc := range []struct {
+		name  string
+		opts  Options
+		stale time.Duration
+	}{
+		{"configured", Options{Stale: 2 * time.Minute}, 2 * time.Minute},
+		{"unset", Options{}, DefaultStale},
+	} {
+		before := time.Now().Truncate(time.Second)
+		got := parseStale(t, renderEvent(t, entity, tc.opts))
+		after := time.Now()
+		if got.Before(before.Add(tc.stale)) || got.After(after.Add(tc.stale)) {
+			t.Errorf("%s: stale %v, want about %v from now", tc.name, got, tc.stale)
+		}
+	}
+}
+
+func TestEntityToCoT_ExpiredDeletes(t *testing.T) {
+	for _, geo := range []*pb.GeoSpatialComponent{{Latitude: 48.1, Longitude: 11.5}, nil} {
+		event := renderEvent(t, &pb.Entity{
+			Id:       "adsb.3c6444",
+			Geo:      geo,
+			Lifetime: &pb.Lifetime{Until: timestamppb.New(time.Now().Add(-time.Second))},
+		}, Options{})
+
+		if event.Type != "t-x-d-d" || event.Detail.ForceDelete == nil {
+			t.Errorf("expired entity rendered as %q, forcedelete %v; want a delete", event.Type, event.Detail.ForceDelete != nil)
+		}
+		if len(event.Detail.Links) != 1 || event.Detail.Links[0].UID != "adsb.3c6444" {
+			t.Errorf("delete links %+v, want adsb.3c6444", event.Detail.Links)
+		}
+	}
+}
+
+const classifiedEvent = `<event version="2.0" uid="ANDROID-1234" type="a-f-G-U-C" how="h-e" access="SECRET//REL TO USA, GBR" time="2026-01-01T12:00:00Z" start="2026-01-01T12:00:00Z" stale="2026-01-01T12:10:00Z">
+  <point lat="48.1" lon="11.5" hae="520" ce="10" le="10"/>
+  <detail>
+    <contact callsign="VIPER"/>
+  </detail>
+</event>`
+
+func TestCoTMarking_RoundTrip(t *testing.T) {
+	entity, marking, err := CoTToEntityWithMarking([]byte(classifiedEvent), "tak", "tak.server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if marking.How != "h-e" || marking.Access != "SECRET//REL TO USA, GBR" {
+		t.Fatalf("marking %+v", marking)
+	}
+
+	event := renderEvent(t, entity, Options{Marking: marking})
+	if event.Access != marking.Access {
+		t.Errorf("access %q, want %q", event.Access, marking.Access)
+	}
+	if event.How != "h-e" {
+		t.Errorf("how %q, want h-e", event.How)
+	}
+}
+
+func TestEntityToCoT_HowFromController(t *testing.T) {
+	geo := &pb.GeoSpatialComponent{Latitude: 48.1, Longitude: 11.5}
+
+	placed := renderEvent(t, &pb.Entity{Id: "base.home", Geo: geo}, Options{})
+	if placed.How != HowHuman || placed.Access != "" {
+		t.Errorf("entity without controller sent with how %q access %q", placed.How, placed.Access)
+	}
+
+	controller := "adsb"
+	feed := renderEvent(t, &pb.Entity{Id: "adsb.3c6444", Geo: geo, Controller: &pb.Controller{Id: &controller}}, Options{})
+	if feed.How != HowMachine {
+		t.Errorf("controlled entity sent with how %q, want %q", feed.How, HowMachine)
+	}
+}
@@ -0,0 +1,177 @@
+package cot
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ATAK emergency event types. An alert is cancelled by a cancel event with
+// the alert's uid.
+const (
+	EmergencyAlertType  = "b-a-o-tbl" // 911 Alert
+	EmergencyCancelType = "b-a-o-can"
+
+	emergencyTypePrefix = "b-a-o-"
+)
+
+// Emergency is the <emergency> detail of an ATAK alert or its cancellation.
+type Emergency struct {
+	XMLName xml.Name `xml:"emergency"`
+	Type    string   `xml:"type,attr,omitempty"`
+	Cancel  bool     `xml:"cancel,attr,omitempty"`
+	Text    string   `xml:",chardata"`
+}
+
+// IsEmergencyCoT returns true if the CoT XML data is an emergency alert or
+// cancellation.
+func IsEmergencyCoT(data string) bool {
+	return strings.Contains(data, `type="`+emergencyTypePrefix)
+}
+
+// CoTEmergencyToEntity converts an emergency CoT XML event to a Hydris
+// entity with NavigationComponent.Emergency set. For a cancellation it
+// returns cancel true and an entity carrying only the id of the alert to
+// expire.
+func CoTEmergencyToEntity(cotXML []byte, controllerName string, trackerID string) (entity *pb.Entity, cancel bool, err error) {
+	var event Event
+	if err := xml.Unmarshal(cotXML, &event); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal CoT XML: %w", err)
+	}
+	if !strings.HasPrefix(event.Type, emergencyTypePrefix) {
+		return nil, false, fmt.Errorf("not an emergency event: %s", event.Type)
+	}
+
+	if event.Type == EmergencyCancelType || (event.Detail.Emergency != nil && event.Detail.Emergency.Cancel) {
+		return &pb.Entity{Id: event.UID}, true, nil
+	}
+
+	callsign := event.Detail.Contact.Callsign
+	if e := event.Detail.Emergency; e != nil && e.Text != "" {
+		callsign = e.Text
+	}
+	if callsign == "" {
+		callsign = event.UID
+	}
+
+	now := time.Now()
+	fromTime := now
+	if t, err := time.Parse(time.RFC3339, event.Time); err == nil {
+		fromTime = t
+	}
+
+	hae := event.Point.Hae
+	entity = &pb.Entity{
+		Id:    event.UID,
+		Label: &callsign,
+		Geo: &pb.GeoSpatialComponent{
+			Latitude:  event.Point.Lat,
+			Longitude: event.Point.Lon,
+			Altitude:  &hae,
+		},
+		Navigation: &pb.NavigationComponent{
+			Emergency: proto.Bool(true),
+		},
+		Controller: &pb.Controller{
+			Id:     &controllerName,
+			Origin: &trackerID,
+		},
+		Track: &pb.TrackComponent{
+			Tracker: &trackerID,
+		},
+		Lifetime: &pb.Lifetime{
+			From:  timestamppb.New(fromTime),
+			Fresh: timestamppb.New(now),
+		},
+	}
+	// An alert stays until it is cancelled unless the sender set a stale
+	// time.
+	if t, err := time.Parse(time.RFC3339, event.Stale); err == nil && t.After(now) {
+		entity.Lifetime.Until = timestamppb.New(t)
+	}
+	return entity, false, nil
+}
+
+// EntityToEmergencyCoT converts a Hydris entity with
+// NavigationComponent.Emergency set to a 911 alert CoT XML event.
+func EntityToEmergencyCoT(entity *pb.Entity) ([]byte, error) {
+	if !entity.GetNavigation().GetEmergency() || entity.Geo == nil {
+		return nil, nil
+	}
+
+	callsign := entity.Id
+	if entity.Label != nil && *entity.Label != "" {
+		callsign = *entity.Label
+	}
+
+	now := time.Now().UTC()
+	staleTime := now.Add(10 * 365 * 24 * time.Hour)
+	if entity.Lifetime != nil && entity.Lifetime.Until != nil {
+		staleTime = entity.Lifetime.Until.AsTime()
+	}
+
+	altitude := 0.0
+	if entity.Geo.Altitude != nil {
+		altitude = *entity.Geo.Altitude
+	}
+
+	event := Event{
+		Version: "2.0",
+		Type:    EmergencyAlertType,
+		How:     "h-e",
+		UID:     entity.Id,
+		Time:    now.Format(time.RFC3339),
+		Start:   now.Format(time.RFC3339),
+		Stale:   staleTime.Format(time.RFC3339),
+		Point: Point{
+			Lat: entity.Geo.Latitude,
+			Lon: entity.Geo.Longitude,
+			Hae: altitude,
+			CE:  9999999.0,
+			LE:  9999999.0,
+		},
+		Detail: Detail{
+			Contact:   Contact{Callsign: callsign},
+			Group:     Group{Name: "Hydris", Role: "Entity"},
+			Emergency: &Emergency{Type: "911 Alert", Text: callsign},
+		},
+	}
+	return marshalEvent(event)
+}
+
+// EntityEmergencyCancelCoT generates a cancel event for the emergency alert
+// of entity, so TAK clients clear it.
+func EntityEmergencyCancelCoT(entity *pb.Entity) ([]byte, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	event := Event{
+		Version: "2.0",
+		Type:    EmergencyCancelType,
+		How:     "h-e",
+		UID:     entity.Id,
+		Time:    now,
+		Start:   now,
+		Stale:   now,
+		Point: Point{
+			CE: 9999999.0,
+			LE: 9999999.0,
+		},
+		Detail: Detail{
+			Emergency: &Emergency{Cancel: true, Text: entity.GetLabel()},
+		},
+	}
+	return marshalEvent(event)
+}
+
+func marshalEvent(event Event) ([]byte, error) {
+	xmlData, err := xml.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal XML: %w", err)
+	}
+	return append(xmlData, '\n'), nil
+}
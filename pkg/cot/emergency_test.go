@@ -0,0 +1,103 @@
+package cot
+
+import (
+	"encoding/xml"
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+const emergencyAlert = `<event version="2.0" uid="ANDROID-1234-9-1-1" type="b-a-o-tbl" how="h-e" time="2026-01-01T12:00:00Z" start="2026-01-01T12:00:00Z" stale="2099-01-01T12:00:00Z">
+  <point lat="48.1" lon="11.5" hae="520" ce="10" le="10"/>
+  <detail>
+    <link uid="ANDROID-1234" type="a-f-G-U-C" relation="p-p"/>
+    <contact callsign="VIPER"/>
+    <emergency type="911 Alert">VIPER-Alert</emergency>
+  </detail>
+</event>`
+
+const emergencyCancel = `<event version="2.0" uid="ANDROID-1234-9-1-1" type="b-a-o-can" how="h-e" time="2026-01-01T12:05:00Z" start="2026-01-01T12:05:00Z" stale="2026-01-01T12:05:10Z">
+  <point lat="48.1" lon="11.5" hae="520" ce="10" le="10"/>
+  <detail>
+    <emergency cancel="true">VIPER</emergency>
+  </detail>
+</event>`
+
+func TestCoTEmergencyToEntity(t *testing.T) {
+	if !IsEmergencyCoT(emergencyAlert) || !IsEmergencyCoT(emergencyCancel) {
+		t.Fatal("emergency events not detected")
+	}
+
+	entity, cancel, err := CoTEmergencyToEntity([]byte(emergencyAlert), "tak", "tak.server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancel {
+		t.Fatal("alert parsed as cancellation")
+	}
+	if entity.Id != "ANDROID-1234-9-1-1" || entity.GetLabel() != "VIPER-Alert" {
+		t.Errorf("got id %q label %q", entity.Id, entity.GetLabel())
+	}
+	if !entity.GetNavigation().GetEmergency() {
+		t.Error("emergency not set")
+	}
+	if entity.Geo.GetLatitude() != 48.1 || entity.Geo.GetLongitude() != 11.5 {
+		t.Errorf("position %v", entity.Geo)
+	}
+
+	entity, cancel, err = CoTEmergencyToEntity([]byte(emergencyCancel), "tak", "tak.server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cancel || entity.Id != "ANDROID-1234-9-1-1" {
+		t.Errorf("got cancel %v for %q, want cancellation of the alert", cancel, entity.Id)
+	}
+
+	if _, _, err := CoTEmergencyToEntity([]byte(`<event type="a-f-G" uid="x"/>`), "tak", "tak.server"); err == nil {
+		t.Error("expected error for a non-emergency event")
+	}
+}
+
+func TestEntityToEmergencyCoT(t *testing.T) {
+	entity := &pb.Entity{
+		Id:         "mavlink.drone1",
+		Label:      proto.String("Drone 1"),
+		Geo:        &pb.GeoSpatialComponent{Latitude: 48.1, Longitude: 11.5},
+		Navigation: &pb.NavigationComponent{Emergency: proto.Bool(true)},
+	}
+	data, err := EntityToEmergencyCoT(entity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var event Event
+	if err := xml.Unmarshal(data, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != EmergencyAlertType || event.UID != "mavlink.drone1" {
+		t.Errorf("got type %q uid %q", event.Type, event.UID)
+	}
+	if event.Detail.Emergency == nil || event.Detail.Emergency.Type != "911 Alert" {
+		t.Errorf("emergency detail %+v", event.Detail.Emergency)
+	}
+	if event.Point.Lat != 48.1 {
+		t.Errorf("lat %v", event.Point.Lat)
+	}
+
+	data, err = EntityEmergencyCancelCoT(entity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := xml.Unmarshal(data, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != EmergencyCancelType || event.Detail.Emergency == nil || !event.Detail.Emergency.Cancel {
+		t.Errorf("got %q %+v, want a cancellation", event.Type, event.Detail.Emergency)
+	}
+
+	entity.Navigation.Emergency = proto.Bool(false)
+	if data, _ := EntityToEmergencyCoT(entity); data != nil {
+		t.Error("entity without emergency exported as alert")
+	}
+}
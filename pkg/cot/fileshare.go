@@ -0,0 +1,167 @@
+package cot
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FileshareType is the CoT type of an ATAK file transfer request, sent when
+// a user shares a file or data package. The receiver downloads the file
+// from SenderURL itself.
+const FileshareType = "b-f-t-r"
+
+// Fileshare is the <fileshare> detail of a file transfer request.
+type Fileshare struct {
+	XMLName        xml.Name `xml:"fileshare"`
+	Filename       string   `xml:"filename,attr"`
+	Name           string   `xml:"name,attr,omitempty"`
+	SenderURL      string   `xml:"senderUrl,attr"`
+	SizeInBytes    int64    `xml:"sizeInBytes,attr,omitempty"`
+	SHA256         string   `xml:"sha256,attr,omitempty"`
+	SenderUID      string   `xml:"senderUid,attr,omitempty"`
+	SenderCallsign string   `xml:"senderCallsign,attr,omitempty"`
+}
+
+// IsFileshareCoT returns true if the CoT XML data is a file transfer
+// request.
+func IsFileshareCoT(data string) bool {
+	return strings.Contains(data, `type="`+FileshareType+`"`)
+}
+
+// CoTFileshareToEntity converts a file transfer request to a Hydris chat
+// entity announcing the file. The file itself is not fetched; the message
+// carries its name, size, hash and URL, and the returned Fileshare lets a
+// caller re-emit the request with EntityToFileshareCoT.
+func CoTFileshareToEntity(cotXML []byte, controllerName string, trackerID string) (*pb.Entity, *Fileshare, error) {
+	var event Event
+	if err := xml.Unmarshal(cotXML, &event); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal CoT XML: %w", err)
+	}
+	fs := event.Detail.Fileshare
+	if event.Type != FileshareType || fs == nil {
+		return nil, nil, fmt.Errorf("not a file transfer request: %s", event.Type)
+	}
+	if fs.SenderURL == "" {
+		return nil, nil, fmt.Errorf("file transfer request %s has no sender URL", event.UID)
+	}
+
+	label := fs.Name
+	if label == "" {
+		label = fs.Filename
+	}
+
+	var sender *string
+	if fs.SenderUID != "" {
+		s := "tak." + fs.SenderUID
+		sender = &s
+	}
+
+	now := time.Now()
+	fromTime := now
+	if t, err := time.Parse(time.RFC3339, event.Time); err == nil {
+		fromTime = t
+	}
+	untilTime := fromTime.Add(3 * time.Hour)
+	if t, err := time.Parse(time.RFC3339, event.Stale); err == nil && t.After(fromTime) {
+		untilTime = t
+	}
+
+	entity := &pb.Entity{
+		Id:    event.UID,
+		Label: &label,
+		Controller: &pb.Controller{
+			Id:     &controllerName,
+			Origin: &trackerID,
+		},
+		Track: &pb.TrackComponent{
+			Tracker: &trackerID,
+		},
+		Chat: &pb.ChatComponent{
+			Sender:  sender,
+			Message: fileshareMessage(fs),
+		},
+		Lifetime: &pb.Lifetime{
+			From:  timestamppb.New(fromTime),
+			Until: timestamppb.New(untilTime),
+			Fresh: timestamppb.New(now),
+		},
+	}
+	// ATAK sends file transfers without a position.
+	if event.Point.Lat != 0 || event.Point.Lon != 0 {
+		hae := event.Point.Hae
+		entity.Geo = &pb.GeoSpatialComponent{
+			Latitude:  event.Point.Lat,
+			Longitude: event.Point.Lon,
+			Altitude:  &hae,
+		}
+	}
+	return entity, fs, nil
+}
+
+// fileshareMessage describes a shared file for chat clients.
+func fileshareMessage(fs *Fileshare) string {
+	var b strings.Builder
+	who := fs.SenderCallsign
+	if who == "" {
+		who = "Someone"
+	}
+	fmt.Fprintf(&b, "%s shared %s", who, fs.Filename)
+	if fs.Name != "" && fs.Name != fs.Filename {
+		fmt.Fprintf(&b, " (%s)", fs.Name)
+	}
+	if fs.SizeInBytes > 0 {
+		b.WriteString(", " + strconv.FormatInt(fs.SizeInBytes, 10) + " bytes")
+	}
+	b.WriteString("\nURL: " + fs.SenderURL)
+	if fs.SHA256 != "" {
+		b.WriteString("\nSHA-256: " + fs.SHA256)
+	}
+	return b.String()
+}
+
+// EntityToFileshareCoT re-emits the file transfer request fs that entity
+// was created from.
+func EntityToFileshareCoT(entity *pb.Entity, fs *Fileshare) ([]byte, error) {
+	now := time.Now().UTC()
+	startTime := now
+	staleTime := now.Add(3 * time.Hour)
+	if entity.Lifetime != nil {
+		if entity.Lifetime.From != nil {
+			startTime = entity.Lifetime.From.AsTime()
+		}
+		if entity.Lifetime.Until != nil {
+			staleTime = entity.Lifetime.Until.AsTime()
+		}
+	}
+
+	var point Point
+	if entity.Geo != nil {
+		point.Lat = entity.Geo.Latitude
+		point.Lon = entity.Geo.Longitude
+		if entity.Geo.Altitude != nil {
+			point.Hae = *entity.Geo.Altitude
+		}
+	}
+	point.CE, point.LE = 9999999.0, 9999999.0
+
+	event := Event{
+		Version: "2.0",
+		Type:    FileshareType,
+		How:     "h-e",
+		UID:     strings.TrimPrefix(entity.Id, "tak."),
+		Time:    now.Format(time.RFC3339),
+		Start:   startTime.Format(time.RFC3339),
+		Stale:   staleTime.Format(time.RFC3339),
+		Point:   point,
+		Detail: Detail{
+			Fileshare: fs,
+		},
+	}
+	return marshalEvent(event)
+}
@@ -0,0 +1,74 @@
+package cot
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+const fileshareRequest = `<event version="2.0" uid="b1c9a1e0-3c5e-4a7a-9f7d-0d4c2f1e9a11" type="b-f-t-r" how="h-e" time="2026-01-01T12:00:00Z" start="2026-01-01T12:00:00Z" stale="2026-01-01T12:10:00Z">
+  <point lat="0" lon="0" hae="0" ce="9999999" le="9999999"/>
+  <detail>
+    <fileshare filename="recon.zip" name="Recon North" senderUrl="https://tak.example:8443/Marti/sync/content?hash=4f2a" sizeInBytes="48213" sha256="4f2a9c" senderUid="ANDROID-1234" senderCallsign="VIPER"/>
+    <ackrequest uid="ack-1" ackrequested="true" tag="recon.zip"/>
+  </detail>
+</event>`
+
+func TestCoTFileshareToEntity(t *testing.T) {
+	if !IsFileshareCoT(fileshareRequest) {
+		t.Fatal("file transfer request not detected")
+	}
+
+	entity, fs, err := CoTFileshareToEntity([]byte(fileshareRequest), "tak", "tak.server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entity.Id != "b1c9a1e0-3c5e-4a7a-9f7d-0d4c2f1e9a11" || entity.GetLabel() != "Recon North" {
+		t.Errorf("got id %q label %q", entity.Id, entity.GetLabel())
+	}
+	if entity.Chat.GetSender() != "tak.ANDROID-1234" {
+		t.Errorf("sender %q", entity.Chat.GetSender())
+	}
+	for _, want := range []string{"VIPER shared recon.zip", "48213 bytes", "URL: https://tak.example:8443/Marti/sync/content?hash=4f2a", "SHA-256: 4f2a9c"} {
+		if !strings.Contains(entity.Chat.GetMessage(), want) {
+			t.Errorf("message %q lacks %q", entity.Chat.GetMessage(), want)
+		}
+	}
+	if entity.Geo != nil {
+		t.Errorf("file transfer without position got Geo %v", entity.Geo)
+	}
+	if got := entity.Lifetime.Until.AsTime().Format("15:04"); got != "12:10" {
+		t.Errorf("until %s, want the stale time", got)
+	}
+	if fs.SenderURL != "https://tak.example:8443/Marti/sync/content?hash=4f2a" || fs.SizeInBytes != 48213 {
+		t.Errorf("fileshare %+v", fs)
+	}
+
+	if _, _, err := CoTFileshareToEntity([]byte(`<event type="a-f-G" uid="x"/>`), "tak", "tak.server"); err == nil {
+		t.Error("expected error for a non-fileshare event")
+	}
+}
+
+func TestEntityToFileshareCoT_RoundTrip(t *testing.T) {
+	entity, fs, err := CoTFileshareToEntity([]byte(fileshareRequest), "tak", "tak.server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity.Id = "tak." + entity.Id
+
+	out, err := EntityToFileshareCoT(entity, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var event Event
+	if err := xml.Unmarshal(out, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != FileshareType || event.UID != "b1c9a1e0-3c5e-4a7a-9f7d-0d4c2f1e9a11" {
+		t.Errorf("got type %q uid %q", event.Type, event.UID)
+	}
+	got := event.Detail.Fileshare
+	if got == nil || got.SenderURL != fs.SenderURL || got.SHA256 != "4f2a9c" || got.Filename != "recon.zip" || got.SenderUID != "ANDROID-1234" {
+		t.Errorf("re-emitted fileshare %+v", got)
+	}
+}
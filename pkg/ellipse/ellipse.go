@@ -0,0 +1,108 @@
+// Package ellipse derives error ellipses from the position covariance of
+// GeoSpatialComponent, to show position uncertainty on a map.
+package ellipse
+
+import (
+	"math"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	pb "github.com/projectqai/proto/go"
+)
+
+// Confidence95 scales a one sigma ellipse to the one containing 95% of a
+// two dimensional normal distribution, sqrt of the 95% quantile of the
+// chi-squared distribution with two degrees of freedom.
+const Confidence95 = 2.4477
+
+// Ellipse is an error ellipse in meters.
+type Ellipse struct {
+	SemiMajorM float64
+	SemiMinorM float64
+	// BearingDeg is the direction of the major axis, clockwise from
+	// north, in [0, 180).
+	BearingDeg float64
+}
+
+// FromCovariance returns the one sigma ellipse of the east/north position
+// covariance [[Mxx, Mxy], [Mxy, Myy]] in m². It reports false if cov has
+// no positive variance.
+func FromCovariance(cov *pb.CovarianceMatrix) (Ellipse, bool) {
+	mxx, myy, mxy := cov.GetMxx(), cov.GetMyy(), cov.GetMxy()
+
+	// Eigenvalues of the symmetric 2×2 matrix.
+	avg := (mxx + myy) / 2
+	diff := (mxx - myy) / 2
+	disc := math.Sqrt(diff*diff + mxy*mxy)
+	major, minor := avg+disc, avg-disc
+	if major <= 0 || math.IsNaN(major) {
+		return Ellipse{}, false
+	}
+	minor = max(minor, 0)
+
+	// The eigenvector of the larger eigenvalue is (mxy, major-mxx) in
+	// east/north; without correlation the axes are east and north.
+	var theta float64
+	switch {
+	case mxy != 0:
+		theta = math.Atan2(mxy, major-mxx)
+	case mxx >= myy:
+		theta = math.Pi / 2
+	}
+	bearing := math.Mod(theta*180/math.Pi+360, 180)
+
+	return Ellipse{
+		SemiMajorM: math.Sqrt(major),
+		SemiMinorM: math.Sqrt(minor),
+		BearingDeg: bearing,
+	}, true
+}
+
+// Scale returns e with both axes multiplied by k, e.g. Confidence95.
+func (e Ellipse) Scale(k float64) Ellipse {
+	e.SemiMajorM *= k
+	e.SemiMinorM *= k
+	return e
+}
+
+// Offsets returns n points on e as east/north offsets in meters from its
+// center, starting at the tip of the major axis.
+func (e Ellipse) Offsets(n int) [][2]float64 {
+	theta := e.BearingDeg * math.Pi / 180
+	sin, cos := math.Sin(theta), math.Cos(theta)
+	out := make([][2]float64, n)
+	for i := range n {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		a := e.SemiMajorM * math.Cos(angle)
+		b := e.SemiMinorM * math.Sin(angle)
+		out[i] = [2]float64{a*sin + b*cos, a*cos - b*sin}
+	}
+	return out
+}
+
+// Ring returns e centered on lat/lon as a closed ring of n+1 points.
+func (e Ellipse) Ring(lat, lon float64, n int) []*pb.PlanarPoint {
+	center := orb.Point{lon, lat}
+	ring := make([]*pb.PlanarPoint, 0, n+1)
+	for _, o := range e.Offsets(n) {
+		bearing := math.Atan2(o[0], o[1]) * 180 / math.Pi
+		p := geo.PointAtBearingAndDistance(center, bearing, math.Hypot(o[0], o[1]))
+		ring = append(ring, &pb.PlanarPoint{Latitude: p.Lat(), Longitude: p.Lon()})
+	}
+	return append(ring, ring[0])
+}
+
+// Shape returns e centered on lat/lon as a polygon of n segments.
+func (e Ellipse) Shape(lat, lon float64, n int) *pb.GeoShapeComponent {
+	return &pb.GeoShapeComponent{
+		Geometry: &pb.Geometry{
+			Planar: &pb.PlanarGeometry{
+				Plane: &pb.PlanarGeometry_Polygon{
+					Polygon: &pb.PlanarPolygon{
+						Outer: &pb.PlanarRing{Points: e.Ring(lat, lon, n)},
+					},
+				},
+			},
+		},
+	}
+}
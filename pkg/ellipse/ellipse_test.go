@@ -0,0 +1,99 @@
+package ellipse
+
+import (
+	"math"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	pb "github.com/projectqai/proto/go"
+)
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestFromCovariance_AxisAligned(t *testing.T) {
+	e, ok := FromCovariance(&pb.CovarianceMatrix{Mxx: 4, Myy: 1})
+	if !ok {
+		t.Fatal("no ellipse")
+	}
+	if !near(e.SemiMajorM, 2) || !near(e.SemiMinorM, 1) || !near(e.BearingDeg, 90) {
+		t.Errorf("got %+v, want 2 m × 1 m with the major axis east", e)
+	}
+
+	e, _ = FromCovariance(&pb.CovarianceMatrix{Mxx: 1, Myy: 9})
+	if !near(e.SemiMajorM, 3) || !near(e.SemiMinorM, 1) || !near(e.BearingDeg, 0) {
+		t.Errorf("got %+v, want 3 m × 1 m with the major axis north", e)
+	}
+}
+
+func TestFromCovariance_Rotated(t *testing.T) {
+	// Axes of 10 m and 5 m, the major one 30° east of north.
+	s, c := 0.5, math.Sqrt(3)/2
+	cov := &pb.CovarianceMatrix{
+		Mxx: 100*s*s + 25*c*c,
+		Myy: 100*c*c + 25*s*s,
+		Mxy: 100*s*c - 25*c*s,
+	}
+	e, ok := FromCovariance(cov)
+	if !ok {
+		t.Fatal("no ellipse")
+	}
+	if !near(e.SemiMajorM, 10) || !near(e.SemiMinorM, 5) || !near(e.BearingDeg, 30) {
+		t.Errorf("got %+v, want 10 m × 5 m at 30°", e)
+	}
+
+	// Negative correlation mirrors the ellipse to 150°.
+	cov.Mxy = -cov.Mxy
+	if e, _ := FromCovariance(cov); !near(e.BearingDeg, 150) {
+		t.Errorf("bearing %v, want 150", e.BearingDeg)
+	}
+}
+
+func TestFromCovariance_Degenerate(t *testing.T) {
+	if _, ok := FromCovariance(nil); ok {
+		t.Error("nil covariance gave an ellipse")
+	}
+	if _, ok := FromCovariance(&pb.CovarianceMatrix{Mxx: -1, Myy: -1}); ok {
+		t.Error("negative variances gave an ellipse")
+	}
+	// Perfect correlation collapses the ellipse to a line.
+	e, ok := FromCovariance(&pb.CovarianceMatrix{Mxx: 1, Myy: 1, Mxy: 1})
+	if !ok || !near(e.SemiMajorM, math.Sqrt2) || e.SemiMinorM != 0 || !near(e.BearingDeg, 45) {
+		t.Errorf("got %+v, %v", e, ok)
+	}
+}
+
+func TestScaleAndOffsets(t *testing.T) {
+	e := Ellipse{SemiMajorM: 10, SemiMinorM: 5, BearingDeg: 30}.Scale(Confidence95)
+	if !near(e.SemiMajorM, 10*Confidence95) || !near(e.SemiMinorM, 5*Confidence95) {
+		t.Fatalf("scaled %+v", e)
+	}
+
+	offsets := e.Offsets(4)
+	tip := offsets[0]
+	if !near(math.Hypot(tip[0], tip[1]), e.SemiMajorM) || !near(math.Atan2(tip[0], tip[1])*180/math.Pi, 30) {
+		t.Errorf("first offset %v is not the major axis tip", tip)
+	}
+	side := offsets[1]
+	if !near(math.Hypot(side[0], side[1]), e.SemiMinorM) {
+		t.Errorf("second offset %v is not on the minor axis", side)
+	}
+}
+
+func TestRing(t *testing.T) {
+	e := Ellipse{SemiMajorM: 1000, SemiMinorM: 200, BearingDeg: 90}
+	ring := e.Ring(54, 10, 16)
+	if len(ring) != 17 || ring[0] != ring[16] {
+		t.Fatalf("ring of %d points, want a closed ring of 17", len(ring))
+	}
+	center := orb.Point{10, 54}
+	if d := geo.Distance(center, orb.Point{ring[0].Longitude, ring[0].Latitude}); math.Abs(d-1000) > 1 {
+		t.Errorf("major tip %.1f m from center, want 1000", d)
+	}
+	if d := geo.Distance(center, orb.Point{ring[4].Longitude, ring[4].Latitude}); math.Abs(d-200) > 1 {
+		t.Errorf("minor tip %.1f m from center, want 200", d)
+	}
+	if ring[0].Longitude <= 10 || math.Abs(ring[0].Latitude-54) > 1e-6 {
+		t.Errorf("major tip %v is not due east", ring[0])
+	}
+}
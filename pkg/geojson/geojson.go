@@ -0,0 +1,195 @@
+// Package geojson renders entities as GeoJSON (RFC 7946) features.
+package geojson
+
+import (
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/projectqai/hydris/pkg/coords"
+	"github.com/projectqai/hydris/pkg/ellipse"
+	pb "github.com/projectqai/proto/go"
+)
+
+// circleSegments is the number of vertices used to approximate a circle,
+// which GeoJSON has no type for.
+const circleSegments = 64
+
+type Geometry struct {
+	Type        string      `json:"type"`
+	Coordinates any         `json:"coordinates,omitempty"`
+	Geometries  []*Geometry `json:"geometries,omitempty"`
+}
+
+type Feature struct {
+	Type       string         `json:"type"`
+	ID         string         `json:"id"`
+	Geometry   *Geometry      `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type FeatureCollection struct {
+	Type     string     `json:"type"`
+	Features []*Feature `json:"features"`
+}
+
+func NewFeatureCollection(features []*Feature) *FeatureCollection {
+	if features == nil {
+		features = []*Feature{}
+	}
+	return &FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+// FromEntity returns the features of entity: a Point for its
+// GeoSpatialComponent and a LineString, Polygon or GeometryCollection for
+// its GeoShapeComponent. Entities without either have no features.
+func FromEntity(entity *pb.Entity) []*Feature {
+	var features []*Feature
+	if entity.Geo != nil {
+		position := []float64{entity.Geo.Longitude, entity.Geo.Latitude}
+		if entity.Geo.Altitude != nil {
+			position = append(position, *entity.Geo.Altitude)
+		}
+		features = append(features, newFeature(entity, "geo", &Geometry{Type: "Point", Coordinates: position}))
+	}
+	if g := planarGeometry(entity.Shape.GetGeometry().GetPlanar()); g != nil {
+		features = append(features, newFeature(entity, "shape", g))
+	}
+	return features
+}
+
+// Uncertainty returns the error ellipse of entity's position covariance,
+// scaled by k (e.g. ellipse.Confidence95), as a Polygon feature of
+// component "uncertainty" with its semi-axes and bearing as properties. It
+// returns nil if entity has no usable covariance.
+func Uncertainty(entity *pb.Entity, k float64) *Feature {
+	if entity.Geo.GetCovariance() == nil {
+		return nil
+	}
+	e, ok := ellipse.FromCovariance(entity.Geo.Covariance)
+	if !ok {
+		return nil
+	}
+	e = e.Scale(k)
+
+	ring := positions(e.Ring(entity.Geo.Latitude, entity.Geo.Longitude, circleSegments))
+	f := newFeature(entity, "uncertainty", &Geometry{Type: "Polygon", Coordinates: [][][]float64{ring}})
+	f.Properties["semi_major_m"] = e.SemiMajorM
+	f.Properties["semi_minor_m"] = e.SemiMinorM
+	f.Properties["bearing_deg"] = e.BearingDeg
+	return f
+}
+
+// AddFrame adds the position of entity in frame to the properties of its
+// "geo" feature among features, under the frame's name: [x, y, z] for
+// ECEF and an object of zone, north, easting and northing for UTM.
+// The feature's coordinates stay WGS84, as RFC 7946 requires.
+func AddFrame(features []*Feature, entity *pb.Entity, frame coords.Frame) {
+	if entity.Geo == nil || frame == coords.WGS84 {
+		return
+	}
+	for _, f := range features {
+		if f.Properties["component"] != "geo" {
+			continue
+		}
+		switch frame {
+		case coords.ECEF:
+			x, y, z := coords.ToECEF(entity.Geo.Latitude, entity.Geo.Longitude, entity.Geo.GetAltitude())
+			f.Properties[frame.String()] = []float64{x, y, z}
+		case coords.UTM:
+			if u, err := coords.ToUTM(entity.Geo.Latitude, entity.Geo.Longitude); err == nil {
+				f.Properties[frame.String()] = u
+			}
+		}
+	}
+}
+
+func newFeature(entity *pb.Entity, component string, geometry *Geometry) *Feature {
+	properties := map[string]any{
+		"id":        entity.Id,
+		"component": component,
+	}
+	if entity.Label != nil {
+		properties["label"] = entity.GetLabel()
+	}
+	if sidc := entity.Symbol.GetMilStd2525C(); sidc != "" {
+		properties["symbol"] = sidc
+	}
+	if controller := entity.Controller.GetId(); controller != "" {
+		properties["controller"] = controller
+	}
+	return &Feature{Type: "Feature", ID: entity.Id, Geometry: geometry, Properties: properties}
+}
+
+func planarGeometry(planar *pb.PlanarGeometry) *Geometry {
+	if planar == nil {
+		return nil
+	}
+
+	switch p := planar.Plane.(type) {
+	case *pb.PlanarGeometry_Point:
+		if p.Point != nil {
+			return &Geometry{Type: "Point", Coordinates: position(p.Point)}
+		}
+	case *pb.PlanarGeometry_Line:
+		if len(p.Line.GetPoints()) >= 2 {
+			return &Geometry{Type: "LineString", Coordinates: positions(p.Line.Points)}
+		}
+	case *pb.PlanarGeometry_Polygon:
+		if len(p.Polygon.GetOuter().GetPoints()) < 3 {
+			return nil
+		}
+		rings := [][][]float64{closed(positions(p.Polygon.Outer.Points))}
+		for _, hole := range p.Polygon.Holes {
+			if len(hole.GetPoints()) >= 3 {
+				rings = append(rings, closed(positions(hole.Points)))
+			}
+		}
+		return &Geometry{Type: "Polygon", Coordinates: rings}
+	case *pb.PlanarGeometry_Circle:
+		if p.Circle.GetCenter() != nil && p.Circle.RadiusM > 0 {
+			center := orb.Point{p.Circle.Center.Longitude, p.Circle.Center.Latitude}
+			ring := make([][]float64, 0, circleSegments+1)
+			for i := 0; i < circleSegments; i++ {
+				pt := geo.PointAtBearingAndDistance(center, float64(i)*360/circleSegments, p.Circle.RadiusM)
+				ring = append(ring, []float64{pt.Lon(), pt.Lat()})
+			}
+			return &Geometry{Type: "Polygon", Coordinates: [][][]float64{closed(ring)}}
+		}
+	case *pb.PlanarGeometry_Collection:
+		var geometries []*Geometry
+		for _, child := range p.Collection.GetGeometries() {
+			if g := planarGeometry(child); g != nil {
+				geometries = append(geometries, g)
+			}
+		}
+		if len(geometries) > 0 {
+			return &Geometry{Type: "GeometryCollection", Geometries: geometries}
+		}
+	}
+	return nil
+}
+
+func position(p *pb.PlanarPoint) []float64 {
+	pos := []float64{p.Longitude, p.Latitude}
+	if p.Altitude != nil {
+		pos = append(pos, *p.Altitude)
+	}
+	return pos
+}
+
+func positions(points []*pb.PlanarPoint) [][]float64 {
+	out := make([][]float64, len(points))
+	for i, p := range points {
+		out[i] = position(p)
+	}
+	return out
+}
+
+// closed returns ring with its first position repeated at the end, as
+// GeoJSON linear rings require.
+func closed(ring [][]float64) [][]float64 {
+	first, last := ring[0], ring[len(ring)-1]
+	if len(first) >= 2 && first[0] == last[0] && first[1] == last[1] {
+		return ring
+	}
+	return append(ring, first)
+}
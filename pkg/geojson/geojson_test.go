@@ -0,0 +1,180 @@
+package geojson
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/projectqai/hydris/pkg/coords"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// roundTrip encodes v and decodes it into a generic JSON document.
+func roundTrip(t *testing.T, v any) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func ring(points ...[2]float64) *pb.PlanarRing {
+	r := &pb.PlanarRing{}
+	for _, p := range points {
+		r.Points = append(r.Points, &pb.PlanarPoint{Longitude: p[0], Latitude: p[1]})
+	}
+	return r
+}
+
+func TestFromEntity_Point(t *testing.T) {
+	alt := 120.0
+	features := FromEntity(&pb.Entity{
+		Id:         "ship1",
+		Label:      proto.String("Ship"),
+		Symbol:     &pb.SymbolComponent{MilStd2525C: "SFSP-----------"},
+		Controller: &pb.Controller{Id: proto.String("ais")},
+		Geo:        &pb.GeoSpatialComponent{Latitude: 54, Longitude: 10, Altitude: &alt},
+	})
+	if len(features) != 1 {
+		t.Fatalf("got %d features", len(features))
+	}
+
+	doc := roundTrip(t, features[0])
+	if doc["type"] != "Feature" || doc["id"] != "ship1" {
+		t.Errorf("feature %v", doc)
+	}
+	geometry := doc["geometry"].(map[string]any)
+	coords := geometry["coordinates"].([]any)
+	if geometry["type"] != "Point" || coords[0] != 10.0 || coords[1] != 54.0 || coords[2] != 120.0 {
+		t.Errorf("geometry %v, want [lon lat alt]", geometry)
+	}
+	props := doc["properties"].(map[string]any)
+	if props["label"] != "Ship" || props["symbol"] != "SFSP-----------" || props["controller"] != "ais" || props["component"] != "geo" {
+		t.Errorf("properties %v", props)
+	}
+}
+
+func TestFromEntity_Shapes(t *testing.T) {
+	cases := []struct {
+		name  string
+		plane *pb.PlanarGeometry
+		want  string
+	}{
+		{"line", &pb.PlanarGeometry{Plane: &pb.PlanarGeometry_Line{Line: ring([2]float64{0, 0}, [2]float64{1, 1})}}, "LineString"},
+		{"polygon", &pb.PlanarGeometry{Plane: &pb.PlanarGeometry_Polygon{Polygon: &pb.PlanarPolygon{
+			Outer: ring([2]float64{0, 0}, [2]float64{1, 0}, [2]float64{1, 1}),
+			Holes: []*pb.PlanarRing{ring([2]float64{0.2, 0.2}, [2]float64{0.4, 0.2}, [2]float64{0.4, 0.4}, [2]float64{0.2, 0.2})},
+		}}}, "Polygon"},
+		{"circle", &pb.PlanarGeometry{Plane: &pb.PlanarGeometry_Circle{Circle: &pb.PlanarCircle{
+			Center: &pb.PlanarPoint{Latitude: 48, Longitude: 11}, RadiusM: 1000,
+		}}}, "Polygon"},
+		{"collection", &pb.PlanarGeometry{Plane: &pb.PlanarGeometry_Collection{Collection: &pb.PlanarGeometryCollection{
+			Geometries: []*pb.PlanarGeometry{{Plane: &pb.PlanarGeometry_Point{Point: &pb.PlanarPoint{Latitude: 1, Longitude: 2}}}},
+		}}}, "GeometryCollection"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			features := FromEntity(&pb.Entity{Id: "zone", Shape: &pb.GeoShapeComponent{Geometry: &pb.Geometry{Planar: tc.plane}}})
+			if len(features) != 1 {
+				t.Fatalf("got %d features", len(features))
+			}
+			geometry := roundTrip(t, features[0])["geometry"].(map[string]any)
+			if geometry["type"] != tc.want {
+				t.Fatalf("type %v, want %s", geometry["type"], tc.want)
+			}
+			if tc.want != "Polygon" {
+				return
+			}
+			for _, r := range geometry["coordinates"].([]any) {
+				pts := r.([]any)
+				first, last := pts[0].([]any), pts[len(pts)-1].([]any)
+				if len(pts) < 4 || first[0] != last[0] || first[1] != last[1] {
+					t.Errorf("ring not closed: %v", pts)
+				}
+			}
+		})
+	}
+}
+
+func TestFromEntity_CircleRadius(t *testing.T) {
+	features := FromEntity(&pb.Entity{Id: "c", Shape: &pb.GeoShapeComponent{Geometry: &pb.Geometry{Planar: &pb.PlanarGeometry{
+		Plane: &pb.PlanarGeometry_Circle{Circle: &pb.PlanarCircle{Center: &pb.PlanarPoint{Latitude: 0, Longitude: 0}, RadiusM: 1000}},
+	}}}})
+	north := features[0].Geometry.Coordinates.([][][]float64)[0][0]
+	// 1 km at the equator is about 0.009 degrees of latitude.
+	if math.Abs(north[1]-0.009) > 0.0005 || math.Abs(north[0]) > 1e-9 {
+		t.Errorf("first vertex %v, want due north of the center", north)
+	}
+}
+
+func TestFromEntity_NoGeometry(t *testing.T) {
+	if features := FromEntity(&pb.Entity{Id: "x"}); len(features) != 0 {
+		t.Errorf("got %v", features)
+	}
+	doc := roundTrip(t, NewFeatureCollection(nil))
+	if doc["type"] != "FeatureCollection" || doc["features"] == nil {
+		t.Errorf("empty collection %v must have a features array", doc)
+	}
+}
+
+func TestUncertainty(t *testing.T) {
+	entity := &pb.Entity{
+		Id:  "track1",
+		Geo: &pb.GeoSpatialComponent{Latitude: 54, Longitude: 10, Covariance: &pb.CovarianceMatrix{Mxx: 400, Myy: 100}},
+	}
+	f := Uncertainty(entity, 2)
+	if f == nil {
+		t.Fatal("no feature")
+	}
+	doc := roundTrip(t, f)
+	props := doc["properties"].(map[string]any)
+	if props["component"] != "uncertainty" || props["semi_major_m"] != 40.0 || props["semi_minor_m"] != 20.0 || props["bearing_deg"] != 90.0 {
+		t.Errorf("properties %v", props)
+	}
+	geometry := doc["geometry"].(map[string]any)
+	rings := geometry["coordinates"].([]any)
+	outer := rings[0].([]any)
+	if geometry["type"] != "Polygon" || len(outer) != circleSegments+1 {
+		t.Errorf("geometry %v with %d positions", geometry["type"], len(outer))
+	}
+
+	if Uncertainty(&pb.Entity{Id: "p", Geo: &pb.GeoSpatialComponent{}}, 2) != nil {
+		t.Error("feature without covariance")
+	}
+}
+
+func TestAddFrame(t *testing.T) {
+	entity := &pb.Entity{
+		Id:    "ship1",
+		Geo:   &pb.GeoSpatialComponent{Latitude: 0, Longitude: 3},
+		Shape: &pb.GeoShapeComponent{Geometry: &pb.Geometry{Planar: &pb.PlanarGeometry{Plane: &pb.PlanarGeometry_Point{Point: &pb.PlanarPoint{}}}}},
+	}
+
+	features := FromEntity(entity)
+	AddFrame(features, entity, coords.ECEF)
+	ecef, ok := features[0].Properties["ecef"].([]float64)
+	if !ok || math.Abs(ecef[0]-6378137) > 1e-6 || math.Abs(ecef[2]) > 1e-6 {
+		t.Errorf("ecef property %v", features[0].Properties["ecef"])
+	}
+	if _, ok := features[1].Properties["ecef"]; ok {
+		t.Error("shape feature got a position")
+	}
+
+	features = FromEntity(entity)
+	AddFrame(features, entity, coords.UTM)
+	doc := roundTrip(t, features[0])
+	utm := doc["properties"].(map[string]any)["utm"].(map[string]any)
+	if utm["zone"] != 31.0 || utm["north"] != true || math.Abs(utm["easting"].(float64)-500000) > 1e-6 {
+		t.Errorf("utm property %v", utm)
+	}
+	if position := doc["geometry"].(map[string]any)["coordinates"].([]any); position[0] != 3.0 {
+		t.Errorf("coordinates changed to %v", position)
+	}
+}
@@ -0,0 +1,111 @@
+// Package idtemplate builds stable entity ids from arbitrary fields, for
+// ingest that has no natural key like an MMSI or ICAO address.
+//
+// A template such as "sensor.{site}.{serial}" mixes literal text with
+// field names in braces. Expanding it substitutes the field values,
+// sanitized so that the result is a valid entity id.
+package idtemplate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Template is a parsed id template.
+type Template struct {
+	// parts alternate between literal text (even indices) and field
+	// names (odd indices).
+	parts []string
+}
+
+// Parse parses s. Literal text must only contain characters valid in an
+// entity id, and every field name must be non-empty and closed.
+func Parse(s string) (*Template, error) {
+	t := &Template{}
+	rest := s
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			t.parts = append(t.parts, rest)
+			break
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("id template %q: unclosed {", s)
+		}
+		name := rest[open+1 : open+end]
+		if name == "" || strings.ContainsAny(name, "{") {
+			return nil, fmt.Errorf("id template %q: invalid field {%s}", s, name)
+		}
+		t.parts = append(t.parts, rest[:open], name)
+		rest = rest[open+end+1:]
+	}
+
+	for i := 0; i < len(t.parts); i += 2 {
+		if lit := t.parts[i]; Sanitize(lit) != lit {
+			return nil, fmt.Errorf("id template %q: %q is not valid in an id", s, lit)
+		}
+	}
+	if len(t.parts) == 1 && t.parts[0] == "" {
+		return nil, fmt.Errorf("id template is empty")
+	}
+	return t, nil
+}
+
+// Fields returns the field names used by t, in order.
+func (t *Template) Fields() []string {
+	var names []string
+	for i := 1; i < len(t.parts); i += 2 {
+		names = append(names, t.parts[i])
+	}
+	return names
+}
+
+// Expand substitutes the values of fields. Missing or empty fields are an
+// error rather than an empty segment, so that different sources cannot
+// collapse onto the same id.
+func (t *Template) Expand(fields map[string]string) (string, error) {
+	return t.ExpandFunc(func(name string) (string, bool) {
+		v, ok := fields[name]
+		return v, ok
+	})
+}
+
+// ExpandFunc is like Expand, with field values returned by lookup.
+func (t *Template) ExpandFunc(lookup func(name string) (string, bool)) (string, error) {
+	var b strings.Builder
+	for i, part := range t.parts {
+		if i%2 == 0 {
+			b.WriteString(part)
+			continue
+		}
+		v, ok := lookup(part)
+		if !ok || v == "" {
+			return "", fmt.Errorf("id field %q is missing", part)
+		}
+		b.WriteString(Sanitize(v))
+	}
+	return b.String(), nil
+}
+
+// Sanitize replaces every character not valid in an entity id with "_".
+// Valid are ASCII letters and digits and "-_.~@:".
+func Sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if validRune(r) {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+func validRune(r rune) bool {
+	if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+		return true
+	}
+	switch r {
+	case '-', '_', '.', '~', '@', ':':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package idtemplate
+
+import "testing"
+
+func mustParse(t *testing.T, s string) *Template {
+	t.Helper()
+	tmpl, err := Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tmpl
+}
+
+func TestExpand(t *testing.T) {
+	tmpl, err := Parse("sensor.{site}.{serial}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tmpl.Fields(); len(got) != 2 || got[0] != "site" || got[1] != "serial" {
+		t.Errorf("fields %v", got)
+	}
+
+	got, err := tmpl.Expand(map[string]string{"site": "north", "serial": "A17"})
+	if err != nil || got != "sensor.north.A17" {
+		t.Errorf("got %q, %v", got, err)
+	}
+
+	if _, err := tmpl.Expand(map[string]string{"site": "north"}); err == nil {
+		t.Error("missing field should be an error")
+	}
+	if _, err := tmpl.Expand(map[string]string{"site": "north", "serial": ""}); err == nil {
+		t.Error("empty field should be an error")
+	}
+
+	got, err = mustParse(t, "{id}").Expand(map[string]string{"id": "buoy-7"})
+	if err != nil || got != "buoy-7" {
+		t.Errorf("field only: got %q, %v", got, err)
+	}
+	got, err = mustParse(t, "static").Expand(nil)
+	if err != nil || got != "static" {
+		t.Errorf("literal only: got %q, %v", got, err)
+	}
+}
+
+func TestExpand_Sanitizes(t *testing.T) {
+	tmpl := mustParse(t, "sensor.{site}.{serial}")
+	got, err := tmpl.Expand(map[string]string{"site": "north/west pier", "serial": "Ünit#3?x=1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "sensor.north_west_pier._nit_3_x_1"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := map[string]string{
+		"abc-DEF_123.~@:": "abc-DEF_123.~@:",
+		"a b/c\\d":        "a_b_c_d",
+		"häuser":          "h_user",
+		"{x}%20":          "_x__20",
+	}
+	for in, want := range tests {
+		if got := Sanitize(in); got != want {
+			t.Errorf("Sanitize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"sensor.{site",
+		"sensor.{}",
+		"sensor.{a{b}",
+		"sensor/{site}",
+		"sensor {site}",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Package kml renders entities as KML 2.2 documents for Google Earth.
+package kml
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/projectqai/hydris/pkg/geojson"
+	pb "github.com/projectqai/proto/go"
+)
+
+const Namespace = "http://www.opengis.net/kml/2.2"
+
+type KML struct {
+	XMLName            xml.Name            `xml:"kml"`
+	Xmlns              string              `xml:"xmlns,attr"`
+	NetworkLinkControl *NetworkLinkControl `xml:"NetworkLinkControl,omitempty"`
+	Document           *Document           `xml:"Document,omitempty"`
+	NetworkLink        *NetworkLink        `xml:"NetworkLink,omitempty"`
+}
+
+type NetworkLinkControl struct {
+	MinRefreshPeriod float64 `xml:"minRefreshPeriod"`
+}
+
+type NetworkLink struct {
+	Name string `xml:"name"`
+	Link Link   `xml:"Link"`
+}
+
+type Link struct {
+	Href            string  `xml:"href"`
+	RefreshMode     string  `xml:"refreshMode"`
+	RefreshInterval float64 `xml:"refreshInterval"`
+}
+
+type Document struct {
+	Name       string       `xml:"name"`
+	Styles     []*Style     `xml:"Style"`
+	Placemarks []*Placemark `xml:"Placemark"`
+}
+
+type Style struct {
+	ID        string    `xml:"id,attr"`
+	IconStyle IconStyle `xml:"IconStyle"`
+	LineStyle LineStyle `xml:"LineStyle"`
+	PolyStyle PolyStyle `xml:"PolyStyle"`
+}
+
+type IconStyle struct {
+	Color string `xml:"color"`
+	Icon  struct {
+		Href string `xml:"href"`
+	} `xml:"Icon"`
+}
+
+type LineStyle struct {
+	Color string  `xml:"color"`
+	Width float64 `xml:"width"`
+}
+
+type PolyStyle struct {
+	Color string `xml:"color"`
+}
+
+type Placemark struct {
+	ID          string    `xml:"id,attr"`
+	Name        string    `xml:"name"`
+	Description string    `xml:"description,omitempty"`
+	StyleURL    string    `xml:"styleUrl,omitempty"`
+	Geometry    *Geometry `xml:",omitempty"`
+}
+
+// Geometry is one of Point, LineString, Polygon or MultiGeometry; only the
+// matching fields are set.
+type Geometry struct {
+	XMLName      xml.Name
+	AltitudeMode string      `xml:"altitudeMode,omitempty"`
+	Coordinates  string      `xml:"coordinates,omitempty"`
+	Outer        *Boundary   `xml:"outerBoundaryIs,omitempty"`
+	Inner        []*Boundary `xml:"innerBoundaryIs,omitempty"`
+	Geometries   []*Geometry `xml:",omitempty"`
+}
+
+type Boundary struct {
+	LinearRing struct {
+		Coordinates string `xml:"coordinates"`
+	} `xml:"LinearRing"`
+}
+
+// NewNetworkLink returns a document that makes Google Earth fetch href
+// every refresh.
+func NewNetworkLink(name, href string, refresh time.Duration) *KML {
+	return &KML{
+		Xmlns: Namespace,
+		NetworkLink: &NetworkLink{
+			Name: name,
+			Link: Link{Href: href, RefreshMode: "onInterval", RefreshInterval: refresh.Seconds()},
+		},
+	}
+}
+
+// NewDocument returns a document with one placemark per position or shape
+// of entities. With minRefresh > 0 the document asks network links not to
+// fetch it more often than that.
+func NewDocument(name string, entities []*pb.Entity, minRefresh time.Duration) *KML {
+	doc := &Document{Name: name}
+	styles := make(map[string]bool)
+
+	for _, e := range entities {
+		styleID := styleFor(e.Symbol.GetMilStd2525C())
+		for _, f := range geojson.FromEntity(e) {
+			g := geometry(f.Geometry)
+			if g == nil {
+				continue
+			}
+			label := e.GetLabel()
+			if label == "" {
+				label = e.Id
+			}
+			doc.Placemarks = append(doc.Placemarks, &Placemark{
+				ID:          e.Id + "." + f.Properties["component"].(string),
+				Name:        label,
+				Description: e.Id,
+				StyleURL:    "#" + styleID,
+				Geometry:    g,
+			})
+			styles[styleID] = true
+		}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(styles)) {
+		doc.Styles = append(doc.Styles, newStyle(id))
+	}
+
+	k := &KML{Xmlns: Namespace, Document: doc}
+	if minRefresh > 0 {
+		k.NetworkLinkControl = &NetworkLinkControl{MinRefreshPeriod: minRefresh.Seconds()}
+	}
+	return k
+}
+
+// Encode writes k as an indented XML document.
+func (k *KML) Encode(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(k); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// EncodeKMZ writes k as a KMZ archive holding a single doc.kml.
+func (k *KML) EncodeKMZ(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	f, err := zw.Create("doc.kml")
+	if err != nil {
+		return err
+	}
+	if err := k.Encode(f); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func geometry(g *geojson.Geometry) *Geometry {
+	switch g.Type {
+	case "Point":
+		pos := g.Coordinates.([]float64)
+		out := &Geometry{XMLName: xml.Name{Local: "Point"}, Coordinates: coordinates([][]float64{pos})}
+		if len(pos) > 2 {
+			out.AltitudeMode = "absolute"
+		}
+		return out
+	case "LineString":
+		return &Geometry{XMLName: xml.Name{Local: "LineString"}, Coordinates: coordinates(g.Coordinates.([][]float64))}
+	case "Polygon":
+		rings := g.Coordinates.([][][]float64)
+		out := &Geometry{XMLName: xml.Name{Local: "Polygon"}, Outer: boundary(rings[0])}
+		for _, hole := range rings[1:] {
+			out.Inner = append(out.Inner, boundary(hole))
+		}
+		return out
+	case "GeometryCollection":
+		out := &Geometry{XMLName: xml.Name{Local: "MultiGeometry"}}
+		for _, child := range g.Geometries {
+			if c := geometry(child); c != nil {
+				out.Geometries = append(out.Geometries, c)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func boundary(ring [][]float64) *Boundary {
+	b := &Boundary{}
+	b.LinearRing.Coordinates = coordinates(ring)
+	return b
+}
+
+// coordinates formats positions as KML tuples "lon,lat[,alt]".
+func coordinates(positions [][]float64) string {
+	tuples := make([]string, len(positions))
+	for i, pos := range positions {
+		parts := make([]string, len(pos))
+		for j, v := range pos {
+			parts[j] = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		tuples[i] = strings.Join(parts, ",")
+	}
+	return strings.Join(tuples, " ")
+}
+
+// affiliationColors are KML aabbggrr colors by the SIDC standard identity.
+var affiliationColors = map[byte]string{
+	'F': "ffff8000", // friend: blue
+	'A': "ffff8000", // assumed friend
+	'H': "ff0000ff", // hostile: red
+	'S': "ff0000ff", // suspect
+	'N': "ff00ff00", // neutral: green
+	'U': "ff00ffff", // unknown: yellow
+}
+
+// dimensionIcons are Google Earth icons by the SIDC battle dimension.
+var dimensionIcons = map[byte]string{
+	'A': "http://maps.google.com/mapfiles/kml/shapes/airports.png",
+	'S': "http://maps.google.com/mapfiles/kml/shapes/sailing.png",
+	'U': "http://maps.google.com/mapfiles/kml/shapes/sailing.png",
+	'P': "http://maps.google.com/mapfiles/kml/shapes/star.png",
+}
+
+const defaultIcon = "http://maps.google.com/mapfiles/kml/shapes/placemark_circle.png"
+
+// styleFor returns the id of the style for a MIL-STD-2525C symbol code,
+// made of its standard identity and battle dimension.
+func styleFor(sidc string) string {
+	affiliation, dimension := byte('U'), byte('G')
+	if len(sidc) >= 3 {
+		affiliation, dimension = sidc[1], sidc[2]
+	}
+	if _, ok := affiliationColors[affiliation]; !ok {
+		affiliation = 'U'
+	}
+	if _, ok := dimensionIcons[dimension]; !ok {
+		dimension = 'G'
+	}
+	return fmt.Sprintf("sidc-%c%c", affiliation, dimension)
+}
+
+func newStyle(id string) *Style {
+	color := affiliationColors[id[5]]
+	icon, ok := dimensionIcons[id[6]]
+	if !ok {
+		icon = defaultIcon
+	}
+	s := &Style{ID: id}
+	s.IconStyle.Color = color
+	s.IconStyle.Icon.Href = icon
+	s.LineStyle = LineStyle{Color: color, Width: 2}
+	// 25% opaque fill in the same color
+	s.PolyStyle.Color = "40" + color[2:]
+	return s
+}
@@ -0,0 +1,186 @@
+package kml
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// parsed mirrors the parts of a KML document the tests look at.
+type parsed struct {
+	XMLName            xml.Name
+	NetworkLinkControl struct {
+		MinRefreshPeriod float64 `xml:"minRefreshPeriod"`
+	}
+	Document struct {
+		Styles []struct {
+			ID string `xml:"id,attr"`
+		} `xml:"Style"`
+		Placemarks []struct {
+			Name     string `xml:"name"`
+			StyleURL string `xml:"styleUrl"`
+			Point    *struct {
+				AltitudeMode string `xml:"altitudeMode"`
+				Coordinates  string `xml:"coordinates"`
+			}
+			Polygon *struct {
+				Outer string   `xml:"outerBoundaryIs>LinearRing>coordinates"`
+				Inner []string `xml:"innerBoundaryIs>LinearRing>coordinates"`
+			}
+		} `xml:"Placemark"`
+	}
+	NetworkLink struct {
+		Link struct {
+			Href            string  `xml:"href"`
+			RefreshMode     string  `xml:"refreshMode"`
+			RefreshInterval float64 `xml:"refreshInterval"`
+		}
+	}
+}
+
+func decode(t *testing.T, data []byte) parsed {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte(xml.Header)) {
+		t.Error("missing XML declaration")
+	}
+
+	// Walk every token first so malformed XML fails regardless of the
+	// struct mapping below.
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("malformed KML: %v\n%s", err, data)
+		}
+	}
+
+	var doc parsed
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.XMLName.Space != Namespace || doc.XMLName.Local != "kml" {
+		t.Errorf("root element %v", doc.XMLName)
+	}
+	return doc
+}
+
+func ring(points ...[2]float64) *pb.PlanarRing {
+	r := &pb.PlanarRing{}
+	for _, p := range points {
+		r.Points = append(r.Points, &pb.PlanarPoint{Longitude: p[0], Latitude: p[1]})
+	}
+	return r
+}
+
+func testEntities() []*pb.Entity {
+	alt := 3000.0
+	return []*pb.Entity{
+		{
+			Id:     "ac1",
+			Label:  proto.String("Hostile & <Air>"),
+			Symbol: &pb.SymbolComponent{MilStd2525C: "SHAP-----------"},
+			Geo:    &pb.GeoSpatialComponent{Latitude: 48.1, Longitude: 11.5, Altitude: &alt},
+		},
+		{
+			Id: "zone",
+			Shape: &pb.GeoShapeComponent{Geometry: &pb.Geometry{Planar: &pb.PlanarGeometry{
+				Plane: &pb.PlanarGeometry_Polygon{Polygon: &pb.PlanarPolygon{
+					Outer: ring([2]float64{11, 48}, [2]float64{12, 48}, [2]float64{12, 49}),
+					Holes: []*pb.PlanarRing{ring([2]float64{11.4, 48.4}, [2]float64{11.6, 48.4}, [2]float64{11.6, 48.6})},
+				}},
+			}}},
+		},
+	}
+}
+
+func TestNewDocument(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewDocument("test", testEntities(), 5*time.Second).Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	doc := decode(t, buf.Bytes())
+
+	if doc.NetworkLinkControl.MinRefreshPeriod != 5 {
+		t.Errorf("minRefreshPeriod %v", doc.NetworkLinkControl.MinRefreshPeriod)
+	}
+
+	placemarks := doc.Document.Placemarks
+	if len(placemarks) != 2 {
+		t.Fatalf("got %d placemarks", len(placemarks))
+	}
+
+	point := placemarks[0]
+	if point.Name != "Hostile & <Air>" || point.Point == nil {
+		t.Fatalf("point placemark %+v", point)
+	}
+	if point.Point.Coordinates != "11.5,48.1,3000" || point.Point.AltitudeMode != "absolute" {
+		t.Errorf("point %+v", point.Point)
+	}
+	if point.StyleURL != "#sidc-HA" {
+		t.Errorf("style %q, want hostile air", point.StyleURL)
+	}
+
+	polygon := placemarks[1]
+	if polygon.Name != "zone" || polygon.Polygon == nil {
+		t.Fatalf("polygon placemark %+v", polygon)
+	}
+	if polygon.Polygon.Outer != "11,48 12,48 12,49 11,48" {
+		t.Errorf("outer ring %q must be closed", polygon.Polygon.Outer)
+	}
+	if len(polygon.Polygon.Inner) != 1 {
+		t.Errorf("inner rings %v", polygon.Polygon.Inner)
+	}
+	if polygon.StyleURL != "#sidc-UG" {
+		t.Errorf("style %q, want the unknown default", polygon.StyleURL)
+	}
+
+	var styles []string
+	for _, s := range doc.Document.Styles {
+		styles = append(styles, s.ID)
+	}
+	if strings.Join(styles, ",") != "sidc-HA,sidc-UG" {
+		t.Errorf("styles %v", styles)
+	}
+}
+
+func TestEncodeKMZ(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewDocument("test", testEntities(), 0).EncodeKMZ(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != 1 || zr.File[0].Name != "doc.kml" {
+		t.Fatalf("archive holds %v", zr.File)
+	}
+	f, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(f)
+	if doc := decode(t, data); len(doc.Document.Placemarks) != 2 {
+		t.Errorf("got %d placemarks", len(doc.Document.Placemarks))
+	}
+}
+
+func TestNewNetworkLink(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewNetworkLink("live", "http://hydris:50051/kml?refresh=30", 30*time.Second).Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	link := decode(t, buf.Bytes()).NetworkLink.Link
+	if link.Href != "http://hydris:50051/kml?refresh=30" || link.RefreshMode != "onInterval" || link.RefreshInterval != 30 {
+		t.Errorf("link %+v", link)
+	}
+}
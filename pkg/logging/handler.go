@@ -71,10 +71,10 @@ func init() {
 	Ring = new(engine.LogRing)
 
 	handler := &modulePrefixHandler{
-		handler: tint.NewHandler(io.MultiWriter(Ring, os.Stderr), &tint.Options{
+		handler: engine.NewTraceHandler(tint.NewHandler(io.MultiWriter(Ring, os.Stderr), &tint.Options{
 			Level:      level,
 			TimeFormat: time.Kitchen,
-		}),
+		})),
 	}
 	slog.SetDefault(slog.New(handler))
 }
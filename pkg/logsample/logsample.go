@@ -0,0 +1,108 @@
+// Package logsample rate-limits the success logs of hot ingest paths.
+package logsample
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultPerSecond is the rate used by the builtins unless configured.
+const DefaultPerSecond = 1
+
+// Handler passes at most perSecond Info records per message and second to
+// the next handler. Records at other levels always pass: warnings and
+// errors must not get lost, and debug output is only enabled on purpose. The first
+// record let through after some were dropped carries their number in a
+// "suppressed" attribute.
+type Handler struct {
+	next  slog.Handler
+	state *state
+}
+
+type state struct {
+	perSecond int
+	now       func() time.Time
+
+	mu      sync.Mutex
+	windows map[string]*window
+}
+
+// window counts the records of one message in the current second.
+type window struct {
+	start      time.Time
+	passed     int
+	suppressed int
+}
+
+// NewHandler returns a Handler in front of next. perSecond <= 0 disables
+// sampling.
+func NewHandler(next slog.Handler, perSecond int) *Handler {
+	return &Handler{next: next, state: &state{perSecond: perSecond, now: time.Now}}
+}
+
+// Logger returns a logger that writes to l's handler through a Handler.
+func Logger(l *slog.Logger, perSecond int) *slog.Logger {
+	if perSecond <= 0 {
+		return l
+	}
+	return slog.New(NewHandler(l.Handler(), perSecond))
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level != slog.LevelInfo || h.state.perSecond <= 0 {
+		return h.next.Handle(ctx, r)
+	}
+	ok, suppressed := h.state.allow(r.Message)
+	if !ok {
+		return nil
+	}
+	if suppressed > 0 {
+		r = r.Clone()
+		r.AddAttrs(slog.Int("suppressed", suppressed))
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs and WithGroup share the rate of h, so a logger derived with
+// With does not get a budget of its own.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{next: h.next.WithAttrs(attrs), state: h.state}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{next: h.next.WithGroup(name), state: h.state}
+}
+
+// allow reports whether a record with msg may pass, and how many were
+// dropped since the last one that did.
+func (s *state) allow(msg string) (bool, int) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.windows == nil {
+		s.windows = make(map[string]*window)
+	}
+	w, ok := s.windows[msg]
+	if !ok {
+		w = &window{start: now}
+		s.windows[msg] = w
+	}
+	if now.Sub(w.start) >= time.Second {
+		w.start = now
+		w.passed = 0
+	}
+	if w.passed >= s.perSecond {
+		w.suppressed++
+		return false, 0
+	}
+	w.passed++
+	suppressed := w.suppressed
+	w.suppressed = 0
+	return true, suppressed
+}
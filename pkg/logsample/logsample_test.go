@@ -0,0 +1,74 @@
+package logsample
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testLogger(perSecond int) (*slog.Logger, *bytes.Buffer, *time.Time) {
+	var buf bytes.Buffer
+	h := NewHandler(slog.NewTextHandler(&buf, nil), perSecond)
+	now := time.Unix(0, 0)
+	h.state.now = func() time.Time { return now }
+	return slog.New(h), &buf, &now
+}
+
+func lines(buf *bytes.Buffer) []string {
+	return strings.Split(strings.TrimSpace(buf.String()), "\n")
+}
+
+func TestHandler_BoundsRapidSuccesses(t *testing.T) {
+	logger, buf, now := testLogger(2)
+	for range 1000 {
+		logger.Info("Pushed entity", "id", "e1")
+	}
+	if got := lines(buf); len(got) != 2 {
+		t.Fatalf("1000 records in one second logged %d lines, want 2", len(got))
+	}
+
+	*now = now.Add(time.Second)
+	buf.Reset()
+	logger.Info("Pushed entity", "id", "e1")
+	if got := buf.String(); !strings.Contains(got, "suppressed=998") {
+		t.Errorf("first line of the next second %q lacks the suppressed count", got)
+	}
+}
+
+func TestHandler_ErrorsAlwaysPass(t *testing.T) {
+	logger, buf, _ := testLogger(1)
+	for range 100 {
+		logger.Info("Pushed entity")
+		logger.Error("Push failed", "error", errors.New("unavailable"))
+	}
+	var errs int
+	for _, l := range lines(buf) {
+		if strings.Contains(l, "Push failed") {
+			errs++
+		}
+	}
+	if errs != 100 {
+		t.Errorf("%d error lines, want 100", errs)
+	}
+}
+
+func TestHandler_PerMessage(t *testing.T) {
+	logger, buf, _ := testLogger(1)
+	for range 10 {
+		logger.Info("Pushed node")
+		logger.With("radio", "r1").Info("Pushed device health")
+	}
+	if got := lines(buf); len(got) != 2 {
+		t.Errorf("got %d lines, want one per message: %q", len(got), got)
+	}
+}
+
+func TestLogger_Disabled(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	if Logger(l, 0) != l {
+		t.Error("a rate of 0 should leave the logger as is")
+	}
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of engine internals for metrics exporters.
+type Stats struct {
+	// EntitiesByController counts entities by Controller.Id.
+	EntitiesByController map[string]int
+	// EntitiesByComponent counts entities carrying each component,
+	// keyed by the Entity field name.
+	EntitiesByComponent map[string]int
+	// ConsumerQueueDepths holds the number of pending changes per
+	// WatchEntities consumer.
+	ConsumerQueueDepths map[uint64]int
+	// ConsumerFilters holds the cost of evaluating each WatchEntities
+	// consumer's filter, to tell which subscriptions are expensive.
+	ConsumerFilters map[uint64]FilterStats
+
+	EntitiesPushed  uint64
+	EntitiesExpired uint64
+	GCRuns          uint64
+	GCDuration      time.Duration
+	// GCLastDuration is how long the most recent GC sweep took.
+	GCLastDuration time.Duration
+}
+
+// FilterStats is the cumulative cost of a consumer's filter.
+type FilterStats struct {
+	// Evaluations counts the entities the filter was evaluated against,
+	// Matches those it matched.
+	Evaluations uint64
+	Matches     uint64
+	// Duration is the time spent evaluating the filter.
+	Duration time.Duration
+}
+
+var statsSource atomic.Pointer[func() Stats]
+
+// SetStatsSource registers the function ReadStats calls. The engine sets
+// it on startup.
+func SetStatsSource(fn func() Stats) {
+	statsSource.Store(&fn)
+}
+
+// ReadStats returns the current engine stats, or false if no engine is
+// running in this process.
+func ReadStats() (Stats, bool) {
+	fn := statsSource.Load()
+	if fn == nil {
+		return Stats{}, false
+	}
+	return (*fn)(), true
+}
@@ -60,6 +60,18 @@ func (m *MuxListener) route(conn net.Conn) {
 	peeked := &peekedConn{Conn: conn}
 	br := bufio.NewReaderSize(conn, 512)
 
+	// A TLS client hello has no line to wait for, and RTSP is never
+	// served over TLS here, so it goes to HTTP straight away.
+	if b, err := br.Peek(1); err == nil && b[0] == tlsHandshakeRecord {
+		peeked.r = br
+		select {
+		case m.httpCh <- peeked:
+		case <-m.closeCh:
+			conn.Close()
+		}
+		return
+	}
+
 	line, err := br.ReadString('\n')
 	if err != nil {
 		// Can't determine protocol; default to HTTP.
@@ -91,6 +103,9 @@ func (m *MuxListener) route(conn net.Conn) {
 	}
 }
 
+// tlsHandshakeRecord is the first byte of a TLS connection.
+const tlsHandshakeRecord = 0x16
+
 func isRTSP(firstLine string) bool {
 	upper := strings.ToUpper(strings.TrimSpace(firstLine))
 	if strings.Contains(upper, "RTSP/") {
@@ -0,0 +1,95 @@
+// Package projection narrows entities down to a subset of their components
+// before they leave the node.
+package projection
+
+import (
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// structural lists the entity fields that are never stripped: without them
+// the receiver cannot identify, attribute, expire or route the entity.
+var structural = []protoreflect.Name{"id", "controller", "lifetime", "routing"}
+
+// Keep clears every component of entity that is not listed in components.
+// Components are identified by their Entity field number, the same values
+// used by EntityFilter.Component. An empty list keeps everything.
+func Keep(entity *pb.Entity, components []uint32) {
+	if entity == nil || len(components) == 0 {
+		return
+	}
+
+	m := entity.ProtoReflect()
+	fields := m.Descriptor().Fields()
+
+	keep := make(map[protoreflect.FieldNumber]bool, len(components)+len(structural))
+	for _, c := range components {
+		keep[protoreflect.FieldNumber(c)] = true
+	}
+	for _, name := range structural {
+		if fd := fields.ByName(name); fd != nil {
+			keep[fd.Number()] = true
+		}
+	}
+
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if !keep[fd.Number()] {
+			m.Clear(fd)
+		}
+		return true
+	})
+}
+
+// Drop clears the listed components of entity and leaves everything else.
+// Structural fields are never cleared.
+func Drop(entity *pb.Entity, components []uint32) {
+	if entity == nil || len(components) == 0 {
+		return
+	}
+
+	m := entity.ProtoReflect()
+	fields := m.Descriptor().Fields()
+
+	protected := make(map[protoreflect.FieldNumber]bool, len(structural))
+	for _, name := range structural {
+		if fd := fields.ByName(name); fd != nil {
+			protected[fd.Number()] = true
+		}
+	}
+
+	for _, c := range components {
+		fd := fields.ByNumber(protoreflect.FieldNumber(c))
+		if fd == nil || protected[fd.Number()] {
+			continue
+		}
+		m.Clear(fd)
+	}
+}
+
+// Components returns the field numbers of the components set on entity, in
+// declaration order. Structural fields are not included.
+func Components(entity *pb.Entity) []uint32 {
+	if entity == nil {
+		return nil
+	}
+
+	m := entity.ProtoReflect()
+	fields := m.Descriptor().Fields()
+
+	protected := make(map[protoreflect.FieldNumber]bool, len(structural))
+	for _, name := range structural {
+		if fd := fields.ByName(name); fd != nil {
+			protected[fd.Number()] = true
+		}
+	}
+
+	var out []uint32
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if protected[fd.Number()] || !m.Has(fd) {
+			continue
+		}
+		out = append(out, uint32(fd.Number()))
+	}
+	return out
+}
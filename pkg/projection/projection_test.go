@@ -0,0 +1,98 @@
+package projection
+
+import (
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func testEntity() *pb.Entity {
+	return &pb.Entity{
+		Id:         "e1",
+		Label:      proto.String("track"),
+		Controller: &pb.Controller{Id: proto.String("adsb"), Node: proto.String("node-a")},
+		Lifetime:   &pb.Lifetime{},
+		Routing:    &pb.Routing{Channels: []*pb.Channel{{}}},
+		Geo:        &pb.GeoSpatialComponent{Latitude: 52, Longitude: 13},
+		Power:      &pb.PowerComponent{},
+		Config:     &pb.ConfigurationComponent{},
+	}
+}
+
+func TestKeep(t *testing.T) {
+	e := testEntity()
+	Keep(e, []uint32{uint32(pb.EntityComponent_EntityComponentGeo)})
+
+	if e.Geo == nil {
+		t.Error("geo should be retained")
+	}
+	if e.Power != nil {
+		t.Error("power should be stripped")
+	}
+	if e.Config != nil {
+		t.Error("config should be stripped")
+	}
+	if e.Label != nil {
+		t.Error("label should be stripped")
+	}
+	if e.Id != "e1" || e.Controller == nil || e.Lifetime == nil || e.Routing == nil {
+		t.Errorf("structural fields must be retained: %v", e)
+	}
+}
+
+func TestKeep_EmptyListKeepsEverything(t *testing.T) {
+	e := testEntity()
+	Keep(e, nil)
+	if !proto.Equal(e, testEntity()) {
+		t.Errorf("entity changed: %v", e)
+	}
+}
+
+func TestDrop(t *testing.T) {
+	e := testEntity()
+	Drop(e, []uint32{
+		uint32(pb.EntityComponent_EntityComponentPower),
+		uint32(pb.EntityComponent_EntityComponentController),
+	})
+
+	if e.Power != nil {
+		t.Error("power should be dropped")
+	}
+	if e.Controller == nil {
+		t.Error("controller is structural and must not be dropped")
+	}
+	if e.Geo == nil || e.Config == nil || e.Label == nil {
+		t.Error("unlisted components should be retained")
+	}
+}
+
+func TestComponents(t *testing.T) {
+	got := Components(testEntity())
+
+	want := map[uint32]bool{
+		uint32(pb.EntityComponent_EntityComponentGeo):    true,
+		uint32(pb.EntityComponent_EntityComponentPower):  true,
+		uint32(pb.EntityComponent_EntityComponentConfig): true,
+	}
+	seen := make(map[uint32]bool)
+	for _, c := range got {
+		seen[c] = true
+	}
+	for c := range want {
+		if !seen[c] {
+			t.Errorf("component %d missing from %v", c, got)
+		}
+	}
+	for _, name := range []string{"id", "controller", "lifetime", "routing"} {
+		fd := testEntity().ProtoReflect().Descriptor().Fields().ByName(protoreflect.Name(name))
+		if seen[uint32(fd.Number())] {
+			t.Errorf("structural field %s should not be listed", name)
+		}
+	}
+
+	if Components(nil) != nil {
+		t.Error("nil entity should have no components")
+	}
+}
@@ -0,0 +1,169 @@
+// Package symbol converts MIL-STD-2525C symbol identification codes
+// (SIDCs) to their MIL-STD-2525D form.
+package symbol
+
+import (
+	"fmt"
+	"strings"
+)
+
+// identities maps the 2525C standard identity to the 2525D context and
+// standard identity digits.
+var identities = map[byte]string{
+	'P': "00", // pending
+	'U': "01", // unknown
+	'A': "02", // assumed friend
+	'F': "03", // friend
+	'N': "04", // neutral
+	'S': "05", // suspect
+	'H': "06", // hostile
+	'G': "10", // exercise pending
+	'W': "11", // exercise unknown
+	'M': "12", // exercise assumed friend
+	'D': "13", // exercise friend
+	'L': "14", // exercise neutral
+	'J': "15", // joker
+	'K': "16", // faker
+}
+
+// statuses maps the 2525C status to the 2525D status digit.
+var statuses = map[byte]byte{
+	'P': '0', // present
+	'A': '1', // anticipated/planned
+	'C': '2', // present, fully capable
+	'D': '3', // damaged
+	'X': '4', // destroyed
+	'F': '5', // full to capacity
+}
+
+// entities maps the function id, without trailing dashes, of each 2525D
+// symbol set to its entity code. Functions are matched by longest prefix,
+// so e.g. mechanized infantry still converts to infantry.
+var entities = map[string]map[string]string{
+	"01": { // air
+		"M":   "110000",
+		"MF":  "110100",
+		"MFQ": "110103",
+		"MH":  "110200",
+		"C":   "120000",
+		"CF":  "120100",
+		"CH":  "120200",
+	},
+	"10": { // land unit
+		"UCA": "120500",
+		"UCI": "121100",
+		"UCR": "121300",
+		"UCD": "130100",
+		"UCF": "130300",
+		"UCE": "140700",
+	},
+	"30": { // sea surface
+		"C": "120000",
+		"N": "130000",
+		"X": "140000",
+	},
+	"35": { // sea subsurface
+		"S": "110000",
+	},
+}
+
+// echelons maps the 2525C echelon character to the 2525D amplifier.
+var echelons = map[byte]string{
+	'A': "11", // team/crew
+	'B': "12", // squad
+	'C': "13", // section
+	'D': "14", // platoon/detachment
+	'E': "15", // company/battery/troop
+	'F': "16", // battalion/squadron
+	'G': "17", // regiment/group
+	'H': "18", // brigade
+	'I': "21", // division
+	'J': "22", // corps
+	'K': "23", // army
+	'L': "24", // army group/front
+	'M': "25", // region/theater
+	'N': "26", // command
+}
+
+// To2525D converts a 15-character MIL-STD-2525C warfighting SIDC to a
+// 20-digit MIL-STD-2525D SIDC. Shorter codes are treated as padded with
+// dashes. Functions without a 2525D equivalent convert to the symbol set's
+// main icon, so the frame and identity are always preserved.
+func To2525D(sidc string) (string, error) {
+	sidc = strings.ToUpper(strings.ReplaceAll(sidc, "*", "-"))
+	if len(sidc) < 3 {
+		return "", fmt.Errorf("SIDC %q too short", sidc)
+	}
+	if len(sidc) < 15 {
+		sidc += strings.Repeat("-", 15-len(sidc))
+	}
+	if sidc[0] != 'S' {
+		return "", fmt.Errorf("SIDC %q: only warfighting symbols (coding scheme S) can be converted", sidc)
+	}
+
+	identity, ok := identities[sidc[1]]
+	if !ok {
+		return "", fmt.Errorf("SIDC %q: unknown standard identity %q", sidc, sidc[1])
+	}
+	function := strings.TrimRight(sidc[4:10], "-")
+	symbolSet, err := symbolSetOf(sidc[2], function)
+	if err != nil {
+		return "", fmt.Errorf("SIDC %q: %w", sidc, err)
+	}
+	status, ok := statuses[sidc[3]]
+	if !ok {
+		status = '0'
+	}
+	amplifier := "00"
+	if symbolSet == "10" {
+		if a, ok := echelons[sidc[11]]; ok {
+			amplifier = a
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("10") // version: 2525D
+	b.WriteString(identity)
+	b.WriteString(symbolSet)
+	b.WriteByte(status)
+	b.WriteByte('0') // headquarters/task force/dummy
+	b.WriteString(amplifier)
+	b.WriteString(entityOf(symbolSet, function))
+	b.WriteString("0000") // sector 1 and 2 modifiers
+	return b.String(), nil
+}
+
+// symbolSetOf returns the 2525D symbol set for a 2525C battle dimension.
+// Ground symbols split into units, equipment and installations by the
+// first character of their function id.
+func symbolSetOf(dimension byte, function string) (string, error) {
+	switch dimension {
+	case 'P':
+		return "05", nil
+	case 'A':
+		return "01", nil
+	case 'G', 'F', 'Z', 'X':
+		switch {
+		case strings.HasPrefix(function, "E"):
+			return "15", nil
+		case strings.HasPrefix(function, "I"):
+			return "20", nil
+		}
+		return "10", nil
+	case 'S':
+		return "30", nil
+	case 'U':
+		return "35", nil
+	}
+	return "", fmt.Errorf("unknown battle dimension %q", dimension)
+}
+
+func entityOf(symbolSet, function string) string {
+	table := entities[symbolSet]
+	for n := len(function); n > 0; n-- {
+		if code, ok := table[function[:n]]; ok {
+			return code
+		}
+	}
+	return "000000"
+}
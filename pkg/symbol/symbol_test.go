@@ -0,0 +1,64 @@
+package symbol
+
+import "testing"
+
+func TestTo2525D(t *testing.T) {
+	tests := []struct {
+		name string
+		sidc string
+		want string
+	}{
+		{"friendly infantry", "SFGPUCI----D---", "10031000141211000000"},
+		{"mechanized infantry falls back to infantry", "SFGPUCIZ-------", "10031000001211000000"},
+		{"hostile armor battalion", "SHGPUCA----F---", "10061000161205000000"},
+		{"neutral field artillery", "SNGPUCF--------", "10041000001303000000"},
+		{"unknown ground unit", "SUGP-----------", "10011000000000000000"},
+		{"ground equipment", "SFGPE----------", "10031500000000000000"},
+		{"ground installation", "SHGPI----------", "10062000000000000000"},
+		{"hostile rotary wing", "SHAPMH---------", "10060100001102000000"},
+		{"friendly drone", "SFAPMFQ--------", "10030100001101030000"},
+		{"civil fixed wing", "SNAPCF---------", "10040100001201000000"},
+		{"assumed friend air track", "SAAP-----------", "10020100000000000000"},
+		{"suspect combatant ship", "SSSPC----------", "10053000001200000000"},
+		{"non-military vessel", "SNSPX----------", "10043000001400000000"},
+		{"hostile submarine", "SHUPS----------", "10063500001100000000"},
+		{"pending space object", "SPPP-----------", "10000500000000000000"},
+		{"planned friendly unit", "SFGAUCR--------", "10031010001213000000"},
+		{"destroyed hostile armor", "SHGXUCA--------", "10061040001205000000"},
+		{"exercise friend", "SDGPUCE--------", "10131000001407000000"},
+		{"joker air", "SJAP-----------", "10150100000000000000"},
+		{"faker sea", "SKSP-----------", "10163000000000000000"},
+		{"short code is padded", "SFG", "10031000000000000000"},
+		{"asterisk padding and lower case", "sfgpuci***", "10031000001211000000"},
+		{"echelon ignored outside land units", "SFAPMF-----D---", "10030100001101000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := To2525D(tt.sidc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 20 {
+				t.Fatalf("got %d digits", len(got))
+			}
+			if got != tt.want {
+				t.Errorf("To2525D(%q) = %s, want %s", tt.sidc, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTo2525D_Invalid(t *testing.T) {
+	for _, sidc := range []string{
+		"",
+		"SF",
+		"GFGP-----------", // tactical graphic
+		"SQGP-----------", // unknown identity
+		"SFQP-----------", // unknown dimension
+	} {
+		if got, err := To2525D(sidc); err == nil {
+			t.Errorf("To2525D(%q) = %s, want error", sidc, got)
+		}
+	}
+}